@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/agent"
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	agentMaxStepsFlag int
+	agentYesFlag      bool
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent <task>",
+	Short: "Let the model use tools to complete a task",
+	Long: `Give the model a task and a few tools, and let it work in steps until it
+has an answer. Each step is streamed as it happens.
+
+Tools:
+  read_file     read a text file in the working directory
+  list_dir      list a directory in the working directory
+  run_command   run a shell command, once you approve it
+
+Files outside the working directory cannot be read. Commands run in the
+working directory only after you confirm each one; --yes runs them without
+asking, which is also needed without a terminal. The whole session is
+saved to history, so "ask -c" can follow up on it.
+
+Examples:
+  ask agent "find which test is flaky and why"
+  ask agent --max-steps 40 "why does the build fail on Go 1.22?"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runAgent,
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.Flags().IntVar(&agentMaxStepsFlag, "max-steps", agent.DefaultMaxSteps, "Stop after this many tool calls")
+	agentCmd.Flags().BoolVarP(&agentYesFlag, "yes", "y", false, "Run commands without asking")
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	if agentMaxStepsFlag < 1 {
+		return usageErrorf("--max-steps must be at least 1")
+	}
+	task := strings.TrimSpace(strings.Join(args, " "))
+	if task == "" {
+		return usageErrorf("task is empty")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	shell := userShell()
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+	model := getModel()
+
+	tools := []agent.Tool{
+		agent.ReadFile(cwd),
+		agent.ListDir(cwd),
+		agent.RunCommand(shell, cwd, confirmCommand),
+	}
+	env := fmt.Sprintf("Environment:\n- OS: %s\n- Shell: %s\n- Working directory: %s", runtime.GOOS, filepath.Base(shell), cwd)
+	system := agent.SystemPrompt(tools, env)
+	if extra, err := buildSystemPrompt(); err != nil {
+		return fmt.Errorf("resolving system prompt: %w", err)
+	} else if extra != "" {
+		system += "\n" + extra
+	}
+
+	writer := stream.NewWriter(os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+	writer.SetCodeStyle(string(getTheme(os.Stdout).Code))
+	// cutShort means the last reply failed partway
+	var cutShort bool
+	a := &agent.Agent{
+		Tools:    tools,
+		MaxSteps: agentMaxStepsFlag,
+		Chat: func(ctx context.Context, messages []provider.Message) (string, error) {
+			response, err := streamChat(ctx, p, &provider.ChatRequest{Model: model, Messages: messages}, writer)
+			cutShort = err != nil
+			return response, err
+		},
+		OnResult: func(call agent.Call, result string, err error) {
+			summary := fmt.Sprintf("%d bytes", len(result))
+			if err != nil {
+				summary = err.Error()
+			} else if call.Tool == "run_command" {
+				summary, _, _ = strings.Cut(result, "\n")
+			}
+			fmt.Fprintf(os.Stderr, "-> %s: %s\n\n", util.Truncate(call.String(), 60), summary)
+		},
+	}
+
+	messages := []provider.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: task},
+	}
+	messages, err = a.Run(ctx, messages)
+	saveAgentSession(p.Name(), model, messages, cutShort)
+	return err
+}
+
+// confirmCommand asks before the agent runs command, unless --yes.
+func confirmCommand(command string) (bool, error) {
+	if agentYesFlag {
+		fmt.Fprintf(os.Stderr, "$ %s\n", command)
+		return true, nil
+	}
+	yes, ok := confirm(fmt.Sprintf("Run `%s`?", command))
+	if !ok {
+		return false, errors.New("no terminal to confirm the command; the user can rerun with --yes")
+	}
+	return yes, nil
+}
+
+// saveAgentSession saves the steps of an agent session to history, even
+// if it ended early: up to its last reply, marked partial if cutShort.
+// A tool result the model never replied to is left out.
+func saveAgentSession(providerName, model string, messages []provider.Message, cutShort bool) {
+	last := len(messages) - 1
+	for last >= 0 && messages[last].Role != "assistant" {
+		last--
+		cutShort = false
+	}
+	if last < 0 {
+		return
+	}
+	reply := history.Message{Role: "assistant", Content: messages[last].Content, Partial: cutShort}
+	if err := saveReply(providerName, model, messages[:last], reply, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/codeblock"
+	"github.com/devaloi/ask/internal/diff"
+)
+
+var (
+	outFlag   string
+	forceFlag bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <file>",
+	Short: "Write the code from a response to a file",
+	Long: `Write the code from a model response to a file.
+
+The response is read from stdin, or when nothing is piped, taken from the
+last answer in the most recent conversation. If it contains fenced code
+blocks, the longest one is written; otherwise the whole response is.
+
+An existing file is only replaced with --force, and a copy of it is kept as
+<file>.bak. In a terminal, a diff of the change is shown for confirmation
+before anything is written.
+
+The same applies to a one-shot chat with --out:
+
+  ask --out Dockerfile "Dockerfile for a Go 1.25 web service"
+
+Examples:
+  ask apply main.go --force
+  ask "Rewrite this without globals" < main.go | ask apply main.go --force`,
+	Args: cobra.ExactArgs(1),
+	RunE: runApply,
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().BoolVar(&forceFlag, "force", false, "Replace an existing file, keeping a .bak copy")
+
+	rootCmd.Flags().StringVarP(&outFlag, "out", "o", "", "Write the code from the response to this file instead of stdout")
+	rootCmd.Flags().BoolVar(&forceFlag, "force", false, "With --out, replace an existing file, keeping a .bak copy")
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	var response string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		id, err := latestConversationID()
+		if err != nil {
+			return err
+		}
+		conv, err := loadConversation(id)
+		if err != nil {
+			return err
+		}
+		for _, msg := range conv.Messages {
+			if msg.Role == "assistant" {
+				response = msg.Content
+			}
+		}
+		if response == "" {
+			return fmt.Errorf("conversation %d has no response to apply", id)
+		}
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		response = string(data)
+	}
+
+	return writeOutput(args[0], response)
+}
+
+// latestConversationID returns the most recently active conversation in
+// the current workspace.
+func latestConversationID() (int64, error) {
+	store, err := openStore()
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+	return store.LatestConversationIDIn(context.Background(), currentWorkspace())
+}
+
+// writeOutput writes the code in response to path. An existing file is
+// replaced only with --force, after copying it to path.bak. When there is
+// a terminal, the change is previewed as a diff and must be confirmed.
+func writeOutput(path, response string) error {
+	code := codeblock.Extract(response)
+	if code == "" {
+		return fmt.Errorf("response contains no code to write")
+	}
+
+	old, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if exists && string(old) == code {
+		fmt.Fprintf(os.Stderr, "%s is unchanged.\n", path)
+		return nil
+	}
+	if exists && !forceFlag {
+		return usageErrorf("%s already exists\n\nUse --force to replace it (the current file is kept as %s.bak)", path, path)
+	}
+
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		oldName := "/dev/null"
+		if exists {
+			oldName = "a/" + diffPath(path)
+		}
+		fmt.Fprint(os.Stderr, diff.Unified(oldName, "b/"+diffPath(path), string(old), code, diff.DefaultContext))
+
+		yes, ok := confirm(fmt.Sprintf("Write %s?", path))
+		if ok && !yes {
+			return fmt.Errorf("aborted: %s not written", path)
+		}
+	}
+
+	perm := fs.FileMode(0644)
+	if exists {
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if err := os.WriteFile(path+".bak", old, perm); err != nil {
+			return fmt.Errorf("writing backup: %w", err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(code), perm); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if exists {
+		fmt.Fprintf(os.Stderr, "Wrote %s (previous version saved as %s.bak)\n", path, path)
+	} else {
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	}
+	return nil
+}
+
+// confirm asks question on the terminal and reports whether the answer
+// was yes. ok is false if there is no terminal to ask on.
+func confirm(question string) (yes, ok bool) {
+	tty, err := openTTY()
+	if err != nil {
+		return false, false
+	}
+	defer tty.Close()
+	if !tty.isTerminal() {
+		return false, false
+	}
+
+	fmt.Fprintf(tty, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, true
+	default:
+		return false, true
+	}
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/keyring"
+	"github.com/devaloi/ask/internal/secret"
+)
+
+// apiKeyEnv maps each provider to the environment variable for its key.
+var apiKeyEnv = map[string]string{
+	"openai":    "OPENAI_API_KEY",
+	"anthropic": "ANTHROPIC_API_KEY",
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage API keys in the OS keyring",
+	Long: `Store provider API keys in the OS keyring (macOS Keychain, the Secret
+Service on Linux, or Windows Credential Manager) instead of config.yaml or
+shell profiles.
+
+"ask auth set" stores the key and points the config file at it:
+
+  providers:
+    openai:
+      api_key: keyring
+
+An OPENAI_API_KEY or ANTHROPIC_API_KEY environment variable still takes
+precedence. On Linux, the secret-tool command (libsecret) must be installed.`,
+}
+
+var authSetCmd = &cobra.Command{
+	Use:   "set <provider>",
+	Short: "Store a provider's API key in the OS keyring",
+	Long: `Store a provider's API key in the OS keyring and set the provider's
+api_key to "keyring" in the config file. The key is read without echo from
+the terminal, or from stdin when piped:
+
+  ask auth set openai
+  pass show openai | ask auth set openai`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthSet,
+}
+
+var authDeleteCmd = &cobra.Command{
+	Use:     "delete <provider>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a provider's API key from the OS keyring",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runAuthDelete,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where each provider's API key comes from",
+	Args:  cobra.NoArgs,
+	RunE:  runAuthStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authSetCmd, authDeleteCmd, authStatusCmd)
+}
+
+// authProviders returns the providers with API keys, sorted.
+func authProviders() []string {
+	names := make([]string, 0, len(apiKeyEnv))
+	for name := range apiKeyEnv {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func checkAuthProvider(name string) error {
+	if _, ok := apiKeyEnv[name]; !ok {
+		return usageErrorf("unknown provider: %s\n\nAvailable providers: %s", name, strings.Join(authProviders(), ", "))
+	}
+	return nil
+}
+
+func runAuthSet(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := checkAuthProvider(name); err != nil {
+		return err
+	}
+
+	key, err := readAPIKey(name)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return usageErrorf("no API key given")
+	}
+
+	if err := keyring.Set(name, key); err != nil {
+		return fmt.Errorf("storing API key: %w", err)
+	}
+	if err := config.SetAPIKey(name, config.KeyringRef); err != nil {
+		return fmt.Errorf("updating config: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Stored the %s API key in the OS keyring\n", name)
+	if env := apiKeyEnv[name]; os.Getenv(env) != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s is set and takes precedence; unset it to use the keyring\n", env)
+	}
+	return nil
+}
+
+// readAPIKey reads a key from the terminal without echo, or from stdin
+// when it is piped.
+func readAPIKey(name string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("reading API key: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s API key: ", name)
+	key, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading API key: %w", err)
+	}
+	return strings.TrimSpace(string(key)), nil
+}
+
+func runAuthDelete(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := checkAuthProvider(name); err != nil {
+		return err
+	}
+
+	if err := keyring.Delete(name); err != nil {
+		if errors.Is(err, keyring.ErrNotFound) {
+			return fmt.Errorf("no %s API key in the OS keyring", name)
+		}
+		return fmt.Errorf("deleting API key: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Deleted the %s API key from the OS keyring\n", name)
+	if cfg.Providers[name].APIKey == config.KeyringRef {
+		fmt.Fprintf(os.Stderr, "Note: the config file still reads the key from the keyring; set providers.%s.api_key or %s\n", name, apiKeyEnv[name])
+	}
+	return nil
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	for _, name := range authProviders() {
+		fmt.Printf("%-10s %s\n", name, apiKeySource(name))
+	}
+	return nil
+}
+
+// apiKeySource describes where a provider's API key comes from.
+func apiKeySource(name string) string {
+	if env := apiKeyEnv[name]; os.Getenv(env) != "" {
+		return "environment (" + env + ")"
+	}
+	key := cfg.Providers[name].APIKey
+	switch {
+	case key == "":
+		return "not set"
+	case key == config.KeyringRef:
+		_, err := keyring.Get(name)
+		switch {
+		case err == nil:
+			return "OS keyring"
+		case errors.Is(err, keyring.ErrNotFound):
+			return "OS keyring (missing; run: ask auth set " + name + ")"
+		default:
+			return fmt.Sprintf("OS keyring (unreadable: %v)", err)
+		}
+	case strings.HasPrefix(key, "${") && strings.HasSuffix(key, "}"):
+		return "not set (" + key + " is empty)"
+	case secret.IsEncrypted(key):
+		method, _, _ := secret.Parse(key)
+		return "config file (encrypted with " + method + ")"
+	default:
+		return "config file"
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/devaloi/ask/internal/autocontext"
+)
+
+// autoContextNone turns automatic context off.
+const autoContextNone = "none"
+
+// autoContextFlag overrides the context sources of the persona or config
+// file.
+var autoContextFlag []string
+
+func init() {
+	rootCmd.PersistentFlags().StringSliceVar(&autoContextFlag, "auto-context", nil, "Add facts about the environment to the system prompt: git, os, cwd, or none")
+}
+
+// getAutoContext returns the context sources to use, from the flag,
+// persona, or config file, warning about and ignoring unknown ones.
+func getAutoContext() []string {
+	sources := cfg.AutoContext
+	if p := getPersona(); len(p.AutoContext) > 0 {
+		sources = p.AutoContext
+	}
+	if autoContextFlag != nil {
+		sources = autoContextFlag
+	}
+
+	var valid []string
+	for _, s := range sources {
+		switch {
+		case s == autoContextNone:
+			return nil
+		case slices.Contains(autocontext.Sources, s):
+			valid = append(valid, s)
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: invalid auto context %q (want git, os, cwd, or none), ignoring\n", s)
+		}
+	}
+	return valid
+}
+
+// autoContextPrompt returns facts about the environment for the system
+// prompt, or "" if automatic context is off.
+func autoContextPrompt() string {
+	sources := getAutoContext()
+	if len(sources) == 0 {
+		return ""
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		dir = "."
+	}
+	return autocontext.Gather(context.Background(), sources, autocontext.Env{Dir: dir, Shell: userShell()})
+}
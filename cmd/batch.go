@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/batch"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	batchOutFlag         string
+	batchConcurrencyFlag int
+	batchRateFlag        int
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch <prompts.jsonl|->",
+	Short: "Run every prompt in a JSON Lines file",
+	Long: `Run every prompt in a JSON Lines file and write one result per line.
+
+Each input line is a JSON string (the prompt), an array of messages, or an
+object with "prompt" or "messages" and optional "id", "system", and "model":
+
+  "What is a goroutine?"
+  {"id": "q2", "prompt": "What is a channel?", "model": "gpt-4o-mini"}
+  [{"role": "system", "content": "Be terse."}, {"role": "user", "content": "What is a mutex?"}]
+
+Each result line holds the input's line number, id, model, and response,
+or an error. -s, -m, and -p apply to every prompt that does not set its own.
+
+With --out, results are written as they complete and prompts already
+answered in the file are skipped, so a failed or interrupted run picks up
+where it left off when run again. Without --out, results go to stdout.
+
+--concurrency runs several prompts at once; results are still written in
+input order. --rate caps requests per minute across all of them, and when
+the provider reports a rate limit every worker pauses before retrying.
+
+Examples:
+  ask batch questions.jsonl --out answers.jsonl
+  ask batch questions.jsonl --out answers.jsonl -j 8 --rate 500
+  jq -c '{id, prompt: .text}' tickets.json | ask batch - -m gpt-4o-mini`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().StringVarP(&batchOutFlag, "out", "o", "", "Append results to this file and skip prompts it has already answered")
+	batchCmd.Flags().IntVarP(&batchConcurrencyFlag, "concurrency", "j", 1, "Number of prompts to run at once")
+	batchCmd.Flags().IntVar(&batchRateFlag, "rate", 0, "Maximum requests per minute across all workers (0 for no limit)")
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if batchConcurrencyFlag < 1 {
+		return usageErrorf("--concurrency must be at least 1")
+	}
+	if batchRateFlag < 0 {
+		return usageErrorf("--rate cannot be negative")
+	}
+
+	items, err := readBatchItems(args[0])
+	if err != nil {
+		return err
+	}
+
+	systemPrompt, err := buildSystemPrompt()
+	if err != nil {
+		return fmt.Errorf("resolving system prompt: %w", err)
+	}
+
+	limiter := batch.NewLimiter(0)
+	if batchRateFlag > 0 {
+		limiter = batch.NewLimiter(time.Minute / time.Duration(batchRateFlag))
+	}
+	p, err := newPausingProvider(getProvider(), func(ctx context.Context, d time.Duration) error {
+		// Hold back every worker, not just this one
+		limiter.Pause(d)
+		return limiter.Wait(ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	pending := items
+	if batchOutFlag != "" {
+		done, err := batch.LoadResults(batchOutFlag)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", batchOutFlag, err)
+		}
+		pending = pending[:0:0]
+		for _, item := range items {
+			if r, ok := done[item.Line]; !ok || !r.OK() {
+				pending = append(pending, item)
+			}
+		}
+
+		f, err := os.OpenFile(batchOutFlag, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", batchOutFlag, err)
+		}
+		defer func() {
+			f.Close()
+			if err := batch.Compact(batchOutFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to compact %s: %v\n", batchOutFlag, err)
+			}
+		}()
+		out = f
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	progress := newBatchProgress(len(pending))
+
+	p = provider.Chain(p, provider.RateLimit(limiter.Wait))
+
+	do := func(ctx context.Context, item batch.Item) batch.Result {
+		result, err := runBatchItem(ctx, p, item, systemPrompt)
+		// Every remaining request would fail the same way
+		if errors.Is(err, provider.ErrAuth) {
+			cancel(err)
+		}
+		progress.finished(result)
+		return result
+	}
+	emit := func(result batch.Result) error {
+		if err := batch.WriteResult(out, result); err != nil {
+			return fmt.Errorf("writing result: %w", err)
+		}
+		if err := context.Cause(ctx); err != nil {
+			return fmt.Errorf("stopping batch: %w", err)
+		}
+		return nil
+	}
+
+	start := time.Now()
+	err = batch.Run(ctx, pending, batchConcurrencyFlag, do, emit)
+	progress.clear()
+	if err != nil {
+		return err
+	}
+	failed := progress.failed
+
+	summary := fmt.Sprintf("%d answered, %d failed", len(pending)-failed, failed)
+	if skipped := len(items) - len(pending); skipped > 0 {
+		summary += fmt.Sprintf(", %d already answered", skipped)
+	}
+	fmt.Fprintf(os.Stderr, "Done: %s\n", summary)
+
+	var batchErr error
+	if failed > 0 {
+		batchErr = fmt.Errorf("%d of %d prompts failed", failed, len(pending))
+		if batchOutFlag != "" {
+			batchErr = fmt.Errorf("%w; run the same command again to retry them", batchErr)
+		}
+	}
+	notifyDone(time.Since(start), "Batch complete: "+summary, batchErr)
+	return batchErr
+}
+
+// readBatchItems parses the prompt file at path, or stdin for "-".
+func readBatchItems(path string) ([]batch.Item, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening prompt file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	items, err := batch.ReadItems(r)
+	if err != nil {
+		return nil, usageErrorf("invalid prompt file %s: %v", path, err)
+	}
+	if len(items) == 0 {
+		return nil, usageErrorf("no prompts in %s", path)
+	}
+	return items, nil
+}
+
+// runBatchItem sends one batch item and returns its result. The error is
+// also recorded in the result.
+func runBatchItem(ctx context.Context, p provider.Provider, item batch.Item, systemPrompt string) (batch.Result, error) {
+	req := &provider.ChatRequest{
+		Messages: item.ChatMessages(systemPrompt),
+		Model:    item.Model,
+	}
+	if req.Model == "" {
+		req.Model = getModel()
+	}
+
+	result := batch.Result{Line: item.Line, ID: item.ID, Model: req.Model}
+	response, err := collectChat(ctx, p, req)
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Response = response
+
+	last := req.Messages[len(req.Messages)-1]
+	logExchange(p.Name(), req.Model, last.Content, response)
+	return result, nil
+}
+
+// collectChat sends req to p and returns the complete response, without
+// displaying it. A response cut short by the token limit is returned with
+// a warning rather than an error.
+func collectChat(ctx context.Context, p provider.Provider, req *provider.ChatRequest) (string, error) {
+	tokens := make(chan string, util.DefaultChannelBuffer)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Chat(ctx, req, tokens)
+	}()
+
+	var response strings.Builder
+	for token := range tokens {
+		response.WriteString(token)
+	}
+	err := <-errCh
+	if errors.Is(err, provider.ErrTruncated) {
+		fmt.Fprintln(os.Stderr, "Warning: the response was cut short by the token limit")
+		err = nil
+	}
+	return response.String(), err
+}
+
+// batchProgress reports how far a batch has got on stderr: a status line
+// redrawn in place on a terminal, and a warning for each failure.
+type batchProgress struct {
+	mu       sync.Mutex
+	total    int
+	done     int
+	failed   int
+	terminal bool
+}
+
+func newBatchProgress(total int) *batchProgress {
+	return &batchProgress{total: total, terminal: term.IsTerminal(int(os.Stderr.Fd()))}
+}
+
+// finished records a completed result.
+func (b *batchProgress) finished(r batch.Result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done++
+	b.clearLocked()
+	if !r.OK() {
+		b.failed++
+		fmt.Fprintf(os.Stderr, "Warning: line %d: %s\n", r.Line, r.Error)
+	}
+	if b.terminal {
+		fmt.Fprintf(os.Stderr, "[%d/%d] %d failed", b.done, b.total, b.failed)
+	}
+}
+
+// clear removes the status line.
+func (b *batchProgress) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clearLocked()
+}
+
+func (b *batchProgress) clearLocked() {
+	if b.terminal {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+	}
+}
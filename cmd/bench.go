@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/bench"
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	benchModelsFlag    []string
+	benchRunsFlag      int
+	benchMaxTokensFlag int
+	benchJSONFlag      bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench <prompt>",
+	Short: "Measure the latency and throughput of models",
+	Long: `Send the same prompt to each model several times and report the median
+time to first token, total latency, and output tokens per second.
+
+Runs alternate between the models, so that a slow spell of the network or
+a provider does not favor one of them. Each model is sent to the provider
+that offers it, or the default provider. Every run is a billed request.
+
+Examples:
+  ask bench -m gpt-4o -m claude-sonnet-4-20250514 --runs 5 "Write a haiku about Go"
+  ask bench --max-tokens 256 --json "Explain TCP slow start"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBench,
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	// Shadows the global --model to take several models
+	benchCmd.Flags().StringArrayVarP(&benchModelsFlag, "model", "m", nil, "Model to benchmark (repeatable; default: the configured model)")
+	benchCmd.Flags().IntVar(&benchRunsFlag, "runs", 3, "Number of runs per model")
+	benchCmd.Flags().IntVar(&benchMaxTokensFlag, "max-tokens", 0, "Limit each response to this many tokens")
+	benchCmd.Flags().BoolVar(&benchJSONFlag, "json", false, "Print the summary as JSON")
+}
+
+// benchResult is the summary of one model's runs.
+type benchResult struct {
+	Model    string
+	Provider string
+	Errors   int
+	bench.Summary
+}
+
+// MarshalJSON encodes the durations in milliseconds.
+func (r benchResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Model           string  `json:"model"`
+		Provider        string  `json:"provider"`
+		Runs            int     `json:"runs"`
+		Errors          int     `json:"errors"`
+		FirstToken      int64   `json:"first_token_ms"`
+		Total           int64   `json:"total_ms"`
+		TotalP90        int64   `json:"total_p90_ms"`
+		TokensPerSecond float64 `json:"tokens_per_second"`
+	}{r.Model, r.Provider, r.Runs, r.Errors, r.FirstToken.Milliseconds(), r.Total.Milliseconds(), r.TotalP90.Milliseconds(), r.TokensPerSecond})
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	if benchRunsFlag < 1 {
+		return usageErrorf("--runs must be at least 1")
+	}
+	if benchMaxTokensFlag < 0 {
+		return usageErrorf("--max-tokens must not be negative")
+	}
+	prompt := strings.TrimSpace(args[0])
+	if prompt == "" {
+		return usageErrorf("prompt is empty")
+	}
+	models := benchModelsFlag
+	if len(models) == 0 {
+		models = []string{getModel()}
+	}
+
+	providers, err := configuredProviders()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	samples := make([][]bench.Sample, len(models))
+	results := make([]benchResult, len(models))
+	for i, model := range models {
+		results[i] = benchResult{Model: model, Provider: providerForModel(providers, model).Name()}
+	}
+
+	for run := 1; run <= benchRunsFlag; run++ {
+		for i, model := range models {
+			p := providerForModel(providers, model)
+			req := &provider.ChatRequest{
+				Model:     model,
+				Messages:  []provider.Message{{Role: "user", Content: prompt}},
+				MaxTokens: benchMaxTokensFlag,
+			}
+			sample, err := measureChat(ctx, p, req)
+			if err != nil {
+				if errors.Is(err, provider.ErrAuth) {
+					return err
+				}
+				results[i].Errors++
+				fmt.Fprintf(os.Stderr, "Warning: run %d of %s failed: %v\n", run, model, err)
+				continue
+			}
+			samples[i] = append(samples[i], sample)
+			fmt.Fprintf(os.Stderr, "Run %d/%d  %-26s  first token %6.2fs  total %6.2fs  %4d tokens\n",
+				run, benchRunsFlag, util.Truncate(model, 26), sample.FirstToken.Seconds(), sample.Total.Seconds(), sample.Tokens)
+		}
+	}
+
+	for i := range results {
+		results[i].Summary = bench.Summarize(samples[i])
+	}
+	if benchJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	printBench(results)
+
+	for _, r := range results {
+		if r.Runs == 0 {
+			return fmt.Errorf("every run of %s failed", r.Model)
+		}
+	}
+	return nil
+}
+
+// measureChat sends req and times its response.
+func measureChat(ctx context.Context, p provider.Provider, req *provider.ChatRequest) (bench.Sample, error) {
+	tokenCh := make(chan string, util.DefaultChannelBuffer)
+	errCh := make(chan error, 1)
+	w := stream.NewWriter(io.Discard, false)
+	w.StartTiming()
+	go func() {
+		errCh <- p.Chat(ctx, req, tokenCh)
+	}()
+
+	for token := range tokenCh {
+		_ = w.Write(token)
+	}
+	w.Flush()
+	timings := w.Timings()
+	sample := bench.Sample{FirstToken: timings.FirstToken(), Total: timings.Total}
+	if err := <-errCh; err != nil {
+		return sample, err
+	}
+	if w.Response() == "" {
+		return sample, errors.New("empty response")
+	}
+	sample.Tokens = tokens.Estimate(w.Response())
+	return sample, nil
+}
+
+// printBench writes the summary table.
+func printBench(results []benchResult) {
+	fmt.Printf("%-26s  %-9s  %4s  %6s  %11s  %9s  %9s  %7s\n", "Model", "Provider", "Runs", "Errors", "First token", "Total", "Total p90", "Tok/s")
+	fmt.Printf("%s  %s  %s  %s  %s  %s  %s  %s\n", strings.Repeat("-", 26), strings.Repeat("-", 9), strings.Repeat("-", 4),
+		strings.Repeat("-", 6), strings.Repeat("-", 11), strings.Repeat("-", 9), strings.Repeat("-", 9), strings.Repeat("-", 7))
+	for _, r := range results {
+		if r.Runs == 0 {
+			fmt.Printf("%-26s  %-9s  %4d  %6d  %11s  %9s  %9s  %7s\n", util.Truncate(r.Model, 26), r.Provider, r.Runs, r.Errors, "-", "-", "-", "-")
+			continue
+		}
+		fmt.Printf("%-26s  %-9s  %4d  %6d  %10.2fs  %8.2fs  %8.2fs  %7.1f\n", util.Truncate(r.Model, 26), r.Provider, r.Runs, r.Errors,
+			r.FirstToken.Seconds(), r.Total.Seconds(), r.TotalP90.Seconds(), r.TokensPerSecond)
+	}
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/budget"
+	"github.com/devaloi/ask/internal/compact"
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Show spending against your budgets",
+	Long: `Show spending so far against the daily and monthly budgets in
+~/.config/ask/config.yaml:
+
+  budget:
+    daily: 2.00        # US dollars, all providers together
+    monthly: 20.00
+    policy: refuse     # warn (default) or refuse
+    providers:
+      openai:
+        monthly: 10.00
+
+Spending is estimated from the tokens of every request ask sends and the
+models' list prices. Once a budget is reached, requests are sent with a
+warning, or refused with policy: refuse. Models without a known price are
+refused too under policy: refuse, until their prices are set under models.`,
+	Args: cobra.NoArgs,
+	RunE: runBudget,
+}
+
+func init() {
+	rootCmd.AddCommand(budgetCmd)
+}
+
+func runBudget(cmd *cobra.Command, args []string) error {
+	limits := budget.Limits(cfg.Budget, "")
+	if len(limits) == 0 {
+		fmt.Fprintln(os.Stderr, "No budgets configured. See: ask budget --help")
+		return nil
+	}
+
+	statuses, err := budgetStatus(context.Background(), limits)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		name := s.Provider
+		if name == "" {
+			name = "all"
+		}
+		mark := ""
+		if s.Exceeded() {
+			mark = "  exceeded"
+		}
+		fmt.Printf("%-10s %-8s $%8.2f of $%.2f%s\n", name, s.Period, s.Spent, s.Amount, mark)
+	}
+	return nil
+}
+
+// usageMu serializes usage records and budget checks between concurrent
+// requests, such as batch workers.
+var usageMu sync.Mutex
+
+// usageRecords is where usage is recorded and spending read: a
+// history.Store, or the daemon while it runs, which also saves one-shot
+// chats and agent sessions. Other history writes, such as the REPL's,
+// open the store themselves.
+type usageRecords interface {
+	RecordUsage(ctx context.Context, u *history.Usage) error
+	Spend(ctx context.Context, providerName string, since time.Time) (float64, error)
+}
+
+// withUsageRecords runs fn with the running daemon, if any, or else the
+// history store, which it closes after.
+func withUsageRecords(fn func(usageRecords) error) error {
+	if d := daemonClient(); d != nil {
+		return fn(d)
+	}
+	return withStore(func(store history.Store) error {
+		return fn(store)
+	})
+}
+
+// budgetStatus returns the spending against each of limits.
+func budgetStatus(ctx context.Context, limits []budget.Limit) ([]budget.Status, error) {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	var statuses []budget.Status
+	err := withUsageRecords(func(records usageRecords) error {
+		var err error
+		statuses, err = budget.Check(limits, time.Now(), func(providerName string, since time.Time) (float64, error) {
+			return records.Spend(ctx, providerName, since)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading usage records: %w", err)
+	}
+	return statuses, nil
+}
+
+// budgetWarning makes sure an exceeded budget is only warned about once.
+var budgetWarning sync.Once
+
+// checkBudget applies the budget policy before a request for model to
+// providerName: with an exceeded budget, it warns once, or refuses with
+// an error wrapping budget.ErrExceeded. Under policy: refuse, a model
+// without a known price is refused too, with budget.ErrUnpriced, since
+// its spending would not count.
+func checkBudget(ctx context.Context, providerName, model string) error {
+	limits := budget.Limits(cfg.Budget, providerName)
+	if len(limits) == 0 {
+		return nil
+	}
+	if _, priced := provider.Cost(model, 0, 0); !priced && getBudgetPolicy() == config.BudgetPolicyRefuse {
+		return fmt.Errorf("%w: %s\n\nSet its input_price and output_price under models in ~/.config/ask/config.yaml", budget.ErrUnpriced, model)
+	}
+	statuses, err := budgetStatus(ctx, limits)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot check budget: %v\n", err)
+		return nil
+	}
+	over := budget.FirstExceeded(statuses)
+	if over == nil {
+		return nil
+	}
+
+	if getBudgetPolicy() == config.BudgetPolicyRefuse {
+		return fmt.Errorf("%w: spent %s\n\nRaise the limit in ~/.config/ask/config.yaml, or wait for the budget to reset", budget.ErrExceeded, over)
+	}
+	budgetWarning.Do(func() {
+		fmt.Fprintf(os.Stderr, "Warning: over budget: spent %s\n", over)
+	})
+	return nil
+}
+
+// getBudgetPolicy returns budget.policy from the config file, warning
+// about and ignoring an invalid value.
+func getBudgetPolicy() string {
+	switch cfg.Budget.Policy {
+	case "", config.BudgetPolicyWarn:
+		return config.BudgetPolicyWarn
+	case config.BudgetPolicyRefuse:
+		return config.BudgetPolicyRefuse
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: invalid budget policy %q (want warn or refuse), using warn\n", cfg.Budget.Policy)
+		return config.BudgetPolicyWarn
+	}
+}
+
+// newProvider returns the named provider with secret checks, retries,
+// budget checks, and usage records, sending its chats through the daemon
+// if one is running. Use it for anything that sends chat requests.
+func newProvider(name string) (provider.Provider, error) {
+	return newPausingProvider(name, pauseToRetry)
+}
+
+// newPausingProvider is newProvider, calling pause rather than
+// pauseToRetry before each retry.
+func newPausingProvider(name string, pause func(ctx context.Context, d time.Duration) error) (provider.Provider, error) {
+	p, err := configuredProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	if d := daemonClient(); d != nil && slices.Contains(d.Status.Providers, name) {
+		p = provider.WrapChat(p, d.Chat(name))
+	}
+	if mode := getRedactMode(); mode != config.RedactOff {
+		p = redactSecrets(p, mode)
+	}
+	if getScrubMode() == config.ScrubPII {
+		p = scrubPII(p)
+	}
+	return provider.Chain(p,
+		provider.RetryWith(retryPolicy(name), pause),
+		provider.Guard(func(ctx context.Context, p provider.Provider, req *provider.ChatRequest) error {
+			return checkBudget(ctx, p.Name(), req.Model)
+		}),
+		provider.Observe(recordUsage),
+	), nil
+}
+
+// retryPolicy returns the configured retry policy for the named
+// provider.
+func retryPolicy(name string) provider.RetryPolicy {
+	rp := cfg.RetryFor(name)
+	return provider.RetryPolicy{
+		Attempts:   rp.MaxAttempts,
+		Backoff:    rp.Backoff,
+		Multiplier: rp.Multiplier,
+		MaxElapsed: rp.MaxElapsed,
+		Statuses:   rp.StatusCodes,
+		Resume:     cfg.Retry.Resume,
+	}
+}
+
+// pauseToRetry says that a failed request will be retried, then waits d.
+func pauseToRetry(ctx context.Context, d time.Duration) error {
+	wait := d
+	if wait > time.Second {
+		wait = wait.Round(time.Second)
+	}
+	fmt.Fprintf(os.Stderr, "Request failed; retrying in %s\n", wait)
+	return provider.Sleep(ctx, d)
+}
+
+// sessionUsage, if set, totals the usage of the requests of the
+// interactive session, guarded by usageMu.
+var sessionUsage *usageTally
+
+// recordUsage records the estimated tokens and cost of a request. Failed
+// requests may still have been billed for what was streamed. Failures
+// are logged rather than reported: usage records must not break chats.
+func recordUsage(p provider.Provider, req *provider.ChatRequest, response string, err error) {
+	if err != nil && response == "" {
+		return
+	}
+	u := estimateUsage(p.Name(), req, response)
+
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	warnUnpriced(u)
+	if sessionUsage != nil {
+		sessionUsage.add(u)
+	}
+	err = withUsageRecords(func(records usageRecords) error {
+		return records.RecordUsage(context.Background(), u)
+	})
+	if err != nil {
+		slog.Warn("cannot record usage", "error", err)
+	}
+}
+
+// unpricedWarned holds the models warned about by warnUnpriced, guarded
+// by usageMu.
+var unpricedWarned = make(map[string]bool)
+
+// warnUnpriced warns, once per model, when u is recorded at no cost
+// because its model has no known price while a budget is set.
+func warnUnpriced(u *history.Usage) {
+	if unpricedWarned[u.Model] || len(budget.Limits(cfg.Budget, u.Provider)) == 0 {
+		return
+	}
+	if _, priced := provider.Cost(u.Model, 0, 0); priced {
+		return
+	}
+	unpricedWarned[u.Model] = true
+	fmt.Fprintf(os.Stderr, "Warning: %s has no known price, so its spending does not count against your budget; set its prices under models in the config file\n", u.Model)
+}
+
+// estimateUsage returns the estimated tokens and cost of an exchange.
+func estimateUsage(providerName string, req *provider.ChatRequest, response string) *history.Usage {
+	u := &history.Usage{
+		Provider:     providerName,
+		Model:        req.Model,
+		InputTokens:  compact.Size(req.Messages),
+		OutputTokens: tokens.Estimate(response),
+	}
+	u.Cost, _ = provider.Cost(u.Model, u.InputTokens, u.OutputTokens)
+	return u
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/cache"
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	cacheFlag   bool
+	noCacheFlag bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the response cache",
+	Long: `Manage the response cache.
+
+When enabled, a one-shot chat whose provider, model, messages, and
+parameters match an earlier one is answered from the cache instantly,
+without calling the provider. Enable it in ~/.config/ask/config.yaml:
+
+  cache:
+    enabled: true
+    ttl: 24h
+
+or for a single command with --cache. --no-cache bypasses it.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached responses",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	rootCmd.Flags().BoolVar(&cacheFlag, "cache", false, "Reuse a cached response to an identical request, and cache this one")
+	rootCmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "Bypass the response cache")
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+	n, err := c.Clear()
+	if err != nil {
+		return fmt.Errorf("clearing cache: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d cached responses\n", n)
+	return nil
+}
+
+// openCache returns the response cache in the user's cache directory.
+func openCache() (*cache.Cache, error) {
+	dir, err := config.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return cache.New(filepath.Join(dir, "responses"), cfg.Cache.TTL), nil
+}
+
+// cachingEnabled reports whether one-shot chats use the response cache.
+func cachingEnabled() bool {
+	if noCacheFlag {
+		return false
+	}
+	return cacheFlag || cfg.Cache.Enabled
+}
+
+// cachedChat is streamChat through the response cache, when it is
+// enabled: a cached response is written to w without calling the
+// provider, and a new response is cached once it completes.
+func cachedChat(ctx context.Context, p provider.Provider, req *provider.ChatRequest, w *stream.Writer) (string, error) {
+	if cachingEnabled() {
+		c, err := openCache()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: response cache unavailable: %v\n", err)
+		} else {
+			p = provider.Chain(p, c.Middleware(func(err error) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to cache response: %v\n", err)
+			}))
+		}
+	}
+	return streamChat(ctx, p, req, w)
+}
@@ -1,116 +1,505 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
+	"github.com/devaloi/ask/internal/clipboard"
 	"github.com/devaloi/ask/internal/config"
-	"github.com/devaloi/ask/internal/history"
-	"github.com/devaloi/ask/internal/provider"
+	"github.com/devaloi/ask/internal/files"
+	"github.com/devaloi/ask/internal/notify"
 	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/internal/transcript"
 	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/internal/version"
+	"github.com/devaloi/ask/internal/webpage"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
 )
 
-var continueFlag int64
+var (
+	continueFlag string
+	dryRunFlag   bool
+	fileFlags    []string
+	urlFlags     []string
+	logFileFlag  string
+	pasteFlag    bool
+	editFlag     bool
+	pagerFlag    bool
+	saveFlag     bool
+	noSaveFlag   bool
+	webFlag      bool
+	lastFlag     bool
+	titleFlag    string
+	tagFlags     []string
+
+	autoContinueFlag bool
+)
+
+// continued is the conversation being continued with -c or --last, if
+// any.
+var continued *history.Conversation
 
 func init() {
-	rootCmd.Flags().Int64VarP(&continueFlag, "continue", "c", 0, "Continue conversation with ID")
+	rootCmd.Flags().StringVarP(&continueFlag, "continue", "c", "", "Continue conversation with ID (latest if omitted)")
+	rootCmd.Flags().Lookup("continue").NoOptDefVal = continueLatest
+	rootCmd.Flags().BoolVar(&lastFlag, "last", false, "Continue the latest conversation in this workspace")
+	rootCmd.Flags().StringVar(&logFileFlag, "log-file", "", "Append a plain-text transcript of prompts and responses to this file")
+	rootCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Print the request that would be sent without sending it")
+	rootCmd.Flags().StringArrayVarP(&fileFlags, "file", "f", nil, "Include a file, directory, or glob in the prompt (repeatable)")
+	rootCmd.Flags().StringArrayVar(&urlFlags, "url", nil, "Include the readable text of a web page in the prompt (repeatable)")
+	rootCmd.Flags().BoolVar(&pasteFlag, "paste", false, "Include the clipboard contents in the prompt")
+	rootCmd.Flags().BoolVarP(&editFlag, "edit", "e", false, "Compose the prompt in $EDITOR")
+	rootCmd.Flags().BoolVar(&pagerFlag, "pager", false, "Page responses longer than the screen through $PAGER")
+	rootCmd.Flags().BoolVar(&saveFlag, "save", false, "Save a one-shot chat to history even when output is piped")
+	rootCmd.Flags().BoolVar(&noSaveFlag, "no-save", false, "Do not save a one-shot chat to history")
+	rootCmd.Flags().BoolVar(&webFlag, "web", false, "Let the model search the web, and list the sources it cites")
+	rootCmd.Flags().BoolVar(&autoContinueFlag, "auto-continue", false, "Ask for the rest of responses cut short by the token limit")
+	rootCmd.Flags().StringVar(&titleFlag, "title", "", "Title the new conversation, instead of after its first prompt; implies --save")
+	rootCmd.Flags().StringSliceVar(&tagFlags, "tag", nil, "Tag the new conversation (repeatable); implies --save")
+	addStdinFlags(rootCmd.Flags())
+}
+
+// defaultAutoContinueLimit is how many times a response cut short by the
+// token limit is continued, unless auto_continue.limit says otherwise.
+const defaultAutoContinueLimit = 3
+
+// autoContinueLimit returns how many times to ask for the rest of a
+// response cut short by the token limit: none unless --auto-continue or
+// auto_continue.enabled in the config file says to.
+func autoContinueLimit() int {
+	if !autoContinueFlag && !cfg.AutoContinue.Enabled {
+		return 0
+	}
+	if cfg.AutoContinue.Limit > 0 {
+		return cfg.AutoContinue.Limit
+	}
+	return defaultAutoContinueLimit
+}
+
+// newChatProvider is newProvider for one-shot chats and the REPL, which
+// also ask for the rest of responses cut short by the token limit when
+// autoContinueLimit says to. Other commands, and API clients of ask
+// serve, keep to the token limit they set.
+func newChatProvider(name string) (provider.Provider, error) {
+	p, err := newProvider(name)
+	if err != nil {
+		return nil, err
+	}
+	if n := autoContinueLimit(); n > 0 {
+		// Each continuation is retried, checked, and recorded on its own
+		p = provider.Chain(p, provider.AutoContinue(n))
+	}
+	return p, nil
+}
+
+// checkTitleAndTags checks --title and --tag, which name new
+// conversations, trimming them.
+func checkTitleAndTags() error {
+	if titleFlag == "" && len(tagFlags) == 0 {
+		return nil
+	}
+	switch {
+	case noSaveFlag:
+		return usageErrorf("--title and --tag cannot be used with --no-save")
+	case continueFlag != "" || lastFlag:
+		return usageErrorf("--title and --tag name new conversations; they cannot be used with --continue or --last")
+	}
+	titleFlag = strings.TrimSpace(titleFlag)
+	var tags []string
+	for _, tag := range tagFlags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return usageErrorf("--tag cannot be empty")
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	tagFlags = tags
+	return nil
 }
 
+// continueLatest is the --continue value used when no ID is given.
+const continueLatest = "latest"
+
 func runChat(cmd *cobra.Command, args []string) error {
+	if !cmd.Flags().Changed("pager") {
+		pagerFlag = cfg.Pager
+	}
+
+	if saveFlag && noSaveFlag {
+		return usageErrorf("--save and --no-save cannot be used together")
+	}
+	if err := checkTitleAndTags(); err != nil {
+		return err
+	}
+	if cacheFlag && noCacheFlag {
+		return usageErrorf("--cache and --no-cache cannot be used together")
+	}
+	if contextPolicyFlag != "" && !slices.Contains(contextPolicies, contextPolicyFlag) {
+		return usageErrorf("invalid --context-policy %q (want %s)", contextPolicyFlag, strings.Join(contextPolicies, ", "))
+	}
+	if scrubFlag != "" && !slices.Contains(scrubModes, scrubFlag) {
+		return usageErrorf("invalid --scrub %q (want %s)", scrubFlag, strings.Join(scrubModes, ", "))
+	}
+	if redactFlag != "" && !slices.Contains(redactModes, redactFlag) {
+		return usageErrorf("invalid --redact %q (want %s)", redactFlag, strings.Join(redactModes, ", "))
+	}
+	if streamJSONFlag && (outFlag != "" || len(postFlags) > 0) {
+		return usageErrorf("--stream-json cannot be used with --out or --post")
+	}
+	if ragKFlag < 1 {
+		return usageErrorf("--rag-k must be at least 1")
+	}
+
+	var continueID int64
+	var err error
+	if lastFlag {
+		if continueFlag != "" {
+			return usageErrorf("--last and --continue cannot be used together")
+		}
+		if continueID, err = latestConversationID(); err != nil {
+			return err
+		}
+	} else if continueID, args, err = resolveContinue(continueFlag, args); err != nil {
+		return err
+	}
+	continued = nil
+	if continueID > 0 {
+		if continued, err = loadConversation(continueID); err != nil {
+			return err
+		}
+	}
+
+	if templateFlag != "" {
+		text, err := loadTemplate(templateFlag)
+		if err != nil {
+			return err
+		}
+		values, err := templateVars()
+		if err != nil {
+			return err
+		}
+		if text, err = fillTemplate(templateFlag, text, values); err != nil {
+			return err
+		}
+		args = []string{withTemplate(text, strings.Join(args, " "))}
+	} else if len(varFlags) > 0 {
+		return usageErrorf("--var needs a template (-t)")
+	}
+
+	if editFlag {
+		prompt, err := composePrompt(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		args = []string{prompt}
+	}
+
 	// If no arguments and stdin is a terminal, enter interactive mode
 	stdinIsTerminal := term.IsTerminal(int(os.Stdin.Fd()))
 
-	if len(args) == 0 && stdinIsTerminal && continueFlag == 0 {
-		return runInteractive()
+	if len(args) == 0 && stdinIsTerminal && !dryRunFlag && len(fileFlags) == 0 && len(urlFlags) == 0 && !pasteFlag && outFlag == "" && !streamJSONFlag {
+		return runInteractive(continued)
 	}
 
 	// One-shot mode (or continue mode)
+	if streamJSONFlag {
+		return emitError(runOneShot(args))
+	}
 	return runOneShot(args)
 }
 
+// composePrompt opens initial in the user's editor and returns the saved
+// prompt, or an error if it was left empty.
+func composePrompt(initial string) (string, error) {
+	if initial != "" {
+		initial += "\n"
+	}
+	text, err := editText(initial, "ask-prompt-*.md")
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("aborting due to empty prompt")
+	}
+	return text, nil
+}
+
+// resolveContinue turns the --continue flag value into a conversation ID.
+//
+// Because the flag's value is optional, "ask -c 42 ..." parses 42 as the
+// first argument; a leading numeric argument is therefore taken as the
+// ID. With no ID, the most recently active conversation in the current
+// workspace is used.
+// It returns 0 when not continuing, along with the remaining arguments.
+func resolveContinue(flag string, args []string) (int64, []string, error) {
+	switch flag {
+	case "":
+		return 0, args, nil
+	case continueLatest:
+		if len(args) > 0 {
+			if id, err := strconv.ParseInt(args[0], 10, 64); err == nil && id > 0 {
+				return id, args[1:], nil
+			}
+		}
+
+		id, err := latestConversationID()
+		if err != nil {
+			return 0, nil, err
+		}
+		return id, args, nil
+	default:
+		id, err := strconv.ParseInt(flag, 10, 64)
+		if err != nil || id <= 0 {
+			return 0, nil, usageErrorf("invalid conversation ID: %s", flag)
+		}
+		return id, args, nil
+	}
+}
+
 func runOneShot(args []string) error {
 	ctx := context.Background()
 
 	// Build prompt from args and stdin
-	prompt, err := buildPrompt(args)
+	prompt, sources, err := buildPrompt(args)
 	if err != nil {
 		return fmt.Errorf("building prompt: %w", err)
 	}
 
-	if strings.TrimSpace(prompt) == "" && continueFlag == 0 {
-		return fmt.Errorf("no prompt provided\n\nUsage: ask \"your question\"\n       cat file | ask \"explain this\"")
+	if strings.TrimSpace(prompt) == "" && continued == nil {
+		return usageErrorf("no prompt provided\n\nUsage: ask \"your question\"\n       cat file | ask \"explain this\"")
+	}
+
+	// Retrieve context for the question itself, falling back to the
+	// whole prompt when it all came from stdin or files
+	query := strings.Join(args, " ")
+	if strings.TrimSpace(query) == "" {
+		query = prompt
+	}
+	prompt, err = withRAGContext(ctx, query, prompt)
+	if err != nil {
+		return err
 	}
 
 	// Get system prompt if specified
-	systemPrompt, err := resolveSystemPrompt(systemFlag)
+	systemPrompt, err := buildSystemPrompt()
 	if err != nil {
 		return fmt.Errorf("resolving system prompt: %w", err)
 	}
 
+	if err := routeRequest(systemPrompt, prompt); err != nil {
+		return err
+	}
+
 	// Create provider
 	providerName := getProvider()
-	p, err := provider.New(providerName, cfg)
+	p, err := newChatProvider(providerName)
 	if err != nil {
 		return fmt.Errorf("creating provider: %w", err)
 	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
 
 	// Build messages - either new or from continued conversation
+	var pending []history.Message
 	var messages []provider.Message
 	var conv *history.Conversation
 
-	if continueFlag > 0 {
-		// Load previous conversation
-		store, err := openStore()
+	if continued != nil {
+		conv = continued
+		pending = slices.Clone(conv.Messages)
+	} else if systemPrompt != "" {
+		pending = append(pending, history.Message{Role: "system", Content: systemPrompt})
+	}
+	if strings.TrimSpace(prompt) != "" {
+		pending = append(pending, history.Message{Role: "user", Content: prompt})
+	}
+
+	queue := func() error {
+		return queuePrompt(p.Name(), getModel(), systemPrompt, prompt, conv)
+	}
+	if queueFlag && !dryRunFlag {
+		// The context is built when the prompt is sent
+		return queue()
+	}
+
+	if dryRunFlag {
+		// Nothing is sent, so nothing is summarized or dropped
+		messages = toProviderMessages(pending)
+	} else {
+		var convID int64
+		if conv != nil {
+			convID = conv.ID
+		}
+		messages, err = contextMessages(ctx, p, getModel(), convID, pending)
 		if err != nil {
-			return fmt.Errorf("opening history store: %w", err)
+			return err
 		}
-		defer store.Close()
+	}
+	if err := lintPrompt(messages, prompt, sources, getModel()); err != nil {
+		return err
+	}
 
-		conv, err = store.GetConversation(continueFlag)
-		if err != nil {
-			return fmt.Errorf("loading conversation %d: %w", continueFlag, err)
+	// Create request
+	req := &provider.ChatRequest{
+		Messages:  messages,
+		Model:     getModel(),
+		WebSearch: webFlag,
+	}
+
+	if dryRunFlag {
+		return printDryRun(ctx, os.Stdout, p, req)
+	}
+	// While the response streams, Ctrl+C stops it rather than the
+	// process, so what was received is kept. A second Ctrl+C exits at once.
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Create writer
+	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	writer := stream.NewWriter(os.Stdout, stdoutIsTerminal)
+	writer.SetCodeStyle(string(getTheme(os.Stdout).Code))
+	if outFlag != "" || len(postFlags) > 0 {
+		// The response goes to a file, where only its code is shown as a
+		// diff, or is printed once post-processed
+		writer = stream.NewWriter(io.Discard, true)
+	}
+	var events *stream.EventWriter
+	if streamJSONFlag {
+		// Programs read the response from delta events
+		events = stream.NewEventWriter(os.Stdout)
+		writer = stream.NewWriter(io.Discard, true)
+		writer.TeeRaw(events)
+		if err := events.Emit(stream.Event{Type: stream.EventStart, Provider: p.Name(), Model: req.Model}); err != nil {
+			return err
 		}
+	}
+	var rendered strings.Builder
+	paging := stdoutIsTerminal && pagerFlag && outFlag == "" && !streamJSONFlag
+	if paging {
+		writer.Tee(&rendered)
+	}
 
-		// Convert history messages to provider messages
-		for _, msg := range conv.Messages {
-			messages = append(messages, provider.Message{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
+	start := time.Now()
+	response, err := cachedChat(ctx, p, req, writer)
+	notifyDone(time.Since(start), response, err)
+	if errors.Is(err, context.Canceled) && !streamJSONFlag {
+		return interruptOneShot(p.Name(), req.Model, prompt, messages, response, conv, stdoutIsTerminal)
+	}
+	if err != nil {
+		if response == "" && !streamJSONFlag {
+			if queued, qErr := offerQueue(err, queue); queued || qErr != nil {
+				return qErr
+			}
+		}
+		// With --stream-json, the deltas so far are followed by an error
+		// event, and the response so far is kept all the same
+		if response != "" {
+			endPartialLine(stdoutIsTerminal)
+			if savePartial(p.Name(), req.Model, prompt, messages, response, conv, stdoutIsTerminal) {
+				fmt.Fprintln(os.Stderr, "[failed; the partial response is saved to history]")
+			}
+		}
+		return err
+	}
+	if events != nil {
+		if err := emitUsageAndDone(events, p.Name(), req, response, start, writer.Timings()); err != nil {
+			return err
 		}
 	}
+	if len(postFlags) > 0 {
+		if response, err = postProcess(ctx, response); err != nil {
+			return err
+		}
+		if outFlag == "" {
+			fmt.Print(response)
+			if response != "" && !strings.HasSuffix(response, "\n") {
+				fmt.Println()
+			}
+		}
+		rendered.Reset()
+		rendered.WriteString(response)
+	}
+	if paging {
+		pageIfLong(rendered.String())
+	}
 
-	// Add system prompt if starting fresh
-	if systemPrompt != "" && continueFlag == 0 {
-		messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+	logExchange(p.Name(), req.Model, prompt, response)
+
+	if shouldSaveHistory(stdoutIsTerminal) && strings.TrimSpace(prompt) != "" {
+		if err := saveToHistory(p.Name(), getModel(), messages, response, conv); err != nil {
+			// Don't fail the command, just warn about history
+			fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
+		}
 	}
 
-	// Add user message if provided
-	if strings.TrimSpace(prompt) != "" {
-		messages = append(messages, provider.Message{Role: "user", Content: prompt})
+	if outFlag != "" {
+		return writeOutput(outFlag, response)
 	}
+	return nil
+}
 
-	// Create request
-	req := &provider.ChatRequest{
-		Messages: messages,
-		Model:    getModel(),
+// interruptOneShot finishes a one-shot chat stopped with Ctrl+C: the
+// response so far is saved to history, marked partial, and the exit code
+// is exitCancelled.
+func interruptOneShot(providerName, model, prompt string, messages []provider.Message, response string, conv *history.Conversation, stdoutIsTerminal bool) error {
+	endPartialLine(stdoutIsTerminal)
+	note := "[cancelled]"
+	if savePartial(providerName, model, prompt, messages, response, conv, stdoutIsTerminal) {
+		note = "[cancelled; the partial response is saved to history]"
 	}
+	fmt.Fprintln(os.Stderr, note)
+	return exitStatus(exitCancelled)
+}
 
-	// Create stream channel
-	tokens := make(chan string, util.DefaultChannelBuffer)
+// endPartialLine ends the line of a response cut short, before the shell
+// prompt, if the response is on the terminal.
+func endPartialLine(stdoutIsTerminal bool) {
+	if stdoutIsTerminal && outFlag == "" && len(postFlags) == 0 && !streamJSONFlag {
+		fmt.Println()
+	}
+}
 
-	// Create writer
-	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
-	writer := stream.NewWriter(os.Stdout, stdoutIsTerminal)
+// savePartial saves response, cut short by Ctrl+C or an error, to
+// history marked partial, if one-shot chats are saved. It reports
+// whether it saved it.
+func savePartial(providerName, model, prompt string, messages []provider.Message, response string, conv *history.Conversation, stdoutIsTerminal bool) bool {
+	if response == "" || strings.TrimSpace(prompt) == "" || !shouldSaveHistory(stdoutIsTerminal) {
+		return false
+	}
+	reply := history.Message{Role: "assistant", Content: response, Partial: true}
+	if err := saveReply(providerName, model, messages, reply, conv); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// streamChat sends req to p, writing tokens to w as they arrive, and
+// returns the complete response. On error, the partial response received
+// so far is returned along with it. A response cut short by the token
+// limit is returned with a warning rather than an error.
+func streamChat(ctx context.Context, p provider.Provider, req *provider.ChatRequest, w *stream.Writer) (string, error) {
+	tokens := make(chan string, util.DefaultChannelBuffer)
+	w.StartTiming()
 
 	// Start streaming in goroutine
 	errCh := make(chan error, 1)
@@ -118,44 +507,106 @@ func runOneShot(args []string) error {
 		errCh <- p.Chat(ctx, req, tokens)
 	}()
 
-	// Read and write tokens, collect response
-	var response strings.Builder
+	// Write tokens; the writer keeps the response
+	var writeErr error
 	for token := range tokens {
-		response.WriteString(token)
-		if err := writer.Write(token); err != nil {
-			return fmt.Errorf("failed to write output: %w", err)
+		if err := w.Write(token); err != nil && writeErr == nil {
+			writeErr = err
 		}
 	}
-	writer.Flush()
+	w.Flush()
+	response := w.Response()
+
+	err := <-errCh
+	if errors.Is(err, provider.ErrTruncated) {
+		// The response is usable, if incomplete
+		if w.IsTTY() && !strings.HasSuffix(response, "\n") {
+			fmt.Fprintln(os.Stderr)
+		}
+		if autoContinueLimit() > 0 {
+			fmt.Fprintln(os.Stderr, "Warning: the response was cut short by the token limit")
+		} else {
+			fmt.Fprintln(os.Stderr, "Warning: the response was cut short by the token limit; --auto-continue asks for the rest")
+		}
+		err = nil
+	}
+	if verboseFlag {
+		reportTimings(w.Timings())
+	}
 
 	// Check for errors from provider
-	if err := <-errCh; err != nil {
-		return fmt.Errorf("chat stream: %w", err)
+	if err != nil {
+		return response, fmt.Errorf("chat stream: %w", err)
 	}
-
-	// Save to history if TTY (don't save when piped)
-	if stdoutIsTerminal && strings.TrimSpace(prompt) != "" {
-		if err := saveToHistory(p.Name(), getModel(), messages, response.String(), conv); err != nil {
-			// Don't fail the command, just warn about history
-			fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
-		}
+	if writeErr != nil {
+		return response, fmt.Errorf("failed to write output: %w", writeErr)
 	}
 
-	return nil
+	return response, nil
 }
 
-func saveToHistory(providerName, model string, messages []provider.Message, response string, existingConv *history.Conversation) error {
-	store, err := openStore()
+// logExchange appends the exchange to the transcript log file, if one is
+// configured. Failures are reported as warnings.
+func logExchange(providerName, model, prompt, response string) {
+	path := logFileFlag
+	if path == "" {
+		path = cfg.LogFile
+	}
+	if path == "" {
+		return
+	}
+
+	err := transcript.Append(path, transcript.Exchange{
+		Time:     time.Now(),
+		Provider: providerName,
+		Model:    model,
+		Prompt:   prompt,
+		Response: response,
+	})
 	if err != nil {
-		return err
+		fmt.Fprintf(os.Stderr, "Warning: failed to write transcript: %v\n", err)
 	}
-	defer store.Close()
+}
+
+// shouldSaveHistory reports whether a one-shot chat is saved to history.
+// --save and --no-save override save_history, which by default saves only
+// when output goes to a terminal, keeping scripted use out of history.
+func shouldSaveHistory(stdoutIsTerminal bool) bool {
+	switch {
+	case noSaveFlag:
+		return false
+	case saveFlag, titleFlag != "", len(tagFlags) > 0:
+		return true
+	}
+
+	switch cfg.SaveHistory {
+	case config.SaveHistoryAlways:
+		return true
+	case config.SaveHistoryNever:
+		return false
+	case config.SaveHistoryTTY, "":
+		return stdoutIsTerminal
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: invalid save_history %q (want tty, always, or never), using tty\n", cfg.SaveHistory)
+		return stdoutIsTerminal
+	}
+}
 
+func saveToHistory(providerName, model string, messages []provider.Message, response string, existingConv *history.Conversation) error {
+	return saveReply(providerName, model, messages, history.Message{Role: "assistant", Content: response}, existingConv)
+}
+
+// saveReply saves an exchange ending in reply to history: messages, for a
+// new conversation, or the last of them, to continue existingConv.
+func saveReply(providerName, model string, messages []provider.Message, reply history.Message, existingConv *history.Conversation) error {
 	conv := existingConv
 	if conv == nil {
 		conv = &history.Conversation{
-			Model:    model,
-			Provider: providerName,
+			Title:     titleFlag,
+			Model:     model,
+			Provider:  providerName,
+			Workspace: currentWorkspace(),
+			Tags:      tagFlags,
 		}
 	}
 
@@ -182,49 +633,174 @@ func saveToHistory(providerName, model string, messages []provider.Message, resp
 	}
 
 	// Add assistant response
-	newMessages = append(newMessages, history.Message{
-		Role:    "assistant",
-		Content: response,
-	})
+	newMessages = append(newMessages, reply)
 
 	conv.Messages = newMessages
-	_, err = store.SaveConversation(conv)
+	if d := daemonClient(); d != nil {
+		return d.SaveConversation(context.Background(), conv)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	_, err = store.SaveConversation(context.Background(), conv)
 	return err
 }
 
-func openStore() (*history.Store, error) {
+// openStore opens the history store. Tests replace it, such as with a
+// history.MemoryStore.
+var openStore = func() (history.Store, error) {
 	dataDir, err := config.GetDataDir()
 	if err != nil {
 		return nil, err
 	}
 
-	dbPath := filepath.Join(dataDir, "history.db")
-	return history.NewStore(dbPath)
+	store, err := history.NewSQLiteStore(filepath.Join(dataDir, "history.db"))
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
 }
 
-func buildPrompt(args []string) (string, error) {
+// inputPlaceholders mark where piped input goes in the prompt argument.
+var inputPlaceholders = []string{"{stdin}", "{input}"}
+
+// buildPrompt assembles the user prompt: included files and web pages,
+// then piped stdin and clipboard input, then the arguments. If the
+// arguments contain an input placeholder, the input is substituted there
+// instead. It also returns the estimated tokens from each source.
+func buildPrompt(args []string) (string, promptSources, error) {
 	var parts []string
+	var sources promptSources
+
+	// Prepend included files
+	if len(fileFlags) > 0 {
+		included, err := files.Include(fileFlags, cfg.MaxIncludeBytes)
+		if err != nil {
+			return "", sources, err
+		}
+		parts = append(parts, included)
+		sources.files += tokens.Estimate(included)
+	}
+	if len(urlFlags) > 0 {
+		fetcher := &webpage.Fetcher{UserAgent: "ask/" + version.Get().Version}
+		included, err := fetcher.Include(context.Background(), urlFlags, cfg.MaxIncludeBytes)
+		if err != nil {
+			return "", sources, err
+		}
+		parts = append(parts, included)
+		sources.files += tokens.Estimate(included)
+	}
+
+	var inputs []string
 
 	// Read from stdin if data is available
 	stdinIsTerminal := term.IsTerminal(int(os.Stdin.Fd()))
 	if !stdinIsTerminal {
-		data, err := io.ReadAll(os.Stdin)
+		input, err := readStdin()
+		if err != nil {
+			return "", sources, err
+		}
+		if input != "" {
+			inputs = append(inputs, input)
+		}
+	}
+
+	// Add clipboard contents, like stdin
+	if pasteFlag {
+		text, err := clipboard.Read()
 		if err != nil {
-			return "", fmt.Errorf("failed to read from stdin: %w", err)
+			return "", sources, fmt.Errorf("reading clipboard: %w", err)
+		}
+		if strings.TrimSpace(text) != "" {
+			inputs = append(inputs, text)
+		}
+	}
+
+	for _, input := range inputs {
+		sources.input += tokens.Estimate(input)
+	}
+
+	question := strings.Join(args, " ")
+	if hasInputPlaceholder(question) {
+		if len(inputs) == 0 {
+			return "", sources, usageErrorf("prompt contains {stdin} but no input was piped")
 		}
-		if len(data) > 0 {
-			parts = append(parts, string(data))
+		input := strings.TrimRight(strings.Join(inputs, "\n\n"), "\n")
+		// In one pass, so input that itself contains a placeholder is
+		// left as it is
+		var pairs []string
+		for _, p := range inputPlaceholders {
+			pairs = append(pairs, p, input)
 		}
+		question = strings.NewReplacer(pairs...).Replace(question)
+	} else {
+		parts = append(parts, inputs...)
 	}
 
 	// Add command line arguments
-	if len(args) > 0 {
-		parts = append(parts, strings.Join(args, " "))
+	if question != "" {
+		parts = append(parts, question)
 	}
 
+	return strings.Join(parts, "\n\n"), sources, nil
+}
+
+// hasInputPlaceholder reports whether s contains an input placeholder.
+func hasInputPlaceholder(s string) bool {
+	for _, p := range inputPlaceholders {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSystemPrompt returns the system prompt for a chat: the configured
+// default_system (unless --no-default-system), then the --as persona's
+// prompt, then each -s, in order, followed by any automatic context.
+func buildSystemPrompt() (string, error) {
+	var specs []string
+	if cfg.DefaultSystem != "" && !noDefaultSystemFlag {
+		specs = append(specs, cfg.DefaultSystem)
+	}
+	if p := getPersona(); p.System != "" {
+		specs = append(specs, p.System)
+	}
+	specs = append(specs, systemFlags...)
+	prompt, err := joinSystemPrompts(specs)
+	if err != nil {
+		return "", err
+	}
+
+	if env := autoContextPrompt(); env != "" {
+		if prompt != "" {
+			prompt += "\n\n"
+		}
+		prompt += env
+	}
+	return prompt, nil
+}
+
+// joinSystemPrompts resolves each spec and joins them with blank lines.
+func joinSystemPrompts(specs []string) (string, error) {
+	var parts []string
+	for _, spec := range specs {
+		prompt, err := resolveSystemPrompt(spec)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(prompt) != "" {
+			parts = append(parts, strings.TrimSpace(prompt))
+		}
+	}
 	return strings.Join(parts, "\n\n"), nil
 }
 
+// resolveSystemPrompt returns the system prompt s, reading it from a file
+// if s is "@path".
 func resolveSystemPrompt(s string) (string, error) {
 	if s == "" {
 		return "", nil
@@ -243,148 +819,53 @@ func resolveSystemPrompt(s string) (string, error) {
 	return s, nil
 }
 
-func runInteractive() error {
-	ctx := context.Background()
-
-	// Create provider
-	providerName := getProvider()
-	p, err := provider.New(providerName, cfg)
+// loadConversation reads conversation id from the history store.
+func loadConversation(id int64) (*history.Conversation, error) {
+	store, err := openStore()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("opening history store: %w", err)
 	}
+	defer store.Close()
 
-	fmt.Printf("ask — using %s/%s\n", p.Name(), getModel())
-	fmt.Println("Type /quit to exit, /new to start fresh, /help for commands")
-	fmt.Println()
-
-	// Get system prompt if specified
-	systemPrompt, err := resolveSystemPrompt(systemFlag)
+	conv, err := store.GetConversation(context.Background(), id)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("loading conversation %d: %w", id, err)
 	}
+	return conv, nil
+}
 
-	// Message history for the conversation
-	var messages []provider.Message
-	if systemPrompt != "" {
-		messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+// reportTimings writes the latency and throughput of a response to
+// stderr, for --verbose.
+func reportTimings(t stream.Timings) {
+	if t.Tokens() == 0 {
+		fmt.Fprintf(os.Stderr, "[no tokens in %.2fs]\n", t.Total.Seconds())
+		return
 	}
-
-	reader := bufio.NewReader(os.Stdin)
-	writer := stream.NewWriter(os.Stdout, true)
-
-	// Track conversation for history
-	var conv *history.Conversation
-
-	for {
-		fmt.Print("> ")
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				fmt.Println()
-				return nil
-			}
-			return fmt.Errorf("failed to read input: %w", err)
-		}
-
-		input = strings.TrimSpace(input)
-		if input == "" {
-			continue
-		}
-
-		// Handle special commands
-		if strings.HasPrefix(input, "/") {
-			cmd := strings.ToLower(input)
-			switch {
-			case cmd == "/quit" || cmd == "/exit" || cmd == "/q":
-				return nil
-			case cmd == "/new" || cmd == "/clear":
-				messages = messages[:0]
-				conv = nil
-				if systemPrompt != "" {
-					messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
-				}
-				fmt.Println("Started new conversation")
-				continue
-			case strings.HasPrefix(cmd, "/model "):
-				newModel := strings.TrimPrefix(input, "/model ")
-				modelFlag = strings.TrimSpace(newModel)
-				fmt.Printf("Switched to model: %s\n", modelFlag)
-				continue
-			case cmd == "/help":
-				printHelp()
-				continue
-			default:
-				fmt.Printf("Unknown command: %s (type /help for commands)\n", input)
-				continue
-			}
-		}
-
-		// Add user message
-		messages = append(messages, provider.Message{Role: "user", Content: input})
-
-		// Create request
-		req := &provider.ChatRequest{
-			Messages: messages,
-			Model:    getModel(),
-		}
-
-		// Stream response
-		tokens := make(chan string, util.DefaultChannelBuffer)
-		errCh := make(chan error, 1)
-
-		go func() {
-			errCh <- p.Chat(ctx, req, tokens)
-		}()
-
-		// Collect response
-		var response strings.Builder
-		for token := range tokens {
-			response.WriteString(token)
-			if err := writer.Write(token); err != nil {
-				fmt.Printf("\nError writing output: %v\n", err)
-				break
-			}
-		}
-		writer.Flush()
-		fmt.Println()
-
-		// Check for errors
-		if err := <-errCh; err != nil {
-			fmt.Printf("Error: %v\n", err)
-			// Remove the failed user message
-			messages = messages[:len(messages)-1]
-			continue
-		}
-
-		// Add assistant response to history
-		responseContent := response.String()
-		messages = append(messages, provider.Message{Role: "assistant", Content: responseContent})
-
-		// Save to history
-		if conv == nil {
-			conv = &history.Conversation{
-				Model:    getModel(),
-				Provider: p.Name(),
-			}
-		}
-		conv.Messages = []history.Message{
-			{Role: "user", Content: input},
-			{Role: "assistant", Content: responseContent},
-		}
-
-		if store, err := openStore(); err == nil {
-			defer store.Close()
-			if id, err := store.SaveConversation(conv); err == nil && conv.ID == 0 {
-				conv.ID = id
-			}
-		}
+	msg := fmt.Sprintf("first token %.2fs, %d tokens in %.2fs", t.FirstToken().Seconds(), t.Tokens(), t.Total.Seconds())
+	if rate := t.TokensPerSecond(); rate > 0 {
+		msg += fmt.Sprintf(", %.1f tokens/s", rate)
 	}
+	fmt.Fprintf(os.Stderr, "[%s]\n", msg)
 }
 
-func printHelp() {
-	fmt.Println(`Commands:
-  /quit, /exit, /q  Exit interactive mode
-  /new, /clear      Start a new conversation
-  /model <name>     Switch model
-  /help             Show this help`)
+// notifyDone sends a desktop notification for a finished response if
+// --notify is set or it took longer than the notify_after threshold.
+// Cancelled responses are not announced.
+func notifyDone(elapsed time.Duration, response string, err error) {
+	if !notifyFlag && (cfg.NotifyAfter <= 0 || elapsed < cfg.NotifyAfter) {
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	title := fmt.Sprintf("ask — response ready (%s)", elapsed.Round(time.Second))
+	body := util.Truncate(response, util.MaxPreviewDisplay)
+	if err != nil {
+		title = "ask — response failed"
+		body = util.Truncate(err.Error(), util.MaxPreviewDisplay)
+	}
+	if err := notify.Send(title, body); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+	}
 }
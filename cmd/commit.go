@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const commitSystemPrompt = `You write git commit messages in the Conventional Commits format.
+
+Given a staged diff, reply with only the commit message: no preamble, no
+code fences, no commentary.
+
+- Subject line: "<type>(<optional scope>): <summary>", at most 72 characters,
+  imperative mood, no trailing period. Types: feat, fix, refactor, perf, test,
+  docs, build, ci, chore, style.
+- If the change needs explanation, add a blank line and a body wrapped at 72
+  columns describing what changed and why, not how.
+- Mention breaking changes in a "BREAKING CHANGE:" footer.`
+
+var commitYesFlag bool
+
+var commitCmd = &cobra.Command{
+	Use:   "commit",
+	Short: "Generate a commit message from the staged diff",
+	Long: `Generate a Conventional Commits message from 'git diff --cached'.
+
+In a terminal, the message is shown for confirmation: commit it, edit it in
+$EDITOR first, or quit. When output is piped, the message is printed only:
+
+  ask commit | git commit -F -
+
+Use --yes to commit without confirming.`,
+	Args: cobra.NoArgs,
+	RunE: runCommit,
+}
+
+func init() {
+	rootCmd.AddCommand(commitCmd)
+	commitCmd.Flags().BoolVarP(&commitYesFlag, "yes", "y", false, "Commit without asking for confirmation")
+}
+
+func runCommit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	diff, err := exec.Command("git", "diff", "--cached").Output()
+	if err != nil {
+		return fmt.Errorf("reading staged diff: %w", err)
+	}
+	if strings.TrimSpace(string(diff)) == "" {
+		return fmt.Errorf("no staged changes\n\nStage changes first with: git add <files>")
+	}
+
+	systemPrompt := commitSystemPrompt
+	if len(systemFlags) > 0 {
+		systemPrompt, err = joinSystemPrompts(systemFlags)
+		if err != nil {
+			return fmt.Errorf("resolving system prompt: %w", err)
+		}
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+
+	req := &provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: string(diff)},
+		},
+		Model: getModel(),
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+	writer := stream.NewWriter(os.Stdout, interactive)
+
+	message, err := streamChat(ctx, p, req, writer)
+	if err != nil {
+		return err
+	}
+	message = strings.TrimSpace(message)
+
+	if commitYesFlag {
+		return gitCommit(message)
+	}
+	if !interactive {
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "\n\n[c]ommit, [e]dit, [q]uit? ")
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "c", "commit", "y", "yes":
+			return gitCommit(message)
+		case "e", "edit":
+			edited, err := editText(message+"\n", "COMMIT_EDITMSG-*.txt")
+			if err != nil {
+				return err
+			}
+			message = strings.TrimSpace(edited)
+			if message == "" {
+				return fmt.Errorf("aborting commit due to empty commit message")
+			}
+			fmt.Fprintf(os.Stderr, "\n%s", message)
+		case "q", "quit", "n", "no", "":
+			return nil
+		}
+	}
+}
+
+// gitCommit runs git commit with message, passing through its output.
+func gitCommit(message string) error {
+	cmd := exec.Command("git", "commit", "-m", message)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit: %w", err)
+	}
+	return nil
+}
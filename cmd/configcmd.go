@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/secret"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var configPassphraseFlag bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the config file",
+}
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the API keys in the config file",
+	Long: `Encrypt the api_key of every provider in ~/.config/ask/config.yaml, for
+keys that must live in the file but should not be in plain text in backups.
+Keys are decrypted when used, with nothing else to change.
+
+The encryption key is kept in the OS keyring, or, with --passphrase, derived
+from a passphrase. ask then asks for the passphrase when it needs a key, or
+reads it from ASK_CONFIG_PASSPHRASE.
+
+Keys that are already encrypted are re-encrypted, so this also switches
+between the keyring and a passphrase, or changes the passphrase. Keys in the
+keyring ("keyring") and ${VAR} references are left alone.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigEncrypt,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the config file for mistakes",
+	Long: `Check ~/.config/ask/config.yaml, or the given file, for mistakes that ask
+otherwise ignores or works around: unknown keys such as typos, values of the
+wrong type or not among a setting's choices, unknown providers, malformed
+${VAR} references in API keys, and settings that contradict each other, such
+as a default model the default provider does not offer.
+
+Each problem is listed with its line and column. The exit code is 1 if
+there are any.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store the API keys in the config file in plain text again",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigDecrypt,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configEncryptCmd, configDecryptCmd, configValidateCmd)
+	configEncryptCmd.Flags().BoolVar(&configPassphraseFlag, "passphrase", false, "Derive the encryption key from a passphrase instead of the OS keyring")
+}
+
+func runConfigEncrypt(cmd *cobra.Command, args []string) error {
+	// Decrypt current keys first: asking for an old passphrase after a
+	// new one would be confusing
+	var k secret.Key
+	var haveKey bool
+	changed, err := config.RewriteAPIKeys(func(name, value string) (string, error) {
+		if secret.IsEncrypted(value) {
+			plain, err := cfg.Decrypt(value)
+			if err != nil {
+				return "", err
+			}
+			value = plain
+		} else if !config.IsPlainAPIKey(value) {
+			return value, nil
+		}
+
+		if !haveKey {
+			var err error
+			if k, err = newEncryptionKey(); err != nil {
+				return "", err
+			}
+			haveKey = true
+		}
+		return secret.Encrypt(value, k)
+	})
+	if err != nil {
+		return fmt.Errorf("encrypting API keys: %w", err)
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(os.Stderr, "No API keys in the config file to encrypt.")
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Encrypted the API keys of %s with the %s\n", strings.Join(changed, ", "), encryptionSource(k.Method))
+	return nil
+}
+
+func runConfigDecrypt(cmd *cobra.Command, args []string) error {
+	changed, err := config.RewriteAPIKeys(func(name, value string) (string, error) {
+		if !secret.IsEncrypted(value) {
+			return value, nil
+		}
+		return cfg.Decrypt(value)
+	})
+	if err != nil {
+		return fmt.Errorf("decrypting API keys: %w", err)
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(os.Stderr, "No encrypted API keys in the config file.")
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Decrypted the API keys of %s\n", strings.Join(changed, ", "))
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		path = args[0]
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && len(args) == 0 {
+		fmt.Printf("No config file at %s; ask uses the defaults.\n", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	problems := config.Validate(data, knownProviders())
+	if len(problems) == 0 {
+		fmt.Printf("%s is valid.\n", path)
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Printf("%s: %s\n", path, p)
+	}
+	return fmt.Errorf("found %d problem(s) in %s", len(problems), path)
+}
+
+// knownProviders maps the providers ask supports to the models each
+// lists.
+func knownProviders() config.Known {
+	return config.Known{
+		"openai":    provider.NewOpenAI("").Models(),
+		"anthropic": provider.NewAnthropic("").Models(),
+		"local":     nil, // any .gguf file
+	}
+}
+
+// newEncryptionKey returns a key for ask config encrypt: from a new
+// passphrase with --passphrase, or else the OS keyring.
+func newEncryptionKey() (secret.Key, error) {
+	if !configPassphraseFlag {
+		return secret.KeyringKey(true)
+	}
+	passphrase := os.Getenv(config.PassphraseEnv)
+	if passphrase == "" {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return secret.Key{}, usageErrorf("no terminal to ask for a passphrase; set %s", config.PassphraseEnv)
+		}
+		var err error
+		if passphrase, err = readPassphrase("New passphrase: "); err != nil {
+			return secret.Key{}, err
+		}
+		again, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return secret.Key{}, err
+		}
+		if again != passphrase {
+			return secret.Key{}, errors.New("passphrases do not match")
+		}
+	}
+	return secret.PassphraseKey(passphrase, nil)
+}
+
+// encryptionSource describes where the key of method is kept.
+func encryptionSource(method string) string {
+	if method == secret.MethodPassphrase {
+		return "passphrase"
+	}
+	return "key in the OS keyring"
+}
+
+// askPassphrase asks for the passphrase of encrypted API keys on the
+// terminal, for config.Config.Passphrase.
+func askPassphrase() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("API keys are encrypted with a passphrase; set %s", config.PassphraseEnv)
+	}
+	return readPassphrase("Passphrase for API keys: ")
+}
+
+// readPassphrase reads a passphrase from the terminal without echo.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("reading passphrase: %w", err)
+	}
+	return string(b), nil
+}
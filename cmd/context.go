@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/devaloi/ask/internal/compact"
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// contextPolicyFlag overrides context_policy from the config file.
+var contextPolicyFlag string
+
+func init() {
+	rootCmd.Flags().StringVar(&contextPolicyFlag, "context-policy", "", "When a conversation nears the context window: summarize, drop-oldest, or error")
+}
+
+// contextPolicies lists the valid context policies.
+var contextPolicies = []string{config.ContextPolicySummarize, config.ContextPolicyDrop, config.ContextPolicyError}
+
+// getContextPolicy returns the context policy from --context-policy or
+// the config file. The flag is validated up front, in runChat.
+func getContextPolicy() string {
+	if contextPolicyFlag != "" {
+		return contextPolicyFlag
+	}
+	if cfg.ContextPolicy == "" {
+		return config.ContextPolicySummarize
+	}
+	if !slices.Contains(contextPolicies, cfg.ContextPolicy) {
+		fmt.Fprintf(os.Stderr, "Warning: invalid context_policy %q (want %s), using summarize\n", cfg.ContextPolicy, strings.Join(contextPolicies, ", "))
+		return config.ContextPolicySummarize
+	}
+	return cfg.ContextPolicy
+}
+
+// contextMessages returns a conversation's messages as sent to model.
+//
+// Turns covered by the conversation's latest stored summary are replaced
+// with the summary. When the rest nears the model's context window, the
+// context policy applies: older turns are summarized with a small model
+// (falling back to dropping them if that fails), dropped with a warning,
+// or the request is refused. Either way, a conversation that still does
+// not fit is an error rather than a request the provider would reject.
+//
+// convID is 0 for a conversation not in history; its summaries are not
+// stored.
+func contextMessages(ctx context.Context, p provider.Provider, model string, convID int64, msgs []history.Message) ([]provider.Message, error) {
+	var store history.Store
+	var prev *history.Summary
+	if convID != 0 {
+		var err error
+		if store, err = openStore(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to open history: %v\n", err)
+		} else {
+			defer store.Close()
+			if prev, err = store.LatestSummary(ctx, convID); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// ids parallels out so the end of a summarized range can be recorded
+	var out []provider.Message
+	var ids []int64
+	summaryAt := -1
+	for _, m := range msgs {
+		if prev != nil && m.Role != "system" {
+			if m.ID != 0 && m.ID <= prev.ThroughMessageID {
+				continue
+			}
+			if summaryAt < 0 {
+				summaryAt = len(out)
+				out = append(out, compact.Message(prev.Content))
+				ids = append(ids, 0)
+			}
+		}
+		out = append(out, provider.Message{Role: m.Role, Content: m.Content})
+		ids = append(ids, m.ID)
+	}
+
+	window := provider.ContextWindow(model)
+	start, end := compact.Cut(out, window)
+	if start < end {
+		policy := getContextPolicy()
+		if policy == config.ContextPolicyError {
+			return checkContextFits(out, model, window, "Start a new conversation, or use --context-policy summarize or drop-oldest")
+		}
+
+		summary := ""
+		if policy == config.ContextPolicySummarize {
+			previous := ""
+			if summaryAt >= 0 {
+				previous = prev.Content
+			}
+			var err error
+			summary, err = summarizeMessages(ctx, p, model, previous, out[start:end])
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				fmt.Fprintf(os.Stderr, "Warning: failed to summarize earlier messages: %v\n", err)
+			}
+		}
+
+		if summary != "" {
+			if store != nil && ids[end-1] != 0 {
+				err := store.SaveSummary(ctx, &history.Summary{
+					ConversationID:   convID,
+					ThroughMessageID: ids[end-1],
+					Content:          summary,
+					Model:            getSummaryModel(p, model),
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save summary: %v\n", err)
+				}
+			}
+		} else {
+			warnDropped(out[start:end], model)
+		}
+
+		// A new summary replaces the previous one; dropped turns keep it
+		kept := slices.Clone(out[:start])
+		if summary != "" {
+			if summaryAt >= 0 {
+				kept = slices.Delete(kept, summaryAt, summaryAt+1)
+			}
+			kept = append(kept, compact.Message(summary))
+		}
+		out = append(kept, out[end:]...)
+	}
+
+	return checkContextFits(out, model, window, "Start a new conversation or shorten the prompt")
+}
+
+// summarizeMessages summarizes msgs, continuing from a previous summary
+// if there is one.
+func summarizeMessages(ctx context.Context, p provider.Provider, chatModel, previous string, msgs []provider.Message) (string, error) {
+	fmt.Fprintf(os.Stderr, "Summarizing %d earlier messages to fit the context window...\n", len(msgs))
+	req := &provider.ChatRequest{
+		Model:    getSummaryModel(p, chatModel),
+		Messages: compact.Request(previous, msgs),
+	}
+	summary, err := collectChat(ctx, p, req)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// warnDropped lists messages left out of a request.
+func warnDropped(msgs []provider.Message, model string) {
+	fmt.Fprintf(os.Stderr, "Warning: dropped the %d oldest messages to fit %s's context window:\n", len(msgs), model)
+	for _, m := range msgs {
+		content := strings.Join(strings.Fields(m.Content), " ")
+		fmt.Fprintf(os.Stderr, "  %-9s  %s\n", m.Role, util.Truncate(content, 60))
+	}
+}
+
+// checkContextFits returns msgs, or an error ending in hint if they
+// exceed window.
+func checkContextFits(msgs []provider.Message, model string, window int, hint string) ([]provider.Message, error) {
+	if size := compact.Size(msgs); window > 0 && size > window {
+		return nil, fmt.Errorf("the conversation is about %d tokens, more than %s's %d-token context window\n\n%s", size, model, window, hint)
+	}
+	return msgs, nil
+}
+
+// getSummaryModel returns the model that summarizes conversations for p:
+// summary_model from the config, else the provider's small model, else
+// the chat model itself.
+func getSummaryModel(p provider.Provider, chatModel string) string {
+	if cfg.SummaryModel != "" {
+		return cfg.SummaryModel
+	}
+	if m := provider.SmallModel(p.Name()); m != "" {
+		return m
+	}
+	return chatModel
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/daemon"
+	"github.com/devaloi/ask/internal/version"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// noDaemonEnv, if set, keeps the CLI from using a running daemon.
+const noDaemonEnv = "ASK_NO_DAEMON"
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run askd, a background daemon that makes requests faster",
+	Long: `Run askd in the foreground. While it runs, ask sends chats through it
+over a unix socket in the data directory, reusing its warm HTTPS connections,
+loaded configuration, and open history database, where usage is also
+recorded, rather than setting them up on every invocation. That saves most of the startup and TLS handshake time of
+scripts that call ask many times.
+
+The daemon reads the config file and API keys when it starts; restart it
+after changing them. Set ASK_NO_DAEMON=1 to bypass it for one command.
+
+Examples:
+  ask daemon &
+  ask daemon status
+  ask daemon stop`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd, daemonStopCmd)
+}
+
+var (
+	daemonOnce sync.Once
+	daemonConn *daemon.Client
+)
+
+// daemonClient returns a client of the running daemon, or nil if there
+// is none, it runs a different version of ask, or $ASK_NO_DAEMON is set.
+// --debug-http also bypasses the daemon, to trace requests sent from here.
+func daemonClient() *daemon.Client {
+	daemonOnce.Do(func() {
+		if os.Getenv(noDaemonEnv) != "" || debugHTTPFlag != "" {
+			return
+		}
+		c, err := dialDaemon()
+		if err != nil {
+			if !errors.Is(err, daemon.ErrNotRunning) {
+				slog.Warn("not using the daemon", "error", err)
+			}
+			return
+		}
+		if v := version.Get().Version; c.Status.Version != v {
+			slog.Warn("not using the daemon: it runs a different version of ask", "daemon", c.Status.Version, "cli", v)
+			return
+		}
+		slog.Debug("using the daemon", "pid", c.Status.PID)
+		daemonConn = c
+	})
+	return daemonConn
+}
+
+// dialDaemon connects to the daemon's socket in the data directory.
+func dialDaemon() (*daemon.Client, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+	return daemon.Dial(context.Background(), daemon.SocketPath(dataDir))
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return err
+	}
+
+	// The daemon sends requests itself, so its providers are plain: the
+	// CLI that asked checks budgets and records usage, through the
+	// daemon's store
+	var providers []provider.Provider
+	var names []string
+	for _, name := range []string{"openai", "anthropic"} {
+		if p, err := configuredProvider(name); err == nil {
+			providers = append(providers, p)
+			names = append(names, name)
+		}
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("no provider has an API key\n\nSee: ask auth set --help")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	defer store.Close()
+
+	socket := daemon.SocketPath(dataDir)
+	listener, err := daemon.Listen(socket)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", socket, err)
+	}
+	handler := daemon.NewServer(version.Get().Version, providers, store)
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-handler.Shutdown:
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "askd listening on %s for %s (pid %d)\n", socket, strings.Join(names, ", "), os.Getpid())
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+	return nil
+}
+
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	c, err := dialDaemon()
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return errors.New("the daemon is not running\n\nStart it with: ask daemon &")
+	}
+	if err != nil {
+		return err
+	}
+	s := c.Status
+	fmt.Printf("Running:   pid %d, up %s\n", s.PID, time.Since(s.Started).Round(time.Second))
+	fmt.Printf("Version:   %s\n", s.Version)
+	fmt.Printf("Providers: %s\n", strings.Join(s.Providers, ", "))
+	if v := version.Get().Version; s.Version != v {
+		fmt.Printf("\nThis ask is %s, so it does not use the daemon. Restart it with:\n  ask daemon stop && ask daemon &\n", v)
+	}
+	return nil
+}
+
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	c, err := dialDaemon()
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return errors.New("the daemon is not running")
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		return fmt.Errorf("stopping the daemon: %w", err)
+	}
+	fmt.Printf("Stopped the daemon (pid %d)\n", c.Status.PID)
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/devaloi/ask/internal/httpdump"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// debugHTTPFlag traces provider HTTP traffic: "-" to stderr, or else to
+// the named file.
+var debugHTTPFlag string
+
+// debugHTTPOut receives the trace once setupDebugHTTP has run;
+// closeDebugHTTP closes it.
+var (
+	debugHTTPOut   io.Writer
+	closeDebugHTTP = func() error { return nil }
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&debugHTTPFlag, "debug-http", "", "Trace provider requests and raw responses, with API keys redacted, to stderr or `file`")
+	rootCmd.PersistentFlags().Lookup("debug-http").NoOptDefVal = "-"
+}
+
+// setupDebugHTTP opens the destination of --debug-http, if it is set.
+func setupDebugHTTP() error {
+	switch debugHTTPFlag {
+	case "":
+		return nil
+	case "-":
+		debugHTTPOut = os.Stderr
+		return nil
+	}
+	// Traces hold prompts and responses; keep them private
+	f, err := os.OpenFile(debugHTTPFlag, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening HTTP trace file: %w", err)
+	}
+	debugHTTPOut = f
+	closeDebugHTTP = f.Close
+	return nil
+}
+
+// traceHTTP makes p write its HTTP traffic to the --debug-http
+// destination, replacing apiKey wherever it appears.
+func traceHTTP(p provider.Provider, apiKey string) {
+	if debugHTTPOut == nil {
+		return
+	}
+	t, ok := provider.As[provider.Transporter](p)
+	if !ok {
+		return
+	}
+	t.SetTransport(&httpdump.Transport{Out: debugHTTPOut, Secrets: []string{apiKey}})
+}
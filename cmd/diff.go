@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/codeblock"
+	"github.com/devaloi/ask/internal/diff"
+	"github.com/devaloi/ask/internal/files"
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const diffSystemPrompt = `You edit files as instructed.
+
+Reply with the complete updated file in a single fenced code block, and
+nothing else: no explanation before or after it. Change only what the
+instruction asks for; keep all other code, comments, and formatting exactly
+as they are.`
+
+var diffFileFlag string
+
+var diffCmd = &cobra.Command{
+	Use:   "diff -f <file> <instruction>",
+	Short: "Ask for changes to a file as a unified diff",
+	Long: `Ask the model to change a file and print the change as a unified diff.
+
+The model rewrites the file; ask compares the result with the original and
+prints a diff that 'git apply' and 'patch -p1' accept. The file itself is
+not modified.
+
+Examples:
+  ask diff -f handler.go "add input validation"
+  ask diff -f handler.go "add input validation" | git apply`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().StringVarP(&diffFileFlag, "file", "f", "", "File to change (required)")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if diffFileFlag == "" {
+		return usageErrorf("no file given\n\nUsage: ask diff -f <file> <instruction>")
+	}
+
+	original, err := os.ReadFile(diffFileFlag)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	if max := cfg.MaxIncludeBytes; max > 0 && int64(len(original)) > max {
+		return fmt.Errorf("%s is %d bytes, exceeding the %d byte limit\n\nRaise max_include_bytes in ~/.config/ask/config.yaml", diffFileFlag, len(original), max)
+	}
+
+	updated, err := rewriteFile(ctx, diffFileFlag, string(original), strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	if updated == string(original) {
+		fmt.Fprintln(os.Stderr, "No changes.")
+		return nil
+	}
+
+	name := diffPath(diffFileFlag)
+	fmt.Print(diff.Unified("a/"+name, "b/"+name, string(original), updated, diff.DefaultContext))
+	return nil
+}
+
+// rewriteFile asks the model to apply instruction to the file at path,
+// whose current contents are original, and returns the updated contents.
+func rewriteFile(ctx context.Context, path, original, instruction string) (string, error) {
+	systemPrompt := diffSystemPrompt
+	if len(systemFlags) > 0 {
+		var err error
+		systemPrompt, err = joinSystemPrompts(systemFlags)
+		if err != nil {
+			return "", fmt.Errorf("resolving system prompt: %w", err)
+		}
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return "", fmt.Errorf("creating provider: %w", err)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return "", err
+	}
+
+	prompt := files.Fence(path, original) + "\n\nInstruction: " + instruction
+	req := &provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Model: getModel(),
+	}
+
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		fmt.Fprintf(os.Stderr, "Rewriting %s with %s...\n", path, req.Model)
+	}
+
+	// Collect silently: only the code in the response is used
+	response, err := streamChat(ctx, p, req, stream.NewWriter(io.Discard, true))
+	if err != nil {
+		return "", err
+	}
+	logExchange(p.Name(), req.Model, prompt, response)
+
+	updated := codeblock.Extract(response)
+	if updated == "" {
+		return "", fmt.Errorf("model returned no code")
+	}
+	if !strings.HasSuffix(original, "\n") && original != "" {
+		updated = strings.TrimSuffix(updated, "\n")
+	}
+	return updated, nil
+}
+
+// diffPath returns the name to use for path in diff headers: relative to
+// the root of its git repository if it is in one, as git diff does, or
+// else relative to the current directory.
+func diffPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	// git reports the repository root with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	out, err := exec.Command("git", "-C", filepath.Dir(abs), "rev-parse", "--show-toplevel").Output()
+	if err == nil {
+		root := strings.TrimSpace(string(out))
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
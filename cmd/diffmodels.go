@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/diff"
+	"github.com/devaloi/ask/internal/theme"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	diffModelsFlag     []string
+	sideBySideFlag     bool
+	diffModelsMaxToken int
+)
+
+var diffModelsCmd = &cobra.Command{
+	Use:   "diff-models -m <model> -m <model> <prompt>",
+	Short: "Compare two models' answers to a prompt",
+	Long: `Send the same prompt to two models and print a word-level diff of their
+answers: words only the first model wrote are shown in red (or [-like
+this-]), and words only the second wrote in green (or {+like this+}).
+--side-by-side prints the two answers in columns instead.
+
+Each model is sent to the provider that offers it, or the default
+provider. Both requests are sent at the same time.
+
+Examples:
+  ask diff-models -m gpt-4o -m claude-sonnet-4-20250514 "Explain Go interfaces"
+  ask diff-models -m gpt-4o-mini -m gpt-4o --side-by-side "Name three sorting algorithms"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runDiffModels,
+}
+
+func init() {
+	rootCmd.AddCommand(diffModelsCmd)
+	// Shadows the global --model to take two models
+	diffModelsCmd.Flags().StringArrayVarP(&diffModelsFlag, "model", "m", nil, "Model to compare (give exactly two)")
+	diffModelsCmd.Flags().BoolVar(&sideBySideFlag, "side-by-side", false, "Print the answers in two columns instead of a diff")
+	diffModelsCmd.Flags().IntVar(&diffModelsMaxToken, "max-tokens", 0, "Limit each answer to this many tokens")
+}
+
+// modelAnswer is one model's answer to the compared prompt.
+type modelAnswer struct {
+	model, provider string
+	response        string
+	elapsed         time.Duration
+	err             error
+}
+
+func runDiffModels(cmd *cobra.Command, args []string) error {
+	if len(diffModelsFlag) != 2 {
+		return usageErrorf("give exactly two models to compare, with -m <model> -m <model>")
+	}
+	if diffModelsMaxToken < 0 {
+		return usageErrorf("--max-tokens must not be negative")
+	}
+	prompt := strings.TrimSpace(strings.Join(args, " "))
+	if prompt == "" {
+		return usageErrorf("prompt is empty")
+	}
+	systemPrompt, err := buildSystemPrompt()
+	if err != nil {
+		return fmt.Errorf("resolving system prompt: %w", err)
+	}
+	var messages []provider.Message
+	if systemPrompt != "" {
+		messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, provider.Message{Role: "user", Content: prompt})
+
+	providers, err := configuredProviders()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	answers := make([]modelAnswer, len(diffModelsFlag))
+	var wg sync.WaitGroup
+	for i, model := range diffModelsFlag {
+		p := providerForModel(providers, model)
+		answers[i] = modelAnswer{model: model, provider: p.Name()}
+		wg.Go(func() {
+			start := time.Now()
+			a := &answers[i]
+			a.response, a.err = collectChat(ctx, p, &provider.ChatRequest{
+				Model:     model,
+				Messages:  messages,
+				MaxTokens: diffModelsMaxToken,
+			})
+			a.elapsed = time.Since(start)
+		})
+	}
+	fmt.Fprintf(os.Stderr, "Asking %s and %s...\n", diffModelsFlag[0], diffModelsFlag[1])
+	wg.Wait()
+	for _, a := range answers {
+		if a.err != nil {
+			return fmt.Errorf("%s: %w", a.model, a.err)
+		}
+	}
+
+	old, new := answers[0], answers[1]
+	if sideBySideFlag {
+		width := 120
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			width = w
+		}
+		fmt.Print(diff.SideBySide(old.label(), new.label(), width))
+		fmt.Println(strings.Repeat("─", width))
+		fmt.Print(diff.SideBySide(old.response, new.response, width))
+		return nil
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n\n", old.label(), new.label())
+	fmt.Println(renderWordDiff(diff.Words(strings.TrimSpace(old.response), strings.TrimSpace(new.response)), theme.ColorEnabled(os.Stdout)))
+	return nil
+}
+
+// renderWordDiff prints removed words in red and added ones in green, or
+// without color between git's --word-diff=plain markers, [-...-] and
+// {+...+}.
+func renderWordDiff(chunks []diff.Chunk, color bool) string {
+	removed, _ := theme.Parse("red")
+	added, _ := theme.Parse("green")
+	var out strings.Builder
+	for _, c := range chunks {
+		switch {
+		case c.Op == diff.Same:
+			out.WriteString(c.Text)
+		case color && c.Op == diff.Removed:
+			out.WriteString(removed.Render(c.Text))
+		case color:
+			out.WriteString(added.Render(c.Text))
+		case c.Op == diff.Removed:
+			out.WriteString("[-" + c.Text + "-]")
+		default:
+			out.WriteString("{+" + c.Text + "+}")
+		}
+	}
+	return out.String()
+}
+
+// label names the model, its provider, and how long it took.
+func (a modelAnswer) label() string {
+	return fmt.Sprintf("%s (%s, %.1fs)", a.model, a.provider, a.elapsed.Seconds())
+}
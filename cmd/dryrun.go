@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/devaloi/ask/internal/httpdump"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// printDryRun writes the HTTP request that p would send for req to w,
+// with credentials redacted, instead of sending it.
+func printDryRun(ctx context.Context, w io.Writer, p provider.Provider, req *provider.ChatRequest) error {
+	httpReq, err := p.BuildRequest(ctx, req)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+
+	var body []byte
+	if httpReq.Body != nil {
+		body, err = io.ReadAll(httpReq.Body)
+		if err != nil {
+			return fmt.Errorf("reading request body: %w", err)
+		}
+	}
+
+	fmt.Fprintf(w, "%s %s\n", httpReq.Method, httpReq.URL)
+	for _, name := range sortedHeaderNames(httpReq.Header) {
+		for _, value := range httpReq.Header.Values(name) {
+			if httpdump.IsSensitive(name) {
+				value = httpdump.RedactHeader(value)
+			}
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintln(w)
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		// Not JSON; print as-is
+		fmt.Fprintln(w, string(body))
+		return nil
+	}
+	fmt.Fprintln(w, pretty.String())
+
+	return nil
+}
+
+// sortedHeaderNames returns the canonical header names in h in sorted order.
+func sortedHeaderNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,812 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/devaloi/ask/internal/budget"
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/upgrade"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// askResult is what a run of ask wrote, and the error it ended with.
+type askResult struct {
+	stdout, stderr string
+	err            error
+}
+
+// runAsk runs ask with args, as from the command line, in a fresh config
+// directory, with m as the provider and store as the history.
+func runAsk(t *testing.T, m *provider.Mock, store history.Store, stdin string, args ...string) askResult {
+	t.Helper()
+	return withAsk(t, m, store, stdin, func() error {
+		resetFlags(rootCmd)
+		commandStarted = false
+		rootCmd.SetArgs(args)
+		return Execute()
+	})
+}
+
+// withAsk runs fn with stdin as standard input, capturing standard
+// output and error, with m and store set up as for runAsk.
+func withAsk(t *testing.T, m *provider.Mock, store history.Store, stdin string, fn func() error) askResult {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv(config.ConfigEnv, "")
+	t.Setenv(config.DataDirEnv, dir)
+	t.Setenv("ASK_PROVIDER", provider.MockName)
+	t.Setenv("ASK_MODEL", "mock-1")
+	t.Setenv("NO_COLOR", "1")
+
+	provider.SetMock(m)
+	t.Cleanup(func() { provider.SetMock(nil) })
+	prevStore := openStore
+	openStore = func() (history.Store, error) { return store, nil }
+	t.Cleanup(func() { openStore = prevStore })
+
+	in := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(in, []byte(stdin), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files := make([]*os.File, 3)
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	prevStdin, prevStdout, prevStderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	err := fn()
+	os.Stdin, os.Stdout, os.Stderr = prevStdin, prevStdout, prevStderr
+
+	stdout, _ := os.ReadFile(files[1].Name())
+	stderr, _ := os.ReadFile(files[2].Name())
+	return askResult{stdout: string(stdout), stderr: string(stderr), err: err}
+}
+
+// resetFlags returns the flags of c and its subcommands to their
+// defaults, since flag values outlive a run.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			_ = s.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.PersistentFlags().VisitAll(reset)
+	c.Flags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func TestOneShot(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		stdin     string
+		wantSaved bool
+	}{
+		{"piped output is not saved", []string{"What is the capital of France?"}, "", false},
+		{"--save", []string{"--save", "What is the capital of France?"}, "", true},
+		{"piped input", []string{"--save", "Which capital is this?"}, "France", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := provider.NewMock(provider.MockResponse{Tokens: []string{"Par", "is"}})
+			store := history.NewMemoryStore()
+			res := runAsk(t, m, store, tt.stdin, tt.args...)
+			if res.err != nil {
+				t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+			}
+			if strings.TrimSpace(res.stdout) != "Paris" {
+				t.Errorf("stdout = %q, want the response", res.stdout)
+			}
+
+			reqs := m.Requests()
+			if len(reqs) != 1 {
+				t.Fatalf("got %d requests, want 1", len(reqs))
+			}
+			prompt := reqs[0].Messages[len(reqs[0].Messages)-1].Content
+			if !strings.Contains(prompt, tt.args[len(tt.args)-1]) || !strings.Contains(prompt, tt.stdin) {
+				t.Errorf("prompt = %q, want the argument and input", prompt)
+			}
+
+			convs, err := store.ListConversations(t.Context(), 10, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved := len(convs) == 1; saved != tt.wantSaved {
+				t.Fatalf("saved %d conversations, want saved = %v", len(convs), tt.wantSaved)
+			}
+			if !tt.wantSaved {
+				return
+			}
+			conv, err := store.GetConversation(t.Context(), convs[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(conv.Messages); n != 2 || conv.Messages[1].Content != "Paris" || conv.Provider != provider.MockName {
+				t.Errorf("saved conversation = %+v", conv)
+			}
+		})
+	}
+}
+
+func TestTitleAndTags(t *testing.T) {
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"Paris"}})
+	store := history.NewMemoryStore()
+	res := runAsk(t, m, store, "", "--title", "Geography quiz", "--tag", "quiz,geo", "--tag", "quiz", "What is the capital of France?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	// Saved though output is piped
+	convs, err := store.ListConversations(t.Context(), 10, "geo")
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("ListConversations() = %+v, %v; want the tagged conversation", convs, err)
+	}
+	if c := convs[0]; c.Title != "Geography quiz" || !slices.Equal(c.Tags, []string{"geo", "quiz"}) {
+		t.Errorf("saved conversation = %+v", c)
+	}
+
+	for _, args := range [][]string{
+		{"--tag", "x", "-c", strconv.FormatInt(convs[0].ID, 10), "More?"},
+		{"--title", "x", "--no-save", "More?"},
+		{"--tag", " ", "More?"},
+	} {
+		res := runAsk(t, nil, store, "", args...)
+		if ExitCode(res.err) != exitUsage {
+			t.Errorf("ask %v: exit code = %d (%v), want %d", args, ExitCode(res.err), res.err, exitUsage)
+		}
+	}
+}
+
+func TestInputPlaceholder(t *testing.T) {
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res := runAsk(t, m, history.NewMemoryStore(), "use {input} here\n", "Given {stdin}, and {input}?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	reqs := m.Requests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	msgs := reqs[0].Messages
+	want := "Given use {input} here, and use {input} here?"
+	if got := msgs[len(msgs)-1].Content; got != want {
+		t.Errorf("prompt = %q, want %q", got, want)
+	}
+}
+
+func TestContinue(t *testing.T) {
+	store := history.NewMemoryStore()
+	earlier := &history.Conversation{Model: "mock-2", Provider: provider.MockName, Messages: []history.Message{
+		{Role: "user", Content: "What is the capital of France?"},
+		{Role: "assistant", Content: "Paris"},
+	}}
+	if _, err := store.SaveConversation(t.Context(), earlier); err != nil {
+		t.Fatal(err)
+	}
+
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"Rome"}})
+	res := runAsk(t, m, store, "", "--save", "--continue="+strconv.FormatInt(earlier.ID, 10), "And of Italy?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	reqs := m.Requests()
+	if len(reqs) != 1 || len(reqs[0].Messages) != 3 || reqs[0].Messages[1].Content != "Paris" {
+		t.Fatalf("requests = %+v, want the earlier exchange sent", reqs)
+	}
+	if reqs[0].Model != "mock-2" {
+		t.Errorf("model = %q, want the conversation's mock-2", reqs[0].Model)
+	}
+	conv, err := store.GetConversation(t.Context(), earlier.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conv.Messages) != 4 || conv.Messages[3].Content != "Rome" {
+		t.Errorf("messages = %+v, want the exchange appended", conv.Messages)
+	}
+
+	// -m still chooses
+	m = provider.NewMock(provider.MockResponse{Tokens: []string{"Madrid"}})
+	res = runAsk(t, m, store, "", "-m", "mock-3", "-c", strconv.FormatInt(earlier.ID, 10), "And of Spain?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	if reqs := m.Requests(); len(reqs) != 1 || reqs[0].Model != "mock-3" {
+		t.Errorf("requests = %+v, want mock-3 as -m says", reqs)
+	}
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		err      error
+		wantCode int
+	}{
+		{"rate limited", []string{"hi"}, &provider.Error{Provider: "openai", Kind: provider.ErrRateLimited, Status: 429}, exitRateLimited},
+		{"auth", []string{"hi"}, &provider.Error{Provider: "openai", Kind: provider.ErrAuth, Status: 401}, exitAuth},
+		{"unknown flag", []string{"--no-such-flag", "hi"}, nil, exitUsage},
+		{"negative --rag-k", []string{"--rag", "docs", "--rag-k", "-1", "hi"}, nil, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := provider.NewMock(provider.MockResponse{Err: tt.err})
+			res := runAsk(t, m, history.NewMemoryStore(), "", tt.args...)
+			if code := ExitCode(res.err); code != tt.wantCode {
+				t.Errorf("exit code = %d (%v), want %d", code, res.err, tt.wantCode)
+			}
+			if tt.err != nil && !errors.Is(res.err, tt.err) {
+				t.Errorf("error = %v, want %v", res.err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	limited := &provider.Error{Provider: "openai", Kind: provider.ErrRateLimited, Status: 429, Retryable: true}
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantCalls int
+		wantOut   string
+	}{
+		{"retried", map[string]string{"ASK_RETRY_BACKOFF": "1ms"}, 2, "recovered"},
+		{"retries off", map[string]string{"ASK_RETRY_MAX_ATTEMPTS": "1"}, 1, ""},
+		{"status not listed", map[string]string{"ASK_RETRY_BACKOFF": "1ms", "ASK_RETRY_STATUS_CODES": "503"}, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			m := provider.NewMock(provider.MockResponse{Err: limited}, provider.MockResponse{Tokens: []string{"recovered"}})
+			res := runAsk(t, m, history.NewMemoryStore(), "", "hi")
+			if n := len(m.Requests()); n != tt.wantCalls {
+				t.Errorf("sent %d requests, want %d", n, tt.wantCalls)
+			}
+			if strings.TrimSpace(res.stdout) != tt.wantOut {
+				t.Errorf("stdout = %q, want %q (%v)", res.stdout, tt.wantOut, res.err)
+			}
+			if tt.wantOut != "" && !strings.Contains(res.stderr, "retrying in 1ms") {
+				t.Errorf("stderr = %q, want a note of the retry", res.stderr)
+			}
+		})
+	}
+}
+
+func TestFailedPartway(t *testing.T) {
+	overloaded := &provider.Error{Provider: "anthropic", Kind: provider.ErrServer, Code: "overloaded_error", Message: "Overloaded"}
+	for _, args := range [][]string{
+		{"--save", "Tell me a story"},
+		{"--save", "--stream-json", "Tell me a story"},
+	} {
+		m := provider.NewMock(provider.MockResponse{Tokens: []string{"Once ", "upon"}, Err: overloaded})
+		store := history.NewMemoryStore()
+		res := runAsk(t, m, store, "", args...)
+		if !errors.Is(res.err, overloaded) {
+			t.Fatalf("ask %v: err = %v, want the stream error", args, res.err)
+		}
+		if !strings.Contains(res.stderr, "partial response is saved") {
+			t.Errorf("ask %v: stderr = %q, want a note of the saved response", args, res.stderr)
+		}
+
+		convs, err := store.ListConversations(t.Context(), 10, "")
+		if err != nil || len(convs) != 1 {
+			t.Fatalf("ask %v: ListConversations() = %+v, %v; want one conversation", args, convs, err)
+		}
+		conv, err := store.GetConversation(t.Context(), convs[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(conv.Messages); n != 2 || conv.Messages[1].Content != "Once upon" || !conv.Messages[1].Partial {
+			t.Errorf("ask %v: saved messages = %+v, want the partial response", args, conv.Messages)
+		}
+	}
+}
+
+func TestAgentFailedPartway(t *testing.T) {
+	// Completed steps are saved even if a later reply fails, with or
+	// without some of it received
+	limited := &provider.Error{Provider: "openai", Kind: provider.ErrRateLimited, Message: "Rate limit reached"}
+	listDir := "Looking.\n```tool\n{\"tool\": \"list_dir\", \"args\": {\"path\": \".\"}}\n```"
+	tests := []struct {
+		name      string
+		second    []string
+		wantLast  string
+		wantCount int
+	}{
+		{"nothing received", nil, listDir, 3},
+		{"partial reply", []string{"The answer"}, "The answer", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := provider.NewMock(
+				provider.MockResponse{Tokens: []string{listDir}},
+				provider.MockResponse{Tokens: tt.second, Err: limited},
+			)
+			store := history.NewMemoryStore()
+			res := runAsk(t, m, store, "", "agent", "list the files")
+			if !errors.Is(res.err, limited) {
+				t.Fatalf("err = %v, want the second reply's error\n%s", res.err, res.stderr)
+			}
+
+			convs, err := store.ListConversations(t.Context(), 10, "")
+			if err != nil || len(convs) != 1 {
+				t.Fatalf("ListConversations() = %+v, %v; want one conversation", convs, err)
+			}
+			conv, err := store.GetConversation(t.Context(), convs[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs := conv.Messages
+			if len(msgs) != tt.wantCount {
+				t.Fatalf("saved %d messages, want %d: %+v", len(msgs), tt.wantCount, msgs)
+			}
+			last := msgs[len(msgs)-1]
+			if last.Role != "assistant" || last.Content != tt.wantLast || last.Partial != (tt.second != nil) {
+				t.Errorf("last saved message = %+v, want %q, partial %v", last, tt.wantLast, tt.second != nil)
+			}
+		})
+	}
+}
+
+func TestTruncatedBatch(t *testing.T) {
+	truncated := &provider.Error{Provider: "openai", Kind: provider.ErrTruncated}
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"func main() {"}, Err: truncated})
+	res := runAsk(t, m, history.NewMemoryStore(), `"Write a program"`+"\n", "batch", "-")
+	if res.err != nil {
+		t.Fatalf("ask batch failed: %v\n%s", res.err, res.stderr)
+	}
+	if !strings.Contains(res.stdout, `"response":"func main() {"`) || strings.Contains(res.stdout, `"error"`) {
+		t.Errorf("stdout = %q, want the response as a result", res.stdout)
+	}
+	if !strings.Contains(res.stderr, "Warning: the response was cut short") {
+		t.Errorf("stderr = %q, want a warning", res.stderr)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	truncated := &provider.Error{Provider: "openai", Kind: provider.ErrTruncated}
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"func main() {"}, Err: truncated})
+	res := runAsk(t, m, history.NewMemoryStore(), "", "Write a program")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	if strings.TrimSpace(res.stdout) != "func main() {" {
+		t.Errorf("stdout = %q, want the response", res.stdout)
+	}
+	if !strings.Contains(res.stderr, "Warning: the response was cut short") {
+		t.Errorf("stderr = %q, want a warning", res.stderr)
+	}
+
+	m = provider.NewMock(
+		provider.MockResponse{Tokens: []string{"func main() {\n"}, Err: truncated},
+		provider.MockResponse{Tokens: []string{"}"}},
+	)
+	res = runAsk(t, m, history.NewMemoryStore(), "", "--auto-continue", "Write a program")
+	if res.err != nil {
+		t.Fatalf("ask --auto-continue failed: %v\n%s", res.err, res.stderr)
+	}
+	if strings.TrimSpace(res.stdout) != "func main() {\n}" || strings.Contains(res.stderr, "Warning") {
+		t.Errorf("stdout = %q, stderr = %q; want the whole response without a warning", res.stdout, res.stderr)
+	}
+	if n := len(m.Requests()); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestInteractive(t *testing.T) {
+	m := provider.NewMock(
+		provider.MockResponse{Tokens: []string{"A ", "language."}},
+		provider.MockResponse{Tokens: []string{"In 2009."}},
+	)
+	store := history.NewMemoryStore()
+	res := withAsk(t, m, store, "What is Go?\n/help\nWhen was it released?\n/usage\n/quit\nignored\n", func() error {
+		resetFlags(rootCmd)
+		initConfig()
+		return runInteractive(nil)
+	})
+	if res.err != nil {
+		t.Fatalf("runInteractive failed: %v\n%s", res.err, res.stderr)
+	}
+	if !strings.Contains(res.stdout, "A language.\n\n") || !strings.Contains(res.stdout, "In 2009.") {
+		t.Errorf("stdout = %q, want both responses, set apart", res.stdout)
+	}
+	if strings.Contains(res.stdout, "─") || strings.Contains(res.stdout, "\x1b[") {
+		t.Errorf("stdout = %q, want no rules or styles when redirected", res.stdout)
+	}
+	usage := "Session usage: 2 requests, "
+	if !strings.Contains(res.stdout, usage) || !strings.Contains(res.stderr, usage) || !strings.Contains(res.stdout, "not counting mock-1") {
+		t.Errorf("stdout = %q\nstderr = %q\nwant the session's usage in both", res.stdout, res.stderr)
+	}
+
+	reqs := m.Requests()
+	if len(reqs) != 2 || len(reqs[1].Messages) != 3 {
+		t.Fatalf("requests = %+v, want the second to follow up the first", reqs)
+	}
+	convs, err := store.ListConversations(t.Context(), 10, "")
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("ListConversations() = %+v, %v; want one conversation", convs, err)
+	}
+	conv, err := store.GetConversation(t.Context(), convs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Title != "What is Go?" || len(conv.Messages) != 4 {
+		t.Errorf("saved conversation = %+v", conv)
+	}
+}
+
+func TestInteractiveTitleAndTags(t *testing.T) {
+	m := provider.NewMock(
+		provider.MockResponse{Tokens: []string{"Paris"}},
+		provider.MockResponse{Tokens: []string{"Rome"}},
+	)
+	store := history.NewMemoryStore()
+	res := withAsk(t, m, store, "Capital of France?\n/new\nCapital of Italy?\n/quit\n", func() error {
+		resetFlags(rootCmd)
+		initConfig()
+		titleFlag, tagFlags = "Quiz", []string{"geo"}
+		return runInteractive(nil)
+	})
+	if res.err != nil {
+		t.Fatalf("runInteractive failed: %v\n%s", res.err, res.stderr)
+	}
+	// --title and --tag name only the first conversation
+	convs, err := store.ListConversations(t.Context(), 10, "")
+	if err != nil || len(convs) != 2 {
+		t.Fatalf("ListConversations() = %+v, %v; want two conversations", convs, err)
+	}
+	wantTags := map[string][]string{"Quiz": {"geo"}, "Capital of Italy?": nil}
+	for _, c := range convs {
+		if tags, ok := wantTags[c.Title]; !ok || !slices.Equal(c.Tags, tags) {
+			t.Errorf("saved conversation = %+v", c)
+		}
+	}
+}
+
+func TestInteractiveError(t *testing.T) {
+	m := provider.NewMock(provider.MockResponse{
+		Tokens: []string{"Partly"},
+		Err:    &provider.Error{Provider: "openai", Kind: provider.ErrServer, Status: 500, Message: "overloaded"},
+	})
+	res := withAsk(t, m, history.NewMemoryStore(), "What is Go?\n/quit\n", func() error {
+		resetFlags(rootCmd)
+		initConfig()
+		return runInteractive(nil)
+	})
+	if res.err != nil {
+		t.Fatalf("runInteractive failed: %v\n%s", res.err, res.stderr)
+	}
+	// Redirected output holds the response, and errors go to stderr
+	if !strings.Contains(res.stdout, "Partly") || strings.Contains(res.stdout, "Error:") {
+		t.Errorf("stdout = %q, want the partial response alone", res.stdout)
+	}
+	if !strings.Contains(res.stderr, "Error: ") || !strings.Contains(res.stderr, "overloaded") {
+		t.Errorf("stderr = %q, want the error", res.stderr)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	key := "sk-ant-" + strings.Repeat("x1Y2", 8)
+
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res := runAsk(t, m, history.NewMemoryStore(), "", "--redact", "mask", "-s", "Deploy with "+key, "How do I deploy?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	sent := m.Requests()[0].Messages[0].Content
+	if strings.Contains(sent, key) || !strings.Contains(sent, "[REDACTED Anthropic API key]") {
+		t.Errorf("system prompt sent = %q, want the key masked", sent)
+	}
+	if !strings.Contains(res.stderr, "Masked 1 secret(s)") {
+		t.Errorf("stderr = %q, want a note of the masked secret", res.stderr)
+	}
+
+	m = provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res = runAsk(t, m, history.NewMemoryStore(), "", "--redact", "block", "-s", "Deploy with "+key, "How do I deploy?")
+	if res.err == nil || !strings.Contains(res.err.Error(), "look like secrets") || len(m.Requests()) != 0 {
+		t.Errorf("err = %v after %d requests; want the request refused", res.err, len(m.Requests()))
+	}
+}
+
+func TestUnpricedBudget(t *testing.T) {
+	for _, policy := range []string{"warn", "refuse"} {
+		t.Run(policy, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte("budget:\n  daily: 1\n  policy: "+policy+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+			res := withAsk(t, m, history.NewMemoryStore(), "", func() error {
+				t.Setenv(config.ConfigEnv, path)
+				resetFlags(rootCmd)
+				commandStarted = false
+				rootCmd.SetArgs([]string{"hi"})
+				return Execute()
+			})
+			switch policy {
+			case "warn":
+				if res.err != nil || !strings.Contains(res.stderr, "mock-1 has no known price") {
+					t.Errorf("err = %v, stderr = %q; want the request sent with a warning", res.err, res.stderr)
+				}
+			case "refuse":
+				if !errors.Is(res.err, budget.ErrUnpriced) || ExitCode(res.err) != exitBudget || len(m.Requests()) != 0 {
+					t.Errorf("err = %v (exit code %d) after %d requests; want the request refused", res.err, ExitCode(res.err), len(m.Requests()))
+				}
+			}
+		})
+	}
+}
+
+func TestModelCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantSent bool
+		wantOut  string
+	}{
+		{"known", []string{"-m", "mock-1", "hi"}, true, ""},
+		{"typo", []string{"-m", "mokc-1", "hi"}, true, `Warning: unknown mock model "mokc-1"; did you mean "mock-1"?`},
+		{"unknown", []string{"-m", "other", "hi"}, true, ""},
+		{"strict typo", []string{"--strict-model", "-m", "mokc-1", "hi"}, false, `did you mean "mock-1"?`},
+		{"strict unknown", []string{"--strict-model", "-m", "other", "hi"}, false, "models are mock-1, mock-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := provider.NewMock()
+			m.ModelNames = []string{"mock-1", "mock-2"}
+			res := runAsk(t, m, history.NewMemoryStore(), "", tt.args...)
+			if sent := len(m.Requests()) == 1; sent != tt.wantSent {
+				t.Errorf("sent = %v (%v), want %v", sent, res.err, tt.wantSent)
+			}
+			if !tt.wantSent && ExitCode(res.err) != exitUsage {
+				t.Errorf("exit code = %d (%v), want %d", ExitCode(res.err), res.err, exitUsage)
+			}
+			output := res.stderr
+			if res.err != nil {
+				output += res.err.Error()
+			}
+			if tt.wantOut == "" && strings.Contains(output, "unknown") {
+				t.Errorf("output = %q, want no warning", output)
+			}
+			if !strings.Contains(output, tt.wantOut) {
+				t.Errorf("output = %q, want %q", output, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestModels(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	res := runAsk(t, nil, history.NewMemoryStore(), "", "models", "--provider", "anthropic", "--min-context", "150k", "--json")
+	if res.err != nil {
+		t.Fatalf("ask models failed: %v\n%s", res.err, res.stderr)
+	}
+	var rows []modelRow
+	if err := json.Unmarshal([]byte(res.stdout), &rows); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, res.stdout)
+	}
+	if len(rows) == 0 {
+		t.Fatal("no models listed")
+	}
+	for _, row := range rows {
+		if row.Provider != "anthropic" || row.ContextWindow < 150000 || !row.Configured {
+			t.Errorf("listed %+v", row)
+		}
+	}
+
+	res = runAsk(t, nil, history.NewMemoryStore(), "", "models", "--min-context", "lots")
+	if ExitCode(res.err) != exitUsage {
+		t.Errorf("exit code = %d (%v), want %d", ExitCode(res.err), res.err, exitUsage)
+	}
+
+	// Models described in the config file
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	conf := "models:\n  gpt-4o:\n    context_window: 64000\n  my-model:\n    provider: openai\n    input_price: 1\n    output_price: 2\n    tools: true\n"
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.SetModels(nil) })
+	res = withAsk(t, nil, history.NewMemoryStore(), "", func() error {
+		t.Setenv(config.ConfigEnv, path)
+		resetFlags(rootCmd)
+		commandStarted = false
+		rootCmd.SetArgs([]string{"models", "-v", "--provider", "openai"})
+		return Execute()
+	})
+	if res.err != nil {
+		t.Fatalf("ask models -v failed: %v\n%s", res.err, res.stderr)
+	}
+	for _, want := range []string{`gpt-4o +64k +\$2\.50/\$10\.00 +vision,tools +2023-10`, `my-model +- +\$1\.00/\$2\.00 +tools +-`} {
+		if !regexp.MustCompile(want).MatchString(res.stdout) {
+			t.Errorf("output does not match %q:\n%s", want, res.stdout)
+		}
+	}
+}
+
+func TestRouteProvider(t *testing.T) {
+	// A route without a provider picks its model's, not the configured one
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	conf := "models:\n  routed-model:\n    provider: mock\nroutes:\n  - model: routed-model\n"
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.SetModels(nil) })
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res := withAsk(t, m, history.NewMemoryStore(), "", func() error {
+		t.Setenv(config.ConfigEnv, path)
+		t.Setenv("ASK_PROVIDER", "openai")
+		resetFlags(rootCmd)
+		commandStarted = false
+		rootCmd.SetArgs([]string{"hi"})
+		return Execute()
+	})
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	if reqs := m.Requests(); len(reqs) != 1 || reqs[0].Model != "routed-model" {
+		t.Errorf("requests = %+v, want one for routed-model", reqs)
+	}
+}
+
+func TestStrictAfterContextPolicy(t *testing.T) {
+	// --strict judges the request the context policy leaves, so dropping
+	// old turns can make a long conversation fit
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	conf := "context_policy: drop-oldest\nmodels:\n  tiny:\n    provider: mock\n    context_window: 400\n"
+	if err := os.WriteFile(path, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { provider.SetModels(nil) })
+
+	store := history.NewMemoryStore()
+	long := strings.Repeat("word ", 150)
+	earlier := &history.Conversation{Model: "tiny", Provider: provider.MockName, Messages: []history.Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+	}}
+	if _, err := store.SaveConversation(t.Context(), earlier); err != nil {
+		t.Fatal(err)
+	}
+
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res := withAsk(t, m, store, "", func() error {
+		t.Setenv(config.ConfigEnv, path)
+		resetFlags(rootCmd)
+		commandStarted = false
+		rootCmd.SetArgs([]string{"--strict", "-c", strconv.FormatInt(earlier.ID, 10), "And then?"})
+		return Execute()
+	})
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	if reqs := m.Requests(); len(reqs) != 1 || len(reqs[0].Messages) >= 5 {
+		t.Errorf("requests = %+v, want one with the oldest turns dropped", reqs)
+	}
+}
+
+func TestStdinSize(t *testing.T) {
+	if tty, err := openTTY(); err == nil {
+		interactive := tty.isTerminal()
+		tty.Close()
+		if interactive {
+			t.Skip("a terminal would be asked to confirm")
+		}
+	}
+	t.Setenv("ASK_MAX_STDIN_BYTES", "16")
+	input := strings.Repeat("log line\n", 10)
+
+	// With no terminal to confirm on, a script gets a warning, not a failure
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res := runAsk(t, m, history.NewMemoryStore(), input, "What happened?")
+	if res.err != nil {
+		t.Fatalf("ask failed: %v\n%s", res.err, res.stderr)
+	}
+	if !strings.Contains(res.stderr, "max_stdin_bytes") || len(m.Requests()) != 1 {
+		t.Errorf("stderr = %q, requests = %d, want a warning and the request sent", res.stderr, len(m.Requests()))
+	}
+
+	m = provider.NewMock(provider.MockResponse{Tokens: []string{"ok"}})
+	res = runAsk(t, m, history.NewMemoryStore(), input, "--strict", "What happened?")
+	if ExitCode(res.err) != exitUsage || len(m.Requests()) != 0 {
+		t.Errorf("with --strict: err = %v, requests = %d, want a usage error and nothing sent", res.err, len(m.Requests()))
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	// Repositories of the same name are different workspaces
+	dir := t.TempDir()
+	var workspaces []string
+	for _, parent := range []string{"a", "b"} {
+		repo := filepath.Join(dir, parent, "api")
+		if err := os.MkdirAll(repo, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+			t.Fatalf("git init: %v\n%s", err, out)
+		}
+		t.Chdir(repo)
+		ws := resolveWorkspace()
+		if name := workspaceName(ws); name != "api" {
+			t.Errorf("workspaceName(%q) = %q, want api", ws, name)
+		}
+		workspaces = append(workspaces, ws)
+	}
+	if workspaces[0] == workspaces[1] {
+		t.Errorf("both repositories are in workspace %q", workspaces[0])
+	}
+	if name := workspaceName("notes"); name != "notes" {
+		t.Errorf(`workspaceName("notes") = %q, want notes`, name)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	m := provider.NewMock(provider.MockResponse{Tokens: []string{"Sunny."}})
+	store := history.NewMemoryStore()
+	res := runAsk(t, m, store, "", "--queue", "--web", "What is the weather?")
+	if res.err != nil {
+		t.Fatalf("ask --queue failed: %v\n%s", res.err, res.stderr)
+	}
+	if n := len(m.Requests()); n != 0 {
+		t.Fatalf("sent %d requests, want the prompt only queued", n)
+	}
+	queue, err := store.ListQueue(t.Context())
+	if err != nil || len(queue) != 1 || queue[0].Prompt != "What is the weather?" || !queue[0].WebSearch {
+		t.Fatalf("ListQueue() = %+v, %v; want the prompt queued with --web", queue, err)
+	}
+
+	res = runAsk(t, m, store, "", "queue", "run")
+	if res.err != nil {
+		t.Fatalf("ask queue run failed: %v\n%s", res.err, res.stderr)
+	}
+	if reqs := m.Requests(); len(reqs) != 1 || !reqs[0].WebSearch {
+		t.Errorf("requests = %+v, want the queued prompt sent with web search", reqs)
+	}
+}
+
+func TestUpgradeCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v99.0.0","assets":[]}`)
+	}))
+	defer srv.Close()
+	prev := newUpgradeChecker
+	newUpgradeChecker = func() *upgrade.Checker { return upgrade.NewCheckerWithBaseURL(upgrade.Repo, srv.URL) }
+	t.Cleanup(func() { newUpgradeChecker = prev })
+
+	res := runAsk(t, nil, history.NewMemoryStore(), "", "upgrade", "--check")
+	if ExitCode(res.err) != exitUpdateAvailable || !strings.Contains(res.stdout, "Update available: v99.0.0") {
+		t.Errorf("exit code = %d (%v), stdout = %q; want the update reported", ExitCode(res.err), res.err, res.stdout)
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/batch"
+	"github.com/devaloi/ask/internal/eval"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	evalConcurrencyFlag int
+	evalJSONFlag        bool
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval <suite.yaml>",
+	Short: "Run a prompt suite against models and check the responses",
+	Long: `Run every case of a prompt suite against its models, check each response,
+and report pass or fail with latency. The exit status is 1 if any check
+fails, so suites can guard prompts against regressions in CI.
+
+A suite is YAML:
+
+  models: [gpt-4o, claude-sonnet-4-20250514]   # default: -m or the configured model
+  system: Be terse.                            # optional
+  cases:
+    - name: capital
+      prompt: What is the capital of France?
+      expect:
+        contains: [Paris]
+        not_contains: [London]
+        ignore_case: true
+    - name: user-json
+      prompt: Give a sample user as JSON with name and age.
+      models: [gpt-4o-mini]                    # overrides the suite's models
+      expect:
+        regex: ['"age":\s*\d+']
+        json_schema:
+          type: object
+          required: [name, age]
+          properties:
+            age: {type: integer, minimum: 0}
+
+Each model is sent to the provider that offers it, or the default provider.
+-m runs every case against that model instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEval,
+}
+
+func init() {
+	rootCmd.AddCommand(evalCmd)
+	evalCmd.Flags().IntVarP(&evalConcurrencyFlag, "concurrency", "j", 1, "Number of cases to run at once")
+	evalCmd.Flags().BoolVar(&evalJSONFlag, "json", false, "Print one JSON result per line")
+}
+
+// evalResult is the outcome of one case against one model.
+type evalResult struct {
+	Case     string        `json:"case"`
+	Model    string        `json:"model"`
+	Provider string        `json:"provider"`
+	Pass     bool          `json:"pass"`
+	Failures []string      `json:"failures,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Latency  time.Duration `json:"-"`
+	Response string        `json:"response"`
+}
+
+// MarshalJSON encodes the latency in milliseconds.
+func (r evalResult) MarshalJSON() ([]byte, error) {
+	type plain evalResult
+	return json.Marshal(struct {
+		plain
+		Latency int64 `json:"latency_ms"`
+	}{plain(r), r.Latency.Milliseconds()})
+}
+
+func runEval(cmd *cobra.Command, args []string) error {
+	if evalConcurrencyFlag < 1 {
+		return usageErrorf("--concurrency must be at least 1")
+	}
+	suite, err := eval.Load(args[0])
+	if err != nil {
+		return usageErrorf("invalid suite %s: %v", args[0], err)
+	}
+
+	providers, err := configuredProviders()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	do := func(ctx context.Context, run eval.Run) evalResult {
+		p := providerForModel(providers, run.Model)
+		req := &provider.ChatRequest{Model: run.Model, Temperature: suite.Temperature}
+		if system := suite.SystemPrompt(run.Case); system != "" {
+			req.Messages = append(req.Messages, provider.Message{Role: "system", Content: system})
+		}
+		req.Messages = append(req.Messages, provider.Message{Role: "user", Content: run.Case.Prompt})
+
+		start := time.Now()
+		response, err := collectChat(ctx, p, req)
+		result := evalResult{Case: run.Case.Name, Model: run.Model, Provider: p.Name(), Latency: time.Since(start), Response: response}
+		if err != nil {
+			result.Error = err.Error()
+			// Every remaining case would fail the same way
+			if errors.Is(err, provider.ErrAuth) {
+				cancel(err)
+			}
+			return result
+		}
+		result.Failures = run.Case.Expect.Check(response)
+		result.Pass = len(result.Failures) == 0
+		return result
+	}
+
+	passed, failed := 0, 0
+	enc := json.NewEncoder(os.Stdout)
+	emit := func(r evalResult) error {
+		if r.Pass {
+			passed++
+		} else {
+			failed++
+		}
+		if evalJSONFlag {
+			return enc.Encode(r)
+		}
+		printEvalResult(r)
+		return nil
+	}
+
+	start := time.Now()
+	runs := suite.Runs(modelFlag, getModel())
+	if err := batch.Run(ctx, runs, evalConcurrencyFlag, do, emit); err != nil {
+		return err
+	}
+	if err := context.Cause(ctx); err != nil {
+		return fmt.Errorf("stopping eval: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Done: %d passed, %d failed in %s\n", passed, failed, time.Since(start).Round(time.Millisecond))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(runs))
+	}
+	return nil
+}
+
+// printEvalResult prints a result line, followed by its failures.
+func printEvalResult(r evalResult) {
+	status := "PASS"
+	switch {
+	case r.Error != "":
+		status = "ERROR"
+	case !r.Pass:
+		status = "FAIL"
+	}
+	fmt.Printf("%-5s  %-24s  %-26s  %6.1fs\n", status, util.Truncate(r.Case, 24), r.Model, r.Latency.Seconds())
+	if r.Error != "" {
+		fmt.Printf("       %s\n", util.Truncate(r.Error, 100))
+	}
+	for _, f := range r.Failures {
+		fmt.Printf("       response %s\n", f)
+	}
+}
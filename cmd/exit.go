@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/devaloi/ask/internal/budget"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// Exit codes. Scripts can branch on these, so they must stay stable.
+const (
+	exitError           = 1   // unclassified failure
+	exitUsage           = 2   // bad flags, arguments, or missing prompt
+	exitAuth            = 3   // missing or rejected API key
+	exitRateLimited     = 4   // provider rate limit
+	exitProvider        = 5   // provider or server error
+	exitContentFiltered = 7   // response blocked by a content filter
+	exitUpdateAvailable = 8   // upgrade --check found a newer release
+	exitBudget          = 9   // refused by an exceeded spending budget
+	exitCancelled       = 130 // interrupted with Ctrl+C (128 + SIGINT)
+)
+
+// usageError marks an error caused by how ask was invoked.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// usageErrorf returns a usageError formatted like fmt.Errorf.
+func usageErrorf(format string, a ...any) error {
+	return usageError{fmt.Errorf(format, a...)}
+}
+
+// exitStatus is an exit status passed on without a message of its own,
+// such as a plugin's: the failure has already been reported.
+type exitStatus int
+
+func (e exitStatus) Error() string { return "" }
+
+// ExitCode returns the process exit status for an error returned by
+// Execute.
+func ExitCode(err error) int {
+	var usage usageError
+	var status exitStatus
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, context.Canceled):
+		return exitCancelled
+	case errors.Is(err, provider.ErrNoAPIKey), errors.Is(err, provider.ErrAuth):
+		return exitAuth
+	case errors.Is(err, provider.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, provider.ErrServer), errors.Is(err, provider.ErrAPI):
+		return exitProvider
+	case errors.Is(err, provider.ErrContentFiltered):
+		return exitContentFiltered
+	case errors.Is(err, budget.ErrExceeded), errors.Is(err, budget.ErrUnpriced):
+		return exitBudget
+	default:
+		return exitError
+	}
+}
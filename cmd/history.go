@@ -1,19 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
-	"github.com/devaloi/ask/internal/config"
-	"github.com/devaloi/ask/internal/history"
 	"github.com/devaloi/ask/internal/util"
 )
 
 var (
 	searchFlag string
 	limitFlag  int
+	allFlag    bool
 )
 
 var historyCmd = &cobra.Command{
@@ -21,57 +23,71 @@ var historyCmd = &cobra.Command{
 	Short: "List recent conversations",
 	Long: `List recent conversations from the history.
 
-Use --search to filter by content.
+In a workspace (see --workspace), only its conversations are listed;
+use --all for every conversation.
+
+Use --search to filter by title, tag, or content.
 Use --limit to control how many results to show.`,
 	RunE: runHistory,
 }
 
 func init() {
 	rootCmd.AddCommand(historyCmd)
-	historyCmd.Flags().StringVar(&searchFlag, "search", "", "Search conversations by content")
+	historyCmd.Flags().StringVar(&searchFlag, "search", "", "Search conversations by title, tag, or content")
 	historyCmd.Flags().IntVar(&limitFlag, "limit", util.DefaultHistoryLimit, "Maximum number of results")
+	historyCmd.Flags().BoolVar(&allFlag, "all", false, "List conversations in every workspace")
 }
 
 func runHistory(cmd *cobra.Command, args []string) error {
-	store, err := getStore()
+	workspace := currentWorkspace()
+	if allFlag {
+		workspace = ""
+	}
+	return listHistory(os.Stdout, workspace, limitFlag, searchFlag)
+}
+
+// listHistory prints up to limit recent conversations in workspace (or
+// all, if it is empty) matching search to w.
+func listHistory(w io.Writer, workspace string, limit int, search string) error {
+	store, err := openStore()
 	if err != nil {
 		return fmt.Errorf("opening history store: %w", err)
 	}
 	defer store.Close()
 
-	conversations, err := store.ListConversations(limitFlag, searchFlag)
+	conversations, err := store.ListConversationsIn(context.Background(), workspace, limit, search)
 	if err != nil {
 		return fmt.Errorf("listing conversations: %w", err)
 	}
 
 	if len(conversations) == 0 {
-		if searchFlag != "" {
-			fmt.Printf("No conversations found matching '%s'\n", searchFlag)
-		} else {
-			fmt.Println("No conversations yet. Start chatting with: ask \"your question\"")
+		switch {
+		case search != "":
+			fmt.Fprintf(w, "No conversations found matching '%s'\n", search)
+		case workspace != "":
+			fmt.Fprintf(w, "No conversations in workspace %s yet. List all with: ask history --all\n", workspaceName(workspace))
+		default:
+			fmt.Fprintln(w, "No conversations yet. Start chatting with: ask \"your question\"")
 		}
 		return nil
 	}
 
-	fmt.Println("ID    Model                  Date         Title")
-	fmt.Println("----  ---------------------  -----------  ----------------------------------------")
+	if workspace != "" {
+		fmt.Fprintf(os.Stderr, "Workspace: %s (--all for every workspace)\n", workspaceName(workspace))
+	}
+
+	fmt.Fprintln(w, "ID    Model                  Date         Title")
+	fmt.Fprintln(w, "----  ---------------------  -----------  ----------------------------------------")
 
 	for _, conv := range conversations {
 		date := conv.CreatedAt.Format("Jan 02 2006")
 		model := util.Truncate(conv.Model, util.MaxModelDisplay)
 		title := util.Truncate(conv.Title, util.MaxTitleDisplay)
-		fmt.Printf("%-4d  %-21s  %-11s  %s\n", conv.ID, model, date, title)
+		if len(conv.Tags) > 0 {
+			title += " [" + strings.Join(conv.Tags, ", ") + "]"
+		}
+		fmt.Fprintf(w, "%-4d  %-21s  %-11s  %s\n", conv.ID, model, date, title)
 	}
 
 	return nil
 }
-
-func getStore() (*history.Store, error) {
-	dataDir, err := config.GetDataDir()
-	if err != nil {
-		return nil, err
-	}
-
-	dbPath := filepath.Join(dataDir, "history.db")
-	return history.NewStore(dbPath)
-}
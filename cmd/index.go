@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/files"
+	"github.com/devaloi/ask/internal/rag"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// embedBatchSize is how many chunks are embedded per request.
+const embedBatchSize = 64
+
+// defaultRAGChunks is how many chunks --rag adds to a prompt by default.
+const defaultRAGChunks = 5
+
+var (
+	indexNameFlag   string
+	indexModelFlag  string
+	indexListFlag   bool
+	indexDeleteFlag string
+
+	ragFlag  string
+	ragKFlag int
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index <path>...",
+	Short: "Index local files for retrieval with --rag",
+	Long: `Index files, directories, or globs so that relevant parts of them can be
+added to prompts automatically with --rag.
+
+Files are split into chunks, embedded with the OpenAI embeddings API, and
+stored in index.db in ask's data directory. The index is named after the
+first path unless --name is given.
+
+Running the same command again updates the index: only changed files are
+re-embedded, and files no longer matched are removed.
+
+Examples:
+  ask index ./docs
+  ask index "src/**/*.go" --name code
+  ask --rag docs "How do I configure retries?"
+  ask index --list
+  ask index --delete docs`,
+	RunE: runIndex,
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.Flags().StringVar(&indexNameFlag, "name", "", "Index name (default: base name of the first path)")
+	indexCmd.Flags().StringVar(&indexModelFlag, "embed-model", provider.DefaultEmbeddingModel, "Embedding model")
+	indexCmd.Flags().BoolVar(&indexListFlag, "list", false, "List indexes")
+	indexCmd.Flags().StringVar(&indexDeleteFlag, "delete", "", "Delete the named index")
+
+	rootCmd.Flags().StringVar(&ragFlag, "rag", "", "Add the most relevant chunks from this index to the prompt")
+	rootCmd.Flags().IntVar(&ragKFlag, "rag-k", defaultRAGChunks, "Number of chunks --rag adds")
+}
+
+func runIndex(cmd *cobra.Command, args []string) error {
+	switch {
+	case indexListFlag:
+		return listIndexes()
+	case indexDeleteFlag != "":
+		return deleteIndex(indexDeleteFlag)
+	case len(args) == 0:
+		return usageErrorf("no paths to index\n\nUsage: ask index <path>...")
+	}
+
+	name := indexNameFlag
+	if name == "" {
+		abs, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+
+	paths, err := files.Expand(args)
+	if err != nil {
+		return err
+	}
+
+	embedder, err := getEmbedder()
+	if err != nil {
+		return err
+	}
+
+	store, err := openIndexStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.EnsureIndex(name, indexModelFlag); err != nil {
+		return fmt.Errorf("creating index: %w", err)
+	}
+	hashes, err := store.FileHashes(name)
+	if err != nil {
+		return fmt.Errorf("reading index: %w", err)
+	}
+
+	ctx := context.Background()
+	indexed := make(map[string]bool)
+	var updated, unchanged int
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		indexed[abs] = true
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if hashes[abs] == hash {
+			unchanged++
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Indexing %s\n", p)
+		chunks := rag.Split(abs, string(data), rag.DefaultChunkSize, rag.DefaultChunkOverlap)
+		vectors, err := embedChunks(ctx, embedder, chunks)
+		if err != nil {
+			return fmt.Errorf("embedding %s: %w", p, err)
+		}
+		if err := store.PutFile(name, abs, hash, chunks, vectors); err != nil {
+			return fmt.Errorf("saving %s: %w", p, err)
+		}
+		updated++
+	}
+
+	var removed int
+	for path := range hashes {
+		if !indexed[path] {
+			if err := store.RemoveFile(name, path); err != nil {
+				return fmt.Errorf("removing %s: %w", path, err)
+			}
+			removed++
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Index %q: %d files indexed, %d unchanged, %d removed\n", name, updated, unchanged, removed)
+	return nil
+}
+
+// embedChunks embeds chunks in batches, returning one vector per chunk.
+func embedChunks(ctx context.Context, e provider.Embedder, chunks []rag.Chunk) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		batch := chunks[start:min(start+embedBatchSize, len(chunks))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.Content
+		}
+		v, err := e.Embed(ctx, indexModelFlag, texts)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, v...)
+	}
+	return vectors, nil
+}
+
+func listIndexes() error {
+	store, err := openIndexStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	indexes, err := store.ListIndexes()
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		fmt.Fprintln(os.Stderr, "No indexes. Create one with: ask index <path>")
+		return nil
+	}
+	for _, idx := range indexes {
+		fmt.Printf("%-20s %4d files %6d chunks  %s  %s\n", idx.Name, idx.Files, idx.Chunks, idx.Model, idx.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+func deleteIndex(name string) error {
+	store, err := openIndexStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.DeleteIndex(name); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Deleted index %q\n", name)
+	return nil
+}
+
+// openIndexStore opens the RAG index database in the data directory.
+func openIndexStore() (*rag.Store, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return nil, err
+	}
+	return rag.NewStore(filepath.Join(dataDir, "index.db"))
+}
+
+// getEmbedder returns the selected provider if it can create embeddings,
+// and otherwise OpenAI.
+func getEmbedder() (provider.Embedder, error) {
+	if p, err := configuredProvider(getProvider()); err == nil {
+		if e, ok := provider.As[provider.Embedder](p); ok {
+			return e, nil
+		}
+	}
+	p, err := configuredProvider("openai")
+	if err != nil {
+		return nil, fmt.Errorf("embeddings use OpenAI: %w", err)
+	}
+	e, _ := provider.As[provider.Embedder](p)
+	return e, nil
+}
+
+// retrieveContext returns the chunks of the --rag index most relevant to
+// query, formatted as fenced blocks headed by path and line range.
+func retrieveContext(ctx context.Context, query string) (string, error) {
+	store, err := openIndexStore()
+	if err != nil {
+		return "", err
+	}
+	defer store.Close()
+
+	idx, err := store.GetIndex(ragFlag)
+	if errors.Is(err, rag.ErrNoIndex) {
+		return "", usageErrorf("unknown index: %s\n\nCreate it with: ask index <path> --name %s", ragFlag, ragFlag)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	embedder, err := getEmbedder()
+	if err != nil {
+		return "", err
+	}
+	vectors, err := embedder.Embed(ctx, idx.Model, []string{query})
+	if err != nil {
+		return "", fmt.Errorf("embedding prompt: %w", err)
+	}
+
+	results, err := store.Search(ragFlag, vectors[0], ragKFlag)
+	if err != nil {
+		return "", fmt.Errorf("searching index: %w", err)
+	}
+
+	blocks := make([]string, len(results))
+	for i, r := range results {
+		// Fence heads the block with "path:"; note the lines after the
+		// path so the fence language still comes from its extension
+		path := displayPath(r.Path)
+		blocks[i] = fmt.Sprintf("%s (lines %d-%d)", path, r.StartLine, r.EndLine) +
+			strings.TrimPrefix(files.Fence(path, r.Content), path)
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// withRAGContext prepends the --rag chunks most relevant to query to
+// prompt. Without --rag, prompt is returned unchanged.
+func withRAGContext(ctx context.Context, query, prompt string) (string, error) {
+	if ragFlag == "" || strings.TrimSpace(query) == "" {
+		return prompt, nil
+	}
+	excerpts, err := retrieveContext(ctx, query)
+	if err != nil || excerpts == "" {
+		return prompt, err
+	}
+	return excerpts + "\n\n" + prompt, nil
+}
+
+// displayPath returns path relative to the working directory when it is
+// beneath it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return rel
+}
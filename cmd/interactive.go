@@ -0,0 +1,537 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/internal/theme"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// session holds the state of an interactive chat.
+type session struct {
+	ctx          context.Context
+	p            provider.Provider
+	systemPrompt string
+	writer       *stream.Writer
+	errWriter    *stream.Writer // for errors, which stay out of redirected output
+	isTTY        bool           // whether stdout is a terminal
+	interrupts   *interrupter
+	theme        theme.Theme
+
+	// messages is the conversation sent with each request. Messages that
+	// have been saved to history carry their IDs.
+	messages []history.Message
+
+	// conv is the history conversation being appended to, or nil until
+	// the first exchange is saved.
+	conv *history.Conversation
+
+	// title and tags are --title and --tag, for the first conversation
+	// saved.
+	title string
+	tags  []string
+
+	// usage totals the session's requests, including retries and
+	// summaries, across conversations and providers.
+	usage *usageTally
+}
+
+// usageTally totals estimated usage, noting the models whose cost it
+// cannot estimate.
+type usageTally struct {
+	history.UsageTotal
+	unpriced []string
+}
+
+func (t *usageTally) add(u *history.Usage) {
+	t.Add(*u)
+	if _, ok := provider.Cost(u.Model, 0, 0); !ok && !slices.Contains(t.unpriced, u.Model) {
+		t.unpriced = append(t.unpriced, u.Model)
+	}
+}
+
+// runInteractive starts the REPL. If conv is non-nil, the session
+// continues that conversation and new exchanges are appended to it.
+func runInteractive(conv *history.Conversation) error {
+	// Create provider
+	providerName := getProvider()
+	p, err := newChatProvider(providerName)
+	if err != nil {
+		return err
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "ask — using %s/%s\n", p.Name(), getModel())
+	fmt.Fprintln(os.Stderr, "Type /quit to exit, /new to start fresh, /help for commands")
+	if conv != nil {
+		fmt.Fprintf(os.Stderr, "Continuing conversation #%d: %s\n", conv.ID, conv.Title)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	// Get system prompt if specified
+	systemPrompt, err := buildSystemPrompt()
+	if err != nil {
+		return err
+	}
+
+	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	s := &session{
+		ctx:          context.Background(),
+		p:            p,
+		systemPrompt: systemPrompt,
+		writer:       stream.NewWriter(os.Stdout, stdoutIsTerminal),
+		errWriter:    stream.NewWriter(os.Stderr, term.IsTerminal(int(os.Stderr.Fd()))),
+		isTTY:        stdoutIsTerminal,
+		theme:        getTheme(os.Stdout),
+		conv:         conv,
+		title:        titleFlag,
+		tags:         tagFlags,
+		usage:        &usageTally{},
+	}
+	s.writer.SetCodeStyle(string(s.theme.Code))
+	s.writer.SetStyles(s.theme.Streams())
+	s.errWriter.SetStyles(getTheme(os.Stderr).Streams())
+	if conv != nil {
+		s.messages = append(s.messages, conv.Messages...)
+	} else {
+		s.reset()
+	}
+
+	usageMu.Lock()
+	sessionUsage = s.usage
+	usageMu.Unlock()
+	defer func() {
+		usageMu.Lock()
+		sessionUsage = nil
+		usageMu.Unlock()
+	}()
+
+	// Ctrl+C cancels the response being generated; at the prompt it exits
+	s.interrupts = newInterrupter(s.printExitUsage)
+	defer s.interrupts.stop()
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, s.theme.Prompt.Render(">")+" ")
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				fmt.Fprintln(os.Stderr)
+				s.printExitUsage()
+				return nil
+			}
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		// Handle special commands
+		if strings.HasPrefix(input, "/") {
+			if quit := s.command(input); quit {
+				s.printExitUsage()
+				return nil
+			}
+			continue
+		}
+
+		s.ask(input)
+	}
+}
+
+// command runs a slash command. It reports whether the session should end.
+func (s *session) command(input string) bool {
+	fields := strings.Fields(input)
+	name := strings.ToLower(fields[0])
+	args := fields[1:]
+
+	switch name {
+	case "/quit", "/exit", "/q":
+		return true
+	case "/new", "/clear":
+		s.conv = nil
+		s.reset()
+		fmt.Fprintln(os.Stderr, "Started new conversation")
+	case "/model":
+		if len(args) == 0 {
+			fmt.Printf("Current model: %s\n", getModel())
+			break
+		}
+		if err := checkModel(s.p, args[0]); err != nil {
+			s.printError(err)
+			break
+		}
+		modelFlag = args[0]
+		fmt.Fprintf(os.Stderr, "Switched to model: %s\n", modelFlag)
+	case "/models":
+		for _, m := range s.p.Models() {
+			marker := "  "
+			if m == getModel() {
+				marker = "* "
+			}
+			fmt.Printf("  %s%s\n", marker, m)
+		}
+	case "/provider":
+		if len(args) == 0 {
+			fmt.Printf("Current provider: %s\n", s.p.Name())
+			break
+		}
+		s.switchProvider(args[0])
+	case "/retry":
+		model := getModel()
+		if len(args) == 2 && (args[0] == "-m" || args[0] == "--model") {
+			model = args[1]
+		} else if len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "Usage: /retry [-m model]")
+			break
+		}
+		s.retry(model)
+	case "/undo":
+		deleteHistory := len(args) == 1 && args[0] == "--history"
+		if len(args) > 0 && !deleteHistory {
+			fmt.Fprintln(os.Stderr, "Usage: /undo [--history]")
+			break
+		}
+		s.undo(deleteHistory)
+	case "/history":
+		search := strings.TrimSpace(strings.TrimPrefix(input, fields[0]))
+		if err := listHistory(os.Stdout, currentWorkspace(), util.DefaultHistoryLimit, search); err != nil {
+			s.printError(err)
+		}
+	case "/show":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: /show <id>")
+			break
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid conversation ID: %s\n", args[0])
+			break
+		}
+		if err := showConversation(os.Stdout, s.theme, id); err != nil {
+			s.printError(err)
+		}
+	case "/edit":
+		s.edit(strings.TrimSpace(strings.TrimPrefix(input, fields[0])))
+	case "/usage":
+		fmt.Println(s.usageSummary())
+	case "/help":
+		printHelp()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s (type /help for commands)\n", input)
+	}
+	return false
+}
+
+// switchProvider replaces the session's provider. If the current model is
+// not offered by the new provider, the new provider's configured default
+// model is used, or else its first.
+func (s *session) switchProvider(name string) {
+	p, err := newChatProvider(name)
+	if err != nil {
+		s.printError(err)
+		return
+	}
+
+	model := getModel()
+	s.p = p
+	providerFlag = name
+	fmt.Fprintf(os.Stderr, "Switched to provider: %s\n", p.Name())
+
+	models := p.Models()
+	if len(models) > 0 && !slices.Contains(models, getModel()) {
+		modelFlag = cfg.ModelFor(name)
+		if modelFlag == "" {
+			modelFlag = models[0]
+		}
+	}
+	if getModel() != model {
+		fmt.Fprintf(os.Stderr, "Switched to model: %s\n", getModel())
+	}
+}
+
+// reset clears the conversation, keeping only the system prompt.
+func (s *session) reset() {
+	s.messages = s.messages[:0]
+	if s.systemPrompt != "" {
+		s.messages = append(s.messages, history.Message{Role: "system", Content: s.systemPrompt})
+	}
+}
+
+// ask sends input as a new user message and records the exchange.
+func (s *session) ask(input string) {
+	input, err := withRAGContext(s.ctx, input, input)
+	if err != nil {
+		s.printError(err)
+		return
+	}
+
+	model := getModel()
+	s.messages = append(s.messages, history.Message{Role: "user", Content: input})
+
+	reply, ok := s.generate(model)
+	if !ok {
+		// Remove the failed user message
+		s.messages = s.messages[:len(s.messages)-1]
+		return
+	}
+
+	logExchange(s.p.Name(), model, input, reply.Content)
+
+	saved := s.save(history.Message{Role: "user", Content: input}, reply)
+	s.messages[len(s.messages)-1] = saved[0]
+	s.messages = append(s.messages, saved[1])
+}
+
+// edit composes a prompt in the user's editor, starting from initial,
+// and sends it.
+func (s *session) edit(initial string) {
+	// Keep Ctrl+C in the editor from ending the session
+	_, done := s.interrupts.begin(s.ctx)
+	prompt, err := composePrompt(initial)
+	done()
+	if err != nil {
+		s.printError(err)
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, prompt)
+	s.ask(prompt)
+}
+
+// retry regenerates the last response, optionally with a different model.
+// The new response replaces the old one, in history too, and records
+// which attempt it is.
+func (s *session) retry(model string) {
+	n := len(s.messages)
+	if n < 2 || s.messages[n-1].Role != "assistant" {
+		fmt.Fprintln(os.Stderr, "Nothing to retry")
+		return
+	}
+
+	previous := s.messages[n-1]
+	s.messages = s.messages[:n-1]
+
+	reply, ok := s.generate(model)
+	if !ok {
+		s.messages = append(s.messages, previous)
+		return
+	}
+
+	logExchange(s.p.Name(), model, s.messages[n-2].Content, reply.Content)
+
+	if previous.ID != 0 {
+		if err := withStore(func(store history.Store) error {
+			return store.DeleteMessages(s.ctx, previous.ID)
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update history: %v\n", err)
+		}
+	}
+
+	reply.Attempt = max(previous.Attempt, 1) + 1
+	saved := s.save(reply)
+	s.messages = append(s.messages, saved[0])
+}
+
+// undo drops the most recent user and assistant messages from the
+// conversation. If deleteHistory is set, they are removed from history too.
+func (s *session) undo(deleteHistory bool) {
+	n := len(s.messages)
+	if n < 2 || s.messages[n-1].Role != "assistant" || s.messages[n-2].Role != "user" {
+		fmt.Fprintln(os.Stderr, "Nothing to undo")
+		return
+	}
+
+	removed := s.messages[n-2:]
+	s.messages = s.messages[:n-2]
+
+	if deleteHistory {
+		var ids []int64
+		for _, msg := range removed {
+			if msg.ID != 0 {
+				ids = append(ids, msg.ID)
+			}
+		}
+		if len(ids) > 0 {
+			if err := withStore(func(store history.Store) error {
+				return store.DeleteMessages(s.ctx, ids...)
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update history: %v\n", err)
+			}
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Removed: %s\n", util.Truncate(removed[0].Content, util.MaxTitleDisplay))
+}
+
+// generate streams a response to the current messages using model.
+// Ctrl+C cancels it; the part received before that or an error is kept,
+// marked partial. It reports false if no usable response was received.
+func (s *session) generate(model string) (history.Message, bool) {
+	ctx, done := s.interrupts.begin(s.ctx)
+	var convID int64
+	if s.conv != nil {
+		convID = s.conv.ID
+	}
+	messages, err := contextMessages(ctx, s.p, model, convID, s.messages)
+	if err != nil {
+		done()
+		if !errors.Is(err, context.Canceled) {
+			s.printError(err)
+		}
+		return history.Message{}, false
+	}
+	req := &provider.ChatRequest{
+		Messages:  messages,
+		Model:     model,
+		WebSearch: webFlag,
+	}
+	response, err := streamChat(ctx, s.p, req, s.writer)
+	done()
+	defer s.endTurn()
+
+	reply := history.Message{Role: "assistant", Content: response}
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			s.printErrorLine("[cancelled]")
+		} else {
+			s.printError(err)
+		}
+		// Keep the partial response in the conversation
+		reply.Partial = true
+		return reply, response != ""
+	}
+	return reply, true
+}
+
+// usageSummary describes the session's usage so far.
+func (s *session) usageSummary() string {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+	t := s.usage
+	requests := "requests"
+	if t.Requests == 1 {
+		requests = "request"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session usage: %d %s, %d input and %d output tokens, about $%.4f",
+		t.Requests, requests, t.InputTokens, t.OutputTokens, t.Cost)
+	if len(t.unpriced) > 0 {
+		fmt.Fprintf(&b, " (not counting %s, with no known price)", strings.Join(t.unpriced, ", "))
+	}
+	return b.String()
+}
+
+// printExitUsage reports the session's usage as it ends, if it sent
+// any requests.
+func (s *session) printExitUsage() {
+	usageMu.Lock()
+	requests := s.usage.Requests
+	usageMu.Unlock()
+	if requests > 0 {
+		fmt.Fprintln(os.Stderr, s.usageSummary())
+	}
+}
+
+// printError reports err in the error style.
+func (s *session) printError(err error) {
+	s.printErrorLine(fmt.Sprintf("Error: %v", err))
+}
+
+// endTurn sets a response apart from the next turn: with a rule on a
+// terminal, or else a blank line.
+func (s *session) endTurn() {
+	if s.isTTY {
+		_ = s.writer.Separator()
+		return
+	}
+	_ = s.writer.EndLine()
+	fmt.Println()
+}
+
+// printErrorLine writes msg to stderr in the error style, after the
+// response on stdout, so that redirected output holds only responses.
+func (s *session) printErrorLine(msg string) {
+	_ = s.writer.EndLine()
+	if err := s.errWriter.ErrorLine(msg); err != nil {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+}
+
+// save appends msgs to the conversation in history, creating it on first
+// use, and returns them with their IDs set. Failures are reported as
+// warnings and the messages are returned unsaved.
+func (s *session) save(msgs ...history.Message) []history.Message {
+	if s.conv == nil {
+		s.conv = &history.Conversation{
+			Title:     s.title,
+			Model:     getModel(),
+			Provider:  s.p.Name(),
+			Workspace: currentWorkspace(),
+			Tags:      s.tags,
+		}
+		s.title, s.tags = "", nil
+	}
+	s.conv.Messages = msgs
+
+	err := withStore(func(store history.Store) error {
+		_, err := store.SaveConversation(s.ctx, s.conv)
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
+	}
+	return s.conv.Messages
+}
+
+// withStore opens the history store, runs fn, and closes the store.
+func withStore(fn func(history.Store) error) error {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// toProviderMessages converts history messages to provider messages.
+func toProviderMessages(msgs []history.Message) []provider.Message {
+	out := make([]provider.Message, len(msgs))
+	for i, msg := range msgs {
+		out[i] = provider.Message{Role: msg.Role, Content: msg.Content}
+	}
+	return out
+}
+
+func printHelp() {
+	fmt.Println(`Commands:
+  /quit, /exit, /q     Exit interactive mode
+  /new, /clear         Start a new conversation
+  /model <name>        Switch model
+  /models              List models for the current provider
+  /provider <name>     Switch provider
+  /retry [-m <model>]  Regenerate the last response
+  /undo [--history]    Remove the last exchange (and from history)
+  /history [search]    List recent conversations
+  /show <id>           Display a conversation
+  /edit [text]         Compose a prompt in $EDITOR
+  /usage               Show the tokens and estimated cost of this session
+  /help                Show this help`)
+}
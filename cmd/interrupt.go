@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// interrupter routes SIGINT in interactive mode: while a response is
+// streaming it cancels the request, otherwise it exits the session.
+type interrupter struct {
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	signals chan os.Signal
+}
+
+// newInterrupter starts handling SIGINT, calling atExit before it exits
+// the session. Call stop to restore the default behavior.
+func newInterrupter(atExit func()) *interrupter {
+	i := &interrupter{signals: make(chan os.Signal, 1)}
+	signal.Notify(i.signals, os.Interrupt)
+
+	go func() {
+		for range i.signals {
+			i.mu.Lock()
+			cancel := i.cancel
+			i.mu.Unlock()
+
+			if cancel == nil {
+				// At the prompt: exit the session
+				fmt.Fprintln(os.Stderr)
+				atExit()
+				os.Exit(exitCancelled)
+			}
+			cancel()
+		}
+	}()
+
+	return i
+}
+
+// begin returns a context for one generation that SIGINT cancels,
+// and a function to call when the generation is over.
+func (i *interrupter) begin(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	i.mu.Lock()
+	i.cancel = cancel
+	i.mu.Unlock()
+
+	return ctx, func() {
+		i.mu.Lock()
+		i.cancel = nil
+		i.mu.Unlock()
+		cancel()
+	}
+}
+
+// stop stops handling SIGINT.
+func (i *interrupter) stop() {
+	signal.Stop(i.signals)
+	close(i.signals)
+}
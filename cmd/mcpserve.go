@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/mcp"
+	"github.com/devaloi/ask/internal/theme"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/internal/version"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "mcp-serve",
+	Short: "Run ask as an MCP server over stdio",
+	Long: `Run ask as a Model Context Protocol server over stdin and stdout, so
+desktop agents and other MCP clients can use your conversation history as
+context and send prompts through ask.
+
+Tools:
+  search_history     find past conversations by text
+  get_conversation   fetch a conversation's messages
+  ask                send a prompt using ask's configured provider and model
+
+Register it with a client by running "ask mcp-serve" as a stdio server, e.g.
+in a Claude Desktop style config:
+
+  {"mcpServers": {"ask": {"command": "ask", "args": ["mcp-serve"]}}}`,
+	Args: cobra.NoArgs,
+	RunE: runMCPServe,
+}
+
+func init() {
+	rootCmd.AddCommand(mcpServeCmd)
+}
+
+func runMCPServe(cmd *cobra.Command, args []string) error {
+	server := mcp.NewServer("ask", version.Get().Version,
+		mcp.Tool{
+			Name:        "search_history",
+			Description: "Search the user's ask conversation history by text in titles and messages. Returns matching conversations, newest first, with their IDs for get_conversation.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "description": "Text to search for; empty lists recent conversations"},
+					"limit": map[string]any{"type": "integer", "description": "Maximum number of results (default 20)"},
+				},
+			},
+			Call: mcpSearchHistory,
+		},
+		mcp.Tool{
+			Name:        "get_conversation",
+			Description: "Fetch all messages of an ask conversation by ID.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"id": map[string]any{"type": "integer", "description": "Conversation ID"},
+				},
+				"required": []string{"id"},
+			},
+			Call: mcpGetConversation,
+		},
+		mcp.Tool{
+			Name:        "ask",
+			Description: "Send a prompt to the LLM configured in ask and return its response.",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"prompt": map[string]any{"type": "string", "description": "The prompt"},
+					"system": map[string]any{"type": "string", "description": "Optional system prompt"},
+					"model":  map[string]any{"type": "string", "description": "Optional model; defaults to ask's configured model"},
+				},
+				"required": []string{"prompt"},
+			},
+			Call: mcpAsk,
+		},
+	)
+
+	// The client ends the session by closing stdin
+	return server.Serve(context.Background(), os.Stdin, os.Stdout)
+}
+
+func mcpSearchHistory(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Limit <= 0 {
+		args.Limit = util.DefaultHistoryLimit
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return "", err
+	}
+	defer store.Close()
+
+	convs, err := store.ListConversations(ctx, args.Limit, args.Query)
+	if err != nil {
+		return "", err
+	}
+	if len(convs) == 0 {
+		return "No matching conversations.", nil
+	}
+
+	var b strings.Builder
+	for _, c := range convs {
+		fmt.Fprintf(&b, "#%d %s (%s/%s, %s)\n", c.ID, c.Title, c.Provider, c.Model, c.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return b.String(), nil
+}
+
+func mcpGetConversation(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.ID <= 0 {
+		return "", fmt.Errorf("id is required")
+	}
+
+	var b strings.Builder
+	if err := showConversation(&b, theme.Theme{}, args.ID); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func mcpAsk(ctx context.Context, raw json.RawMessage) (string, error) {
+	var args struct {
+		Prompt string `json:"prompt"`
+		System string `json:"system"`
+		Model  string `json:"model"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Prompt) == "" {
+		return "", fmt.Errorf("prompt is required")
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return "", err
+	}
+
+	req := &provider.ChatRequest{Model: args.Model}
+	if req.Model == "" {
+		req.Model = getModel()
+	}
+	if args.System != "" {
+		req.Messages = append(req.Messages, provider.Message{Role: "system", Content: args.System})
+	}
+	req.Messages = append(req.Messages, provider.Message{Role: "user", Content: args.Prompt})
+
+	response, err := collectChat(ctx, p, req)
+	if err != nil {
+		return "", err
+	}
+	logExchange(p.Name(), req.Model, args.Prompt, response)
+	return response, nil
+}
@@ -1,47 +1,276 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
-	"github.com/devaloi/ask/internal/provider"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	modelsJSONFlag       bool
+	modelsProviderFlag   string
+	modelsVisionFlag     bool
+	modelsMinContextFlag string
+	modelsVerboseFlag    bool
 )
 
 var modelsCmd = &cobra.Command{
 	Use:   "models",
 	Short: "List available models for each provider",
-	RunE:  runModels,
+	Long: `List the models of each provider, marking the default with *.
+
+Filters select models by what ask knows of them; models it knows nothing
+about pass only when no filter is given.
+
+Examples:
+  ask models
+  ask models -v --provider anthropic
+  ask models --vision --min-context 200k --json`,
+	Args: cobra.NoArgs,
+	RunE: runModels,
 }
 
 func init() {
 	rootCmd.AddCommand(modelsCmd)
+	modelsCmd.Flags().BoolVar(&modelsJSONFlag, "json", false, "Print as JSON")
+	modelsCmd.Flags().StringVar(&modelsProviderFlag, "provider", "", "List only this provider's models")
+	modelsCmd.Flags().BoolVar(&modelsVisionFlag, "vision", false, "List only models that accept images")
+	modelsCmd.Flags().StringVar(&modelsMinContextFlag, "min-context", "", "List only models with at least this context window, in tokens (e.g. 100k)")
+	modelsCmd.Flags().BoolVarP(&modelsVerboseFlag, "verbose", "v", false, "Show each model's context window, price, capabilities, and knowledge cutoff")
 }
 
-func runModels(cmd *cobra.Command, args []string) error {
-	defaultProvider := getProvider()
-	defaultModel := getModel()
+// modelProviders are the providers ask models lists.
+var modelProviders = []string{"openai", "anthropic"}
 
-	providers := []string{"openai", "anthropic"}
+// modelRow is a model as ask models lists it.
+type modelRow struct {
+	provider.ModelInfo
+	Default    bool `json:"default"`
+	Configured bool `json:"configured"` // its provider has an API key
+}
 
-	for _, name := range providers {
-		p, err := provider.New(name, cfg)
+func runModels(cmd *cobra.Command, args []string) error {
+	providers := modelProviders
+	if modelsProviderFlag != "" {
+		if !slices.Contains(modelProviders, modelsProviderFlag) {
+			return usageErrorf("unknown provider %q (want %s)", modelsProviderFlag, strings.Join(modelProviders, " or "))
+		}
+		providers = []string{modelsProviderFlag}
+	}
+	var minContext int64
+	if modelsMinContextFlag != "" {
+		n, err := util.ParseCount(modelsMinContextFlag)
 		if err != nil {
-			fmt.Printf("%s: (not configured)\n", name)
-			continue
+			return usageErrorf("invalid --min-context: %v", err)
+		}
+		minContext = n
+	}
+
+	rows := []modelRow{}
+	for _, name := range providers {
+		for _, row := range providerModels(name) {
+			if modelsVisionFlag && !row.Vision || minContext > 0 && int64(row.ContextWindow) < minContext {
+				continue
+			}
+			rows = append(rows, row)
 		}
+	}
 
-		models := p.Models()
-		fmt.Printf("%s:\n", name)
-		for _, m := range models {
+	if modelsJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range providers {
+		listed := false
+		for _, row := range rows {
+			if row.Provider != name {
+				continue
+			}
+			if !listed {
+				if row.Configured {
+					fmt.Fprintf(w, "%s:\n", name)
+				} else {
+					fmt.Fprintf(w, "%s (not configured):\n", name)
+				}
+				listed = true
+			}
 			marker := "  "
-			if name == defaultProvider && m == defaultModel {
+			if row.Default {
 				marker = "* "
 			}
-			fmt.Printf("  %s%s\n", marker, m)
+			if modelsVerboseFlag {
+				fmt.Fprintf(w, "  %s%s\t%s\n", marker, row.Name, modelDetails(row.ModelInfo))
+			} else {
+				fmt.Fprintf(w, "  %s%s\n", marker, row.Name)
+			}
+		}
+		if listed {
+			fmt.Fprintln(w)
 		}
-		fmt.Println()
 	}
+	return w.Flush()
+}
+
+// modelDetails describes m for ask models -v, with "-" for what ask does
+// not know.
+func modelDetails(m provider.ModelInfo) string {
+	window, price, cutoff := "-", "-", "-"
+	if m.ContextWindow > 0 {
+		window = formatTokenCount(m.ContextWindow)
+	}
+	if m.Price != (provider.Price{}) {
+		price = fmt.Sprintf("$%.2f/$%.2f", m.Price.Input, m.Price.Output)
+	}
+	if m.KnowledgeCutoff != "" {
+		cutoff = m.KnowledgeCutoff
+	}
+	var can []string
+	if m.Vision {
+		can = append(can, "vision")
+	}
+	if m.Tools {
+		can = append(can, "tools")
+	}
+	if len(can) == 0 {
+		can = append(can, "-")
+	}
+	return strings.Join([]string{window, price, strings.Join(can, ","), cutoff}, "\t")
+}
 
+// formatTokenCount formats n tokens briefly, e.g. 128k or 1.05M.
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1e6:
+		return fmt.Sprintf("%.3gM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.3gk", float64(n)/1e3)
+	}
+	return fmt.Sprint(n)
+}
+
+// providerModels returns the models of the provider called name: those
+// it lists, if it is configured, then the others ask knows it offers.
+func providerModels(name string) []modelRow {
+	var names []string
+	p, err := configuredProvider(name)
+	if err == nil {
+		names = p.Models()
+	}
+	for _, m := range provider.Catalog() {
+		if m.Provider == name && !slices.Contains(names, m.Name) {
+			names = append(names, m.Name)
+		}
+	}
+
+	defaultProvider, defaultModel := getProvider(), getModel()
+	rows := make([]modelRow, len(names))
+	for i, model := range names {
+		info, _ := provider.LookupModel(model)
+		info.Name, info.Provider = model, name
+		info.ContextWindow = provider.ContextWindow(model)
+		rows[i] = modelRow{
+			ModelInfo:  info,
+			Default:    name == defaultProvider && model == defaultModel,
+			Configured: err == nil,
+		}
+	}
+	return rows
+}
+
+// checkModelFlag checks the model given with --model, if any, against
+// the models p lists.
+func checkModelFlag(p provider.Provider) error {
+	if modelFlag == "" {
+		return nil
+	}
+	return checkModel(p, modelFlag)
+}
+
+// checkModel checks model against the models p lists, and those ask
+// knows of for it, including from the config file. Providers add models
+// faster than ask lists them, so an unknown model is sent anyway, with a
+// warning if it looks like a typo of a known one, unless --strict-model
+// refuses it.
+func checkModel(p provider.Provider, model string) error {
+	known := p.Models()
+	if len(known) == 0 {
+		// Any model goes, e.g. a local model file
+		return nil
+	}
+	known = slices.Clone(known)
+	for _, m := range provider.Catalog() {
+		if (m.Provider == p.Name() || m.Provider == "") && !slices.Contains(known, m.Name) {
+			known = append(known, m.Name)
+		}
+	}
+	if slices.Contains(known, model) {
+		return nil
+	}
+
+	suggestion, ok := util.Closest(model, known)
+	switch {
+	case strictModelFlag && ok:
+		return usageErrorf("unknown %s model %q; did you mean %q?", p.Name(), model, suggestion)
+	case strictModelFlag:
+		return usageErrorf("unknown %s model %q; models are %s\n\nLeave out --strict-model to send it anyway", p.Name(), model, strings.Join(known, ", "))
+	case ok:
+		fmt.Fprintf(os.Stderr, "Warning: unknown %s model %q; did you mean %q?\n", p.Name(), model, suggestion)
+	}
 	return nil
 }
+
+// applyModels tells the provider package what the config file says of
+// models, under models and context_windows, so that context windows,
+// costs, and routing use it.
+func applyModels() {
+	provider.SetModels(nil)
+	var overrides []provider.ModelInfo
+	for _, name := range slices.Sorted(maps.Keys(cfg.ContextWindows)) {
+		if _, ok := cfg.Models[name]; !ok {
+			m, _ := provider.LookupModel(name)
+			m.Name, m.ContextWindow = name, cfg.ContextWindows[name]
+			overrides = append(overrides, m)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Models)) {
+		c := cfg.Models[name]
+		m, _ := provider.LookupModel(name)
+		m.Name = name
+		if c.Provider != "" {
+			m.Provider = c.Provider
+		}
+		if c.ContextWindow > 0 {
+			m.ContextWindow = c.ContextWindow
+		} else if n := cfg.ContextWindows[name]; n > 0 {
+			m.ContextWindow = n
+		}
+		if c.InputPrice > 0 {
+			m.Price.Input = c.InputPrice
+		}
+		if c.OutputPrice > 0 {
+			m.Price.Output = c.OutputPrice
+		}
+		if c.Vision != nil {
+			m.Vision = *c.Vision
+		}
+		if c.Tools != nil {
+			m.Tools = *c.Tools
+		}
+		if c.KnowledgeCutoff != "" {
+			m.KnowledgeCutoff = c.KnowledgeCutoff
+		}
+		overrides = append(overrides, m)
+	}
+	provider.SetModels(overrides)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// pagerCommand returns the user's pager command line, from $ASK_PAGER,
+// then $PAGER, falling back to less, or more on Windows.
+func pagerCommand() []string {
+	for _, env := range []string{"ASK_PAGER", "PAGER"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"more"}
+	}
+	return []string{"less"}
+}
+
+// pageIfLong shows rendered in the pager if it is taller than the
+// terminal on stdout. The response has already been streamed, so the
+// pager is a way to scroll back through it.
+func pageIfLong(rendered string) {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height <= 0 || strings.Count(rendered, "\n")+1 < height {
+		return
+	}
+
+	pager := pagerCommand()
+	if pager[0] == "cat" {
+		return
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(rendered + "\n")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// Like git: keep colors, and leave the output on screen on exit
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: pager %s failed: %v\n", pager[0], err)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+)
+
+var personaCmd = &cobra.Command{
+	Use:   "persona",
+	Short: "Manage personas (named system prompt, model, and provider)",
+	Long: `Personas bind a name to a system prompt, model, and provider, stored in the
+config file. Use one with --as:
+
+  ask persona add reviewer -s @review.md -m claude-sonnet-4-20250514 -p anthropic
+  git diff | ask --as reviewer "Review this"
+
+A persona may also set the automatic context, with --auto-context:
+
+  ask persona add debug --auto-context git,os,cwd -s "Help debug build failures."`,
+}
+
+var personaAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or replace a persona from -s, -m, -p, and --auto-context",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPersonaAdd,
+}
+
+var personaListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List personas",
+	Args:  cobra.NoArgs,
+	RunE:  runPersonaList,
+}
+
+var personaRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a persona",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runPersonaRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(personaCmd)
+	personaCmd.AddCommand(personaAddCmd, personaListCmd, personaRemoveCmd)
+}
+
+func runPersonaAdd(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if strings.ContainsAny(name, " \t\n:") {
+		return usageErrorf("invalid persona name: %q", name)
+	}
+	if len(systemFlags) > 1 {
+		return usageErrorf("a persona takes a single -s system prompt")
+	}
+
+	p := config.Persona{Model: modelFlag, Provider: providerFlag, AutoContext: autoContextFlag}
+	if len(systemFlags) == 1 {
+		p.System = systemFlags[0]
+		// Store file references absolute so the persona works anywhere
+		if path, ok := strings.CutPrefix(p.System, "@"); ok {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("resolving %s: %w", path, err)
+			}
+			p.System = "@" + abs
+		}
+	}
+	if p.System == "" && p.Model == "" && p.Provider == "" && len(p.AutoContext) == 0 {
+		return usageErrorf("nothing to save\n\nGive the persona at least one of -s, -m, -p, or --auto-context")
+	}
+
+	if err := config.SetPersona(name, p); err != nil {
+		return fmt.Errorf("saving persona: %w", err)
+	}
+	fmt.Printf("Saved persona %q\n", name)
+	return nil
+}
+
+func runPersonaList(cmd *cobra.Command, args []string) error {
+	if len(cfg.Personas) == 0 {
+		fmt.Println("No personas. Add one with: ask persona add <name> -s <prompt> -m <model>")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Personas))
+	for name := range cfg.Personas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		p := cfg.Personas[name]
+		var details []string
+		if p.Provider != "" {
+			details = append(details, "provider: "+p.Provider)
+		}
+		if p.Model != "" {
+			details = append(details, "model: "+p.Model)
+		}
+		if len(p.AutoContext) > 0 {
+			details = append(details, "context: "+strings.Join(p.AutoContext, ","))
+		}
+		if p.System != "" {
+			details = append(details, "system: "+summarizeSystem(p.System))
+		}
+		fmt.Printf("%-16s %s\n", name, strings.Join(details, ", "))
+	}
+	return nil
+}
+
+func runPersonaRemove(cmd *cobra.Command, args []string) error {
+	found, err := config.RemovePersona(args[0])
+	if err != nil {
+		return fmt.Errorf("removing persona: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("no persona named %q", args[0])
+	}
+	fmt.Printf("Removed persona %q\n", args[0])
+	return nil
+}
+
+// summarizeSystem returns the first line of a system prompt, shortened to
+// fit on a listing line.
+func summarizeSystem(s string) string {
+	const max = 50
+	s, _, cut := strings.Cut(strings.TrimSpace(s), "\n")
+	if r := []rune(s); len(r) > max {
+		s, cut = string(r[:max]), true
+	}
+	if cut {
+		s += "..."
+	}
+	return s
+}
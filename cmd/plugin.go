@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/plugin"
+)
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List plugin commands found on PATH",
+	Long: `List plugin commands: executables named ask-<name> on PATH, which
+"ask <name> [args...]" runs, like git and kubectl plugins. ask's own
+commands take precedence.
+
+A plugin gets its arguments, the terminal, and these environment variables:
+
+  ASK_CONFIG            path of the config file
+  ASK_DATA_DIR          directory of the history database and other data
+  ASK_PROVIDER          the configured provider
+  ASK_MODEL             the configured model
+  ASK_WORKSPACE         the current workspace, if any
+  ASK_CONVERSATION_ID   the most recently active conversation in it, if any
+  ASK_EXECUTABLE        path of ask, to call it back`,
+	Args: cobra.NoArgs,
+	RunE: runPlugins,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+func runPlugins(cmd *cobra.Command, args []string) error {
+	plugins := plugin.List(os.Getenv("PATH"))
+	if len(plugins) == 0 {
+		fmt.Fprintln(os.Stderr, "No plugins found. Add an executable named ask-<name> to your PATH.")
+		return nil
+	}
+	for _, p := range plugins {
+		mark := ""
+		if shadowedByCommand(p.Name) {
+			mark = "  (shadowed by ask " + p.Name + ")"
+		}
+		fmt.Printf("%-20s %s%s\n", p.Name, p.Path, mark)
+	}
+	return nil
+}
+
+// shadowedByCommand reports whether name is one of ask's own commands.
+// help and completion are added by cobra when it executes.
+func shadowedByCommand(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	c, _, err := rootCmd.Find([]string{name})
+	return err == nil && c != rootCmd
+}
+
+// runPlugin runs the plugin named by args[0], when it is not one of ask's
+// commands and an ask-<name> executable is on PATH. It reports whether a
+// plugin ran; if not, args are ask's own.
+func runPlugin(args []string) (bool, error) {
+	if len(args) == 0 || !plugin.ValidName(args[0]) || shadowedByCommand(args[0]) {
+		return false, nil
+	}
+	path, err := plugin.Find(args[0])
+	if err != nil {
+		return false, nil
+	}
+
+	initConfig()
+	setupLogging()
+	defer closeLog()
+	slog.Debug("running plugin", "name", args[0], "path", path)
+
+	c := exec.Command(path, args[1:]...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), pluginEnv()...)
+
+	// Ctrl+C reaches the plugin, which decides how to stop
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return true, exitStatus(exitErr.ExitCode())
+	}
+	if err != nil {
+		return true, fmt.Errorf("running plugin %s: %w", args[0], err)
+	}
+	return true, nil
+}
+
+// pluginEnv returns the environment variables that tell a plugin about
+// ask's configuration and conversation context. Each is left out if it
+// cannot be determined.
+func pluginEnv() []string {
+	var env []string
+	add := func(key, value string) {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	if path, err := config.Path(); err == nil {
+		add(config.ConfigEnv, path)
+	}
+	if dir, err := config.GetDataDir(); err == nil {
+		add(config.DataDirEnv, dir)
+	}
+	add("ASK_PROVIDER", getProvider())
+	add("ASK_MODEL", getModel())
+	add("ASK_WORKSPACE", currentWorkspace())
+	if store, err := openStore(); err == nil {
+		if id, err := store.LatestConversationIDIn(context.Background(), currentWorkspace()); err == nil {
+			add("ASK_CONVERSATION_ID", strconv.FormatInt(id, 10))
+		}
+		store.Close()
+	}
+	if exe, err := os.Executable(); err == nil {
+		add("ASK_EXECUTABLE", exe)
+	}
+	return env
+}
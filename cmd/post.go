@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/devaloi/ask/internal/postprocess"
+)
+
+// postFlags are the --post steps applied to one-shot responses.
+var postFlags []string
+
+func init() {
+	rootCmd.Flags().StringArrayVar(&postFlags, "post", nil, "Pipe the complete response through a command or built-in filter (strip-markdown, code, trim) before printing and saving; repeatable")
+}
+
+// postProcess runs response through the --post steps.
+func postProcess(ctx context.Context, response string) (string, error) {
+	p := postprocess.Pipeline{Steps: postFlags, Shell: userShell(), Stderr: os.Stderr}
+	return p.Run(ctx, response)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/devaloi/ask/internal/compact"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// strictFlag refuses oversized prompts, and oversized stdin no one can
+// confirm, instead of warning about them.
+var strictFlag bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&strictFlag, "strict", false, "Refuse to send a request over prompt_warn_tokens or the model's context window, or unconfirmed stdin over max_stdin_bytes, instead of warning")
+}
+
+// promptSources are the estimated tokens of the included files and web
+// pages, and of the piped and pasted input, in a prompt buildPrompt
+// built.
+type promptSources struct {
+	files, input int
+}
+
+// lintPrompt checks the estimated size of msgs, as the context policy
+// left them, whose last message is prompt, built from sources, unless
+// prompt is empty, before they are sent to model. A request over
+// prompt_warn_tokens or the model's context window gets a warning with a
+// breakdown of where its tokens come from, or with --strict, is refused.
+func lintPrompt(msgs []provider.Message, prompt string, sources promptSources, model string) error {
+	total := compact.Size(msgs)
+	var problem string
+	if window := provider.ContextWindow(model); window > 0 && total > window {
+		problem = fmt.Sprintf("more than %s's %d-token context window", model, window)
+	} else if limit := cfg.PromptWarnTokens; limit > 0 && total > limit {
+		problem = fmt.Sprintf("more than prompt_warn_tokens (%d)", limit)
+	} else {
+		return nil
+	}
+
+	breakdown := promptBreakdown(msgs, prompt, sources)
+	if strictFlag {
+		return fmt.Errorf("the request is about %d tokens, %s:\n%s\n\nShorten the prompt, or leave out --strict to send it anyway", total, problem, breakdown)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: the request is about %d tokens, %s:\n%s\n", total, problem, breakdown)
+	return nil
+}
+
+// promptBreakdown lists the estimated tokens of each source of msgs: the
+// system prompt, earlier messages, and the parts of prompt.
+func promptBreakdown(msgs []provider.Message, prompt string, sources promptSources) string {
+	var system, earlier, last int
+	for i, m := range msgs {
+		size := compact.Size(msgs[i : i+1])
+		switch {
+		case m.Role == "system":
+			system += size
+		case i == len(msgs)-1 && prompt != "":
+			last = size
+		default:
+			earlier += size
+		}
+	}
+	files, input := sources.files, sources.input
+	rest := max(last-files-input, 0)
+
+	total := max(system+earlier+last, 1)
+	var b strings.Builder
+	for _, row := range []struct {
+		name   string
+		tokens int
+	}{
+		{"system", system},
+		{"history", earlier},
+		{"files", files},
+		{"stdin", input},
+		{"prompt", rest},
+	} {
+		if row.tokens == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-8s %8d  %3d%%\n", row.name, row.tokens, row.tokens*100/total)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
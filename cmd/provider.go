@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/devaloi/ask/internal/keyring"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// configuredProvider creates the named provider with its API key from the
+// environment, the config file, or the OS keyring.
+func configuredProvider(name string) (provider.Provider, error) {
+	apiKey, err := cfg.GetAPIKey(name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, fmt.Errorf("%s %w in the OS keyring.\n\nStore it with: ask auth set %s", name, provider.ErrNoAPIKey, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s API key: %w", name, err)
+	}
+	p, err := provider.New(name, apiKey)
+	if err == nil {
+		traceHTTP(p, apiKey)
+	}
+	if errors.Is(err, provider.ErrNoAPIKey) {
+		return nil, fmt.Errorf("%w.\n\nStore it in the OS keyring with \"ask auth set %s\", set the %s\nenvironment variable, or add it to ~/.config/ask/config.yaml:\n\n  providers:\n    %s:\n      api_key: your-key-here", err, name, apiKeyEnv[name], name)
+	}
+	return p, err
+}
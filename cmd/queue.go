@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// queueFlag saves a one-shot prompt to send later instead of sending it.
+var queueFlag bool
+
+var queueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "Send prompts saved for later",
+	Long: `Prompts can be saved to send later, for when the network or provider
+is unreachable, such as on a flight:
+
+  ask --queue "Summarize the trade-offs of CRDTs" -f notes.md
+
+When a prompt fails to send because the provider cannot be reached, ask
+also offers to queue it. "ask queue run" later sends every queued prompt
+and saves the responses to history, where "ask history" lists them.`,
+}
+
+var queueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued prompts",
+	Args:  cobra.NoArgs,
+	RunE:  runQueueList,
+}
+
+var queueRemoveCmd = &cobra.Command{
+	Use:     "remove <id>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a queued prompt without sending it",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runQueueRemove,
+}
+
+var queueRunCmd = &cobra.Command{
+	Use:   "run [id...]",
+	Short: "Send queued prompts, all of them or those given, saving the responses to history",
+	RunE:  runQueueRun,
+}
+
+func init() {
+	rootCmd.Flags().BoolVar(&queueFlag, "queue", false, `Save the prompt to send later with "ask queue run" instead of sending it`)
+	rootCmd.AddCommand(queueCmd)
+	queueCmd.AddCommand(queueListCmd, queueRemoveCmd, queueRunCmd)
+}
+
+// queuePrompt saves a one-shot prompt to send later: system and prompt
+// for a new conversation, or prompt to continue conv, searching the web
+// if --web says to.
+func queuePrompt(providerName, model, system, prompt string, conv *history.Conversation) error {
+	if titleFlag != "" || len(tagFlags) > 0 {
+		fmt.Fprintln(os.Stderr, "Warning: queued prompts do not keep --title and --tag")
+	}
+	q := &history.QueuedPrompt{Prompt: prompt, Provider: providerName, Model: model, WebSearch: webFlag}
+	if conv != nil {
+		q.ConversationID = conv.ID
+	} else {
+		q.System = system
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	if err := store.QueuePrompt(context.Background(), q); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Queued prompt %d. Send it later with: ask queue run\n", q.ID)
+	return nil
+}
+
+// offerQueue asks whether to queue a prompt that failed with err because
+// the provider could not be reached, and reports whether it was queued.
+// Without a terminal to ask on, nothing is queued.
+func offerQueue(err error, queue func() error) (bool, error) {
+	if !isUnreachable(err) {
+		return false, nil
+	}
+	if yes, ok := confirm("The provider could not be reached. Queue the prompt to send later?"); !yes || !ok {
+		return false, nil
+	}
+	return true, queue()
+}
+
+// isUnreachable reports whether err means the provider could not be
+// reached, rather than that it rejected the request.
+func isUnreachable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, provider.ErrServer)
+}
+
+func runQueueList(cmd *cobra.Command, args []string) error {
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	queue, err := store.ListQueue(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(queue) == 0 {
+		fmt.Println("No queued prompts. Queue one with: ask --queue \"your question\"")
+		return nil
+	}
+
+	fmt.Println("ID    Queued            Model                     Prompt")
+	fmt.Println("----  ----------------  ------------------------  ------------------------------")
+	for _, q := range queue {
+		what := queueLabel(q)
+		if q.ConversationID > 0 {
+			what += fmt.Sprintf(" (continues %d)", q.ConversationID)
+		}
+		fmt.Printf("%-4d  %-16s  %-24s  %s\n", q.ID, q.CreatedAt.Local().Format("2006-01-02 15:04"), util.Truncate(q.Model, 24), what)
+		if q.LastError != "" {
+			fmt.Printf("      last attempt: %s\n", util.Truncate(q.LastError, 70))
+		}
+	}
+	return nil
+}
+
+func runQueueRemove(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return usageErrorf("invalid queued prompt ID: %s", args[0])
+	}
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	if err := store.DeleteQueued(context.Background(), id); err != nil {
+		return err
+	}
+	fmt.Printf("Removed queued prompt %d\n", id)
+	return nil
+}
+
+func runQueueRun(cmd *cobra.Command, args []string) error {
+	var ids []int64
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return usageErrorf("invalid queued prompt ID: %s", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	queue, err := store.ListQueue(ctx)
+	if err != nil {
+		return err
+	}
+	var run []history.QueuedPrompt
+	for _, id := range ids {
+		i := slices.IndexFunc(queue, func(q history.QueuedPrompt) bool { return q.ID == id })
+		if i < 0 {
+			return fmt.Errorf("queued prompt %d not found", id)
+		}
+		run = append(run, queue[i])
+	}
+	if len(ids) == 0 {
+		run = queue
+	}
+	if len(run) == 0 {
+		fmt.Fprintln(os.Stderr, "No queued prompts.")
+		return nil
+	}
+
+	sent, failed := 0, 0
+	for i, q := range run {
+		err := sendQueued(ctx, q)
+		if err == nil {
+			// Even if interrupted now, the prompt was sent; it must not be
+			// sent again
+			if err := store.DeleteQueued(context.Background(), q.ID); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Sent queued prompt %d (%s)\n", q.ID, queueLabel(q))
+			sent++
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failed++
+		fmt.Fprintf(os.Stderr, "Error: queued prompt %d (%s): %v\n", q.ID, queueLabel(q), err)
+		if err := store.SetQueueError(ctx, q.ID, err.Error()); err != nil {
+			return err
+		}
+		if isUnreachable(err) {
+			// The rest would fail the same way
+			failed += len(run) - i - 1
+			break
+		}
+	}
+	if sent > 0 {
+		fmt.Fprintln(os.Stderr, "Responses are saved in history: ask history")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queued prompts were not sent and stay queued", failed, len(run))
+	}
+	return nil
+}
+
+// sendQueued sends a queued prompt and saves the exchange to history.
+func sendQueued(ctx context.Context, q history.QueuedPrompt) error {
+	p, err := newProvider(q.Provider)
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+
+	var conv *history.Conversation
+	var pending []history.Message
+	if q.ConversationID > 0 {
+		if conv, err = loadConversation(q.ConversationID); err != nil {
+			return err
+		}
+		pending = append(pending, conv.Messages...)
+	} else if q.System != "" {
+		pending = append(pending, history.Message{Role: "system", Content: q.System})
+	}
+	pending = append(pending, history.Message{Role: "user", Content: q.Prompt})
+
+	messages, err := contextMessages(ctx, p, q.Model, q.ConversationID, pending)
+	if err != nil {
+		return err
+	}
+	response, err := collectChat(ctx, p, &provider.ChatRequest{Messages: messages, Model: q.Model, WebSearch: q.WebSearch})
+	if err != nil {
+		return err
+	}
+	logExchange(p.Name(), q.Model, q.Prompt, response)
+	if err := saveToHistory(p.Name(), q.Model, messages, response, conv); err != nil {
+		return fmt.Errorf("saving to history: %w", err)
+	}
+	return nil
+}
+
+// queueLabel names a queued prompt by its first line.
+func queueLabel(q history.QueuedPrompt) string {
+	first, _, _ := strings.Cut(strings.TrimSpace(q.Prompt), "\n")
+	return util.Truncate(first, 40)
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/redact"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// redactFlag overrides redact from the config file.
+var redactFlag string
+
+func init() {
+	rootCmd.Flags().StringVar(&redactFlag, "redact", "", "When a request contains secrets such as API keys: warn, mask, block, or off")
+}
+
+// redactModes lists the valid redact values.
+var redactModes = []string{config.RedactWarn, config.RedactMask, config.RedactBlock, config.RedactOff}
+
+// getRedactMode returns the redact mode from --redact or the config
+// file. The flag is validated up front, in runChat.
+func getRedactMode() string {
+	if redactFlag != "" {
+		return redactFlag
+	}
+	if cfg.Redact == "" {
+		return config.RedactWarn
+	}
+	if !slices.Contains(redactModes, cfg.Redact) {
+		fmt.Fprintf(os.Stderr, "Warning: invalid redact %q (want %s), using warn\n", cfg.Redact, strings.Join(redactModes, ", "))
+		return config.RedactWarn
+	}
+	return cfg.Redact
+}
+
+// redacted is a provider whose requests are checked for secrets before
+// they are sent, which are reported, masked, or refused by mode.
+type redacted struct {
+	provider.Provider
+	mode string
+}
+
+// redactSecrets wraps p to check every message of its requests for
+// secrets: the prompt, and also system prompts, context, tool results,
+// and the input of commands other than chat.
+func redactSecrets(p provider.Provider, mode string) provider.Provider {
+	return &redacted{Provider: p, mode: mode}
+}
+
+func (r *redacted) Chat(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+	req, err := r.redact(req)
+	if err != nil {
+		close(stream)
+		return err
+	}
+	return r.Provider.Chat(ctx, req, stream)
+}
+
+// BuildRequest builds the request as sent, so --dry-run shows what is
+// masked.
+func (r *redacted) BuildRequest(ctx context.Context, req *provider.ChatRequest) (*http.Request, error) {
+	req, err := r.redact(req)
+	if err != nil {
+		return nil, err
+	}
+	return r.Provider.BuildRequest(ctx, req)
+}
+
+// Unwrap returns the provider r wraps.
+func (r *redacted) Unwrap() provider.Provider {
+	return r.Provider
+}
+
+// reportedSecrets holds the secrets already reported, guarded by
+// reportedMu, so a conversation that sends one with every request is
+// told about it once.
+var (
+	reportedMu      sync.Mutex
+	reportedSecrets = make(map[string]bool)
+)
+
+// redact looks for secrets in req's messages and, by the redact mode,
+// warns about them, returns a copy of req with them masked, or refuses
+// to send it.
+func (r *redacted) redact(req *provider.ChatRequest) (*provider.ChatRequest, error) {
+	out := *req
+	out.Messages = slices.Clone(req.Messages)
+	var all, unreported []string
+	count := 0
+	for i, m := range out.Messages {
+		found := redact.Find(m.Content)
+		if len(found) == 0 {
+			continue
+		}
+		all = append(all, redact.Describe(found))
+		if fresh := markReported(m.Content, found); len(fresh) > 0 {
+			unreported = append(unreported, redact.Describe(fresh))
+			count += len(fresh)
+		}
+		if r.mode == config.RedactMask {
+			out.Messages[i].Content = redact.Mask(m.Content, found)
+		}
+	}
+	if len(all) == 0 {
+		return req, nil
+	}
+
+	switch r.mode {
+	case config.RedactBlock:
+		return nil, fmt.Errorf("the request contains what look like secrets: %s\n\nRemove them, or use --redact mask to send them masked", strings.Join(all, ", "))
+	case config.RedactMask:
+		if count > 0 {
+			fmt.Fprintf(os.Stderr, "Masked %d secret(s) in the request: %s\n", count, strings.Join(unreported, ", "))
+		}
+	default:
+		if count > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: the request contains what look like secrets: %s (use --redact mask to mask them)\n", strings.Join(unreported, ", "))
+		}
+	}
+	return &out, nil
+}
+
+// markReported returns those of found, secrets in text, not reported
+// before, and records them as reported.
+func markReported(text string, found []redact.Finding) []redact.Finding {
+	reportedMu.Lock()
+	defer reportedMu.Unlock()
+	var fresh []redact.Finding
+	for _, f := range found {
+		if secret := text[f.Start:f.End]; !reportedSecrets[secret] {
+			reportedSecrets[secret] = true
+			fresh = append(fresh, f)
+		}
+	}
+	return fresh
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <id>",
+	Short: "Re-send a conversation's prompts to another model",
+	Long: `Re-send the user turns of a conversation in history, one at a time, to
+the model given with -m (or the default model), and its provider, and save
+the answers as a new conversation. The original conversation is left as it was.
+
+Each turn is sent with the replayed answers to the turns before it, not
+the original ones, so the new conversation is the one the model would
+have had. System prompts are kept.
+
+Examples:
+  ask replay 42 -m claude-sonnet-4-20250514
+  ask replay 42 -p openai -m gpt-4o`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return usageErrorf("invalid conversation ID: %s", args[0])
+	}
+	orig, err := loadConversation(id)
+	if err != nil {
+		return err
+	}
+	turns := 0
+	for _, msg := range orig.Messages {
+		if msg.Role == "user" {
+			turns++
+		}
+	}
+	if turns == 0 {
+		return fmt.Errorf("conversation %d has no prompts to replay", id)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	providers, err := configuredProviders()
+	if err != nil {
+		return err
+	}
+	model := getModel()
+	p := providers[0]
+	if providerFlag == "" {
+		// -m alone is sent to the provider that offers the model
+		p = providerForModel(providers, model)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+
+	conv := &history.Conversation{
+		Title:     util.Truncate(fmt.Sprintf("Replay of #%d: %s", orig.ID, orig.Title), util.MaxTitleLength),
+		Model:     model,
+		Provider:  p.Name(),
+		Workspace: currentWorkspace(),
+	}
+	stdoutIsTerminal := term.IsTerminal(int(os.Stdout.Fd()))
+	th := getTheme(os.Stdout)
+	fmt.Fprintf(os.Stderr, "Replaying conversation %d (%s) with %s\n", orig.ID, orig.Model, model)
+
+	// Only the prompts are replayed; the original answers are dropped
+	var replayed []history.Message
+	turn := 0
+	for _, msg := range orig.Messages {
+		if msg.Role == "system" {
+			replayed = append(replayed, history.Message{Role: msg.Role, Content: msg.Content})
+		}
+		if msg.Role != "user" {
+			continue
+		}
+		turn++
+		replayed = append(replayed, history.Message{Role: "user", Content: msg.Content})
+		fmt.Println(th.User.Render(fmt.Sprintf("[You, %d of %d]", turn, turns)))
+		fmt.Println(msg.Content)
+		fmt.Println()
+
+		messages, err := contextMessages(ctx, p, model, conv.ID, replayed)
+		if err != nil {
+			return replayStopped(conv, turn, turns, err)
+		}
+		writer := stream.NewWriter(os.Stdout, stdoutIsTerminal)
+		writer.SetCodeStyle(string(th.Code))
+		writer.SetStyles(th.Streams())
+		_ = writer.Prefix("[Assistant]")
+		response, err := streamChat(ctx, p, &provider.ChatRequest{Messages: messages, Model: model}, writer)
+		if err != nil {
+			return replayStopped(conv, turn, turns, err)
+		}
+		_ = writer.Separator()
+		logExchange(p.Name(), model, msg.Content, response)
+
+		replayed = append(replayed, history.Message{Role: "assistant", Content: response})
+		conv.Messages = replayed
+		if err := withStore(func(store history.Store) error {
+			_, err := store.SaveConversation(context.Background(), conv)
+			return err
+		}); err != nil {
+			return fmt.Errorf("saving to history: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Saved the replay as conversation %d. Compare: ask show %d; ask show %d\n", conv.ID, orig.ID, conv.ID)
+	return nil
+}
+
+// replayStopped reports how far a replay got before err stopped it.
+func replayStopped(conv *history.Conversation, turn, turns int, err error) error {
+	if conv.ID != 0 {
+		fmt.Fprintf(os.Stderr, "The first %d of %d turns are saved as conversation %d.\n", turn-1, turns, conv.ID)
+	}
+	return fmt.Errorf("turn %d: %w", turn, err)
+}
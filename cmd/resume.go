@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/picker"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+)
+
+var (
+	resumeLimitFlag int
+	resumeAllFlag   bool
+)
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Pick a recent conversation and continue it",
+	Long: `Open a fuzzy-searchable list of recent conversations and continue the
+selected one in interactive mode.
+
+Type to filter by title, model, or message preview. Use the arrow keys (or
+Ctrl+N/Ctrl+P) to move, Enter to select, and Esc to cancel.
+
+In a workspace (see --workspace), only its conversations are listed; use
+--all for every conversation.`,
+	Args: cobra.NoArgs,
+	RunE: runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	resumeCmd.Flags().IntVar(&resumeLimitFlag, "limit", util.DefaultResumeLimit, "Maximum number of conversations to list")
+	resumeCmd.Flags().BoolVar(&resumeAllFlag, "all", false, "List conversations in every workspace")
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return usageErrorf("resume requires a terminal\n\nUse: ask --continue <id>")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+
+	workspace := currentWorkspace()
+	if resumeAllFlag {
+		workspace = ""
+	}
+	summaries, err := store.ListConversationsIn(context.Background(), workspace, resumeLimitFlag, "")
+	if err != nil {
+		store.Close()
+		return fmt.Errorf("listing conversations: %w", err)
+	}
+	if len(summaries) == 0 {
+		store.Close()
+		fmt.Fprintln(os.Stderr, "No conversations yet. Start chatting with: ask \"your question\"")
+		return nil
+	}
+
+	// Load full conversations for their previews
+	convs := make([]*history.Conversation, 0, len(summaries))
+	items := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		conv, err := store.GetConversation(context.Background(), summary.ID)
+		if err != nil {
+			store.Close()
+			return fmt.Errorf("loading conversation %d: %w", summary.ID, err)
+		}
+		convs = append(convs, conv)
+		items = append(items, resumeItem(conv, time.Now()))
+	}
+	store.Close()
+
+	index, err := picker.Pick(os.Stdin, os.Stdout, "resume> ", items)
+	if errors.Is(err, picker.ErrCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	continued = convs[index]
+	return runInteractive(continued)
+}
+
+// resumeItem formats a conversation as a single picker line.
+func resumeItem(conv *history.Conversation, now time.Time) string {
+	var preview string
+	for i := len(conv.Messages) - 1; i >= 0; i-- {
+		if conv.Messages[i].Role != "system" {
+			preview = conv.Messages[i].Content
+			break
+		}
+	}
+
+	return fmt.Sprintf("#%-4d %-*s %8s  %s — %s",
+		conv.ID,
+		util.MaxModelDisplay, util.Truncate(conv.Model, util.MaxModelDisplay),
+		formatAge(now.Sub(conv.CreatedAt)),
+		util.Truncate(conv.Title, util.MaxTitleDisplay),
+		util.Truncate(preview, util.MaxPreviewDisplay),
+	)
+}
+
+// formatAge renders a duration as a compact relative age like "5m ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	default:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/24/30))
+	}
+}
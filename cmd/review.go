@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/review"
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const reviewSystemPrompt = `You are a senior engineer reviewing a diff.
+
+Report only real problems and worthwhile improvements: bugs, security issues,
+race conditions, error handling gaps, missing tests, and unclear code. Do not
+restate what the diff does or praise it. Refer to line numbers in the new
+version of each file.
+
+Format the review in Markdown: a one-paragraph summary, then a "### <file>"
+heading per file with findings as bullets, each starting with its severity
+(**error**, **warning**, or **suggestion**) and line number. If there is
+nothing to report, say so in the summary and stop.`
+
+const reviewJSONSystemPrompt = `You are a senior engineer reviewing a diff.
+
+Report only real problems and worthwhile improvements: bugs, security issues,
+race conditions, error handling gaps, missing tests, and unclear code. Do not
+restate what the diff does or praise it.
+
+Reply with only a JSON object, no code fences or commentary:
+{"summary": "<one paragraph>", "findings": [{"file": "<path>", "line": <line
+in the new version, or 0>, "severity": "error" | "warning" | "suggestion",
+"message": "<finding>"}]}`
+
+var (
+	reviewStagedFlag bool
+	reviewBranchFlag string
+	reviewJSONFlag   bool
+)
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [file.diff]",
+	Short: "Review a git diff",
+	Long: `Review a diff and report findings grouped by file.
+
+The diff is read from, in order of precedence: a diff file argument ("-" for
+stdin), --staged, --branch, piped stdin, or uncommitted changes ('git diff
+HEAD').
+
+With --json, findings are printed as a JSON object for CI annotation:
+  {"summary": "...", "findings": [{"file", "line", "severity", "message"}]}
+
+Examples:
+  ask review
+  ask review --staged
+  ask review --branch main
+  gh pr diff 42 | ask review --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReview,
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+	reviewCmd.Flags().BoolVar(&reviewStagedFlag, "staged", false, "Review staged changes")
+	reviewCmd.Flags().StringVar(&reviewBranchFlag, "branch", "", "Review changes since branching from this branch")
+	reviewCmd.Flags().BoolVar(&reviewJSONFlag, "json", false, "Print findings as JSON")
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if reviewStagedFlag && reviewBranchFlag != "" {
+		return usageErrorf("--staged and --branch cannot be used together")
+	}
+
+	diff, err := readReviewDiff(args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return fmt.Errorf("no changes to review")
+	}
+	if max := cfg.MaxIncludeBytes; max > 0 && int64(len(diff)) > max {
+		return fmt.Errorf("diff is %d bytes, exceeding the %d byte limit\n\nReview a narrower diff or raise max_include_bytes in ~/.config/ask/config.yaml", len(diff), max)
+	}
+
+	systemPrompt := reviewSystemPrompt
+	if reviewJSONFlag {
+		systemPrompt = reviewJSONSystemPrompt
+	}
+	if len(systemFlags) > 0 {
+		systemPrompt, err = joinSystemPrompts(systemFlags)
+		if err != nil {
+			return fmt.Errorf("resolving system prompt: %w", err)
+		}
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+	if err := checkModelFlag(p); err != nil {
+		return err
+	}
+
+	req := &provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: "```diff\n" + diff + "\n```"},
+		},
+		Model: getModel(),
+	}
+
+	if !reviewJSONFlag {
+		writer := stream.NewWriter(os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+		writer.SetCodeStyle(string(getTheme(os.Stdout).Code))
+		_, err := streamChat(ctx, p, req, writer)
+		return err
+	}
+
+	// Collect silently, then validate and normalize the JSON
+	response, err := streamChat(ctx, p, req, stream.NewWriter(io.Discard, true))
+	if err != nil {
+		return err
+	}
+	report, err := review.Parse(response)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// readReviewDiff returns the diff selected by args and flags.
+func readReviewDiff(args []string) (string, error) {
+	switch {
+	case len(args) == 1 && args[0] == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return string(data), nil
+	case len(args) == 1:
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return "", fmt.Errorf("reading diff: %w", err)
+		}
+		return string(data), nil
+	case reviewStagedFlag:
+		return gitDiff("--cached")
+	case reviewBranchFlag != "":
+		return gitDiff(reviewBranchFlag + "...HEAD")
+	case !term.IsTerminal(int(os.Stdin.Fd())):
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return string(data), nil
+	default:
+		return gitDiff("HEAD")
+	}
+}
+
+// gitDiff runs git diff with args and returns its output.
+func gitDiff(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"diff"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// git can follow its error with a full usage message
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return "", fmt.Errorf("git diff: %s", msg)
+		}
+		return "", fmt.Errorf("git diff: %w", err)
+	}
+	return string(out), nil
+}
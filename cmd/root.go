@@ -2,23 +2,40 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/logging"
+	"github.com/devaloi/ask/internal/theme"
+	"github.com/devaloi/ask/internal/version"
+	"github.com/devaloi/ask/pkg/ask/provider"
 )
 
 var (
 	cfg *config.Config
 
 	// Global flags
-	providerFlag string
-	modelFlag    string
-	systemFlag   string
+	providerFlag        string
+	modelFlag           string
+	strictModelFlag     bool
+	systemFlags         []string
+	noDefaultSystemFlag bool
+	notifyFlag          bool
+	verboseFlag         bool
+	personaFlag         string
+	logLevelFlag        string
+	configFlag          string
 )
 
+// closeLog closes the diagnostic log file, if one is open.
+var closeLog = func() error { return nil }
+
 var rootCmd = &cobra.Command{
 	Use:   "ask [prompt]",
 	Short: "Chat with LLMs from your terminal",
@@ -35,48 +52,215 @@ Examples:
   ask                             # interactive mode
 
 Configuration:
-  Config file: ~/.config/ask/config.yaml
-  Environment: OPENAI_API_KEY, ANTHROPIC_API_KEY, ASK_PROVIDER, ASK_MODEL`,
+  Config file: ~/.config/ask/config.yaml, or --config or ASK_CONFIG
+  Data:        ~/.local/share/ask, or ASK_DATA_DIR
+  Environment: OPENAI_API_KEY, ANTHROPIC_API_KEY, ASK_PROVIDER, ASK_MODEL,
+               and ASK_<SETTING> for any setting, e.g. ASK_CACHE_TTL`,
+	Args:          cobra.ArbitraryArgs,
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	RunE:          runChat,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Flags and arguments have been validated by this point
+		commandStarted = true
+
+		if _, ok := cfg.Personas[personaFlag]; personaFlag != "" && !ok {
+			return usageErrorf("unknown persona: %s\n\nList personas with: ask persona list", personaFlag)
+		}
+		if logLevelFlag != "" {
+			if _, err := logging.ParseLevel(logLevelFlag); err != nil {
+				return usageError{err}
+			}
+		}
+		migrateDataDir()
+		setupLogging()
+		if err := setupDebugHTTP(); err != nil {
+			return err
+		}
+		slog.Debug("starting", "command", cmd.CommandPath(), "version", version.Get().Version)
+		return nil
+	},
+	RunE: runChat,
 }
 
-// Execute runs the root command.
+// commandStarted reports whether a command got past flag and argument
+// validation; errors before that are usage errors.
+var commandStarted bool
+
+// Execute runs the root command. Use ExitCode to map its error to an
+// exit status.
 func Execute() error {
-	return rootCmd.Execute()
+	if ran, err := runPlugin(os.Args[1:]); ran {
+		return err
+	}
+
+	// Windows consoles only process escape sequences when asked
+	theme.EnableVirtualTerminal(os.Stdout)
+	theme.EnableVirtualTerminal(os.Stderr)
+
+	err := rootCmd.Execute()
+	if err != nil {
+		slog.Debug("command failed", "error", err)
+	}
+	_ = closeLog()
+	_ = closeDebugHTTP()
+	if err != nil && !commandStarted {
+		return usageError{err}
+	}
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.Version = version.Get().Version
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err}
+	})
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVarP(&providerFlag, "provider", "p", "", "LLM provider (openai, anthropic)")
+	rootCmd.PersistentFlags().StringVarP(&providerFlag, "provider", "p", "", "LLM provider (openai, anthropic, local)")
 	rootCmd.PersistentFlags().StringVarP(&modelFlag, "model", "m", "", "Model to use")
-	rootCmd.PersistentFlags().StringVarP(&systemFlag, "system", "s", "", "System prompt (or @filepath)")
+	rootCmd.PersistentFlags().BoolVar(&strictModelFlag, "strict-model", false, "Refuse a --model the provider does not list, instead of sending it")
+	rootCmd.PersistentFlags().StringArrayVarP(&systemFlags, "system", "s", nil, "System prompt (or @filepath); repeatable, added to default_system")
+	rootCmd.PersistentFlags().BoolVar(&noDefaultSystemFlag, "no-default-system", false, "Do not use default_system from the config file")
+	rootCmd.PersistentFlags().BoolVar(&notifyFlag, "notify", false, "Send a desktop notification when a response completes")
+	rootCmd.PersistentFlags().StringVar(&personaFlag, "as", "", "Use a persona's system prompt, model, and provider")
+	rootCmd.PersistentFlags().BoolVar(&verboseFlag, "verbose", false, "Report the time to first token and tokens per second after each response")
+	rootCmd.PersistentFlags().StringVar(&logLevelFlag, "log-level", "", "Write diagnostics at this level: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Read the config from this `file` (default $ASK_CONFIG or ~/.config/ask/config.yaml)")
 }
 
 func initConfig() {
+	config.SetPath(configFlag)
 	var err error
 	cfg, err = config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "warning: config load failed: %v, using defaults\n", err)
 		cfg = config.DefaultConfig()
 	}
+	cfg.Passphrase = askPassphrase
+	applyModels()
 }
 
-// getProvider returns the provider name to use, applying flag/env/config precedence.
+// setupLogging configures the diagnostic log from --log-level and the
+// config file. Problems are warnings; logging is never worth failing a
+// command over.
+func setupLogging() {
+	opts := logging.Options{Level: cfg.LogLevel}
+	if logLevelFlag != "" {
+		opts.Level = logLevelFlag
+	}
+	if cfg.DebugLog {
+		stateDir, err := config.GetStateDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: debug log unavailable: %v\n", err)
+		} else {
+			opts.File = filepath.Join(stateDir, "debug.log")
+		}
+	}
+
+	closeFn, err := logging.Setup(opts, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		opts.Level = ""
+		closeFn, _ = logging.Setup(opts, os.Stderr)
+	}
+	closeLog = closeFn
+}
+
+// migrateDataDir moves history and other data kept beside the config
+// file by earlier versions to the data directory, saying so.
+func migrateDataDir() {
+	from, err := config.MigrateDataDir()
+	if from == "" {
+		return
+	}
+	if errors.Is(err, config.ErrDataInUse) {
+		fmt.Fprintf(os.Stderr, "Note: not moving history and other data from %s yet, since another ask process may be using it; it will be moved on a later run\n", from)
+		return
+	}
+	dataDir, _ := config.GetDataDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\nMove the rest of the history and other data in %s to %s yourself.\n", err, from, dataDir)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Moved history and other data from %s to %s\n", from, dataDir)
+}
+
+// getProvider returns the provider name to use, applying
+// flag/route/persona/conversation/env/config precedence.
 func getProvider() string {
 	if providerFlag != "" {
 		return providerFlag
 	}
+	if routedProvider != "" {
+		return routedProvider
+	}
+	if p := getPersona(); p.Provider != "" {
+		return p.Provider
+	}
+	if p := continuedProvider(); p != "" {
+		return p
+	}
 	return cfg.DefaultProvider
 }
 
-// getModel returns the model to use, applying flag/env/config precedence.
+// getModel returns the model to use, applying
+// flag/route/persona/conversation/env/config precedence. The continued
+// conversation's model applies only with its provider. From the config,
+// it is default_model for the default provider, or the provider's own
+// default_model, or else its built-in default.
 func getModel() string {
 	if modelFlag != "" {
 		return modelFlag
 	}
+	if routedModel != "" {
+		return routedModel
+	}
+	if p := getPersona(); p.Model != "" {
+		return p.Model
+	}
+	name := getProvider()
+	if continued != nil && continued.Model != "" && name == continued.Provider {
+		return continued.Model
+	}
+	if m := cfg.ModelFor(name); m != "" {
+		return m
+	}
+	if m := provider.DefaultModel(name); m != "" {
+		return m
+	}
 	return cfg.DefaultModel
 }
+
+// continuedProvider returns the provider of the conversation being
+// continued, or "" if there is none or the model chosen with -m or --as
+// is known to be another provider's.
+func continuedProvider() string {
+	if continued == nil || continued.Provider == "" {
+		return ""
+	}
+	model := modelFlag
+	if model == "" {
+		model = getPersona().Model
+	}
+	if m, ok := provider.LookupModel(model); ok && m.Provider != "" && m.Provider != continued.Provider {
+		return ""
+	}
+	return continued.Provider
+}
+
+// getPersona returns the persona selected with --as, or an empty one.
+func getPersona() config.Persona {
+	return cfg.Personas[personaFlag]
+}
+
+// getTheme returns the configured color theme for output written to f.
+// It is empty when f is not a terminal or NO_COLOR is set.
+func getTheme(f *os.File) theme.Theme {
+	th, err := theme.New(cfg.Theme, theme.ColorEnabled(f))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid theme: %v\n", err)
+		return theme.Theme{}
+	}
+	return th
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/devaloi/ask/internal/routing"
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	// routeFlag turns routing by the config file's routes on or off.
+	routeFlag string
+	// taskFlag describes the request for routing.
+	taskFlag string
+)
+
+// The model and provider picked by routeRequest, if any.
+var routedModel, routedProvider string
+
+func init() {
+	rootCmd.Flags().StringVar(&routeFlag, "route", "on", "Pick the model by the routes in the config file: on or off")
+	rootCmd.Flags().StringVar(&taskFlag, "task", "", "Describe the request for routing, e.g. review or summarize")
+}
+
+// routeRequest picks the model for a one-shot request of systemPrompt and
+// prompt by the config file's routes. Routes do not apply when a model or
+// provider was chosen with -m, -p, or --as, when continuing a
+// conversation, which keeps its model, or with --route off. A route
+// without a provider sends a model ask knows to that model's provider.
+func routeRequest(systemPrompt, prompt string) error {
+	routedModel, routedProvider = "", ""
+	switch routeFlag {
+	case "on":
+	case "off":
+		return nil
+	default:
+		return usageErrorf("invalid --route %q (want on or off)", routeFlag)
+	}
+	if len(cfg.Routes) == 0 || modelFlag != "" || providerFlag != "" || continued != nil {
+		return nil
+	}
+	if p := getPersona(); p.Model != "" || p.Provider != "" {
+		return nil
+	}
+
+	for i, r := range cfg.Routes {
+		if r.Model == "" {
+			fmt.Fprintf(os.Stderr, "Warning: route %d has no model, ignoring it\n", i+1)
+		}
+	}
+	req := routing.Request{
+		Tokens: tokens.Estimate(systemPrompt) + tokens.Estimate(prompt),
+		Code:   routing.HasCode(prompt),
+		Task:   taskFlag,
+	}
+	i, ok := routing.Match(cfg.Routes, req)
+	if !ok {
+		return nil
+	}
+	route := cfg.Routes[i]
+	routedModel, routedProvider = route.Model, route.Provider
+	if routedProvider == "" {
+		// A known model goes to its own provider
+		if m, ok := provider.LookupModel(route.Model); ok {
+			routedProvider = m.Provider
+		}
+	}
+	slog.Info("routed request", "route", i+1, "provider", getProvider(), "model", getModel(), "tokens", req.Tokens, "code", req.Code)
+	return nil
+}
@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/cron"
+	"github.com/devaloi/ask/internal/templates"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	scheduleOutFlag   string
+	scheduleWatchFlag bool
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Send saved prompts on a schedule",
+	Long: `Schedules send a prompt or template on a cron schedule, saving each
+response to history and, with --out, appending it to a file:
+
+  ask schedule add "0 9 * * 1" -t weekly-summary --out ~/reports/weekly.md
+  ask schedule add @daily "What happened in the Go community yesterday?"
+
+Schedules have five fields: minute, hour, day of month, month, and day of
+week, in local time. "ask schedule run" sends the prompts that are due, so
+run it every minute from cron:
+
+  * * * * * ask schedule run
+
+or keep "ask schedule run --watch" running, for example as a systemd
+user service. A schedule that was missed runs once when next checked.`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <schedule> [prompt]",
+	Short: "Add a scheduled prompt, from -t and/or the prompt argument",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runScheduleAdd,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List scheduled prompts",
+	Args:  cobra.NoArgs,
+	RunE:  runScheduleList,
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:     "remove <id>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a scheduled prompt",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runScheduleRemove,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run [id...]",
+	Short: "Send the scheduled prompts that are due, or those given now",
+	RunE:  runScheduleRun,
+}
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd, scheduleListCmd, scheduleRemoveCmd, scheduleRunCmd)
+	scheduleAddCmd.Flags().StringVarP(&templateFlag, "template", "t", "", "Send this saved template, followed by any prompt")
+	scheduleAddCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a template variable, as name=value (repeatable)")
+	scheduleAddCmd.Flags().StringVarP(&scheduleOutFlag, "out", "o", "", "Also append responses to this file")
+	scheduleRunCmd.Flags().BoolVar(&scheduleWatchFlag, "watch", false, "Keep running, sending prompts as they fall due")
+}
+
+func runScheduleAdd(cmd *cobra.Command, args []string) error {
+	spec := args[0]
+	s, err := cron.Parse(spec)
+	if err != nil {
+		return usageErrorf("invalid schedule %q: %v", spec, err)
+	}
+	sched := &history.Schedule{
+		Spec:     spec,
+		Template: templateFlag,
+		Prompt:   strings.Join(args[1:], " "),
+		Provider: getProvider(),
+		Model:    getModel(),
+	}
+	if sched.Template == "" && strings.TrimSpace(sched.Prompt) == "" {
+		return usageErrorf("nothing to send\n\nGive a prompt, a template with -t, or both")
+	}
+	if sched.Template != "" {
+		text, err := loadTemplate(sched.Template)
+		if err != nil {
+			return err
+		}
+		// Variables are settled now, as runs have no one to ask
+		if sched.Vars, err = templateVars(); err != nil {
+			return err
+		}
+		if _, err := fillTemplate(sched.Template, text, sched.Vars); err != nil {
+			return err
+		}
+	} else if len(varFlags) > 0 {
+		return usageErrorf("--var needs a template (-t)")
+	}
+	if scheduleOutFlag != "" {
+		// Scheduled runs may start anywhere
+		if sched.Output, err = filepath.Abs(scheduleOutFlag); err != nil {
+			return fmt.Errorf("resolving %s: %w", scheduleOutFlag, err)
+		}
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	if err := store.AddSchedule(context.Background(), sched); err != nil {
+		return err
+	}
+	fmt.Printf("Added schedule %d, next run %s\n", sched.ID, formatNextRun(s.Next(time.Now())))
+	return nil
+}
+
+func runScheduleList(cmd *cobra.Command, args []string) error {
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	schedules, err := store.ListSchedules(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		fmt.Println("No schedules. Add one with: ask schedule add \"0 9 * * 1\" -t <template>")
+		return nil
+	}
+
+	fmt.Println("ID    Schedule          Next run          Last run          Prompt")
+	fmt.Println("----  ----------------  ----------------  ----------------  ------------------------------")
+	for _, sched := range schedules {
+		next := "invalid"
+		if s, err := cron.Parse(sched.Spec); err == nil {
+			next = formatNextRun(s.Next(time.Now()))
+		}
+		last := "never"
+		if !sched.LastRun.IsZero() {
+			last = sched.LastRun.Local().Format("2006-01-02 15:04")
+		}
+		what := scheduleLabel(sched)
+		if sched.Output != "" {
+			what += " -> " + sched.Output
+		}
+		fmt.Printf("%-4d  %-16s  %-16s  %-16s  %s\n", sched.ID, util.Truncate(sched.Spec, 16), next, last, what)
+	}
+	return nil
+}
+
+func runScheduleRemove(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || id <= 0 {
+		return usageErrorf("invalid schedule ID: %s", args[0])
+	}
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	if err := store.DeleteSchedule(context.Background(), id); err != nil {
+		return err
+	}
+	fmt.Printf("Removed schedule %d\n", id)
+	return nil
+}
+
+func runScheduleRun(cmd *cobra.Command, args []string) error {
+	var ids []int64
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return usageErrorf("invalid schedule ID: %s", arg)
+		}
+		ids = append(ids, id)
+	}
+	if scheduleWatchFlag && len(ids) > 0 {
+		return usageErrorf("--watch runs every schedule; give no IDs")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if !scheduleWatchFlag {
+		return runSchedules(ctx, ids, time.Now())
+	}
+	for {
+		if err := runSchedules(ctx, nil, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		// Wake at the start of the next minute, when schedules fall due
+		if err := provider.Sleep(ctx, time.Until(time.Now().Truncate(time.Minute).Add(time.Minute))); err != nil {
+			return nil
+		}
+	}
+}
+
+// runSchedules sends the schedules with ids, or if there are none, every
+// schedule that is due at now.
+func runSchedules(ctx context.Context, ids []int64, now time.Time) error {
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening history store: %w", err)
+	}
+	defer store.Close()
+	schedules, err := store.ListSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(schedules, func(s history.Schedule) bool { return s.ID == id }) {
+			return fmt.Errorf("schedule %d not found", id)
+		}
+	}
+
+	var run []history.Schedule
+	for _, sched := range schedules {
+		if len(ids) == 0 && !scheduleDue(sched, now) {
+			continue
+		}
+		if len(ids) > 0 && !slices.Contains(ids, sched.ID) {
+			continue
+		}
+		// Recorded before sending, so that a slow or failing prompt is
+		// not sent again by the next check
+		if err := store.SetScheduleRun(ctx, sched.ID, now); err != nil {
+			return err
+		}
+		run = append(run, sched)
+	}
+
+	failed := 0
+	for _, sched := range run {
+		if err := runSchedule(ctx, sched, now); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fmt.Fprintf(os.Stderr, "Error: schedule %d (%s): %v\n", sched.ID, scheduleLabel(sched), err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Ran schedule %d (%s)\n", sched.ID, scheduleLabel(sched))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d scheduled prompts failed", failed, len(run))
+	}
+	return nil
+}
+
+// scheduleDue reports whether sched should run at now: whether a run
+// time has passed since it last ran, or was added.
+func scheduleDue(sched history.Schedule, now time.Time) bool {
+	s, err := cron.Parse(sched.Spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: schedule %d has an invalid schedule %q: %v\n", sched.ID, sched.Spec, err)
+		return false
+	}
+	since := sched.LastRun
+	if since.IsZero() {
+		since = sched.CreatedAt
+	}
+	next := s.Next(since.In(now.Location()))
+	return !next.IsZero() && !next.After(now)
+}
+
+// runSchedule sends sched's prompt and saves the response.
+func runSchedule(ctx context.Context, sched history.Schedule, now time.Time) error {
+	prompt := sched.Prompt
+	if sched.Template != "" {
+		text, err := loadTemplate(sched.Template)
+		if err != nil {
+			return err
+		}
+		if text, err = templates.Fill(text, sched.Vars); err != nil {
+			return err
+		}
+		prompt = withTemplate(text, prompt)
+	}
+	systemPrompt, err := buildSystemPrompt()
+	if err != nil {
+		return fmt.Errorf("resolving system prompt: %w", err)
+	}
+
+	var messages []provider.Message
+	if systemPrompt != "" {
+		messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, provider.Message{Role: "user", Content: prompt})
+
+	p, err := newProvider(sched.Provider)
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+	response, err := collectChat(ctx, p, &provider.ChatRequest{Messages: messages, Model: sched.Model})
+	if err != nil {
+		return err
+	}
+
+	if err := saveToHistory(sched.Provider, sched.Model, messages, response, nil); err != nil {
+		return fmt.Errorf("saving to history: %w", err)
+	}
+	if sched.Output != "" {
+		f, err := os.OpenFile(sched.Output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("opening output: %w", err)
+		}
+		_, err = fmt.Fprintf(f, "## %s (%s)\n\n%s\n\n", scheduleLabel(sched), now.Format("2006-01-02 15:04"), strings.TrimSpace(response))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// scheduleLabel names a schedule by its template or prompt.
+func scheduleLabel(sched history.Schedule) string {
+	if sched.Template != "" {
+		return sched.Template
+	}
+	return util.Truncate(sched.Prompt, 40)
+}
+
+// formatNextRun formats a schedule's next run time.
+func formatNextRun(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04")
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/redact"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// scrubFlag overrides scrub from the config file.
+var scrubFlag string
+
+func init() {
+	rootCmd.Flags().StringVar(&scrubFlag, "scrub", "", "Mask personal data in requests and restore it in responses: pii or off")
+}
+
+// scrubModes lists the valid scrub values.
+var scrubModes = []string{config.ScrubPII, config.ScrubOff}
+
+// getScrubMode returns the scrub mode from --scrub or the config file.
+// The flag is validated up front, in runChat.
+func getScrubMode() string {
+	if scrubFlag != "" {
+		return scrubFlag
+	}
+	if cfg.Scrub == "" {
+		return config.ScrubOff
+	}
+	if !slices.Contains(scrubModes, cfg.Scrub) {
+		fmt.Fprintf(os.Stderr, "Warning: invalid scrub %q (want %s), using off\n", cfg.Scrub, strings.Join(scrubModes, ", "))
+		return config.ScrubOff
+	}
+	return cfg.Scrub
+}
+
+// piiScrubber is shared by every provider in the process, so a value
+// keeps its placeholder throughout an interactive session.
+var piiScrubber = sync.OnceValue(redact.NewScrubber)
+
+// scrubbed is a provider whose requests have personal data replaced by
+// placeholders, which are replaced back in its responses.
+type scrubbed struct {
+	provider.Provider
+	scrubber *redact.Scrubber
+}
+
+// scrubPII wraps p to scrub personal data from its requests.
+func scrubPII(p provider.Provider) provider.Provider {
+	return &scrubbed{Provider: p, scrubber: piiScrubber()}
+}
+
+func (s *scrubbed) Chat(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+	inner := make(chan string, cap(stream))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Provider.Chat(ctx, s.scrub(req), inner)
+	}()
+	s.scrubber.RestoreStream(inner, stream)
+	return <-errCh
+}
+
+// BuildRequest builds the request as sent, so --dry-run shows the
+// placeholders.
+func (s *scrubbed) BuildRequest(ctx context.Context, req *provider.ChatRequest) (*http.Request, error) {
+	return s.Provider.BuildRequest(ctx, s.scrub(req))
+}
+
+// Unwrap returns the provider s wraps.
+func (s *scrubbed) Unwrap() provider.Provider {
+	return s.Provider
+}
+
+// scrub returns a copy of req with personal data in its messages
+// replaced by placeholders.
+func (s *scrubbed) scrub(req *provider.ChatRequest) *provider.ChatRequest {
+	scrubbed := *req
+	scrubbed.Messages = make([]provider.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		m.Content = s.scrubber.Scrub(m.Content)
+		scrubbed.Messages[i] = m
+	}
+	slog.Debug("scrubbed personal data", "values", s.scrubber.Len())
+	return &scrubbed
+}
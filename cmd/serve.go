@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/server"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var (
+	servePortFlag  int
+	serveHostFlag  string
+	serveTokenFlag string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an OpenAI-compatible API backed by ask's configuration",
+	Long: `Serve an OpenAI-compatible chat completions API on localhost, so editors and
+other tools can use ask's providers, keys, and defaults.
+
+Endpoints:
+  POST /v1/chat/completions   streaming and non-streaming
+  GET  /v1/models
+  GET  /metrics               Prometheus metrics
+  GET  /healthz               health check, without the token
+
+A request's model picks the provider that offers it. Other models are sent
+to the default provider, and an empty model uses the default model (-p, -m,
+and --as apply). With --save, every completed conversation is saved to
+history.
+
+The server listens on 127.0.0.1 only, unless --host says otherwise. Set
+--token (or ASK_SERVE_TOKEN) to require it as a bearer token. Without a
+token, only requests addressed to localhost are served, so web pages
+cannot reach the server through a domain that resolves to 127.0.0.1, and
+chat requests must be sent as application/json.
+
+Examples:
+  ask serve --port 8080
+  OPENAI_BASE_URL=http://127.0.0.1:8080/v1 some-tool`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().IntVar(&servePortFlag, "port", 8080, "Port to listen on")
+	serveCmd.Flags().StringVar(&serveHostFlag, "host", "127.0.0.1", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveTokenFlag, "token", "", "Bearer token clients must send (default $ASK_SERVE_TOKEN)")
+	serveCmd.Flags().BoolVar(&saveFlag, "save", false, "Save completed conversations to history")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	providers, err := configuredProviders()
+	if err != nil {
+		return err
+	}
+	defaultProvider := providers[0]
+
+	models := []string{getModel()}
+	for _, p := range providers {
+		for _, m := range p.Models() {
+			if !slices.Contains(models, m) {
+				models = append(models, m)
+			}
+		}
+	}
+
+	token := serveTokenFlag
+	if token == "" {
+		token = os.Getenv("ASK_SERVE_TOKEN")
+	}
+	if token == "" && !server.IsLoopback(serveHostFlag) {
+		fmt.Fprintf(os.Stderr, "Warning: serving on %s without a token; only requests to localhost are served. Set --token or ASK_SERVE_TOKEN to serve other hosts\n", serveHostFlag)
+	}
+
+	var saveMu sync.Mutex
+	handler := server.New(server.Options{
+		Resolve: func(model string) (provider.Provider, string, error) {
+			if model == "" {
+				return defaultProvider, getModel(), nil
+			}
+			return providerForModel(providers, model), model, nil
+		},
+		Models: models,
+		Token:  token,
+		OnComplete: func(p provider.Provider, req *provider.ChatRequest, response string) {
+			last := req.Messages[len(req.Messages)-1]
+			logExchange(p.Name(), req.Model, last.Content, response)
+			if !saveFlag {
+				return
+			}
+			saveMu.Lock()
+			defer saveMu.Unlock()
+			if err := saveToHistory(p.Name(), req.Model, req.Messages, response, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save to history: %v\n", err)
+			}
+		},
+	})
+
+	addr := net.JoinHostPort(serveHostFlag, strconv.Itoa(servePortFlag))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving %s/%s on http://%s/v1 (Ctrl+C to stop)\n", defaultProvider.Name(), getModel(), listener.Addr())
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+	return nil
+}
+
+// configuredProviders returns the default provider followed by each other
+// provider that has an API key.
+func configuredProviders() ([]provider.Provider, error) {
+	defaultProvider, err := newProvider(getProvider())
+	if err != nil {
+		return nil, fmt.Errorf("creating provider: %w", err)
+	}
+	providers := []provider.Provider{defaultProvider}
+	for _, name := range []string{"openai", "anthropic"} {
+		if name == defaultProvider.Name() {
+			continue
+		}
+		// Providers without an API key are simply not offered
+		if p, err := newProvider(name); err == nil {
+			providers = append(providers, p)
+		}
+	}
+	return providers, nil
+}
+
+// providerForModel returns the first of providers that offers model, or
+// the first provider, the default, if none does.
+func providerForModel(providers []provider.Provider, model string) provider.Provider {
+	for _, p := range providers {
+		if slices.Contains(p.Models(), model) {
+			return p
+		}
+	}
+	return providers[0]
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const shSystemPrompt = `You translate requests into a single shell command.
+
+Reply with only the command: no explanation, no code fences, no leading "$".
+Prefer one line; chain with && or pipes when needed. Use only tools commonly
+installed on the target system. If the request is destructive, still return
+the command; the user confirms before anything runs.
+
+Target system:
+- OS: %s
+- Shell: %s
+- Working directory: %s`
+
+var shCmd = &cobra.Command{
+	Use:   "sh <description>",
+	Short: "Generate a shell command from a description",
+	Long: `Generate a shell command from a natural-language description.
+
+The command is displayed and only runs after you confirm it; it is never
+executed automatically. Your OS, shell, and working directory are sent as
+context. When output is piped, the command is printed only.
+
+Examples:
+  ask sh "find files modified in the last day and tar them"
+  ask sh "show the 10 largest directories here"`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSh,
+}
+
+func init() {
+	rootCmd.AddCommand(shCmd)
+}
+
+func runSh(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	shell := userShell()
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "unknown"
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+
+	req := &provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: fmt.Sprintf(shSystemPrompt, runtime.GOOS, filepath.Base(shell), cwd)},
+			{Role: "user", Content: strings.Join(args, " ")},
+		},
+		Model: getModel(),
+	}
+
+	interactive := term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+
+	// Collect silently: the raw response may include fences to strip
+	// before it is shown
+	response, err := streamChat(ctx, p, req, stream.NewWriter(io.Discard, true))
+	if err != nil {
+		return err
+	}
+	command := cleanCommand(response)
+	if command == "" {
+		return fmt.Errorf("model returned no command")
+	}
+
+	if !interactive {
+		fmt.Println(command)
+		return nil
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "\n  %s\n\n[r]un, [e]dit, [q]uit? ", command)
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r", "run", "y", "yes":
+			return runShell(shell, command)
+		case "e", "edit":
+			edited, err := editText(command+"\n", "ask-sh-*.sh")
+			if err != nil {
+				return err
+			}
+			command = strings.TrimSpace(edited)
+			if command == "" {
+				return nil
+			}
+		case "q", "quit", "n", "no", "":
+			return nil
+		}
+	}
+}
+
+// userShell returns the path of the user's login shell.
+func userShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// cleanCommand strips code fences and prompt markers a model may add
+// despite instructions.
+func cleanCommand(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		lines := strings.Split(s, "\n")
+		lines = lines[1:]
+		if len(lines) > 0 && strings.HasPrefix(strings.TrimSpace(lines[len(lines)-1]), "```") {
+			lines = lines[:len(lines)-1]
+		}
+		s = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return strings.TrimSpace(strings.TrimPrefix(s, "$ "))
+}
+
+// runShell runs command with shell, attached to the terminal.
+func runShell(shell, command string) error {
+	flag := "-c"
+	if filepath.Base(shell) == "cmd.exe" {
+		flag = "/C"
+	}
+
+	cmd := exec.Command(shell, flag, command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
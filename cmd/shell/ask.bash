@@ -0,0 +1,39 @@
+# ask shell integration for bash. Load with:
+#   eval "$(ask shell-init bash)"
+#
+# After each command, exports ASK_LAST_COMMAND and ASK_LAST_STATUS (and
+# ASK_LAST_STDERR when stderr capture is on) for "ask why".
+
+if [ -n "$__ask_capture_stderr" ]; then
+    __ask_stderr="${XDG_RUNTIME_DIR:-${TMPDIR:-/tmp}}/ask-stderr-$$"
+    : > "$__ask_stderr"
+    exec 2> >(tee -a "$__ask_stderr" >&2)
+    trap 'rm -f "$__ask_stderr" "$__ask_stderr.last"' EXIT
+fi
+
+__ask_prompt_command() {
+    local ret=$? entry
+    entry=$(HISTTIMEFORMAT= builtin history 1)
+    if [[ $entry =~ ^\ *([0-9]+)\*?\ +(.*)$ ]] && [ "${BASH_REMATCH[1]}" != "$__ask_histnum" ]; then
+        __ask_histnum=${BASH_REMATCH[1]}
+        case ${BASH_REMATCH[2]} in
+        "ask why"*) ;;
+        *)
+            export ASK_LAST_COMMAND="${BASH_REMATCH[2]}" ASK_LAST_STATUS=$ret
+            if [ -n "$__ask_stderr" ]; then
+                cp -f "$__ask_stderr" "$__ask_stderr.last"
+                export ASK_LAST_STDERR="$__ask_stderr.last"
+            fi
+            ;;
+        esac
+    fi
+    if [ -n "$__ask_stderr" ]; then
+        : > "$__ask_stderr"
+    fi
+    return $ret
+}
+
+case ";$PROMPT_COMMAND;" in
+*";__ask_prompt_command;"*) ;;
+*) PROMPT_COMMAND="__ask_prompt_command${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
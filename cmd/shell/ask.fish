@@ -0,0 +1,14 @@
+# ask shell integration for fish. Load with:
+#   ask shell-init fish | source
+#
+# After each command, exports ASK_LAST_COMMAND and ASK_LAST_STATUS for
+# "ask why".
+
+function __ask_postexec --on-event fish_postexec
+    set -l last_status $status
+    if test -z "$argv[1]"; or string match -q 'ask why*' -- $argv[1]
+        return
+    end
+    set -gx ASK_LAST_COMMAND $argv[1]
+    set -gx ASK_LAST_STATUS $last_status
+end
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed shell/ask.bash shell/ask.zsh shell/ask.fish
+var shellScripts embed.FS
+
+var captureStderrFlag bool
+
+var shellInitCmd = &cobra.Command{
+	Use:   "shell-init <bash|zsh|fish>",
+	Short: "Print shell integration for 'ask why'",
+	Long: `Print a shell snippet that records each command and its exit status, so
+'ask why' can explain the most recent failure.
+
+Add to your shell's rc file:
+  eval "$(ask shell-init bash)"   # ~/.bashrc
+  eval "$(ask shell-init zsh)"    # ~/.zshrc
+  ask shell-init fish | source    # ~/.config/fish/config.fish
+
+With --capture-stderr (bash and zsh), stderr is also teed to a temporary file
+so 'ask why' can see error output. This makes stderr a pipe rather than the
+terminal, which disables color and progress output in some programs.`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	RunE:      runShellInit,
+}
+
+func init() {
+	rootCmd.AddCommand(shellInitCmd)
+	shellInitCmd.Flags().BoolVar(&captureStderrFlag, "capture-stderr", false, "Also capture each command's stderr (bash and zsh)")
+}
+
+func runShellInit(cmd *cobra.Command, args []string) error {
+	shell := args[0]
+	script, err := shellScripts.ReadFile("shell/ask." + shell)
+	if err != nil {
+		return usageErrorf("unsupported shell: %s (supported: bash, zsh, fish)", shell)
+	}
+
+	if captureStderrFlag {
+		if shell == "fish" {
+			return usageErrorf("--capture-stderr is not supported for fish")
+		}
+		fmt.Println("__ask_capture_stderr=1")
+	}
+	fmt.Print(string(script))
+	return nil
+}
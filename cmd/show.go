@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/theme"
 )
 
 var showCmd = &cobra.Command{
@@ -23,39 +28,54 @@ func init() {
 func runShow(cmd *cobra.Command, args []string) error {
 	id, err := strconv.ParseInt(args[0], 10, 64)
 	if err != nil {
-		return fmt.Errorf("invalid conversation ID: %s", args[0])
+		return usageErrorf("invalid conversation ID: %s", args[0])
 	}
 
-	store, err := getStore()
+	return showConversation(os.Stdout, getTheme(os.Stdout), id)
+}
+
+// showConversation prints conversation id to w, styling role labels
+// with th.
+func showConversation(w io.Writer, th theme.Theme, id int64) error {
+	store, err := openStore()
 	if err != nil {
 		return fmt.Errorf("opening history store: %w", err)
 	}
 	defer store.Close()
 
-	conv, err := store.GetConversation(id)
+	conv, err := store.GetConversation(context.Background(), id)
 	if err != nil {
 		return fmt.Errorf("loading conversation %d: %w", id, err)
 	}
 
-	fmt.Printf("Conversation #%d: %s\n", conv.ID, conv.Title)
-	fmt.Printf("Model: %s | Provider: %s | Date: %s\n",
+	fmt.Fprintf(w, "Conversation #%d: %s\n", conv.ID, conv.Title)
+	fmt.Fprintf(w, "Model: %s | Provider: %s | Date: %s\n",
 		conv.Model, conv.Provider, conv.CreatedAt.Format("Jan 02 2006 15:04"))
-	fmt.Println(strings.Repeat("-", 60))
-	fmt.Println()
+	if len(conv.Tags) > 0 {
+		fmt.Fprintf(w, "Tags: %s\n", strings.Join(conv.Tags, ", "))
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 60))
+	fmt.Fprintln(w)
 
 	for _, msg := range conv.Messages {
 		if msg.Role == "system" {
 			continue // Skip system messages in display
 		}
 
-		roleLabel := "You"
+		roleLabel, style := "You", th.User
 		if msg.Role == "assistant" {
-			roleLabel = "Assistant"
+			roleLabel, style = "Assistant", th.Assistant
+		}
+		if msg.Attempt > 1 {
+			roleLabel += fmt.Sprintf(", attempt %d", msg.Attempt)
+		}
+		if msg.Partial {
+			roleLabel += ", partial"
 		}
 
-		fmt.Printf("[%s]\n", roleLabel)
-		fmt.Println(msg.Content)
-		fmt.Println()
+		fmt.Fprintln(w, style.Render("["+roleLabel+"]"))
+		fmt.Fprintln(w, msg.Content)
+		fmt.Fprintln(w)
 	}
 
 	return nil
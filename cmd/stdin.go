@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/internal/util"
+)
+
+var (
+	headFlag       int
+	tailFlag       int
+	stdinLimitFlag string
+
+	// stdinGuard enables the max_stdin_bytes check; commands that only
+	// inspect the prompt turn it off.
+	stdinGuard = true
+)
+
+// addStdinFlags registers the stdin truncation flags.
+func addStdinFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&headFlag, "head", 0, "Keep only the first N lines of stdin")
+	flags.IntVar(&tailFlag, "tail", 0, "Keep only the last N lines of stdin")
+	flags.StringVar(&stdinLimitFlag, "stdin-limit", "", "Truncate stdin to this size (e.g. 64K, 1M)")
+}
+
+// readStdin reads piped input, applies --head, --tail, and --stdin-limit,
+// and checks the result against max_stdin_bytes.
+func readStdin() (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	input := string(data)
+
+	if headFlag > 0 {
+		input = headLines(input, headFlag)
+	}
+	if tailFlag > 0 {
+		input = tailLines(input, tailFlag)
+	}
+	if stdinLimitFlag != "" {
+		limit, err := util.ParseSize(stdinLimitFlag)
+		if err != nil {
+			return "", usageErrorf("--stdin-limit: %v", err)
+		}
+		input = truncateBytes(input, limit)
+	}
+
+	if stdinGuard {
+		if err := checkStdinSize(input); err != nil {
+			return "", err
+		}
+	}
+	return input, nil
+}
+
+// checkStdinSize asks for confirmation if input exceeds max_stdin_bytes.
+// When there is no terminal to ask on, it warns and sends the input, or
+// with --strict, refuses it. Explicit truncation flags count as
+// confirmation.
+func checkStdinSize(input string) error {
+	size := int64(len(input))
+	max := cfg.MaxStdinBytes
+	if max <= 0 || size <= max || headFlag > 0 || tailFlag > 0 || stdinLimitFlag != "" {
+		return nil
+	}
+
+	summary := fmt.Sprintf("stdin is %s (~%d tokens), over the %s max_stdin_bytes limit",
+		util.FormatSize(size), tokens.Estimate(input), util.FormatSize(max))
+
+	yes, ok := confirm(fmt.Sprintf("Warning: %s.\nSend it anyway?", summary))
+	if !ok && !strictFlag {
+		fmt.Fprintf(os.Stderr, "Warning: %s; sending it anyway (use --head, --tail, or --stdin-limit to trim it)\n", summary)
+		return nil
+	}
+	if !ok {
+		return usageErrorf("%s\n\nTruncate it with --head N, --tail N, or --stdin-limit SIZE, or raise max_stdin_bytes in ~/.config/ask/config.yaml", summary)
+	}
+	if !yes {
+		return fmt.Errorf("aborted: stdin too large")
+	}
+	return nil
+}
+
+// headLines returns the first n lines of s.
+func headLines(s string, n int) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "")
+}
+
+// tailLines returns the last n lines of s, ignoring a trailing newline.
+func tailLines(s string, n int) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[len(lines)-n:], "") + "\n"
+}
+
+// truncateBytes cuts s to at most limit bytes, at a line boundary when
+// there is one, and notes how much was dropped. A limit of 0 keeps s.
+func truncateBytes(s string, limit int64) string {
+	if limit <= 0 || int64(len(s)) <= limit {
+		return s
+	}
+	cut := strings.ToValidUTF8(s[:limit], "")
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i+1]
+	}
+	return fmt.Sprintf("%s\n[... truncated %s]\n", cut, util.FormatSize(int64(len(s)-len(cut))))
+}
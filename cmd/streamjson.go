@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"time"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// streamJSONFlag writes one-shot responses as JSON Lines events.
+var streamJSONFlag bool
+
+func init() {
+	rootCmd.Flags().BoolVar(&streamJSONFlag, "stream-json", false, "Write the response as newline-delimited JSON events (start, delta, usage, done, error)")
+}
+
+// emitUsageAndDone writes the usage and done events that end a response,
+// with the response's timings.
+func emitUsageAndDone(events *stream.EventWriter, providerName string, req *provider.ChatRequest, response string, start time.Time, timings stream.Timings) error {
+	u := estimateUsage(providerName, req, response)
+	if err := events.Emit(stream.Event{Type: stream.EventUsage, Usage: &stream.Usage{
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		Cost:         u.Cost,
+	}}); err != nil {
+		return err
+	}
+	return events.Emit(stream.Event{
+		Type:         stream.EventDone,
+		ElapsedMS:    time.Since(start).Milliseconds(),
+		FirstTokenMS: timings.FirstToken().Milliseconds(),
+		TokensPerSec: math.Round(timings.TokensPerSecond()*10) / 10,
+	})
+}
+
+// emitError writes an error event for err, if there is one, and returns
+// err.
+func emitError(err error) error {
+	if err != nil {
+		_ = stream.NewEventWriter(os.Stdout).Emit(stream.Event{Type: stream.EventError, Error: err.Error(), ExitCode: ExitCode(err)})
+	}
+	return err
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/templates"
+)
+
+var (
+	// templateFlag names a saved prompt template to start the prompt with.
+	templateFlag string
+	// varFlags are name=value settings of the template's variables.
+	varFlags []string
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage saved prompt templates",
+	Long: `Templates are prompts saved by name in ~/.local/share/ask/templates/<name>.md.
+Use one with -t; any arguments are added after it:
+
+  ask template edit weekly-summary
+  git log --since=1.week | ask -t weekly-summary
+  ask -t review "Focus on error handling" < main.go
+
+A template may contain {stdin} to place piped input within it, and
+variables: {{name}}, or {{name=default}} to make one optional. Set them
+with --var name=value; on a terminal, ask prompts for those not set:
+
+  Review {{file}} for {{focus=bugs and edge cases}}.
+
+  ask -t review --var file=main.go < main.go`,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List templates",
+	Args:  cobra.NoArgs,
+	RunE:  runTemplateList,
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a template",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTemplateShow,
+}
+
+var templateEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Create or edit a template in $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTemplateEdit,
+}
+
+var templateRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a template",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runTemplateRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd, templateShowCmd, templateEditCmd, templateRemoveCmd)
+	rootCmd.Flags().StringVarP(&templateFlag, "template", "t", "", "Start the prompt with a saved template (see: ask template)")
+	rootCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a template variable, as name=value (repeatable)")
+}
+
+// templatesDir returns the directory templates are saved in.
+func templatesDir() (string, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "templates"), nil
+}
+
+// loadTemplate returns the text of the named template.
+func loadTemplate(name string) (string, error) {
+	dir, err := templatesDir()
+	if err != nil {
+		return "", err
+	}
+	text, err := templates.Load(dir, name)
+	if errors.Is(err, templates.ErrNotFound) {
+		return "", fmt.Errorf("no template named %q\n\nCreate it with: ask template edit %s", name, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("loading template: %w", err)
+	}
+	return text, nil
+}
+
+// templateVars returns the template variables set with --var.
+func templateVars() (map[string]string, error) {
+	values := make(map[string]string)
+	for _, v := range varFlags {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, usageErrorf("invalid --var %q (want name=value)", v)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, nil
+}
+
+// fillTemplate fills the variables of the named template's text. Those
+// not in values are asked for on the terminal, if there is one, and
+// added to values; otherwise required ones are an error.
+func fillTemplate(name, text string, values map[string]string) (string, error) {
+	vars := templates.Vars(text)
+	for given := range values {
+		if !slices.ContainsFunc(vars, func(v templates.Var) bool { return v.Name == given }) {
+			return "", usageErrorf("template %q has no variable %q", name, given)
+		}
+	}
+
+	var unset []templates.Var
+	for _, v := range vars {
+		if _, ok := values[v.Name]; !ok {
+			unset = append(unset, v)
+		}
+	}
+	if len(unset) > 0 {
+		if err := askTemplateVars(unset, values); err != nil {
+			return "", err
+		}
+	}
+
+	if missing := templates.Missing(text, values); len(missing) > 0 {
+		return "", usageErrorf("template %q needs a value for %s\n\nSet it with --var name=value", name, strings.Join(missing, ", "))
+	}
+	return templates.Fill(text, values)
+}
+
+// askTemplateVars asks on the terminal for the value of each of vars,
+// adding them to values. An empty answer keeps a variable's default.
+// Without a terminal, it asks nothing.
+func askTemplateVars(vars []templates.Var, values map[string]string) error {
+	tty, err := openTTY()
+	if err != nil {
+		return nil
+	}
+	defer tty.Close()
+	if !tty.isTerminal() {
+		return nil
+	}
+
+	r := bufio.NewReader(tty)
+	for _, v := range vars {
+		for {
+			if v.Required {
+				fmt.Fprintf(tty, "%s: ", v.Name)
+			} else {
+				fmt.Fprintf(tty, "%s [%s]: ", v.Name, v.Default)
+			}
+			answer, err := r.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("reading template variable %s: %w", v.Name, err)
+			}
+			answer = strings.TrimRight(answer, "\r\n")
+			if answer != "" {
+				values[v.Name] = answer
+			}
+			// Required variables are asked for again until given
+			if answer != "" || !v.Required {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// withTemplate returns a prompt of a template's text followed by prompt.
+func withTemplate(text, prompt string) string {
+	if strings.TrimSpace(prompt) == "" {
+		return text
+	}
+	return text + "\n\n" + prompt
+}
+
+func runTemplateList(cmd *cobra.Command, args []string) error {
+	dir, err := templatesDir()
+	if err != nil {
+		return err
+	}
+	names, err := templates.List(dir)
+	if err != nil {
+		return fmt.Errorf("listing templates: %w", err)
+	}
+	if len(names) == 0 {
+		fmt.Println("No templates. Add one with: ask template edit <name>")
+		return nil
+	}
+
+	for _, name := range names {
+		text, err := templates.Load(dir, name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-20s %s\n", name, summarizeSystem(text))
+	}
+	return nil
+}
+
+func runTemplateShow(cmd *cobra.Command, args []string) error {
+	text, err := loadTemplate(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(text)
+	return nil
+}
+
+func runTemplateEdit(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if !templates.ValidName(name) {
+		return usageErrorf("invalid template name: %q", name)
+	}
+	dir, err := templatesDir()
+	if err != nil {
+		return err
+	}
+	path := templates.Path(dir, name)
+
+	initial, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading template: %w", err)
+	}
+	text, err := editText(string(initial), "ask-template-*.md")
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("aborting due to empty template")
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("creating templates directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(text+"\n"), 0600); err != nil {
+		return fmt.Errorf("saving template: %w", err)
+	}
+	fmt.Printf("Saved template %q\n", name)
+	return nil
+}
+
+func runTemplateRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if !templates.ValidName(name) {
+		return usageErrorf("invalid template name: %q", name)
+	}
+	dir, err := templatesDir()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(templates.Path(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no template named %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("removing template: %w", err)
+	}
+	fmt.Printf("Removed template %q\n", name)
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens [prompt]",
+	Short: "Count tokens for a prompt",
+	Long: `Count the tokens a prompt would consume, and how much of the model's
+context window that uses. The prompt is built from arguments, stdin, -f files,
+--url pages and the system prompt exactly as it would be for a chat request.
+
+Anthropic models are counted exactly with the count_tokens API, and OpenAI
+models with their tiktoken encoding (cl100k_base or o200k_base), whose
+vocabulary is downloaded once to the cache directory. Other providers, or
+when counting fails, get a local estimate, marked as such.
+
+Examples:
+  ask tokens < server.log
+  ask tokens -m claude-sonnet-4-20250514 -f 'src/**/*.go'`,
+	RunE: runTokens,
+}
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	tokensCmd.Flags().StringArrayVarP(&fileFlags, "file", "f", nil, "Include a file, directory, or glob in the prompt (repeatable)")
+	tokensCmd.Flags().StringArrayVar(&urlFlags, "url", nil, "Include the readable text of a web page in the prompt (repeatable)")
+	tokensCmd.Flags().BoolVar(&pasteFlag, "paste", false, "Include the clipboard contents in the prompt")
+	addStdinFlags(tokensCmd.Flags())
+}
+
+func runTokens(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// Counting is how users check a large input, so never block on it
+	stdinGuard = false
+	prompt, _, err := buildPrompt(args)
+	if err != nil {
+		return fmt.Errorf("building prompt: %w", err)
+	}
+
+	systemPrompt, err := buildSystemPrompt()
+	if err != nil {
+		return fmt.Errorf("resolving system prompt: %w", err)
+	}
+
+	if strings.TrimSpace(prompt) == "" && systemPrompt == "" {
+		return usageErrorf("no prompt provided\n\nUsage: ask tokens \"your prompt\"\n       ask tokens < file")
+	}
+
+	var messages []provider.Message
+	if systemPrompt != "" {
+		messages = append(messages, provider.Message{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, provider.Message{Role: "user", Content: prompt})
+
+	req := &provider.ChatRequest{
+		Messages: messages,
+		Model:    getModel(),
+	}
+
+	count, method := countTokens(ctx, req)
+	fmt.Printf("Model:   %s\n", req.Model)
+	if method == "" {
+		fmt.Printf("Tokens:  ~%d (estimated)\n", count)
+	} else {
+		fmt.Printf("Tokens:  %d (%s)\n", count, method)
+	}
+
+	if window := provider.ContextWindow(req.Model); window > 0 {
+		fmt.Printf("Context: %.1f%% of %d\n", float64(count)*100/float64(window), window)
+	} else {
+		fmt.Println("Context: unknown for this model")
+	}
+
+	return nil
+}
+
+// countTokens counts the input tokens of req, and says how: with the
+// provider's count_tokens API, or an OpenAI encoding's name. An empty
+// method means the count is an estimate, because the model has no known
+// tokenizer or counting failed.
+func countTokens(ctx context.Context, req *provider.ChatRequest) (int, string) {
+	p, err := configuredProvider(getProvider())
+	if err == nil {
+		if counter, ok := provider.As[provider.TokenCounter](p); ok {
+			count, err := counter.CountTokens(ctx, req)
+			if err == nil {
+				return count, "count_tokens API"
+			}
+			fmt.Fprintf(os.Stderr, "Warning: counting tokens: %v; estimating instead\n", err)
+		}
+	}
+
+	if name := tokens.EncodingFor(req.Model); name != "" {
+		enc, err := loadEncoding(ctx, name)
+		if err == nil {
+			return countMessages(enc, req.Messages), name
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; estimating instead\n", err)
+	}
+
+	count := 0
+	for _, msg := range req.Messages {
+		count += tokens.Estimate(msg.Content)
+	}
+	return count, ""
+}
+
+// loadEncoding loads the named OpenAI encoding, keeping its vocabulary
+// in the cache directory.
+func loadEncoding(ctx context.Context, name string) (*tokens.Encoding, error) {
+	dir, err := config.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return tokens.Load(ctx, name, filepath.Join(dir, "tiktoken"))
+}
+
+// countMessages counts msgs as OpenAI's chat format encodes them: each
+// message's role and content, three tokens framing each message, and
+// three priming the reply.
+func countMessages(enc *tokens.Encoding, msgs []provider.Message) int {
+	count := 3
+	for _, msg := range msgs {
+		count += 3 + enc.Count(msg.Role) + enc.Count(msg.Content)
+	}
+	return count
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"runtime"
+
+	"golang.org/x/term"
+)
+
+// tty is the terminal the user is at, opened directly so that ask can
+// ask questions and run editors when standard input or output is
+// redirected.
+type tty struct {
+	in, out *os.File
+}
+
+// openTTY opens the controlling terminal: /dev/tty, or on Windows the
+// console's input and output, CONIN$ and CONOUT$.
+func openTTY() (*tty, error) {
+	if runtime.GOOS != "windows" {
+		f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		return &tty{in: f, out: f}, nil
+	}
+
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return &tty{in: in, out: out}, nil
+}
+
+// isTerminal reports whether t is an interactive terminal, rather than,
+// say, /dev/null in a service.
+func (t *tty) isTerminal() bool {
+	return term.IsTerminal(int(t.in.Fd())) && term.IsTerminal(int(t.out.Fd()))
+}
+
+func (t *tty) Read(p []byte) (int, error)  { return t.in.Read(p) }
+func (t *tty) Write(p []byte) (int, error) { return t.out.Write(p) }
+
+func (t *tty) Close() error {
+	err := t.in.Close()
+	if t.out != t.in {
+		if outErr := t.out.Close(); err == nil {
+			err = outErr
+		}
+	}
+	return err
+}
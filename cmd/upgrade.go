@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/upgrade"
+	"github.com/devaloi/ask/internal/version"
+)
+
+var upgradeCheckFlag bool
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade ask to the latest release",
+	Long: `Download the latest GitHub release of ask, verify its SHA-256 checksum,
+and replace the running binary in place.
+
+With --check, only report whether an update is available: the exit status is
+0 when ask is up to date and 8 when a newer release exists.`,
+	Args: cobra.NoArgs,
+	RunE: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().BoolVar(&upgradeCheckFlag, "check", false, "Only check whether an update is available")
+}
+
+// newUpgradeChecker returns the checker for ask's releases. Tests replace
+// it, such as with one for a local server.
+var newUpgradeChecker = func() *upgrade.Checker {
+	return upgrade.NewChecker(upgrade.Repo)
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	current := version.Get().Version
+
+	checker := newUpgradeChecker()
+	rel, err := checker.Latest(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !upgrade.Newer(rel.Version, current) {
+		fmt.Printf("ask is up to date (%s)\n", current)
+		return nil
+	}
+
+	if upgradeCheckFlag {
+		fmt.Printf("Update available: %s (current %s)\n", rel.Version, current)
+		return exitStatus(exitUpdateAvailable)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating ask binary: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("locating ask binary: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Downloading ask %s for %s/%s...\n", rel.Version, runtime.GOOS, runtime.GOARCH)
+	data, err := checker.Download(ctx, rel, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	if err := upgrade.Replace(exe, data); err != nil {
+		return fmt.Errorf("replacing %s: %w", exe, err)
+	}
+
+	fmt.Printf("Upgraded ask %s → %s\n", current, rel.Version)
+	return nil
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/budget"
+	"github.com/devaloi/ask/pkg/ask/history"
+)
+
+var (
+	usageMonthFlag bool
+	usageTodayFlag bool
+	usageSinceFlag string
+	usageUntilFlag string
+	usageByFlag    []string
+	usageJSONFlag  bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and spending",
+	Long: `Report the tokens and estimated cost of the requests ask has sent, per
+day, provider, and model, to reconcile against provider invoices.
+
+Costs are estimated from token counts and list prices; models without a
+known price count as free.
+
+Examples:
+  ask usage                        # this month, per day, provider, and model
+  ask usage --today --by model
+  ask usage --since 2025-01-01 --until 2025-01-31 --json`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().BoolVar(&usageMonthFlag, "month", false, "Report this calendar month (the default)")
+	usageCmd.Flags().BoolVar(&usageTodayFlag, "today", false, "Report today")
+	usageCmd.Flags().StringVar(&usageSinceFlag, "since", "", "Report from this date (YYYY-MM-DD)")
+	usageCmd.Flags().StringVar(&usageUntilFlag, "until", "", "Report up to and including this date (YYYY-MM-DD)")
+	usageCmd.Flags().StringSliceVar(&usageByFlag, "by", []string{history.GroupDay, history.GroupProvider, history.GroupModel}, "Group by any of day, provider, and model")
+	usageCmd.Flags().BoolVar(&usageJSONFlag, "json", false, "Print as JSON")
+}
+
+// usageReport is the JSON form of a usage report.
+type usageReport struct {
+	Since time.Time            `json:"since"`
+	Until time.Time            `json:"until"`
+	Rows  []history.UsageTotal `json:"rows"`
+	Total history.UsageTotal   `json:"total"`
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	since, until, err := usageRange(time.Now())
+	if err != nil {
+		return err
+	}
+	for _, by := range usageByFlag {
+		if !slices.Contains([]string{history.GroupDay, history.GroupProvider, history.GroupModel}, by) {
+			return usageErrorf("invalid --by %q (want day, provider, or model)", by)
+		}
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return fmt.Errorf("opening usage records: %w", err)
+	}
+	defer store.Close()
+
+	records, err := store.ListUsage(context.Background(), since, until)
+	if err != nil {
+		return err
+	}
+	report := usageReport{Since: since, Until: until, Rows: history.SumUsage(records, usageByFlag...)}
+	for _, u := range records {
+		report.Total.Add(u)
+	}
+	if report.Rows == nil {
+		report.Rows = []history.UsageTotal{}
+	}
+
+	if usageJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	if len(records) == 0 {
+		fmt.Fprintf(os.Stderr, "No usage recorded from %s to %s\n", since.Format(time.DateOnly), until.AddDate(0, 0, -1).Format(time.DateOnly))
+		return nil
+	}
+	printUsage(os.Stdout, report)
+	return nil
+}
+
+// usageRange returns the report's time range from the flags: this month
+// by default.
+func usageRange(now time.Time) (since, until time.Time, err error) {
+	set := 0
+	for _, b := range []bool{usageMonthFlag, usageTodayFlag, usageSinceFlag != ""} {
+		if b {
+			set++
+		}
+	}
+	if set > 1 {
+		return since, until, usageErrorf("use only one of --month, --today, and --since")
+	}
+
+	until = budget.Day.Start(now).AddDate(0, 0, 1)
+	switch {
+	case usageTodayFlag:
+		since = budget.Day.Start(now)
+	case usageSinceFlag != "":
+		if since, err = time.ParseInLocation(time.DateOnly, usageSinceFlag, time.Local); err != nil {
+			return since, until, usageErrorf("invalid --since %q (want YYYY-MM-DD)", usageSinceFlag)
+		}
+	default:
+		since = budget.Month.Start(now)
+	}
+	if usageUntilFlag != "" {
+		day, err := time.ParseInLocation(time.DateOnly, usageUntilFlag, time.Local)
+		if err != nil {
+			return since, until, usageErrorf("invalid --until %q (want YYYY-MM-DD)", usageUntilFlag)
+		}
+		until = day.AddDate(0, 0, 1)
+	}
+	if !since.Before(until) {
+		return since, until, usageErrorf("--until is before the start of the report")
+	}
+	return since, until, nil
+}
+
+// printUsage writes report as a table, with a column for each grouping.
+func printUsage(w io.Writer, report usageReport) {
+	type column struct {
+		title string
+		width int
+		value func(history.UsageTotal) string
+	}
+	var columns []column
+	if slices.Contains(usageByFlag, history.GroupDay) {
+		columns = append(columns, column{"Day", 10, func(t history.UsageTotal) string { return t.Day }})
+	}
+	if slices.Contains(usageByFlag, history.GroupProvider) {
+		columns = append(columns, column{"Provider", 9, func(t history.UsageTotal) string { return t.Provider }})
+	}
+	if slices.Contains(usageByFlag, history.GroupModel) {
+		columns = append(columns, column{"Model", 26, func(t history.UsageTotal) string { return t.Model }})
+	}
+
+	row := func(labels []string, requests, input, output, cost string) {
+		var b strings.Builder
+		for i, c := range columns {
+			fmt.Fprintf(&b, "%-*s  ", c.width, labels[i])
+		}
+		fmt.Fprintf(&b, "%8s  %11s  %11s  %10s", requests, input, output, cost)
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+	totals := func(t history.UsageTotal) (string, string, string, string) {
+		return fmt.Sprint(t.Requests), fmt.Sprint(t.InputTokens), fmt.Sprint(t.OutputTokens), fmt.Sprintf("$%.4f", t.Cost)
+	}
+
+	labels := make([]string, len(columns))
+	rules := make([]string, len(columns))
+	for i, c := range columns {
+		labels[i] = c.title
+		rules[i] = strings.Repeat("-", c.width)
+	}
+	row(labels, "Requests", "Input", "Output", "Cost")
+	row(rules, strings.Repeat("-", 8), strings.Repeat("-", 11), strings.Repeat("-", 11), strings.Repeat("-", 10))
+
+	for _, t := range report.Rows {
+		for i, c := range columns {
+			labels[i] = c.value(t)
+		}
+		requests, input, output, cost := totals(t)
+		row(labels, requests, input, output, cost)
+	}
+
+	if len(columns) > 0 && len(report.Rows) > 1 {
+		clear(labels)
+		labels[0] = "Total"
+		requests, input, output, cost := totals(report.Total)
+		row(labels, requests, input, output, cost)
+	}
+}
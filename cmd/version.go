@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/devaloi/ask/internal/version"
+)
+
+var versionJSONFlag bool
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print version and build information",
+	Args:  cobra.NoArgs,
+	RunE:  runVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+	versionCmd.Flags().BoolVar(&versionJSONFlag, "json", false, "Print as JSON")
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	info := version.Get()
+
+	if versionJSONFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	fmt.Printf("ask %s\n", info.Version)
+	fmt.Printf("  commit:   %s\n", info.Commit)
+	fmt.Printf("  built:    %s\n", info.Date)
+	fmt.Printf("  go:       %s\n", info.GoVersion)
+	fmt.Printf("  platform: %s\n", info.Platform)
+	return nil
+}
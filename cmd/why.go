@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/stream"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const whySystemPrompt = `You explain why a shell command failed and how to fix it.
+
+Be brief: state the most likely cause in a sentence or two, then give the
+fix, preferring a corrected command. If the error output is missing or does
+not explain the failure, say what to check.
+
+Target system:
+- OS: %s
+- Shell: %s
+- Working directory: %s`
+
+// whyMaxStderr caps how much captured stderr is sent; the end of the
+// output is kept since errors usually come last.
+const whyMaxStderr = 8 * 1024
+
+var whyCmd = &cobra.Command{
+	Use:   "why [question]",
+	Short: "Explain why the last shell command failed",
+	Long: `Explain the most recent command run in your shell, using the command
+line, its exit status, and (if captured) its stderr.
+
+Requires the shell hook from 'ask shell-init'. Extra arguments are passed
+along as a follow-up question.
+
+Examples:
+  ask why
+  ask why "is there a flag to skip this check?"`,
+	RunE: runWhy,
+}
+
+func init() {
+	rootCmd.AddCommand(whyCmd)
+}
+
+func runWhy(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	command := os.Getenv("ASK_LAST_COMMAND")
+	if command == "" {
+		return usageErrorf("no previous command recorded\n\nEnable the shell hook in your shell's rc file:\n\n  eval \"$(ask shell-init bash)\"   # or zsh\n  ask shell-init fish | source")
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Command: %s\n", command)
+	if status := os.Getenv("ASK_LAST_STATUS"); status != "" {
+		fmt.Fprintf(&prompt, "Exit status: %s\n", status)
+	}
+	if path := os.Getenv("ASK_LAST_STDERR"); path != "" {
+		if stderr := readTail(path, whyMaxStderr); strings.TrimSpace(stderr) != "" {
+			fmt.Fprintf(&prompt, "\nStderr:\n```\n%s\n```\n", strings.TrimRight(stderr, "\n"))
+		}
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(&prompt, "\n%s\n", strings.Join(args, " "))
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "unknown"
+	}
+
+	p, err := newProvider(getProvider())
+	if err != nil {
+		return fmt.Errorf("creating provider: %w", err)
+	}
+
+	req := &provider.ChatRequest{
+		Messages: []provider.Message{
+			{Role: "system", Content: fmt.Sprintf(whySystemPrompt, runtime.GOOS, filepath.Base(userShell()), cwd)},
+			{Role: "user", Content: prompt.String()},
+		},
+		Model: getModel(),
+	}
+
+	writer := stream.NewWriter(os.Stdout, term.IsTerminal(int(os.Stdout.Fd())))
+	writer.SetCodeStyle(string(getTheme(os.Stdout).Code))
+	_, err = streamChat(ctx, p, req, writer)
+	return err
+}
+
+// readTail returns up to the last max bytes of the file at path, or ""
+// if it cannot be read.
+func readTail(path string, max int) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if len(data) > max {
+		data = data[len(data)-max:]
+		// Drop the partial first line
+		if i := strings.IndexByte(string(data), '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return string(data)
+}
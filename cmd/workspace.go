@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// workspaceEnv sets the workspace, like --workspace.
+const workspaceEnv = "ASK_WORKSPACE"
+
+// workspaceFlag names the workspace to save and find conversations in.
+var workspaceFlag string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&workspaceFlag, "workspace", "", "Keep conversations in this workspace (default: the git repository)")
+}
+
+var (
+	workspaceOnce sync.Once
+	workspace     string
+)
+
+// currentWorkspace returns the workspace conversations are saved in and
+// continued from: --workspace, $ASK_WORKSPACE, or the path of the git
+// repository around the working directory, so that repositories of the
+// same name are kept apart. It returns "" outside any, where history is
+// not scoped.
+func currentWorkspace() string {
+	workspaceOnce.Do(func() {
+		workspace = resolveWorkspace()
+	})
+	return workspace
+}
+
+func resolveWorkspace() string {
+	if workspaceFlag != "" {
+		return workspaceFlag
+	}
+	if name := os.Getenv(workspaceEnv); name != "" {
+		return name
+	}
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Clean(strings.TrimSpace(string(out)))
+}
+
+// workspaceName returns how workspace is shown: the name of a
+// repository's directory, or a workspace set by name as it is.
+func workspaceName(workspace string) string {
+	if filepath.IsAbs(workspace) {
+		return filepath.Base(workspace)
+	}
+	return workspace
+}
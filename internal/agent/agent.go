@@ -0,0 +1,205 @@
+// Package agent runs a model in a loop with a small set of tools: the
+// model asks for a tool, the agent runs it and sends back the result, and
+// so on until the model gives a final answer.
+//
+// Tools are called with a text protocol rather than provider-specific
+// tool calling, so every provider works. To call a tool, the model ends
+// its reply with a fenced block whose info string is "tool":
+//
+//	```tool
+//	{"tool": "read_file", "args": {"path": "main.go"}}
+//	```
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/devaloi/ask/internal/codeblock"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// DefaultMaxSteps is the number of tool calls after which an agent stops.
+const DefaultMaxSteps = 20
+
+// ErrMaxSteps means the model kept calling tools past the step limit.
+var ErrMaxSteps = errors.New("too many steps without a final answer")
+
+// Tool is something the model can do.
+type Tool struct {
+	Name        string
+	Description string
+	Params      []Param
+
+	// Run does the work. Its error is reported to the model, which may
+	// try something else.
+	Run func(ctx context.Context, args map[string]string) (string, error)
+}
+
+// Param is a tool argument. All arguments are strings.
+type Param struct {
+	Name        string
+	Description string
+}
+
+// Call is a tool call made by the model.
+type Call struct {
+	Tool string            `json:"tool"`
+	Args map[string]string `json:"args"`
+}
+
+// String returns the tool name and argument values, for display.
+func (c Call) String() string {
+	s := c.Tool
+	for _, name := range slices.Sorted(maps.Keys(c.Args)) {
+		s += " " + c.Args[name]
+	}
+	return s
+}
+
+// ParseCall returns the tool call that ends response, or nil if it has
+// none, meaning response is the final answer. A malformed call is an
+// error.
+func ParseCall(response string) (*Call, error) {
+	var block *codeblock.Block
+	for _, b := range codeblock.Parse(response) {
+		if b.Info == "tool" {
+			block = &b
+		}
+	}
+	if block == nil {
+		return nil, nil
+	}
+
+	var call Call
+	if err := json.Unmarshal([]byte(block.Code), &call); err != nil {
+		return nil, fmt.Errorf("invalid tool call: %w", err)
+	}
+	if call.Tool == "" {
+		return nil, errors.New(`invalid tool call: no "tool"`)
+	}
+	return &call, nil
+}
+
+// SystemPrompt describes tools and the calling protocol to the model,
+// followed by env, facts such as the operating system.
+func SystemPrompt(tools []Tool, env string) string {
+	var b strings.Builder
+	b.WriteString(`You are an agent that completes the user's task by using tools, one at a
+time, and then answering.
+
+To use a tool, end your reply with a single fenced block like this, and
+nothing after it:
+
+` + "```tool" + `
+{"tool": "<name>", "args": {"<param>": "<value>"}}
+` + "```" + `
+
+The result comes back in the next message. Briefly say what you are doing
+before each call. Investigate before concluding, but do not repeat calls.
+When you have the answer, reply without a tool block.
+
+Tools:
+`)
+	for _, t := range tools {
+		fmt.Fprintf(&b, "\n- %s: %s\n", t.Name, t.Description)
+		for _, p := range t.Params {
+			fmt.Fprintf(&b, "    %s: %s\n", p.Name, p.Description)
+		}
+	}
+	if env != "" {
+		b.WriteString("\n" + env + "\n")
+	}
+	return b.String()
+}
+
+// Agent runs the tool loop.
+type Agent struct {
+	Tools []Tool
+
+	// MaxSteps limits tool calls; zero means DefaultMaxSteps.
+	MaxSteps int
+
+	// Chat sends messages to the model and returns its reply, typically
+	// streaming it to the user.
+	Chat func(ctx context.Context, messages []provider.Message) (string, error)
+
+	// OnResult, if set, is called after each tool call.
+	OnResult func(call Call, result string, err error)
+}
+
+// Run continues the conversation in messages until the model answers
+// without calling a tool. It returns the messages with every step
+// appended, ending with the answer; on error, those up to the failure,
+// including any part of a reply received before it.
+func (a *Agent) Run(ctx context.Context, messages []provider.Message) ([]provider.Message, error) {
+	maxSteps := a.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultMaxSteps
+	}
+
+	for step := 0; ; step++ {
+		reply, err := a.Chat(ctx, messages)
+		if err != nil {
+			if reply != "" {
+				messages = append(messages, provider.Message{Role: "assistant", Content: reply})
+			}
+			return messages, err
+		}
+		messages = append(messages, provider.Message{Role: "assistant", Content: reply})
+
+		call, err := ParseCall(reply)
+		if err == nil && call == nil {
+			return messages, nil
+		}
+		if step >= maxSteps {
+			return messages, fmt.Errorf("%w (limit %d)", ErrMaxSteps, maxSteps)
+		}
+
+		var result string
+		if err == nil {
+			result, err = a.run(ctx, *call)
+			if a.OnResult != nil {
+				a.OnResult(*call, result, err)
+			}
+		}
+		if ctx.Err() != nil {
+			return messages, ctx.Err()
+		}
+		messages = append(messages, provider.Message{Role: "user", Content: resultMessage(call, result, err)})
+	}
+}
+
+// run runs call with its tool.
+func (a *Agent) run(ctx context.Context, call Call) (string, error) {
+	for _, t := range a.Tools {
+		if t.Name == call.Tool {
+			return t.Run(ctx, call.Args)
+		}
+	}
+	return "", fmt.Errorf("unknown tool %q", call.Tool)
+}
+
+// resultMessage reports the outcome of a tool call to the model.
+func resultMessage(call *Call, result string, err error) string {
+	if err != nil {
+		name := "tool call"
+		if call != nil {
+			name = call.Tool
+		}
+		return fmt.Sprintf("Error from %s: %v", name, err)
+	}
+	fence := "```"
+	for strings.Contains(result, fence) {
+		fence += "`"
+	}
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return fmt.Sprintf("Result of %s:\n%s\n%s%s", call.Tool, fence, result, fence)
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+func TestParseCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     *Call
+		wantErr  bool
+	}{
+		{"answer", "The test is flaky because of a race.", nil, false},
+		{"other block", "Run:\n```go\nfmt.Println()\n```", nil, false},
+		{
+			"call",
+			"Let me look.\n```tool\n{\"tool\": \"read_file\", \"args\": {\"path\": \"a.go\"}}\n```\n",
+			&Call{Tool: "read_file", Args: map[string]string{"path": "a.go"}},
+			false,
+		},
+		{
+			"last call wins",
+			"```tool\n{\"tool\": \"list_dir\"}\n```\n```tool\n{\"tool\": \"read_file\"}\n```",
+			&Call{Tool: "read_file"},
+			false,
+		},
+		{"bad json", "```tool\n{tool: read_file}\n```", nil, true},
+		{"no tool", "```tool\n{\"args\": {}}\n```", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCall(tt.response)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCall() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && (got.Tool != tt.want.Tool || got.String() != tt.want.String()) {
+				t.Errorf("ParseCall() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgentRun(t *testing.T) {
+	replies := []string{
+		"Checking.\n```tool\n{\"tool\": \"echo\", \"args\": {\"text\": \"hi\"}}\n```",
+		"```tool\n{\"tool\": \"nope\"}\n```",
+		"```tool\nnot json\n```",
+		"Done: hi",
+	}
+	var seen [][]provider.Message
+	var results []string
+	a := &Agent{
+		Tools: []Tool{{
+			Name: "echo",
+			Run: func(ctx context.Context, args map[string]string) (string, error) {
+				return args["text"], nil
+			},
+		}},
+		Chat: func(ctx context.Context, messages []provider.Message) (string, error) {
+			seen = append(seen, messages)
+			return replies[len(seen)-1], nil
+		},
+		OnResult: func(call Call, result string, err error) {
+			results = append(results, call.Tool+"="+result)
+		},
+	}
+
+	messages, err := a.Run(context.Background(), []provider.Message{{Role: "user", Content: "say hi"}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(messages) != 8 || messages[7].Content != "Done: hi" {
+		t.Fatalf("Run() messages = %+v", messages)
+	}
+	for i, want := range []string{"Result of echo:\n```\nhi\n```", `Error from nope: unknown tool "nope"`, "Error from tool call: invalid tool call"} {
+		if got := messages[2+2*i]; got.Role != "user" || !strings.HasPrefix(got.Content, want) {
+			t.Errorf("message %d = %+v, want %q", 2+2*i, got, want)
+		}
+	}
+	if strings.Join(results, ",") != "echo=hi,nope=" {
+		t.Errorf("OnResult calls = %v", results)
+	}
+}
+
+func TestAgentRun_MaxSteps(t *testing.T) {
+	a := &Agent{
+		MaxSteps: 2,
+		Tools:    []Tool{{Name: "loop", Run: func(context.Context, map[string]string) (string, error) { return "", nil }}},
+		Chat: func(context.Context, []provider.Message) (string, error) {
+			return "```tool\n{\"tool\": \"loop\"}\n```", nil
+		},
+	}
+	messages, err := a.Run(context.Background(), nil)
+	if !errors.Is(err, ErrMaxSteps) {
+		t.Errorf("Run() error = %v, want ErrMaxSteps", err)
+	}
+	if len(messages) != 5 {
+		t.Errorf("Run() returned %d messages, want 5", len(messages))
+	}
+}
+
+func TestAgentRun_FailedReply(t *testing.T) {
+	dropped := errors.New("stream dropped")
+	calls := 0
+	a := &Agent{
+		Tools: []Tool{{Name: "echo", Run: func(context.Context, map[string]string) (string, error) { return "", nil }}},
+		Chat: func(ctx context.Context, messages []provider.Message) (string, error) {
+			calls++
+			if calls == 1 {
+				return "```tool\n{\"tool\": \"echo\"}\n```", nil
+			}
+			return "Almost", dropped
+		},
+	}
+	messages, err := a.Run(context.Background(), []provider.Message{{Role: "user", Content: "go"}})
+	if !errors.Is(err, dropped) {
+		t.Errorf("Run() error = %v, want the chat error", err)
+	}
+	if len(messages) != 4 || messages[3].Role != "assistant" || messages[3].Content != "Almost" {
+		t.Errorf("Run() messages = %+v, want them to end with the partial reply", messages)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+
+	for _, path := range []string{"sub/a.txt", ".", filepath.Join(root, "sub")} {
+		if _, err := resolve(root, path); err != nil {
+			t.Errorf("resolve(%q) error = %v", path, err)
+		}
+	}
+	for _, path := range []string{"", "..", "sub/../../x", outside} {
+		if _, err := resolve(root, path); err == nil {
+			t.Errorf("resolve(%q) succeeded", path)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolve(root, "link"); err == nil {
+			t.Error("resolve() followed a symlink out of root")
+		}
+	}
+}
+
+func TestReadFileAndListDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if got, err := ReadFile(root).Run(ctx, map[string]string{"path": "notes.txt"}); err != nil || got != "hello\n" {
+		t.Errorf("read_file = %q, %v", got, err)
+	}
+	if got, err := ListDir(root).Run(ctx, map[string]string{}); err != nil || got != "dir/\nnotes.txt\n" {
+		t.Errorf("list_dir = %q, %v", got, err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	ctx := context.Background()
+	tool := RunCommand("/bin/sh", t.TempDir(), func(string) (bool, error) { return true, nil })
+	got, err := tool.Run(ctx, map[string]string{"command": "echo out; echo err >&2; exit 3"})
+	if err != nil || !strings.HasPrefix(got, "exit status 3\n") || !strings.Contains(got, "out\n") || !strings.Contains(got, "err\n") {
+		t.Errorf("run_command = %q, %v", got, err)
+	}
+
+	declined := RunCommand("/bin/sh", t.TempDir(), func(string) (bool, error) { return false, nil })
+	if _, err := declined.Run(ctx, map[string]string{"command": "true"}); !errors.Is(err, ErrDeclined) {
+		t.Errorf("declined run_command error = %v, want ErrDeclined", err)
+	}
+}
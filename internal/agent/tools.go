@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits on what tools send back to the model.
+const (
+	maxReadBytes    = 64 * 1024
+	maxListEntries  = 500
+	maxOutputBytes  = 32 * 1024
+	commandDeadline = 2 * time.Minute
+)
+
+// ErrDeclined means the user declined to run a command.
+var ErrDeclined = errors.New("the user declined to run this command")
+
+// ReadFile returns a tool that reads text files under root.
+func ReadFile(root string) Tool {
+	return Tool{
+		Name:        "read_file",
+		Description: fmt.Sprintf("Read a text file (up to %d KB).", maxReadBytes/1024),
+		Params:      []Param{{"path", "file path, relative to the working directory"}},
+		Run: func(ctx context.Context, args map[string]string) (string, error) {
+			path, err := resolve(root, args["path"])
+			if err != nil {
+				return "", err
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			defer f.Close()
+			data, err := io.ReadAll(io.LimitReader(f, maxReadBytes+1))
+			if err != nil {
+				return "", err
+			}
+			if bytes.IndexByte(data, 0) >= 0 {
+				return "", errors.New("binary file")
+			}
+			if len(data) > maxReadBytes {
+				return string(data[:maxReadBytes]) + "\n[truncated]", nil
+			}
+			return string(data), nil
+		},
+	}
+}
+
+// ListDir returns a tool that lists directories under root.
+func ListDir(root string) Tool {
+	return Tool{
+		Name:        "list_dir",
+		Description: "List a directory. Subdirectories end in /.",
+		Params:      []Param{{"path", `directory path, relative to the working directory ("." for it)`}},
+		Run: func(ctx context.Context, args map[string]string) (string, error) {
+			dir := args["path"]
+			if dir == "" {
+				dir = "."
+			}
+			path, err := resolve(root, dir)
+			if err != nil {
+				return "", err
+			}
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return "", err
+			}
+			var b strings.Builder
+			for i, e := range entries {
+				if i == maxListEntries {
+					fmt.Fprintf(&b, "[%d more]\n", len(entries)-i)
+					break
+				}
+				b.WriteString(e.Name())
+				if e.IsDir() {
+					b.WriteString("/")
+				}
+				b.WriteString("\n")
+			}
+			if b.Len() == 0 {
+				return "(empty)", nil
+			}
+			return b.String(), nil
+		},
+	}
+}
+
+// RunCommand returns a tool that runs shell commands in dir with shell
+// (-c, or /C for cmd.exe), each only once confirm approves it.
+func RunCommand(shell, dir string, confirm func(command string) (bool, error)) Tool {
+	return Tool{
+		Name:        "run_command",
+		Description: "Run a shell command, after the user approves it, and get its exit status and output.",
+		Params:      []Param{{"command", "the command line"}},
+		Run: func(ctx context.Context, args map[string]string) (string, error) {
+			command := strings.TrimSpace(args["command"])
+			if command == "" {
+				return "", errors.New("command is empty")
+			}
+			ok, err := confirm(command)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", ErrDeclined
+			}
+
+			flag := "-c"
+			if filepath.Base(shell) == "cmd.exe" {
+				flag = "/C"
+			}
+			ctx, cancel := context.WithTimeout(ctx, commandDeadline)
+			defer cancel()
+			var output bytes.Buffer
+			cmd := exec.CommandContext(ctx, shell, flag, command)
+			cmd.Dir = dir
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			err = cmd.Run()
+
+			var exitErr *exec.ExitError
+			status := "exit status 0"
+			switch {
+			case errors.Is(ctx.Err(), context.DeadlineExceeded):
+				status = fmt.Sprintf("killed after %s", commandDeadline)
+			case errors.As(err, &exitErr):
+				status = exitErr.Error()
+			case err != nil:
+				return "", err
+			}
+			out := output.String()
+			if len(out) > maxOutputBytes {
+				// The end of the output usually says what went wrong
+				out = "[truncated]\n" + out[len(out)-maxOutputBytes:]
+			}
+			return status + "\n" + out, nil
+		},
+	}
+}
+
+// resolve returns path, relative to root, if it is inside root. Symbolic
+// links are followed, so they cannot lead outside.
+func resolve(root, path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is empty")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	if err != nil || !filepath.IsLocal(rel) && rel != "." {
+		return "", fmt.Errorf("%s is outside the working directory", path)
+	}
+	return realPath, nil
+}
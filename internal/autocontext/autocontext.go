@@ -0,0 +1,180 @@
+// Package autocontext gathers facts about the user's environment, such as
+// the git branch and status, so that questions like "why does my build
+// fail" reach the model with the situation they were asked in.
+package autocontext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sources of context.
+const (
+	Git = "git" // repository, branch, status, and last commit
+	OS  = "os"  // operating system, architecture, and shell
+	Cwd = "cwd" // working directory and its entries
+)
+
+// Sources lists every source, in the order they are gathered.
+var Sources = []string{Git, OS, Cwd}
+
+// Limits on how much of the environment is described.
+const (
+	maxStatusLines = 30
+	maxEntries     = 50
+	timeout        = 2 * time.Second
+)
+
+// Env is where context is gathered.
+type Env struct {
+	Dir   string // working directory
+	Shell string
+}
+
+// Gather describes env from each of sources, for a system prompt. Sources
+// with nothing to say, such as git outside a repository, are left out;
+// it returns "" if all are.
+func Gather(ctx context.Context, sources []string, env Env) string {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var sections []string
+	for _, source := range Sources {
+		if !slices.Contains(sources, source) {
+			continue
+		}
+		var section string
+		switch source {
+		case Git:
+			section = gitSection(ctx, env.Dir)
+		case OS:
+			section = osSection(ctx, env.Shell)
+		case Cwd:
+			section = cwdSection(env.Dir)
+		}
+		if section != "" {
+			sections = append(sections, section)
+		}
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+	return "The user's environment, for context:\n\n" + strings.Join(sections, "\n\n")
+}
+
+// gitSection describes the repository containing dir.
+func gitSection(ctx context.Context, dir string) string {
+	root, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Git repository: %s\n", root)
+	if branch, err := git(ctx, dir, "branch", "--show-current"); err == nil && branch != "" {
+		fmt.Fprintf(&b, "Branch: %s\n", branch)
+	} else if head, err := git(ctx, dir, "rev-parse", "--short", "HEAD"); err == nil {
+		fmt.Fprintf(&b, "Branch: detached at %s\n", head)
+	}
+	if last, err := git(ctx, dir, "log", "-1", "--format=%h %s"); err == nil && last != "" {
+		fmt.Fprintf(&b, "Last commit: %s\n", last)
+	}
+
+	status, err := git(ctx, dir, "status", "--short")
+	switch {
+	case err != nil:
+	case status == "":
+		b.WriteString("Status: clean")
+	default:
+		b.WriteString("Status:\n")
+		b.WriteString(limitLines(status, maxStatusLines))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// osSection describes the operating system and shell.
+func osSection(ctx context.Context, shell string) string {
+	name := osName(ctx)
+	s := fmt.Sprintf("OS: %s (%s/%s)", name, runtime.GOOS, runtime.GOARCH)
+	if shell != "" {
+		s += "\nShell: " + shell
+	}
+	return s
+}
+
+// osName returns the name and version of the operating system, as far
+// as it can be found.
+func osName(ctx context.Context) string {
+	switch runtime.GOOS {
+	case "linux":
+		if data, err := os.ReadFile("/etc/os-release"); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if v, ok := strings.CutPrefix(line, "PRETTY_NAME="); ok {
+					return strings.Trim(v, `"'`)
+				}
+			}
+		}
+		return "Linux"
+	case "darwin":
+		if out, err := exec.CommandContext(ctx, "sw_vers", "-productVersion").Output(); err == nil {
+			return "macOS " + strings.TrimSpace(string(out))
+		}
+		return "macOS"
+	case "windows":
+		return "Windows"
+	default:
+		return runtime.GOOS
+	}
+}
+
+// cwdSection describes the working directory and what it contains.
+func cwdSection(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "Working directory: " + dir
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Working directory: %s\n", dir)
+	if len(entries) == 0 {
+		b.WriteString("(empty)")
+		return b.String()
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	b.WriteString(limitLines(strings.Join(names, "\n"), maxEntries))
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// limitLines returns up to n lines of s, noting how many were left out.
+func limitLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n... and %d more", len(lines)-n)
+}
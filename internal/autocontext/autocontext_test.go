@@ -0,0 +1,84 @@
+package autocontext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGather_None(t *testing.T) {
+	if got := Gather(context.Background(), nil, Env{Dir: t.TempDir()}); got != "" {
+		t.Errorf("Gather() = %q, want empty", got)
+	}
+	// Outside a repository, git has nothing to say
+	if got := Gather(context.Background(), []string{Git}, Env{Dir: t.TempDir()}); got != "" {
+		t.Errorf("Gather(git) = %q, want empty", got)
+	}
+}
+
+func TestGather_OS(t *testing.T) {
+	got := Gather(context.Background(), []string{OS}, Env{Shell: "/bin/zsh"})
+	if !strings.Contains(got, runtime.GOOS+"/"+runtime.GOARCH) || !strings.Contains(got, "Shell: /bin/zsh") {
+		t.Errorf("Gather(os) = %q", got)
+	}
+}
+
+func TestGather_Cwd(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for i := range maxEntries + 5 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.txt", i)), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := Gather(context.Background(), []string{Cwd}, Env{Dir: dir})
+	for _, want := range []string{"Working directory: " + dir, "f00.txt", "... and 6 more"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Gather(cwd) = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "src/") {
+		t.Errorf("Gather(cwd) listed entries past the limit: %q", got)
+	}
+}
+
+func TestGather_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "a.txt")
+	run("commit", "-q", "-m", "Add a")
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Gather(context.Background(), []string{Git, Cwd}, Env{Dir: dir})
+	for _, want := range []string{"Branch: main", "Add a", "?? b.txt", "Working directory: "} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Gather() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Index(got, "Branch:") > strings.Index(got, "Working directory:") {
+		t.Errorf("Gather() sections out of order: %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Package batch reads prompt files and reads and writes result files for
+// ask batch. Both are JSON Lines: one JSON value per line.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// maxLineSize bounds a single line of a prompt or result file.
+const maxLineSize = 16 * 1024 * 1024
+
+// Item is one prompt to run. Exactly one of Prompt and Messages is set.
+type Item struct {
+	// Line is the 1-based line number of the item in the input file.
+	Line int `json:"-"`
+
+	// ID is an optional caller-supplied identifier copied to the result.
+	ID json.RawMessage `json:"id,omitempty"`
+
+	Prompt   string             `json:"prompt,omitempty"`
+	Messages []provider.Message `json:"messages,omitempty"`
+
+	// System and Model override the command-line settings for this item.
+	System string `json:"system,omitempty"`
+	Model  string `json:"model,omitempty"`
+}
+
+// Result is the outcome of one Item. Error is empty on success.
+type Result struct {
+	Line     int             `json:"line"`
+	ID       json.RawMessage `json:"id,omitempty"`
+	Model    string          `json:"model"`
+	Response string          `json:"response"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// OK reports whether the item was answered successfully.
+func (r Result) OK() bool {
+	return r.Error == ""
+}
+
+// ParseItem parses one line of a prompt file. A line is a JSON string
+// (the prompt), an array of messages, or an object with "prompt" or
+// "messages" and optional "id", "system", and "model".
+func ParseItem(line []byte) (Item, error) {
+	line = bytes.TrimSpace(line)
+
+	var item Item
+	switch {
+	case len(line) > 0 && line[0] == '"':
+		if err := json.Unmarshal(line, &item.Prompt); err != nil {
+			return Item{}, err
+		}
+	case len(line) > 0 && line[0] == '[':
+		if err := json.Unmarshal(line, &item.Messages); err != nil {
+			return Item{}, err
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&item); err != nil {
+			return Item{}, err
+		}
+	}
+
+	switch {
+	case item.Prompt != "" && len(item.Messages) > 0:
+		return Item{}, errors.New(`only one of "prompt" and "messages" may be set`)
+	case item.Prompt == "" && len(item.Messages) == 0:
+		return Item{}, errors.New(`no "prompt" or "messages"`)
+	}
+	for _, msg := range item.Messages {
+		switch msg.Role {
+		case "system", "user", "assistant":
+		default:
+			return Item{}, fmt.Errorf("invalid message role %q", msg.Role)
+		}
+	}
+	return item, nil
+}
+
+// ReadItems parses a prompt file. Blank lines are skipped; any other line
+// that does not parse is an error naming its line number.
+func ReadItems(r io.Reader) ([]Item, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+
+	var items []Item
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		item, err := ParseItem(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		item.Line = n
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ChatMessages returns the conversation to send for the item. system is used
+// when the item has no system prompt of its own.
+func (it Item) ChatMessages(system string) []provider.Message {
+	if it.System != "" {
+		system = it.System
+	}
+
+	var messages []provider.Message
+	hasSystem := len(it.Messages) > 0 && it.Messages[0].Role == "system"
+	if system != "" && !hasSystem {
+		messages = append(messages, provider.Message{Role: "system", Content: system})
+	}
+	if it.Prompt != "" {
+		return append(messages, provider.Message{Role: "user", Content: it.Prompt})
+	}
+	return append(messages, it.Messages...)
+}
+
+// LoadResults reads a result file, returning the last result recorded for
+// each input line. A missing file has no results. Lines that do not parse,
+// such as one cut short when a run was killed, are ignored.
+func LoadResults(path string) (map[int]Result, error) {
+	results := make(map[int]Result)
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+	for scanner.Scan() {
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.Line <= 0 {
+			continue
+		}
+		results[r.Line] = r
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// WriteResult appends r to w as a single line.
+func WriteResult(w io.Writer, r Result) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Compact rewrites the result file at path to hold only the last result
+// for each line, in line order. The file is replaced atomically.
+func Compact(path string) error {
+	results, err := LoadResults(path)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]int, 0, len(results))
+	for line := range results {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ask-batch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, line := range lines {
+		if err := WriteResult(w, results[line]); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
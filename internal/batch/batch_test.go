@@ -0,0 +1,117 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseItem(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		prompt  string
+		msgs    int
+		wantErr bool
+	}{
+		{"string", `"What is Go?"`, "What is Go?", 0, false},
+		{"object", `{"id": 7, "prompt": "What is Go?", "model": "gpt-4o-mini"}`, "What is Go?", 0, false},
+		{"messages array", `[{"role": "user", "content": "hi"}]`, "", 1, false},
+		{"messages object", `{"messages": [{"role": "system", "content": "terse"}, {"role": "user", "content": "hi"}]}`, "", 2, false},
+		{"both", `{"prompt": "a", "messages": [{"role": "user", "content": "b"}]}`, "", 0, true},
+		{"neither", `{"id": 1}`, "", 0, true},
+		{"empty string", `""`, "", 0, true},
+		{"bad role", `[{"role": "tool", "content": "x"}]`, "", 0, true},
+		{"unknown field", `{"promt": "typo"}`, "", 0, true},
+		{"not json", `What is Go?`, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := ParseItem([]byte(tt.line))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseItem() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if item.Prompt != tt.prompt || len(item.Messages) != tt.msgs {
+				t.Errorf("ParseItem() = %+v, want prompt %q and %d messages", item, tt.prompt, tt.msgs)
+			}
+		})
+	}
+}
+
+func TestReadItems(t *testing.T) {
+	input := "\"first\"\n\n{\"prompt\": \"third\"}\n"
+	items, err := ReadItems(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadItems() error = %v", err)
+	}
+	if len(items) != 2 || items[0].Line != 1 || items[1].Line != 3 {
+		t.Errorf("ReadItems() = %+v, want lines 1 and 3", items)
+	}
+
+	_, err = ReadItems(strings.NewReader("\"ok\"\n{bad\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadItems() error = %v, want one naming line 2", err)
+	}
+}
+
+func TestChatMessages(t *testing.T) {
+	item := Item{Prompt: "hi"}
+	msgs := item.ChatMessages("be terse")
+	if len(msgs) != 2 || msgs[0].Role != "system" || msgs[0].Content != "be terse" {
+		t.Errorf("ChatMessages() = %+v, want default system prompt first", msgs)
+	}
+
+	item.System = "be verbose"
+	if msgs := item.ChatMessages("be terse"); msgs[0].Content != "be verbose" {
+		t.Errorf("ChatMessages() system = %q, want the item's own", msgs[0].Content)
+	}
+
+	item, _ = ParseItem([]byte(`[{"role": "system", "content": "own"}, {"role": "user", "content": "hi"}]`))
+	if msgs := item.ChatMessages("be terse"); len(msgs) != 2 || msgs[0].Content != "own" {
+		t.Errorf("ChatMessages() = %+v, want the conversation unchanged", msgs)
+	}
+}
+
+func TestLoadResultsAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+
+	results, err := LoadResults(path)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("LoadResults(missing) = %v, %v; want no results", results, err)
+	}
+
+	content := `{"line":3,"model":"m","response":"","error":"rate limited"}
+{"line":1,"model":"m","response":"one"}
+{"line":3,"model":"m","response":"three"}
+{"line":2,"model":"m","resp`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = LoadResults(path)
+	if err != nil {
+		t.Fatalf("LoadResults() error = %v", err)
+	}
+	if len(results) != 2 || !results[3].OK() || results[3].Response != "three" {
+		t.Errorf("LoadResults() = %+v, want lines 1 and 3 with the retry winning", results)
+	}
+
+	if err := Compact(path); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"line":1,"model":"m","response":"one"}
+{"line":3,"model":"m","response":"three"}
+`
+	if string(data) != want {
+		t.Errorf("Compact() wrote:\n%s\nwant:\n%s", data, want)
+	}
+}
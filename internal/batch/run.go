@@ -0,0 +1,121 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Run calls do for each item, up to concurrency at a time, and passes the
+// results to emit in item order regardless of the order they complete in.
+// emit is never called concurrently. If emit returns an error, Run cancels
+// the context given to do calls still running, starts no more, and returns
+// that error once they have finished.
+func Run[T, R any](ctx context.Context, items []T, concurrency int, do func(context.Context, T) R, emit func(R) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexed struct {
+		i int
+		r R
+	}
+	jobs := make(chan int)
+	done := make(chan indexed)
+
+	var wg sync.WaitGroup
+	for range min(concurrency, len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				done <- indexed{i, do(ctx, items[i])}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range items {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	pending := make(map[int]R)
+	next := 0
+	for d := range done {
+		if err != nil {
+			continue // drain
+		}
+		pending[d.i] = d.r
+		for r, ok := pending[next]; ok; r, ok = pending[next] {
+			delete(pending, next)
+			next++
+			if err = emit(r); err != nil {
+				cancel()
+				break
+			}
+		}
+	}
+	return err
+}
+
+// Limiter spaces out requests shared by many goroutines.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewLimiter returns a Limiter allowing one request per interval. A zero
+// interval allows requests immediately, except while paused.
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{interval: interval}
+}
+
+// Wait blocks until the caller may send a request, or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause holds back every request not yet started for at least d, such as
+// after the provider reports a rate limit.
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.next) {
+		l.next = until
+	}
+}
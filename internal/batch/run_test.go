@@ -0,0 +1,106 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_OrderAndConcurrency(t *testing.T) {
+	items := make([]Item, 20)
+	for i := range items {
+		items[i].Line = i + 1
+	}
+
+	var running, peak atomic.Int32
+	do := func(ctx context.Context, it Item) Result {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		// Later items finish first
+		time.Sleep(time.Duration(len(items)-it.Line) * time.Millisecond)
+		running.Add(-1)
+		return Result{Line: it.Line}
+	}
+
+	var got []int
+	err := Run(context.Background(), items, 4, do, func(r Result) error {
+		got = append(got, r.Line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(got) != len(items) {
+		t.Fatalf("emitted %d results, want %d", len(got), len(items))
+	}
+	for i, line := range got {
+		if line != i+1 {
+			t.Fatalf("emitted lines %v, want input order", got)
+		}
+	}
+	if p := peak.Load(); p > 4 || p < 2 {
+		t.Errorf("peak concurrency = %d, want 2-4", p)
+	}
+}
+
+func TestRun_EmitErrorStops(t *testing.T) {
+	items := make([]Item, 50)
+	for i := range items {
+		items[i].Line = i + 1
+	}
+
+	var calls atomic.Int32
+	do := func(ctx context.Context, it Item) Result {
+		calls.Add(1)
+		return Result{Line: it.Line}
+	}
+
+	stop := errors.New("stop")
+	err := Run(context.Background(), items, 2, do, func(r Result) error {
+		if r.Line == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("Run() error = %v, want %v", err, stop)
+	}
+	if n := calls.Load(); n >= int32(len(items)) {
+		t.Errorf("do called %d times, want Run to stop early", n)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(20 * time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("3 waits took %v, want at least 40ms", elapsed)
+	}
+
+	l.Pause(50 * time.Millisecond)
+	start = time.Now()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("wait after pause took %v, want about 50ms", elapsed)
+	}
+
+	l.Pause(time.Hour)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package bench summarizes latency and throughput measurements of
+// streamed responses.
+package bench
+
+import (
+	"slices"
+	"time"
+)
+
+// Sample is one measured response.
+type Sample struct {
+	// FirstToken is the time from sending the request to the first token.
+	FirstToken time.Duration
+
+	// Total is the time from sending the request to the end of the
+	// response.
+	Total time.Duration
+
+	// Tokens is the number of tokens in the response.
+	Tokens int
+}
+
+// TokensPerSecond is the generation rate after the first token, or 0 if
+// it cannot be measured.
+func (s Sample) TokensPerSecond() float64 {
+	gen := s.Total - s.FirstToken
+	if gen <= 0 || s.Tokens < 2 {
+		return 0
+	}
+	// The first token's time is in FirstToken
+	return float64(s.Tokens-1) / gen.Seconds()
+}
+
+// Summary condenses the samples of one model.
+type Summary struct {
+	Runs            int
+	FirstToken      time.Duration // median
+	Total           time.Duration // median
+	TotalP90        time.Duration
+	TokensPerSecond float64 // median
+}
+
+// Summarize returns the medians (and 90th percentile total) of samples.
+func Summarize(samples []Sample) Summary {
+	s := Summary{Runs: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+	var first, total []time.Duration
+	var rates []float64
+	for _, sample := range samples {
+		first = append(first, sample.FirstToken)
+		total = append(total, sample.Total)
+		if r := sample.TokensPerSecond(); r > 0 {
+			rates = append(rates, r)
+		}
+	}
+	s.FirstToken = Percentile(first, 50)
+	s.Total = Percentile(total, 50)
+	s.TotalP90 = Percentile(total, 90)
+	s.TokensPerSecond = Percentile(rates, 50)
+	return s
+}
+
+// Percentile returns the p-th percentile of values by linear
+// interpolation between the closest ranks, or zero for no values.
+func Percentile[T time.Duration | float64](values []T, p float64) T {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lo)
+	return sorted[lo] + T(frac*float64(sorted[lo+1]-sorted[lo]))
+}
@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 2.5},
+		{90, 3.7},
+		{100, 4},
+	}
+	for _, tt := range tests {
+		if got := Percentile(values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if got := Percentile([]float64(nil), 50); got != 0 {
+		t.Errorf("Percentile(nil) = %v, want 0", got)
+	}
+	if values[0] != 4 {
+		t.Errorf("Percentile sorted its input")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := []Sample{
+		{FirstToken: 200 * time.Millisecond, Total: 1200 * time.Millisecond, Tokens: 101},
+		{FirstToken: 400 * time.Millisecond, Total: 2400 * time.Millisecond, Tokens: 101},
+		{FirstToken: 300 * time.Millisecond, Total: 1300 * time.Millisecond, Tokens: 51},
+	}
+	s := Summarize(samples)
+	if s.Runs != 3 || s.FirstToken != 300*time.Millisecond || s.Total != 1300*time.Millisecond {
+		t.Errorf("Summarize() = %+v", s)
+	}
+	if s.TotalP90 != 2180*time.Millisecond {
+		t.Errorf("TotalP90 = %v, want 2.18s", s.TotalP90)
+	}
+	// Rates are 100, 50, and 50 tokens/s
+	if s.TokensPerSecond != 50 {
+		t.Errorf("TokensPerSecond = %v, want 50", s.TokensPerSecond)
+	}
+
+	if got := (Sample{FirstToken: time.Second, Total: time.Second, Tokens: 1}).TokensPerSecond(); got != 0 {
+		t.Errorf("TokensPerSecond() of a single token = %v, want 0", got)
+	}
+	if got := Summarize(nil); got.Runs != 0 || got.Total != 0 {
+		t.Errorf("Summarize(nil) = %+v", got)
+	}
+}
@@ -0,0 +1,127 @@
+// Package budget checks recorded spending against daily and monthly
+// limits.
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/devaloi/ask/internal/config"
+)
+
+// ErrExceeded means a spending limit has been reached.
+var ErrExceeded = errors.New("spending budget exceeded")
+
+// ErrUnpriced means a model's spending cannot be counted against a limit
+// because its price is unknown.
+var ErrUnpriced = errors.New("no known price to budget for")
+
+// Period is the span a limit covers, in local time.
+type Period int
+
+const (
+	Day Period = iota
+	Month
+)
+
+// Start returns the start of the period containing now.
+func (p Period) Start(now time.Time) time.Time {
+	y, m, d := now.Date()
+	if p == Month {
+		d = 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+func (p Period) String() string {
+	if p == Month {
+		return "monthly"
+	}
+	return "daily"
+}
+
+// Limit caps spending in US dollars over a period.
+type Limit struct {
+	Provider string // empty for all providers together
+	Period   Period
+	Amount   float64
+}
+
+func (l Limit) String() string {
+	if l.Provider == "" {
+		return l.Period.String() + " budget"
+	}
+	return fmt.Sprintf("%s %s budget", l.Period, l.Provider)
+}
+
+// Limits returns the limits in b that apply to a request to providerName:
+// the overall limits and that provider's own. An empty providerName
+// returns the limits of every provider.
+func Limits(b config.Budget, providerName string) []Limit {
+	var limits []Limit
+	add := func(name string, bl config.BudgetLimits) {
+		if bl.Daily > 0 {
+			limits = append(limits, Limit{Provider: name, Period: Day, Amount: bl.Daily})
+		}
+		if bl.Monthly > 0 {
+			limits = append(limits, Limit{Provider: name, Period: Month, Amount: bl.Monthly})
+		}
+	}
+
+	add("", b.BudgetLimits)
+	names := make([]string, 0, len(b.Providers))
+	for name := range b.Providers {
+		if providerName == "" || name == providerName {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		add(name, b.Providers[name])
+	}
+	return limits
+}
+
+// SpendFunc returns the spending since a time for one provider, or for
+// all of them if providerName is empty.
+type SpendFunc func(providerName string, since time.Time) (float64, error)
+
+// Status is a limit and the spending so far in its current period.
+type Status struct {
+	Limit
+	Spent float64
+}
+
+// Exceeded reports whether the limit has been reached.
+func (s Status) Exceeded() bool {
+	return s.Spent >= s.Amount
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf("$%.2f of the $%.2f %s", s.Spent, s.Amount, s.Limit)
+}
+
+// Check returns the status of each limit at time now.
+func Check(limits []Limit, now time.Time, spent SpendFunc) ([]Status, error) {
+	statuses := make([]Status, 0, len(limits))
+	for _, l := range limits {
+		amount, err := spent(l.Provider, l.Period.Start(now))
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, Status{Limit: l, Spent: amount})
+	}
+	return statuses, nil
+}
+
+// FirstExceeded returns the first exceeded limit in statuses, or nil.
+func FirstExceeded(statuses []Status) *Status {
+	for i := range statuses {
+		if statuses[i].Exceeded() {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package budget
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/devaloi/ask/internal/config"
+)
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2025, 3, 14, 15, 9, 26, 0, time.Local)
+	if got, want := Day.Start(now), time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("Day.Start() = %v, want %v", got, want)
+	}
+	if got, want := Month.Start(now), time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("Month.Start() = %v, want %v", got, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	b := config.Budget{
+		BudgetLimits: config.BudgetLimits{Monthly: 50},
+		Providers: map[string]config.BudgetLimits{
+			"openai":    {Daily: 2, Monthly: 20},
+			"anthropic": {Daily: 1},
+		},
+	}
+
+	tests := []struct {
+		provider string
+		want     []Limit
+	}{
+		{"openai", []Limit{{"", Month, 50}, {"openai", Day, 2}, {"openai", Month, 20}}},
+		{"other", []Limit{{"", Month, 50}}},
+		{"", []Limit{{"", Month, 50}, {"anthropic", Day, 1}, {"openai", Day, 2}, {"openai", Month, 20}}},
+	}
+	for _, tt := range tests {
+		got := Limits(b, tt.provider)
+		if len(got) != len(tt.want) {
+			t.Errorf("Limits(%q) = %v, want %v", tt.provider, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Limits(%q)[%d] = %v, want %v", tt.provider, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	if got := Limits(config.Budget{}, "openai"); len(got) != 0 {
+		t.Errorf("Limits() with no budget = %v, want none", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2025, 3, 14, 12, 0, 0, 0, time.Local)
+	spent := func(providerName string, since time.Time) (float64, error) {
+		switch {
+		case providerName == "openai" && since.Day() == 14:
+			return 2.5, nil
+		case providerName == "openai":
+			return 10, nil
+		default:
+			return 30, nil
+		}
+	}
+
+	limits := []Limit{{"", Month, 50}, {"openai", Day, 2}, {"openai", Month, 20}}
+	statuses, err := Check(limits, now, spent)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	wantSpent := []float64{30, 2.5, 10}
+	for i, s := range statuses {
+		if s.Spent != wantSpent[i] {
+			t.Errorf("status %d spent = %v, want %v", i, s.Spent, wantSpent[i])
+		}
+	}
+
+	over := FirstExceeded(statuses)
+	if over == nil || over.Limit != limits[1] {
+		t.Fatalf("FirstExceeded() = %v, want the daily openai limit", over)
+	}
+	if got, want := over.String(), "$2.50 of the $2.00 daily openai budget"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if FirstExceeded(statuses[2:]) != nil {
+		t.Errorf("FirstExceeded() reported a limit that was not reached")
+	}
+
+	failing := func(string, time.Time) (float64, error) { return 0, errors.New("no database") }
+	if _, err := Check(limits, now, failing); err == nil {
+		t.Errorf("Check() with a failing SpendFunc succeeded")
+	}
+}
@@ -0,0 +1,166 @@
+// Package cache stores responses on disk so that repeated identical
+// requests can be answered without calling the provider.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// DefaultTTL is how long responses are reused unless configured otherwise.
+const DefaultTTL = 24 * time.Hour
+
+// Cache is a directory of cached responses, one file per request.
+type Cache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// entry is the on-disk form of a cached response.
+type entry struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	Response  string    `json:"response"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New returns a cache in dir whose entries expire after ttl (DefaultTTL
+// if ttl is not positive). The directory is created on first write.
+func New(dir string, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// Key identifies a request: the provider, model, messages, and sampling
+// parameters all have to match for a cached response to be reused.
+func Key(providerName string, req *provider.ChatRequest) string {
+	data, _ := json.Marshal(struct {
+		Provider    string             `json:"provider"`
+		Model       string             `json:"model"`
+		Messages    []provider.Message `json:"messages"`
+		Temperature float64            `json:"temperature"`
+		MaxTokens   int                `json:"max_tokens"`
+		WebSearch   bool               `json:"web_search,omitempty"`
+	}{providerName, req.Model, req.Messages, req.Temperature, req.MaxTokens, req.WebSearch})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get returns the cached response for key, if there is one that has not
+// expired. Expired and unreadable entries are removed.
+func (c *Cache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || c.now().Sub(e.CreatedAt) > c.ttl {
+		_ = os.Remove(c.path(key))
+		return "", false
+	}
+	return e.Response, true
+}
+
+// Put stores response for key.
+func (c *Cache) Put(key, providerName, model, response string) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+	data, err := json.Marshal(entry{Provider: providerName, Model: model, Response: response, CreatedAt: c.now()})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every entry and returns how many there were.
+func (c *Cache) Clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, name)); err != nil {
+			return removed, err
+		}
+		if strings.HasSuffix(name, ".json") {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Middleware answers chat requests from the cache when it can, without
+// calling the provider, and caches responses that complete. putFailed,
+// if not nil, is called with errors storing a response.
+func (c *Cache) Middleware(putFailed func(error)) provider.Middleware {
+	return func(p provider.Provider) provider.Provider {
+		return provider.WrapChat(p, func(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+			key := Key(p.Name(), req)
+			if response, ok := c.Get(key); ok {
+				defer close(stream)
+				select {
+				case stream <- response:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			response, err := provider.Forward(ctx, p.Chat, req, stream)
+			if err != nil {
+				return err
+			}
+			if err := c.Put(key, p.Name(), req.Model, response); err != nil && putFailed != nil {
+				putFailed(err)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+func TestKey(t *testing.T) {
+	base := &provider.ChatRequest{Model: "gpt-4o", Messages: []provider.Message{{Role: "user", Content: "hi"}}}
+	key := Key("openai", base)
+
+	tests := []struct {
+		name     string
+		provider string
+		req      *provider.ChatRequest
+		same     bool
+	}{
+		{"identical", "openai", &provider.ChatRequest{Model: "gpt-4o", Messages: []provider.Message{{Role: "user", Content: "hi"}}}, true},
+		{"provider", "anthropic", base, false},
+		{"model", "openai", &provider.ChatRequest{Model: "gpt-4o-mini", Messages: base.Messages}, false},
+		{"content", "openai", &provider.ChatRequest{Model: "gpt-4o", Messages: []provider.Message{{Role: "user", Content: "hi!"}}}, false},
+		{"role", "openai", &provider.ChatRequest{Model: "gpt-4o", Messages: []provider.Message{{Role: "system", Content: "hi"}}}, false},
+		{"temperature", "openai", &provider.ChatRequest{Model: "gpt-4o", Messages: base.Messages, Temperature: 0.5}, false},
+		{"max tokens", "openai", &provider.ChatRequest{Model: "gpt-4o", Messages: base.Messages, MaxTokens: 10}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Key(tt.provider, tt.req) == key; got != tt.same {
+				t.Errorf("same key = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "responses")
+	c := New(dir, time.Hour)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("Get() on empty cache found an entry")
+	}
+	if err := c.Put("k", "openai", "gpt-4o", "hello"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got, ok := c.Get("k"); !ok || got != "hello" {
+		t.Errorf("Get() = %q, %v; want hello, true", got, ok)
+	}
+
+	// Entries expire after the TTL and are removed
+	now = now.Add(2 * time.Hour)
+	if _, ok := c.Get("k"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "k.json")); !os.IsNotExist(err) {
+		t.Errorf("expired entry not removed: %v", err)
+	}
+
+	// Corrupt entries are misses
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("bad"); ok {
+		t.Error("Get() returned a corrupt entry")
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir, 0)
+	for _, k := range []string{"a", "b"} {
+		if err := c.Put(k, "openai", "gpt-4o", k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "keep.txt"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := c.Clear()
+	if err != nil || n != 2 {
+		t.Errorf("Clear() = %d, %v; want 2, nil", n, err)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("entry survived Clear()")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "keep.txt")); err != nil {
+		t.Errorf("Clear() removed an unrelated file: %v", err)
+	}
+
+	if n, err := New(filepath.Join(dir, "missing"), 0).Clear(); n != 0 || err != nil {
+		t.Errorf("Clear() of missing dir = %d, %v", n, err)
+	}
+}
+
+// echoProvider answers with the last message and counts its calls.
+type echoProvider struct {
+	calls int
+}
+
+func (e *echoProvider) Chat(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+	defer close(stream)
+	e.calls++
+	stream <- req.Messages[len(req.Messages)-1].Content
+	return nil
+}
+
+func (e *echoProvider) BuildRequest(ctx context.Context, req *provider.ChatRequest) (*http.Request, error) {
+	return nil, nil
+}
+
+func (e *echoProvider) Models() []string { return nil }
+func (e *echoProvider) Name() string     { return "echo" }
+
+func TestMiddleware(t *testing.T) {
+	c := New(t.TempDir(), time.Hour)
+	e := &echoProvider{}
+	p := provider.Chain(e, c.Middleware(func(err error) { t.Errorf("Put failed: %v", err) }))
+
+	send := func(content string) string {
+		t.Helper()
+		stream := make(chan string, 1)
+		req := &provider.ChatRequest{Model: "m", Messages: []provider.Message{{Role: "user", Content: content}}}
+		if err := p.Chat(context.Background(), req, stream); err != nil {
+			t.Fatalf("Chat() error = %v", err)
+		}
+		var b strings.Builder
+		for token := range stream {
+			b.WriteString(token)
+		}
+		return b.String()
+	}
+
+	if got := send("one"); got != "one" {
+		t.Errorf("first response = %q", got)
+	}
+	if got := send("one"); got != "one" {
+		t.Errorf("cached response = %q", got)
+	}
+	send("two")
+	if e.calls != 2 {
+		t.Errorf("provider called %d times, want 2 (one per distinct request)", e.calls)
+	}
+}
@@ -0,0 +1,71 @@
+// Package clipboard reads the system clipboard using the platform's
+// command-line tools.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// ErrUnavailable is returned when no clipboard tool is installed.
+var ErrUnavailable = errors.New("no clipboard tool found")
+
+// Read returns the text contents of the clipboard.
+func Read() (string, error) {
+	candidates := commands(runtime.GOOS, os.Getenv("WAYLAND_DISPLAY") != "")
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("clipboard is not supported on %s", runtime.GOOS)
+	}
+
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c[0]); err != nil {
+			continue
+		}
+		out, err := exec.Command(c[0], c[1:]...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+				return "", fmt.Errorf("%s: %s", c[0], strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", fmt.Errorf("%s: %w", c[0], err)
+		}
+		text := string(out)
+		if runtime.GOOS == "windows" {
+			text = strings.ReplaceAll(text, "\r\n", "\n")
+		}
+		return text, nil
+	}
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c[0])
+	}
+	return "", fmt.Errorf("%w: install one of %s", ErrUnavailable, strings.Join(names, ", "))
+}
+
+// commands returns the commands that print the clipboard on goos, in
+// order of preference.
+func commands(goos string, wayland bool) [][]string {
+	switch goos {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw"}}
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		x11 := [][]string{
+			{"xclip", "-selection", "clipboard", "-o"},
+			{"xsel", "--clipboard", "--output"},
+		}
+		wl := []string{"wl-paste", "--no-newline"}
+		if wayland {
+			return append([][]string{wl}, x11...)
+		}
+		return append(x11, wl)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,29 @@
+package clipboard
+
+import "testing"
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		goos    string
+		wayland bool
+		first   string
+		count   int
+	}{
+		{goos: "darwin", first: "pbpaste", count: 1},
+		{goos: "windows", first: "powershell", count: 1},
+		{goos: "linux", first: "xclip", count: 3},
+		{goos: "linux", wayland: true, first: "wl-paste", count: 3},
+		{goos: "plan9", count: 0},
+	}
+
+	for _, tt := range tests {
+		got := commands(tt.goos, tt.wayland)
+		if len(got) != tt.count {
+			t.Errorf("commands(%q, %v) returned %d commands, want %d", tt.goos, tt.wayland, len(got), tt.count)
+			continue
+		}
+		if tt.count > 0 && got[0][0] != tt.first {
+			t.Errorf("commands(%q, %v)[0] = %q, want %q", tt.goos, tt.wayland, got[0][0], tt.first)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package codeblock extracts code from Markdown-formatted model responses.
+package codeblock
+
+import "strings"
+
+// Block is a fenced code block.
+type Block struct {
+	Info string // the text after the opening fence, such as a language
+	Code string // the contents, each line ending in a newline
+}
+
+// Parse returns the fenced code blocks in s, opened by a line starting
+// with three or more backticks or tildes and closed by a line of at least
+// as many of the same character. A block left open runs to the end of s.
+func Parse(s string) []Block {
+	var blocks []Block
+	var fence string
+	var cur Block
+	var code strings.Builder
+
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if f := fencePrefix(trimmed); f != "" {
+				fence = f
+				cur = Block{Info: strings.TrimSpace(trimmed[len(f):])}
+				code.Reset()
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			cur.Code = code.String()
+			blocks = append(blocks, cur)
+			fence = ""
+			continue
+		}
+		code.WriteString(line)
+		code.WriteByte('\n')
+	}
+
+	if fence != "" {
+		cur.Code = code.String()
+		blocks = append(blocks, cur)
+	}
+	return blocks
+}
+
+// fencePrefix returns the run of three or more backticks or tildes that
+// line starts with, or "".
+func fencePrefix(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// Extract returns the code from a response that should consist of code:
+// the longest fenced block, or the whole response without surrounding
+// blank lines when it has no fences. The result ends in a newline unless
+// it is empty.
+func Extract(s string) string {
+	var code string
+	for _, b := range Parse(s) {
+		if len(b.Code) > len(code) {
+			code = b.Code
+		}
+	}
+	if code == "" {
+		code = strings.Trim(s, "\n")
+		if strings.TrimSpace(code) == "" {
+			return ""
+		}
+		code += "\n"
+	}
+	return code
+}
@@ -0,0 +1,44 @@
+package codeblock
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	s := "Here you go:\n\n````markdown\nUse:\n```\nask\n```\n````\n\nand a shell one:\n~~~\necho ```\n~~~~\n"
+	blocks := Parse(s)
+
+	want := []Block{
+		{Info: "markdown", Code: "Use:\n```\nask\n```\n"},
+		{Info: "", Code: "echo ```\n"},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("Parse() returned %d blocks, want %d: %+v", len(blocks), len(want), blocks)
+	}
+	for i := range want {
+		if blocks[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], want[i])
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no fences", "\n\nx := 1\ny := 2\n\n", "x := 1\ny := 2\n"},
+		{"single block", "Sure:\n```go\nx := 1\n```\nDone.", "x := 1\n"},
+		{"longest block", "```sh\ngo test\n```\n```go\nfunc f() {\n}\n```\n", "func f() {\n}\n"},
+		{"unclosed block", "```go\nx := 1\ny := 2", "x := 1\ny := 2\n"},
+		{"indented fence", "  ```\n  x\n  ```\n", "  x\n"},
+		{"empty", "  \n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.in); got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Package compact shortens conversations that approach a model's context
+// window by replacing their older turns with a summary.
+package compact
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+const (
+	// Threshold is the share of the context window a conversation may use
+	// before it is compacted.
+	Threshold = 0.75
+
+	// RecentShare is the share of the context window kept verbatim from
+	// the end of a compacted conversation.
+	RecentShare = 0.25
+
+	// messageOverhead approximates the tokens each message costs beyond
+	// its content (role and separators).
+	messageOverhead = 4
+)
+
+// summarizePrompt instructs the model that writes summaries.
+const summarizePrompt = `You summarize conversations so they can continue within a smaller context.
+Summarize the conversation you are given, keeping the facts, decisions, code,
+names, and open questions that later turns may refer to. Write concise notes
+in the third person, with no preamble.`
+
+// Size estimates the tokens messages use.
+func Size(messages []provider.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += tokens.Estimate(m.Content) + messageOverhead
+	}
+	return n
+}
+
+// Cut returns the range messages[start:end] to replace with a summary.
+// The range is empty when the messages fit within Threshold of window,
+// window is unknown (zero), or there is too little to summarize.
+//
+// Leading system messages are always kept, as are the most recent
+// messages that fit in RecentShare of the window (and at least the last
+// message). The kept messages start at a user turn.
+func Cut(messages []provider.Message, window int) (start, end int) {
+	if window <= 0 || float64(Size(messages)) <= Threshold*float64(window) {
+		return 0, 0
+	}
+
+	for start < len(messages) && messages[start].Role == "system" {
+		start++
+	}
+
+	budget := int(RecentShare * float64(window))
+	end = len(messages) - 1
+	kept := Size(messages[end:])
+	for end > start {
+		size := Size(messages[end-1 : end])
+		if kept+size > budget {
+			break
+		}
+		kept += size
+		end--
+	}
+	for end < len(messages)-1 && messages[end].Role != "user" {
+		end++
+	}
+
+	if end-start < 2 {
+		return 0, 0
+	}
+	return start, end
+}
+
+// Request returns the messages asking a model to summarize messages.
+// previous is an earlier summary of the conversation before them, if any,
+// which the new summary replaces.
+func Request(previous string, messages []provider.Message) []provider.Message {
+	var b strings.Builder
+	if previous != "" {
+		fmt.Fprintf(&b, "Summary of the conversation so far:\n\n%s\n\nIt continues:\n\n", strings.TrimSpace(previous))
+	}
+	for i, m := range messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "%s: %s", roleLabel(m.Role), strings.TrimSpace(m.Content))
+	}
+
+	return []provider.Message{
+		{Role: "system", Content: summarizePrompt},
+		{Role: "user", Content: b.String()},
+	}
+}
+
+// Message returns the message that stands in for the summarized turns.
+func Message(summary string) provider.Message {
+	return provider.Message{
+		Role:    "system",
+		Content: "Summary of the earlier part of this conversation:\n\n" + strings.TrimSpace(summary),
+	}
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return role
+}
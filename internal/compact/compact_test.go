@@ -0,0 +1,80 @@
+package compact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// turns returns a system message followed by n user/assistant messages of
+// roughly size tokens each.
+func turns(n, size int) []provider.Message {
+	msgs := []provider.Message{{Role: "system", Content: "Be brief."}}
+	for i := range n {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msgs = append(msgs, provider.Message{Role: role, Content: strings.Repeat("word ", size)})
+	}
+	return msgs
+}
+
+func TestCut(t *testing.T) {
+	tests := []struct {
+		name      string
+		messages  []provider.Message
+		window    int
+		wantStart int
+		wantEnd   int
+	}{
+		{"fits", turns(4, 100), 1000, 0, 0},
+		{"unknown window", turns(40, 100), 0, 0, 0},
+		// Each turn is ~104 tokens: over 750, keep what fits in 250 from
+		// a user turn
+		{"compacts older turns", turns(10, 100), 1000, 1, 9},
+		{"keeps user turn first", turns(9, 100), 1000, 1, 9},
+		{"nothing to summarize", turns(2, 1000), 1000, 0, 0},
+		{"last message always kept", turns(5, 400), 1000, 1, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := Cut(tt.messages, tt.window)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("Cut() = %d, %d; want %d, %d", start, end, tt.wantStart, tt.wantEnd)
+			}
+			if end > start && tt.messages[end].Role != "user" && end != len(tt.messages)-1 {
+				t.Errorf("kept messages start with %s", tt.messages[end].Role)
+			}
+		})
+	}
+}
+
+func TestRequest(t *testing.T) {
+	msgs := []provider.Message{
+		{Role: "user", Content: "What is Go?"},
+		{Role: "assistant", Content: "A language.\n"},
+	}
+
+	req := Request("", msgs)
+	if len(req) != 2 || req[0].Role != "system" || req[1].Role != "user" {
+		t.Fatalf("Request() = %+v", req)
+	}
+	if want := "User: What is Go?\n\nAssistant: A language."; req[1].Content != want {
+		t.Errorf("transcript = %q, want %q", req[1].Content, want)
+	}
+
+	req = Request("They discussed Rust.", msgs)
+	if !strings.HasPrefix(req[1].Content, "Summary of the conversation so far:\n\nThey discussed Rust.") {
+		t.Errorf("transcript with previous summary = %q", req[1].Content)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	m := Message(" notes \n")
+	if m.Role != "system" || !strings.HasSuffix(m.Content, "\n\nnotes") {
+		t.Errorf("Message() = %+v", m)
+	}
+}
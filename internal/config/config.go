@@ -8,9 +8,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/devaloi/ask/internal/keyring"
+	"github.com/devaloi/ask/internal/routing"
+	"github.com/devaloi/ask/internal/secret"
 )
 
 // Config holds all application configuration.
@@ -18,22 +24,323 @@ type Config struct {
 	DefaultProvider string              `yaml:"default_provider"`
 	DefaultModel    string              `yaml:"default_model"`
 	Providers       map[string]Provider `yaml:"providers"`
+
+	// MaxIncludeBytes caps the total size of files included with -f.
+	// Zero or negative disables the limit.
+	MaxIncludeBytes int64 `yaml:"max_include_bytes"`
+
+	// MaxStdinBytes is the piped input size above which ask asks for
+	// confirmation (or refuses, without a terminal) before sending.
+	// Zero or negative disables the check.
+	MaxStdinBytes int64 `yaml:"max_stdin_bytes"`
+
+	// PromptWarnTokens is the estimated request size, in tokens, above
+	// which ask warns (or refuses, with --strict) before sending. Zero
+	// or negative checks only against the model's context window.
+	PromptWarnTokens int `yaml:"prompt_warn_tokens"`
+
+	// Redact decides what happens to secrets, such as API keys and
+	// private keys, found in a prompt: RedactWarn, RedactMask,
+	// RedactBlock, or RedactOff.
+	Redact string `yaml:"redact"`
+
+	// Scrub, if ScrubPII, replaces email addresses, phone numbers, and
+	// IP addresses in requests with placeholders, and puts them back in
+	// responses. ScrubOff (the default) sends requests as they are.
+	Scrub string `yaml:"scrub"`
+
+	// LogFile, if set, receives a plain-text transcript of every exchange.
+	LogFile string `yaml:"log_file"`
+
+	// LogLevel enables the diagnostic log at this level (debug, info,
+	// warn, or error). ASK_DEBUG=1 sets it to debug.
+	LogLevel string `yaml:"log_level"`
+
+	// DebugLog writes the diagnostic log to debug.log in the data
+	// directory instead of stderr.
+	DebugLog bool `yaml:"debug_log"`
+
+	// SaveHistory controls when one-shot chats are saved to history:
+	// SaveHistoryTTY (only when output is a terminal), SaveHistoryAlways,
+	// or SaveHistoryNever.
+	SaveHistory string `yaml:"save_history"`
+
+	// DefaultSystem is a system prompt (or @filepath) used for every
+	// chat. -s adds to it.
+	DefaultSystem string `yaml:"default_system"`
+
+	// AutoContext adds facts about the environment to the system prompt,
+	// from any of the sources in autocontext.Sources (git, os, cwd).
+	AutoContext []string `yaml:"auto_context"`
+
+	// NotifyAfter, if positive, sends a desktop notification when a
+	// response takes at least this long (e.g. "30s").
+	NotifyAfter time.Duration `yaml:"notify_after"`
+
+	// Pager pages responses longer than the screen through $PAGER once
+	// they complete.
+	Pager bool `yaml:"pager"`
+
+	// ContextPolicy decides what happens when a conversation nears the
+	// model's context window: ContextPolicySummarize, ContextPolicyDrop,
+	// or ContextPolicyError.
+	ContextPolicy string `yaml:"context_policy"`
+
+	// ContextWindows sets the context window, in tokens, of models ask
+	// does not know, or overrides the built-in sizes.
+	ContextWindows map[string]int `yaml:"context_windows"`
+
+	// Models describes models ask does not know, or overrides what it
+	// knows of them, by name.
+	Models map[string]Model `yaml:"models"`
+
+	// SummaryModel summarizes the older turns of conversations that
+	// approach the model's context window. Empty uses the provider's
+	// small model.
+	SummaryModel string `yaml:"summary_model"`
+
+	// Cache reuses responses to identical one-shot requests.
+	Cache Cache `yaml:"cache"`
+
+	// Budget limits daily and monthly spending, estimated from recorded
+	// usage.
+	Budget Budget `yaml:"budget"`
+
+	// Retry decides which failed requests are sent again, and when, for
+	// all providers together and for each provider.
+	Retry Retry `yaml:"retry"`
+
+	// AutoContinue asks for the rest of responses cut short by the token
+	// limit.
+	AutoContinue AutoContinue `yaml:"auto_continue"`
+
+	// Routes pick the model for one-shot requests that do not name one,
+	// by the first rule the request matches.
+	Routes []routing.Rule `yaml:"routes"`
+
+	// Personas are named combinations of system prompt, model, and
+	// provider, selected with --as.
+	Personas map[string]Persona `yaml:"personas"`
+
+	// Theme controls terminal colors.
+	Theme Theme `yaml:"theme"`
+
+	// Passphrase, if set, is asked for the passphrase of encrypted API
+	// keys when PassphraseEnv is not set.
+	Passphrase func() (string, error) `yaml:"-"`
+
+	// keys holds the encryption keys found so far, by method and salt.
+	keys map[string]secret.Key
+}
+
+// Theme holds color settings. Each color field is a spec such as
+// "bold cyan", "208", or "#ff8800"; empty fields use the preset's color.
+type Theme struct {
+	Preset    string `yaml:"preset"` // dark (default), light, or none
+	User      string `yaml:"user"`
+	Assistant string `yaml:"assistant"`
+	Code      string `yaml:"code"`
+	Error     string `yaml:"error"`
+	Prompt    string `yaml:"prompt"`
+	Separator string `yaml:"separator"`
+}
+
+// Cache holds response cache settings.
+type Cache struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"` // how long responses are reused; default 24h
+}
+
+// AutoContinue holds the settings for continuing responses cut short by
+// the token limit.
+type AutoContinue struct {
+	Enabled bool `yaml:"enabled"`
+	Limit   int  `yaml:"limit"` // most continuations of a response; default 3
+}
+
+// BudgetLimits are spending limits in US dollars. Zero means no limit.
+type BudgetLimits struct {
+	Daily   float64 `yaml:"daily"`
+	Monthly float64 `yaml:"monthly"`
 }
 
+// Budget holds spending limits for all providers together and for each
+// provider.
+type Budget struct {
+	BudgetLimits `yaml:",inline"`
+	Providers    map[string]BudgetLimits `yaml:"providers"`
+
+	// Policy decides what happens once a limit is reached:
+	// BudgetPolicyWarn (default) or BudgetPolicyRefuse.
+	Policy string `yaml:"policy"`
+}
+
+// RetryPolicy decides which failed requests are sent again, and when.
+// Zero fields are unset: a provider's policy takes the values of the one
+// for all providers, which takes DefaultRetryPolicy's.
+type RetryPolicy struct {
+	MaxAttempts int           `yaml:"max_attempts"` // sends in all, including the first; 1 turns retries off
+	Backoff     time.Duration `yaml:"backoff"`      // pause before the first retry
+	Multiplier  float64       `yaml:"multiplier"`   // how much longer each later pause is
+	MaxElapsed  time.Duration `yaml:"max_elapsed"`  // no retry starts later than this after the first send
+	StatusCodes []int         `yaml:"status_codes"` // HTTP statuses retried
+}
+
+// DefaultRetryPolicy retries rate limits and overloaded or failing
+// servers three times, after 5, 10, and 20 seconds, or when the provider
+// says its limit resets, but gives up rather than wait past two minutes.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	Backoff:     5 * time.Second,
+	Multiplier:  2,
+	MaxElapsed:  2 * time.Minute,
+	StatusCodes: []int{429, 500, 502, 503, 504, 529},
+}
+
+// Model is what ask knows of a model, as it is used for context windows,
+// costs, routing, and ask models. Unset fields keep the built-in values.
+type Model struct {
+	Provider        string  `yaml:"provider"`
+	ContextWindow   int     `yaml:"context_window"`   // tokens
+	InputPrice      float64 `yaml:"input_price"`      // US dollars per million tokens
+	OutputPrice     float64 `yaml:"output_price"`     // US dollars per million tokens
+	Vision          *bool   `yaml:"vision"`           // accepts images
+	Tools           *bool   `yaml:"tools"`            // can call tools
+	KnowledgeCutoff string  `yaml:"knowledge_cutoff"` // YYYY-MM
+}
+
+// Retry holds the retry policy for all providers together and for each
+// provider.
+type Retry struct {
+	RetryPolicy `yaml:",inline"`
+	Providers   map[string]RetryPolicy `yaml:"providers"`
+
+	// Resume reconnects when a response's stream fails partway and
+	// continues it from where it broke off, with providers that can.
+	Resume bool `yaml:"resume"`
+}
+
+// or returns p with its unset fields taken from q.
+func (p RetryPolicy) or(q RetryPolicy) RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = q.MaxAttempts
+	}
+	if p.Backoff == 0 {
+		p.Backoff = q.Backoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = q.Multiplier
+	}
+	if p.MaxElapsed == 0 {
+		p.MaxElapsed = q.MaxElapsed
+	}
+	if p.StatusCodes == nil {
+		p.StatusCodes = q.StatusCodes
+	}
+	return p
+}
+
+// RetryFor returns the retry policy for the named provider.
+func (c *Config) RetryFor(providerName string) RetryPolicy {
+	return c.Retry.Providers[providerName].or(c.Retry.RetryPolicy).or(DefaultRetryPolicy)
+}
+
+// Budget policy values.
+const (
+	BudgetPolicyWarn   = "warn"
+	BudgetPolicyRefuse = "refuse"
+)
+
+// Persona is a reusable chat mode. Empty fields leave the defaults alone.
+type Persona struct {
+	System   string `yaml:"system,omitempty"` // system prompt or @filepath
+	Model    string `yaml:"model,omitempty"`
+	Provider string `yaml:"provider,omitempty"`
+
+	// AutoContext replaces the config file's auto_context; "none" turns
+	// it off.
+	AutoContext []string `yaml:"auto_context,omitempty"`
+}
+
+// SaveHistory values.
+const (
+	SaveHistoryTTY    = "tty"
+	SaveHistoryAlways = "always"
+	SaveHistoryNever  = "never"
+)
+
+// Redact values.
+const (
+	RedactWarn  = "warn"
+	RedactMask  = "mask"
+	RedactBlock = "block"
+	RedactOff   = "off"
+)
+
+// Scrub values.
+const (
+	ScrubOff = "off"
+	ScrubPII = "pii"
+)
+
+// ContextPolicy values.
+const (
+	ContextPolicySummarize = "summarize"
+	ContextPolicyDrop      = "drop-oldest"
+	ContextPolicyError     = "error"
+)
+
 // Provider holds provider-specific configuration.
 type Provider struct {
+	// APIKey is the key itself, a ${VAR} environment reference,
+	// KeyringRef to read it from the OS keyring, or the key encrypted by
+	// "ask config encrypt".
 	APIKey string `yaml:"api_key"`
+
+	// DefaultModel is the model used with the provider when no other is
+	// chosen. The top-level default_model takes precedence for the
+	// default provider.
+	DefaultModel string `yaml:"default_model"`
+}
+
+// KeyringRef as an api_key means the key is stored in the OS keyring
+// under the provider's name.
+const KeyringRef = "keyring"
+
+// PassphraseEnv is the environment variable holding the passphrase of
+// API keys encrypted with one.
+const PassphraseEnv = "ASK_CONFIG_PASSPHRASE"
+
+// ModelFor returns the configured model for the named provider:
+// default_model if it is the default provider and that is set, or else
+// its own default_model. It returns "" if neither is set.
+func (c *Config) ModelFor(providerName string) string {
+	if providerName == c.DefaultProvider && c.DefaultModel != "" {
+		return c.DefaultModel
+	}
+	return c.Providers[providerName].DefaultModel
+}
+
+// IsPlainAPIKey reports whether an api_key value is a key in plain text,
+// rather than empty, a reference, or encrypted.
+func IsPlainAPIKey(value string) bool {
+	isEnvRef := strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}")
+	return value != "" && value != KeyringRef && !isEnvRef && !secret.IsEncrypted(value)
 }
 
 // DefaultConfig returns the default configuration.
 func DefaultConfig() *Config {
 	return &Config{
 		DefaultProvider: "openai",
-		DefaultModel:    "gpt-4o",
 		Providers: map[string]Provider{
 			"openai":    {},
 			"anthropic": {},
 		},
+		MaxIncludeBytes: 512 * 1024,
+		MaxStdinBytes:   512 * 1024,
+		SaveHistory:     SaveHistoryTTY,
+		ContextPolicy:   ContextPolicySummarize,
+		Redact:          RedactWarn,
 	}
 }
 
@@ -45,21 +352,51 @@ func Load() (*Config, error) {
 	// Try to load config file
 	configPath, err := getConfigPath()
 	if err == nil {
-		if data, err := os.ReadFile(configPath); err == nil {
+		data, err := os.ReadFile(configPath)
+		if err == nil {
 			if err := yaml.Unmarshal(data, cfg); err != nil {
 				return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 			}
+		} else if explicitPath() {
+			// A config file asked for by name should exist
+			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
 	}
 
 	// Apply environment overrides
-	cfg.applyEnvOverrides()
+	if err := cfg.applyEnvOverrides(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
-// getConfigPath returns the path to the config file.
+// ConfigEnv and DataDirEnv are the environment variables that override
+// the config file's path and the data directory.
+const (
+	ConfigEnv  = "ASK_CONFIG"
+	DataDirEnv = "ASK_DATA_DIR"
+)
+
+// pathOverride is the config file given with SetPath.
+var pathOverride string
+
+// SetPath makes path the config file, taking precedence over ConfigEnv.
+// An empty path restores the default.
+func SetPath(path string) {
+	pathOverride = path
+}
+
+// getConfigPath returns the path to the config file: the one given with
+// SetPath or in ConfigEnv, or else config.yaml in the user's config
+// directory.
 func getConfigPath() (string, error) {
+	if pathOverride != "" {
+		return pathOverride, nil
+	}
+	if v := os.Getenv(ConfigEnv); v != "" {
+		return v, nil
+	}
 	configDir, err := os.UserConfigDir()
 	if err != nil {
 		return "", err
@@ -67,63 +404,187 @@ func getConfigPath() (string, error) {
 	return filepath.Join(configDir, "ask", "config.yaml"), nil
 }
 
-// applyEnvOverrides applies environment variable overrides to the config.
-func (c *Config) applyEnvOverrides() {
-	// Override default provider
-	if v := os.Getenv("ASK_PROVIDER"); v != "" {
-		c.DefaultProvider = v
-	}
+// explicitPath reports whether the config file was named with SetPath or
+// ConfigEnv rather than defaulted.
+func explicitPath() bool {
+	return pathOverride != "" || os.Getenv(ConfigEnv) != ""
+}
 
-	// Override default model
-	if v := os.Getenv("ASK_MODEL"); v != "" {
-		c.DefaultModel = v
+// GetAPIKey returns the API key for the specified provider. A key
+// stored in the OS keyring is read, and an encrypted key decrypted, on
+// first use; it is an error if it is missing (keyring.ErrNotFound) or
+// cannot be read or decrypted.
+func (c *Config) GetAPIKey(providerName string) (string, error) {
+	p, ok := c.Providers[providerName]
+	if !ok {
+		return "", nil
 	}
-
-	// Override API keys
-	if v := os.Getenv("OPENAI_API_KEY"); v != "" {
-		p := c.Providers["openai"]
-		p.APIKey = v
-		c.Providers["openai"] = p
+	switch {
+	case p.APIKey == KeyringRef:
+		key, err := keyring.Get(providerName)
+		if err != nil {
+			return "", err
+		}
+		p.APIKey = key
+		c.Providers[providerName] = p
+	case secret.IsEncrypted(p.APIKey):
+		key, err := c.Decrypt(p.APIKey)
+		if err != nil {
+			return "", fmt.Errorf("decrypting %s API key: %w", providerName, err)
+		}
+		p.APIKey = key
+		c.Providers[providerName] = p
 	}
+	return p.APIKey, nil
+}
 
-	if v := os.Getenv("ANTHROPIC_API_KEY"); v != "" {
-		p := c.Providers["anthropic"]
-		p.APIKey = v
-		c.Providers["anthropic"] = p
+// Decrypt decrypts a value encrypted by "ask config encrypt", with the
+// key from the OS keyring or the passphrase in PassphraseEnv or from
+// c.Passphrase. Keys are remembered for later values.
+func (c *Config) Decrypt(value string) (string, error) {
+	return secret.Decrypt(value, c.encryptionKey)
+}
+
+// encryptionKey returns the key for method and salt.
+func (c *Config) encryptionKey(method string, salt []byte) (secret.Key, error) {
+	id := method + ":" + string(salt)
+	if k, ok := c.keys[id]; ok {
+		return k, nil
 	}
 
-	// Resolve environment variable references in config file API keys
-	for name, provider := range c.Providers {
-		if strings.HasPrefix(provider.APIKey, "${") && strings.HasSuffix(provider.APIKey, "}") {
-			envVar := strings.TrimSuffix(strings.TrimPrefix(provider.APIKey, "${"), "}")
-			if v := os.Getenv(envVar); v != "" {
-				provider.APIKey = v
-				c.Providers[name] = provider
-			}
+	var k secret.Key
+	var err error
+	switch method {
+	case secret.MethodKeyring:
+		k, err = secret.KeyringKey(false)
+	case secret.MethodPassphrase:
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" && c.Passphrase != nil {
+			passphrase, err = c.Passphrase()
+		}
+		if err == nil && passphrase == "" {
+			err = fmt.Errorf("API keys are encrypted with a passphrase; set %s", PassphraseEnv)
 		}
+		if err == nil {
+			k, err = secret.PassphraseKey(passphrase, salt)
+		}
+	default:
+		err = fmt.Errorf("unknown encryption method %q", method)
+	}
+	if err != nil {
+		return secret.Key{}, err
 	}
-}
 
-// GetAPIKey returns the API key for the specified provider.
-func (c *Config) GetAPIKey(providerName string) string {
-	if p, ok := c.Providers[providerName]; ok {
-		return p.APIKey
+	if c.keys == nil {
+		c.keys = make(map[string]secret.Key)
 	}
-	return ""
+	c.keys[id] = k
+	return k, nil
 }
 
-// GetDataDir returns the data directory for storing history and other data.
+// GetDataDir returns the data directory for storing history and other
+// data, creating it if need be.
 func GetDataDir() (string, error) {
-	configDir, err := os.UserConfigDir()
+	dataDir, err := dataDirPath()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user config dir: %w", err)
+		return "", err
 	}
 
-	dataDir := filepath.Join(configDir, "ask")
-
 	if err := os.MkdirAll(dataDir, 0750); err != nil {
 		return "", fmt.Errorf("failed to create data dir: %w", err)
 	}
 
 	return dataDir, nil
 }
+
+// dataDirPath returns the data directory: DataDirEnv if it is set, the
+// legacy directory while MigrateDataDir finds its data in use, or else
+// ask's directory in the user's data directory.
+func dataDirPath() (string, error) {
+	if dir := os.Getenv(DataDirEnv); dir != "" {
+		return dir, nil
+	}
+	if dataDirOverride != "" {
+		return dataDirOverride, nil
+	}
+	dir, err := userDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ask"), nil
+}
+
+// GetStateDir returns the directory for logs, creating it if need be:
+// ask's directory in $XDG_STATE_HOME (default ~/.local/state) on Unix,
+// or the data directory elsewhere, if DataDirEnv is set, or while
+// MigrateDataDir finds the legacy data in use.
+func GetStateDir() (string, error) {
+	stateDir, err := stateDirPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(stateDir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create state dir: %w", err)
+	}
+	return stateDir, nil
+}
+
+// stateDirPath returns the directory GetStateDir creates.
+func stateDirPath() (string, error) {
+	if os.Getenv(DataDirEnv) != "" || dataDirOverride != "" || !usesXDG() {
+		return dataDirPath()
+	}
+	dir, err := xdgDir("XDG_STATE_HOME", ".local/state")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ask"), nil
+}
+
+// userDataDir returns the user's directory for application data:
+// $XDG_DATA_HOME (default ~/.local/share) on Unix, or on macOS and
+// Windows, where settings and data are kept together, os.UserConfigDir.
+func userDataDir() (string, error) {
+	if !usesXDG() {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user config dir: %w", err)
+		}
+		return dir, nil
+	}
+	return xdgDir("XDG_DATA_HOME", ".local/share")
+}
+
+// usesXDG reports whether the system follows the XDG base directory
+// spec.
+func usesXDG() bool {
+	switch runtime.GOOS {
+	case "darwin", "ios", "windows", "plan9":
+		return false
+	}
+	return true
+}
+
+// xdgDir returns the XDG base directory named by env, or if it is unset
+// or relative, as the spec requires it not to be, fallback in the home
+// directory.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home dir: %w", err)
+	}
+	return filepath.Join(home, filepath.FromSlash(fallback)), nil
+}
+
+// GetCacheDir returns the directory for cached data, which may be deleted
+// at any time.
+func GetCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "ask"), nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/devaloi/ask/internal/secret"
+)
+
+func TestGetAPIKey_Encrypted(t *testing.T) {
+	k, err := secret.PassphraseKey("hunter2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	openai, _ := secret.Encrypt("sk-openai", k)
+	anthropic, _ := secret.Encrypt("sk-ant", k)
+
+	t.Setenv(PassphraseEnv, "")
+	prompts := 0
+	cfg := &Config{
+		Providers: map[string]Provider{
+			"openai":    {APIKey: openai},
+			"anthropic": {APIKey: anthropic},
+			"plain":     {APIKey: "sk-plain"},
+		},
+		Passphrase: func() (string, error) {
+			prompts++
+			return "hunter2", nil
+		},
+	}
+
+	for name, want := range map[string]string{"openai": "sk-openai", "anthropic": "sk-ant", "plain": "sk-plain"} {
+		if got, err := cfg.GetAPIKey(name); err != nil || got != want {
+			t.Errorf("GetAPIKey(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("asked for the passphrase %d times, want once", prompts)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	cfg = &Config{Providers: map[string]Provider{"openai": {APIKey: openai}}}
+	if _, err := cfg.GetAPIKey("openai"); !errors.Is(err, secret.ErrDecrypt) {
+		t.Errorf("GetAPIKey() with a wrong passphrase: err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestPathOverrides(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv("HOME", dir)
+	t.Setenv(ConfigEnv, "")
+	t.Setenv(DataDirEnv, "")
+	t.Cleanup(func() { SetPath("") })
+
+	envFile := filepath.Join(dir, "env.yaml")
+	flagFile := filepath.Join(dir, "flag.yaml")
+	for file, model := range map[string]string{envFile: "from-env", flagFile: "from-flag"} {
+		if err := os.WriteFile(file, []byte("default_model: "+model+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name, env, flag string
+		wantPath        string
+		wantModel       string
+		wantErr         bool
+	}{
+		{name: "default", wantPath: filepath.Join(dir, "xdg", "ask", "config.yaml"), wantModel: ""},
+		{name: "env", env: envFile, wantPath: envFile, wantModel: "from-env"},
+		{name: "flag wins", env: envFile, flag: flagFile, wantPath: flagFile, wantModel: "from-flag"},
+		{name: "missing", flag: filepath.Join(dir, "missing.yaml"), wantPath: filepath.Join(dir, "missing.yaml"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ConfigEnv, tt.env)
+			SetPath(tt.flag)
+			if path, err := Path(); err != nil || path != tt.wantPath {
+				t.Errorf("Path() = %q, %v; want %q", path, err, tt.wantPath)
+			}
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Load() of a missing file succeeded")
+				}
+				return
+			}
+			if err != nil || cfg.DefaultModel != tt.wantModel {
+				t.Errorf("Load() = model %q, %v; want %q", cfg.DefaultModel, err, tt.wantModel)
+			}
+		})
+	}
+
+	data := filepath.Join(dir, "data")
+	t.Setenv(DataDirEnv, data)
+	if got, err := GetDataDir(); err != nil || got != data {
+		t.Errorf("GetDataDir() = %q, %v; want %q", got, err, data)
+	}
+	if _, err := os.Stat(data); err != nil {
+		t.Errorf("data dir not created: %v", err)
+	}
+}
+
+func TestModelFor(t *testing.T) {
+	c := DefaultConfig()
+	c.Providers["openai"] = Provider{DefaultModel: "gpt-4o-mini"}
+	c.Providers["anthropic"] = Provider{DefaultModel: "claude-3-5-haiku-20241022"}
+
+	tests := []struct {
+		name         string
+		defaultModel string
+		provider     string
+		want         string
+	}{
+		{"provider default", "", "anthropic", "claude-3-5-haiku-20241022"},
+		{"default provider's own default", "", "openai", "gpt-4o-mini"},
+		{"default_model wins for the default provider", "o3", "openai", "o3"},
+		{"default_model is not for other providers", "o3", "anthropic", "claude-3-5-haiku-20241022"},
+		{"none", "o3", "local", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.DefaultModel = tt.defaultModel
+			if got := c.ModelFor(tt.provider); got != tt.want {
+				t.Errorf("ModelFor(%q) = %q, want %q", tt.provider, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryFor(t *testing.T) {
+	c := DefaultConfig()
+	if err := yaml.Unmarshal([]byte(`retry:
+  max_attempts: 2
+  max_elapsed: 1m
+  providers:
+    anthropic:
+      backoff: 1s
+      status_codes: [529]
+`), c); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		provider string
+		want     RetryPolicy
+	}{
+		{"openai", RetryPolicy{MaxAttempts: 2, Backoff: 5 * time.Second, Multiplier: 2, MaxElapsed: time.Minute, StatusCodes: DefaultRetryPolicy.StatusCodes}},
+		{"anthropic", RetryPolicy{MaxAttempts: 2, Backoff: time.Second, Multiplier: 2, MaxElapsed: time.Minute, StatusCodes: []int{529}}},
+	}
+	for _, tt := range tests {
+		got := c.RetryFor(tt.provider)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RetryFor(%q) = %+v, want %+v", tt.provider, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Path returns the path of the config file.
+func Path() (string, error) {
+	return getConfigPath()
+}
+
+// SetPersona adds or replaces persona name in the config file. The rest
+// of the file, including comments, is preserved.
+func SetPersona(name string, p Persona) error {
+	path, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	return setPersona(path, name, p)
+}
+
+// RemovePersona deletes persona name from the config file. It reports
+// whether the persona existed.
+func RemovePersona(name string) (bool, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return false, err
+	}
+	return removePersona(path, name)
+}
+
+// SetAPIKey sets providers.<name>.api_key in the config file, preserving
+// the rest of the file.
+func SetAPIKey(name, value string) error {
+	path, err := getConfigPath()
+	if err != nil {
+		return err
+	}
+	return setAPIKey(path, name, value)
+}
+
+func setAPIKey(path, name, value string) error {
+	return editFile(path, func(root *yaml.Node) error {
+		p := ensureMapping(ensureMapping(root, "providers"), name)
+		setKey(p, "api_key", &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+		return nil
+	})
+}
+
+// RewriteAPIKeys replaces each providers.<name>.api_key in the config
+// file with rewrite's result, preserving the rest of the file, and
+// returns the names of the providers whose keys changed. If rewrite
+// fails, the file is left alone.
+func RewriteAPIKeys(rewrite func(name, value string) (string, error)) ([]string, error) {
+	path, err := getConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return rewriteAPIKeys(path, rewrite)
+}
+
+func rewriteAPIKeys(path string, rewrite func(name, value string) (string, error)) ([]string, error) {
+	var changed []string
+	err := editFile(path, func(root *yaml.Node) error {
+		providers := lookup(root, "providers")
+		if providers == nil || providers.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(providers.Content); i += 2 {
+			name, p := providers.Content[i].Value, providers.Content[i+1]
+			if p.Kind != yaml.MappingNode {
+				continue
+			}
+			key := lookup(p, "api_key")
+			if key == nil || key.Kind != yaml.ScalarNode {
+				continue
+			}
+			value, err := rewrite(name, key.Value)
+			if err != nil {
+				return fmt.Errorf("%s API key: %w", name, err)
+			}
+			if value != key.Value {
+				// Edit in place to keep comments
+				key.Value = value
+				key.Tag = "!!str"
+				key.Style = 0
+				changed = append(changed, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func setPersona(path, name string, p Persona) error {
+	return editFile(path, func(root *yaml.Node) error {
+		var value yaml.Node
+		if err := value.Encode(p); err != nil {
+			return err
+		}
+		setKey(ensureMapping(root, "personas"), name, &value)
+		return nil
+	})
+}
+
+func removePersona(path, name string) (bool, error) {
+	var found bool
+	err := editFile(path, func(root *yaml.Node) error {
+		personas := lookup(root, "personas")
+		if personas == nil || personas.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(personas.Content); i += 2 {
+			if personas.Content[i].Value == name {
+				personas.Content = append(personas.Content[:i], personas.Content[i+2:]...)
+				found = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return found, err
+}
+
+// editFile applies edit to the top-level mapping of the YAML file at
+// path, creating the file if needed, and writes the result back.
+func editFile(path string, edit func(root *yaml.Node) error) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	if err := edit(root); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+	// The file may hold API keys
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// lookup returns the value for key in mapping m, or nil.
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ensureMapping returns the mapping value for key in m, creating or
+// replacing it if it is missing or not a mapping.
+func ensureMapping(m *yaml.Node, key string) *yaml.Node {
+	if v := lookup(m, key); v != nil && v.Kind == yaml.MappingNode {
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	setKey(m, key, v)
+	return v
+}
+
+// setKey sets key in mapping m to value, appending it if new.
+func setKey(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetPersona_PreservesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "# my settings\ndefault_provider: anthropic # preferred\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setPersona(path, "reviewer", Persona{System: "@/prompts/review.md", Model: "claude-sonnet-4", AutoContext: []string{"git"}}); err != nil {
+		t.Fatalf("setPersona() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# my settings", "# preferred", "default_provider: anthropic"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config lost %q:\n%s", want, data)
+		}
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("rewritten config does not parse: %v", err)
+	}
+	got := cfg.Personas["reviewer"]
+	if got.System != "@/prompts/review.md" || got.Model != "claude-sonnet-4" || got.Provider != "" || !reflect.DeepEqual(got.AutoContext, []string{"git"}) {
+		t.Errorf("persona = %+v", got)
+	}
+}
+
+func TestSetPersona_ReplacesAndCreates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ask", "config.yaml")
+
+	if err := setPersona(path, "terse", Persona{System: "Be terse."}); err != nil {
+		t.Fatalf("setPersona() on missing file error = %v", err)
+	}
+	if err := setPersona(path, "terse", Persona{Model: "gpt-4o-mini"}); err != nil {
+		t.Fatalf("setPersona() replace error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Personas["terse"]; !reflect.DeepEqual(got, Persona{Model: "gpt-4o-mini"}) {
+		t.Errorf("persona = %+v, want replaced", got)
+	}
+}
+
+func TestRemovePersona(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := setPersona(path, "a", Persona{Model: "m1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setPersona(path, "b", Persona{Model: "m2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := removePersona(path, "a")
+	if err != nil || !found {
+		t.Fatalf("removePersona(a) = %v, %v; want true, nil", found, err)
+	}
+	found, err = removePersona(path, "missing")
+	if err != nil || found {
+		t.Errorf("removePersona(missing) = %v, %v; want false, nil", found, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Personas["a"]; ok {
+		t.Error("persona a still present")
+	}
+	if cfg.Personas["b"].Model != "m2" {
+		t.Errorf("persona b = %+v", cfg.Personas["b"])
+	}
+}
+
+func TestSetAPIKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "providers:\n  openai:\n    api_key: sk-old # rotate me\n  anthropic:\n    api_key: ${ANTHROPIC_KEY}\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := setAPIKey(path, "openai", KeyringRef); err != nil {
+		t.Fatalf("setAPIKey() error = %v", err)
+	}
+	if err := setAPIKey(path, "other", KeyringRef); err != nil {
+		t.Fatalf("setAPIKey() new provider error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-old") {
+		t.Errorf("old key still in config:\n%s", data)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"openai": KeyringRef, "anthropic": "${ANTHROPIC_KEY}", "other": KeyringRef}
+	for name, key := range want {
+		if got := cfg.Providers[name].APIKey; got != key {
+			t.Errorf("providers.%s.api_key = %q, want %q", name, got, key)
+		}
+	}
+}
+
+func TestRewriteAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "default_provider: openai\nproviders:\n  openai:\n    api_key: sk-old # rotate me\n  anthropic:\n    api_key: ${ANTHROPIC_KEY}\n  local: {}\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := rewriteAPIKeys(path, func(name, value string) (string, error) {
+		if !IsPlainAPIKey(value) {
+			return value, nil
+		}
+		return "enc:test:" + name, nil
+	})
+	if err != nil {
+		t.Fatalf("rewriteAPIKeys() error = %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "openai" {
+		t.Errorf("changed = %v, want [openai]", changed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"api_key: enc:test:openai # rotate me", "api_key: ${ANTHROPIC_KEY}", "default_provider: openai"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config missing %q:\n%s", want, data)
+		}
+	}
+
+	_, err = rewriteAPIKeys(path, func(name, value string) (string, error) {
+		return "", errors.New("no key")
+	})
+	if err == nil {
+		t.Error("rewriteAPIKeys() with a failing rewrite succeeded")
+	}
+	after, _ := os.ReadFile(path)
+	if string(after) != string(data) {
+		t.Errorf("failed rewrite changed the file:\n%s", after)
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix starts the names of the environment variables that override
+// settings. Each setting's variable is its path in the config file, in
+// upper case with dots as underscores: cache.ttl is ASK_CACHE_TTL.
+// Settings holding maps or lists of mappings, such as providers and
+// routes, have none.
+const envPrefix = "ASK_"
+
+// envAliases are shorter names for some settings' variables, by their
+// full names. The full name wins if both are set.
+var envAliases = map[string]string{
+	"ASK_DEFAULT_PROVIDER": "ASK_PROVIDER",
+	"ASK_DEFAULT_MODEL":    "ASK_MODEL",
+}
+
+// envSetting is a setting an environment variable can override.
+type envSetting struct {
+	name  string // e.g. ASK_CACHE_TTL
+	value reflect.Value
+}
+
+// envSettings returns the settings in struct v, whose variables' names
+// start with prefix.
+func envSettings(v reflect.Value, prefix string) []envSetting {
+	var settings []envSetting
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if opts == "inline" {
+			settings = append(settings, envSettings(fv, prefix)...)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		name = prefix + strings.ToUpper(name)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			settings = append(settings, envSettings(fv, name+"_")...)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.String && fv.Type().Elem().Kind() != reflect.Int,
+			fv.Kind() == reflect.Map, fv.Kind() == reflect.Func:
+			// Not expressible as one string
+		default:
+			settings = append(settings, envSetting{name: name, value: fv})
+		}
+	}
+	return settings
+}
+
+// applyEnvOverrides applies environment variable overrides to the config.
+func (c *Config) applyEnvOverrides() error {
+	for _, s := range envSettings(reflect.ValueOf(c).Elem(), envPrefix) {
+		v := os.Getenv(s.name)
+		if alias, ok := envAliases[s.name]; ok && v == "" {
+			v = os.Getenv(alias)
+		}
+		if v == "" {
+			continue
+		}
+		if err := setFromEnv(s.value, v); err != nil {
+			return fmt.Errorf("invalid %s: %w", s.name, err)
+		}
+	}
+
+	// Enable debug logging
+	if v := os.Getenv("ASK_DEBUG"); v != "" && v != "0" && v != "false" {
+		c.LogLevel = "debug"
+	}
+
+	// Override API keys
+	if v := os.Getenv("OPENAI_API_KEY"); v != "" {
+		p := c.Providers["openai"]
+		p.APIKey = v
+		c.Providers["openai"] = p
+	}
+
+	if v := os.Getenv("ANTHROPIC_API_KEY"); v != "" {
+		p := c.Providers["anthropic"]
+		p.APIKey = v
+		c.Providers["anthropic"] = p
+	}
+
+	// Resolve environment variable references in config file API keys
+	for name, provider := range c.Providers {
+		if strings.HasPrefix(provider.APIKey, "${") && strings.HasSuffix(provider.APIKey, "}") {
+			envVar := strings.TrimSuffix(strings.TrimPrefix(provider.APIKey, "${"), "}")
+			if v := os.Getenv(envVar); v != "" {
+				provider.APIKey = v
+				c.Providers[name] = provider
+			}
+		}
+	}
+	return nil
+}
+
+// setFromEnv sets v from the environment variable value s. Lists are
+// separated by commas.
+func setFromEnv(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", s)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for item := range strings.SplitSeq(s, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setFromEnv(elem, item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem)
+		}
+		v.Set(items)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
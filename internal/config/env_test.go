@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(c *Config) bool
+		wantErr string
+	}{
+		{
+			name:  "string",
+			env:   map[string]string{"ASK_DEFAULT_SYSTEM": "Be brief."},
+			check: func(c *Config) bool { return c.DefaultSystem == "Be brief." },
+		},
+		{
+			name:  "alias",
+			env:   map[string]string{"ASK_MODEL": "gpt-4o-mini"},
+			check: func(c *Config) bool { return c.DefaultModel == "gpt-4o-mini" },
+		},
+		{
+			name:  "full name wins",
+			env:   map[string]string{"ASK_MODEL": "gpt-4o-mini", "ASK_DEFAULT_MODEL": "o3"},
+			check: func(c *Config) bool { return c.DefaultModel == "o3" },
+		},
+		{
+			name:  "nested duration and bool",
+			env:   map[string]string{"ASK_CACHE_TTL": "1h", "ASK_CACHE_ENABLED": "true"},
+			check: func(c *Config) bool { return c.Cache.TTL == time.Hour && c.Cache.Enabled },
+		},
+		{
+			name:  "inline struct and number",
+			env:   map[string]string{"ASK_BUDGET_DAILY": "2.5", "ASK_MAX_STDIN_BYTES": "1024"},
+			check: func(c *Config) bool { return c.Budget.Daily == 2.5 && c.MaxStdinBytes == 1024 },
+		},
+		{
+			name:  "list",
+			env:   map[string]string{"ASK_AUTO_CONTEXT": "git, os"},
+			check: func(c *Config) bool { return slices.Equal(c.AutoContext, []string{"git", "os"}) },
+		},
+		{
+			name:  "numbers",
+			env:   map[string]string{"ASK_RETRY_STATUS_CODES": "429, 503"},
+			check: func(c *Config) bool { return slices.Equal(c.Retry.StatusCodes, []int{429, 503}) },
+		},
+		{
+			name:    "invalid",
+			env:     map[string]string{"ASK_PAGER": "sometimes"},
+			wantErr: `invalid ASK_PAGER: "sometimes" is not true or false`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			c := DefaultConfig()
+			err := c.applyEnvOverrides()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("applyEnvOverrides() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !tt.check(c) {
+				t.Errorf("applyEnvOverrides() = %v; config %+v", err, c)
+			}
+		})
+	}
+}
+
+func TestEnvSettings(t *testing.T) {
+	var names []string
+	for _, s := range envSettings(reflect.ValueOf(DefaultConfig()).Elem(), envPrefix) {
+		names = append(names, s.name)
+	}
+	for _, want := range []string{"ASK_DEFAULT_PROVIDER", "ASK_LOG_LEVEL", "ASK_CACHE_TTL", "ASK_BUDGET_MONTHLY", "ASK_BUDGET_POLICY", "ASK_THEME_PRESET"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("no variable %s among %v", want, names)
+		}
+	}
+	for _, name := range names {
+		if strings.Contains(name, "PROVIDERS") || strings.Contains(name, "ROUTES") || strings.Contains(name, "PASSPHRASE") {
+			t.Errorf("variable %s for a setting that cannot be one string", name)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrDataInUse is returned by MigrateDataDir when another ask process
+// may be using the legacy data, which is then used where it is.
+var ErrDataInUse = errors.New("data in use by another ask process")
+
+// Entries of the legacy directory that earlier versions created: the
+// history and the index, with their SQLite journals, and saved templates
+// belong in the data directory, and logs in the state directory. Nothing
+// else there is moved; it may be the user's own.
+var (
+	dataEntries  = []string{"history.db", "index.db", "templates"}
+	stateEntries = []string{"debug.log"}
+)
+
+// openSuffixes name the files SQLite keeps beside a database while it is
+// open: the WAL and its index, or the rollback journal.
+var openSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// dataDirOverride is the legacy directory while its data is in use.
+var dataDirOverride string
+
+// MigrateDataDir moves the history and other data that earlier versions
+// kept beside config.yaml, in ask's directory in os.UserConfigDir, to
+// the data and state directories, leaving the config file where it is.
+// It returns the directory the data was moved from, or "" if nothing was
+// moved or tried.
+//
+// It runs once, before the data directory exists, and not if DataDirEnv
+// is set. While a daemon or another ask process may be using the data,
+// it returns ErrDataInUse and leaves the data where it is, to be used
+// there until a later run moves it.
+func MigrateDataDir() (string, error) {
+	if os.Getenv(DataDirEnv) != "" {
+		return "", nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", nil
+	}
+	legacy := filepath.Join(configDir, "ask")
+	dataDir, err := dataDirPath()
+	if err != nil {
+		return "", err
+	}
+	stateDir, err := stateDirPath()
+	if err != nil {
+		return "", err
+	}
+	moved, err := migrateDir(legacy, dataDir, stateDir)
+	if errors.Is(err, ErrDataInUse) {
+		dataDirOverride = legacy
+	}
+	if !moved && err == nil {
+		return "", nil
+	}
+	return legacy, err
+}
+
+// migrateDir moves the dataEntries of legacy to dataDir, and its
+// stateEntries to stateDir, if dataDir does not exist. It reports
+// whether it moved anything.
+func migrateDir(legacy, dataDir, stateDir string) (bool, error) {
+	if legacy == dataDir {
+		return false, nil
+	}
+	if _, err := os.Stat(dataDir); !errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	entries, err := os.ReadDir(legacy)
+	if err != nil {
+		return false, nil
+	}
+
+	var moving []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type()&fs.ModeSocket != 0 {
+			// A daemon may be running
+			return false, ErrDataInUse
+		}
+		for _, suffix := range openSuffixes {
+			if db, ok := strings.CutSuffix(name, suffix); ok && slices.Contains(dataEntries, db) {
+				return false, ErrDataInUse
+			}
+		}
+		if slices.Contains(dataEntries, name) || slices.Contains(stateEntries, name) {
+			moving = append(moving, name)
+		}
+	}
+	if len(moving) == 0 {
+		return false, nil
+	}
+
+	for _, dir := range []string{dataDir, stateDir} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return false, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	for i, name := range moving {
+		to := filepath.Join(dataDir, name)
+		if slices.Contains(stateEntries, name) {
+			to = filepath.Join(stateDir, name)
+		}
+		if _, err := os.Lstat(to); err == nil {
+			continue // never overwrite
+		}
+		if err := move(filepath.Join(legacy, name), to); err != nil {
+			if i == 0 {
+				// Try again next time
+				_ = os.Remove(dataDir)
+			}
+			return i > 0, fmt.Errorf("failed to move %s to %s: %w", name, filepath.Dir(to), err)
+		}
+	}
+	return true, nil
+}
+
+// move renames from to to, or copies it and removes the original if
+// they are on different file systems.
+func move(from, to string) error {
+	if os.Rename(from, to) == nil {
+		return nil
+	}
+	info, err := os.Lstat(from)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		err = os.CopyFS(to, os.DirFS(from))
+	} else {
+		err = copyFile(from, to, info.Mode().Perm())
+	}
+	if err != nil {
+		_ = os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyFile copies the file from to a new file to with permissions perm.
+func copyFile(from, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDir(t *testing.T) {
+	write := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "(missing)"
+		}
+		return string(data)
+	}
+
+	t.Run("moves data and logs", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy, data, state := filepath.Join(dir, "config"), filepath.Join(dir, "data"), filepath.Join(dir, "state")
+		write(t, filepath.Join(legacy, "config.yaml"), "config")
+		write(t, filepath.Join(legacy, "history.db"), "history")
+		write(t, filepath.Join(legacy, "templates", "standup.md"), "template")
+		write(t, filepath.Join(legacy, "debug.log"), "log")
+		write(t, filepath.Join(legacy, "review.md"), "prompt")
+
+		moved, err := migrateDir(legacy, data, state)
+		if !moved || err != nil {
+			t.Fatalf("migrateDir() = %v, %v; want moved", moved, err)
+		}
+		for path, want := range map[string]string{
+			filepath.Join(legacy, "config.yaml"):           "config",
+			filepath.Join(data, "history.db"):              "history",
+			filepath.Join(data, "templates", "standup.md"): "template",
+			filepath.Join(state, "debug.log"):              "log",
+			filepath.Join(legacy, "history.db"):            "(missing)",
+			filepath.Join(legacy, "review.md"):             "prompt",
+			filepath.Join(data, "review.md"):               "(missing)",
+		} {
+			if got := read(path); got != want {
+				t.Errorf("%s = %q, want %q", path, got, want)
+			}
+		}
+
+		// Only once
+		write(t, filepath.Join(legacy, "index.db"), "index")
+		if moved, err := migrateDir(legacy, data, state); moved || err != nil {
+			t.Errorf("second migrateDir() = %v, %v; want nothing moved", moved, err)
+		}
+	})
+
+	t.Run("nothing to move", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy, data := filepath.Join(dir, "config"), filepath.Join(dir, "data")
+		write(t, filepath.Join(legacy, "config.yaml"), "config")
+		if moved, err := migrateDir(legacy, data, data); moved || err != nil {
+			t.Errorf("migrateDir() = %v, %v; want nothing moved", moved, err)
+		}
+		if _, err := os.Stat(data); !os.IsNotExist(err) {
+			t.Errorf("data dir created with nothing to move: %v", err)
+		}
+	})
+
+	t.Run("daemon running", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy, data := filepath.Join(dir, "config"), filepath.Join(dir, "data")
+		write(t, filepath.Join(legacy, "history.db"), "history")
+		l, err := net.Listen("unix", filepath.Join(legacy, "d.sock"))
+		if err != nil {
+			t.Skip(err)
+		}
+		defer l.Close()
+		if moved, err := migrateDir(legacy, data, data); moved || !errors.Is(err, ErrDataInUse) {
+			t.Errorf("migrateDir() = %v, %v; want it to wait for the daemon", moved, err)
+		}
+	})
+
+	t.Run("database open", func(t *testing.T) {
+		dir := t.TempDir()
+		legacy, data := filepath.Join(dir, "config"), filepath.Join(dir, "data")
+		write(t, filepath.Join(legacy, "history.db"), "history")
+		write(t, filepath.Join(legacy, "history.db-wal"), "")
+		if moved, err := migrateDir(legacy, data, data); moved || !errors.Is(err, ErrDataInUse) {
+			t.Errorf("migrateDir() = %v, %v; want it to wait for the database to be closed", moved, err)
+		}
+		if got := read(filepath.Join(legacy, "history.db")); got != "history" {
+			t.Errorf("history.db = %q, want it left in place", got)
+		}
+	})
+}
+
+func TestDataAndStateDirs(t *testing.T) {
+	if !usesXDG() {
+		t.Skip("no XDG directories on this system")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Setenv(DataDirEnv, "")
+
+	if got, err := GetDataDir(); err != nil || got != filepath.Join(dir, ".local", "share", "ask") {
+		t.Errorf("GetDataDir() = %q, %v", got, err)
+	}
+	if got, err := GetStateDir(); err != nil || got != filepath.Join(dir, "state", "ask") {
+		t.Errorf("GetStateDir() = %q, %v", got, err)
+	}
+
+	t.Setenv(DataDirEnv, filepath.Join(dir, "all"))
+	if got, err := GetStateDir(); err != nil || got != filepath.Join(dir, "all") {
+		t.Errorf("GetStateDir() with %s = %q, %v; want the data dir", DataDirEnv, got, err)
+	}
+}
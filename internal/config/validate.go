@@ -0,0 +1,452 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/devaloi/ask/internal/logging"
+	"github.com/devaloi/ask/internal/util"
+)
+
+// Problem is a mistake found in a config file.
+type Problem struct {
+	Line, Column int // 1-based; 0 if unknown
+	Message      string
+}
+
+func (p Problem) String() string {
+	if p.Column > 0 {
+		return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// Known maps the providers a config file may name to the models each
+// offers, for checking names and spotting a model set for the wrong
+// provider.
+type Known map[string][]string
+
+// Validate checks the contents of a config file against the Config
+// schema, where Load silently ignores mistakes: unknown keys, such as
+// typos, values of the wrong type or not among a setting's choices,
+// unknown providers, malformed ${VAR} references, and settings that
+// contradict each other. Problems are returned in file order.
+func Validate(data []byte, known Known) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []Problem{syntaxProblem(err)}
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil
+	}
+
+	v := &validator{known: known}
+	root := doc.Content[0]
+	v.checkType(root, reflect.TypeFor[Config](), "")
+	if root.Kind == yaml.MappingNode {
+		v.checkValues(root)
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.problems
+}
+
+var yamlLineRE = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// syntaxProblem turns a YAML parse error into a Problem.
+func syntaxProblem(err error) Problem {
+	msg := err.Error()
+	if m := yamlLineRE.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return Problem{Line: line, Message: "invalid YAML: " + m[2]}
+	}
+	return Problem{Line: 1, Message: "invalid YAML: " + strings.TrimPrefix(msg, "yaml: ")}
+}
+
+type validator struct {
+	known    Known
+	problems []Problem
+}
+
+func (v *validator) report(n *yaml.Node, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	durationType    = reflect.TypeFor[time.Duration]()
+	unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+)
+
+// checkType checks that n can be decoded into a value of type t, and
+// that mappings for structs have only the struct's keys. path names n in
+// messages.
+func (v *validator) checkType(n *yaml.Node, t reflect.Type, path string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		v.checkDecode(n, t, path)
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			v.report(n, "%s should be a mapping of keys to values", describePath(path))
+			return
+		}
+		fields := yamlFields(t)
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if first, ok := seen[key.Value]; ok {
+				v.report(key, "duplicate key %q (first set on line %d)", joinPath(path, key.Value), first.Line)
+				continue
+			}
+			seen[key.Value] = key
+			ft, ok := fields[key.Value]
+			if !ok {
+				v.report(key, "unknown key %q%s", joinPath(path, key.Value), suggest(key.Value, mapKeys(fields)))
+				continue
+			}
+			v.checkType(value, ft, joinPath(path, key.Value))
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			v.report(n, "%s should be a mapping of names to values", describePath(path))
+			return
+		}
+		seen := make(map[string]*yaml.Node)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if first, ok := seen[key.Value]; ok {
+				v.report(key, "duplicate key %q (first set on line %d)", joinPath(path, key.Value), first.Line)
+				continue
+			}
+			seen[key.Value] = key
+			v.checkType(value, t.Elem(), joinPath(path, key.Value))
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			v.report(n, "%s should be a list", describePath(path))
+			return
+		}
+		for i, item := range n.Content {
+			v.checkType(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	default:
+		v.checkDecode(n, t, path)
+	}
+}
+
+// checkDecode checks that n decodes into a value of type t.
+func (v *validator) checkDecode(n *yaml.Node, t reflect.Type, path string) {
+	if err := n.Decode(reflect.New(t).Interface()); err == nil {
+		return
+	}
+	var want string
+	switch {
+	case t == durationType:
+		want = `a duration such as "30s" or "24h"`
+	case t.Kind() == reflect.Bool:
+		want = "true or false"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		want = "a whole number"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		want = "a number"
+	case t.Kind() == reflect.String:
+		want = "a string"
+	default:
+		want = "a valid " + t.Name()
+	}
+	if n.Kind == yaml.ScalarNode {
+		v.report(n, "%s should be %s, not %q", path, want, n.Value)
+		return
+	}
+	v.report(n, "%s should be %s", path, want)
+}
+
+// yamlFields maps the YAML keys of struct t, including those of inlined
+// structs, to their types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if slices.Contains(strings.Split(opts, ","), "inline") {
+			for k, ft := range yamlFields(f.Type) {
+				fields[k] = ft
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// checkValues checks the values of the top-level mapping root: provider
+// names, choices, ${VAR} references, and conflicting settings.
+func (v *validator) checkValues(root *yaml.Node) {
+	for _, c := range []struct {
+		key     string
+		choices []string
+	}{
+		{"redact", []string{RedactWarn, RedactMask, RedactBlock, RedactOff}},
+		{"scrub", []string{ScrubOff, ScrubPII}},
+		{"save_history", []string{SaveHistoryTTY, SaveHistoryAlways, SaveHistoryNever}},
+		{"context_policy", []string{ContextPolicySummarize, ContextPolicyDrop, ContextPolicyError}},
+	} {
+		v.checkChoice(scalar(lookup(root, c.key)), c.key, c.choices)
+	}
+	if n := scalar(lookup(root, "log_level")); n != nil && n.Value != "" {
+		if _, err := logging.ParseLevel(n.Value); err != nil {
+			v.report(n, "log_level is %q; it should be one of %s", n.Value, strings.Join(logging.Levels, ", "))
+		}
+	}
+
+	defaultProvider := DefaultConfig().DefaultProvider
+	if n := scalar(lookup(root, "default_provider")); n != nil {
+		if v.checkProvider(n) {
+			defaultProvider = n.Value
+		}
+	}
+	if n := scalar(lookup(root, "default_model")); n != nil {
+		v.checkModel(n, defaultProvider, "default_provider")
+	}
+
+	providers := mapping(lookup(root, "providers"))
+	for i := 0; providers != nil && i+1 < len(providers.Content); i += 2 {
+		name, p := providers.Content[i], mapping(providers.Content[i+1])
+		v.checkProvider(name)
+		if p != nil {
+			if key := scalar(lookup(p, "api_key")); key != nil {
+				v.checkEnvRef(key, "providers."+name.Value+".api_key")
+			}
+			if n := scalar(lookup(p, "default_model")); n != nil {
+				v.checkModel(n, name.Value, "the provider")
+			}
+		}
+	}
+
+	models := mapping(lookup(root, "models"))
+	for i := 0; models != nil && i+1 < len(models.Content); i += 2 {
+		name, m := models.Content[i], mapping(models.Content[i+1])
+		if m == nil {
+			continue
+		}
+		if n := scalar(lookup(m, "provider")); n != nil && v.checkProvider(n) {
+			v.checkModel(name, n.Value, "models."+name.Value+".provider")
+		}
+		if n := scalar(lookup(m, "knowledge_cutoff")); n != nil && n.Value != "" {
+			if _, err := time.Parse("2006-01", n.Value); err != nil {
+				v.report(n, "models.%s.knowledge_cutoff is %q; it should be a month, as YYYY-MM", name.Value, n.Value)
+			}
+		}
+	}
+
+	for i, item := range sequence(lookup(root, "routes")) {
+		rule := mapping(item)
+		if rule == nil {
+			continue
+		}
+		providerName := defaultProvider
+		if n := scalar(lookup(rule, "provider")); n != nil && v.checkProvider(n) {
+			providerName = n.Value
+		}
+		if n := scalar(lookup(rule, "model")); n != nil {
+			v.checkModel(n, providerName, fmt.Sprintf("routes[%d].provider", i))
+		}
+	}
+
+	personas := mapping(lookup(root, "personas"))
+	for i := 0; personas != nil && i+1 < len(personas.Content); i += 2 {
+		name, p := personas.Content[i].Value, mapping(personas.Content[i+1])
+		if p == nil {
+			continue
+		}
+		// A persona without a provider uses the default one
+		providerName := defaultProvider
+		if n := scalar(lookup(p, "provider")); n != nil && v.checkProvider(n) {
+			providerName = n.Value
+		}
+		if n := scalar(lookup(p, "model")); n != nil {
+			v.checkModel(n, providerName, "personas."+name+".provider")
+		}
+	}
+
+	if retry := mapping(lookup(root, "retry")); retry != nil {
+		byProvider := mapping(lookup(retry, "providers"))
+		for i := 0; byProvider != nil && i+1 < len(byProvider.Content); i += 2 {
+			v.checkProvider(byProvider.Content[i])
+		}
+	}
+
+	if budget := mapping(lookup(root, "budget")); budget != nil {
+		v.checkChoice(scalar(lookup(budget, "policy")), "budget.policy", []string{BudgetPolicyWarn, BudgetPolicyRefuse})
+		v.checkLimits(budget, "budget")
+		byProvider := mapping(lookup(budget, "providers"))
+		for i := 0; byProvider != nil && i+1 < len(byProvider.Content); i += 2 {
+			name := byProvider.Content[i]
+			v.checkProvider(name)
+			if limits := mapping(byProvider.Content[i+1]); limits != nil {
+				v.checkLimits(limits, "budget.providers."+name.Value)
+			}
+		}
+	}
+}
+
+// checkChoice checks that n, the value of key, if set, is one of choices.
+func (v *validator) checkChoice(n *yaml.Node, key string, choices []string) {
+	if n == nil || n.Value == "" || slices.Contains(choices, n.Value) {
+		return
+	}
+	v.report(n, "%s is %q; it should be one of %s", key, n.Value, strings.Join(choices, ", "))
+}
+
+// checkProvider reports whether n names a known provider, reporting it
+// if not.
+func (v *validator) checkProvider(n *yaml.Node) bool {
+	if _, ok := v.known[n.Value]; ok || v.known == nil {
+		return true
+	}
+	names := mapKeys(v.known)
+	if s := suggest(n.Value, names); s != "" {
+		v.report(n, "unknown provider %q%s", n.Value, s)
+	} else {
+		v.report(n, "unknown provider %q; providers are %s", n.Value, strings.Join(names, ", "))
+	}
+	return false
+}
+
+// checkModel reports model n if only a provider other than providerName
+// offers it; providerKey is the setting that chose providerName.
+func (v *validator) checkModel(n *yaml.Node, providerName, providerKey string) {
+	if slices.Contains(v.known[providerName], n.Value) {
+		return
+	}
+	for _, name := range mapKeys(v.known) {
+		if slices.Contains(v.known[name], n.Value) {
+			v.report(n, "model %q is offered by %s, but %s is %s", n.Value, name, providerKey, providerName)
+			return
+		}
+	}
+}
+
+var envNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// checkEnvRef checks that n, the value of key, is a well-formed ${VAR}
+// reference if it looks like an attempt at one.
+func (v *validator) checkEnvRef(n *yaml.Node, key string) {
+	s := n.Value
+	if !strings.HasPrefix(s, "$") {
+		return
+	}
+	if name, ok := strings.CutPrefix(s, "${"); ok {
+		name, closed := strings.CutSuffix(name, "}")
+		switch {
+		case !closed:
+			v.report(n, "%s: environment reference %q is missing its closing }", key, s)
+		case !envNameRE.MatchString(name):
+			v.report(n, "%s: %q is not a valid environment variable name", key, name)
+		}
+		return
+	}
+	if envNameRE.MatchString(s[1:]) {
+		v.report(n, "%s: %q is not expanded; write ${%s}", key, s, s[1:])
+	}
+}
+
+// checkLimits checks that the daily limit in budget mapping m, at path,
+// is not more than its monthly limit.
+func (v *validator) checkLimits(m *yaml.Node, path string) {
+	daily, monthly := scalar(lookup(m, "daily")), scalar(lookup(m, "monthly"))
+	if daily == nil || monthly == nil {
+		return
+	}
+	d, err1 := strconv.ParseFloat(daily.Value, 64)
+	mo, err2 := strconv.ParseFloat(monthly.Value, 64)
+	if err1 == nil && err2 == nil && d > 0 && mo > 0 && d > mo {
+		v.report(daily, "%s.daily ($%g) is more than %s.monthly ($%g)", path, d, path, mo)
+	}
+}
+
+// suggest returns a "did you mean" hint for s among candidates, or "".
+func suggest(s string, candidates []string) string {
+	if c, ok := util.Closest(s, candidates); ok {
+		return fmt.Sprintf("; did you mean %q?", c)
+	}
+	return ""
+}
+
+func scalar(n *yaml.Node) *yaml.Node {
+	if n != nil && n.Kind == yaml.ScalarNode && n.Tag != "!!null" {
+		return n
+	}
+	return nil
+}
+
+func mapping(n *yaml.Node) *yaml.Node {
+	if n != nil && n.Kind == yaml.MappingNode {
+		return n
+	}
+	return nil
+}
+
+func sequence(n *yaml.Node) []*yaml.Node {
+	if n != nil && n.Kind == yaml.SequenceNode {
+		return n.Content
+	}
+	return nil
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the config file"
+	}
+	return path
+}
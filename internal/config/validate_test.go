@@ -0,0 +1,217 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+var testKnown = Known{
+	"openai":    {"gpt-4o", "gpt-4o-mini"},
+	"anthropic": {"claude-sonnet-4-20250514"},
+	"local":     nil,
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string // problems, as Problem.String
+	}{
+		{
+			name: "valid",
+			yaml: `default_provider: anthropic
+default_model: claude-sonnet-4-20250514
+providers:
+  openai:
+    api_key: ${OPENAI_API_KEY}
+  anthropic:
+    api_key: keyring
+notify_after: 30s
+cache:
+  enabled: true
+  ttl: 12h
+budget:
+  daily: 1
+  monthly: 20
+  providers:
+    openai:
+      monthly: 10
+routes:
+  - max_tokens: 500
+    model: gpt-4o-mini
+    provider: openai
+personas:
+  reviewer:
+    system: Review code.
+    model: gpt-4o
+    provider: openai
+log_level: WARNING
+models:
+  gpt-4o:
+    input_price: 2
+    tools: false
+  my-finetuned-model:
+    provider: openai
+    context_window: 32000
+    knowledge_cutoff: 2025-01
+`,
+		},
+		{name: "empty", yaml: ""},
+		{
+			name: "unknown keys",
+			yaml: `defualt_model: gpt-4o
+theme:
+  preset: dark
+  asistant: green
+frobnicate: true
+`,
+			want: []string{
+				`line 1, column 1: unknown key "defualt_model"; did you mean "default_model"?`,
+				`line 4, column 3: unknown key "theme.asistant"; did you mean "assistant"?`,
+				`line 5, column 1: unknown key "frobnicate"`,
+			},
+		},
+		{
+			name: "wrong types",
+			yaml: `max_include_bytes: lots
+pager: sometimes
+notify_after: 30
+auto_context: git
+cache: true
+`,
+			want: []string{
+				`line 1, column 20: max_include_bytes should be a whole number, not "lots"`,
+				`line 2, column 8: pager should be true or false, not "sometimes"`,
+				`line 3, column 15: notify_after should be a duration such as "30s" or "24h", not "30"`,
+				`line 4, column 15: auto_context should be a list`,
+				`line 5, column 8: cache should be a mapping of keys to values`,
+			},
+		},
+		{
+			name: "duplicate key",
+			yaml: "default_model: gpt-4o\ndefault_model: gpt-4o-mini\n",
+			want: []string{`line 2, column 1: duplicate key "default_model" (first set on line 1)`},
+		},
+		{
+			name: "bad choices",
+			yaml: `redact: hide
+context_policy: drop
+log_level: loud
+budget:
+  policy: block
+`,
+			want: []string{
+				`line 1, column 9: redact is "hide"; it should be one of warn, mask, block, off`,
+				`line 2, column 17: context_policy is "drop"; it should be one of summarize, drop-oldest, error`,
+				`line 3, column 12: log_level is "loud"; it should be one of debug, info, warn, error`,
+				`line 5, column 11: budget.policy is "block"; it should be one of warn, refuse`,
+			},
+		},
+		{
+			name: "unknown providers",
+			yaml: `default_provider: opneai
+providers:
+  antropic:
+    api_key: x
+personas:
+  p:
+    provider: mistral
+`,
+			want: []string{
+				`line 1, column 19: unknown provider "opneai"; did you mean "openai"?`,
+				`line 3, column 3: unknown provider "antropic"; did you mean "anthropic"?`,
+				`line 7, column 15: unknown provider "mistral"; providers are anthropic, local, openai`,
+			},
+		},
+		{
+			name: "env references",
+			yaml: `providers:
+  openai:
+    api_key: ${OPENAI_API_KEY
+  anthropic:
+    api_key: $ANTHROPIC_API_KEY
+  local:
+    api_key: ${1BAD}
+`,
+			want: []string{
+				`line 3, column 14: providers.openai.api_key: environment reference "${OPENAI_API_KEY" is missing its closing }`,
+				`line 5, column 14: providers.anthropic.api_key: "$ANTHROPIC_API_KEY" is not expanded; write ${ANTHROPIC_API_KEY}`,
+				`line 7, column 14: providers.local.api_key: "1BAD" is not a valid environment variable name`,
+			},
+		},
+		{
+			name: "conflicting defaults",
+			yaml: `default_provider: anthropic
+default_model: gpt-4o
+providers:
+  openai:
+    default_model: claude-sonnet-4-20250514
+routes:
+  - model: claude-sonnet-4-20250514
+    provider: openai
+budget:
+  daily: 50
+  monthly: 20
+`,
+			want: []string{
+				`line 2, column 16: model "gpt-4o" is offered by openai, but default_provider is anthropic`,
+				`line 5, column 20: model "claude-sonnet-4-20250514" is offered by anthropic, but the provider is openai`,
+				`line 7, column 12: model "claude-sonnet-4-20250514" is offered by anthropic, but routes[0].provider is openai`,
+				`line 10, column 10: budget.daily ($50) is more than budget.monthly ($20)`,
+			},
+		},
+		{
+			name: "retry",
+			yaml: `retry:
+  max_attempts: 3
+  backoff: soon
+  providers:
+    antropic:
+      status_codes: [529]
+`,
+			want: []string{
+				`line 3, column 12: retry.backoff should be a duration such as "30s" or "24h", not "soon"`,
+				`line 5, column 5: unknown provider "antropic"; did you mean "anthropic"?`,
+			},
+		},
+		{
+			name: "models",
+			yaml: `models:
+  gpt-4o:
+    provider: anthropic
+    vision: maybe
+  my-model:
+    knowledge_cutoff: last spring
+`,
+			want: []string{
+				`line 2, column 3: model "gpt-4o" is offered by openai, but models.gpt-4o.provider is anthropic`,
+				`line 4, column 13: models.gpt-4o.vision should be true or false, not "maybe"`,
+				`line 6, column 23: models.my-model.knowledge_cutoff is "last spring"; it should be a month, as YYYY-MM`,
+			},
+		},
+		{
+			name: "syntax error",
+			yaml: "default_model: gpt-4o\n  bad: [indent\n",
+			want: []string{`line 2: invalid YAML: `},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Validate([]byte(tt.yaml), testKnown)
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %d problems:\n%s\nwant %d:\n%s", len(got), strings.Join(got, "\n"), len(tt.want), strings.Join(tt.want, "\n"))
+			}
+			for i := range got {
+				// Syntax errors come from the YAML parser; match their start
+				if !strings.HasPrefix(got[i], tt.want[i]) {
+					t.Errorf("problem %d = %s\nwant %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// dialTimeout bounds how long the CLI waits to find out whether a daemon
+// is running; past it, the CLI goes on without one.
+const dialTimeout = 250 * time.Millisecond
+
+// ErrNotRunning means no daemon is listening on the socket.
+var ErrNotRunning = errors.New("daemon is not running")
+
+// Client talks to a running daemon.
+type Client struct {
+	http   *http.Client
+	Status Status
+}
+
+// Dial connects to the daemon listening on socketPath and returns its
+// status, or ErrNotRunning if there is none.
+func Dial(ctx context.Context, socketPath string) (*Client, error) {
+	if _, err := os.Stat(socketPath); err != nil {
+		return nil, ErrNotRunning
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	c := &Client{http: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}}
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	resp, err := c.do(ctx, http.MethodGet, "/v1/status", nil)
+	if err != nil {
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return nil, ErrNotRunning
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&c.Status); err != nil {
+		return nil, fmt.Errorf("reading daemon status: %w", err)
+	}
+	return c, nil
+}
+
+// do sends a request to the daemon, failing unless it succeeds.
+func (c *Client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	// The host is ignored: every connection goes to the socket
+	req, err := http.NewRequestWithContext(ctx, method, "http://askd"+path, r)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("daemon: %s", strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// Chat returns a provider.ChatFunc that sends chats to the named provider
+// in the daemon, streaming tokens as the daemon receives them.
+func (c *Client) Chat(providerName string) provider.ChatFunc {
+	return func(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+		defer close(stream)
+		resp, err := c.do(ctx, http.MethodPost, "/v1/chat", chatRequest{
+			Provider:    providerName,
+			Model:       req.Model,
+			Messages:    req.Messages,
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+			WebSearch:   req.WebSearch,
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var f frame
+			if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+				return fmt.Errorf("reading daemon response: %w", err)
+			}
+			if f.Done {
+				return frameError(f)
+			}
+			select {
+			case stream <- f.Token:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading daemon response: %w", err)
+		}
+		return errors.New("daemon response ended early")
+	}
+}
+
+// frameError returns the error a final frame reports, if any, in the
+// category it had in the daemon.
+func frameError(f frame) error {
+	if f.Error == "" {
+		return nil
+	}
+	if sentinel, ok := errorKinds[f.Kind]; ok {
+		if f.Detail != nil {
+			// Keep the details, such as whether a retry may succeed
+			detail := *f.Detail
+			detail.Kind = sentinel
+			return &remoteError{msg: f.Error, kind: &detail}
+		}
+		return &remoteError{msg: f.Error, kind: sentinel}
+	}
+	return errors.New(f.Error)
+}
+
+// remoteError is an error from the daemon, matching its category with
+// errors.Is, and its provider.Error, if any, with errors.As.
+type remoteError struct {
+	msg  string
+	kind error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.kind }
+
+// SaveConversation saves conv to history through the daemon, setting its
+// ID and those of its new messages.
+func (c *Client) SaveConversation(ctx context.Context, conv *history.Conversation) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/conversations", conv)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var saved history.Conversation
+	if err := json.NewDecoder(resp.Body).Decode(&saved); err != nil {
+		return fmt.Errorf("reading saved conversation: %w", err)
+	}
+	if len(saved.Messages) != len(conv.Messages) {
+		return errors.New("daemon returned a different conversation")
+	}
+	conv.ID = saved.ID
+	for i := range conv.Messages {
+		conv.Messages[i].ID = saved.Messages[i].ID
+		conv.Messages[i].ConversationID = saved.Messages[i].ConversationID
+		conv.Messages[i].Attempt = saved.Messages[i].Attempt
+	}
+	return nil
+}
+
+// RecordUsage records u in the daemon's history and sets its ID.
+func (c *Client) RecordUsage(ctx context.Context, u *history.Usage) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/usage", u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var recorded history.Usage
+	if err := json.NewDecoder(resp.Body).Decode(&recorded); err != nil {
+		return fmt.Errorf("reading recorded usage: %w", err)
+	}
+	u.ID, u.CreatedAt = recorded.ID, recorded.CreatedAt
+	return nil
+}
+
+// Spend returns the total recorded cost since the given time, for one
+// provider or, if providerName is empty, for all of them, as
+// history.Store's Spend does.
+func (c *Client) Spend(ctx context.Context, providerName string, since time.Time) (float64, error) {
+	query := url.Values{"provider": {providerName}, "since": {since.Format(time.RFC3339Nano)}}
+	resp, err := c.do(ctx, http.MethodGet, "/v1/spend?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var s spend
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return 0, fmt.Errorf("reading spending: %w", err)
+	}
+	return s.Spent, nil
+}
+
+// Shutdown asks the daemon to stop.
+func (c *Client) Shutdown(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/shutdown", nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
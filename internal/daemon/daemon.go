@@ -0,0 +1,247 @@
+// Package daemon runs ask's requests in a long-lived background process,
+// so that each invocation of the CLI reuses its warm HTTPS connections,
+// loaded configuration, and open history database instead of starting
+// from scratch. The CLI talks to it over HTTP on a unix socket.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// SocketName is the name of the daemon's socket in the data directory.
+const SocketName = "askd.sock"
+
+// SocketPath returns the path of the daemon's socket in dataDir.
+func SocketPath(dataDir string) string {
+	return filepath.Join(dataDir, SocketName)
+}
+
+// Listen listens on socketPath for clients, replacing the socket of a
+// daemon that did not stop cleanly. It fails if a daemon is running.
+func Listen(socketPath string) (net.Listener, error) {
+	if c, err := Dial(context.Background(), socketPath); err == nil {
+		return nil, fmt.Errorf("a daemon is already running (pid %d)", c.Status.PID)
+	}
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+
+	// Only the user may send requests with their API keys
+	return listenPrivate(socketPath)
+}
+
+// Status describes a running daemon.
+type Status struct {
+	Version   string    `json:"version"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+	Providers []string  `json:"providers"`
+}
+
+// chatRequest is a chat request sent to the daemon.
+type chatRequest struct {
+	Provider    string             `json:"provider"`
+	Model       string             `json:"model"`
+	Messages    []provider.Message `json:"messages"`
+	Temperature float64            `json:"temperature,omitempty"`
+	MaxTokens   int                `json:"max_tokens,omitempty"`
+	WebSearch   bool               `json:"web_search,omitempty"`
+}
+
+// frame is one line of a streamed chat response: a token, or the end of
+// the response with the error, if any, that ended it.
+type frame struct {
+	Token string `json:"token,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error string `json:"error,omitempty"`
+	Kind  string `json:"kind,omitempty"` // see errorKinds
+
+	// Detail is the provider's account of the error, if it gave one.
+	Detail *provider.Error `json:"detail,omitempty"`
+}
+
+// errorKinds names the provider errors that keep their category when
+// they cross the socket, so the CLI still exits with the right code.
+var errorKinds = map[string]error{
+	"no_api_key":       provider.ErrNoAPIKey,
+	"auth":             provider.ErrAuth,
+	"rate_limited":     provider.ErrRateLimited,
+	"server":           provider.ErrServer,
+	"api":              provider.ErrAPI,
+	"content_filtered": provider.ErrContentFiltered,
+}
+
+// Server handles the daemon's requests:
+//
+//	GET  /v1/status         the daemon's Status
+//	POST /v1/chat           a chat, streamed as JSON lines
+//	POST /v1/conversations  save a conversation to history
+//	POST /v1/usage          record the usage of a request
+//	GET  /v1/spend          the spending since a time, for budgets
+//	POST /v1/shutdown       stop the daemon
+type Server struct {
+	providers map[string]provider.Provider
+	store     history.Store
+	status    Status
+	mux       *http.ServeMux
+	stopOnce  sync.Once
+
+	// Shutdown is closed when a client asks the daemon to stop.
+	Shutdown chan struct{}
+}
+
+// NewServer returns a Server sending chats to providers, by name, and
+// saving conversations to store.
+func NewServer(version string, providers []provider.Provider, store history.Store) *Server {
+	s := &Server{
+		providers: make(map[string]provider.Provider),
+		store:     store,
+		status:    Status{Version: version, PID: os.Getpid(), Started: time.Now()},
+		mux:       http.NewServeMux(),
+		Shutdown:  make(chan struct{}),
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+		s.status.Providers = append(s.status.Providers, p.Name())
+	}
+	s.mux.HandleFunc("GET /v1/status", s.handleStatus)
+	s.mux.HandleFunc("POST /v1/chat", s.handleChat)
+	s.mux.HandleFunc("POST /v1/conversations", s.handleSaveConversation)
+	s.mux.HandleFunc("POST /v1/usage", s.handleRecordUsage)
+	s.mux.HandleFunc("GET /v1/spend", s.handleSpend)
+	s.mux.HandleFunc("POST /v1/shutdown", s.handleShutdown)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status)
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid chat request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, ok := s.providers[req.Provider]
+	if !ok {
+		http.Error(w, "provider not configured in the daemon: "+req.Provider, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+
+	stream := make(chan string, util.DefaultChannelBuffer)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Chat(r.Context(), &provider.ChatRequest{
+			Messages:    req.Messages,
+			Model:       req.Model,
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+			WebSearch:   req.WebSearch,
+		}, stream)
+	}()
+	for token := range stream {
+		// A failed write means the client went away, which cancels the
+		// request's context and so the chat
+		if enc.Encode(frame{Token: token}) == nil && flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	end := frame{Done: true}
+	if err := <-errCh; err != nil {
+		end.Error = err.Error()
+		for kind, sentinel := range errorKinds {
+			if errors.Is(err, sentinel) {
+				end.Kind = kind
+			}
+		}
+		var pe *provider.Error
+		if errors.As(err, &pe) {
+			end.Detail = pe
+		}
+	}
+	_ = enc.Encode(end)
+}
+
+func (s *Server) handleSaveConversation(w http.ResponseWriter, r *http.Request) {
+	var conv history.Conversation
+	if err := json.NewDecoder(r.Body).Decode(&conv); err != nil {
+		http.Error(w, "invalid conversation: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := s.store.SaveConversation(r.Context(), &conv); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, conv)
+}
+
+func (s *Server) handleRecordUsage(w http.ResponseWriter, r *http.Request) {
+	var u history.Usage
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		http.Error(w, "invalid usage: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.RecordUsage(r.Context(), &u); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+// spend is the response to GET /v1/spend?provider=&since=, with since in
+// RFC 3339 format and an empty provider meaning all of them.
+type spend struct {
+	Spent float64 `json:"spent"`
+}
+
+func (s *Server) handleSpend(w http.ResponseWriter, r *http.Request) {
+	since, err := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	total, err := s.store.Spend(r.Context(), r.URL.Query().Get("provider"), since)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, spend{Spent: total})
+}
+
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+	s.stopOnce.Do(func() { close(s.Shutdown) })
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devaloi/ask/pkg/ask/history"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// fakeProvider streams a fixed set of tokens, then returns err.
+type fakeProvider struct {
+	tokens []string
+	err    error
+	got    *provider.ChatRequest
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+	defer close(stream)
+	f.got = req
+	for _, t := range f.tokens {
+		stream <- t
+	}
+	return f.err
+}
+
+func (f *fakeProvider) BuildRequest(ctx context.Context, req *provider.ChatRequest) (*http.Request, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeProvider) Models() []string { return nil }
+func (f *fakeProvider) Name() string     { return "openai" }
+
+// startDaemon serves a daemon with p and a fresh store, returning a
+// client connected to it and the daemon's socket.
+func startDaemon(t *testing.T, p provider.Provider) (*Client, *Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := history.NewSQLiteStore(filepath.Join(dir, "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	socket := SocketPath(dir)
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer("v1.2.3", []provider.Provider{p}, store)
+	httpSrv := &http.Server{Handler: srv}
+	go httpSrv.Serve(l)
+	t.Cleanup(func() { httpSrv.Close() })
+
+	c, err := Dial(context.Background(), socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv, socket
+}
+
+func chat(c *Client, name string, req *provider.ChatRequest) (string, error) {
+	stream := make(chan string)
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.Chat(name)(context.Background(), req, stream) }()
+	var b strings.Builder
+	for token := range stream {
+		b.WriteString(token)
+	}
+	return b.String(), <-errCh
+}
+
+func TestChat(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"Hello", ", ", "world"}}
+	c, _, _ := startDaemon(t, p)
+	if c.Status.Version != "v1.2.3" || len(c.Status.Providers) != 1 {
+		t.Errorf("Status = %+v", c.Status)
+	}
+
+	req := &provider.ChatRequest{
+		Model:     "gpt-4o",
+		Messages:  []provider.Message{{Role: "user", Content: "hi"}},
+		MaxTokens: 10,
+		WebSearch: true,
+	}
+	got, err := chat(c, "openai", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Hello, world" {
+		t.Errorf("response = %q", got)
+	}
+	if p.got.Model != "gpt-4o" || p.got.MaxTokens != 10 || !p.got.WebSearch || p.got.Messages[0].Content != "hi" {
+		t.Errorf("provider got %+v", p.got)
+	}
+}
+
+func TestChatErrors(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"partial"}, err: fmt.Errorf("OpenAI %w: slow down", provider.ErrRateLimited)}
+	c, _, _ := startDaemon(t, p)
+
+	got, err := chat(c, "openai", &provider.ChatRequest{Model: "gpt-4o"})
+	if got != "partial" {
+		t.Errorf("response = %q", got)
+	}
+	if !errors.Is(err, provider.ErrRateLimited) || err.Error() != "OpenAI rate limited: slow down" {
+		t.Errorf("error = %v, want the rate limit error", err)
+	}
+
+	_, err = chat(c, "anthropic", &provider.ChatRequest{})
+	if err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("error = %v, want provider not configured", err)
+	}
+}
+
+func TestChatErrors_Detail(t *testing.T) {
+	quota := &provider.Error{Provider: "openai", Kind: provider.ErrRateLimited, Status: 429, Code: "insufficient_quota", Message: "out of credit"}
+	c, _, _ := startDaemon(t, &fakeProvider{err: quota})
+
+	_, err := chat(c, "openai", &provider.ChatRequest{Model: "gpt-4o"})
+	var pe *provider.Error
+	if !errors.As(err, &pe) {
+		t.Fatalf("error = %v, want a provider.Error", err)
+	}
+	if pe.Status != 429 || pe.Code != "insufficient_quota" || pe.Retryable {
+		t.Errorf("detail = %+v, want the provider's", pe)
+	}
+	if !errors.Is(err, provider.ErrRateLimited) || provider.IsRetryable(err) {
+		t.Errorf("error = %v, want a rate limit that is not retryable", err)
+	}
+}
+
+func TestSaveConversation(t *testing.T) {
+	c, _, _ := startDaemon(t, &fakeProvider{})
+	conv := &history.Conversation{
+		Model:    "gpt-4o",
+		Provider: "openai",
+		Messages: []history.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+	}
+	if err := c.SaveConversation(context.Background(), conv); err != nil {
+		t.Fatal(err)
+	}
+	if conv.ID == 0 || conv.Messages[1].ID == 0 || conv.Messages[1].ConversationID != conv.ID {
+		t.Errorf("IDs not set: %+v", conv)
+	}
+
+	conv.Messages = append(conv.Messages, history.Message{Role: "user", Content: "again"})
+	id := conv.ID
+	if err := c.SaveConversation(context.Background(), conv); err != nil {
+		t.Fatal(err)
+	}
+	if conv.ID != id || conv.Messages[2].ID == 0 {
+		t.Errorf("appending: %+v", conv)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	c, _, _ := startDaemon(t, &fakeProvider{})
+	ctx := context.Background()
+	since := time.Now().Add(-time.Minute)
+	for _, u := range []*history.Usage{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, Cost: 0.25},
+		{Provider: "anthropic", Model: "claude-sonnet-4-0", Cost: 0.5},
+	} {
+		if err := c.RecordUsage(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.ID == 0 {
+			t.Errorf("RecordUsage() did not set the ID: %+v", u)
+		}
+	}
+
+	for name, want := range map[string]float64{"openai": 0.25, "": 0.75} {
+		if got, err := c.Spend(ctx, name, since); err != nil || got != want {
+			t.Errorf("Spend(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if got, err := c.Spend(ctx, "", time.Now().Add(time.Minute)); err != nil || got != 0 {
+		t.Errorf("Spend() since later = %v, %v; want 0", got, err)
+	}
+}
+
+func TestDialAndListen(t *testing.T) {
+	socket := SocketPath(t.TempDir())
+	if _, err := Dial(context.Background(), socket); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Dial() error = %v, want ErrNotRunning", err)
+	}
+
+	// A socket left behind by a daemon that died is replaced
+	l, err := Listen(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("socket mode = %v, want it private to the user", info.Mode())
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := Dial(context.Background(), socket); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Dial() stale socket error = %v, want ErrNotRunning", err)
+	}
+	if l, err = Listen(socket); err != nil {
+		t.Fatalf("Listen() over a stale socket: %v", err)
+	}
+	l.Close()
+
+	c, srv, running := startDaemon(t, &fakeProvider{})
+	if _, err := Listen(running); err == nil {
+		t.Error("Listen() with a daemon running succeeded")
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-srv.Shutdown:
+	default:
+		t.Error("Shutdown did not signal the server")
+	}
+}
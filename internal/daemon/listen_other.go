@@ -0,0 +1,11 @@
+//go:build !unix
+
+package daemon
+
+import "net"
+
+// listenPrivate listens on the unix socket socketPath. Elsewhere than
+// Unix, the socket takes the access rights of the data directory.
+func listenPrivate(socketPath string) (net.Listener, error) {
+	return net.Listen("unix", socketPath)
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package daemon
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenPrivate listens on the unix socket socketPath, creating it with
+// permissions for the user alone, so that no one else can connect before
+// they could be tightened.
+func listenPrivate(socketPath string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socketPath)
+}
@@ -0,0 +1,207 @@
+// Package diff produces unified diffs between two versions of a text file.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change,
+// as in diff -u.
+const DefaultContext = 3
+
+// op is a line-level edit.
+type op byte
+
+const (
+	opEqual  op = ' '
+	opDelete op = '-'
+	opInsert op = '+'
+)
+
+type edit struct {
+	op   op
+	line string // including its newline, if any
+}
+
+// Unified returns a unified diff that turns a into b, with oldName and
+// newName in the file headers and context unchanged lines around each
+// hunk. It returns "" when a and b are equal. The output is suitable for
+// patch and git apply.
+func Unified(oldName, newName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	edits := lineDiff(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(edits, context) {
+		h.write(&out)
+	}
+	return out.String()
+}
+
+// splitLines splits s after each newline. A final line without a newline
+// is kept as is.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineDiff returns a shortest edit script turning a into b, using Myers'
+// algorithm.
+func lineDiff(a, b []string) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds v[-d..d] from before step d, for backtracking
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil // unreachable: d = n+m always reaches the end
+}
+
+// backtrack walks the recorded steps from the end of both inputs to the
+// start, collecting the edits in order.
+func backtrack(a, b []string, trace [][]int) []edit {
+	var edits []edit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		get := func(k int) int { return v[k+d] }
+
+		prevX, prevY := 0, 0
+		if d > 0 {
+			k := x - y
+			prevK := k - 1
+			if k == -d || (k != d && get(k-1) < get(k+1)) {
+				prevK = k + 1
+			}
+			prevX = get(prevK)
+			prevY = prevX - prevK
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{opEqual, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{opInsert, b[y]})
+			} else {
+				x--
+				edits = append(edits, edit{opDelete, a[x]})
+			}
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// hunk is a run of edits with its starting line in each file (1-based).
+type hunk struct {
+	oldStart, newStart int
+	edits              []edit
+}
+
+// hunks groups edits into hunks, each change surrounded by up to context
+// unchanged lines. Changes closer together than twice that share a hunk.
+func hunks(edits []edit, context int) []hunk {
+	var result []hunk
+	oldLine, newLine := 1, 1
+	start := -1     // index in edits of the current hunk's first edit
+	lastChange := 0 // index of the last change in the current hunk
+	var hStart hunk
+
+	for i, e := range edits {
+		if e.op != opEqual {
+			if start >= 0 && i-lastChange-1 > 2*context {
+				h := hStart
+				h.edits = edits[start : lastChange+context+1]
+				result = append(result, h)
+				start = -1
+			}
+			if start < 0 {
+				start = max(i-context, 0)
+				hStart = hunk{oldStart: oldLine - (i - start), newStart: newLine - (i - start)}
+			}
+			lastChange = i
+		}
+		if e.op != opInsert {
+			oldLine++
+		}
+		if e.op != opDelete {
+			newLine++
+		}
+	}
+	if start >= 0 {
+		h := hStart
+		h.edits = edits[start:min(lastChange+context+1, len(edits))]
+		result = append(result, h)
+	}
+	return result
+}
+
+// write formats the hunk in unified diff syntax.
+func (h hunk) write(out *strings.Builder) {
+	oldLen, newLen := 0, 0
+	for _, e := range h.edits {
+		if e.op != opInsert {
+			oldLen++
+		}
+		if e.op != opDelete {
+			newLen++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(h.oldStart, oldLen), hunkRange(h.newStart, newLen))
+	for _, e := range h.edits {
+		out.WriteByte(byte(e.op))
+		out.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk's start and length. An empty range starts at
+// the line before it, as diff -u does.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		start--
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\nTWO\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	want := `--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,5 @@
+ one
+-two
++TWO
+ three
+ four
+ five
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`
+	if got := Unified("a/f.txt", "b/f.txt", a, b, DefaultContext); got != want {
+		t.Errorf("Unified() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_Edges(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "x\n", "x\n", ""},
+		{"new file", "", "x\ny\n", "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{"emptied", "x\n", "", "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-x\n"},
+		{
+			"no newline at end",
+			"x\ny", "x\ny\n",
+			"--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n\\ No newline at end of file\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, DefaultContext); got != tt.want {
+				t.Errorf("Unified() =\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomFile := func() string {
+		var b strings.Builder
+		for range rng.Intn(40) {
+			b.WriteString(strconv.Itoa(rng.Intn(6)) + "\n")
+		}
+		return b.String()
+	}
+
+	for i := range 500 {
+		a, b := randomFile(), randomFile()
+		context := rng.Intn(4)
+		patch := Unified("a", "b", a, b, context)
+		got, err := applyPatch(a, patch)
+		if err != nil {
+			t.Fatalf("case %d: %v\npatch:\n%s", i, err, patch)
+		}
+		if got != b {
+			t.Fatalf("case %d: patch produced %q, want %q\npatch:\n%s", i, got, b, patch)
+		}
+	}
+}
+
+// applyPatch applies a unified diff from Unified to a, checking that every
+// context and deleted line matches.
+func applyPatch(a, patch string) (string, error) {
+	if patch == "" {
+		return a, nil
+	}
+	src := splitLines(a)
+	lines := strings.SplitAfter(patch, "\n")[2:]
+
+	var out []string
+	pos := 0
+	for i := 0; i < len(lines) && lines[i] != ""; i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") {
+			var oldStart, oldLen, newStart, newLen int
+			if _, err := fmt.Sscanf(line, "@@ -%d,%d +%d,%d @@", &oldStart, &oldLen, &newStart, &newLen); err != nil {
+				return "", fmt.Errorf("bad hunk header %q", line)
+			}
+			if oldLen > 0 {
+				oldStart--
+			}
+			if oldStart < pos {
+				return "", fmt.Errorf("hunk %q overlaps the previous one", line)
+			}
+			out = append(out, src[pos:oldStart]...)
+			pos = oldStart
+			continue
+		}
+
+		text := line[1:]
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+			text = strings.TrimSuffix(text, "\n")
+			i++
+		}
+		switch line[0] {
+		case ' ', '-':
+			if pos >= len(src) || src[pos] != text {
+				return "", fmt.Errorf("line %d: have %q, patch expects %q", pos+1, src[min(pos, len(src)-1)], text)
+			}
+			pos++
+			if line[0] == ' ' {
+				out = append(out, text)
+			}
+		case '+':
+			out = append(out, text)
+		default:
+			return "", fmt.Errorf("bad patch line %q", line)
+		}
+	}
+	out = append(out, src[pos:]...)
+	return strings.Join(out, ""), nil
+}
@@ -0,0 +1,157 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordOp says where the text of a Chunk is.
+type WordOp int
+
+const (
+	Same    WordOp = iota // in both texts
+	Removed               // only in the old text
+	Added                 // only in the new text
+)
+
+// Chunk is a run of text with the same WordOp.
+type Chunk struct {
+	Op   WordOp
+	Text string
+}
+
+// maxWordTokens bounds the words and spaces compared word by word, since
+// the comparison's memory grows with the square of the differences.
+// Longer texts are compared line by line.
+const maxWordTokens = 4000
+
+// Words compares a and b word by word, returning chunks that spell out a
+// when the Added ones are left out, and b when the Removed ones are.
+// Differences in whitespace alone are not changes; the Same chunks have
+// b's whitespace.
+func Words(a, b string) []Chunk {
+	ta, tb := splitWords(a), splitWords(b)
+	if len(ta)+len(tb) > maxWordTokens {
+		ta, tb = splitLines(a), splitLines(b)
+	}
+
+	var chunks []Chunk
+	add := func(op WordOp, text string) {
+		if n := len(chunks); n > 0 && chunks[n-1].Op == op {
+			chunks[n-1].Text += text
+			return
+		}
+		chunks = append(chunks, Chunk{op, text})
+	}
+	i, j := 0, 0
+	for _, e := range lineDiff(wordKeys(ta), wordKeys(tb)) {
+		switch e.op {
+		case opEqual:
+			add(Same, tb[j])
+			i++
+			j++
+		case opDelete:
+			add(Removed, ta[i])
+			i++
+		case opInsert:
+			add(Added, tb[j])
+			j++
+		}
+	}
+	return chunks
+}
+
+// splitWords splits s into words, punctuation marks, and the whitespace
+// between them.
+func splitWords(s string) []string {
+	var tokens []string
+	start, prev := 0, tokenClass(0)
+	for i, r := range s {
+		class := classOf(r)
+		if i > start && (class != prev || class == punctClass) {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prev = class
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+type tokenClass int
+
+const (
+	wordClass tokenClass = iota + 1
+	spaceClass
+	punctClass // each mark is a token of its own
+)
+
+func classOf(r rune) tokenClass {
+	switch {
+	case unicode.IsSpace(r):
+		return spaceClass
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\'':
+		return wordClass
+	default:
+		return punctClass
+	}
+}
+
+// wordKeys returns the tokens as compared: all whitespace is the same.
+func wordKeys(tokens []string) []string {
+	keys := make([]string, len(tokens))
+	for i, t := range tokens {
+		if strings.TrimSpace(t) == "" {
+			t = " "
+		}
+		keys[i] = t
+	}
+	return keys
+}
+
+// SideBySide lays out a and b in two columns, wrapped to fit width
+// characters in all, separated by " │ ".
+func SideBySide(a, b string, width int) string {
+	col := max((width-3)/2, 10)
+	left, right := wrap(a, col), wrap(b, col)
+
+	var out strings.Builder
+	for i := range max(len(left), len(right)) {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		out.WriteString(l)
+		out.WriteString(strings.Repeat(" ", col-utf8.RuneCountInString(l)))
+		out.WriteString(" │ ")
+		out.WriteString(r)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+// wrap splits s into lines of at most width characters, breaking at
+// spaces where it can.
+func wrap(s string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(s, "\n"), "\n") {
+		line = strings.ReplaceAll(strings.TrimRight(line, " \t\r"), "\t", "    ")
+		for utf8.RuneCountInString(line) > width {
+			runes := []rune(line)
+			cut := width
+			if i := strings.LastIndex(string(runes[:width+1]), " "); i > 0 {
+				cut = utf8.RuneCountInString(string(runes[:width+1])[:i])
+			}
+			lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+			line = strings.TrimLeft(string(runes[cut:]), " ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render writes chunks in git's --word-diff=plain style.
+func render(chunks []Chunk) string {
+	var b strings.Builder
+	for _, c := range chunks {
+		switch c.Op {
+		case Same:
+			b.WriteString(c.Text)
+		case Removed:
+			b.WriteString("[-" + c.Text + "-]")
+		case Added:
+			b.WriteString("{+" + c.Text + "+}")
+		}
+	}
+	return b.String()
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{"equal", "the same text", "the same text", "the same text"},
+		{"changed word", "Use a mutex here.", "Use a channel here.", "Use a [-mutex-]{+channel+} here."},
+		{"added words", "Go is fast.", "Go is fast and simple.", "Go is fast{+ and simple+}."},
+		{"whitespace only", "one two\nthree", "one  two three", "one  two three"},
+		{"empty", "", "new", "{+new+}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Words(tt.a, tt.b)
+			if got := render(chunks); got != tt.want {
+				t.Errorf("Words() = %q, want %q", got, tt.want)
+			}
+
+			var a, b strings.Builder
+			for _, c := range chunks {
+				if c.Op != Added {
+					a.WriteString(c.Text)
+				}
+				if c.Op != Removed {
+					b.WriteString(c.Text)
+				}
+			}
+			if b.String() != tt.b {
+				t.Errorf("new text = %q, want %q", b.String(), tt.b)
+			}
+			if strings.Join(strings.Fields(a.String()), " ") != strings.Join(strings.Fields(tt.a), " ") {
+				t.Errorf("old text = %q, want the words of %q", a.String(), tt.a)
+			}
+		})
+	}
+}
+
+func TestWordsLongTexts(t *testing.T) {
+	var a strings.Builder
+	for i := range maxWordTokens {
+		fmt.Fprintf(&a, "line %d\n", i)
+	}
+	b := strings.Replace(a.String(), "line 0\n", "first line\n", 1)
+	chunks := Words(a.String(), b)
+	if len(chunks) != 3 || render(chunks[:2]) != "[-line 0\n-]{+first line\n+}" {
+		t.Errorf("Words() of long texts = %d chunks starting %q, want a line diff", len(chunks), render(chunks)[:60])
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	got := SideBySide("short\nanswer", "a much longer answer that wraps", 33)
+	want := "" +
+		"short           │ a much longer\n" +
+		"answer          │ answer that\n" +
+		"                │ wraps\n"
+	if got != want {
+		t.Errorf("SideBySide() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devaloi/ask/internal/codeblock"
+	"github.com/devaloi/ask/internal/util"
+)
+
+// Check returns a description of each expectation response fails, or
+// nil if it passes them all.
+func (e Expect) Check(response string) []string {
+	var failures []string
+
+	haystack := response
+	if e.IgnoreCase {
+		haystack = strings.ToLower(response)
+	}
+	for _, s := range e.Contains {
+		needle := s
+		if e.IgnoreCase {
+			needle = strings.ToLower(s)
+		}
+		if !strings.Contains(haystack, needle) {
+			failures = append(failures, fmt.Sprintf("does not contain %q", s))
+		}
+	}
+	for _, s := range e.NotContains {
+		needle := s
+		if e.IgnoreCase {
+			needle = strings.ToLower(s)
+		}
+		if strings.Contains(haystack, needle) {
+			failures = append(failures, fmt.Sprintf("contains %q", s))
+		}
+	}
+	for _, re := range e.regexps {
+		if !re.MatchString(response) {
+			failures = append(failures, fmt.Sprintf("does not match /%s/", re))
+		}
+	}
+
+	if e.JSONSchema != nil {
+		var value any
+		// Models often fence JSON even when asked not to
+		text := codeblock.Extract(response)
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			failures = append(failures, fmt.Sprintf("is not JSON: %v (%s)", err, util.Truncate(strings.TrimSpace(text), 60)))
+		} else {
+			for _, err := range ValidateSchema(e.JSONSchema, value) {
+				failures = append(failures, "does not match the JSON schema: "+err)
+			}
+		}
+	}
+	return failures
+}
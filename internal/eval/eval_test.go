@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const suiteYAML = `
+models: [gpt-4o, claude-sonnet-4-20250514]
+system: Be terse.
+cases:
+  - name: capital
+    prompt: What is the capital of France?
+    expect:
+      contains: [Paris]
+      not_contains: [London]
+      ignore_case: true
+  - prompt: Give a user as JSON
+    system: Reply with JSON only.
+    models: [gpt-4o-mini]
+    expect:
+      regex: ['"name"']
+      json_schema:
+        type: object
+        required: [name, age]
+        properties:
+          name: {type: string, minLength: 1}
+          age: {type: integer, minimum: 0}
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(suiteYAML))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if s.Cases[1].Name != "case 2" {
+		t.Errorf("unnamed case = %q, want %q", s.Cases[1].Name, "case 2")
+	}
+	if got := s.SystemPrompt(&s.Cases[0]); got != "Be terse." {
+		t.Errorf("SystemPrompt(case 1) = %q", got)
+	}
+	if got := s.SystemPrompt(&s.Cases[1]); got != "Reply with JSON only." {
+		t.Errorf("SystemPrompt(case 2) = %q", got)
+	}
+
+	var got []string
+	for _, r := range s.Runs("", "default") {
+		got = append(got, r.Case.Name+"/"+r.Model)
+	}
+	want := "capital/gpt-4o capital/claude-sonnet-4-20250514 case 2/gpt-4o-mini"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Runs() = %v, want %s", got, want)
+	}
+	if runs := s.Runs("o3", "default"); len(runs) != 2 || runs[0].Model != "o3" || runs[1].Model != "o3" {
+		t.Errorf("Runs(override) = %+v, want each case once with o3", runs)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := map[string]string{
+		"no cases":   "models: [gpt-4o]\n",
+		"no prompt":  "cases:\n  - name: x\n",
+		"duplicate":  "cases:\n  - {name: a, prompt: p}\n  - {name: a, prompt: q}\n",
+		"bad regex":  "cases:\n  - {prompt: p, expect: {regex: ['(']}}\n",
+		"not a yaml": "cases: [",
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: Parse() succeeded", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s, err := Parse([]byte(suiteYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	capital, user := s.Cases[0].Expect, s.Cases[1].Expect
+
+	tests := []struct {
+		name     string
+		expect   Expect
+		response string
+		failures int
+	}{
+		{"contains", capital, "The capital is paris.", 0},
+		{"missing", capital, "I don't know.", 1},
+		{"forbidden", capital, "Paris, not London.", 1},
+		{"json", user, `{"name": "Ada", "age": 36}`, 0},
+		{"fenced json", user, "```json\n{\"name\": \"Ada\", \"age\": 36}\n```", 0},
+		{"schema violations", user, `{"name": "", "age": -1.5}`, 2},
+		{"below minimum", user, `{"name": "Ada", "age": -1}`, 1},
+		{"not json", user, `name: Ada`, 2},
+	}
+	for _, tt := range tests {
+		got := tt.expect.Check(tt.response)
+		if len(got) != tt.failures {
+			t.Errorf("%s: Check() = %q, want %d failures", tt.name, got, tt.failures)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]any{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 2},
+			"status": map[string]any{"enum": []any{"ok", "error"}},
+			"code":   map[string]any{"type": []any{"integer", "null"}},
+			"id":     map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+		},
+	}
+
+	tests := []struct {
+		json string
+		errs int
+	}{
+		{`{"tags": ["a"], "status": "ok", "code": null, "id": "abc"}`, 0},
+		{`{"tags": ["a", 1, "c"]}`, 2},
+		{`{"status": "maybe"}`, 1},
+		{`{"code": 1.5}`, 1},
+		{`{"id": "ABC"}`, 1},
+		{`{"extra": true}`, 1},
+		{`[]`, 1},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.json), &v); err != nil {
+			t.Fatal(err)
+		}
+		if errs := ValidateSchema(schema, v); len(errs) != tt.errs {
+			t.Errorf("ValidateSchema(%s) = %q, want %d errors", tt.json, errs, tt.errs)
+		}
+	}
+}
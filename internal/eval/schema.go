@@ -0,0 +1,189 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// ValidateSchema checks value, decoded from JSON, against a JSON Schema
+// and returns a description of each violation. It supports the common
+// subset of keywords: type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, and maximum.
+func ValidateSchema(schema map[string]any, value any) []string {
+	var errs []string
+	validate(schema, value, "$", &errs)
+	return errs
+}
+
+func validate(schema map[string]any, value any, path string, errs *[]string) {
+	fail := func(format string, a ...any) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, a...))
+	}
+
+	if t, ok := schema["type"]; ok {
+		var types []string
+		switch t := t.(type) {
+		case string:
+			types = []string{t}
+		case []any:
+			for _, v := range t {
+				if s, ok := v.(string); ok {
+					types = append(types, s)
+				}
+			}
+		}
+		if !slices.ContainsFunc(types, func(t string) bool { return hasType(value, t) }) {
+			fail("want %s, got %s", joinTypes(types), typeOf(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		if !slices.ContainsFunc(enum, func(e any) bool { return equal(e, value) }) {
+			fail("%v is not one of %v", value, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !equal(c, value) {
+		fail("want %v, got %v", c, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := v[name]; !present {
+						fail("missing required property %q", name)
+					}
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := props[name].(map[string]any); ok {
+				validate(sub, v[name], path+"."+name, errs)
+				continue
+			}
+			if _, declared := props[name]; declared {
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", name)
+				}
+			case map[string]any:
+				validate(extra, v[name], path+"."+name, errs)
+			}
+		}
+
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			fail("want at least %v items, got %d", n, len(v))
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			fail("want at most %v items, got %d", n, len(v))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			fail("want at least %v characters, got %v", n, length)
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			fail("want at most %v characters, got %v", n, length)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				fail("invalid pattern %q in schema: %v", p, err)
+			} else if !re.MatchString(v) {
+				fail("%q does not match /%s/", v, p)
+			}
+		}
+
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			fail("%v is less than the minimum %v", v, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			fail("%v is greater than the maximum %v", v, n)
+		}
+	}
+}
+
+// hasType reports whether a JSON value has the JSON Schema type t.
+func hasType(value any, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == t
+	}
+}
+
+// typeOf returns the JSON Schema type name of a JSON value.
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("one of %v", types)
+}
+
+// number converts a schema keyword's value, decoded from YAML, to a float.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// equal compares a schema value, decoded from YAML, with a JSON value.
+func equal(schemaValue, value any) bool {
+	if n, ok := number(schemaValue); ok {
+		f, isNum := value.(float64)
+		return isNum && f == n
+	}
+	return reflect.DeepEqual(schemaValue, value)
+}
@@ -0,0 +1,130 @@
+// Package eval loads prompt suites and checks model responses against
+// their expectations, for regression-testing prompts in CI.
+package eval
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of prompts and the checks their responses must pass.
+type Suite struct {
+	// Models are the models every case runs against, unless the case
+	// lists its own.
+	Models []string `yaml:"models"`
+
+	// System is the system prompt for cases that do not set one.
+	System string `yaml:"system"`
+
+	// Temperature is the sampling temperature, if set.
+	Temperature float64 `yaml:"temperature"`
+
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is one prompt and its expectations.
+type Case struct {
+	Name   string   `yaml:"name"`
+	Prompt string   `yaml:"prompt"`
+	System string   `yaml:"system"`
+	Models []string `yaml:"models"`
+	Expect Expect   `yaml:"expect"`
+}
+
+// Expect lists the checks a response must pass. All of them apply.
+type Expect struct {
+	Contains    []string `yaml:"contains"`
+	NotContains []string `yaml:"not_contains"`
+	Regex       []string `yaml:"regex"`
+
+	// JSONSchema, if set, requires the response (or its code block) to
+	// be JSON valid against this schema.
+	JSONSchema map[string]any `yaml:"json_schema"`
+
+	// IgnoreCase makes Contains and NotContains case-insensitive.
+	IgnoreCase bool `yaml:"ignore_case"`
+
+	regexps []*regexp.Regexp
+}
+
+// Run is a case to send to one model.
+type Run struct {
+	Case  *Case
+	Model string
+}
+
+// Load reads and validates the suite at path.
+func Load(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses and validates a suite.
+func Parse(data []byte) (*Suite, error) {
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.Cases) == 0 {
+		return nil, fmt.Errorf("suite has no cases")
+	}
+
+	names := make(map[string]bool)
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate case name %q", c.Name)
+		}
+		names[c.Name] = true
+		if c.Prompt == "" {
+			return nil, fmt.Errorf("%s: prompt is required", c.Name)
+		}
+		for _, expr := range c.Expect.Regex {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid regex: %w", c.Name, err)
+			}
+			c.Expect.regexps = append(c.Expect.regexps, re)
+		}
+	}
+	return &s, nil
+}
+
+// Runs returns every case paired with each of its models, in suite order.
+// defaultModel is used for cases when neither they nor the suite name a
+// model; override, if set, replaces all the models.
+func (s *Suite) Runs(override, defaultModel string) []Run {
+	var runs []Run
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		models := c.Models
+		if len(models) == 0 {
+			models = s.Models
+		}
+		if override != "" || len(models) == 0 {
+			models = []string{cmp.Or(override, defaultModel)}
+		}
+		for _, m := range models {
+			runs = append(runs, Run{Case: c, Model: m})
+		}
+	}
+	return runs
+}
+
+// SystemPrompt returns the system prompt for c.
+func (s *Suite) SystemPrompt(c *Case) string {
+	if c.System != "" {
+		return c.System
+	}
+	return s.System
+}
@@ -0,0 +1,240 @@
+// Package files reads local files for inclusion in prompts.
+package files
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// binarySniffLen is how much of a file is inspected for NUL bytes,
+	// matching git's heuristic.
+	binarySniffLen = 8000
+
+	// bytesPerToken is a rough average used to estimate token counts.
+	bytesPerToken = 4
+)
+
+// Include expands patterns (files, directories, or globs), reads the
+// matching files, and returns their contents formatted as fenced code
+// blocks with filename headers, separated by blank lines.
+//
+// If maxBytes is positive and the matched files exceed it in total,
+// Include returns an error without reading them.
+func Include(patterns []string, maxBytes int64) (string, error) {
+	paths, err := Expand(patterns)
+	if err != nil {
+		return "", err
+	}
+
+	var total int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", fmt.Errorf("failed to stat file %s: %w", p, err)
+		}
+		total += info.Size()
+	}
+	if maxBytes > 0 && total > maxBytes {
+		return "", fmt.Errorf("included files total %d bytes (~%d tokens) across %d files, exceeding the %d byte limit\n\nNarrow the pattern or raise max_include_bytes in ~/.config/ask/config.yaml",
+			total, total/bytesPerToken, len(paths), maxBytes)
+	}
+
+	var blocks []string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		blocks = append(blocks, Fence(p, string(data)))
+	}
+
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// Expand resolves patterns to a de-duplicated list of text files.
+//
+// A plain file path is returned as-is and must not be binary. Directories
+// are walked recursively and globs (which may use "**" to match any number
+// of directories) are matched against the files beneath their static
+// prefix; in both cases files ignored by .gitignore, files under .git,
+// and binary files are skipped.
+func Expand(patterns []string) ([]string, error) {
+	var paths []string
+	seen := make(map[string]bool)
+
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+	}
+
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+
+		base, rest := splitGlob(pattern)
+		if rest == "" {
+			info, err := os.Stat(base)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", base, err)
+			}
+			if !info.IsDir() {
+				binary, err := isBinaryFile(base)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file %s: %w", base, err)
+				}
+				if binary {
+					return nil, fmt.Errorf("file %s appears to be binary", base)
+				}
+				add(filepath.FromSlash(base))
+				continue
+			}
+			rest = "**"
+		}
+
+		if base == "" {
+			base = "."
+		}
+		matches, err := walk(base, rest)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files matched %s", pattern)
+		}
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	return paths, nil
+}
+
+// walk returns the sorted text files under base whose paths relative to
+// base match pattern, honoring .gitignore files along the way.
+func walk(base, pattern string) ([]string, error) {
+	var ignores ignoreSet
+	if err := loadParentIgnores(&ignores, base); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(base), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if p != filepath.FromSlash(base) && ignores.ignored(p, true) {
+				return filepath.SkipDir
+			}
+			return ignores.load(p)
+		}
+
+		if !d.Type().IsRegular() || ignores.ignored(p, false) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(base), p)
+		if err != nil {
+			return err
+		}
+		if !matchPath(pattern, filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		binary, err := isBinaryFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", p, err)
+		}
+		if !binary {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", base, err)
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// loadParentIgnores loads the .gitignore files in the directories between
+// the current directory and base, exclusive of base itself. Absolute and
+// parent-relative bases have no such directories.
+func loadParentIgnores(ignores *ignoreSet, base string) error {
+	clean := filepath.ToSlash(filepath.Clean(base))
+	if clean == "." || filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return nil
+	}
+
+	dir := "."
+	for _, seg := range strings.Split(clean, "/") {
+		if err := ignores.load(dir); err != nil {
+			return err
+		}
+		dir = filepath.Join(dir, seg)
+	}
+	return nil
+}
+
+// isBinaryFile reports whether the file at path looks binary, i.e. its
+// first bytes contain a NUL byte.
+func isBinaryFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return bytes.IndexByte(buf[:n], 0) >= 0, nil
+}
+
+// Fence wraps content in a fenced code block headed by name.
+// The fence is made longer than any backtick run in content so that
+// embedded code blocks do not terminate it early.
+func Fence(name, content string) string {
+	fence := strings.Repeat("`", max(3, longestBacktickRun(content)+1))
+	lang := strings.TrimPrefix(filepath.Ext(name), ".")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:\n", name)
+	fmt.Fprintf(&b, "%s%s\n", fence, lang)
+	b.WriteString(content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(fence)
+
+	return b.String()
+}
+
+// longestBacktickRun returns the length of the longest run of backticks in s.
+func longestBacktickRun(s string) int {
+	longest, current := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
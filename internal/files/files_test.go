@@ -0,0 +1,250 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFence(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{
+			name:    "go file",
+			file:    "main.go",
+			content: "package main\n",
+			want:    "main.go:\n```go\npackage main\n```",
+		},
+		{
+			name:    "missing trailing newline",
+			file:    "notes.txt",
+			content: "hello",
+			want:    "notes.txt:\n```txt\nhello\n```",
+		},
+		{
+			name:    "no extension",
+			file:    "Makefile",
+			content: "build:\n",
+			want:    "Makefile:\n```\nbuild:\n```",
+		},
+		{
+			name:    "embedded code fence",
+			file:    "README.md",
+			content: "```go\nx := 1\n```\n",
+			want:    "README.md:\n````md\n```go\nx := 1\n```\n````",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fence(tt.file, tt.content); got != tt.want {
+				t.Errorf("Fence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("package a\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("notes\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Include([]string{a, b}, 0)
+	if err != nil {
+		t.Fatalf("Include() error = %v", err)
+	}
+
+	want := Fence(a, "package a\n") + "\n\n" + Fence(b, "notes\n")
+	if got != want {
+		t.Errorf("Include() = %q, want %q", got, want)
+	}
+}
+
+func TestInclude_MissingFile(t *testing.T) {
+	_, err := Include([]string{filepath.Join(t.TempDir(), "missing.go")}, 0)
+	if err == nil {
+		t.Fatal("Include() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "missing.go") {
+		t.Errorf("error = %q, want to mention the file name", err.Error())
+	}
+}
+
+// writeTree creates files under dir from a map of slash-separated paths to contents.
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	t.Helper()
+	for name, content := range tree {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":          "build/\n*.log\n",
+		"main.go":             "package main\n",
+		"src/a.go":            "package src\n",
+		"src/a_test.go":       "package src\n",
+		"src/deep/b.go":       "package deep\n",
+		"src/deep/notes.md":   "# notes\n",
+		"src/deep/.gitignore": "secret.go\n",
+		"src/deep/secret.go":  "package deep\n",
+		"src/image.png":       "\x89PNG\x00\x00",
+		"build/out.go":        "package build\n",
+		"debug.log":           "log\n",
+		".git/HEAD":           "ref: refs/heads/main\n",
+	})
+
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{
+			name:     "single file",
+			patterns: []string{"main.go"},
+			want:     []string{"main.go"},
+		},
+		{
+			name:     "recursive glob",
+			patterns: []string{"src/**/*.go"},
+			want:     []string{"src/a.go", "src/a_test.go", "src/deep/b.go"},
+		},
+		{
+			name:     "single-level glob",
+			patterns: []string{"src/*.go"},
+			want:     []string{"src/a.go", "src/a_test.go"},
+		},
+		{
+			name:     "directory skips ignored and binary files",
+			patterns: []string{"src"},
+			want:     []string{"src/a.go", "src/a_test.go", "src/deep/.gitignore", "src/deep/b.go", "src/deep/notes.md"},
+		},
+		{
+			name:     "root glob honors gitignore",
+			patterns: []string{"**/*.go"},
+			want:     []string{"main.go", "src/a.go", "src/a_test.go", "src/deep/b.go"},
+		},
+		{
+			name:     "explicit file bypasses gitignore",
+			patterns: []string{"debug.log"},
+			want:     []string{"debug.log"},
+		},
+		{
+			name:     "duplicates removed",
+			patterns: []string{"main.go", "*.go"},
+			want:     []string{"main.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.patterns)
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+			for i := range got {
+				got[i] = filepath.ToSlash(got[i])
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":   "package main\n",
+		"image.png": "\x89PNG\x00\x00",
+	})
+
+	t.Chdir(dir)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  string
+	}{
+		{name: "explicit binary file", patterns: []string{"image.png"}, wantErr: "appears to be binary"},
+		{name: "glob with no matches", patterns: []string{"*.rs"}, wantErr: "no files matched"},
+		{name: "missing file", patterns: []string{"nope.go"}, wantErr: "nope.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand(tt.patterns)
+			if err == nil {
+				t.Fatal("Expand() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInclude_MaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.txt": strings.Repeat("a", 600),
+		"b.txt": strings.Repeat("b", 600),
+	})
+
+	t.Chdir(dir)
+
+	if _, err := Include([]string{"*.txt"}, 2000); err != nil {
+		t.Errorf("Include() under limit error = %v", err)
+	}
+
+	_, err := Include([]string{"*.txt"}, 1000)
+	if err == nil {
+		t.Fatal("Include() over limit expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "1200 bytes") || !strings.Contains(err.Error(), "1000 byte limit") {
+		t.Errorf("error = %q, want sizes and limit", err.Error())
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "src/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"a/**/c.go", "a/c.go", true},
+		{"a/**/c.go", "a/b/d/c.go", true},
+		{"a/**", "a/b/c", true},
+		{"a/*/c.go", "a/b/d/c.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPath(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchPath(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single parsed .gitignore pattern.
+type ignoreRule struct {
+	base     string // slash-separated directory containing the .gitignore
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // pattern contains a slash and matches relative to base
+}
+
+// ignoreSet holds the .gitignore rules in effect for a directory walk.
+// Rules are evaluated in order; the last matching rule wins.
+type ignoreSet struct {
+	rules []ignoreRule
+}
+
+// load parses the .gitignore in dir, if any, and appends its rules.
+// A missing file is not an error.
+func (s *ignoreSet) load(dir string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	base := filepath.ToSlash(filepath.Clean(dir))
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(base, scanner.Text()); ok {
+			s.rules = append(s.rules, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseIgnoreRule parses one line of a .gitignore located in base.
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.pattern = line
+
+	return rule, true
+}
+
+// ignored reports whether the file or directory at name is ignored.
+func (s *ignoreSet) ignored(name string, isDir bool) bool {
+	name = filepath.ToSlash(filepath.Clean(name))
+
+	ignored := false
+	for _, rule := range s.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		rel, ok := relativeTo(rule.base, name)
+		if !ok {
+			continue
+		}
+
+		var matched bool
+		if rule.anchored {
+			matched = matchPath(rule.pattern, rel)
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(rel))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// relativeTo returns name relative to base if name is inside base.
+func relativeTo(base, name string) (string, bool) {
+	if base == "." {
+		if strings.HasPrefix(name, "../") || name == ".." || path.IsAbs(name) {
+			return "", false
+		}
+		return name, true
+	}
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(name, prefix), true
+}
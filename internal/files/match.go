@@ -0,0 +1,64 @@
+package files
+
+import (
+	"path"
+	"strings"
+)
+
+// hasMeta reports whether s contains any glob metacharacters.
+func hasMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// matchPath reports whether the slash-separated name matches pattern.
+// In addition to path.Match syntax, a "**" segment matches zero or more
+// path segments.
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive ** segments
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// splitGlob splits a slash-separated glob into the leading directory that
+// contains no metacharacters and the remaining pattern.
+func splitGlob(pattern string) (base, rest string) {
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		if hasMeta(seg) {
+			base = strings.Join(segments[:i], "/")
+			if base == "" && strings.HasPrefix(pattern, "/") {
+				base = "/"
+			}
+			return base, strings.Join(segments[i:], "/")
+		}
+	}
+	return pattern, ""
+}
@@ -1,32 +0,0 @@
-package history
-
-// migrate runs database migrations.
-func (s *Store) migrate() error {
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS conversations (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			title TEXT NOT NULL,
-			model TEXT NOT NULL,
-			provider TEXT NOT NULL,
-			created_at DATETIME NOT NULL
-		)`,
-		`CREATE TABLE IF NOT EXISTS messages (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			conversation_id INTEGER NOT NULL,
-			role TEXT NOT NULL,
-			content TEXT NOT NULL,
-			created_at DATETIME NOT NULL,
-			FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_conversations_created_at ON conversations(created_at)`,
-	}
-
-	for _, migration := range migrations {
-		if _, err := s.db.Exec(migration); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
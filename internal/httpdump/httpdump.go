@@ -0,0 +1,206 @@
+// Package httpdump traces HTTP traffic for debugging: the requests sent,
+// and the responses received line by line as they stream, with
+// credentials redacted.
+package httpdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Redacted replaces credentials in traced output.
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders lists request headers that carry credentials.
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"X-Api-Key":     true,
+}
+
+// IsSensitive reports whether the header name carries credentials.
+func IsSensitive(name string) bool {
+	return sensitiveHeaders[http.CanonicalHeaderKey(name)]
+}
+
+// RedactHeader hides a credential, keeping any auth scheme prefix (e.g.
+// "Bearer").
+func RedactHeader(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " " + Redacted
+	}
+	return Redacted
+}
+
+// Transport is an http.RoundTripper that writes each request and its
+// response to Out: the request line, headers, and body, prefixed with
+// "> ", then the status and headers, and the body's lines as they are
+// read, prefixed with "< ". Lines of concurrent requests are told apart
+// by a sequence number.
+type Transport struct {
+	// Base sends the requests; nil means http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Out receives the trace.
+	Out io.Writer
+
+	// Secrets, such as API keys, are replaced with Redacted wherever
+	// they appear, in addition to the values of sensitive headers.
+	Secrets []string
+
+	mu  sync.Mutex
+	seq atomic.Int64
+}
+
+// RoundTrip sends req with Base, tracing the exchange.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := t.seq.Add(1)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		t.dumpRequest(n, req, body)
+	} else {
+		t.dumpRequest(n, req, nil)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		t.write(n, "<", []string{"error: " + err.Error()})
+		return nil, err
+	}
+
+	lines := []string{fmt.Sprintf("%s %s (%s)", resp.Proto, resp.Status, time.Since(start).Round(time.Millisecond))}
+	lines = append(lines, t.headerLines(resp.Header)...)
+	t.write(n, "<", append(lines, ""))
+	resp.Body = &bodyTracer{ReadCloser: resp.Body, t: t, n: n}
+	return resp, nil
+}
+
+// dumpRequest writes req, with body, indented if it is JSON.
+func (t *Transport) dumpRequest(n int64, req *http.Request, body []byte) {
+	lines := []string{req.Method + " " + req.URL.String()}
+	lines = append(lines, t.headerLines(req.Header)...)
+	if len(body) > 0 {
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			body = pretty.Bytes()
+		}
+		lines = append(lines, "")
+		lines = append(lines, strings.Split(strings.TrimRight(string(body), "\n"), "\n")...)
+	}
+	t.write(n, ">", lines)
+}
+
+// headerLines returns the lines of h in sorted order, with credentials
+// redacted.
+func (t *Transport) headerLines(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var lines []string
+	for _, name := range names {
+		for _, value := range h.Values(name) {
+			if IsSensitive(name) {
+				value = RedactHeader(value)
+			}
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return lines
+}
+
+// write writes lines, each prefixed with the request number n and dir,
+// with secrets redacted. Lines are written together, so the lines of
+// concurrent requests do not interleave mid-line.
+func (t *Transport) write(n int64, dir string, lines []string) {
+	var b strings.Builder
+	for _, line := range lines {
+		for _, secret := range t.Secrets {
+			if secret != "" {
+				line = strings.ReplaceAll(line, secret, Redacted)
+			}
+		}
+		fmt.Fprintf(&b, "[%d] %s", n, dir)
+		if line != "" {
+			b.WriteString(" " + line)
+		}
+		b.WriteByte('\n')
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = io.WriteString(t.Out, b.String())
+}
+
+// bodyTracer traces a response body line by line as it is read.
+type bodyTracer struct {
+	io.ReadCloser
+	t       *Transport
+	n       int64
+	partial []byte
+	done    bool
+}
+
+func (b *bodyTracer) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.partial = append(b.partial, p[:n]...)
+	if i := bytes.LastIndexByte(b.partial, '\n'); i >= 0 {
+		lines := strings.Split(string(b.partial[:i]), "\n")
+		for j, line := range lines {
+			lines[j] = strings.TrimSuffix(line, "\r")
+		}
+		b.t.write(b.n, "<", lines)
+		b.partial = slices.Delete(b.partial, 0, i+1)
+	}
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *bodyTracer) Close() error {
+	b.finish(nil)
+	return b.ReadCloser.Close()
+}
+
+// finish writes what remains of the body once it ends with err, or is
+// closed.
+func (b *bodyTracer) finish(err error) {
+	if b.done {
+		return
+	}
+	b.done = true
+	var lines []string
+	if len(b.partial) > 0 {
+		lines = append(lines, string(b.partial))
+		b.partial = nil
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		lines = append(lines, "(end of body)")
+	case err != nil:
+		lines = append(lines, "(body error: "+err.Error()+")")
+	default:
+		lines = append(lines, "(body closed)")
+	}
+	b.t.write(b.n, "<", lines)
+}
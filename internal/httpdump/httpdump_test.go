@@ -0,0 +1,99 @@
+package httpdump
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactHeader(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Bearer sk-secret", "Bearer [REDACTED]"},
+		{"sk-ant-secret", "[REDACTED]"},
+	}
+	for _, tt := range tests {
+		if got := RedactHeader(tt.in); got != tt.want {
+			t.Errorf("RedactHeader(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	const key = "sk-secret-123"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"model":"gpt-4o","key":"`+key+`"}` {
+			t.Errorf("server got body %q; the trace must not change it", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"text\":\"hi\"}\r\n\r\ndata: [DONE]\n\ntrailing")
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	client := &http.Client{Transport: &Transport{Out: &out, Secrets: []string{key}}}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"model":"gpt-4o","key":"`+key+`"}`))
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("X-Api-Key", key)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasSuffix(string(body), "trailing") {
+		t.Errorf("response body = %q, want it passed through", body)
+	}
+
+	got := regexp.MustCompile(`\(\d+ms\)`).ReplaceAllString(out.String(), "(Nms)")
+	got = regexp.MustCompile(`(?m)^\[1\] < (Content-Length|Date): .*\n`).ReplaceAllString(got, "")
+	want := `[1] > POST ` + srv.URL + `
+[1] > Authorization: Bearer [REDACTED]
+[1] > X-Api-Key: [REDACTED]
+[1] >
+[1] > {
+[1] >   "model": "gpt-4o",
+[1] >   "key": "[REDACTED]"
+[1] > }
+[1] < HTTP/1.1 200 OK (Nms)
+[1] < Content-Type: text/event-stream
+[1] <
+[1] < data: {"text":"hi"}
+[1] <
+[1] < data: [DONE]
+[1] <
+[1] < trailing
+[1] < (end of body)
+`
+	if got != want {
+		t.Errorf("trace =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(out.String(), key) {
+		t.Error("trace contains the API key")
+	}
+}
+
+func TestTransport_Error(t *testing.T) {
+	var out bytes.Buffer
+	fail := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	client := &http.Client{Transport: &Transport{Base: fail, Out: &out}}
+	if _, err := client.Get("http://example.invalid/"); err == nil {
+		t.Fatal("Get() error = nil, want the transport's")
+	}
+	if !strings.Contains(out.String(), "[1] < error: connection refused") {
+		t.Errorf("trace = %q, want the error", out.String())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,175 @@
+// Package keyring stores secrets in the operating system's credential
+// store: the macOS Keychain, the Secret Service on Linux and BSD (GNOME
+// Keyring, KWallet), or the Windows Credential Manager.
+//
+// macOS and Secret Service access goes through the platform's
+// command-line tools (security and secret-tool); Windows is called
+// directly. Secrets are passed to the tools on stdin, never as arguments.
+package keyring
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Service is the name ask's secrets are stored under.
+const Service = "ask"
+
+var (
+	// ErrNotFound is returned when no secret is stored for the account.
+	ErrNotFound = errors.New("secret not found in keyring")
+
+	// ErrUnsupported is returned when the platform has no supported
+	// credential store.
+	ErrUnsupported = errors.New("no supported keyring on this system")
+)
+
+// Set stores secret for account, replacing any existing secret.
+func Set(account, secret string) error {
+	if runtime.GOOS == "windows" {
+		return windowsSet(target(account), account, secret)
+	}
+	c, err := setCommand(runtime.GOOS, account, secret)
+	if err != nil {
+		return err
+	}
+	_, err = c.run()
+	return err
+}
+
+// Get returns the secret stored for account, or ErrNotFound.
+func Get(account string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return windowsGet(target(account))
+	}
+	c, err := getCommand(runtime.GOOS, account)
+	if err != nil {
+		return "", err
+	}
+	out, err := c.run()
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimRight(out, "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, account)
+	}
+	return secret, nil
+}
+
+// Delete removes the secret stored for account, or returns ErrNotFound.
+func Delete(account string) error {
+	if runtime.GOOS == "windows" {
+		return windowsDelete(target(account))
+	}
+	c, err := deleteCommand(runtime.GOOS, account)
+	if err != nil {
+		return err
+	}
+	_, err = c.run()
+	return err
+}
+
+// target is the Windows credential name for account.
+func target(account string) string {
+	return Service + ":" + account
+}
+
+// command is a credential tool invocation.
+type command struct {
+	args  []string
+	stdin string
+
+	// notFound lists the exit codes that mean the secret does not exist
+	notFound []int
+}
+
+// Exit codes for a missing item: security reports errSecItemNotFound
+// as 44; secret-tool exits 1 without output.
+const (
+	securityNotFound   = 44
+	secretToolNotFound = 1
+)
+
+func setCommand(goos, account, secret string) (command, error) {
+	switch goos {
+	case "darwin":
+		// -i reads the command from stdin; -X takes the secret as hex
+		// so no quoting is needed
+		return command{
+			args:  []string{"security", "-i"},
+			stdin: fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", Service, quote(account), hex.EncodeToString([]byte(secret))),
+		}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return command{
+			args:  []string{"secret-tool", "store", "--label", Service + ": " + account, "service", Service, "account", account},
+			stdin: secret,
+		}, nil
+	}
+	return command{}, fmt.Errorf("%w (%s)", ErrUnsupported, goos)
+}
+
+func getCommand(goos, account string) (command, error) {
+	switch goos {
+	case "darwin":
+		return command{
+			args:     []string{"security", "find-generic-password", "-s", Service, "-a", account, "-w"},
+			notFound: []int{securityNotFound},
+		}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return command{
+			args:     []string{"secret-tool", "lookup", "service", Service, "account", account},
+			notFound: []int{secretToolNotFound},
+		}, nil
+	}
+	return command{}, fmt.Errorf("%w (%s)", ErrUnsupported, goos)
+}
+
+func deleteCommand(goos, account string) (command, error) {
+	switch goos {
+	case "darwin":
+		return command{
+			args:     []string{"security", "delete-generic-password", "-s", Service, "-a", account},
+			notFound: []int{securityNotFound},
+		}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		// secret-tool clear succeeds whether or not anything matched
+		return command{args: []string{"secret-tool", "clear", "service", Service, "account", account}}, nil
+	}
+	return command{}, fmt.Errorf("%w (%s)", ErrUnsupported, goos)
+}
+
+// run executes the command and returns its output.
+func (c command) run() (string, error) {
+	if _, err := exec.LookPath(c.args[0]); err != nil {
+		return "", fmt.Errorf("%w: %s is not installed", ErrUnsupported, c.args[0])
+	}
+	cmd := exec.Command(c.args[0], c.args[1:]...)
+	cmd.Stdin = strings.NewReader(c.stdin)
+	out, err := cmd.Output()
+	if err == nil {
+		return string(out), nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		for _, code := range c.notFound {
+			if exitErr.ExitCode() == code {
+				return "", ErrNotFound
+			}
+		}
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return "", fmt.Errorf("%s: %s", c.args[0], msg)
+		}
+	}
+	return "", fmt.Errorf("%s: %w", c.args[0], err)
+}
+
+// quote quotes s for the security tool's interactive mode.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package keyring
+
+// The Windows credential store is only reachable on Windows.
+
+func windowsSet(target, account, secret string) error { return ErrUnsupported }
+
+func windowsGet(target string) (string, error) { return "", ErrUnsupported }
+
+func windowsDelete(target string) error { return ErrUnsupported }
@@ -0,0 +1,59 @@
+package keyring
+
+import (
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		goos string
+		tool string
+	}{
+		{goos: "darwin", tool: "security"},
+		{goos: "linux", tool: "secret-tool"},
+		{goos: "freebsd", tool: "secret-tool"},
+		{goos: "plan9"},
+	}
+
+	for _, tt := range tests {
+		for name, build := range map[string]func() (command, error){
+			"set":    func() (command, error) { return setCommand(tt.goos, "openai", "sk-secret") },
+			"get":    func() (command, error) { return getCommand(tt.goos, "openai") },
+			"delete": func() (command, error) { return deleteCommand(tt.goos, "openai") },
+		} {
+			c, err := build()
+			if tt.tool == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Errorf("%s on %s: error = %v, want ErrUnsupported", name, tt.goos, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s on %s: error = %v", name, tt.goos, err)
+			}
+			if c.args[0] != tt.tool {
+				t.Errorf("%s on %s runs %q, want %q", name, tt.goos, c.args[0], tt.tool)
+			}
+			if slices.ContainsFunc(c.args, func(a string) bool { return strings.Contains(a, "sk-secret") }) {
+				t.Errorf("%s on %s passes the secret as an argument: %q", name, tt.goos, c.args)
+			}
+		}
+	}
+}
+
+func TestSetCommand_Stdin(t *testing.T) {
+	c, _ := setCommand("linux", "openai", "sk-secret")
+	if c.stdin != "sk-secret" {
+		t.Errorf("secret-tool stdin = %q, want the secret", c.stdin)
+	}
+
+	c, _ = setCommand("darwin", `we"ird`, "sk-secret")
+	want := `add-generic-password -U -s ask -a "we\"ird" -X ` + hex.EncodeToString([]byte("sk-secret")) + "\n"
+	if c.stdin != want {
+		t.Errorf("security stdin = %q, want %q", c.stdin, want)
+	}
+}
@@ -0,0 +1,82 @@
+// Package logging configures ask's structured diagnostic log, written
+// with log/slog throughout the code base. It is off unless a level or a
+// log file is set.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Levels lists the accepted level names.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// ParseLevel converts a level name to a slog level.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q (want %s)", s, strings.Join(Levels, ", "))
+}
+
+// Options select where diagnostics go.
+type Options struct {
+	// Level is the minimum level logged. Empty means everything when
+	// File is set, and no logging otherwise.
+	Level string
+
+	// File, if set, receives the log as JSON lines instead of stderr
+	// receiving it as text.
+	File string
+}
+
+// Setup installs the default slog logger described by opts. The returned
+// function closes the log file, if any.
+func Setup(opts Options, stderr io.Writer) (func() error, error) {
+	noop := func() error { return nil }
+
+	if opts.Level == "" && opts.File == "" {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return noop, nil
+	}
+
+	level := slog.LevelDebug
+	if opts.Level != "" {
+		var err error
+		if level, err = ParseLevel(opts.Level); err != nil {
+			return noop, err
+		}
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	if opts.File == "" {
+		slog.SetDefault(slog.New(slog.NewTextHandler(stderr, handlerOpts)))
+		return noop, nil
+	}
+
+	f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return noop, fmt.Errorf("opening log file: %w", err)
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(f, handlerOpts)))
+	return f.Close, nil
+}
+
+// Truncate shortens s for logging, so that large payloads such as
+// response bodies do not swamp the log.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + fmt.Sprintf("...(%d more bytes)", len(s)-n)
+}
@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"trace", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var stderr bytes.Buffer
+	if _, err := Setup(Options{}, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	slog.Error("hidden")
+	if stderr.Len() != 0 {
+		t.Errorf("logged %q with logging off", stderr.String())
+	}
+
+	if _, err := Setup(Options{Level: "warn"}, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("hidden")
+	slog.Warn("shown", "attempt", 2)
+	if out := stderr.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "msg=shown attempt=2") {
+		t.Errorf("stderr = %q", out)
+	}
+
+	if _, err := Setup(Options{Level: "loud"}, &stderr); err == nil {
+		t.Error("Setup() with an invalid level succeeded")
+	}
+}
+
+func TestSetup_File(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	path := filepath.Join(t.TempDir(), "debug.log")
+	var stderr bytes.Buffer
+	closeLog, err := Setup(Options{File: path}, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.Debug("request", "model", "gpt-4o")
+	if err := closeLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &entry); err != nil {
+		t.Fatalf("log file is not one JSON line: %q", data)
+	}
+	if entry["msg"] != "request" || entry["model"] != "gpt-4o" {
+		t.Errorf("entry = %v", entry)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("logged to stderr as well: %q", stderr.String())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short", 10); got != "short" {
+		t.Errorf("Truncate() = %q", got)
+	}
+	if got := Truncate("abcdefghij", 4); got != "abcd...(6 more bytes)" {
+		t.Errorf("Truncate() = %q", got)
+	}
+}
@@ -0,0 +1,236 @@
+// Package mcp implements a Model Context Protocol server over stdio,
+// exposing tools to MCP clients such as desktop agents.
+//
+// Messages are JSON-RPC 2.0, one per line. Only the tools capability is
+// supported.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// LatestProtocolVersion is the newest protocol revision the server speaks.
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedVersions lists the protocol revisions the server accepts.
+var supportedVersions = []string{"2024-11-05", "2025-03-26", LatestProtocolVersion}
+
+// maxMessageSize bounds a single incoming message.
+const maxMessageSize = 16 * 1024 * 1024
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool is a function exposed to clients.
+type Tool struct {
+	Name        string
+	Description string
+
+	// InputSchema is a JSON Schema object describing the arguments.
+	InputSchema map[string]any
+
+	// Call runs the tool with the arguments sent by the client. An
+	// error is reported to the client as a failed tool result.
+	Call func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// Server serves tools to one client.
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+
+	mu       sync.Mutex // guards out and inFlight
+	out      io.Writer
+	inFlight map[string]context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a server that identifies itself with name and version.
+func NewServer(name, version string, tools ...Tool) *Server {
+	return &Server{name: name, version: version, tools: tools, inFlight: make(map[string]context.CancelFunc)}
+}
+
+// request is an incoming JSON-RPC request or notification.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted or ctx is done. Tool calls run concurrently; Serve waits for
+// them before returning.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.out = w
+	defer s.wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxMessageSize)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(nil, nil, &rpcError{codeParseError, "parse error: " + err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			if req.ID != nil {
+				s.reply(req.ID, nil, &rpcError{codeInvalidRequest, "invalid request"})
+			}
+			continue
+		}
+		s.handle(ctx, req)
+	}
+	return scanner.Err()
+}
+
+// handle dispatches one message. Notifications (no ID) get no reply.
+func (s *Server) handle(ctx context.Context, req request) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := LatestProtocolVersion
+		if slices.Contains(supportedVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		s.reply(req.ID, map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": s.name, "version": s.version},
+		}, nil)
+	case "ping":
+		s.reply(req.ID, map[string]any{}, nil)
+	case "tools/list":
+		s.reply(req.ID, map[string]any{"tools": s.toolList()}, nil)
+	case "tools/call":
+		s.callTool(ctx, req)
+	case "notifications/cancelled":
+		var params struct {
+			RequestID json.RawMessage `json:"requestId"`
+		}
+		if json.Unmarshal(req.Params, &params) == nil {
+			s.mu.Lock()
+			if cancel, ok := s.inFlight[string(params.RequestID)]; ok {
+				cancel()
+			}
+			s.mu.Unlock()
+		}
+	default:
+		if req.ID != nil {
+			s.reply(req.ID, nil, &rpcError{codeMethodNotFound, "method not found: " + req.Method})
+		}
+	}
+}
+
+func (s *Server) toolList() []map[string]any {
+	list := make([]map[string]any, 0, len(s.tools))
+	for _, t := range s.tools {
+		list = append(list, map[string]any{
+			"name":        t.Name,
+			"description": t.Description,
+			"inputSchema": t.InputSchema,
+		})
+	}
+	return list
+}
+
+// callTool runs a tool in the background so slow tools do not hold up
+// other requests.
+func (s *Server) callTool(ctx context.Context, req request) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.reply(req.ID, nil, &rpcError{codeInvalidParams, "invalid params: " + err.Error()})
+		return
+	}
+	i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == params.Name })
+	if i < 0 {
+		s.reply(req.ID, nil, &rpcError{codeInvalidParams, "unknown tool: " + params.Name})
+		return
+	}
+	if len(params.Arguments) == 0 {
+		params.Arguments = json.RawMessage("{}")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	key := string(req.ID)
+	s.mu.Lock()
+	s.inFlight[key] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.inFlight, key)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		text, err := s.tools[i].Call(ctx, params.Arguments)
+		if ctx.Err() != nil && err != nil {
+			return // cancelled by the client, which expects no reply
+		}
+		isError := err != nil
+		if isError {
+			text = err.Error()
+		}
+		s.reply(req.ID, map[string]any{
+			"content": []map[string]string{{"type": "text", "text": text}},
+			"isError": isError,
+		}, nil)
+	}()
+}
+
+// reply writes a response as a single line.
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	data, err := json.Marshal(response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: id, Error: &rpcError{-32603, fmt.Sprintf("encoding result: %v", err)}})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.out.Write(append(data, '\n'))
+}
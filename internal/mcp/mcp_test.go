@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var echoTool = Tool{
+	Name:        "echo",
+	Description: "Echo the text argument",
+	InputSchema: map[string]any{"type": "object"},
+	Call: func(ctx context.Context, args json.RawMessage) (string, error) {
+		var a struct{ Text string }
+		if err := json.Unmarshal(args, &a); err != nil {
+			return "", err
+		}
+		if a.Text == "" {
+			return "", errors.New("text is required")
+		}
+		return a.Text, nil
+	},
+}
+
+// serve runs the server over input and returns responses keyed by ID.
+func serve(t *testing.T, input string) map[string]map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	s := NewServer("test", "1.0", echoTool)
+	if err := s.Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	responses := make(map[string]map[string]any)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("bad response line %q: %v", line, err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+	}
+	return responses
+}
+
+func TestServe_Initialize(t *testing.T) {
+	responses := serve(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}
+{"jsonrpc":"2.0","method":"notifications/initialized"}
+{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}
+{"jsonrpc":"2.0","id":3,"method":"ping"}
+`)
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3 (notifications get none): %v", len(responses), responses)
+	}
+
+	result := responses["1"]["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v, want the client's supported version", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Errorf("capabilities = %v, want tools", result["capabilities"])
+	}
+	if v := responses["2"]["result"].(map[string]any)["protocolVersion"]; v != LatestProtocolVersion {
+		t.Errorf("protocolVersion for unknown client version = %v, want %s", v, LatestProtocolVersion)
+	}
+}
+
+func TestServe_Tools(t *testing.T) {
+	responses := serve(t, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}
+{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}
+{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}
+{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"nope"}}
+{"jsonrpc":"2.0","id":"x","method":"resources/list"}
+not json
+`)
+
+	tools := responses["1"]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "echo" {
+		t.Errorf("tools/list = %v", tools)
+	}
+
+	check := func(id, text string, isError bool) {
+		t.Helper()
+		result := responses[id]["result"].(map[string]any)
+		content := result["content"].([]any)[0].(map[string]any)
+		if content["text"] != text || result["isError"] != isError {
+			t.Errorf("call %s = %v, want text %q isError %v", id, result, text, isError)
+		}
+	}
+	check("2", "hi", false)
+	check("3", "text is required", true)
+
+	errCode := func(id string) float64 {
+		e, _ := responses[id]["error"].(map[string]any)
+		code, _ := e["code"].(float64)
+		return code
+	}
+	if c := errCode("4"); c != codeInvalidParams {
+		t.Errorf("unknown tool error code = %v, want %d", c, codeInvalidParams)
+	}
+	if c := errCode(`"x"`); c != codeMethodNotFound {
+		t.Errorf("unknown method error code = %v, want %d", c, codeMethodNotFound)
+	}
+	if c := errCode("null"); c != codeParseError {
+		t.Errorf("parse error code = %v, want %d", c, codeParseError)
+	}
+}
@@ -0,0 +1,68 @@
+// Package notify sends desktop notifications using the platform's
+// native tooling.
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Send shows a desktop notification with the given title and body. It
+// uses notify-send on Linux and BSD, osascript on macOS, and a
+// PowerShell toast on Windows.
+func Send(title, body string) error {
+	name, args, err := command(runtime.GOOS, title, body)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found in PATH", name)
+	}
+	if out, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// command returns the program and arguments that show a notification
+// on goos.
+func command(goos, title, body string) (string, []string, error) {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s",
+			appleScriptString(body), appleScriptString(title))
+		return "osascript", []string{"-e", script}, nil
+	case "windows":
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", toastScript(title, body)}, nil
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		return "notify-send", []string{"--app-name=ask", title, body}, nil
+	default:
+		return "", nil, fmt.Errorf("desktop notifications are not supported on %s", goos)
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// powerShellString quotes s as a single-quoted PowerShell literal.
+func powerShellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// toastScript builds a PowerShell script that shows a Windows toast.
+func toastScript(title, body string) string {
+	return strings.Join([]string{
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null",
+		"$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+		"$x = $t.GetElementsByTagName('text')",
+		"$x.Item(0).AppendChild($t.CreateTextNode(" + powerShellString(title) + ")) > $null",
+		"$x.Item(1).AppendChild($t.CreateTextNode(" + powerShellString(body) + ")) > $null",
+		"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('ask').Show([Windows.UI.Notifications.ToastNotification]::new($t))",
+	}, "; ")
+}
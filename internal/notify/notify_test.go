@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			goos:     "linux",
+			wantName: "notify-send",
+			wantArgs: []string{"--app-name=ask", "ask", `say "hi"`},
+		},
+		{
+			goos:     "darwin",
+			wantName: "osascript",
+			wantArgs: []string{"-e", `display notification "say \"hi\"" with title "ask"`},
+		},
+		{
+			goos:    "plan9",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			name, args, err := command(tt.goos, "ask", `say "hi"`)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("command() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if !slices.Equal(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCommand_WindowsQuoting(t *testing.T) {
+	name, args, err := command("windows", "ask", "it's done")
+	if err != nil {
+		t.Fatalf("command() error = %v", err)
+	}
+	if name != "powershell" {
+		t.Errorf("name = %q, want powershell", name)
+	}
+	script := args[len(args)-1]
+	if !strings.Contains(script, "CreateTextNode('it''s done')") {
+		t.Errorf("script does not quote body correctly:\n%s", script)
+	}
+}
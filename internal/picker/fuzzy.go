@@ -0,0 +1,81 @@
+package picker
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring weights for fuzzy matching.
+const (
+	scoreMatch       = 1
+	scoreConsecutive = 5
+	scoreWordStart   = 8
+)
+
+// Score reports whether every rune of query appears in text in order,
+// ignoring case, and how good the match is. Consecutive runs and matches
+// at the start of words score higher. An empty query matches everything
+// with a score of zero.
+func Score(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+
+	score := 0
+	qi := 0
+	prevMatch := -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if unicode.IsSpace(q[qi]) {
+			// Spaces in the query only separate terms
+			qi++
+			ti--
+			continue
+		}
+		if t[ti] != q[qi] {
+			continue
+		}
+
+		score += scoreMatch
+		if ti == prevMatch+1 {
+			score += scoreConsecutive
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += scoreWordStart
+		}
+		prevMatch = ti
+		qi++
+	}
+
+	// Trailing spaces in the query are not required to match
+	for qi < len(q) && unicode.IsSpace(q[qi]) {
+		qi++
+	}
+
+	return score, qi == len(q)
+}
+
+// Filter returns the indexes of items matching query, best match first.
+// Items with equal scores keep their original order.
+func Filter(query string, items []string) []int {
+	type match struct {
+		index int
+		score int
+	}
+
+	var matches []match
+	for i, item := range items {
+		if score, ok := Score(query, item); ok {
+			matches = append(matches, match{index: i, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].score > matches[b].score
+	})
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches {
+		indexes[i] = m.index
+	}
+	return indexes
+}
@@ -0,0 +1,63 @@
+package picker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		match bool
+	}{
+		{"", "anything", true},
+		{"gor", "What is a goroutine?", true},
+		{"GOR", "what is a goroutine?", true},
+		{"wiag", "What is a goroutine?", true},
+		{"go rout", "What is a goroutine?", true},
+		{"xyz", "What is a goroutine?", false},
+		{"tuoroog", "goroutine", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := Score(tt.query, tt.text); ok != tt.match {
+			t.Errorf("Score(%q, %q) match = %v, want %v", tt.query, tt.text, ok, tt.match)
+		}
+	}
+}
+
+func TestScore_Ranking(t *testing.T) {
+	consecutive, _ := Score("go", "go channels")
+	scattered, _ := Score("go", "a big onion")
+	if consecutive <= scattered {
+		t.Errorf("consecutive score %d should beat scattered score %d", consecutive, scattered)
+	}
+
+	wordStart, _ := Score("c", "go channels")
+	midWord, _ := Score("c", "go ticks")
+	if wordStart <= midWord {
+		t.Errorf("word-start score %d should beat mid-word score %d", wordStart, midWord)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{
+		"rust lifetimes",
+		"go channels",
+		"a big onion",
+		"python decorators",
+	}
+
+	got := Filter("go", items)
+	want := []int{1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter(%q) = %v, want %v", "go", got, want)
+	}
+
+	got = Filter("", items)
+	want = []int{0, 1, 2, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter(empty) = %v, want %v", got, want)
+	}
+}
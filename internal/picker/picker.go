@@ -0,0 +1,189 @@
+// Package picker implements a minimal fuzzy-searchable list selector
+// for the terminal.
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user dismisses the picker.
+var ErrCancelled = errors.New("selection cancelled")
+
+// Terminal control sequences.
+const (
+	altScreenOn  = "\x1b[?1049h"
+	altScreenOff = "\x1b[?1049l"
+	clearScreen  = "\x1b[H\x1b[2J"
+	reverseOn    = "\x1b[7m"
+	reset        = "\x1b[0m"
+)
+
+// Key codes read in raw mode.
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+	keyCtrlH     = 8
+)
+
+// Pick shows items in a full-screen list on the terminal, filtered by
+// what the user types, and returns the index of the chosen item.
+// It returns ErrCancelled if the user presses Esc or Ctrl+C.
+func Pick(in, out *os.File, prompt string, items []string) (int, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	io.WriteString(out, altScreenOn)
+	defer io.WriteString(out, altScreenOff)
+
+	p := &picker{prompt: prompt, items: items}
+	p.refilter()
+
+	buf := make([]byte, 64)
+	for {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		p.render(out, width, height)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+
+		if index, done, err := p.handle(buf[:n], height); done {
+			return index, err
+		}
+	}
+}
+
+// picker holds the interactive state of a Pick call.
+type picker struct {
+	prompt   string
+	items    []string
+	query    []rune
+	matches  []int // indexes into items, best first
+	selected int   // index into matches
+	offset   int   // first visible match
+}
+
+// refilter recomputes matches for the current query and resets the selection.
+func (p *picker) refilter() {
+	p.matches = Filter(string(p.query), p.items)
+	p.selected = 0
+	p.offset = 0
+}
+
+// handle applies one read of key input. It returns done when the picker
+// should close, with the chosen item index or an error.
+func (p *picker) handle(keys []byte, height int) (index int, done bool, err error) {
+	switch {
+	case len(keys) == 1 && (keys[0] == keyCtrlC || keys[0] == keyEscape):
+		return 0, true, ErrCancelled
+	case len(keys) == 1 && keys[0] == keyCtrlD && len(p.query) == 0:
+		return 0, true, ErrCancelled
+	case len(keys) == 1 && keys[0] == keyEnter:
+		if len(p.matches) == 0 {
+			return 0, false, nil
+		}
+		return p.matches[p.selected], true, nil
+	case string(keys) == "\x1b[A" || string(keys) == "\x1bOA" ||
+		len(keys) == 1 && (keys[0] == keyCtrlP || keys[0] == keyCtrlK):
+		p.move(-1, height)
+	case string(keys) == "\x1b[B" || string(keys) == "\x1bOB" ||
+		len(keys) == 1 && (keys[0] == keyCtrlN || keys[0] == keyCtrlJ):
+		p.move(1, height)
+	case len(keys) == 1 && (keys[0] == keyBackspace || keys[0] == keyCtrlH):
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.refilter()
+		}
+	case len(keys) == 1 && keys[0] == keyCtrlU:
+		p.query = p.query[:0]
+		p.refilter()
+	case keys[0] >= ' ' && keys[0] != keyBackspace:
+		// Printable input, possibly several runes from a paste
+		for _, r := range string(keys) {
+			if r >= ' ' && r != keyBackspace {
+				p.query = append(p.query, r)
+			}
+		}
+		p.refilter()
+	}
+	return 0, false, nil
+}
+
+// move changes the selection by delta, scrolling to keep it visible.
+func (p *picker) move(delta, height int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.selected = max(0, min(len(p.matches)-1, p.selected+delta))
+
+	visible := max(1, height-2)
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+visible {
+		p.offset = p.selected - visible + 1
+	}
+}
+
+// render draws the prompt line, a status line, and the visible matches.
+func (p *picker) render(w io.Writer, width, height int) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+
+	fmt.Fprintf(&b, "%s%s\r\n", p.prompt, string(p.query))
+	fmt.Fprintf(&b, "  %d/%d  (↑/↓ move, enter select, esc cancel)\r\n", len(p.matches), len(p.items))
+
+	visible := max(1, height-2)
+	for i := p.offset; i < len(p.matches) && i < p.offset+visible; i++ {
+		line := truncate(p.items[p.matches[i]], width-2)
+		if i == p.selected {
+			fmt.Fprintf(&b, "%s> %s%s", reverseOn, line, reset)
+		} else {
+			fmt.Fprintf(&b, "  %s", line)
+		}
+		if i < p.offset+visible-1 {
+			b.WriteString("\r\n")
+		}
+	}
+
+	// Park the cursor at the end of the query
+	fmt.Fprintf(&b, "\x1b[1;%dH", len([]rune(p.prompt))+len(p.query)+1)
+
+	io.WriteString(w, b.String())
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
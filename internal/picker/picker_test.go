@@ -0,0 +1,87 @@
+package picker
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPicker_Handle(t *testing.T) {
+	items := []string{"alpha", "beta", "gamma", "delta"}
+
+	tests := []struct {
+		name      string
+		keys      []string
+		wantIndex int
+		wantErr   error
+	}{
+		{name: "enter selects first", keys: []string{"\r"}, wantIndex: 0},
+		{name: "down arrow", keys: []string{"\x1b[B", "\r"}, wantIndex: 1},
+		{name: "ctrl-n ctrl-n ctrl-p", keys: []string{"\x0e", "\x0e", "\x10", "\r"}, wantIndex: 1},
+		{name: "up clamps at top", keys: []string{"\x1b[A", "\r"}, wantIndex: 0},
+		{name: "down clamps at bottom", keys: []string{"\x1b[B", "\x1b[B", "\x1b[B", "\x1b[B", "\x1b[B", "\r"}, wantIndex: 3},
+		{name: "typing filters", keys: []string{"g", "m", "\r"}, wantIndex: 2},
+		{name: "pasted query", keys: []string{"del", "\r"}, wantIndex: 3},
+		{name: "backspace widens", keys: []string{"d", "e", "l", "\x7f", "\x7f", "\x7f", "\r"}, wantIndex: 0},
+		{name: "escape cancels", keys: []string{"\x1b"}, wantErr: ErrCancelled},
+		{name: "ctrl-c cancels", keys: []string{"b", "\x03"}, wantErr: ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &picker{prompt: "> ", items: items}
+			p.refilter()
+
+			for i, key := range tt.keys {
+				index, done, err := p.handle([]byte(key), 24)
+				if !done {
+					continue
+				}
+				if i != len(tt.keys)-1 {
+					t.Fatalf("picker closed early at key %d", i)
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if err == nil && index != tt.wantIndex {
+					t.Errorf("index = %d, want %d", index, tt.wantIndex)
+				}
+				return
+			}
+			t.Fatal("picker did not close")
+		})
+	}
+}
+
+func TestPicker_EnterWithNoMatches(t *testing.T) {
+	p := &picker{prompt: "> ", items: []string{"alpha"}}
+	p.refilter()
+
+	p.handle([]byte("zzz"), 24)
+	if _, done, _ := p.handle([]byte("\r"), 24); done {
+		t.Error("enter with no matches should not close the picker")
+	}
+}
+
+func TestPicker_RenderScrolls(t *testing.T) {
+	items := []string{"one", "two", "three", "four", "five"}
+	p := &picker{prompt: "> ", items: items}
+	p.refilter()
+
+	// Height 4 leaves room for two items below the prompt and status lines
+	for range 3 {
+		p.move(1, 4)
+	}
+
+	var buf bytes.Buffer
+	p.render(&buf, 80, 4)
+	out := buf.String()
+
+	if strings.Contains(out, "two") {
+		t.Errorf("render should have scrolled past %q: %q", "two", out)
+	}
+	if !strings.Contains(out, reverseOn+"> four") {
+		t.Errorf("render should highlight %q: %q", "four", out)
+	}
+}
@@ -0,0 +1,88 @@
+// Package plugin finds plugin commands: executables named ask-<name> on
+// PATH, which "ask <name>" runs like git and kubectl plugins.
+package plugin
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Prefix starts the file name of every plugin executable.
+const Prefix = "ask-"
+
+// Plugin is a plugin executable.
+type Plugin struct {
+	Name string // the subcommand, without Prefix
+	Path string
+}
+
+// validName matches the names that may be plugins, so that prompts and
+// paths are never looked up as executables.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidName reports whether name could name a plugin.
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Find returns the path of the plugin executable for name.
+func Find(name string) (string, error) {
+	if !ValidName(name) {
+		return "", exec.ErrNotFound
+	}
+	return exec.LookPath(Prefix + name)
+}
+
+// List returns the plugins in the directories of pathList, such as
+// $PATH, sorted by name. Like Find, an earlier directory wins.
+func List(pathList string) []Plugin {
+	var plugins []Plugin
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name())
+			if !ok || seen[name] || !executable(dir, e) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, e.Name())})
+		}
+	}
+	slices.SortFunc(plugins, func(a, b Plugin) int { return strings.Compare(a.Name, b.Name) })
+	return plugins
+}
+
+// pluginName returns the plugin name of an executable's file name.
+func pluginName(file string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		file = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+	name, ok := strings.CutPrefix(file, Prefix)
+	return name, ok && ValidName(name)
+}
+
+// executable reports whether the entry is a file that can be run.
+func executable(dir string, e fs.DirEntry) bool {
+	info, err := os.Stat(filepath.Join(dir, e.Name()))
+	if err != nil || info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"deploy", true},
+		{"jira-ticket", true},
+		{"v2_sync", true},
+		{"", false},
+		{"-x", false},
+		{"../bin/sh", false},
+		{"what is go?", false},
+	}
+	for _, tt := range tests {
+		if got := ValidName(tt.name); got != tt.want {
+			t.Errorf("ValidName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses Unix file modes")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	write := func(dir, name string, mode os.FileMode) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first, "ask-deploy", 0755)
+	write(first, "ask-notes.txt", 0644)
+	write(first, "other", 0755)
+	write(second, "ask-deploy", 0755)
+	write(second, "ask-audit", 0755)
+	if err := os.Mkdir(filepath.Join(second, "ask-dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := List(first + string(os.PathListSeparator) + second + string(os.PathListSeparator) + filepath.Join(first, "missing"))
+	want := []Plugin{
+		{Name: "audit", Path: filepath.Join(second, "ask-audit")},
+		{Name: "deploy", Path: filepath.Join(first, "ask-deploy")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("List() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("List()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package postprocess
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	heading    = regexp.MustCompile(`^ {0,3}#{1,6}\s+`)
+	rule       = regexp.MustCompile(`^ {0,3}(-( *-){2,}|\*( *\*){2,}|_( *_){2,}) *$`)
+	quote      = regexp.MustCompile(`^ {0,3}> ?`)
+	image      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	link       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	strong     = regexp.MustCompile(`(\*\*|__)([^*_\n]+?)(\*\*|__)`)
+	emphasis   = regexp.MustCompile(`(^|[\s(])[*_]([^*_\s][^*_\n]*?)[*_]`)
+	strike     = regexp.MustCompile(`~~([^~\n]+)~~`)
+	inlineCode = regexp.MustCompile("`([^`\n]+)`")
+)
+
+// StripMarkdown returns s as plain text: fences, heading markers,
+// emphasis, inline code marks, and rules are removed, and links become
+// their text followed by the URL in parentheses. Code inside fences is
+// kept verbatim.
+func StripMarkdown(s string) string {
+	var b strings.Builder
+	fence := ""
+	for _, line := range strings.SplitAfter(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+				continue
+			}
+			b.WriteString(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if rule.MatchString(strings.TrimRight(line, "\n")) {
+			continue
+		}
+
+		line = heading.ReplaceAllString(line, "")
+		line = quote.ReplaceAllString(line, "")
+		line = image.ReplaceAllString(line, "$1")
+		line = link.ReplaceAllStringFunc(line, func(m string) string {
+			parts := link.FindStringSubmatch(m)
+			if parts[1] == parts[2] {
+				return parts[2]
+			}
+			return parts[1] + " (" + parts[2] + ")"
+		})
+		line = inlineCode.ReplaceAllString(line, "$1")
+		line = strong.ReplaceAllString(line, "$2")
+		line = strike.ReplaceAllString(line, "$1")
+		line = emphasis.ReplaceAllString(line, "$1$2")
+		b.WriteString(line)
+	}
+	return b.String()
+}
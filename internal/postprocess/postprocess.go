@@ -0,0 +1,80 @@
+// Package postprocess transforms complete responses with a pipeline of
+// built-in filters and external commands.
+package postprocess
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/devaloi/ask/internal/codeblock"
+)
+
+// builtins are the filters a step can name instead of a command.
+var builtins = map[string]func(string) string{
+	"strip-markdown": StripMarkdown,
+	"code":           codeblock.Extract,
+	"trim":           func(s string) string { return strings.TrimSpace(s) + "\n" },
+}
+
+// Builtins returns the names of the built-in filters, sorted.
+func Builtins() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Pipeline runs a response through its steps in order, each step's output
+// being the next one's input.
+type Pipeline struct {
+	// Steps are built-in filter names or shell command lines.
+	Steps []string
+
+	// Shell runs command lines, with -c (or /C for cmd.exe).
+	Shell string
+
+	// Stderr receives the commands' standard error.
+	Stderr io.Writer
+}
+
+// Run returns s after every step. A command that fails stops the
+// pipeline.
+func (p Pipeline) Run(ctx context.Context, s string) (string, error) {
+	for _, step := range p.Steps {
+		if filter, ok := builtins[step]; ok {
+			s = filter(s)
+			continue
+		}
+		out, err := p.command(ctx, step, s)
+		if err != nil {
+			return "", fmt.Errorf("post-processing with %q: %w", step, err)
+		}
+		s = out
+	}
+	return s, nil
+}
+
+func (p Pipeline) command(ctx context.Context, line, input string) (string, error) {
+	flag := "-c"
+	if filepath.Base(p.Shell) == "cmd.exe" {
+		flag = "/C"
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Shell, flag, line)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = p.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
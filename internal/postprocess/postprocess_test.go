@@ -0,0 +1,57 @@
+package postprocess
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "## Install\nRun it.\n", "Install\nRun it.\n"},
+		{"emphasis", "This is **very** _important_ and *nice*.\n", "This is very important and nice.\n"},
+		{"inline code", "Call `Run()` first.\n", "Call Run() first.\n"},
+		{"link", "See [the docs](https://example.com).\n", "See the docs (https://example.com).\n"},
+		{"bare link", "[https://example.com](https://example.com)\n", "https://example.com\n"},
+		{"fence", "Code:\n```go\nx := a * b * c\n```\nDone.\n", "Code:\nx := a * b * c\nDone.\n"},
+		{"rule and quote", "Above\n---\n> quoted\n", "Above\nquoted\n"},
+		{"identifiers", "snake_case_name and 2 * 3 * 4\n", "snake_case_name and 2 * 3 * 4\n"},
+		{"lists", "- one\n- two\n", "- one\n- two\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripMarkdown(tt.in); got != tt.want {
+				t.Errorf("StripMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	p := Pipeline{
+		Steps:  []string{"code", "tr a-z A-Z", "trim"},
+		Shell:  "sh",
+		Stderr: io.Discard,
+	}
+	got, err := p.Run(context.Background(), "Here:\n```\nhello\n```\n")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got != "HELLO\n" {
+		t.Errorf("Run() = %q, want %q", got, "HELLO\n")
+	}
+
+	p.Steps = []string{"exit 3"}
+	if _, err := p.Run(context.Background(), "x"); err == nil || !strings.Contains(err.Error(), `"exit 3"`) {
+		t.Errorf("Run() with a failing command error = %v, want it to name the command", err)
+	}
+}
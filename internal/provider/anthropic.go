@@ -1,217 +0,0 @@
-package provider
-
-import (
-	"bytes"
-	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-
-	"github.com/devaloi/ask/internal/sse"
-	"github.com/devaloi/ask/internal/util"
-)
-
-const (
-	anthropicAPIURL     = "https://api.anthropic.com/v1/messages"
-	anthropicAPIVersion = "2023-06-01"
-	defaultMaxTokens    = 4096
-)
-
-// Anthropic implements the Provider interface for Anthropic's Claude API.
-type Anthropic struct {
-	apiKey string
-	client *http.Client
-}
-
-// NewAnthropic creates a new Anthropic provider with the given API key.
-func NewAnthropic(apiKey string) *Anthropic {
-	return &Anthropic{
-		apiKey: apiKey,
-		client: &http.Client{},
-	}
-}
-
-// Name returns the provider name.
-func (a *Anthropic) Name() string {
-	return "anthropic"
-}
-
-// Models returns the list of available Claude models.
-func (a *Anthropic) Models() []string {
-	return []string{
-		"claude-sonnet-4-20250514",
-		"claude-3-5-haiku-20241022",
-		"claude-3-opus-20240229",
-	}
-}
-
-// anthropicRequest is the request body for the Anthropic API.
-type anthropicRequest struct {
-	Model       string             `json:"model"`
-	Messages    []anthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
-	MaxTokens   int                `json:"max_tokens"`
-	Temperature float64            `json:"temperature,omitempty"`
-	Stream      bool               `json:"stream"`
-}
-
-// anthropicMessage represents a message in the Anthropic API format.
-type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// anthropicSSEEvent represents a parsed SSE event from the Anthropic API.
-type anthropicSSEEvent struct {
-	Type  string          `json:"type"`
-	Delta json.RawMessage `json:"delta,omitempty"`
-}
-
-// anthropicDelta represents the delta object in content_block_delta events.
-type anthropicDelta struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
-}
-
-// Chat sends a chat request to the Anthropic API and streams tokens to the channel.
-func (a *Anthropic) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
-	defer close(stream)
-
-	// Separate system messages from user/assistant messages
-	var systemPrompt string
-	var messages []anthropicMessage
-
-	for _, msg := range req.Messages {
-		if msg.Role == "system" {
-			// Anthropic expects system as a top-level field
-			if systemPrompt != "" {
-				systemPrompt += "\n\n"
-			}
-			systemPrompt += msg.Content
-		} else {
-			messages = append(messages, anthropicMessage(msg))
-		}
-	}
-
-	// Set max_tokens to default if not specified (required by Anthropic API)
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = defaultMaxTokens
-	}
-
-	// Build the request body
-	apiReq := anthropicRequest{
-		Model:     req.Model,
-		Messages:  messages,
-		System:    systemPrompt,
-		MaxTokens: maxTokens,
-		Stream:    true,
-	}
-
-	// Only include temperature if it's set (non-zero)
-	if req.Temperature > 0 {
-		apiReq.Temperature = req.Temperature
-	}
-
-	body, err := json.Marshal(apiReq)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Create the HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicAPIURL, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set required headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", a.apiKey)
-	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
-
-	// Send the request
-	resp, err := a.client.Do(httpReq)
-	if err != nil {
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Handle HTTP errors
-	if resp.StatusCode != http.StatusOK {
-		return a.handleHTTPError(resp)
-	}
-
-	// Parse SSE stream
-	return a.parseSSEStream(ctx, resp.Body, stream)
-}
-
-// handleHTTPError returns an appropriate error message based on the HTTP status code.
-func (a *Anthropic) handleHTTPError(resp *http.Response) error {
-	switch resp.StatusCode {
-	case http.StatusUnauthorized:
-		return fmt.Errorf("invalid API key: check your ANTHROPIC_API_KEY")
-	case http.StatusTooManyRequests:
-		return fmt.Errorf("rate limited: please wait and try again")
-	default:
-		if resp.StatusCode >= 500 {
-			return fmt.Errorf("Anthropic service error: please try again later")
-		}
-		// Read error body for other errors
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("Anthropic API error (status %d): failed to read response body: %w", resp.StatusCode, err)
-		}
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-}
-
-// parseSSEStream parses the SSE stream from the Anthropic API and sends tokens to the channel.
-func (a *Anthropic) parseSSEStream(ctx context.Context, body io.Reader, stream chan<- string) error {
-	reader := sse.NewReader(ctx, body)
-	events := make(chan sse.Event, util.DefaultChannelBuffer)
-
-	errCh := make(chan error, 1)
-	go func() {
-		errCh <- reader.Read(events)
-		close(events)
-	}()
-
-	for event := range events {
-		// Handle message_stop event
-		if event.Type == "message_stop" {
-			return nil
-		}
-
-		// Only process content_block_delta events
-		if event.Type != "content_block_delta" {
-			continue
-		}
-
-		var sseEvent anthropicSSEEvent
-		if err := json.Unmarshal([]byte(event.Data), &sseEvent); err != nil {
-			continue // Skip malformed JSON
-		}
-
-		// Extract text from delta
-		if sseEvent.Delta != nil {
-			var delta anthropicDelta
-			if err := json.Unmarshal(sseEvent.Delta, &delta); err != nil {
-				continue // Skip malformed delta
-			}
-
-			if delta.Text != "" {
-				select {
-				case stream <- delta.Text:
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-		}
-	}
-
-	return <-errCh
-}
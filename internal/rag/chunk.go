@@ -0,0 +1,80 @@
+// Package rag indexes local files as embedded chunks in SQLite and
+// retrieves the chunks most relevant to a prompt.
+package rag
+
+import "strings"
+
+// Chunking defaults. Chunks break at blank lines where they can, so
+// paragraphs and code blocks tend to stay whole.
+const (
+	DefaultChunkSize    = 1500 // characters
+	DefaultChunkOverlap = 2    // lines repeated at the start of the next chunk
+)
+
+// Chunk is a span of lines from a file.
+type Chunk struct {
+	Path      string
+	StartLine int // 1-based, inclusive
+	EndLine   int // 1-based, inclusive
+	Content   string
+}
+
+// Split breaks text into chunks of at most size characters (a single
+// longer line becomes its own chunk). Consecutive chunks share overlap
+// lines. Chunks that are only whitespace are dropped.
+func Split(path, text string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+
+	var chunks []Chunk
+	emit := func(start, end int) {
+		content := strings.Join(lines[start:end], "")
+		if strings.TrimSpace(content) == "" {
+			return
+		}
+		chunks = append(chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end, Content: content})
+	}
+
+	start, length := 0, 0
+	for i := 0; i < len(lines); i++ {
+		if length > 0 && length+len(lines[i]) > size {
+			// Back up to the last blank line in the second half of the
+			// chunk, if any
+			end := i
+			for j := i - 1; j > start && length > size/2; j-- {
+				if strings.TrimSpace(lines[j]) == "" {
+					end = j + 1
+					break
+				}
+				length -= len(lines[j])
+			}
+			emit(start, end)
+
+			next := max(end-overlap, start+1)
+			length = 0
+			for _, l := range lines[next:end] {
+				length += len(l)
+			}
+			if end < len(lines) && length+len(lines[end]) > size {
+				next, length = end, 0 // no room for overlap
+			}
+			start = next
+			i = end - 1
+			continue
+		}
+		length += len(lines[i])
+	}
+	if start < len(lines) {
+		emit(start, len(lines))
+	}
+	return chunks
+}
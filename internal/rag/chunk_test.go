@@ -0,0 +1,71 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+		want    [][2]int // start and end lines
+	}{
+		{"empty", "", 100, 0, nil},
+		{"blank only", "\n\n  \n", 100, 0, nil},
+		{"fits", "a\nb\nc\n", 100, 0, [][2]int{{1, 3}}},
+		{"no trailing newline", "a\nb", 100, 0, [][2]int{{1, 2}}},
+		{"hard split", "aaaa\nbbbb\ncccc\ndddd\n", 10, 0, [][2]int{{1, 2}, {3, 4}}},
+		{"overlap", "aaaa\nbbbb\ncccc\ndddd\n", 15, 1, [][2]int{{1, 3}, {3, 4}}},
+		{"breaks at blank line", "aaaa\nbbbb\n\ncccc\ndddd\n", 18, 0, [][2]int{{1, 3}, {4, 5}}},
+		{"ignores early blank line", "aaaa\n\nbbbb\ncccc\ndddd\n", 18, 0, [][2]int{{1, 4}, {5, 5}}},
+		{"long line", "aaaa\n" + strings.Repeat("x", 30) + "\nbbbb\n", 10, 1, [][2]int{{1, 1}, {2, 2}, {3, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split("f.md", tt.text, tt.size, tt.overlap)
+			var got [][2]int
+			for _, c := range chunks {
+				got = append(got, [2]int{c.StartLine, c.EndLine})
+				if c.Path != "f.md" {
+					t.Errorf("Path = %q", c.Path)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Split() spans = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Split() spans = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSplit_CoversText(t *testing.T) {
+	text := strings.Repeat("The quick brown fox.\nJumps over.\n\n", 200)
+	chunks := Split("f", text, 300, 2)
+
+	lines := strings.SplitAfter(text, "\n")
+	next := 1
+	for _, c := range chunks {
+		if c.StartLine > next {
+			t.Fatalf("gap before line %d", c.StartLine)
+		}
+		if len(c.Content) > 300 {
+			t.Errorf("chunk %d-%d is %d chars", c.StartLine, c.EndLine, len(c.Content))
+		}
+		if want := strings.Join(lines[c.StartLine-1:c.EndLine], ""); c.Content != want {
+			t.Fatalf("chunk %d-%d content does not match its lines", c.StartLine, c.EndLine)
+		}
+		next = c.EndLine + 1
+	}
+	if next != len(lines) {
+		t.Errorf("chunks end at line %d, want %d", next-1, len(lines)-1)
+	}
+}
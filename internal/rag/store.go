@@ -0,0 +1,285 @@
+package rag
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrNoIndex is returned when a named index does not exist.
+var ErrNoIndex = errors.New("no such index")
+
+// migrations is the ordered list of schema changes. The database's
+// user_version records how many have been applied, so new migrations must
+// only ever be appended.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS indexes (
+		name TEXT PRIMARY KEY,
+		model TEXT NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS files (
+		index_name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		hash TEXT NOT NULL,
+		PRIMARY KEY (index_name, path),
+		FOREIGN KEY (index_name) REFERENCES indexes(name) ON DELETE CASCADE
+	)`,
+	`CREATE TABLE IF NOT EXISTS chunks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		index_name TEXT NOT NULL,
+		path TEXT NOT NULL,
+		start_line INTEGER NOT NULL,
+		end_line INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		embedding BLOB NOT NULL,
+		FOREIGN KEY (index_name, path) REFERENCES files(index_name, path) ON DELETE CASCADE
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_chunks_file ON chunks(index_name, path)`,
+}
+
+// Index describes a named index.
+type Index struct {
+	Name      string
+	Model     string // embedding model; queries must use the same one
+	Files     int
+	Chunks    int
+	UpdatedAt time.Time
+}
+
+// Result is a chunk matched by a search.
+type Result struct {
+	Chunk
+	Score float64 // cosine similarity
+}
+
+// Store handles SQLite index storage.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens the index database at the given path, creating it and
+// running migrations if needed.
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	store := &Store{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return store, nil
+}
+
+// Close closes the database connection.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		if _, err := s.db.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := s.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureIndex creates the named index for the given embedding model. An
+// existing index built with a different model is cleared, since its
+// vectors cannot be compared with the new model's.
+func (s *Store) EnsureIndex(name, model string) error {
+	var existing string
+	err := s.db.QueryRow(`SELECT model FROM indexes WHERE name = ?`, name).Scan(&existing)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err = s.db.Exec(`INSERT INTO indexes (name, model, updated_at) VALUES (?, ?, ?)`, name, model, time.Now())
+		return err
+	case err != nil:
+		return err
+	case existing != model:
+		if err := s.DeleteIndex(name); err != nil {
+			return err
+		}
+		return s.EnsureIndex(name, model)
+	}
+	return nil
+}
+
+// GetIndex returns the named index, or ErrNoIndex.
+func (s *Store) GetIndex(name string) (*Index, error) {
+	idx := &Index{Name: name}
+	err := s.db.QueryRow(`
+		SELECT model, updated_at,
+			(SELECT COUNT(*) FROM files WHERE index_name = indexes.name),
+			(SELECT COUNT(*) FROM chunks WHERE index_name = indexes.name)
+		FROM indexes WHERE name = ?`, name).Scan(&idx.Model, &idx.UpdatedAt, &idx.Files, &idx.Chunks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNoIndex, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// ListIndexes returns all indexes sorted by name.
+func (s *Store) ListIndexes() ([]Index, error) {
+	rows, err := s.db.Query(`SELECT name FROM indexes ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	indexes := make([]Index, 0, len(names))
+	for _, name := range names {
+		idx, err := s.GetIndex(name)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, *idx)
+	}
+	return indexes, nil
+}
+
+// DeleteIndex removes the named index and everything in it.
+func (s *Store) DeleteIndex(name string) error {
+	res, err := s.db.Exec(`DELETE FROM indexes WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrNoIndex, name)
+	}
+	return nil
+}
+
+// FileHashes returns the content hash of each file in the index, keyed by
+// path, so callers can skip files that have not changed.
+func (s *Store) FileHashes(name string) (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT path, hash FROM files WHERE index_name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, err
+		}
+		hashes[path] = hash
+	}
+	return hashes, rows.Err()
+}
+
+// PutFile replaces a file's chunks in the index. vectors holds one
+// embedding per chunk.
+func (s *Store) PutFile(name, path, hash string, chunks []Chunk, vectors [][]float32) error {
+	if len(chunks) != len(vectors) {
+		return fmt.Errorf("got %d embeddings for %d chunks", len(vectors), len(chunks))
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM files WHERE index_name = ? AND path = ?`, name, path); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO files (index_name, path, hash) VALUES (?, ?, ?)`, name, path, hash); err != nil {
+		return err
+	}
+	for i, c := range chunks {
+		_, err := tx.Exec(`INSERT INTO chunks (index_name, path, start_line, end_line, content, embedding) VALUES (?, ?, ?, ?, ?, ?)`,
+			name, path, c.StartLine, c.EndLine, c.Content, encodeVector(vectors[i]))
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE indexes SET updated_at = ? WHERE name = ?`, time.Now(), name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Debug("indexed file", "index", name, "path", path, "chunks", len(chunks))
+	return nil
+}
+
+// RemoveFile removes a file and its chunks from the index.
+func (s *Store) RemoveFile(name, path string) error {
+	_, err := s.db.Exec(`DELETE FROM files WHERE index_name = ? AND path = ?`, name, path)
+	return err
+}
+
+// Search returns the k chunks most similar to query, best first.
+func (s *Store) Search(name string, query []float32, k int) ([]Result, error) {
+	rows, err := s.db.Query(`SELECT path, start_line, end_line, content, embedding FROM chunks WHERE index_name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		var r Result
+		var blob []byte
+		if err := rows.Scan(&r.Path, &r.StartLine, &r.EndLine, &r.Content, &blob); err != nil {
+			return nil, err
+		}
+		v, err := decodeVector(blob)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", r.Path, r.StartLine, err)
+		}
+		r.Score = cosine(query, v)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(results, func(a, b Result) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	if len(results) > k {
+		results = results[:max(k, 0)]
+	}
+	return results, nil
+}
@@ -0,0 +1,125 @@
+package rag
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore_Search(t *testing.T) {
+	s := newTestStore(t)
+	if err := s.EnsureIndex("docs", "m1"); err != nil {
+		t.Fatal(err)
+	}
+
+	a := []Chunk{{Path: "a.md", StartLine: 1, EndLine: 2, Content: "alpha"}, {Path: "a.md", StartLine: 3, EndLine: 4, Content: "beta"}}
+	if err := s.PutFile("docs", "a.md", "h1", a, [][]float32{{1, 0}, {0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	b := []Chunk{{Path: "b.md", StartLine: 1, EndLine: 1, Content: "gamma"}}
+	if err := s.PutFile("docs", "b.md", "h2", b, [][]float32{{1, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Search("docs", []float32{1, 0.1}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Content != "alpha" || results[1].Content != "gamma" {
+		t.Errorf("Search() = %+v, want alpha then gamma", results)
+	}
+
+	// Re-putting a file replaces its chunks
+	if err := s.PutFile("docs", "a.md", "h3", a[:1], [][]float32{{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveFile("docs", "b.md"); err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := s.FileHashes("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes["a.md"] != "h3" {
+		t.Errorf("FileHashes() = %v", hashes)
+	}
+	idx, err := s.GetIndex("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Files != 1 || idx.Chunks != 1 || idx.Model != "m1" {
+		t.Errorf("GetIndex() = %+v", idx)
+	}
+
+	if err := s.PutFile("docs", "c.md", "h", b, nil); err == nil {
+		t.Error("PutFile() with missing embeddings succeeded")
+	}
+}
+
+func TestStore_Indexes(t *testing.T) {
+	s := newTestStore(t)
+	for _, name := range []string{"b", "a"} {
+		if err := s.EnsureIndex(name, "m1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.PutFile("a", "x", "h", []Chunk{{Path: "x", StartLine: 1, EndLine: 1, Content: "x"}}, [][]float32{{1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching models clears the index
+	if err := s.EnsureIndex("a", "m2"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.ListIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "a" || list[0].Model != "m2" || list[0].Chunks != 0 {
+		t.Errorf("ListIndexes() = %+v", list)
+	}
+
+	if err := s.DeleteIndex("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetIndex("b"); !errors.Is(err, ErrNoIndex) {
+		t.Errorf("GetIndex() after delete error = %v, want ErrNoIndex", err)
+	}
+	if err := s.DeleteIndex("b"); !errors.Is(err, ErrNoIndex) {
+		t.Errorf("DeleteIndex() twice error = %v, want ErrNoIndex", err)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{2, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 0}, []float32{-1, 0}, -1},
+		{[]float32{1, 0}, []float32{1}, 0},
+		{[]float32{0, 0}, []float32{1, 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := cosine(tt.a, tt.b); got != tt.want {
+			t.Errorf("cosine(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	v := []float32{0.5, -1.25, 3}
+	got, err := decodeVector(encodeVector(v))
+	if err != nil || len(got) != 3 || got[0] != v[0] || got[1] != v[1] || got[2] != v[2] {
+		t.Errorf("vector round trip = %v, %v", got, err)
+	}
+}
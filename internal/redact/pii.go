@@ -0,0 +1,197 @@
+package redact
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// piiDetector finds one kind of personal data. valid, if set, rejects
+// matches that only look like it.
+type piiDetector struct {
+	kind    string // placeholder prefix, e.g. "EMAIL"
+	pattern *regexp.Regexp
+	valid   func(string) bool
+}
+
+var piiDetectors = []piiDetector{
+	{kind: "EMAIL", pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{kind: "IP", pattern: regexp.MustCompile(`(?:\d{1,3}\.){3}\d{1,3}`), valid: isIP},
+	{kind: "IP", pattern: regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`), valid: isIP},
+	{kind: "PHONE", pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?|\d{1,4}[ .-])(?:\d{2,4}[ .-]){1,3}\d{2,4}`), valid: isPhone},
+}
+
+func isIP(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && !addr.IsUnspecified()
+}
+
+// isPhone reports whether s has as many digits as a phone number: 10 to
+// 15, or from 8 with a country code.
+func isPhone(s string) bool {
+	digits := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits >= 10 && digits <= 15 || strings.HasPrefix(s, "+") && digits >= 8 && digits <= 15
+}
+
+// placeholder matches the placeholders a Scrubber puts in text.
+var placeholder = regexp.MustCompile(`\[(?:EMAIL|PHONE|IP)_\d+\]`)
+
+// maxPlaceholder is longer than any placeholder a Scrubber makes.
+const maxPlaceholder = 24
+
+// Scrubber replaces personal data, such as email addresses, phone
+// numbers, and IP addresses, with placeholders such as [EMAIL_1], and
+// puts it back in text that uses the placeholders, such as a model's
+// response. The same value always gets the same placeholder. A Scrubber
+// is safe for concurrent use.
+type Scrubber struct {
+	mu      sync.Mutex
+	byValue map[string]string // value -> placeholder
+	values  map[string]string // placeholder -> value
+	counts  map[string]int    // placeholders made, by kind
+}
+
+// NewScrubber returns a Scrubber with no placeholders yet.
+func NewScrubber() *Scrubber {
+	return &Scrubber{
+		byValue: make(map[string]string),
+		values:  make(map[string]string),
+		counts:  make(map[string]int),
+	}
+}
+
+// Scrub returns text with its personal data replaced by placeholders.
+func (s *Scrubber) Scrub(text string) string {
+	type match struct {
+		kind       string
+		start, end int
+	}
+	var found []match
+	for _, d := range piiDetectors {
+		for _, m := range d.pattern.FindAllStringIndex(text, -1) {
+			start, end := m[0], m[1]
+			value := text[start:end]
+			if !standsAlone(text, start, end) || d.valid != nil && !d.valid(value) {
+				continue
+			}
+			if slices.ContainsFunc(found, func(f match) bool { return start < f.end && f.start < end }) {
+				continue
+			}
+			found = append(found, match{d.kind, start, end})
+		}
+	}
+	if len(found) == 0 {
+		return text
+	}
+	slices.SortFunc(found, func(a, b match) int { return a.start - b.start })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b strings.Builder
+	last := 0
+	for _, f := range found {
+		b.WriteString(text[last:f.start])
+		b.WriteString(s.placeholder(f.kind, text[f.start:f.end]))
+		last = f.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// standsAlone reports whether text[start:end] is not part of a longer
+// word or number, such as a version string or a C++ scope.
+func standsAlone(text string, start, end int) bool {
+	isPart := func(r rune) bool {
+		return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isPart(r) {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isPart(r) {
+		// A sentence may end right after the value
+		if r != '.' || end+1 < len(text) && !unicode.IsSpace(rune(text[end+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// placeholder returns value's placeholder, making one if needed. s.mu
+// must be held.
+func (s *Scrubber) placeholder(kind, value string) string {
+	if p, ok := s.byValue[value]; ok {
+		return p
+	}
+	s.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", kind, s.counts[kind])
+	s.byValue[value] = p
+	s.values[p] = value
+	return p
+}
+
+// Len returns the number of values s has replaced.
+func (s *Scrubber) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.values)
+}
+
+// Restore returns text with s's placeholders replaced by their values.
+// Placeholders s did not make are left alone.
+func (s *Scrubber) Restore(text string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return placeholder.ReplaceAllStringFunc(text, func(p string) string {
+		if v, ok := s.values[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// RestoreStream passes tokens from in to out with s's placeholders
+// replaced by their values, holding back the start of a placeholder
+// split across tokens until it is complete. It closes out when in is
+// closed.
+func (s *Scrubber) RestoreStream(in <-chan string, out chan<- string) {
+	defer close(out)
+	var pending string
+	for token := range in {
+		pending += token
+		held := ""
+		if i := strings.LastIndexByte(pending, '['); i >= 0 && mayBePlaceholder(pending[i:]) {
+			pending, held = pending[:i], pending[i:]
+		}
+		if pending != "" {
+			out <- s.Restore(pending)
+		}
+		pending = held
+	}
+	if pending != "" {
+		out <- s.Restore(pending)
+	}
+}
+
+// mayBePlaceholder reports whether text could be the start of a
+// placeholder still being streamed.
+func mayBePlaceholder(text string) bool {
+	if len(text) >= maxPlaceholder || strings.Contains(text, "]") {
+		return false
+	}
+	for _, r := range text[1:] {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
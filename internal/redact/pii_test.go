@@ -0,0 +1,73 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Email alice@example.com and bob.smith+ask@mail.example.co.uk.", "Email [EMAIL_1] and [EMAIL_2]."},
+		{"Connection from 192.168.1.20:5432 refused", "Connection from [IP_1]:5432 refused"},
+		{"listen on 2001:db8::8a2e:370:7334 and ::1", "listen on [IP_1] and [IP_2]"},
+		{"Call +1 (555) 123-4567 or 555.123.4567", "Call [PHONE_1] or [PHONE_2]"},
+		{"London: +44 20 7946 0958", "London: [PHONE_1]"},
+		{"alice@example.com wrote to alice@example.com", "[EMAIL_1] wrote to [EMAIL_1]"},
+		{"Released 2024-01-15 as v1.22.3.4", "Released 2024-01-15 as v1.22.3.4"},
+		{"std::vector<int> and 12:30:45", "std::vector<int> and 12:30:45"},
+		{"version 1.2.3.4.5, port 999.1.1.1", "version 1.2.3.4.5, port 999.1.1.1"},
+		{"order 12345", "order 12345"},
+	}
+	for _, tt := range tests {
+		if got := NewScrubber().Scrub(tt.text); got != tt.want {
+			t.Errorf("Scrub(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := NewScrubber()
+	scrubbed := s.Scrub("Write to alice@example.com from 10.0.0.1")
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+	// Values keep their placeholders across calls
+	if got := s.Scrub("cc alice@example.com"); got != "cc [EMAIL_1]" {
+		t.Errorf("Scrub() = %q", got)
+	}
+
+	response := "Sent to [EMAIL_1] via [IP_1]; [EMAIL_9] and [link] are left alone."
+	want := "Sent to alice@example.com via 10.0.0.1; [EMAIL_9] and [link] are left alone."
+	if got := s.Restore(response); got != want {
+		t.Errorf("Restore() = %q, want %q", got, want)
+	}
+	if got := s.Restore(scrubbed); got != "Write to alice@example.com from 10.0.0.1" {
+		t.Errorf("Restore(Scrub()) = %q", got)
+	}
+}
+
+func TestRestoreStream(t *testing.T) {
+	s := NewScrubber()
+	s.Scrub("alice@example.com 10.0.0.1")
+
+	in := make(chan string)
+	out := make(chan string)
+	go s.RestoreStream(in, out)
+	go func() {
+		for _, token := range []string{"Hi [", "EMA", "IL_1", "], see [IP_", "1] and a[", "0] index [E"} {
+			in <- token
+		}
+		close(in)
+	}()
+	var b strings.Builder
+	for token := range out {
+		b.WriteString(token)
+	}
+	want := "Hi alice@example.com, see 10.0.0.1 and a[0] index [E"
+	if b.String() != want {
+		t.Errorf("RestoreStream() = %q, want %q", b.String(), want)
+	}
+}
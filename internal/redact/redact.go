@@ -0,0 +1,142 @@
+// Package redact finds secrets, such as API keys, cloud credentials,
+// tokens, and private keys, in text about to be sent to a model, so they
+// can be masked before they leave the machine.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Finding is a secret found in text.
+type Finding struct {
+	Kind       string // e.g. "AWS access key"
+	Start, End int    // byte offsets of the secret
+	Line       int    // 1-based line of Start
+}
+
+// detector finds one kind of secret. If the pattern has a group, only
+// the group is the secret, e.g. the value of an assignment.
+type detector struct {
+	kind    string
+	pattern *regexp.Regexp
+
+	// random, if set, requires the secret to look random, so that
+	// passwords such as "changeme" and references such as ${TOKEN} are
+	// left alone.
+	random bool
+}
+
+// Detectors are tried in order; a later match overlapping an earlier one
+// is dropped, so more specific patterns come first.
+var detectors = []detector{
+	{kind: "private key", pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY[A-Z ]*-----(?s:.*?)(?:-----END [A-Z ]*PRIVATE KEY[A-Z ]*-----|\z)`)},
+	{kind: "AWS access key", pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`)},
+	{kind: "AWS secret key", pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`)},
+	{kind: "JWT", pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{kind: "Anthropic API key", pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{kind: "OpenAI API key", pattern: regexp.MustCompile(`\bsk-(?:proj-|svcacct-|admin-)?[A-Za-z0-9_-]{20,}`)},
+	{kind: "GitHub token", pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{kind: "Slack token", pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{kind: "Google API key", pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{kind: "Stripe key", pattern: regexp.MustCompile(`\b[rs]k_(?:live|test)_[0-9A-Za-z]{16,}\b`)},
+	{
+		kind:    "secret",
+		pattern: regexp.MustCompile(`(?i)[\w.-]*(?:secret|token|passw(?:or)?d|pwd|api_?key|access_?key|private_?key|credentials?|auth)[\w.-]*["']?\s*[:=]\s*["']?([^\s"'` + "`" + `,;]{12,})`),
+		random:  true,
+	},
+}
+
+// minEntropy is the Shannon entropy, in bits per character, above which
+// a value assigned to a secret-sounding name is taken to be random.
+// English words and identifiers score lower; keys and tokens higher.
+const minEntropy = 3.5
+
+// Find returns the secrets in text, in order.
+func Find(text string) []Finding {
+	var found []Finding
+	for _, d := range detectors {
+		for _, m := range d.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if len(m) > 2 {
+				start, end = m[2], m[3]
+			}
+			if d.random && !looksRandom(text[start:end]) {
+				continue
+			}
+			if overlaps(found, start, end) {
+				continue
+			}
+			found = append(found, Finding{Kind: d.kind, Start: start, End: end})
+		}
+	}
+	slices.SortFunc(found, func(a, b Finding) int { return a.Start - b.Start })
+	for i := range found {
+		found[i].Line = strings.Count(text[:found[i].Start], "\n") + 1
+	}
+	return found
+}
+
+func overlaps(found []Finding, start, end int) bool {
+	for _, f := range found {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// looksRandom reports whether value looks like a generated secret rather
+// than a word, a placeholder, or a reference to one.
+func looksRandom(value string) bool {
+	if strings.HasPrefix(value, "$") || strings.ContainsAny(value, "(){}<>") {
+		return false
+	}
+	return Entropy(value) >= minEntropy
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Mask returns text with each of found, as returned by Find for text,
+// replaced by a placeholder naming its kind.
+func Mask(text string, found []Finding) string {
+	var b strings.Builder
+	last := 0
+	for _, f := range found {
+		b.WriteString(text[last:f.Start])
+		fmt.Fprintf(&b, "[REDACTED %s]", f.Kind)
+		last = f.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Describe summarizes found for a message, e.g.
+// "AWS access key (line 3), JWT (line 10)".
+func Describe(found []Finding) string {
+	parts := make([]string, len(found))
+	for i, f := range found {
+		parts[i] = fmt.Sprintf("%s (line %d)", f.Kind, f.Line)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,68 @@
+// Package review parses structured code review output from a model.
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severities, from most to least serious.
+const (
+	SeverityError      = "error"
+	SeverityWarning    = "warning"
+	SeveritySuggestion = "suggestion"
+)
+
+// Finding is a single review comment.
+type Finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Report is a complete review.
+type Report struct {
+	Summary  string    `json:"summary"`
+	Findings []Finding `json:"findings"`
+}
+
+// Parse extracts a Report from a model response. Code fences and text
+// around the JSON object are ignored. Findings are sorted by file and
+// line, and unknown severities become warnings.
+func Parse(response string) (*Report, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("review response contains no JSON object")
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(response[start:end+1]), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse review JSON: %w", err)
+	}
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+
+	for i := range report.Findings {
+		f := &report.Findings[i]
+		switch s := strings.ToLower(strings.TrimSpace(f.Severity)); s {
+		case SeverityError, SeverityWarning, SeveritySuggestion:
+			f.Severity = s
+		default:
+			f.Severity = SeverityWarning
+		}
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	return &report, nil
+}
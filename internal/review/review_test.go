@@ -0,0 +1,54 @@
+package review
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	response := "Here is the review:\n```json\n" + `{
+  "summary": "Mostly fine.",
+  "findings": [
+    {"file": "b.go", "line": 3, "severity": "ERROR", "message": "nil dereference"},
+    {"file": "a.go", "line": 20, "severity": "nit", "message": "naming"},
+    {"file": "a.go", "line": 4, "severity": "suggestion", "message": "extract helper"}
+  ]
+}` + "\n```\n"
+
+	report, err := Parse(response)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if report.Summary != "Mostly fine." {
+		t.Errorf("Summary = %q", report.Summary)
+	}
+
+	want := []Finding{
+		{File: "a.go", Line: 4, Severity: SeveritySuggestion, Message: "extract helper"},
+		{File: "a.go", Line: 20, Severity: SeverityWarning, Message: "naming"},
+		{File: "b.go", Line: 3, Severity: SeverityError, Message: "nil dereference"},
+	}
+	if len(report.Findings) != len(want) {
+		t.Fatalf("got %d findings, want %d", len(report.Findings), len(want))
+	}
+	for i, f := range report.Findings {
+		if f != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, f, want[i])
+		}
+	}
+}
+
+func TestParse_NoFindings(t *testing.T) {
+	report, err := Parse(`{"summary": "Looks good."}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if report.Findings == nil || len(report.Findings) != 0 {
+		t.Errorf("Findings = %#v, want empty non-nil slice", report.Findings)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, response := range []string{"", "no json here", "{not json}"} {
+		if _, err := Parse(response); err == nil {
+			t.Errorf("Parse(%q) expected error", response)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package routing picks the model for a request by simple rules, such as
+// sending short questions to a small model and long code to a large one.
+package routing
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// Rule sends requests matching all of its conditions to Model. Unset
+// conditions match anything, but no rule matches a request too large for
+// its model's known context window.
+type Rule struct {
+	// Task matches requests given this --task hint.
+	Task string `yaml:"task,omitempty"`
+
+	// MinTokens and MaxTokens bound the request's estimated size.
+	MinTokens int `yaml:"min_tokens,omitempty"`
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+
+	// Code matches requests that do, or do not, contain code.
+	Code *bool `yaml:"code,omitempty"`
+
+	// MaxCost, in US dollars, matches requests whose input would cost at
+	// most this much with Model. Models without a known price never
+	// match.
+	MaxCost float64 `yaml:"max_cost,omitempty"`
+
+	Model    string `yaml:"model"`
+	Provider string `yaml:"provider,omitempty"` // default: the configured provider
+}
+
+// Request describes a request to route.
+type Request struct {
+	Tokens int // estimated input tokens
+	Code   bool
+	Task   string
+}
+
+// Match returns the index of the first of rules that req matches, or
+// false if none does. Rules without a model are skipped.
+func Match(rules []Rule, req Request) (int, bool) {
+	for i, r := range rules {
+		if r.Model != "" && r.matches(req) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (r Rule) matches(req Request) bool {
+	if r.Task != "" && !strings.EqualFold(r.Task, req.Task) {
+		return false
+	}
+	if r.MinTokens > 0 && req.Tokens < r.MinTokens {
+		return false
+	}
+	if r.MaxTokens > 0 && req.Tokens > r.MaxTokens {
+		return false
+	}
+	if r.Code != nil && *r.Code != req.Code {
+		return false
+	}
+	if window := provider.ContextWindow(r.Model); window > 0 && req.Tokens > window {
+		return false
+	}
+	if r.MaxCost > 0 {
+		cost, ok := provider.Cost(r.Model, req.Tokens, 0)
+		if !ok || cost > r.MaxCost {
+			return false
+		}
+	}
+	return true
+}
+
+// codeLine matches lines that look like source code: declarations,
+// imports, and statements ending in braces or semicolons.
+var codeLine = regexp.MustCompile(`(?m)^\s*(func|def|class|import|package|from \S+ import|#include|public|private|fn|let|const|var|return|if \(|for \(|SELECT|INSERT|CREATE TABLE)\b.*$|[{};]\s*$`)
+
+// minCodeLines is how many code-like lines make text count as code
+// without a fenced block.
+const minCodeLines = 2
+
+// HasCode reports whether text contains code: a fenced block, or several
+// lines that look like source code.
+func HasCode(text string) bool {
+	if strings.Contains(text, "```") {
+		return true
+	}
+	return len(codeLine.FindAllStringIndex(text, minCodeLines)) >= minCodeLines
+}
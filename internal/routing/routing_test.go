@@ -0,0 +1,65 @@
+package routing
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	yes, no := true, false
+	rules := []Rule{
+		{Task: "review", Model: "claude-sonnet-4-20250514", Provider: "anthropic"},
+		{Code: &yes, MinTokens: 2000, Model: "claude-sonnet-4-20250514"},
+		{MaxTokens: 200, Code: &no, Model: "gpt-4o-mini"},
+		{MaxCost: 0.01, Model: "gpt-4o"},
+		{MaxTokens: 100},
+	}
+	tests := []struct {
+		name    string
+		req     Request
+		want    int
+		matched bool
+	}{
+		{"task hint", Request{Tokens: 10, Task: "Review"}, 0, true},
+		{"long code", Request{Tokens: 5000, Code: true}, 1, true},
+		{"short code is not long code", Request{Tokens: 50, Code: true}, 3, true},
+		{"short question", Request{Tokens: 50}, 2, true},
+		{"under the cost ceiling", Request{Tokens: 3000}, 3, true},
+		{"over the cost ceiling", Request{Tokens: 10000}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Match(rules, tt.req)
+			if ok != tt.matched || (ok && got != tt.want) {
+				t.Errorf("Match() = %d, %v; want %d, %v", got, ok, tt.want, tt.matched)
+			}
+		})
+	}
+
+	// Unknown prices never satisfy a cost ceiling
+	if _, ok := Match([]Rule{{MaxCost: 100, Model: "my-model"}}, Request{Tokens: 1}); ok {
+		t.Error("Match() matched a cost ceiling without a price")
+	}
+
+	// Nor does a model too small for the request
+	if _, ok := Match([]Rule{{Model: "gpt-3.5-turbo"}}, Request{Tokens: 20000}); ok {
+		t.Error("Match() matched a model whose context window the request exceeds")
+	}
+}
+
+func TestHasCode(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"What is a goroutine?", false},
+		{"Explain this:\n```\nx := 1\n```", true},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}\n", true},
+		{"import os\n\ndef main():\n    return os.getcwd()\n", true},
+		{"const x = 1;\nlet y = 2;\n", true},
+		{"Use a semicolon;", false},
+		{"The function returns early. If it fails, we retry.\nThen we stop.", false},
+	}
+	for _, tt := range tests {
+		if got := HasCode(tt.text); got != tt.want {
+			t.Errorf("HasCode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package secret encrypts values such as API keys for storage in the
+// config file, with a key kept in the OS keyring or derived from a
+// passphrase.
+//
+// An encrypted value is self-describing text:
+//
+//	enc:keyring:<base64 nonce and ciphertext>
+//	enc:passphrase:<base64 salt>:<base64 nonce and ciphertext>
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/devaloi/ask/internal/keyring"
+)
+
+// Prefix starts every encrypted value.
+const Prefix = "enc:"
+
+// Key sources.
+const (
+	MethodKeyring    = "keyring"
+	MethodPassphrase = "passphrase"
+)
+
+// KeyringAccount is the OS keyring account holding the encryption key.
+const KeyringAccount = "config-encryption-key"
+
+// keySize is the AES-256 key size; saltSize is the passphrase salt size.
+const (
+	keySize  = 32
+	saltSize = 16
+)
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-SHA256.
+var pbkdf2Iterations = 600_000
+
+// ErrDecrypt means a value could not be decrypted with the key, such as
+// after a wrong passphrase.
+var ErrDecrypt = errors.New("cannot decrypt: wrong key or passphrase, or corrupted value")
+
+var encoding = base64.RawStdEncoding
+
+// IsEncrypted reports whether value is an encrypted value.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Key is an encryption key and how to find it again.
+type Key struct {
+	Method string // MethodKeyring or MethodPassphrase
+	Salt   []byte // for MethodPassphrase
+	bytes  []byte
+}
+
+// KeyringKey returns the key stored in the OS keyring. With create, a new
+// random key is stored if there is none.
+func KeyringKey(create bool) (Key, error) {
+	stored, err := keyring.Get(KeyringAccount)
+	if errors.Is(err, keyring.ErrNotFound) && create {
+		b := make([]byte, keySize)
+		if _, err := rand.Read(b); err != nil {
+			return Key{}, err
+		}
+		if err := keyring.Set(KeyringAccount, hex.EncodeToString(b)); err != nil {
+			return Key{}, fmt.Errorf("storing encryption key: %w", err)
+		}
+		return Key{Method: MethodKeyring, bytes: b}, nil
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("reading encryption key from the OS keyring: %w", err)
+	}
+	b, err := hex.DecodeString(stored)
+	if err != nil || len(b) != keySize {
+		return Key{}, errors.New("invalid encryption key in the OS keyring")
+	}
+	return Key{Method: MethodKeyring, bytes: b}, nil
+}
+
+// PassphraseKey derives a key from passphrase. A nil salt picks a new
+// random one.
+func PassphraseKey(passphrase string, salt []byte) (Key, error) {
+	if passphrase == "" {
+		return Key{}, errors.New("passphrase is empty")
+	}
+	if salt == nil {
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return Key{}, err
+		}
+	}
+	b, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, keySize)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Method: MethodPassphrase, Salt: salt, bytes: b}, nil
+}
+
+// Encrypt encrypts plaintext with k.
+func Encrypt(plaintext string, k Key) (string, error) {
+	gcm, err := newGCM(k.bytes)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := encoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+
+	switch k.Method {
+	case MethodKeyring:
+		return Prefix + MethodKeyring + ":" + sealed, nil
+	case MethodPassphrase:
+		return Prefix + MethodPassphrase + ":" + encoding.EncodeToString(k.Salt) + ":" + sealed, nil
+	default:
+		return "", fmt.Errorf("unknown key method %q", k.Method)
+	}
+}
+
+// Parse returns the key method and salt of an encrypted value.
+func Parse(value string) (method string, salt []byte, err error) {
+	method, _, salt, err = parse(value)
+	return method, salt, err
+}
+
+// Decrypt decrypts value, getting its key from key, which is given the
+// value's key method and salt.
+func Decrypt(value string, key func(method string, salt []byte) (Key, error)) (string, error) {
+	method, sealed, salt, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	k, err := key(method, salt)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(k.bytes)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", ErrDecrypt
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// parse splits an encrypted value into its parts.
+func parse(value string) (method string, sealed, salt []byte, err error) {
+	rest, ok := strings.CutPrefix(value, Prefix)
+	if !ok {
+		return "", nil, nil, errors.New("value is not encrypted")
+	}
+	parts := strings.Split(rest, ":")
+	method = parts[0]
+	switch {
+	case method == MethodKeyring && len(parts) == 2:
+		sealed, err = encoding.DecodeString(parts[1])
+	case method == MethodPassphrase && len(parts) == 3:
+		if salt, err = encoding.DecodeString(parts[1]); err == nil {
+			sealed, err = encoding.DecodeString(parts[2])
+		}
+	default:
+		return "", nil, nil, fmt.Errorf("invalid encrypted value %q", Prefix+method+":...")
+	}
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	return method, sealed, salt, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, errors.New("invalid encryption key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
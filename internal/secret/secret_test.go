@@ -0,0 +1,78 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// Keep tests fast; the strength of the derivation is not under test
+	pbkdf2Iterations = 1000
+}
+
+func TestPassphraseRoundTrip(t *testing.T) {
+	k, err := PassphraseKey("correct horse", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := Encrypt("sk-secret", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value) || !strings.HasPrefix(value, "enc:passphrase:") || strings.Contains(value, "sk-secret") {
+		t.Fatalf("Encrypt() = %q", value)
+	}
+
+	method, salt, err := Parse(value)
+	if err != nil || method != MethodPassphrase || string(salt) != string(k.Salt) {
+		t.Errorf("Parse() = %q, %x, %v; want passphrase, %x", method, salt, err, k.Salt)
+	}
+
+	decrypt := func(passphrase string) (string, error) {
+		return Decrypt(value, func(method string, salt []byte) (Key, error) {
+			return PassphraseKey(passphrase, salt)
+		})
+	}
+	if got, err := decrypt("correct horse"); err != nil || got != "sk-secret" {
+		t.Errorf("Decrypt() = %q, %v; want sk-secret", got, err)
+	}
+	if _, err := decrypt("wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Decrypt() with a wrong passphrase: err = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestEncryptUsesFreshNonces(t *testing.T) {
+	k := Key{Method: MethodKeyring, bytes: make([]byte, keySize)}
+	a, _ := Encrypt("same", k)
+	b, _ := Encrypt("same", k)
+	if a == b {
+		t.Errorf("Encrypt() twice = %q both times", a)
+	}
+	got, err := Decrypt(a, func(method string, salt []byte) (Key, error) {
+		if method != MethodKeyring || salt != nil {
+			t.Errorf("key(%q, %x), want keyring without salt", method, salt)
+		}
+		return k, nil
+	})
+	if err != nil || got != "same" {
+		t.Errorf("Decrypt() = %q, %v", got, err)
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	k := Key{Method: MethodKeyring, bytes: make([]byte, keySize)}
+	key := func(string, []byte) (Key, error) { return k, nil }
+	for _, value := range []string{
+		"sk-plain",
+		"enc:",
+		"enc:rot13:abc",
+		"enc:keyring:!!!",
+		"enc:keyring:YWJj",
+		"enc:passphrase:onlyone",
+	} {
+		if _, err := Decrypt(value, key); err == nil {
+			t.Errorf("Decrypt(%q) succeeded", value)
+		}
+	}
+}
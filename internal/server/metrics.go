@@ -0,0 +1,159 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request
+// duration histogram.
+var latencyBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Status labels of completed requests, besides the error types of
+// errorStatus.
+const (
+	statusOK       = "ok"
+	statusCanceled = "canceled"
+)
+
+// metrics counts chat completions for /metrics, in the Prometheus text
+// exposition format.
+type metrics struct {
+	mu       sync.Mutex
+	inFlight int
+	requests map[requestKey]int
+	latency  map[modelKey]*histogram
+	tokens   map[tokenKey]int
+}
+
+type modelKey struct{ provider, model string }
+
+type requestKey struct {
+	modelKey
+	status string
+}
+
+type tokenKey struct {
+	modelKey
+	kind string // "prompt" or "completion"
+}
+
+type histogram struct {
+	counts []int // per bucket, not cumulative, plus one for +Inf
+	sum    float64
+	total  int
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests: make(map[requestKey]int),
+		latency:  make(map[modelKey]*histogram),
+		tokens:   make(map[tokenKey]int),
+	}
+}
+
+// start counts a request in flight until the returned func is called.
+func (m *metrics) start() func() {
+	m.mu.Lock()
+	m.inFlight++
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}
+}
+
+// observe records a completed chat request.
+func (m *metrics) observe(providerName, model, status string, elapsed time.Duration, promptTokens, completionTokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := modelKey{providerName, model}
+	m.requests[requestKey{key, status}]++
+
+	h := m.latency[key]
+	if h == nil {
+		h = &histogram{counts: make([]int, len(latencyBuckets)+1)}
+		m.latency[key] = h
+	}
+	seconds := elapsed.Seconds()
+	i, _ := slices.BinarySearch(latencyBuckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.total++
+
+	m.tokens[tokenKey{key, "prompt"}] += promptTokens
+	m.tokens[tokenKey{key, "completion"}] += completionTokens
+}
+
+// write writes the metrics to w.
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# HELP ask_requests_in_flight Chat completions being served.\n")
+	b.WriteString("# TYPE ask_requests_in_flight gauge\n")
+	fmt.Fprintf(&b, "ask_requests_in_flight %d\n", m.inFlight)
+
+	b.WriteString("# HELP ask_requests_total Chat completions served, by outcome.\n")
+	b.WriteString("# TYPE ask_requests_total counter\n")
+	for _, k := range sortedKeys(m.requests, func(k requestKey) string { return k.provider + "\x00" + k.model + "\x00" + k.status }) {
+		fmt.Fprintf(&b, "ask_requests_total{%s,status=%s} %d\n", k.labels(), quote(k.status), m.requests[k])
+	}
+
+	b.WriteString("# HELP ask_request_duration_seconds Time to serve a chat completion.\n")
+	b.WriteString("# TYPE ask_request_duration_seconds histogram\n")
+	for _, k := range sortedKeys(m.latency, modelKey.String) {
+		h := m.latency[k]
+		cumulative := 0
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "ask_request_duration_seconds_bucket{%s,le=%s} %d\n", k.labels(), quote(formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(&b, "ask_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", k.labels(), h.total)
+		fmt.Fprintf(&b, "ask_request_duration_seconds_sum{%s} %s\n", k.labels(), formatFloat(h.sum))
+		fmt.Fprintf(&b, "ask_request_duration_seconds_count{%s} %d\n", k.labels(), h.total)
+	}
+
+	b.WriteString("# HELP ask_tokens_total Estimated tokens of chat completions.\n")
+	b.WriteString("# TYPE ask_tokens_total counter\n")
+	for _, k := range sortedKeys(m.tokens, func(k tokenKey) string { return k.provider + "\x00" + k.model + "\x00" + k.kind }) {
+		fmt.Fprintf(&b, "ask_tokens_total{%s,type=%s} %d\n", k.labels(), quote(k.kind), m.tokens[k])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (k modelKey) String() string {
+	return k.provider + "\x00" + k.model
+}
+
+func (k modelKey) labels() string {
+	return "provider=" + quote(k.provider) + ",model=" + quote(k.model)
+}
+
+// sortedKeys returns the keys of m ordered by key, for stable output.
+func sortedKeys[K comparable, V any](m map[K]V, key func(K) string) []K {
+	return slices.SortedFunc(maps.Keys(m), func(a, b K) int {
+		return strings.Compare(key(a), key(b))
+	})
+}
+
+// quote quotes a label value, escaping as the text format requires.
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
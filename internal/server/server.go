@@ -0,0 +1,397 @@
+// Package server serves an OpenAI-compatible chat completions API backed
+// by ask's providers, so other tools can reuse ask's configuration.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// maxBodyBytes bounds the size of a request body.
+const maxBodyBytes = 32 * 1024 * 1024
+
+// Options configures a Server.
+type Options struct {
+	// Resolve returns the provider and model to use for the model named
+	// in a request, which may be empty.
+	Resolve func(model string) (provider.Provider, string, error)
+
+	// Models lists the model IDs reported by /v1/models.
+	Models []string
+
+	// Token, if set, must be sent as a bearer token with every request.
+	// Without one, only requests addressed to a loopback host, such as
+	// localhost, are served, so a web page cannot reach the server by
+	// rebinding its own domain to 127.0.0.1.
+	Token string
+
+	// OnComplete, if set, is called after each successful completion.
+	OnComplete func(p provider.Provider, req *provider.ChatRequest, response string)
+}
+
+// Server handles /v1/chat/completions and /v1/models, with Prometheus
+// metrics on /metrics and a health check on /healthz.
+type Server struct {
+	opts    Options
+	mux     *http.ServeMux
+	metrics *metrics
+}
+
+// New returns a Server using opts.
+func New(opts Options) *Server {
+	s := &Server{opts: opts, mux: http.NewServeMux(), metrics: newMetrics()}
+	s.mux.HandleFunc("POST /v1/chat/completions", s.handleChat)
+	s.mux.HandleFunc("GET /v1/models", s.handleModels)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	return s
+}
+
+// ServeHTTP implements http.Handler. Every endpoint but /healthz requires
+// the token, if one is set, so load balancers can check health without it.
+// Without a token, they require a loopback Host.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/healthz":
+	case s.opts.Token != "":
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid or missing bearer token")
+			return
+		}
+	case !IsLoopback(r.Host):
+		writeError(w, http.StatusForbidden, "invalid_request_error", "without a token, only requests to localhost are served")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// IsLoopback reports whether host, with or without a port, is localhost
+// or a loopback address.
+func IsLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// chatRequest is the subset of the OpenAI chat completions request that
+// ask supports.
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Stream      bool          `json:"stream"`
+	Temperature *float64      `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+
+	// MaxCompletionTokens is the newer name for MaxTokens.
+	MaxCompletionTokens int `json:"max_completion_tokens"`
+}
+
+// chatMessage is a request message. Content is either a string or an
+// array of content parts, of which only text parts are supported.
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the message content as plain text.
+func (m chatMessage) text() (string, error) {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("message content must be a string or an array of parts")
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return "", fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+		b.WriteString(p.Text)
+	}
+	return b.String(), nil
+}
+
+// toChatRequest validates r and converts it to a provider request.
+func (r *chatRequest) toChatRequest(model string) (*provider.ChatRequest, error) {
+	if len(r.Messages) == 0 {
+		return nil, errors.New("messages must not be empty")
+	}
+
+	req := &provider.ChatRequest{Model: model, MaxTokens: r.MaxTokens}
+	if r.MaxCompletionTokens > 0 {
+		req.MaxTokens = r.MaxCompletionTokens
+	}
+	if r.Temperature != nil {
+		req.Temperature = *r.Temperature
+	}
+
+	for i, m := range r.Messages {
+		role := m.Role
+		if role == "developer" {
+			role = "system"
+		}
+		if role != "system" && role != "user" && role != "assistant" {
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, m.Role)
+		}
+		content, err := m.text()
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		req.Messages = append(req.Messages, provider.Message{Role: role, Content: content})
+	}
+	return req, nil
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	// Browsers send other types cross-origin without asking first
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "invalid_request_error", "Content-Type must be application/json")
+		return
+	}
+
+	var body chatRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error())
+		return
+	}
+
+	p, model, err := s.opts.Resolve(body.Model)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	req, err := body.toChatRequest(model)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	defer s.metrics.start()()
+	start := time.Now()
+	id := "chatcmpl-" + randomID()
+	created := start.Unix()
+	stream := make(chan string, util.DefaultChannelBuffer)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- p.Chat(r.Context(), req, stream)
+	}()
+
+	var response string
+	if body.Stream {
+		response, err = s.streamResponse(w, id, created, model, stream, errCh)
+	} else {
+		var b strings.Builder
+		for token := range stream {
+			b.WriteString(token)
+		}
+		response = b.String()
+		err = <-errCh
+		switch {
+		case errors.Is(err, provider.ErrTruncated):
+			// The response is usable, if incomplete
+			err = nil
+			writeJSON(w, http.StatusOK, completion(id, created, model, req, response, "length"))
+		case err != nil:
+			status, typ := errorStatus(err)
+			writeError(w, status, typ, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, completion(id, created, model, req, response, "stop"))
+		}
+	}
+
+	status := statusOK
+	switch {
+	case errors.Is(err, context.Canceled):
+		status = statusCanceled
+	case err != nil:
+		_, status = errorStatus(err)
+	}
+	s.metrics.observe(p.Name(), model, status, time.Since(start), promptTokens(req), tokens.Estimate(response))
+	if err != nil {
+		return
+	}
+
+	if s.opts.OnComplete != nil {
+		s.opts.OnComplete(p, req, response)
+	}
+}
+
+// streamResponse relays tokens as server-sent events in the OpenAI chunk
+// format. A provider error after the stream has started is sent as an
+// error event; a response cut short by the token limit ends normally,
+// with the finish reason "length".
+func (s *Server) streamResponse(w http.ResponseWriter, id string, created int64, model string, stream <-chan string, errCh <-chan error) (string, error) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(v any) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(chunk(id, created, model, map[string]string{"role": "assistant", "content": ""}, nil))
+	var b strings.Builder
+	for token := range stream {
+		b.WriteString(token)
+		send(chunk(id, created, model, map[string]string{"content": token}, nil))
+	}
+
+	stop := "stop"
+	if err := <-errCh; errors.Is(err, provider.ErrTruncated) {
+		stop = "length"
+	} else if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			_, typ := errorStatus(err)
+			send(errorBody(typ, err.Error()))
+		}
+		return b.String(), err
+	}
+
+	send(chunk(id, created, model, map[string]string{}, &stop))
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return b.String(), nil
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = s.metrics.write(w)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		OwnedBy string `json:"owned_by"`
+	}
+	data := make([]model, 0, len(s.opts.Models))
+	for _, id := range s.opts.Models {
+		data = append(data, model{ID: id, Object: "model", OwnedBy: "ask"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+// completion returns a non-streaming chat.completion response. Token
+// counts are estimates, since providers do not report usage to ask.
+func completion(id string, created int64, model string, req *provider.ChatRequest, response, finishReason string) map[string]any {
+	promptTokens := promptTokens(req)
+	completionTokens := tokens.Estimate(response)
+
+	return map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": created,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": response},
+			"finish_reason": finishReason,
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      promptTokens + completionTokens,
+		},
+	}
+}
+
+// promptTokens estimates the input tokens of req.
+func promptTokens(req *provider.ChatRequest) int {
+	n := 0
+	for _, m := range req.Messages {
+		n += tokens.Estimate(m.Content)
+	}
+	return n
+}
+
+// chunk returns a chat.completion.chunk event.
+func chunk(id string, created int64, model string, delta map[string]string, finishReason *string) map[string]any {
+	return map[string]any{
+		"id":      id,
+		"object":  "chat.completion.chunk",
+		"created": created,
+		"model":   model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finishReason,
+		}},
+	}
+}
+
+// errorStatus maps a provider error to an HTTP status and OpenAI error
+// type.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, provider.ErrRateLimited):
+		return http.StatusTooManyRequests, "rate_limit_error"
+	case errors.Is(err, provider.ErrContentFiltered):
+		return http.StatusBadRequest, "content_filter"
+	case errors.Is(err, provider.ErrAPI):
+		return http.StatusBadRequest, "invalid_request_error"
+	default:
+		// Includes ask's own API key being missing or rejected, which is
+		// not the client's fault
+		return http.StatusBadGateway, "upstream_error"
+	}
+}
+
+func errorBody(typ, message string) map[string]any {
+	return map[string]any{"error": map[string]string{"message": message, "type": typ}}
+}
+
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	writeJSON(w, status, errorBody(typ, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// randomID returns a random hex string for response IDs.
+func randomID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devaloi/ask/internal/tokens"
+	"github.com/devaloi/ask/pkg/ask/provider"
+)
+
+// fakeProvider streams a fixed set of tokens, then returns err.
+type fakeProvider struct {
+	tokens []string
+	err    error
+	got    *provider.ChatRequest
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, req *provider.ChatRequest, stream chan<- string) error {
+	defer close(stream)
+	f.got = req
+	for _, t := range f.tokens {
+		stream <- t
+	}
+	return f.err
+}
+
+func (f *fakeProvider) BuildRequest(ctx context.Context, req *provider.ChatRequest) (*http.Request, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) Models() []string { return []string{"fake-1"} }
+func (f *fakeProvider) Name() string     { return "fake" }
+
+func newTestServer(p *fakeProvider, token string, completed *string) *Server {
+	return New(Options{
+		Resolve: func(model string) (provider.Provider, string, error) {
+			if model == "" {
+				model = "fake-1"
+			}
+			if model == "missing" {
+				return nil, "", fmt.Errorf("unknown model: %s", model)
+			}
+			return p, model, nil
+		},
+		Models: []string{"fake-1"},
+		Token:  token,
+		OnComplete: func(_ provider.Provider, _ *provider.ChatRequest, response string) {
+			if completed != nil {
+				*completed = response
+			}
+		},
+	})
+}
+
+func post(s *Server, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestChat_NonStreaming(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"Hel", "lo"}}
+	var completed string
+	s := newTestServer(p, "", &completed)
+
+	body := `{"model": "fake-1", "temperature": 0.5, "max_tokens": 10, "messages": [
+		{"role": "developer", "content": "Be brief."},
+		{"role": "user", "content": [{"type": "text", "text": "Hi"}, {"type": "text", "text": "!"}]}
+	]}`
+	rec := post(s, body, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Object  string
+		Model   string
+		Choices []struct {
+			Message      provider.Message
+			FinishReason string `json:"finish_reason"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Object != "chat.completion" || resp.Model != "fake-1" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response: %s", rec.Body)
+	}
+	if c := resp.Choices[0]; c.Message.Content != "Hello" || c.Message.Role != "assistant" || c.FinishReason != "stop" {
+		t.Errorf("choice = %+v", c)
+	}
+	if completed != "Hello" {
+		t.Errorf("OnComplete got %q, want %q", completed, "Hello")
+	}
+
+	want := []provider.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hi!"}}
+	if len(p.got.Messages) != 2 || p.got.Messages[0] != want[0] || p.got.Messages[1] != want[1] {
+		t.Errorf("provider messages = %+v, want %+v", p.got.Messages, want)
+	}
+	if p.got.Temperature != 0.5 || p.got.MaxTokens != 10 {
+		t.Errorf("provider request = %+v", p.got)
+	}
+}
+
+func TestChat_Streaming(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"a", "b"}}
+	s := newTestServer(p, "", nil)
+
+	rec := post(s, `{"stream": true, "messages": [{"role": "user", "content": "hi"}]}`, "")
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var content strings.Builder
+	var finish string
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	for _, ev := range events[:len(events)-1] {
+		var c struct {
+			Object  string
+			Choices []struct {
+				Delta        map[string]string
+				FinishReason *string `json:"finish_reason"`
+			}
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(ev, "data: ")), &c); err != nil {
+			t.Fatalf("bad event %q: %v", ev, err)
+		}
+		if c.Object != "chat.completion.chunk" {
+			t.Errorf("object = %q", c.Object)
+		}
+		content.WriteString(c.Choices[0].Delta["content"])
+		if c.Choices[0].FinishReason != nil {
+			finish = *c.Choices[0].FinishReason
+		}
+	}
+	if events[len(events)-1] != "data: [DONE]" {
+		t.Errorf("last event = %q, want [DONE]", events[len(events)-1])
+	}
+	if content.String() != "ab" || finish != "stop" {
+		t.Errorf("streamed %q with finish %q", content.String(), finish)
+	}
+}
+
+func TestChat_Truncated(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"cut"}, err: &provider.Error{Provider: "fake", Kind: provider.ErrTruncated}}
+	var completed string
+	s := newTestServer(p, "", &completed)
+
+	rec := post(s, `{"max_tokens": 1, "messages": [{"role": "user", "content": "hi"}]}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Choices []struct {
+			Message      provider.Message
+			FinishReason string `json:"finish_reason"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if c := resp.Choices[0]; c.Message.Content != "cut" || c.FinishReason != "length" {
+		t.Errorf("choice = %+v, want the partial content with finish_reason length", c)
+	}
+	if completed != "cut" {
+		t.Errorf("OnComplete got %q, want %q", completed, "cut")
+	}
+
+	rec = post(s, `{"stream": true, "messages": [{"role": "user", "content": "hi"}]}`, "")
+	body := rec.Body.String()
+	if !strings.Contains(body, `"finish_reason":"length"`) || !strings.HasSuffix(body, "data: [DONE]\n\n") || strings.Contains(body, `"error"`) {
+		t.Errorf("streamed body = %s, want a normal end with finish_reason length", body)
+	}
+}
+
+func TestChat_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"bad json", `{`, nil, http.StatusBadRequest},
+		{"no messages", `{"messages": []}`, nil, http.StatusBadRequest},
+		{"bad role", `{"messages": [{"role": "tool", "content": "x"}]}`, nil, http.StatusBadRequest},
+		{"image part", `{"messages": [{"role": "user", "content": [{"type": "image_url"}]}]}`, nil, http.StatusBadRequest},
+		{"unknown model", `{"model": "missing", "messages": [{"role": "user", "content": "x"}]}`, nil, http.StatusBadRequest},
+		{"rate limited", `{"messages": [{"role": "user", "content": "x"}]}`, fmt.Errorf("OpenAI %w", provider.ErrRateLimited), http.StatusTooManyRequests},
+		{"upstream auth", `{"messages": [{"role": "user", "content": "x"}]}`, fmt.Errorf("%w: check your key", provider.ErrAuth), http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&fakeProvider{err: tt.err}, "", nil)
+			rec := post(s, tt.body, "")
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+			var body struct {
+				Error struct{ Message, Type string }
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Message == "" {
+				t.Errorf("body = %s, want an OpenAI error object", rec.Body)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := newTestServer(&fakeProvider{tokens: []string{"ok"}}, "secret", nil)
+	body := `{"messages": [{"role": "user", "content": "x"}]}`
+
+	if rec := post(s, body, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", rec.Code)
+	}
+	if rec := post(s, body, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: status = %d, want 401", rec.Code)
+	}
+	if rec := post(s, body, "secret"); rec.Code != http.StatusOK {
+		t.Errorf("with token: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCrossSiteRequests(t *testing.T) {
+	body := `{"messages": [{"role": "user", "content": "x"}]}`
+	request := func(host, contentType string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Host = host
+		req.Header.Set("Content-Type", contentType)
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		host        string
+		contentType string
+		status      int
+	}{
+		{"localhost", "", "localhost:8080", "application/json", http.StatusOK},
+		{"loopback IPv4", "", "127.0.0.1:8080", "application/json; charset=utf-8", http.StatusOK},
+		{"loopback IPv6", "", "[::1]:8080", "application/json", http.StatusOK},
+		{"rebound domain", "", "attacker.example:8080", "application/json", http.StatusForbidden},
+		{"LAN address", "", "192.168.1.5:8080", "application/json", http.StatusForbidden},
+		{"any host with a token", "secret", "ask.example:8080", "application/json", http.StatusOK},
+		{"simple form post", "", "localhost:8080", "text/plain", http.StatusUnsupportedMediaType},
+		{"no content type", "", "localhost:8080", "", http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(&fakeProvider{tokens: []string{"ok"}}, tt.token, nil)
+			req := request(tt.host, tt.contentType)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestModels(t *testing.T) {
+	s := newTestServer(&fakeProvider{}, "", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost/v1/models", nil))
+
+	var resp struct {
+		Data []struct{ ID string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].ID != "fake-1" {
+		t.Errorf("models = %s", rec.Body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	p := &fakeProvider{tokens: []string{"Hello there"}}
+	s := newTestServer(p, "secret", nil)
+	post(s, `{"messages": [{"role": "user", "content": "Say hello"}]}`, "secret")
+	post(s, `{"stream": true, "messages": [{"role": "user", "content": "Say hello"}]}`, "secret")
+	p.err = fmt.Errorf("OpenAI %w", provider.ErrRateLimited)
+	post(s, `{"messages": [{"role": "user", "content": "Again"}]}`, "secret")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status = %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	for _, want := range []string{
+		`ask_requests_in_flight 0`,
+		`ask_requests_total{provider="fake",model="fake-1",status="ok"} 2`,
+		`ask_requests_total{provider="fake",model="fake-1",status="rate_limit_error"} 1`,
+		`ask_request_duration_seconds_bucket{provider="fake",model="fake-1",le="+Inf"} 3`,
+		`ask_request_duration_seconds_count{provider="fake",model="fake-1"} 3`,
+		// The rate-limited request streamed a response before failing
+		fmt.Sprintf(`ask_tokens_total{provider="fake",model="fake-1",type="completion"} %d`, 3*tokens.Estimate("Hello there")),
+		`# TYPE ask_request_duration_seconds histogram`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("metrics missing %q:\n%s", want, rec.Body)
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(&fakeProvider{}, "secret", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://localhost/healthz", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"ok"`) {
+		t.Errorf("healthz = %d %s, want 200 ok without a token", rec.Code, rec.Body)
+	}
+}
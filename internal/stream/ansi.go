@@ -0,0 +1,80 @@
+package stream
+
+// escapeState tracks progress through an ANSI escape sequence.
+type escapeState int
+
+const (
+	escNone   escapeState = iota // not in a sequence
+	escStart                     // after ESC
+	escCSI                       // after ESC [, until a final byte
+	escOSC                       // after ESC ], until BEL or ESC \
+	escOSCEsc                    // ESC seen inside an OSC sequence
+)
+
+// next returns the state after r.
+func (s escapeState) next(r rune) escapeState {
+	switch s {
+	case escNone:
+		if r == '\x1b' {
+			return escStart
+		}
+		return escNone
+	case escStart:
+		switch r {
+		case '[':
+			return escCSI
+		case ']':
+			return escOSC
+		default:
+			// Two-character sequence such as ESC 7
+			return escNone
+		}
+	case escCSI:
+		if r >= 0x40 && r <= 0x7e {
+			return escNone
+		}
+		return escCSI
+	case escOSC:
+		switch r {
+		case '\a':
+			return escNone
+		case '\x1b':
+			return escOSCEsc
+		}
+		return escOSC
+	case escOSCEsc:
+		if r == '\\' {
+			return escNone
+		}
+		return escOSC
+	}
+	return escNone
+}
+
+// runeWidth returns the number of terminal columns r occupies.
+// Wide East Asian characters and most emoji take two columns.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r >= 0x300 && r <= 0x36f, r == 0x200d, r >= 0xfe00 && r <= 0xfe0f:
+		// Combining marks, zero-width joiner, and variation selectors
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0x303e,
+		r >= 0x3041 && r <= 0x33ff,
+		r >= 0x3400 && r <= 0x4dbf,
+		r >= 0x4e00 && r <= 0x9fff,
+		r >= 0xa000 && r <= 0xa4cf,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
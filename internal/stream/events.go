@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Event types written by an EventWriter.
+const (
+	EventStart = "start" // the request is being sent
+	EventDelta = "delta" // a piece of the response
+	EventUsage = "usage" // estimated tokens and cost of the exchange
+	EventDone  = "done"  // the response is complete
+	EventError = "error" // the request failed; no done event follows
+)
+
+// Event is one line of newline-delimited JSON output, for programs that
+// wrap ask, such as editor plugins.
+type Event struct {
+	Type         string  `json:"type"`
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Text         string  `json:"text,omitempty"`
+	Usage        *Usage  `json:"usage,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	ExitCode     int     `json:"exit_code,omitempty"`
+	ElapsedMS    int64   `json:"elapsed_ms,omitempty"`
+	FirstTokenMS int64   `json:"first_token_ms,omitempty"`
+	TokensPerSec float64 `json:"tokens_per_sec,omitempty"`
+}
+
+// Usage is the estimated usage of an exchange.
+type Usage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// EventWriter writes events as JSON Lines. As an io.Writer, such as a
+// Writer's TeeRaw destination, it writes each write as a delta event.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventWriter returns an EventWriter writing to out.
+func NewEventWriter(out io.Writer) *EventWriter {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return &EventWriter{enc: enc}
+}
+
+// Emit writes ev as one line.
+func (e *EventWriter) Emit(ev Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.enc.Encode(ev)
+}
+
+// Write emits p as a delta event.
+func (e *EventWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := e.Emit(Event{Type: EventDelta, Text: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,32 @@
+package stream
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEventWriter(t *testing.T) {
+	var out bytes.Buffer
+	events := NewEventWriter(&out)
+
+	_ = events.Emit(Event{Type: EventStart, Provider: "openai", Model: "gpt-4o"})
+	w := NewWriter(io.Discard, true)
+	w.TeeRaw(events)
+	_ = w.Write("<b>Hi")
+	_ = w.Write("")
+	_ = w.Write("\n")
+	w.Flush()
+	_ = events.Emit(Event{Type: EventUsage, Usage: &Usage{InputTokens: 3, OutputTokens: 2}})
+	_ = events.Emit(Event{Type: EventDone, ElapsedMS: 12})
+
+	want := `{"type":"start","provider":"openai","model":"gpt-4o"}
+{"type":"delta","text":"<b>Hi"}
+{"type":"delta","text":"\n"}
+{"type":"usage","usage":{"input_tokens":3,"output_tokens":2,"cost":0}}
+{"type":"done","elapsed_ms":12}
+`
+	if got := out.String(); got != want {
+		t.Errorf("events =\n%s\nwant\n%s", got, want)
+	}
+}
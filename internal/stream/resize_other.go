@@ -0,0 +1,14 @@
+//go:build !unix
+
+package stream
+
+// watchResize is a no-op on platforms without SIGWINCH.
+func watchResize() uint64 {
+	return 0
+}
+
+// resizeGeneration always returns 0 on platforms without SIGWINCH, so
+// the width is only read when the writer is created.
+func resizeGeneration() uint64 {
+	return 0
+}
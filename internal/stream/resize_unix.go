@@ -0,0 +1,37 @@
+//go:build unix
+
+package stream
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+var (
+	resizeOnce sync.Once
+	resizeGen  atomic.Uint64
+)
+
+// watchResize starts listening for SIGWINCH, once per process, and
+// returns the current resize generation.
+func watchResize() uint64 {
+	resizeOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGWINCH)
+		go func() {
+			for range ch {
+				resizeGen.Add(1)
+			}
+		}()
+	})
+	return resizeGen.Load()
+}
+
+// resizeGeneration returns a counter that changes whenever the terminal
+// is resized.
+func resizeGeneration() uint64 {
+	return resizeGen.Load()
+}
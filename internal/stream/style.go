@@ -0,0 +1,83 @@
+package stream
+
+import "strings"
+
+// Styles are escape sequences for the output around responses, such as
+// role labels and errors. They are used only on a terminal; empty fields
+// leave text unstyled.
+type Styles struct {
+	Prefix    string // role prefixes, such as "[Assistant]"
+	Error     string // error lines
+	Separator string // the rule between turns
+}
+
+// styleReset ends a style.
+const styleReset = "\x1b[0m"
+
+// defaultRuleWidth is the width of a separator when the terminal's is
+// unknown.
+const defaultRuleWidth = 40
+
+// SetStyles sets the styles of Prefix, ErrorLine, and Separator.
+func (w *Writer) SetStyles(s Styles) {
+	w.styles = s
+}
+
+// Prefix writes a role prefix, such as "[Assistant]", on a line of its
+// own, to introduce the response that follows.
+func (w *Writer) Prefix(label string) error {
+	return w.line(w.styles.Prefix, label)
+}
+
+// ErrorLine writes msg, such as an error that cut a response short, on a
+// line of its own.
+func (w *Writer) ErrorLine(msg string) error {
+	return w.line(w.styles.Error, msg)
+}
+
+// EndLine ends the line the output was left on, if any, so that what is
+// written elsewhere, such as an error on standard error, starts on a line
+// of its own.
+func (w *Writer) EndLine() error {
+	if !w.lineOpen {
+		return nil
+	}
+	if err := w.output("\n"); err != nil {
+		return err
+	}
+	return w.drain()
+}
+
+// Separator writes a rule across the terminal, to set turns apart. It
+// writes nothing when the output is not a terminal.
+func (w *Writer) Separator() error {
+	if !w.isTTY {
+		return nil
+	}
+	width := w.width
+	if width <= 0 {
+		width = defaultRuleWidth
+	}
+	return w.line(w.styles.Separator, strings.Repeat("─", width))
+}
+
+// line writes text on a line of its own, in style on a terminal. It is
+// not part of the response, so Response and TeeRaw destinations do not
+// see it.
+func (w *Writer) line(style, text string) error {
+	var b strings.Builder
+	if w.lineOpen {
+		b.WriteByte('\n')
+	}
+	if w.isTTY && style != "" {
+		b.WriteString(style + text + styleReset)
+	} else {
+		b.WriteString(text)
+	}
+	b.WriteByte('\n')
+
+	if err := w.output(b.String()); err != nil {
+		return err
+	}
+	return w.drain()
+}
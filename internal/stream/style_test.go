@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testStyles = Styles{Prefix: "\x1b[2m", Error: "\x1b[31m", Separator: "\x1b[90m"}
+
+func TestWriter_Prefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		isTTY bool
+		want  string
+	}{
+		{"terminal", true, "\x1b[2m[Assistant]\x1b[0m\nHi"},
+		{"pipe", false, "[Assistant]\nHi\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tt.isTTY)
+			w.SetStyles(testStyles)
+			_ = w.Prefix("[Assistant]")
+			_ = w.Write("Hi")
+			w.Flush()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+			if got := w.Response(); got != "Hi" {
+				t.Errorf("Response() = %q, want the prefix left out", got)
+			}
+		})
+	}
+}
+
+func TestWriter_ErrorLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true)
+	w.SetStyles(testStyles)
+
+	// An error after a partial line starts a line of its own
+	_ = w.Write("The answer is")
+	w.Flush()
+	_ = w.ErrorLine("Error: connection reset")
+	_ = w.ErrorLine("Error: again")
+
+	want := "The answer is\n\x1b[31mError: connection reset\x1b[0m\n\x1b[31mError: again\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestWriter_EndLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true)
+	_ = w.Write("The answer is")
+	w.Flush()
+	_ = w.EndLine()
+	_ = w.EndLine()
+
+	if got, want := buf.String(), "The answer is\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestWriter_Separator(t *testing.T) {
+	t.Run("terminal", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, true)
+		w.width = 10
+		w.SetStyles(testStyles)
+		_ = w.Write("Done.\n")
+		w.Flush()
+		_ = w.Separator()
+
+		want := "Done.\n\x1b[90m" + strings.Repeat("─", 10) + "\x1b[0m\n"
+		if got := buf.String(); got != want {
+			t.Errorf("output = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("unknown width", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, true)
+		_ = w.Separator()
+		if got, want := buf.String(), strings.Repeat("─", defaultRuleWidth)+"\n"; got != want {
+			t.Errorf("output = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, false)
+		w.SetStyles(testStyles)
+		_ = w.Separator()
+		if buf.Len() != 0 {
+			t.Errorf("output = %q, want none", buf.String())
+		}
+	})
+}
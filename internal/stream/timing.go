@@ -0,0 +1,91 @@
+package stream
+
+import "time"
+
+// Timings records when the tokens of a response arrived, for latency and
+// throughput figures.
+type Timings struct {
+	// Start is when the request was sent.
+	Start time.Time
+
+	// Arrivals holds the time of each token since Start.
+	Arrivals []time.Duration
+
+	// Total is the time from Start to the end of the response.
+	Total time.Duration
+
+	ended bool
+}
+
+// Tokens returns the number of tokens received. Providers stream about a
+// token per chunk, so this counts chunks.
+func (t Timings) Tokens() int {
+	return len(t.Arrivals)
+}
+
+// FirstToken returns the time to the first token, or 0 if none arrived.
+func (t Timings) FirstToken() time.Duration {
+	if len(t.Arrivals) == 0 {
+		return 0
+	}
+	return t.Arrivals[0]
+}
+
+// Gaps returns the time between each token and the one before it.
+func (t Timings) Gaps() []time.Duration {
+	if len(t.Arrivals) < 2 {
+		return nil
+	}
+	gaps := make([]time.Duration, len(t.Arrivals)-1)
+	for i := range gaps {
+		gaps[i] = t.Arrivals[i+1] - t.Arrivals[i]
+	}
+	return gaps
+}
+
+// TokensPerSecond is the generation rate after the first token, or 0 if
+// it cannot be measured.
+func (t Timings) TokensPerSecond() float64 {
+	if len(t.Arrivals) < 2 {
+		return 0
+	}
+	gen := t.Arrivals[len(t.Arrivals)-1] - t.Arrivals[0]
+	if gen <= 0 {
+		return 0
+	}
+	// The first token's time is in FirstToken
+	return float64(len(t.Arrivals)-1) / gen.Seconds()
+}
+
+// StartTiming starts recording the timings of the next response, from
+// now: call it as the request is sent. Writers not started record none.
+func (w *Writer) StartTiming() {
+	w.timing = &Timings{Start: time.Now()}
+}
+
+// Timings returns the timings of the current response, or, once flushed,
+// of the response Flush ended. They are zero unless StartTiming was
+// called.
+func (w *Writer) Timings() Timings {
+	if w.timing == nil {
+		return Timings{}
+	}
+	return *w.timing
+}
+
+// recordToken notes the arrival of a token.
+func (w *Writer) recordToken(token string) {
+	if w.timing == nil || token == "" || w.timing.ended {
+		return
+	}
+	w.timing.Arrivals = append(w.timing.Arrivals, time.Since(w.timing.Start))
+}
+
+// recordEnd notes the end of the response.
+func (w *Writer) recordEnd() {
+	if w.timing == nil || w.timing.ended {
+		return
+	}
+	w.timing.Total = time.Since(w.timing.Start)
+	w.timing.ended = true
+}
@@ -0,0 +1,89 @@
+package stream
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	tests := []struct {
+		name       string
+		arrivals   []time.Duration
+		wantFirst  time.Duration
+		wantGaps   []time.Duration
+		wantPerSec float64
+	}{
+		{name: "no tokens"},
+		{
+			name:      "one token",
+			arrivals:  []time.Duration{300 * time.Millisecond},
+			wantFirst: 300 * time.Millisecond,
+		},
+		{
+			name:       "steady",
+			arrivals:   []time.Duration{time.Second, 1250 * time.Millisecond, 1500 * time.Millisecond, 2 * time.Second},
+			wantFirst:  time.Second,
+			wantGaps:   []time.Duration{250 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond},
+			wantPerSec: 3,
+		},
+		{
+			name:      "burst",
+			arrivals:  []time.Duration{time.Second, time.Second},
+			wantFirst: time.Second,
+			wantGaps:  []time.Duration{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := Timings{Arrivals: tt.arrivals}
+			if got := tm.Tokens(); got != len(tt.arrivals) {
+				t.Errorf("Tokens() = %d, want %d", got, len(tt.arrivals))
+			}
+			if got := tm.FirstToken(); got != tt.wantFirst {
+				t.Errorf("FirstToken() = %v, want %v", got, tt.wantFirst)
+			}
+			if got := tm.Gaps(); !slices.Equal(got, tt.wantGaps) {
+				t.Errorf("Gaps() = %v, want %v", got, tt.wantGaps)
+			}
+			if got := tm.TokensPerSecond(); got != tt.wantPerSec {
+				t.Errorf("TokensPerSecond() = %v, want %v", got, tt.wantPerSec)
+			}
+		})
+	}
+}
+
+func TestWriter_Timings(t *testing.T) {
+	w := NewWriter(io.Discard, false)
+	_ = w.Write("untimed")
+	w.Flush()
+	if got := w.Timings(); got.Tokens() != 0 || got.Total != 0 {
+		t.Errorf("Timings() before StartTiming = %+v, want zero", got)
+	}
+
+	w.StartTiming()
+	for _, token := range []string{"a", "", "b", "c"} {
+		_ = w.Write(token)
+		time.Sleep(time.Millisecond)
+	}
+	w.Flush()
+	got := w.Timings()
+	if got.Tokens() != 3 {
+		t.Errorf("Tokens() = %d, want 3 (empty tokens are not counted)", got.Tokens())
+	}
+	if !slices.IsSorted(got.Arrivals) {
+		t.Errorf("Arrivals = %v, want increasing", got.Arrivals)
+	}
+	if got.Total < got.Arrivals[len(got.Arrivals)-1] {
+		t.Errorf("Total = %v, before the last token at %v", got.Total, got.Arrivals[len(got.Arrivals)-1])
+	}
+
+	// Output after the response ended is not part of its timings
+	_ = w.Write("late")
+	w.Flush()
+	if after := w.Timings(); after.Tokens() != 3 || after.Total != got.Total {
+		t.Errorf("Timings() after a later response = %+v, want unchanged %+v", after, got)
+	}
+}
@@ -5,37 +5,396 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
 )
 
+// defaultFlushInterval is how long terminal output is coalesced: about a
+// frame at 60 Hz, too short to notice, but long enough to gather the
+// tokens of fast models into one write.
+const defaultFlushInterval = 16 * time.Millisecond
+
+// maxPending is how much coalesced output is held before it is written
+// regardless.
+const maxPending = 4096
+
 // Writer handles streaming output to the terminal.
 // It adapts its behavior based on whether the output is a TTY or a pipe.
+//
+// A Writer also keeps the raw text of the response being written, so
+// callers need not collect the tokens themselves, and can copy output to
+// other destinations as it streams: rendered with Tee, or raw with TeeRaw.
+//
+// On a terminal, text is word-wrapped at the terminal width. Words are
+// held back until the whitespace after them arrives, so a word is never
+// split across lines unless it is wider than the terminal. ANSI escape
+// sequences pass through intact and take up no width.
+//
+// Terminal output is also coalesced: rather than a write per token, it
+// is written at the end of each line, and otherwise at most every
+// defaultFlushInterval.
 type Writer struct {
 	out   io.Writer
 	isTTY bool
+
+	// raw receives tokens as written. response holds the tokens since the
+	// last Flush; flushed means the next Write starts a new response.
+	raw      []io.Writer
+	response strings.Builder
+	flushed  bool
+
+	// fd is the terminal to query for its width, or -1 if out is not a
+	// terminal. width is the wrap column, or 0 to disable wrapping.
+	fd         int
+	width      int
+	resizeSeen uint64
+
+	col     int             // current column on the output line
+	spaces  string          // whitespace seen since the last word
+	word    strings.Builder // the word being accumulated
+	wordCol int             // display width of word
+	split   bool            // part of the current word was already emitted
+	esc     escapeState     // position within an ANSI escape sequence
+
+	// codeStyle, if set, is written before fenced code blocks and reset
+	// after them. lineHead holds backticks at the start of a line until
+	// it is known whether they form a fence.
+	codeStyle    string
+	lineHead     string
+	midLine      bool
+	inCode       bool
+	closingFence bool
+
+	// flushInterval, if positive, coalesces output into pending, written
+	// at newlines, when it reaches maxPending, or flushInterval after the
+	// last write, by a timer if no more output comes. mu guards pending
+	// and writes to out against the timer; timerErr is a failed timer
+	// write, returned by the next Write.
+	flushInterval time.Duration
+	mu            sync.Mutex
+	pending       []byte
+	lastWrite     time.Time
+	timerArmed    bool
+	timerErr      error
+
+	// styles are for the output around responses; lineOpen means the
+	// output so far does not end with a newline.
+	styles   Styles
+	lineOpen bool
+
+	// timing, if set by StartTiming, records when tokens arrive.
+	timing *Timings
 }
 
 // NewWriter creates a new stream writer.
 // When isTTY is true, output may include formatting.
 // When false (piped), output is raw text only.
 func NewWriter(out io.Writer, isTTY bool) *Writer {
-	return &Writer{
+	w := &Writer{
 		out:   out,
 		isTTY: isTTY,
+		fd:    -1,
+	}
+
+	if f, ok := out.(*os.File); ok && isTTY && term.IsTerminal(int(f.Fd())) {
+		w.fd = int(f.Fd())
+		w.resizeSeen = watchResize()
+		w.width = terminalWidth(w.fd)
+		w.flushInterval = defaultFlushInterval
+	}
+
+	return w
+}
+
+// Tee copies everything the writer outputs, as rendered, to dst.
+func (w *Writer) Tee(dst io.Writer) {
+	w.out = io.MultiWriter(w.out, dst)
+}
+
+// TeeRaw copies each token to dst exactly as written, without wrapping
+// or styling, such as to an event stream.
+func (w *Writer) TeeRaw(dst io.Writer) {
+	w.raw = append(w.raw, dst)
+}
+
+// Response returns the raw text written since the previous Flush, or, once
+// flushed, the text of the response Flush ended.
+func (w *Writer) Response() string {
+	return w.response.String()
+}
+
+// terminalWidth returns the width of the terminal fd, or 0 if unknown.
+func terminalWidth(fd int) int {
+	width, _, err := term.GetSize(fd)
+	if err != nil || width <= 0 {
+		return 0
 	}
+	return width
 }
 
-// Write writes a token to the output immediately.
+// Write writes a token to the output.
+// When wrapping, the trailing partial word is buffered until it is
+// complete or Flush is called. The token is recorded in Response and
+// copied to TeeRaw destinations even if writing the output fails.
 func (w *Writer) Write(token string) error {
-	_, err := io.WriteString(w.out, token)
+	if w.flushed {
+		w.response.Reset()
+		w.flushed = false
+	}
+	w.response.WriteString(token)
+	w.recordToken(token)
+	var rawErr error
+	for _, dst := range w.raw {
+		if _, err := io.WriteString(dst, token); err != nil && rawErr == nil {
+			rawErr = err
+		}
+	}
+	if err := w.render(token); err != nil {
+		return err
+	}
+	return rawErr
+}
+
+// render writes a token to the output, formatted for the terminal.
+func (w *Writer) render(token string) error {
+	if w.fd >= 0 {
+		if gen := resizeGeneration(); gen != w.resizeSeen {
+			w.resizeSeen = gen
+			w.width = terminalWidth(w.fd)
+		}
+	}
+
+	if w.width <= 0 && w.codeStyle == "" && w.word.Len() == 0 && w.spaces == "" {
+		return w.output(token)
+	}
+
+	var out strings.Builder
+	for _, r := range token {
+		w.styleRune(&out, r)
+	}
+
+	return w.output(out.String())
+}
+
+// output writes rendered text to out, or when coalescing, adds it to the
+// pending output, writing that if a line ended, it is full, or
+// flushInterval has passed since the last write.
+func (w *Writer) output(s string) error {
+	if s != "" {
+		w.lineOpen = !strings.HasSuffix(s, "\n")
+	}
+	if w.flushInterval <= 0 {
+		_, err := io.WriteString(w.out, s)
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.timerErr; err != nil {
+		w.timerErr = nil
+		return err
+	}
+	w.pending = append(w.pending, s...)
+	if strings.Contains(s, "\n") || len(w.pending) >= maxPending || time.Since(w.lastWrite) >= w.flushInterval {
+		return w.writePending()
+	}
+	if !w.timerArmed && len(w.pending) > 0 {
+		w.timerArmed = true
+		time.AfterFunc(w.flushInterval-time.Since(w.lastWrite), w.flushTimer)
+	}
+	return nil
+}
+
+// flushTimer writes output left pending when no more arrived in time.
+func (w *Writer) flushTimer() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timerArmed = false
+	if err := w.writePending(); err != nil && w.timerErr == nil {
+		w.timerErr = err
+	}
+}
+
+// drain writes any output coalesced but not yet written.
+func (w *Writer) drain() error {
+	if w.flushInterval <= 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writePending()
+}
+
+// writePending writes the pending output. w.mu must be held.
+func (w *Writer) writePending() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.pending)
+	w.pending = w.pending[:0]
+	w.lastWrite = time.Now()
 	return err
 }
 
-// Flush ensures all output has been written.
+// SetCodeStyle sets an escape sequence to write before fenced code blocks
+// (lines starting with ```). An empty style disables code highlighting.
+func (w *Writer) SetCodeStyle(style string) {
+	w.codeStyle = style
+}
+
+// codeReset ends a code block style.
+const codeReset = "\x1b[0m"
+
+// styleRune detects code fences at the start of lines, inserting the code
+// style around fenced blocks, and passes r on for wrapping.
+func (w *Writer) styleRune(out *strings.Builder, r rune) {
+	if w.codeStyle == "" {
+		w.wrapRune(out, r)
+		return
+	}
+
+	if !w.midLine {
+		if r == '`' {
+			w.lineHead += "`"
+			if w.lineHead == "```" {
+				if w.inCode {
+					w.closingFence = true
+				} else {
+					w.inCode = true
+					w.wrapString(out, w.codeStyle)
+				}
+				w.wrapString(out, w.lineHead)
+				w.lineHead = ""
+				w.midLine = true
+			}
+			return
+		}
+		w.wrapString(out, w.lineHead)
+		w.lineHead = ""
+		w.midLine = r != '\n'
+	}
+
+	if r == '\n' {
+		if w.closingFence {
+			w.wrapString(out, codeReset)
+			w.inCode = false
+			w.closingFence = false
+		}
+		w.midLine = false
+	}
+	w.wrapRune(out, r)
+}
+
+// wrapString feeds each rune of s through wrapRune.
+func (w *Writer) wrapString(out *strings.Builder, s string) {
+	for _, r := range s {
+		w.wrapRune(out, r)
+	}
+}
+
+// wrapRune feeds one rune through the word-wrapping state machine,
+// appending anything ready for output to out.
+func (w *Writer) wrapRune(out *strings.Builder, r rune) {
+	// Escape sequences stay attached to the surrounding word
+	if w.esc != escNone || r == '\x1b' {
+		w.esc = w.esc.next(r)
+		w.word.WriteRune(r)
+		return
+	}
+
+	switch r {
+	case '\n':
+		w.emitWord(out)
+		w.split = false
+		w.spaces = ""
+		out.WriteRune(r)
+		w.col = 0
+	case ' ', '\t':
+		w.emitWord(out)
+		w.split = false
+		w.spaces += string(r)
+	default:
+		w.word.WriteRune(r)
+		w.wordCol += runeWidth(r)
+
+		// Words wider than the whole line cannot be kept intact; let
+		// the terminal hard-wrap them rather than buffering forever
+		if w.width > 0 && w.wordCol >= w.width {
+			w.emitWord(out)
+			w.split = true
+		}
+	}
+}
+
+// emitWord writes any pending whitespace and word, starting a new line
+// first if the word would not fit on the current one.
+func (w *Writer) emitWord(out *strings.Builder) {
+	if w.word.Len() == 0 {
+		return
+	}
+
+	spaceCol := len(w.spaces) + 3*strings.Count(w.spaces, "\t")
+	// Continuations of an over-long word are never broken
+	fits := w.width <= 0 || w.col == 0 || w.col+spaceCol+w.wordCol <= w.width
+	if !w.split && !fits {
+		out.WriteString("\n")
+		w.col = 0
+	} else {
+		out.WriteString(w.spaces)
+		w.col += spaceCol
+	}
+
+	out.WriteString(w.word.String())
+	w.col += w.wordCol
+	if w.width > 0 && w.col > w.width {
+		// The terminal hard-wrapped the word
+		w.col %= w.width
+	}
+
+	w.spaces = ""
+	w.word.Reset()
+	w.wordCol = 0
+}
+
+// Flush ensures all output has been written, and ends the response.
 // For TTY output, adds a newline if needed.
 func (w *Writer) Flush() {
+	if w.flushed {
+		// Nothing was written since the previous response
+		w.response.Reset()
+	}
+	w.flushed = true
+	w.recordEnd()
+	var out strings.Builder
+	w.wrapString(&out, w.lineHead)
+	if w.inCode {
+		w.wrapString(&out, codeReset)
+	}
+	w.lineHead = ""
+	w.midLine = false
+	w.inCode = false
+	w.closingFence = false
+	w.emitWord(&out)
+	out.WriteString(w.spaces)
+	w.spaces = ""
+	w.col = 0
+	var err error
+	if out.Len() > 0 {
+		err = w.output(out.String())
+	}
+	if drainErr := w.drain(); err == nil {
+		err = drainErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write output: %v\n", err)
+	}
+
 	if !w.isTTY {
 		// For piped output, ensure there's a trailing newline
-		if _, err := io.WriteString(w.out, "\n"); err != nil {
+		if err := w.output("\n"); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: failed to write trailing newline: %v\n", err)
 		}
 	}
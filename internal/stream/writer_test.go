@@ -2,7 +2,11 @@ package stream
 
 import (
 	"bytes"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewWriter(t *testing.T) {
@@ -101,6 +105,89 @@ func TestWriter_Flush_TTYMode(t *testing.T) {
 	}
 }
 
+func TestWriter_Tee(t *testing.T) {
+	var out, copied bytes.Buffer
+	w := NewWriter(&out, true)
+	w.SetCodeStyle("\x1b[33m")
+	w.Tee(&copied)
+
+	_ = w.Write("text\n```\ncode\n```\n")
+	w.Flush()
+
+	if out.String() != copied.String() {
+		t.Errorf("Tee copy = %q, want %q", copied.String(), out.String())
+	}
+	if !strings.Contains(copied.String(), "\x1b[33m") {
+		t.Errorf("Tee copy %q is missing rendered styling", copied.String())
+	}
+}
+
+func TestWriter_TeeRaw(t *testing.T) {
+	var out, raw bytes.Buffer
+	w := NewWriter(&out, true)
+	w.SetCodeStyle("\x1b[33m")
+	w.TeeRaw(&raw)
+
+	tokens := []string{"text\n", "```\ncode", "\n```\n"}
+	for _, token := range tokens {
+		_ = w.Write(token)
+	}
+	w.Flush()
+
+	want := strings.Join(tokens, "")
+	if raw.String() != want {
+		t.Errorf("TeeRaw copy = %q, want %q", raw.String(), want)
+	}
+	if w.Response() != want {
+		t.Errorf("Response() = %q, want %q", w.Response(), want)
+	}
+	if out.String() == want {
+		t.Errorf("output %q was not styled", out.String())
+	}
+}
+
+func TestWriter_Response(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, false)
+
+	_ = w.Write("first ")
+	_ = w.Write("answer")
+	if got := w.Response(); got != "first answer" {
+		t.Errorf("Response() while streaming = %q, want %q", got, "first answer")
+	}
+	w.Flush()
+	if got := w.Response(); got != "first answer" {
+		t.Errorf("Response() after Flush = %q, want %q", got, "first answer")
+	}
+
+	_ = w.Write("second")
+	w.Flush()
+	if got := w.Response(); got != "second" {
+		t.Errorf("Response() of the next response = %q, want %q", got, "second")
+	}
+
+	w.Flush()
+	if got := w.Response(); got != "" {
+		t.Errorf("Response() of an empty response = %q, want empty", got)
+	}
+}
+
+func TestWriter_RecordsAfterWriteError(t *testing.T) {
+	w := NewWriter(failingWriter{}, false)
+	if err := w.Write("lost"); err == nil {
+		t.Fatal("Write() to a failing output succeeded")
+	}
+	if got := w.Response(); got != "lost" {
+		t.Errorf("Response() = %q, want %q", got, "lost")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
 func TestWriter_IsTTY(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -118,3 +205,300 @@ func TestWriter_IsTTY(t *testing.T) {
 		}
 	})
 }
+
+func TestWriter_WordWrap(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		tokens []string
+		want   string
+	}{
+		{
+			name:   "fits on one line",
+			width:  20,
+			tokens: []string{"hello ", "world"},
+			want:   "hello world",
+		},
+		{
+			name:   "wraps at word boundary",
+			width:  11,
+			tokens: []string{"the quick brown fox"},
+			want:   "the quick\nbrown fox",
+		},
+		{
+			name:   "word split across tokens",
+			width:  11,
+			tokens: []string{"the qu", "ick br", "own fox"},
+			want:   "the quick\nbrown fox",
+		},
+		{
+			name:   "exact fit",
+			width:  9,
+			tokens: []string{"the quick"},
+			want:   "the quick",
+		},
+		{
+			name:   "newline resets column",
+			width:  10,
+			tokens: []string{"aaaaaaaa\nbbbbbbbb cc"},
+			want:   "aaaaaaaa\nbbbbbbbb\ncc",
+		},
+		{
+			name:   "indentation preserved",
+			width:  20,
+			tokens: []string{"code:\n    x := 1"},
+			want:   "code:\n    x := 1",
+		},
+		{
+			name:   "long word not split",
+			width:  5,
+			tokens: []string{"ab abcdefghij"},
+			want:   "ab\nabcdefghij",
+		},
+		{
+			name:   "ansi sequences take no width",
+			width:  11,
+			tokens: []string{"\x1b[1mthe\x1b[0m quick brown"},
+			want:   "\x1b[1mthe\x1b[0m quick\nbrown",
+		},
+		{
+			name:   "ansi sequence split across tokens",
+			width:  11,
+			tokens: []string{"the \x1b[3", "2mquick\x1b[0m brown"},
+			want:   "the \x1b[32mquick\x1b[0m\nbrown",
+		},
+		{
+			name:   "wide characters count double",
+			width:  7,
+			tokens: []string{"日本 語です"},
+			want:   "日本\n語です",
+		},
+		{
+			name:   "zero width disables wrapping",
+			width:  0,
+			tokens: []string{"the quick brown fox"},
+			want:   "the quick brown fox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, true)
+			w.width = tt.width
+
+			for _, token := range tt.tokens {
+				if err := w.Write(token); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			w.Flush()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriter_WordWrap_BuffersPartialWord(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, true)
+	w.width = 40
+
+	_ = w.Write("hello wor")
+	if got := buf.String(); got != "hello" {
+		t.Errorf("before Flush = %q, want %q", got, "hello")
+	}
+
+	w.Flush()
+	if got := buf.String(); got != "hello wor" {
+		t.Errorf("after Flush = %q, want %q", got, "hello wor")
+	}
+}
+
+func TestWriter_PipeModeNeverWraps(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false)
+
+	line := strings.Repeat("word ", 100)
+	_ = w.Write(line)
+	w.Flush()
+
+	if got := buf.String(); got != line+"\n" {
+		t.Errorf("pipe output was modified: %q", got)
+	}
+}
+
+func TestWriter_CodeStyle(t *testing.T) {
+	const style = "\x1b[33m"
+
+	tests := []struct {
+		name   string
+		tokens []string
+		want   string
+	}{
+		{
+			name:   "fenced block styled",
+			tokens: []string{"Try:\n```go\nx := 1\n```\nDone"},
+			want:   "Try:\n" + style + "```go\nx := 1\n```" + codeReset + "\nDone",
+		},
+		{
+			name:   "fence split across tokens",
+			tokens: []string{"Try:\n`", "``", "go\nx\n``", "`\nDone"},
+			want:   "Try:\n" + style + "```go\nx\n```" + codeReset + "\nDone",
+		},
+		{
+			name:   "inline backticks untouched",
+			tokens: []string{"use `x` here\n`y` too"},
+			want:   "use `x` here\n`y` too",
+		},
+		{
+			name:   "unterminated block reset on flush",
+			tokens: []string{"```\ncode"},
+			want:   style + "```\ncode" + codeReset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, true)
+			w.SetCodeStyle(style)
+
+			for _, token := range tt.tokens {
+				if err := w.Write(token); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			w.Flush()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// countingWriter counts writes, standing in for syscalls to a terminal.
+// It is safe for the writes of a flush timer.
+type countingWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+func TestWriter_Coalesces(t *testing.T) {
+	var out countingWriter
+	w := NewWriter(&out, true)
+	w.flushInterval = time.Hour
+
+	for _, token := range []string{"The", " quick", " brown", " fox"} {
+		_ = w.Write(token)
+	}
+	// The first token after a pause is written at once
+	if got := out.String(); got != "The" {
+		t.Errorf("before a newline = %q, want %q", got, "The")
+	}
+
+	_ = w.Write(" jumps.\nOver")
+	if got := out.String(); got != "The quick brown fox jumps.\nOver" {
+		t.Errorf("after a newline = %q", got)
+	}
+
+	_ = w.Write(" the dog")
+	w.Flush()
+	if got := out.String(); got != "The quick brown fox jumps.\nOver the dog" {
+		t.Errorf("after Flush = %q", got)
+	}
+	if out.writes != 3 {
+		t.Errorf("%d writes, want 3", out.writes)
+	}
+}
+
+func TestWriter_CoalesceTimer(t *testing.T) {
+	var out countingWriter
+	w := NewWriter(&out, true)
+	w.flushInterval = 5 * time.Millisecond
+
+	_ = w.Write("Hello")
+	_ = w.Write(", world")
+	deadline := time.Now().Add(time.Second)
+	for out.String() != "Hello, world" {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending output not written by the timer: %q", out.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	w.Flush()
+}
+
+func TestWriter_CoalesceLimit(t *testing.T) {
+	var out countingWriter
+	w := NewWriter(&out, true)
+	w.flushInterval = time.Hour
+
+	_ = w.Write("x")
+	long := strings.Repeat("y", maxPending)
+	_ = w.Write(long)
+	if got := out.String(); got != "x"+long {
+		t.Errorf("full pending output not written: %d bytes of %d", len(got), len(long)+1)
+	}
+}
+
+// benchmarkTokens is a response as a fast model streams it: short tokens,
+// with a newline now and then.
+func benchmarkTokens() []string {
+	var tokens []string
+	for i := range 2000 {
+		switch {
+		case i%40 == 39:
+			tokens = append(tokens, ".\n")
+		case i%2 == 0:
+			tokens = append(tokens, " the")
+		default:
+			tokens = append(tokens, " stream")
+		}
+	}
+	return tokens
+}
+
+func BenchmarkWriter(b *testing.B) {
+	tokens := benchmarkTokens()
+	for _, bm := range []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"unbuffered", 0},
+		{"coalesced", defaultFlushInterval},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			var writes int
+			for b.Loop() {
+				var out countingWriter
+				w := NewWriter(&out, true)
+				w.width = 80
+				w.flushInterval = bm.interval
+				for _, token := range tokens {
+					_ = w.Write(token)
+				}
+				w.Flush()
+				writes += out.writes
+			}
+			b.ReportMetric(float64(writes)/float64(b.N), "writes/op")
+		})
+	}
+}
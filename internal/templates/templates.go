@@ -0,0 +1,72 @@
+// Package templates stores named prompt templates, one file per template
+// in a directory, so repeated prompts can be reused by name.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Ext is the extension of template files.
+const Ext = ".md"
+
+// ErrNotFound is returned for a template that does not exist.
+var ErrNotFound = errors.New("template not found")
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidName reports whether name can name a template: letters, digits,
+// and "_", "-", or ".", not starting with punctuation.
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// Path returns the file of the named template in dir.
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+Ext)
+}
+
+// Load returns the text of the named template in dir, without trailing
+// whitespace. It wraps ErrNotFound if there is no such template.
+func Load(dir, name string) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("invalid template name %q", name)
+	}
+	data, err := os.ReadFile(Path(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), " \t\r\n"), nil
+}
+
+// List returns the names of the templates in dir, sorted. A missing dir
+// has none.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), Ext)
+		if !ok || e.IsDir() || !ValidName(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"weekly-summary", true},
+		{"v1.2_notes", true},
+		{"", false},
+		{"-flag", false},
+		{".hidden", false},
+		{"a/b", false},
+		{"two words", false},
+	}
+	for _, tt := range tests {
+		if got := ValidName(tt.name); got != tt.want {
+			t.Errorf("ValidName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "summary.md"), []byte("Summarize this week.\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Load(dir, "summary")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got != "Summarize this week." {
+		t.Errorf("Load() = %q", got)
+	}
+
+	if _, err := Load(dir, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load(missing) error = %v, want ErrNotFound", err)
+	}
+	if _, err := Load(dir, "../summary"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Load(../summary) error = %v, want invalid name", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.md", "a.md", "notes.txt", ".hidden.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.md"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+
+	got, err = List(filepath.Join(dir, "missing"))
+	if err != nil || got != nil {
+		t.Errorf("List(missing) = %v, %v; want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,76 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Var is a variable in a template, written {{name}}, or {{name=default}}
+// to give a default value that makes it optional.
+type Var struct {
+	Name     string
+	Default  string
+	Required bool
+}
+
+// varPattern matches variable placeholders. Anything else in double
+// braces, such as {{.Field}}, is left as it is.
+var varPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(?:=([^{}]*))?\}\}`)
+
+// Vars returns the variables of text in order of first use. A variable
+// with a default anywhere is optional.
+func Vars(text string) []Var {
+	var vars []Var
+	index := make(map[string]int)
+	for _, m := range varPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[2]:m[3]]
+		hasDefault := m[4] >= 0
+		v := Var{Name: name, Required: !hasDefault}
+		if hasDefault {
+			v.Default = strings.TrimSpace(text[m[4]:m[5]])
+		}
+
+		i, seen := index[name]
+		if !seen {
+			index[name] = len(vars)
+			vars = append(vars, v)
+			continue
+		}
+		if hasDefault && vars[i].Required {
+			vars[i] = v
+		}
+	}
+	return vars
+}
+
+// Missing returns the names of text's required variables that values
+// lacks.
+func Missing(text string, values map[string]string) []string {
+	var missing []string
+	for _, v := range Vars(text) {
+		if _, ok := values[v.Name]; !ok && v.Required {
+			missing = append(missing, v.Name)
+		}
+	}
+	return missing
+}
+
+// Fill replaces text's variables with values, or their defaults. It
+// fails if a required variable has no value.
+func Fill(text string, values map[string]string) (string, error) {
+	if missing := Missing(text, values); len(missing) > 0 {
+		return "", fmt.Errorf("missing template variables: %s", strings.Join(missing, ", "))
+	}
+	defaults := make(map[string]string)
+	for _, v := range Vars(text) {
+		defaults[v.Name] = v.Default
+	}
+	return varPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := varPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return defaults[name]
+	}), nil
+}
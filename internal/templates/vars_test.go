@@ -0,0 +1,88 @@
+package templates
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestVars(t *testing.T) {
+	text := "Summarize {{ project }} for {{audience=the team}}.\n{{project}} {{audience}} {{.Field}} {{1bad}} {{tone=}}"
+	want := []Var{
+		{Name: "project", Required: true},
+		{Name: "audience", Default: "the team"},
+		{Name: "tone"},
+	}
+	if got := Vars(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("Vars() = %+v, want %+v", got, want)
+	}
+
+	// A default given on a later use still makes the variable optional
+	if got := Vars("{{a}} {{a=x}}"); !reflect.DeepEqual(got, []Var{{Name: "a", Default: "x"}}) {
+		t.Errorf("Vars() = %+v", got)
+	}
+	if got := Vars("no variables {here}"); got != nil {
+		t.Errorf("Vars() = %+v, want none", got)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	text := "{{a}} {{b}} {{c=3}}"
+	if got := Missing(text, map[string]string{"b": ""}); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("Missing() = %v, want [a]", got)
+	}
+	if got := Missing(text, map[string]string{"a": "1", "b": "2"}); got != nil {
+		t.Errorf("Missing() = %v, want none", got)
+	}
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		values  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "values and defaults",
+			text:   "Review {{ file }} for {{focus=bugs}}; {{file}} again. Keep {{.Field}}.",
+			values: map[string]string{"file": "main.go"},
+			want:   "Review main.go for bugs; main.go again. Keep {{.Field}}.",
+		},
+		{
+			name:   "value overrides default",
+			text:   "{{focus=bugs}}",
+			values: map[string]string{"focus": "style"},
+			want:   "style",
+		},
+		{
+			name:   "empty value",
+			text:   "[{{a}}]",
+			values: map[string]string{"a": ""},
+			want:   "[]",
+		},
+		{
+			name:    "missing required",
+			text:    "{{a}} {{b}}",
+			values:  map[string]string{"b": "x"},
+			wantErr: true,
+		},
+		{
+			name: "no variables",
+			text: "plain {stdin}",
+			want: "plain {stdin}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fill(tt.text, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fill() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Fill() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package theme
+
+import "os"
+
+// EnableVirtualTerminal reports true: terminals outside Windows process
+// ANSI escape sequences without being asked.
+func EnableVirtualTerminal(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,196 @@
+// Package theme resolves color configuration into terminal styles.
+package theme
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/devaloi/ask/internal/config"
+	"github.com/devaloi/ask/internal/stream"
+)
+
+// Reset is the SGR sequence that clears all styling.
+const Reset = "\x1b[0m"
+
+// Style is an ANSI SGR escape sequence, or empty for unstyled text.
+type Style string
+
+// Render wraps text in the style, resetting afterwards.
+func (s Style) Render(text string) string {
+	if s == "" {
+		return text
+	}
+	return string(s) + text + Reset
+}
+
+// Theme holds the styles for each colored element.
+type Theme struct {
+	User      Style // "You" role labels
+	Assistant Style // "Assistant" role labels
+	Code      Style // fenced code blocks in responses
+	Error     Style // error messages
+	Prompt    Style // the interactive prompt character
+	Separator Style // the rule between interactive turns
+}
+
+// Streams returns the styles for a stream.Writer's output around
+// responses.
+func (t Theme) Streams() stream.Styles {
+	return stream.Styles{
+		Prefix:    string(t.Assistant),
+		Error:     string(t.Error),
+		Separator: string(t.Separator),
+	}
+}
+
+// presets are the built-in themes, in color spec syntax.
+var presets = map[string]config.Theme{
+	"dark": {
+		User:      "bold cyan",
+		Assistant: "bold green",
+		Code:      "yellow",
+		Error:     "bold red",
+		Prompt:    "bold magenta",
+		Separator: "dim",
+	},
+	"light": {
+		User:      "bold blue",
+		Assistant: "bold green",
+		Code:      "magenta",
+		Error:     "bold red",
+		Prompt:    "bold blue",
+		Separator: "dim",
+	},
+	"none": {},
+}
+
+// New resolves cfg into a Theme. Fields left empty in cfg fall back to
+// its preset (dark by default). If color is false, the theme is empty.
+func New(cfg config.Theme, color bool) (Theme, error) {
+	if !color {
+		return Theme{}, nil
+	}
+
+	name := cfg.Preset
+	if name == "" {
+		name = "dark"
+	}
+	preset, ok := presets[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme preset: %s (available: dark, light, none)", name)
+	}
+
+	var t Theme
+	for _, field := range []struct {
+		dst    *Style
+		spec   string
+		preset string
+	}{
+		{&t.User, cfg.User, preset.User},
+		{&t.Assistant, cfg.Assistant, preset.Assistant},
+		{&t.Code, cfg.Code, preset.Code},
+		{&t.Error, cfg.Error, preset.Error},
+		{&t.Prompt, cfg.Prompt, preset.Prompt},
+		{&t.Separator, cfg.Separator, preset.Separator},
+	} {
+		spec := field.spec
+		if spec == "" {
+			spec = field.preset
+		}
+		style, err := Parse(spec)
+		if err != nil {
+			return Theme{}, err
+		}
+		*field.dst = style
+	}
+
+	return t, nil
+}
+
+// ColorEnabled reports whether colored output should be written to f:
+// it must be a terminal that processes escape sequences, and NO_COLOR
+// (https://no-color.org) must be unset.
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd())) && EnableVirtualTerminal(f)
+}
+
+// attributes maps attribute names to SGR parameters.
+var attributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+}
+
+// colors maps the basic color names to their foreground SGR parameters.
+var colors = map[string]int{
+	"black":   30,
+	"red":     31,
+	"green":   32,
+	"yellow":  33,
+	"blue":    34,
+	"magenta": 35,
+	"cyan":    36,
+	"white":   37,
+	"default": 39,
+}
+
+// Parse converts a color spec into a Style. A spec is a space-separated
+// list of attributes (bold, dim, italic, underline) and at most one
+// color: a name such as "cyan" or "bright-cyan", "gray", a 256-color
+// index such as "208", or a hex RGB value such as "#ff8800". An empty
+// spec or "none" yields no style.
+func Parse(spec string) (Style, error) {
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	if spec == "" || spec == "none" {
+		return "", nil
+	}
+
+	var params []string
+	for _, word := range strings.Fields(spec) {
+		if p, ok := attributes[word]; ok {
+			params = append(params, p)
+			continue
+		}
+
+		p, err := parseColor(word)
+		if err != nil {
+			return "", fmt.Errorf("invalid color %q in %q", word, spec)
+		}
+		params = append(params, p)
+	}
+
+	return Style("\x1b[" + strings.Join(params, ";") + "m"), nil
+}
+
+// parseColor converts a single color word into SGR parameters.
+func parseColor(word string) (string, error) {
+	if code, ok := colors[word]; ok {
+		return strconv.Itoa(code), nil
+	}
+	if word == "gray" || word == "grey" {
+		return "90", nil
+	}
+	if name, ok := strings.CutPrefix(word, "bright-"); ok {
+		if code, ok := colors[name]; ok && name != "default" {
+			return strconv.Itoa(code + 60), nil
+		}
+	}
+	if n, err := strconv.Atoi(word); err == nil && n >= 0 && n <= 255 {
+		return "38;5;" + word, nil
+	}
+	if hex, ok := strings.CutPrefix(word, "#"); ok && len(hex) == 6 {
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err == nil {
+			return fmt.Sprintf("38;2;%d;%d;%d", rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+		}
+	}
+	return "", fmt.Errorf("unknown color %q", word)
+}
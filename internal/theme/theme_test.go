@@ -0,0 +1,115 @@
+package theme
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/devaloi/ask/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Style
+		wantErr bool
+	}{
+		{spec: "", want: ""},
+		{spec: "none", want: ""},
+		{spec: "red", want: "\x1b[31m"},
+		{spec: "bold cyan", want: "\x1b[1;36m"},
+		{spec: "Bright-Blue", want: "\x1b[94m"},
+		{spec: "gray", want: "\x1b[90m"},
+		{spec: "208", want: "\x1b[38;5;208m"},
+		{spec: "italic #ff8800", want: "\x1b[3;38;2;255;136;0m"},
+		{spec: "chartreuse", wantErr: true},
+		{spec: "256", wantErr: true},
+		{spec: "#ff88", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("default preset is dark", func(t *testing.T) {
+		th, err := New(config.Theme{}, true)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		want, _ := Parse(presets["dark"].Error)
+		if th.Error != want {
+			t.Errorf("Error style = %q, want %q", th.Error, want)
+		}
+	})
+
+	t.Run("overrides apply on top of preset", func(t *testing.T) {
+		th, err := New(config.Theme{Preset: "light", Code: "green"}, true)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if th.Code != "\x1b[32m" {
+			t.Errorf("Code style = %q, want green", th.Code)
+		}
+		want, _ := Parse(presets["light"].Prompt)
+		if th.Prompt != want {
+			t.Errorf("Prompt style = %q, want light preset %q", th.Prompt, want)
+		}
+	})
+
+	t.Run("none preset", func(t *testing.T) {
+		th, err := New(config.Theme{Preset: "none"}, true)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if th != (Theme{}) {
+			t.Errorf("none preset = %+v, want empty theme", th)
+		}
+	})
+
+	t.Run("color disabled", func(t *testing.T) {
+		th, err := New(config.Theme{Code: "not-a-color"}, false)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if th != (Theme{}) {
+			t.Errorf("disabled theme = %+v, want empty theme", th)
+		}
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		if _, err := New(config.Theme{Preset: "neon"}, true); err == nil {
+			t.Error("New() expected error for unknown preset")
+		}
+	})
+
+	t.Run("invalid color", func(t *testing.T) {
+		_, err := New(config.Theme{User: "bold plaid"}, true)
+		if err == nil || !strings.Contains(err.Error(), "plaid") {
+			t.Errorf("New() error = %v, want invalid color error", err)
+		}
+	})
+}
+
+func TestColorEnabled_NoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(nil) {
+		t.Error("ColorEnabled() = true with NO_COLOR set")
+	}
+}
+
+func TestStyle_Render(t *testing.T) {
+	if got := Style("").Render("x"); got != "x" {
+		t.Errorf("empty style Render = %q, want %q", got, "x")
+	}
+	if got := Style("\x1b[31m").Render("x"); got != "\x1b[31mx"+Reset {
+		t.Errorf("Render = %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Encoding is a byte-level BPE tokenizer compatible with tiktoken, such
+// as OpenAI's cl100k_base and o200k_base.
+type Encoding struct {
+	name  string
+	ranks map[string]int
+	split func(text string) []string
+}
+
+// NewEncoding returns an Encoding called name, with merge ranks read
+// from r in the .tiktoken format: one base64 token and its rank per
+// line. split is the encoding's pre-tokenizer, SplitCL100K or
+// SplitO200K.
+func NewEncoding(name string, r io.Reader, split func(text string) []string) (*Encoding, error) {
+	ranks := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: want a token and its rank", name, line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+		ranks[string(b)] = n
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("%s has no tokens", name)
+	}
+	return &Encoding{name: name, ranks: ranks, split: split}, nil
+}
+
+// Name returns the encoding's name, such as "o200k_base".
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Count returns the number of tokens text encodes to, treating any
+// special tokens in it as ordinary text.
+func (e *Encoding) Count(text string) int {
+	n := 0
+	for _, piece := range e.split(text) {
+		if _, ok := e.ranks[piece]; ok {
+			n++
+			continue
+		}
+		n += e.mergeCount(piece)
+	}
+	return n
+}
+
+// mergeCount returns the number of tokens piece is merged into: starting
+// from single bytes, the adjacent pair with the lowest rank is merged
+// until no pair is in the vocabulary.
+func (e *Encoding) mergeCount(piece string) int {
+	// bounds[i] is where part i starts; the last entry ends the piece
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	rank := func(i int) int {
+		if i+2 >= len(bounds) {
+			return math.MaxInt
+		}
+		if r, ok := e.ranks[piece[bounds[i]:bounds[i+2]]]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+
+	ranks := make([]int, len(bounds)-1)
+	for i := range ranks {
+		ranks[i] = rank(i)
+	}
+	for len(ranks) > 1 {
+		best := 0
+		for i, r := range ranks {
+			if r < ranks[best] {
+				best = i
+			}
+		}
+		if ranks[best] == math.MaxInt {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+		ranks = append(ranks[:best], ranks[best+1:]...)
+		ranks[best] = rank(best)
+		if best > 0 {
+			ranks[best-1] = rank(best - 1)
+		}
+	}
+	return len(bounds) - 1
+}
@@ -0,0 +1,177 @@
+package tokens
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// vocab returns merge ranks for every byte, then for each of merges in
+// order, in the .tiktoken format.
+func vocab(merges ...string) string {
+	var b strings.Builder
+	rank := 0
+	add := func(token string) {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+		rank++
+	}
+	for c := range 256 {
+		add(string([]byte{byte(c)}))
+	}
+	for _, m := range merges {
+		add(m)
+	}
+	return b.String()
+}
+
+func TestEncoding_Count(t *testing.T) {
+	enc, err := NewEncoding("test", strings.NewReader(vocab("ll", "he", "hell", "hello", " w", " wor", "or")), SplitCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"hello world", 4}, // hello, " wor" l d
+		{"hell", 1},
+		{"help", 3}, // he l p
+		{"日", 3},    // one token per byte
+	}
+	for _, tt := range tests {
+		if got := enc.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncoding_LowestRankFirst(t *testing.T) {
+	// "bc" ranks before "ab" and "cd", so "abcd" is a, bc, d, never ab, cd
+	enc, err := NewEncoding("test", strings.NewReader(vocab("bc", "ab", "cd")), SplitCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := enc.Count("abcd"); got != 3 {
+		t.Errorf(`Count("abcd") = %d, want 3 (a, bc, d)`, got)
+	}
+}
+
+func TestNewEncoding_Invalid(t *testing.T) {
+	for _, data := range []string{"", "aGk=\n", "!!! 1\n", "aGk= x\n"} {
+		if _, err := NewEncoding("test", strings.NewReader(data), SplitCL100K); err == nil {
+			t.Errorf("NewEncoding(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestSplitCL100K(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello world", []string{"hello", " world"}},
+		{"I'm here", []string{"I", "'m", " here"}},
+		{"don't", []string{"don", "'t"}},
+		{"HelloWorld", []string{"HelloWorld"}},
+		{"1234567", []string{"123", "456", "7"}},
+		{"  indented", []string{" ", " indented"}},
+		{"a\n\nb", []string{"a", "\n\n", "b"}},
+		{"x = 1;\n", []string{"x", " =", " ", "1", ";\n"}},
+		{"end  ", []string{"end", "  "}},
+		{"\n  \nx", []string{"\n  \n", "x"}},
+		{"?\n/", []string{"?\n", "/"}},
+	}
+	for _, tt := range tests {
+		if got := SplitCL100K(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitCL100K(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSplitO200K(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"hello world", []string{"hello", " world"}},
+		{"don't", []string{"don't"}},
+		{"HelloWorld", []string{"Hello", "World"}},
+		{"HTTPServer", []string{"HTTPServer"}},
+		{"ABC", []string{"ABC"}},
+		{"1234567", []string{"123", "456", "7"}},
+		{"a/b", []string{"a", "/b"}},
+		{"?\n/", []string{"?\n/"}},
+		{"x = 1;\n", []string{"x", " =", " ", "1", ";\n"}},
+	}
+	for _, tt := range tests {
+		if got := SplitO200K(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitO200K(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingFor(t *testing.T) {
+	tests := map[string]string{
+		"gpt-4o-mini":       O200K,
+		"gpt-4.1":           O200K,
+		"o3-mini":           O200K,
+		"gpt-4-turbo":       CL100K,
+		"gpt-3.5-turbo":     CL100K,
+		"claude-sonnet-4-0": "",
+		"llama3":            "",
+	}
+	for model, want := range tests {
+		if got := EncodingFor(model); got != want {
+			t.Errorf("EncodingFor(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	data := vocab("he", "ll", "hell", "hello")
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		fmt.Fprint(w, data)
+	}))
+	defer srv.Close()
+
+	vocabularies["test"] = vocabulary{url: srv.URL, sha256: checksum([]byte(data)), split: SplitCL100K}
+	vocabularies["tampered"] = vocabulary{url: srv.URL, sha256: checksum([]byte("other")), split: SplitCL100K}
+	defer delete(vocabularies, "test")
+	defer delete(vocabularies, "tampered")
+
+	dir := t.TempDir()
+	for range 2 {
+		enc, err := Load(context.Background(), "test", dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := enc.Count("hello"); got != 1 {
+			t.Errorf(`Count("hello") = %d, want 1`, got)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("downloaded %d times, want once, then read from the cache", downloads)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.tiktoken")); err != nil {
+		t.Errorf("vocabulary not cached: %v", err)
+	}
+
+	if _, err := Load(context.Background(), "tampered", dir); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Load() of a tampered vocabulary: err = %v, want a checksum mismatch", err)
+	}
+	if _, err := Load(context.Background(), "p50k_base", dir); err == nil {
+		t.Error("Load() of an unknown encoding succeeded")
+	}
+}
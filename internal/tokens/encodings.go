@@ -0,0 +1,110 @@
+package tokens
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Names of the OpenAI encodings.
+const (
+	CL100K = "cl100k_base"
+	O200K  = "o200k_base"
+)
+
+// vocabulary is where an encoding's merge ranks are published, as
+// tiktoken downloads them, and their SHA-256.
+type vocabulary struct {
+	url    string
+	sha256 string
+	split  func(text string) []string
+}
+
+var vocabularies = map[string]vocabulary{
+	CL100K: {
+		url:    "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		sha256: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+		split:  SplitCL100K,
+	},
+	O200K: {
+		url:    "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		sha256: "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+		split:  SplitO200K,
+	},
+}
+
+// EncodingFor returns the name of the encoding OpenAI's model uses, or
+// "" if it is not an OpenAI model ask knows the encoding of.
+func EncodingFor(model string) string {
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o"} {
+		if strings.HasPrefix(model, prefix) {
+			return O200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "text-embedding-3", "text-embedding-ada-002"} {
+		if strings.HasPrefix(model, prefix) {
+			return CL100K
+		}
+	}
+	return ""
+}
+
+// Load returns the named encoding, reading its vocabulary from dir, or
+// downloading it there the first time. A vocabulary whose checksum does
+// not match is not used.
+func Load(ctx context.Context, name, dir string) (*Encoding, error) {
+	v, ok := vocabularies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	path := filepath.Join(dir, name+".tiktoken")
+	data, err := os.ReadFile(path)
+	if err != nil || checksum(data) != v.sha256 {
+		if data, err = download(ctx, v.url); err != nil {
+			return nil, fmt.Errorf("downloading the %s vocabulary: %w", name, err)
+		}
+		if sum := checksum(data); sum != v.sha256 {
+			return nil, fmt.Errorf("checksum mismatch for the %s vocabulary: got %s, want %s", name, sum, v.sha256)
+		}
+		// The vocabulary is only cached; failing to keep it is not fatal
+		if err := os.MkdirAll(dir, 0755); err == nil {
+			_ = os.WriteFile(path, data, 0644)
+		}
+	}
+	return NewEncoding(name, bytes.NewReader(data), v.split)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// download returns the body of url.
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
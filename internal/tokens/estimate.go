@@ -0,0 +1,103 @@
+// Package tokens counts tokens in text: exactly with an OpenAI BPE
+// encoding, or estimated without a tokenizer vocabulary.
+package tokens
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Estimate returns an approximate token count for text.
+//
+// It mirrors the pre-tokenization step of tiktoken's cl100k/o200k
+// encodings: text is split into letter runs, digit groups of up to
+// three, punctuation runs, and whitespace, and each piece is charged
+// what BPE typically spends on it. Most English words are a single
+// token; non-ASCII letters are charged per rune since BPE rarely merges
+// them. The result errs on the high side, which is the safe direction
+// when checking a prompt against a context window.
+func Estimate(text string) int {
+	count := 0
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case unicode.IsLetter(r):
+			// A letter run; the preceding space is merged into the word
+			ascii := 0
+			nonASCII := 0
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsLetter(r) && !unicode.IsMark(r) {
+					break
+				}
+				if r < utf8.RuneSelf {
+					ascii++
+				} else {
+					nonASCII++
+				}
+				i += size
+			}
+			count += ceilDiv(ascii, 6) + nonASCII
+
+		case unicode.IsDigit(r):
+			digits := 0
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsDigit(r) {
+					break
+				}
+				digits++
+				i += size
+			}
+			count += ceilDiv(digits, 3)
+
+		case unicode.IsSpace(r):
+			// Single spaces attach to the following word; longer runs
+			// such as indentation are encoded in chunks
+			spaces := 0
+			newline := false
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				if r == '\n' {
+					newline = true
+				}
+				spaces++
+				i += size
+			}
+			switch {
+			case newline:
+				count += ceilDiv(spaces, 4)
+			case spaces > 1:
+				count += ceilDiv(spaces-1, 4)
+			}
+
+		default:
+			// Punctuation and symbols: runs are usually merged in pairs
+			puncts := 0
+			for i < len(text) {
+				r, size = utf8.DecodeRuneInString(text[i:])
+				if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+					break
+				}
+				if r < utf8.RuneSelf {
+					puncts++
+				} else {
+					puncts += 2
+				}
+				i += size
+			}
+			count += ceilDiv(puncts, 2)
+		}
+	}
+
+	return count
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
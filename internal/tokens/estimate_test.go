@@ -0,0 +1,41 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "single word", text: "hello", want: 1},
+		{name: "words", text: "hello world", want: 2},
+		{name: "long word", text: "internationalization", want: 4},
+		{name: "digits grouped by three", text: "1234567", want: 3},
+		{name: "punctuation", text: "a, b.", want: 4},
+		{name: "newline", text: "a\nb", want: 3},
+		{name: "non-ascii letters", text: "日本語", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Estimate(tt.text); got != tt.want {
+				t.Errorf("Estimate(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimate_Scales(t *testing.T) {
+	line := "The quick brown fox jumps over the lazy dog.\n"
+	one := Estimate(line)
+	many := Estimate(strings.Repeat(line, 100))
+
+	if many < 90*one || many > 110*one {
+		t.Errorf("Estimate of 100 lines = %d, want about %d", many, 100*one)
+	}
+}
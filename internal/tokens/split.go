@@ -0,0 +1,199 @@
+package tokens
+
+import (
+	"unicode"
+)
+
+// The pre-tokenizers below split text the way the regular expressions
+// of tiktoken's encodings do. Go's regexp has no lookahead, which both
+// patterns use, so each alternative is matched by hand, in order, with
+// the same greedy backtracking.
+
+// SplitCL100K splits text into the pieces cl100k_base encodes
+// separately.
+func SplitCL100K(text string) []string {
+	return splitWith(text, func(rs []rune, i int) int {
+		if n := contraction(rs, i); n > 0 {
+			return n
+		}
+		// [^\r\n\p{L}\p{N}]?\p{L}+
+		if isPrefix(rs, i) && i+1 < len(rs) && unicode.IsLetter(rs[i+1]) {
+			return 1 + runLength(rs, i+1, unicode.IsLetter)
+		}
+		if unicode.IsLetter(rs[i]) {
+			return runLength(rs, i, unicode.IsLetter)
+		}
+		if n := numbers(rs, i); n > 0 {
+			return n
+		}
+		if n := punctuation(rs, i, isNewline); n > 0 {
+			return n
+		}
+		return whitespace(rs, i)
+	})
+}
+
+// SplitO200K splits text into the pieces o200k_base encodes separately.
+func SplitO200K(text string) []string {
+	return splitWith(text, func(rs []rune, i int) int {
+		// [^\r\n\p{L}\p{N}]?[upper]*[lower]+(contraction)?
+		if isPrefix(rs, i) {
+			if n := lowerWord(rs, i+1); n > 0 {
+				return 1 + n
+			}
+		}
+		if n := lowerWord(rs, i); n > 0 {
+			return n
+		}
+		// [^\r\n\p{L}\p{N}]?[upper]+[lower]*(contraction)?
+		if isPrefix(rs, i) {
+			if n := upperWord(rs, i+1); n > 0 {
+				return 1 + n
+			}
+		}
+		if n := upperWord(rs, i); n > 0 {
+			return n
+		}
+		if n := numbers(rs, i); n > 0 {
+			return n
+		}
+		if n := punctuation(rs, i, func(r rune) bool { return isNewline(r) || r == '/' }); n > 0 {
+			return n
+		}
+		return whitespace(rs, i)
+	})
+}
+
+// splitWith splits text into pieces, each as long as match says the
+// piece starting at rune i is.
+func splitWith(text string, match func(rs []rune, i int) int) []string {
+	rs := []rune(text)
+	var pieces []string
+	for i := 0; i < len(rs); {
+		n := max(match(rs, i), 1)
+		pieces = append(pieces, string(rs[i:i+n]))
+		i += n
+	}
+	return pieces
+}
+
+// contraction matches (?i:'s|'t|'re|'ve|'m|'ll|'d) at i.
+func contraction(rs []rune, i int) int {
+	if i >= len(rs) || rs[i] != '\'' {
+		return 0
+	}
+	for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		n := len(suffix)
+		if i+1+n > len(rs) {
+			continue
+		}
+		matched := true
+		for j, c := range suffix {
+			if unicode.ToLower(rs[i+1+j]) != c {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return 1 + n
+		}
+	}
+	return 0
+}
+
+// lowerWord matches [upper]*[lower]+(contraction)? at i, where upper is
+// [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}] and lower [\p{Ll}\p{Lm}\p{Lo}\p{M}].
+func lowerWord(rs []rune, i int) int {
+	end := i + runLength(rs, i, isUpper)
+	// Give back upper runes until a lower run follows
+	for k := end; k >= i; k-- {
+		if k < len(rs) && isLower(rs[k]) {
+			k += runLength(rs, k, isLower)
+			return k - i + contraction(rs, k)
+		}
+	}
+	return 0
+}
+
+// upperWord matches [upper]+[lower]*(contraction)? at i.
+func upperWord(rs []rune, i int) int {
+	n := runLength(rs, i, isUpper)
+	if n == 0 {
+		return 0
+	}
+	k := i + n
+	k += runLength(rs, k, isLower)
+	return k - i + contraction(rs, k)
+}
+
+// numbers matches \p{N}{1,3} at i.
+func numbers(rs []rune, i int) int {
+	return min(runLength(rs, i, unicode.IsNumber), 3)
+}
+
+// punctuation matches ` ?[^\s\p{L}\p{N}]+` at i, followed by any runes
+// trailing says end it.
+func punctuation(rs []rune, i int, trailing func(rune) bool) int {
+	k := i
+	if rs[k] == ' ' && k+1 < len(rs) && isSymbol(rs[k+1]) {
+		k++
+	}
+	n := runLength(rs, k, isSymbol)
+	if n == 0 {
+		return 0
+	}
+	k += n
+	k += runLength(rs, k, trailing)
+	return k - i
+}
+
+// whitespace matches \s*[\r\n]+|\s+(?!\S)|\s+ at i.
+func whitespace(rs []rune, i int) int {
+	n := runLength(rs, i, unicode.IsSpace)
+	if n == 0 {
+		return 0
+	}
+	// \s*[\r\n]+ ends at the last newline in the run
+	for k := i + n - 1; k >= i; k-- {
+		if isNewline(rs[k]) {
+			return k + 1 - i
+		}
+	}
+	// \s+(?!\S) leaves the last space to start the next word
+	if i+n < len(rs) && n > 1 {
+		return n - 1
+	}
+	return n
+}
+
+// runLength returns how many runes from i satisfy f.
+func runLength(rs []rune, i int, f func(rune) bool) int {
+	n := 0
+	for i+n < len(rs) && f(rs[i+n]) {
+		n++
+	}
+	return n
+}
+
+// isPrefix reports whether r matches [^\r\n\p{L}\p{N}], which may lead a
+// word.
+func isPrefix(r []rune, i int) bool {
+	return !isNewline(r[i]) && !unicode.IsLetter(r[i]) && !unicode.IsNumber(r[i])
+}
+
+// isSymbol reports whether r matches [^\s\p{L}\p{N}].
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+func isUpper(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLower(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
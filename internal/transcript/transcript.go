@@ -0,0 +1,73 @@
+// Package transcript appends plain-text records of chat exchanges to a
+// log file, independent of the history database.
+package transcript
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Exchange is a single prompt and its response.
+type Exchange struct {
+	Time     time.Time
+	Provider string
+	Model    string
+	Prompt   string
+	Response string
+}
+
+// Format writes e to w as a timestamped transcript entry.
+func Format(w io.Writer, e Exchange) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s/%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Provider, e.Model)
+	for _, line := range strings.Split(strings.TrimRight(e.Prompt, "\n"), "\n") {
+		fmt.Fprintf(&b, "> %s\n", line)
+	}
+	b.WriteString("\n")
+	b.WriteString(strings.TrimRight(e.Response, "\n"))
+	b.WriteString("\n\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Append adds e to the transcript file at path, creating the file and its
+// parent directories if needed. A leading "~/" is expanded to the user's
+// home directory.
+func Append(path string, e Exchange) error {
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	if err := Format(f, e); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write log file: %w", err)
+	}
+	return f.Close()
+}
+
+// expandHome replaces a leading "~/" in path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,79 @@
+package transcript
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := Format(&buf, Exchange{
+		Time:     time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Provider: "openai",
+		Model:    "gpt-4o",
+		Prompt:   "line one\nline two\n",
+		Response: "The answer.\n",
+	})
+	if err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	want := "[2026-01-02 15:04:05] openai/gpt-4o\n> line one\n> line two\n\nThe answer.\n\n"
+	if buf.String() != want {
+		t.Errorf("Format() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "ask.log")
+
+	for _, prompt := range []string{"first", "second"} {
+		err := Append(path, Exchange{
+			Time:     time.Now(),
+			Provider: "anthropic",
+			Model:    "claude-sonnet-4-20250514",
+			Prompt:   prompt,
+			Response: "ok",
+		})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading log: %v", err)
+	}
+
+	content := string(data)
+	if strings.Count(content, "anthropic/claude-sonnet-4-20250514") != 2 {
+		t.Errorf("expected two entries, got %q", content)
+	}
+	if strings.Index(content, "> first") > strings.Index(content, "> second") {
+		t.Errorf("entries out of order: %q", content)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	got, err := expandHome("~/notes/ask.log")
+	if err != nil {
+		t.Fatalf("expandHome() error = %v", err)
+	}
+	if want := filepath.Join(home, "notes", "ask.log"); got != want {
+		t.Errorf("expandHome() = %q, want %q", got, want)
+	}
+
+	got, _ = expandHome("/var/log/ask.log")
+	if got != "/var/log/ask.log" {
+		t.Errorf("expandHome() changed absolute path to %q", got)
+	}
+}
@@ -0,0 +1,252 @@
+// Package upgrade checks GitHub releases for a newer ask and replaces the
+// running binary with it.
+//
+// Releases are expected to carry one raw binary per platform, named by
+// AssetName, and a checksums.txt in sha256sum format covering them.
+package upgrade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBaseURL = "https://api.github.com"
+
+	// Repo is the GitHub repository releases are published to.
+	Repo = "devaloi/ask"
+
+	checksumsAsset = "checksums.txt"
+)
+
+// Release is a published GitHub release.
+type Release struct {
+	Version string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Checker queries a repository's releases.
+type Checker struct {
+	client  *http.Client
+	baseURL string
+	repo    string
+}
+
+// NewChecker creates a Checker for repo ("owner/name") on GitHub.
+func NewChecker(repo string) *Checker {
+	return NewCheckerWithBaseURL(repo, defaultBaseURL)
+}
+
+// NewCheckerWithBaseURL creates a Checker using a custom API base URL (for testing).
+func NewCheckerWithBaseURL(repo, baseURL string) *Checker {
+	return &Checker{
+		client:  &http.Client{},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		repo:    repo,
+	}
+}
+
+// Latest returns the most recent non-prerelease release.
+func (c *Checker) Latest(ctx context.Context) (*Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", c.baseURL, c.repo)
+	body, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("checking latest release: %w", err)
+	}
+
+	var rel Release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("failed to decode release: %w", err)
+	}
+	if rel.Version == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &rel, nil
+}
+
+// Download fetches the binary for goos/goarch from rel and verifies it
+// against the release's checksums.
+func (c *Checker) Download(ctx context.Context, rel *Release, goos, goarch string) ([]byte, error) {
+	name := AssetName(goos, goarch)
+	binary, ok := rel.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no binary for %s/%s (%s)", rel.Version, goos, goarch, name)
+	}
+	sums, ok := rel.asset(checksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified binary", rel.Version, checksumsAsset)
+	}
+
+	sumsData, err := c.get(ctx, sums.URL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", checksumsAsset, err)
+	}
+	want, err := findChecksum(sumsData, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.get(ctx, binary.URL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s: %w", name, err)
+	}
+	got := sha256.Sum256(data)
+	if hex.EncodeToString(got[:]) != want {
+		return nil, fmt.Errorf("checksum mismatch for %s: got %x, want %s", name, got, want)
+	}
+	return data, nil
+}
+
+// get performs a GET request and returns the response body.
+func (c *Checker) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// asset returns the asset called name.
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// AssetName returns the release asset name of the binary for goos/goarch.
+func AssetName(goos, goarch string) string {
+	name := fmt.Sprintf("ask_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// findChecksum returns the hex SHA-256 for name from sha256sum output.
+func findChecksum(sums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// "<hash>  <name>", or "<hash> *<name>" in binary mode
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, checksumsAsset)
+}
+
+// Newer reports whether version latest is newer than current. A current
+// version that is not a release (such as "dev") is always older.
+func Newer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return true
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "v1.2.3" (the "v" and any pre-release or build
+// suffix are optional) into its numeric parts.
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// Replace atomically replaces the executable at path with data, keeping
+// its permissions. The old binary is moved aside first so that a running
+// executable can be replaced on Windows too.
+func Replace(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ask-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing new binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	old := path + ".old"
+	if err := os.Rename(path, old); err != nil {
+		return fmt.Errorf("moving old binary aside: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		// Put the old binary back
+		_ = os.Rename(old, path)
+		return fmt.Errorf("installing new binary: %w", err)
+	}
+	// Fails on Windows while the old binary is running; it is harmless
+	_ = os.Remove(old)
+	return nil
+}
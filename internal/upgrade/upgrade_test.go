@@ -0,0 +1,137 @@
+package upgrade
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.2.0", "v1.10.0", false},
+		{"v2.0.0", "v1.99.99", true},
+		{"1.2.1", "v1.2.0", true},
+		{"v1.2.0", "dev", true},
+		{"v1.2.0", "v0.0.0-20261016075727-b600cb8feb32", true},
+		{"v1.2.0", "v1.2.0-rc.1", false},
+		{"nightly", "v1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	if got := AssetName("linux", "arm64"); got != "ask_linux_arm64" {
+		t.Errorf("AssetName(linux, arm64) = %q", got)
+	}
+	if got := AssetName("windows", "amd64"); got != "ask_windows_amd64.exe" {
+		t.Errorf("AssetName(windows, amd64) = %q", got)
+	}
+}
+
+// newReleaseServer serves a release containing binary for linux/amd64,
+// with checksums listing sum for it.
+func newReleaseServer(t *testing.T, binary []byte, sum string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/devaloi/ask/releases/latest":
+			fmt.Fprintf(w, `{"tag_name":"v1.2.0","assets":[
+				{"name":"ask_linux_amd64","browser_download_url":"%[1]s/dl/ask_linux_amd64"},
+				{"name":"checksums.txt","browser_download_url":"%[1]s/dl/checksums.txt"}]}`, server.URL)
+		case "/dl/ask_linux_amd64":
+			w.Write(binary)
+		case "/dl/checksums.txt":
+			fmt.Fprintf(w, "%s  ask_darwin_arm64\n%s  ask_linux_amd64\n", strings.Repeat("0", 64), sum)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestChecker_LatestAndDownload(t *testing.T) {
+	binary := []byte("new ask binary")
+	server := newReleaseServer(t, binary, fmt.Sprintf("%x", sha256.Sum256(binary)))
+	c := NewCheckerWithBaseURL(Repo, server.URL)
+
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if rel.Version != "v1.2.0" {
+		t.Errorf("Version = %q, want v1.2.0", rel.Version)
+	}
+
+	data, err := c.Download(context.Background(), rel, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if string(data) != string(binary) {
+		t.Errorf("Download() = %q, want %q", data, binary)
+	}
+
+	if _, err := c.Download(context.Background(), rel, "plan9", "386"); err == nil {
+		t.Error("Download() expected error for missing platform")
+	}
+}
+
+func TestChecker_DownloadChecksumMismatch(t *testing.T) {
+	server := newReleaseServer(t, []byte("tampered"), strings.Repeat("a", 64))
+	c := NewCheckerWithBaseURL(Repo, server.URL)
+
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	_, err = c.Download(context.Background(), rel, "linux", "amd64")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Download() error = %v, want checksum mismatch", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ask")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Replace(path, []byte("new")); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("binary = %q, want %q", data, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".old"); !os.IsNotExist(err) {
+		t.Errorf("old binary left behind: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package util
+
+import "strings"
+
+// Closest returns the candidate most like s, for "did you mean"
+// suggestions, or false if none is close enough to be a likely typo:
+// within a third of its length in single-character edits, ignoring case.
+func Closest(s string, candidates []string) (string, bool) {
+	s = strings.ToLower(s)
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := EditDistance(s, strings.ToLower(c))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if bestDist < 0 || bestDist > max(len([]rune(s))/3, 1) {
+		return "", false
+	}
+	return best, true
+}
+
+// EditDistance returns the Levenshtein distance between a and b: the
+// fewest characters inserted, deleted, or replaced to turn one into the
+// other.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,45 @@
+package util
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"defualt_model", "default_model", 2},
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"default_model", "default_provider", "default_system"}
+	tests := []struct {
+		s      string
+		want   string
+		wantOK bool
+	}{
+		{"defualt_model", "default_model", true},
+		{"Default_Provider", "default_provider", true},
+		{"default_sytem", "default_system", true},
+		{"pager", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Closest(tt.s, candidates)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Closest(%q) = %q, %v; want %q, %v", tt.s, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if _, ok := Closest("x", nil); ok {
+		t.Error("Closest with no candidates found one")
+	}
+}
@@ -7,4 +7,6 @@ const (
 	MaxTitleLength       = 50
 	MaxModelDisplay      = 21
 	MaxTitleDisplay      = 40
+	MaxPreviewDisplay    = 80
+	DefaultResumeLimit   = 50
 )
@@ -0,0 +1,73 @@
+package util
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1500", want: 1500},
+		{in: "64K", want: 64 << 10},
+		{in: "64kb", want: 64 << 10},
+		{in: "2MiB", want: 2 << 20},
+		{in: " 1g ", want: 1 << 30},
+		{in: "", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "12X", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseCount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "128000", want: 128000},
+		{in: "100k", want: 100000},
+		{in: "1.5M", want: 1500000},
+		{in: " 200 K ", want: 200000},
+		{in: "", wantErr: true},
+		{in: "-5k", wantErr: true},
+		{in: "1g", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCount(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCount(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:      "512 bytes",
+		1536:     "1.5 KiB",
+		50 << 20: "50.0 MiB",
+		3 << 30:  "3.0 GiB",
+	}
+	for in, want := range tests {
+		if got := FormatSize(in); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
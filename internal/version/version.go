@@ -0,0 +1,87 @@
+// Package version reports build metadata for the ask binary.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, injected at build time with
+//
+//	-ldflags "-X github.com/devaloi/ask/internal/version.Version=v1.2.3 ..."
+//
+// Unset values fall back to the module and VCS information Go embeds in
+// the binary.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the build metadata of the running binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fillFromBuildInfo(&info, bi)
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+// fillFromBuildInfo sets fields of info that are still empty from the
+// build information embedded by the go command.
+func fillFromBuildInfo(info *Info, bi *debug.BuildInfo) {
+	if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+
+	var modified bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = shortCommit(s.Value)
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if modified && info.Commit != "" && Commit == "" {
+		info.Commit += "-dirty"
+	}
+}
+
+// shortCommit abbreviates a full commit hash.
+func shortCommit(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,56 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestFillFromBuildInfo(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.time", Value: "2026-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	var info Info
+	fillFromBuildInfo(&info, bi)
+
+	if info.Version != "v1.4.0" {
+		t.Errorf("Version = %q, want %q", info.Version, "v1.4.0")
+	}
+	if info.Commit != "0123456789ab-dirty" {
+		t.Errorf("Commit = %q, want %q", info.Commit, "0123456789ab-dirty")
+	}
+	if info.Date != "2026-01-02T03:04:05Z" {
+		t.Errorf("Date = %q", info.Date)
+	}
+}
+
+func TestFillFromBuildInfo_KeepsInjectedValues(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+		},
+	}
+
+	info := Info{Version: "v2.0.0", Commit: "abc1234"}
+	fillFromBuildInfo(&info, bi)
+
+	if info.Version != "v2.0.0" || info.Commit != "abc1234" {
+		t.Errorf("injected values overwritten: %+v", info)
+	}
+}
+
+func TestGet_Defaults(t *testing.T) {
+	info := Get()
+	if info.Version == "" || info.Commit == "" || info.Date == "" {
+		t.Errorf("Get() left empty fields: %+v", info)
+	}
+	if info.GoVersion == "" || info.Platform == "" {
+		t.Errorf("Get() missing runtime info: %+v", info)
+	}
+}
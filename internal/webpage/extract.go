@@ -0,0 +1,301 @@
+package webpage
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// skipped are elements left out with everything in them: code, styling,
+// and page furniture such as navigation rather than content.
+var skipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "canvas": true, "iframe": true, "object": true,
+	"head": true, "title": true, "nav": true, "footer": true, "aside": true,
+	"form": true, "button": true, "select": true, "dialog": true,
+}
+
+// rawText are skipped elements whose content is not markup, so may hold
+// anything but their end tag.
+var rawText = map[string]bool{"script": true, "style": true}
+
+// breaks are elements that start and end on a line of their own, by how
+// many newlines: 2 for a paragraph break, 1 for a line break.
+var breaks = map[string]int{
+	"p": 2, "blockquote": 2, "ul": 2, "ol": 2, "dl": 2, "table": 2,
+	"section": 2, "article": 2, "main": 2, "header": 2, "figure": 2,
+	"hr": 2, "address": 2, "details": 2,
+	"div": 1, "li": 1, "tr": 1, "dt": 1, "dd": 1, "figcaption": 1,
+	"summary": 1, "caption": 1,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// Extract returns the title and readable text of an HTML document. The
+// text is that of the document's <main> or <article> elements, if it has
+// any, with navigation, scripts, and the like left out. Headings, list
+// items, and paragraphs are kept on lines of their own, and preformatted
+// text keeps its spacing.
+func Extract(doc string) (title, text string) {
+	doc = stripComments(doc)
+	title = collapse(html.UnescapeString(elementContent(doc, "title")))
+	title = strings.TrimSpace(title)
+
+	lower := asciiLower(doc)
+	for _, name := range []string{"main", "article"} {
+		start := findTag(lower, name)
+		end := strings.LastIndex(lower, "</"+name)
+		if start >= 0 && end > start {
+			doc = doc[start:end]
+			break
+		}
+	}
+
+	e := extractor{}
+	e.run(doc)
+	text = blankLines.ReplaceAllString(string(e.out), "\n\n")
+	return title, strings.TrimSpace(text)
+}
+
+// extractor accumulates the text of a document.
+type extractor struct {
+	out []byte
+	pre int // depth of <pre> elements
+}
+
+func (e *extractor) run(doc string) {
+	var skip string // element being skipped
+	skipDepth := 0
+
+	for i := 0; i < len(doc); {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			if skip == "" {
+				e.text(doc[i:])
+			}
+			break
+		}
+		if skip == "" {
+			e.text(doc[i : i+lt])
+		}
+		i += lt
+		gt := strings.IndexByte(doc[i:], '>')
+		if gt < 0 {
+			break
+		}
+		name, closing, selfClosing := parseTag(doc[i+1 : i+gt])
+		i += gt + 1
+		if name == "" {
+			continue
+		}
+
+		if skip != "" {
+			if name == skip && !selfClosing {
+				if closing {
+					skipDepth--
+				} else {
+					skipDepth++
+				}
+				if skipDepth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+		if rawText[name] && !closing && !selfClosing {
+			end := strings.Index(asciiLower(doc[i:]), "</"+name)
+			if end < 0 {
+				break
+			}
+			i += end
+			continue
+		}
+		if skipped[name] && !closing && !selfClosing {
+			skip, skipDepth = name, 1
+			continue
+		}
+		e.tag(name, closing)
+	}
+}
+
+// tag lays out the text around an element's start or end tag.
+func (e *extractor) tag(name string, closing bool) {
+	switch {
+	case name == "br":
+		e.trimSpace()
+		e.out = append(e.out, '\n')
+	case len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6':
+		e.newlines(2)
+		if !closing {
+			e.out = append(e.out, strings.Repeat("#", int(name[1]-'0'))+" "...)
+		}
+	case name == "li":
+		e.newlines(1)
+		if !closing {
+			e.out = append(e.out, "- "...)
+		}
+	case name == "td" || name == "th":
+		if !closing && len(e.out) > 0 && e.out[len(e.out)-1] != '\n' {
+			e.trimSpace()
+			e.out = append(e.out, " | "...)
+		}
+	case name == "pre":
+		e.newlines(2)
+		if closing {
+			e.pre = max(e.pre-1, 0)
+		} else {
+			e.pre++
+		}
+	default:
+		if n, ok := breaks[name]; ok {
+			e.newlines(n)
+		}
+	}
+}
+
+// text adds text, collapsing its whitespace outside <pre>.
+func (e *extractor) text(s string) {
+	s = html.UnescapeString(s)
+	if e.pre > 0 {
+		e.out = append(e.out, s...)
+		return
+	}
+	s = collapse(s)
+	if n := len(e.out); n == 0 || e.out[n-1] == '\n' || e.out[n-1] == ' ' {
+		s = strings.TrimLeft(s, " ")
+	}
+	e.out = append(e.out, s...)
+}
+
+// newlines ends the current line, so that at least n newlines (or none,
+// at the start) precede what comes next.
+func (e *extractor) newlines(n int) {
+	e.trimSpace()
+	if len(e.out) == 0 {
+		return
+	}
+	have := 0
+	for i := len(e.out) - 1; i >= 0 && e.out[i] == '\n'; i-- {
+		have++
+	}
+	for ; have < n; have++ {
+		e.out = append(e.out, '\n')
+	}
+}
+
+// trimSpace removes spaces at the end of the text, outside <pre>.
+func (e *extractor) trimSpace() {
+	if e.pre > 0 {
+		return
+	}
+	for len(e.out) > 0 && e.out[len(e.out)-1] == ' ' {
+		e.out = e.out[:len(e.out)-1]
+	}
+}
+
+// parseTag returns the lowercase name of the tag between < and >, or ""
+// for comments, doctypes, and anything else that is not an element.
+func parseTag(tag string) (name string, closing, selfClosing bool) {
+	if rest, ok := strings.CutPrefix(tag, "/"); ok {
+		tag, closing = rest, true
+	}
+	selfClosing = strings.HasSuffix(tag, "/")
+	end := strings.IndexFunc(tag, func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+	if end < 0 {
+		end = len(tag)
+	}
+	if end == 0 {
+		return "", false, false
+	}
+	return asciiLower(tag[:end]), closing, selfClosing
+}
+
+// stripComments removes <!-- --> comments.
+func stripComments(doc string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(doc, "<!--")
+		if start < 0 {
+			b.WriteString(doc)
+			return b.String()
+		}
+		b.WriteString(doc[:start])
+		end := strings.Index(doc[start+4:], "-->")
+		if end < 0 {
+			return b.String()
+		}
+		doc = doc[start+4+end+3:]
+	}
+}
+
+// elementContent returns the raw content of the first element called name.
+func elementContent(doc, name string) string {
+	lower := asciiLower(doc)
+	start := findTag(lower, name)
+	if start < 0 {
+		return ""
+	}
+	gt := strings.IndexByte(doc[start:], '>')
+	if gt < 0 {
+		return ""
+	}
+	start += gt + 1
+	end := strings.Index(lower[start:], "</"+name)
+	if end < 0 {
+		return ""
+	}
+	return doc[start : start+end]
+}
+
+// findTag returns the index of the first start tag called name in lower,
+// a lowercased document, or -1.
+func findTag(lower, name string) int {
+	for i := 0; ; {
+		j := strings.Index(lower[i:], "<"+name)
+		if j < 0 {
+			return -1
+		}
+		i += j
+		next := i + 1 + len(name)
+		if next >= len(lower) || strings.IndexByte(" \t\r\n/>", lower[next]) >= 0 {
+			return i
+		}
+		i = next
+	}
+}
+
+// collapse replaces each run of whitespace in s with a single space.
+func collapse(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// asciiLower lowercases the ASCII letters of s, keeping byte offsets the
+// same as in s.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,145 @@
+// Package webpage fetches web pages and extracts their readable text for
+// inclusion in prompts.
+package webpage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDownloadBytes caps how much of a page is downloaded, before any
+	// text is extracted.
+	maxDownloadBytes = 10 << 20
+
+	// bytesPerToken is a rough average used to estimate token counts.
+	bytesPerToken = 4
+
+	timeout = 30 * time.Second
+)
+
+// Page is the readable text of a web page.
+type Page struct {
+	URL   string
+	Title string
+	Text  string
+}
+
+// Format returns the page's text as a fenced block headed by its source,
+// like files included with -f.
+func (p Page) Format() string {
+	header := p.URL
+	if p.Title != "" {
+		header = fmt.Sprintf("%s (%s)", p.URL, p.Title)
+	}
+	fence := strings.Repeat("`", max(3, longestBacktickRun(p.Text)+1))
+	return fmt.Sprintf("%s:\n%s\n%s\n%s", header, fence, strings.TrimRight(p.Text, "\n"), fence)
+}
+
+// Fetcher fetches pages.
+type Fetcher struct {
+	Client    *http.Client
+	UserAgent string
+}
+
+// Fetch downloads rawURL and returns its readable text: HTML is reduced
+// to its main content, and other text is returned as it is. Other
+// content, such as images or PDFs, is an error.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q (want http:// or https://)", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9, */*;q=0.5")
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rawURL, err)
+	}
+	if len(body) > maxDownloadBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", rawURL, maxDownloadBytes>>20)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	page := &Page{URL: rawURL}
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title, page.Text = Extract(string(body))
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") || mediaType == "application/xml":
+		page.Text = string(body)
+	default:
+		return nil, fmt.Errorf("%s is %s, not text", rawURL, mediaType)
+	}
+	if strings.TrimSpace(page.Text) == "" {
+		return nil, fmt.Errorf("%s has no readable text", rawURL)
+	}
+	return page, nil
+}
+
+// Include fetches urls and returns their formatted text, separated by
+// blank lines. If maxBytes is positive and the text exceeds it in
+// total, Include returns an error.
+func (f *Fetcher) Include(ctx context.Context, urls []string, maxBytes int64) (string, error) {
+	var blocks []string
+	var total int64
+	for _, u := range urls {
+		page, err := f.Fetch(ctx, u)
+		if err != nil {
+			return "", err
+		}
+		total += int64(len(page.Text))
+		blocks = append(blocks, page.Format())
+	}
+	if maxBytes > 0 && total > maxBytes {
+		return "", fmt.Errorf("fetched pages total %d bytes of text (~%d tokens) across %d URLs, exceeding the %d byte limit\n\nInclude fewer pages or raise max_include_bytes in ~/.config/ask/config.yaml",
+			total, total/bytesPerToken, len(urls), maxBytes)
+	}
+	return strings.Join(blocks, "\n\n"), nil
+}
+
+// longestBacktickRun returns the length of the longest run of backticks in s.
+func longestBacktickRun(s string) int {
+	longest, current := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
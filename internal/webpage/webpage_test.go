@@ -0,0 +1,155 @@
+package webpage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantTitle string
+		wantText  string
+	}{
+		{
+			name: "boilerplate removed",
+			doc: `<!DOCTYPE html><html><head><title> Go &amp; You </title>
+<style>body { color: red }</style><script>if (a < b) { x = "</div>" }</script></head>
+<body><nav><a href="/">Home</a><nav>nested</nav> menu</nav>
+<!-- a comment -->
+<h1>Hello</h1>
+<p>First   paragraph,
+with <b>bold</b> text.</p><p>Second&nbsp;one.</p>
+<footer>Copyright</footer></body></html>`,
+			wantTitle: "Go & You",
+			wantText:  "# Hello\n\nFirst paragraph, with bold text.\n\nSecond one.",
+		},
+		{
+			name: "main content preferred",
+			doc: `<body><header>Site</header><div>Sidebar</div>
+<MAIN class="x"><h2>Docs</h2><ul><li>one</li><li>two</li></ul></MAIN>
+<div>After</div></body>`,
+			wantText: "## Docs\n\n- one\n- two",
+		},
+		{
+			name:     "articles",
+			doc:      `<div>Ads</div><article><p>A</p></article><article><p>B</p></article>`,
+			wantText: "A\n\nB",
+		},
+		{
+			name:     "preformatted",
+			doc:      "<p>Run:</p><pre>  go  test\n    ./...</pre><p>Done<br>now</p>",
+			wantText: "Run:\n\n  go  test\n    ./...\n\nDone\nnow",
+		},
+		{
+			name:     "table",
+			doc:      "<table><tr><th>Name</th><th>Age</th></tr><tr><td>Ann</td><td>30</td></tr></table>",
+			wantText: "Name | Age\nAnn | 30",
+		},
+		{
+			name:     "plain text",
+			doc:      "just text",
+			wantText: "just text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, text := Extract(tt.doc)
+			if title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", title, tt.wantTitle)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			if got := r.Header.Get("User-Agent"); got != "ask/test" {
+				t.Errorf("User-Agent = %q", got)
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<title>T</title><body><p>Hello</p></body>"))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("<p>kept</p>\n"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		case "/empty":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<script>x()</script>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{UserAgent: "ask/test"}
+	ctx := context.Background()
+
+	page, err := f.Fetch(ctx, srv.URL+"/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "T" || page.Text != "Hello" {
+		t.Errorf("Fetch() = %+v", page)
+	}
+	if want := srv.URL + "/page (T):\n```\nHello\n```"; page.Format() != want {
+		t.Errorf("Format() = %q, want %q", page.Format(), want)
+	}
+
+	page, err = f.Fetch(ctx, srv.URL+"/text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Text != "<p>kept</p>\n" {
+		t.Errorf("Fetch() text = %q", page.Text)
+	}
+
+	for path, wantErr := range map[string]string{
+		"/image":   "not text",
+		"/empty":   "no readable text",
+		"/missing": "404",
+	} {
+		if _, err := f.Fetch(ctx, srv.URL+path); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Fetch(%s) error = %v, want %q", path, err, wantErr)
+		}
+	}
+	for _, u := range []string{"ftp://example.com/x", "example.com", "http://"} {
+		if _, err := f.Fetch(ctx, u); err == nil || !strings.Contains(err.Error(), "invalid URL") {
+			t.Errorf("Fetch(%s) error = %v, want invalid URL", u, err)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/")))
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{}
+	urls := []string{srv.URL + "/one", srv.URL + "/two"}
+	got, err := f.Include(context.Background(), urls, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := urls[0] + ":\n```\none\n```\n\n" + urls[1] + ":\n```\ntwo\n```"
+	if got != want {
+		t.Errorf("Include() = %q, want %q", got, want)
+	}
+
+	if _, err := f.Include(context.Background(), urls, 5); err == nil || !strings.Contains(err.Error(), "max_include_bytes") {
+		t.Errorf("Include() error = %v, want size limit", err)
+	}
+}
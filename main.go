@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/devaloi/ask/cmd"
@@ -8,6 +9,11 @@ import (
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		// Errors without a message, such as a plugin's exit status, have
+		// already been reported
+		if msg := err.Error(); msg != "" {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+		}
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -0,0 +1,31 @@
+// Package ask is the root of ask's public Go API, for programs that embed
+// its multi-provider streaming client rather than running the CLI. The
+// API is in its subpackages:
+//
+//   - provider: the Provider interface, the OpenAI and Anthropic
+//     implementations, and middleware such as retries and logging
+//   - sse: the Server-Sent Events reader the providers stream with
+//   - history: the SQLite conversation store the CLI keeps history in
+//
+// A chat streams its response as it arrives:
+//
+//	p, err := provider.New("anthropic", os.Getenv("ANTHROPIC_API_KEY"))
+//	if err != nil {
+//		return err
+//	}
+//	tokens := make(chan string)
+//	errc := make(chan error, 1)
+//	go func() {
+//		errc <- p.Chat(ctx, &provider.ChatRequest{
+//			Model:    "claude-sonnet-4-20250514",
+//			Messages: []provider.Message{{Role: "user", Content: "Hello"}},
+//		}, tokens)
+//	}()
+//	for token := range tokens {
+//		fmt.Print(token)
+//	}
+//	return <-errc
+//
+// Everything else, such as configuration, is internal to the CLI and may
+// change between releases.
+package ask
@@ -0,0 +1,16 @@
+//go:build cgo
+
+package history
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// isBusy reports whether err means another connection holds a lock the
+// operation needed.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
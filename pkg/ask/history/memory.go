@@ -0,0 +1,451 @@
+package history
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devaloi/ask/internal/util"
+)
+
+// MemoryStore is a Store that keeps everything in memory, for tests of
+// code that uses a Store. It behaves as SQLiteStore does, except that
+// nothing outlives it, and Close does nothing, so it may be reopened.
+type MemoryStore struct {
+	mu            sync.Mutex
+	lastID        int64 // shared by all records; IDs only need be unique
+	conversations map[int64]*Conversation
+	summaries     []Summary
+	queue         []QueuedPrompt
+	schedules     []Schedule
+	usage         []Usage
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{conversations: make(map[int64]*Conversation)}
+}
+
+// Close does nothing; the store's contents remain.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// nextID returns a new record ID. s.mu must be held.
+func (s *MemoryStore) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+// SaveConversation saves a new conversation with its messages.
+// If the conversation has an ID, it appends the new messages.
+// Returns the conversation ID.
+func (s *MemoryStore) SaveConversation(ctx context.Context, conv *Conversation) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	stored := s.conversations[conv.ID]
+	if conv.ID == 0 {
+		title := conv.Title
+		if title == "" {
+			for _, msg := range conv.Messages {
+				if msg.Role == "user" {
+					title = util.Truncate(msg.Content, util.MaxTitleLength)
+					break
+				}
+			}
+		}
+		stored = &Conversation{
+			ID:        s.nextID(),
+			Title:     title,
+			Model:     conv.Model,
+			Provider:  conv.Provider,
+			Workspace: conv.Workspace,
+			CreatedAt: now,
+		}
+		s.conversations[stored.ID] = stored
+	} else if stored == nil {
+		return 0, fmt.Errorf("conversation %d not found", conv.ID)
+	}
+	for _, tag := range conv.Tags {
+		if !slices.Contains(stored.Tags, tag) {
+			stored.Tags = append(stored.Tags, tag)
+		}
+	}
+	slices.Sort(stored.Tags)
+
+	conv.ID = stored.ID
+	for i := range conv.Messages {
+		msg := &conv.Messages[i]
+		if msg.ID != 0 {
+			continue
+		}
+		msg.ID = s.nextID()
+		msg.ConversationID = stored.ID
+		msg.Attempt = max(msg.Attempt, 1)
+		saved := *msg
+		saved.CreatedAt = now
+		stored.Messages = append(stored.Messages, saved)
+	}
+	return conv.ID, nil
+}
+
+// ListConversations returns recent conversations, optionally filtered by search.
+func (s *MemoryStore) ListConversations(ctx context.Context, limit int, search string) ([]Conversation, error) {
+	return s.ListConversationsIn(ctx, "", limit, search)
+}
+
+// ListConversationsIn is like ListConversations, but only returns those
+// in workspace, unless it is empty. As in SQLite, search ignores the case
+// of ASCII letters only.
+func (s *MemoryStore) ListConversationsIn(ctx context.Context, workspace string, limit int, search string) ([]Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search = asciiLower(search)
+	var conversations []Conversation
+	for _, conv := range s.conversations {
+		if workspace != "" && conv.Workspace != workspace {
+			continue
+		}
+		if search != "" && !conv.matches(search) {
+			continue
+		}
+		c := *conv
+		c.Messages = nil
+		c.Tags = slices.Clone(conv.Tags)
+		conversations = append(conversations, c)
+	}
+	slices.SortFunc(conversations, func(a, b Conversation) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	if limit >= 0 && len(conversations) > limit {
+		conversations = conversations[:limit]
+	}
+	return conversations, nil
+}
+
+// matches reports whether the conversation's title, a tag, or a message
+// contains search, which is in lower case.
+func (c *Conversation) matches(search string) bool {
+	if strings.Contains(asciiLower(c.Title), search) {
+		return true
+	}
+	if slices.ContainsFunc(c.Tags, func(tag string) bool {
+		return strings.Contains(asciiLower(tag), search)
+	}) {
+		return true
+	}
+	return slices.ContainsFunc(c.Messages, func(m Message) bool {
+		return strings.Contains(asciiLower(m.Content), search)
+	})
+}
+
+// asciiLower lower-cases the ASCII letters of s, as SQLite's LIKE
+// compares them.
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+// GetConversation returns a conversation with all its messages.
+func (s *MemoryStore) GetConversation(ctx context.Context, id int64) (*Conversation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.conversations[id]
+	if stored == nil {
+		return nil, fmt.Errorf("conversation %d not found", id)
+	}
+	conv := *stored
+	conv.Messages = slices.Clone(stored.Messages)
+	conv.Tags = slices.Clone(stored.Tags)
+	return &conv, nil
+}
+
+// LatestConversationID returns the ID of the most recently active
+// conversation, i.e. the one with the newest message.
+func (s *MemoryStore) LatestConversationID(ctx context.Context) (int64, error) {
+	return s.LatestConversationIDIn(ctx, "")
+}
+
+// LatestConversationIDIn is like LatestConversationID, but only considers
+// conversations in workspace, unless it is empty.
+func (s *MemoryStore) LatestConversationIDIn(ctx context.Context, workspace string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *Conversation
+	var latestAt time.Time
+	for _, conv := range s.conversations {
+		if workspace != "" && conv.Workspace != workspace {
+			continue
+		}
+		at := conv.CreatedAt
+		if n := len(conv.Messages); n > 0 {
+			at = conv.Messages[n-1].CreatedAt
+		}
+		if latest == nil || at.After(latestAt) || at.Equal(latestAt) && conv.ID > latest.ID {
+			latest, latestAt = conv, at
+		}
+	}
+	if latest == nil {
+		if workspace != "" {
+			return 0, fmt.Errorf("no conversations to continue in workspace %s", workspace)
+		}
+		return 0, fmt.Errorf("no conversations to continue")
+	}
+	return latest.ID, nil
+}
+
+// DeleteMessages removes the messages with the given IDs.
+func (s *MemoryStore) DeleteMessages(ctx context.Context, ids ...int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conv := range s.conversations {
+		conv.Messages = slices.DeleteFunc(conv.Messages, func(m Message) bool {
+			return slices.Contains(ids, m.ID)
+		})
+	}
+	return nil
+}
+
+// SaveSummary stores a summary for a conversation and sets its ID.
+func (s *MemoryStore) SaveSummary(ctx context.Context, sum *Summary) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum.ID = s.nextID()
+	saved := *sum
+	saved.CreatedAt = time.Now()
+	s.summaries = append(s.summaries, saved)
+	return nil
+}
+
+// LatestSummary returns the summary covering the most of a conversation,
+// or nil if it has none.
+func (s *MemoryStore) LatestSummary(ctx context.Context, conversationID int64) (*Summary, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *Summary
+	for i := range s.summaries {
+		sum := &s.summaries[i]
+		if sum.ConversationID != conversationID {
+			continue
+		}
+		if latest == nil || sum.ThroughMessageID > latest.ThroughMessageID ||
+			sum.ThroughMessageID == latest.ThroughMessageID && sum.ID > latest.ID {
+			latest = sum
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	sum := *latest
+	return &sum, nil
+}
+
+// QueuePrompt stores q and sets its ID and CreatedAt.
+func (s *MemoryStore) QueuePrompt(ctx context.Context, q *QueuedPrompt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q.ID, q.CreatedAt = s.nextID(), time.Now()
+	saved := *q
+	saved.LastError = ""
+	s.queue = append(s.queue, saved)
+	return nil
+}
+
+// ListQueue returns every queued prompt, oldest first.
+func (s *MemoryStore) ListQueue(ctx context.Context) ([]QueuedPrompt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.queue), nil
+}
+
+// DeleteQueued removes a queued prompt.
+func (s *MemoryStore) DeleteQueued(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.queue, func(q QueuedPrompt) bool { return q.ID == id })
+	if i < 0 {
+		return fmt.Errorf("queued prompt %d not found", id)
+	}
+	s.queue = slices.Delete(s.queue, i, i+1)
+	return nil
+}
+
+// SetQueueError records why sending a queued prompt failed.
+func (s *MemoryStore) SetQueueError(ctx context.Context, id int64, msg string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.queue, func(q QueuedPrompt) bool { return q.ID == id }); i >= 0 {
+		s.queue[i].LastError = msg
+	}
+	return nil
+}
+
+// AddSchedule stores sched and sets its ID and CreatedAt.
+func (s *MemoryStore) AddSchedule(ctx context.Context, sched *Schedule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sched.ID, sched.CreatedAt = s.nextID(), time.Now()
+	saved := *sched
+	saved.Vars = maps.Clone(sched.Vars)
+	saved.LastRun = time.Time{}
+	s.schedules = append(s.schedules, saved)
+	return nil
+}
+
+// ListSchedules returns every schedule, oldest first.
+func (s *MemoryStore) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules := slices.Clone(s.schedules)
+	for i := range schedules {
+		schedules[i].Vars = maps.Clone(schedules[i].Vars)
+	}
+	return schedules, nil
+}
+
+// DeleteSchedule removes a schedule.
+func (s *MemoryStore) DeleteSchedule(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.schedules, func(sched Schedule) bool { return sched.ID == id })
+	if i < 0 {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	s.schedules = slices.Delete(s.schedules, i, i+1)
+	return nil
+}
+
+// SetScheduleRun records when a schedule last ran.
+func (s *MemoryStore) SetScheduleRun(ctx context.Context, id int64, t time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.schedules, func(sched Schedule) bool { return sched.ID == id }); i >= 0 {
+		s.schedules[i].LastRun = t
+	}
+	return nil
+}
+
+// RecordUsage stores u and sets its ID. A zero CreatedAt means now.
+func (s *MemoryStore) RecordUsage(ctx context.Context, u *Usage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	u.ID = s.nextID()
+	s.usage = append(s.usage, *u)
+	return nil
+}
+
+// Spend returns the total recorded cost since the given time, for one
+// provider or, if providerName is empty, for all of them.
+func (s *MemoryStore) Spend(ctx context.Context, providerName string, since time.Time) (float64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total float64
+	for _, u := range s.usage {
+		if !u.CreatedAt.Before(since) && (providerName == "" || u.Provider == providerName) {
+			total += u.Cost
+		}
+	}
+	return total, nil
+}
+
+// ListUsage returns the usage recorded in [since, until), oldest first.
+func (s *MemoryStore) ListUsage(ctx context.Context, since, until time.Time) ([]Usage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Usage
+	for _, u := range s.usage {
+		if !u.CreatedAt.Before(since) && u.CreatedAt.Before(until) {
+			records = append(records, u)
+		}
+	}
+	slices.SortStableFunc(records, func(a, b Usage) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return records, nil
+}
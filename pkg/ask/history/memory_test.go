@@ -0,0 +1,234 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestStores checks that MemoryStore behaves as SQLiteStore does, so
+// that tests using it test what ask does.
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name string
+		open func(t *testing.T) Store
+	}{
+		{"sqlite", func(t *testing.T) Store {
+			store, err := NewSQLiteStore(":memory:")
+			if err != nil {
+				t.Fatalf("NewSQLiteStore failed: %v", err)
+			}
+			return store
+		}},
+		{"memory", func(*testing.T) Store { return NewMemoryStore() }},
+	}
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store Store)
+	}{
+		{"conversations", testConversations},
+		{"summaries", testSummaries},
+		{"queue and schedules", testQueueAndSchedules},
+		{"usage", testUsage},
+		{"cancelled context", testCancelled},
+	}
+	for _, s := range stores {
+		for _, tt := range tests {
+			t.Run(s.name+"/"+tt.name, func(t *testing.T) {
+				store := s.open(t)
+				defer store.Close()
+				tt.fn(t, store)
+			})
+		}
+	}
+}
+
+func testConversations(t *testing.T, store Store) {
+	ctx := t.Context()
+	first := &Conversation{Model: "gpt-4o", Provider: "openai", Workspace: "/src/ask", Messages: []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "What is a Monad?"},
+	}}
+	second := &Conversation{Title: "Functors", Model: "claude-sonnet-4", Provider: "anthropic", Tags: []string{"math", "fp"}, Messages: []Message{
+		{Role: "user", Content: "And a functor?"},
+	}}
+	for _, conv := range []*Conversation{first, second} {
+		if _, err := store.SaveConversation(ctx, conv); err != nil {
+			t.Fatalf("SaveConversation failed: %v", err)
+		}
+	}
+	if first.ID == 0 || first.ID == second.ID || first.Messages[1].ID == 0 || first.Messages[1].Attempt != 1 {
+		t.Errorf("SaveConversation did not set IDs: %+v", first)
+	}
+
+	// Appending to the first makes it the latest
+	first.Messages = append(first.Messages, Message{Role: "assistant", Content: "A burrito.", Partial: true})
+	if _, err := store.SaveConversation(ctx, first); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+	if id, err := store.LatestConversationID(ctx); err != nil || id != first.ID {
+		t.Errorf("LatestConversationID() = %d, %v; want %d", id, err, first.ID)
+	}
+	if _, err := store.LatestConversationIDIn(ctx, "/src/other"); err == nil {
+		t.Error("LatestConversationIDIn of an empty workspace succeeded")
+	}
+
+	got, err := store.GetConversation(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if got.Title != "What is a Monad?" || got.Workspace != "/src/ask" || len(got.Messages) != 3 {
+		t.Fatalf("GetConversation() = %+v", got)
+	}
+	if msg := got.Messages[2]; msg.Content != "A burrito." || !msg.Partial || msg.ConversationID != first.ID || msg.CreatedAt.IsZero() {
+		t.Errorf("appended message = %+v", msg)
+	}
+	if _, err := store.GetConversation(ctx, 999); err == nil {
+		t.Error("GetConversation of a missing conversation succeeded")
+	}
+
+	search := []struct {
+		workspace, search string
+		want              []int64
+	}{
+		{"", "", []int64{second.ID, first.ID}},
+		{"", "monad", []int64{first.ID}},
+		{"", "BURRITO", []int64{first.ID}},
+		{"", "functor", []int64{second.ID}},
+		{"", "MATH", []int64{second.ID}},
+		{"", "nothing", nil},
+		{"/src/ask", "", []int64{first.ID}},
+	}
+	for _, tt := range search {
+		convs, err := store.ListConversationsIn(ctx, tt.workspace, 10, tt.search)
+		if err != nil {
+			t.Fatalf("ListConversationsIn failed: %v", err)
+		}
+		var ids []int64
+		for _, c := range convs {
+			ids = append(ids, c.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("ListConversationsIn(%q, %q) = %v, want %v", tt.workspace, tt.search, ids, tt.want)
+		}
+	}
+	if convs, _ := store.ListConversations(ctx, 1, ""); len(convs) != 1 || !slices.Equal(convs[0].Tags, []string{"fp", "math"}) {
+		t.Errorf("ListConversations with limit 1 = %+v, want the second with its tags", convs)
+	}
+
+	// Saving again adds tags
+	second.Tags = []string{"math", "haskell"}
+	if _, err := store.SaveConversation(ctx, second); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+	if got, err := store.GetConversation(ctx, second.ID); err != nil || !slices.Equal(got.Tags, []string{"fp", "haskell", "math"}) {
+		t.Errorf("GetConversation() = %+v, %v; want tags fp, haskell, math", got, err)
+	}
+
+	if err := store.DeleteMessages(ctx, first.Messages[2].ID); err != nil {
+		t.Fatalf("DeleteMessages failed: %v", err)
+	}
+	if got, _ = store.GetConversation(ctx, first.ID); len(got.Messages) != 2 {
+		t.Errorf("got %d messages after deleting one, want 2", len(got.Messages))
+	}
+}
+
+func testSummaries(t *testing.T, store Store) {
+	ctx := t.Context()
+	if sum, err := store.LatestSummary(ctx, 1); sum != nil || err != nil {
+		t.Errorf("LatestSummary() = %+v, %v; want nil, nil", sum, err)
+	}
+	for _, sum := range []*Summary{
+		{ConversationID: 1, ThroughMessageID: 8, Content: "later"},
+		{ConversationID: 1, ThroughMessageID: 4, Content: "earlier"},
+		{ConversationID: 2, ThroughMessageID: 9, Content: "other"},
+	} {
+		if err := store.SaveSummary(ctx, sum); err != nil || sum.ID == 0 {
+			t.Fatalf("SaveSummary() = %v, ID %d", err, sum.ID)
+		}
+	}
+	if sum, err := store.LatestSummary(ctx, 1); err != nil || sum.Content != "later" {
+		t.Errorf("LatestSummary() = %+v, %v; want the later one", sum, err)
+	}
+}
+
+func testQueueAndSchedules(t *testing.T, store Store) {
+	ctx := t.Context()
+	q := &QueuedPrompt{Prompt: "What is a monad?", Provider: "openai", Model: "gpt-4o", WebSearch: true}
+	if err := store.QueuePrompt(ctx, q); err != nil {
+		t.Fatalf("QueuePrompt failed: %v", err)
+	}
+	if err := store.SetQueueError(ctx, q.ID, "no such host"); err != nil {
+		t.Fatalf("SetQueueError failed: %v", err)
+	}
+	if queue, err := store.ListQueue(ctx); err != nil || len(queue) != 1 || queue[0].LastError != "no such host" || !queue[0].WebSearch {
+		t.Errorf("ListQueue() = %+v, %v", queue, err)
+	}
+	if err := store.DeleteQueued(ctx, q.ID); err != nil {
+		t.Fatalf("DeleteQueued failed: %v", err)
+	}
+	if err := store.DeleteQueued(ctx, q.ID); err == nil {
+		t.Error("DeleteQueued of a deleted prompt succeeded")
+	}
+
+	sched := &Schedule{Spec: "@daily", Template: "standup", Vars: map[string]string{"team": "core"}}
+	if err := store.AddSchedule(ctx, sched); err != nil {
+		t.Fatalf("AddSchedule failed: %v", err)
+	}
+	sched.Vars["team"] = "changed after saving"
+	ran := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := store.SetScheduleRun(ctx, sched.ID, ran); err != nil {
+		t.Fatalf("SetScheduleRun failed: %v", err)
+	}
+	schedules, err := store.ListSchedules(ctx)
+	if err != nil || len(schedules) != 1 {
+		t.Fatalf("ListSchedules() = %+v, %v", schedules, err)
+	}
+	if got := schedules[0]; got.Vars["team"] != "core" || !got.LastRun.Equal(ran) {
+		t.Errorf("schedule = %+v", got)
+	}
+	if err := store.DeleteSchedule(ctx, sched.ID); err != nil {
+		t.Fatalf("DeleteSchedule failed: %v", err)
+	}
+	if err := store.DeleteSchedule(ctx, sched.ID); err == nil {
+		t.Error("DeleteSchedule of a deleted schedule succeeded")
+	}
+}
+
+func testUsage(t *testing.T, store Store) {
+	ctx := t.Context()
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, u := range []*Usage{
+		{Provider: "openai", Cost: 0.5, CreatedAt: day.Add(-time.Hour)},
+		{Provider: "openai", Cost: 1, CreatedAt: day},
+		{Provider: "anthropic", Cost: 2, CreatedAt: day.Add(time.Hour)},
+	} {
+		if err := store.RecordUsage(ctx, u); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+	}
+	if spend, err := store.Spend(ctx, "openai", day); err != nil || spend != 1 {
+		t.Errorf("Spend(openai) = %v, %v; want 1", spend, err)
+	}
+	if spend, err := store.Spend(ctx, "", day); err != nil || spend != 3 {
+		t.Errorf("Spend() = %v, %v; want 3", spend, err)
+	}
+	records, err := store.ListUsage(ctx, day, day.Add(time.Hour))
+	if err != nil || len(records) != 1 || records[0].Cost != 1 {
+		t.Errorf("ListUsage() = %+v, %v; want the record at the start only", records, err)
+	}
+}
+
+func testCancelled(t *testing.T, store Store) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	conv := &Conversation{Messages: []Message{{Role: "user", Content: "hi"}}}
+	if _, err := store.SaveConversation(ctx, conv); !errors.Is(err, context.Canceled) {
+		t.Errorf("SaveConversation() error = %v, want context.Canceled", err)
+	}
+	if _, err := store.ListConversations(ctx, 10, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListConversations() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,122 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migrations is the ordered list of schema changes. The database's
+// user_version records how many have been applied, so new migrations must
+// only ever be appended.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS conversations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		model TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id INTEGER NOT NULL,
+		role TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_messages_conversation_id ON messages(conversation_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_conversations_created_at ON conversations(created_at)`,
+	`ALTER TABLE messages ADD COLUMN attempt INTEGER NOT NULL DEFAULT 1`,
+	`CREATE TABLE IF NOT EXISTS summaries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id INTEGER NOT NULL,
+		through_message_id INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		model TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (conversation_id) REFERENCES conversations(id) ON DELETE CASCADE
+	)`,
+	`CREATE TABLE IF NOT EXISTS usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		input_tokens INTEGER NOT NULL,
+		output_tokens INTEGER NOT NULL,
+		cost REAL NOT NULL,
+		created_at DATETIME NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_usage_created_at ON usage(created_at)`,
+	`CREATE TABLE IF NOT EXISTS schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		spec TEXT NOT NULL,
+		template TEXT NOT NULL,
+		prompt TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		output TEXT NOT NULL,
+		last_run DATETIME,
+		created_at DATETIME NOT NULL
+	)`,
+	`ALTER TABLE schedules ADD COLUMN vars TEXT NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE conversations ADD COLUMN workspace TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX IF NOT EXISTS idx_conversations_workspace ON conversations(workspace)`,
+	`CREATE TABLE IF NOT EXISTS queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		conversation_id INTEGER NOT NULL,
+		system TEXT NOT NULL,
+		prompt TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		model TEXT NOT NULL,
+		last_error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	)`,
+	`ALTER TABLE messages ADD COLUMN partial INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS tags (
+		conversation_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (conversation_id, tag)
+	)`,
+	`ALTER TABLE queue ADD COLUMN web_search INTEGER NOT NULL DEFAULT 0`,
+}
+
+// migrate runs database migrations that have not yet been applied. They
+// run in one write, so that processes opening a new database at the same
+// time do not both apply them.
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version >= len(migrations) {
+		return nil
+	}
+
+	var applied int
+	err := s.write(context.Background(), func(tx *sql.Tx) error {
+		// Another process may have migrated since the version was read
+		if err := tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+			return err
+		}
+
+		// Databases created before versioning have user_version 0 but may
+		// already contain the initial tables; those migrations are
+		// idempotent.
+		for i := version; i < len(migrations); i++ {
+			if _, err := tx.Exec(migrations[i]); err != nil {
+				return fmt.Errorf("migration %d: %w", i+1, err)
+			}
+		}
+		_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations)))
+		applied = max(len(migrations)-version, 0)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		slog.Info("applied history migrations", "count", applied, "version", len(migrations))
+	}
+	return nil
+}
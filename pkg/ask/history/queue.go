@@ -0,0 +1,87 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// QueuedPrompt is a prompt saved to send later, such as when the provider
+// could not be reached.
+type QueuedPrompt struct {
+	ID int64
+
+	// ConversationID is the conversation the prompt continues, or 0 for
+	// a new one, which starts with System if it is not empty.
+	ConversationID int64
+	System         string
+	Prompt         string
+	Provider       string
+	Model          string
+
+	// WebSearch lets the model search the web, as --web does.
+	WebSearch bool
+
+	// LastError is why the last attempt to send the prompt failed, if
+	// there was one.
+	LastError string
+
+	CreatedAt time.Time
+}
+
+// QueuePrompt stores q and sets its ID and CreatedAt.
+func (s *SQLiteStore) QueuePrompt(ctx context.Context, q *QueuedPrompt) error {
+	createdAt := time.Now()
+	id, err := s.insert(ctx,
+		`INSERT INTO queue (conversation_id, system, prompt, provider, model, web_search, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		q.ConversationID, q.System, q.Prompt, q.Provider, q.Model, q.WebSearch, createdAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue prompt: %w", err)
+	}
+	q.ID, q.CreatedAt = id, createdAt
+	return nil
+}
+
+// ListQueue returns every queued prompt, oldest first.
+func (s *SQLiteStore) ListQueue(ctx context.Context) ([]QueuedPrompt, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, conversation_id, system, prompt, provider, model, web_search, last_error, created_at
+		FROM queue
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var queue []QueuedPrompt
+	for rows.Next() {
+		var q QueuedPrompt
+		if err := rows.Scan(&q.ID, &q.ConversationID, &q.System, &q.Prompt, &q.Provider, &q.Model, &q.WebSearch, &q.LastError, &q.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan queued prompt: %w", err)
+		}
+		queue = append(queue, q)
+	}
+	return queue, rows.Err()
+}
+
+// DeleteQueued removes a queued prompt.
+func (s *SQLiteStore) DeleteQueued(ctx context.Context, id int64) error {
+	n, err := s.exec(ctx, `DELETE FROM queue WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete queued prompt: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("queued prompt %d not found", id)
+	}
+	return nil
+}
+
+// SetQueueError records why sending a queued prompt failed.
+func (s *SQLiteStore) SetQueueError(ctx context.Context, id int64, msg string) error {
+	if _, err := s.exec(ctx, `UPDATE queue SET last_error = ? WHERE id = ?`, msg, id); err != nil {
+		return fmt.Errorf("failed to update queued prompt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package history
+
+import "testing"
+
+func TestQueue(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	fresh := &QueuedPrompt{System: "Be brief.", Prompt: "What is a monad?", Provider: "openai", Model: "gpt-4o"}
+	followUp := &QueuedPrompt{ConversationID: 7, Prompt: "And a functor?", Provider: "anthropic", Model: "claude-sonnet-4"}
+	for _, q := range []*QueuedPrompt{fresh, followUp} {
+		if err := store.QueuePrompt(t.Context(), q); err != nil {
+			t.Fatalf("QueuePrompt failed: %v", err)
+		}
+		if q.ID == 0 || q.CreatedAt.IsZero() {
+			t.Errorf("QueuePrompt did not set the ID and CreatedAt: %+v", q)
+		}
+	}
+	if err := store.SetQueueError(t.Context(), fresh.ID, "no such host"); err != nil {
+		t.Fatalf("SetQueueError failed: %v", err)
+	}
+
+	queue, err := store.ListQueue(t.Context())
+	if err != nil {
+		t.Fatalf("ListQueue failed: %v", err)
+	}
+	if len(queue) != 2 {
+		t.Fatalf("got %d queued prompts, want 2", len(queue))
+	}
+	got := queue[0]
+	if got.ID != fresh.ID || got.System != fresh.System || got.Prompt != fresh.Prompt || got.Model != fresh.Model || got.LastError != "no such host" {
+		t.Errorf("queued prompt = %+v, want %+v", got, fresh)
+	}
+	if queue[1].ConversationID != 7 || queue[1].Provider != "anthropic" || queue[1].LastError != "" {
+		t.Errorf("queued prompt = %+v, want %+v", queue[1], followUp)
+	}
+
+	if err := store.DeleteQueued(t.Context(), fresh.ID); err != nil {
+		t.Fatalf("DeleteQueued failed: %v", err)
+	}
+	if err := store.DeleteQueued(t.Context(), fresh.ID); err == nil {
+		t.Error("DeleteQueued of a deleted prompt succeeded")
+	}
+	if queue, err = store.ListQueue(t.Context()); err != nil || len(queue) != 1 {
+		t.Errorf("ListQueue = %d prompts, %v; want 1", len(queue), err)
+	}
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Schedule is a prompt to send on a cron schedule. Its prompt is the
+// named Template followed by Prompt, either of which may be empty.
+type Schedule struct {
+	ID       int64
+	Spec     string // cron schedule, as parsed by cron.Parse
+	Template string
+	Vars     map[string]string // values of the template's variables
+	Prompt   string
+	Provider string
+	Model    string
+
+	// Output is a file to append responses to, besides saving them as
+	// conversations.
+	Output string
+
+	LastRun   time.Time // zero if never run
+	CreatedAt time.Time
+}
+
+// AddSchedule stores sched and sets its ID and CreatedAt.
+func (s *SQLiteStore) AddSchedule(ctx context.Context, sched *Schedule) error {
+	vars, err := json.Marshal(sched.Vars)
+	if err != nil {
+		return fmt.Errorf("failed to encode template variables: %w", err)
+	}
+	createdAt := time.Now()
+	id, err := s.insert(ctx,
+		`INSERT INTO schedules (spec, template, vars, prompt, provider, model, output, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sched.Spec, sched.Template, string(vars), sched.Prompt, sched.Provider, sched.Model, sched.Output, createdAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add schedule: %w", err)
+	}
+	sched.ID, sched.CreatedAt = id, createdAt
+	return nil
+}
+
+// ListSchedules returns every schedule, oldest first.
+func (s *SQLiteStore) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, spec, template, vars, prompt, provider, model, output, last_run, created_at
+		FROM schedules
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []Schedule
+	for rows.Next() {
+		var sched Schedule
+		var vars string
+		var lastRun sql.NullTime
+		if err := rows.Scan(&sched.ID, &sched.Spec, &sched.Template, &vars, &sched.Prompt, &sched.Provider, &sched.Model, &sched.Output, &lastRun, &sched.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		if err := json.Unmarshal([]byte(vars), &sched.Vars); err != nil {
+			return nil, fmt.Errorf("failed to decode template variables of schedule %d: %w", sched.ID, err)
+		}
+		sched.LastRun = lastRun.Time
+		schedules = append(schedules, sched)
+	}
+	return schedules, rows.Err()
+}
+
+// DeleteSchedule removes a schedule.
+func (s *SQLiteStore) DeleteSchedule(ctx context.Context, id int64) error {
+	n, err := s.exec(ctx, `DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	return nil
+}
+
+// SetScheduleRun records when a schedule last ran.
+func (s *SQLiteStore) SetScheduleRun(ctx context.Context, id int64, t time.Time) error {
+	if _, err := s.exec(ctx, `UPDATE schedules SET last_run = ? WHERE id = ?`, t.UTC(), id); err != nil {
+		return fmt.Errorf("failed to update schedule: %w", err)
+	}
+	return nil
+}
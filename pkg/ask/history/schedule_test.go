@@ -0,0 +1,62 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedules(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	weekly := &Schedule{Spec: "0 9 * * 1", Template: "weekly-summary", Vars: map[string]string{"team": "core"}, Provider: "openai", Model: "gpt-4o", Output: "/tmp/weekly.md"}
+	daily := &Schedule{Spec: "@daily", Prompt: "What's new in Go?", Provider: "anthropic", Model: "claude-sonnet-4"}
+	for _, sched := range []*Schedule{weekly, daily} {
+		if err := store.AddSchedule(t.Context(), sched); err != nil {
+			t.Fatalf("AddSchedule failed: %v", err)
+		}
+		if sched.ID == 0 || sched.CreatedAt.IsZero() {
+			t.Errorf("AddSchedule did not set the ID and CreatedAt: %+v", sched)
+		}
+	}
+
+	ran := time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC)
+	if err := store.SetScheduleRun(t.Context(), weekly.ID, ran); err != nil {
+		t.Fatalf("SetScheduleRun failed: %v", err)
+	}
+
+	schedules, err := store.ListSchedules(t.Context())
+	if err != nil {
+		t.Fatalf("ListSchedules failed: %v", err)
+	}
+	if len(schedules) != 2 {
+		t.Fatalf("got %d schedules, want 2", len(schedules))
+	}
+	got := schedules[0]
+	if got.ID != weekly.ID || got.Spec != weekly.Spec || got.Template != weekly.Template || got.Output != weekly.Output || got.Model != weekly.Model {
+		t.Errorf("schedule = %+v, want %+v", got, weekly)
+	}
+	if got.Vars["team"] != "core" {
+		t.Errorf("Vars = %v, want team=core", got.Vars)
+	}
+	if !got.LastRun.Equal(ran) {
+		t.Errorf("LastRun = %v, want %v", got.LastRun, ran)
+	}
+	if !schedules[1].LastRun.IsZero() || schedules[1].Prompt != daily.Prompt {
+		t.Errorf("schedule = %+v, want %+v", schedules[1], daily)
+	}
+
+	if err := store.DeleteSchedule(t.Context(), weekly.ID); err != nil {
+		t.Fatalf("DeleteSchedule failed: %v", err)
+	}
+	if err := store.DeleteSchedule(t.Context(), weekly.ID); err == nil {
+		t.Error("DeleteSchedule of a deleted schedule succeeded")
+	}
+	schedules, err = store.ListSchedules(t.Context())
+	if err != nil || len(schedules) != 1 {
+		t.Errorf("ListSchedules = %d schedules, %v; want 1", len(schedules), err)
+	}
+}
@@ -0,0 +1,404 @@
+// Package history provides conversation storage: SQLite on disk, and
+// an in-memory store for tests.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/devaloi/ask/internal/util"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Message represents a single message in a conversation.
+type Message struct {
+	ID             int64
+	ConversationID int64
+	Role           string
+	Content        string
+	CreatedAt      time.Time
+
+	// Attempt numbers regenerated responses; the stored message is the
+	// attempt that was kept.
+	Attempt int
+
+	// Partial marks a response that was cut short, such as by Ctrl+C.
+	Partial bool
+}
+
+// Conversation represents a conversation with an LLM.
+type Conversation struct {
+	ID        int64
+	Title     string
+	Model     string
+	Provider  string
+	CreatedAt time.Time
+	Messages  []Message
+
+	// Workspace groups the conversations of a project, such as a git
+	// repository. Empty means none.
+	Workspace string
+
+	// Tags label the conversation, in sorted order when loaded. Saving
+	// adds any the stored conversation lacks.
+	Tags []string
+}
+
+// Summary condenses the start of a conversation, up to and including
+// message ThroughMessageID, so that long conversations fit in a model's
+// context window. The summarized messages themselves are kept.
+type Summary struct {
+	ID               int64
+	ConversationID   int64
+	ThroughMessageID int64
+	Content          string
+	Model            string
+	CreatedAt        time.Time
+}
+
+// Store stores conversations, summaries, queued prompts, schedules, and
+// usage records. SQLiteStore is the one ask uses; MemoryStore stands in
+// for it in tests.
+type Store interface {
+	Close() error
+
+	SaveConversation(ctx context.Context, conv *Conversation) (int64, error)
+	ListConversations(ctx context.Context, limit int, search string) ([]Conversation, error)
+	ListConversationsIn(ctx context.Context, workspace string, limit int, search string) ([]Conversation, error)
+	GetConversation(ctx context.Context, id int64) (*Conversation, error)
+	LatestConversationID(ctx context.Context) (int64, error)
+	LatestConversationIDIn(ctx context.Context, workspace string) (int64, error)
+	DeleteMessages(ctx context.Context, ids ...int64) error
+
+	SaveSummary(ctx context.Context, sum *Summary) error
+	LatestSummary(ctx context.Context, conversationID int64) (*Summary, error)
+
+	QueuePrompt(ctx context.Context, q *QueuedPrompt) error
+	ListQueue(ctx context.Context) ([]QueuedPrompt, error)
+	DeleteQueued(ctx context.Context, id int64) error
+	SetQueueError(ctx context.Context, id int64, msg string) error
+
+	AddSchedule(ctx context.Context, sched *Schedule) error
+	ListSchedules(ctx context.Context) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, id int64) error
+	SetScheduleRun(ctx context.Context, id int64, t time.Time) error
+
+	RecordUsage(ctx context.Context, u *Usage) error
+	Spend(ctx context.Context, providerName string, since time.Time) (float64, error)
+	ListUsage(ctx context.Context, since, until time.Time) ([]Usage, error)
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// SQLiteStore handles SQLite conversation storage. Several stores, in one
+// process or many, may use the same database at once.
+type SQLiteStore struct {
+	db *sql.DB
+	mu sync.Mutex // queues this store's writes
+}
+
+// NewSQLiteStore creates a new SQLite store at the given path.
+// It creates the database and runs migrations if needed.
+func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", dataSourceName(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	store := &SQLiteStore{db: db}
+
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return store, nil
+}
+
+// Close closes the database connection.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// SaveConversation saves a new conversation with its messages.
+// If the conversation has an ID, it appends the new messages.
+// Returns the conversation ID.
+func (s *SQLiteStore) SaveConversation(ctx context.Context, conv *Conversation) (int64, error) {
+	// IDs are kept aside until the write commits, since it may be retried
+	var convID int64
+	msgIDs := make([]int64, len(conv.Messages))
+	err := s.write(ctx, func(tx *sql.Tx) error {
+		convID = conv.ID
+		if convID == 0 {
+			// New conversation
+			title := conv.Title
+			if title == "" && len(conv.Messages) > 0 {
+				// Auto-generate title from first user message
+				for _, msg := range conv.Messages {
+					if msg.Role == "user" {
+						title = util.Truncate(msg.Content, util.MaxTitleLength)
+						break
+					}
+				}
+			}
+
+			result, err := tx.ExecContext(ctx,
+				`INSERT INTO conversations (title, model, provider, workspace, created_at) VALUES (?, ?, ?, ?, ?)`,
+				title, conv.Model, conv.Provider, conv.Workspace, time.Now(),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert conversation: %w", err)
+			}
+
+			convID, err = result.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to get conversation ID: %w", err)
+			}
+		}
+
+		for _, tag := range conv.Tags {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO tags (conversation_id, tag) VALUES (?, ?)`, convID, tag); err != nil {
+				return fmt.Errorf("failed to insert tag: %w", err)
+			}
+		}
+
+		// Insert messages
+		for i, msg := range conv.Messages {
+			msgIDs[i] = msg.ID
+			if msg.ID != 0 {
+				continue
+			}
+			result, err := tx.ExecContext(ctx,
+				`INSERT INTO messages (conversation_id, role, content, created_at, attempt, partial) VALUES (?, ?, ?, ?, ?, ?)`,
+				convID, msg.Role, msg.Content, time.Now(), max(msg.Attempt, 1), msg.Partial,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert message: %w", err)
+			}
+			if msgIDs[i], err = result.LastInsertId(); err != nil {
+				return fmt.Errorf("failed to get message ID: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	conv.ID = convID
+	for i := range conv.Messages {
+		msg := &conv.Messages[i]
+		if msg.ID == 0 {
+			msg.ID = msgIDs[i]
+			msg.ConversationID = convID
+			msg.Attempt = max(msg.Attempt, 1)
+		}
+	}
+
+	slog.Debug("saved conversation", "id", conv.ID, "messages", len(conv.Messages))
+	return conv.ID, nil
+}
+
+// ListConversations returns recent conversations, optionally filtered by search.
+func (s *SQLiteStore) ListConversations(ctx context.Context, limit int, search string) ([]Conversation, error) {
+	return s.ListConversationsIn(ctx, "", limit, search)
+}
+
+// ListConversationsIn is like ListConversations, but only returns those
+// in workspace, unless it is empty.
+func (s *SQLiteStore) ListConversationsIn(ctx context.Context, workspace string, limit int, search string) ([]Conversation, error) {
+	var rows *sql.Rows
+	var err error
+
+	if search != "" {
+		// Search in titles, tags, and message content
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT DISTINCT c.id, c.title, c.model, c.provider, c.workspace, c.created_at, `+tagList+`
+			FROM conversations c
+			LEFT JOIN messages m ON c.id = m.conversation_id
+			WHERE (c.title LIKE ? OR m.content LIKE ? OR c.id IN (SELECT conversation_id FROM tags WHERE tag LIKE ?))
+				AND (? = '' OR c.workspace = ?)
+			ORDER BY c.created_at DESC
+			LIMIT ?
+		`, "%"+search+"%", "%"+search+"%", "%"+search+"%", workspace, workspace, limit)
+	} else {
+		rows, err = s.db.QueryContext(ctx, `
+			SELECT c.id, c.title, c.model, c.provider, c.workspace, c.created_at, `+tagList+`
+			FROM conversations c
+			WHERE ? = '' OR c.workspace = ?
+			ORDER BY c.created_at DESC
+			LIMIT ?
+		`, workspace, workspace, limit)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var conversations []Conversation
+	for rows.Next() {
+		var conv Conversation
+		var tags string
+		if err := rows.Scan(&conv.ID, &conv.Title, &conv.Model, &conv.Provider, &conv.Workspace, &conv.CreatedAt, &tags); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conv.Tags = splitTags(tags)
+		conversations = append(conversations, conv)
+	}
+
+	slog.Debug("listed conversations", "workspace", workspace, "search", search, "limit", limit, "found", len(conversations))
+	return conversations, rows.Err()
+}
+
+// GetConversation returns a conversation with all its messages.
+func (s *SQLiteStore) GetConversation(ctx context.Context, id int64) (*Conversation, error) {
+	conv := &Conversation{}
+
+	var tags string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.id, c.title, c.model, c.provider, c.workspace, c.created_at, `+tagList+`
+		FROM conversations c
+		WHERE c.id = ?
+	`, id).Scan(&conv.ID, &conv.Title, &conv.Model, &conv.Provider, &conv.Workspace, &conv.CreatedAt, &tags)
+	conv.Tags = splitTags(tags)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("conversation %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, role, content, created_at, attempt, partial
+		FROM messages
+		WHERE conversation_id = ?
+		ORDER BY created_at ASC, id ASC
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.Role, &msg.Content, &msg.CreatedAt, &msg.Attempt, &msg.Partial); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		msg.ConversationID = id
+		conv.Messages = append(conv.Messages, msg)
+	}
+
+	slog.Debug("loaded conversation", "id", id, "messages", len(conv.Messages))
+	return conv, rows.Err()
+}
+
+// tagList selects the tags of conversation c, separated by tagSeparator.
+const tagList = `COALESCE((SELECT group_concat(tag, char(31)) FROM tags WHERE conversation_id = c.id), '')`
+
+// tagSeparator separates the tags tagList selects: the ASCII unit
+// separator, which no tag is expected to hold.
+const tagSeparator = "\x1f"
+
+// splitTags returns the tags tagList selected, sorted.
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	tags := strings.Split(s, tagSeparator)
+	slices.Sort(tags)
+	return tags
+}
+
+// LatestConversationID returns the ID of the most recently active
+// conversation, i.e. the one with the newest message.
+func (s *SQLiteStore) LatestConversationID(ctx context.Context) (int64, error) {
+	return s.LatestConversationIDIn(ctx, "")
+}
+
+// LatestConversationIDIn is like LatestConversationID, but only considers
+// conversations in workspace, unless it is empty.
+func (s *SQLiteStore) LatestConversationIDIn(ctx context.Context, workspace string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT c.id
+		FROM conversations c
+		LEFT JOIN messages m ON c.id = m.conversation_id
+		WHERE ? = '' OR c.workspace = ?
+		GROUP BY c.id
+		ORDER BY MAX(COALESCE(m.created_at, c.created_at)) DESC, c.id DESC
+		LIMIT 1
+	`, workspace, workspace).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		if workspace != "" {
+			return 0, fmt.Errorf("no conversations to continue in workspace %s", workspace)
+		}
+		return 0, fmt.Errorf("no conversations to continue")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest conversation: %w", err)
+	}
+
+	return id, nil
+}
+
+// DeleteMessages removes the messages with the given IDs.
+func (s *SQLiteStore) DeleteMessages(ctx context.Context, ids ...int64) error {
+	err := s.write(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, id); err != nil {
+				return fmt.Errorf("failed to delete message %d: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Debug("deleted messages", "ids", ids)
+	return nil
+}
+
+// SaveSummary stores a summary for a conversation and sets its ID.
+func (s *SQLiteStore) SaveSummary(ctx context.Context, sum *Summary) error {
+	id, err := s.insert(ctx,
+		`INSERT INTO summaries (conversation_id, through_message_id, content, model, created_at) VALUES (?, ?, ?, ?, ?)`,
+		sum.ConversationID, sum.ThroughMessageID, sum.Content, sum.Model, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert summary: %w", err)
+	}
+	sum.ID = id
+	slog.Debug("saved summary", "conversation", sum.ConversationID, "through_message", sum.ThroughMessageID)
+	return nil
+}
+
+// LatestSummary returns the summary covering the most of a conversation,
+// or nil if it has none.
+func (s *SQLiteStore) LatestSummary(ctx context.Context, conversationID int64) (*Summary, error) {
+	sum := &Summary{ConversationID: conversationID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, through_message_id, content, model, created_at
+		FROM summaries
+		WHERE conversation_id = ?
+		ORDER BY through_message_id DESC, id DESC
+		LIMIT 1
+	`, conversationID).Scan(&sum.ID, &sum.ThroughMessageID, &sum.Content, &sum.Model, &sum.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+	return sum, nil
+}
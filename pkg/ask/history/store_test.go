@@ -1,6 +1,10 @@
 package history
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -8,10 +12,10 @@ import (
 	"github.com/devaloi/ask/internal/util"
 )
 
-func TestNewStore(t *testing.T) {
-	store, err := NewStore(":memory:")
+func TestNewSQLiteStore(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -29,9 +33,9 @@ func TestNewStore(t *testing.T) {
 }
 
 func TestSaveConversation_NewConversation(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -45,7 +49,7 @@ func TestSaveConversation_NewConversation(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
@@ -60,9 +64,9 @@ func TestSaveConversation_NewConversation(t *testing.T) {
 }
 
 func TestSaveConversation_AutoGenerateTitle(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -117,12 +121,12 @@ func TestSaveConversation_AutoGenerateTitle(t *testing.T) {
 				Messages: tt.messages,
 			}
 
-			id, err := store.SaveConversation(conv)
+			id, err := store.SaveConversation(t.Context(), conv)
 			if err != nil {
 				t.Fatalf("SaveConversation failed: %v", err)
 			}
 
-			retrieved, err := store.GetConversation(id)
+			retrieved, err := store.GetConversation(t.Context(), id)
 			if err != nil {
 				t.Fatalf("GetConversation failed: %v", err)
 			}
@@ -135,9 +139,9 @@ func TestSaveConversation_AutoGenerateTitle(t *testing.T) {
 }
 
 func TestSaveConversation_AppendMessages(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -152,7 +156,7 @@ func TestSaveConversation_AppendMessages(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
@@ -163,7 +167,7 @@ func TestSaveConversation_AppendMessages(t *testing.T) {
 		{Role: "assistant", Content: "Second response"},
 	}
 
-	id2, err := store.SaveConversation(conv)
+	id2, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation (append) failed: %v", err)
 	}
@@ -173,7 +177,7 @@ func TestSaveConversation_AppendMessages(t *testing.T) {
 	}
 
 	// Verify all messages are stored
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -191,9 +195,9 @@ func TestSaveConversation_AppendMessages(t *testing.T) {
 }
 
 func TestListConversations_OrderedByDate(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -205,14 +209,14 @@ func TestListConversations_OrderedByDate(t *testing.T) {
 			Model:    "gpt-4",
 			Provider: "openai",
 		}
-		_, err := store.SaveConversation(conv)
+		_, err := store.SaveConversation(t.Context(), conv)
 		if err != nil {
 			t.Fatalf("SaveConversation failed: %v", err)
 		}
 		time.Sleep(10 * time.Millisecond) // Ensure different timestamps
 	}
 
-	conversations, err := store.ListConversations(10, "")
+	conversations, err := store.ListConversations(t.Context(), 10, "")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -231,9 +235,9 @@ func TestListConversations_OrderedByDate(t *testing.T) {
 }
 
 func TestListConversations_Limit(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -244,7 +248,7 @@ func TestListConversations_Limit(t *testing.T) {
 			Model:    "gpt-4",
 			Provider: "openai",
 		}
-		_, err := store.SaveConversation(conv)
+		_, err := store.SaveConversation(t.Context(), conv)
 		if err != nil {
 			t.Fatalf("SaveConversation failed: %v", err)
 		}
@@ -261,7 +265,7 @@ func TestListConversations_Limit(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		conversations, err := store.ListConversations(tt.limit, "")
+		conversations, err := store.ListConversations(t.Context(), tt.limit, "")
 		if err != nil {
 			t.Fatalf("ListConversations failed: %v", err)
 		}
@@ -273,9 +277,9 @@ func TestListConversations_Limit(t *testing.T) {
 }
 
 func TestListConversations_SearchByTitle(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -297,14 +301,14 @@ func TestListConversations_SearchByTitle(t *testing.T) {
 			Provider: "test",
 			Messages: []Message{{Role: "user", Content: c.content}},
 		}
-		_, err := store.SaveConversation(conv)
+		_, err := store.SaveConversation(t.Context(), conv)
 		if err != nil {
 			t.Fatalf("SaveConversation failed: %v", err)
 		}
 	}
 
 	// Search by title
-	conversations, err := store.ListConversations(10, "Go Programming")
+	conversations, err := store.ListConversations(t.Context(), 10, "Go Programming")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -319,9 +323,9 @@ func TestListConversations_SearchByTitle(t *testing.T) {
 }
 
 func TestListConversations_SearchByContent(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -339,11 +343,11 @@ func TestListConversations_SearchByContent(t *testing.T) {
 		Messages: []Message{{Role: "user", Content: "Explain Docker"}},
 	}
 
-	store.SaveConversation(conv1)
-	store.SaveConversation(conv2)
+	store.SaveConversation(t.Context(), conv1)
+	store.SaveConversation(t.Context(), conv2)
 
 	// Search by message content
-	conversations, err := store.ListConversations(10, "kubernetes")
+	conversations, err := store.ListConversations(t.Context(), 10, "kubernetes")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -358,9 +362,9 @@ func TestListConversations_SearchByContent(t *testing.T) {
 }
 
 func TestListConversations_SearchNoMatches(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -370,9 +374,9 @@ func TestListConversations_SearchNoMatches(t *testing.T) {
 		Provider: "test",
 		Messages: []Message{{Role: "user", Content: "Hello world"}},
 	}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
-	conversations, err := store.ListConversations(10, "nonexistent-search-term-xyz")
+	conversations, err := store.ListConversations(t.Context(), 10, "nonexistent-search-term-xyz")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -383,13 +387,13 @@ func TestListConversations_SearchNoMatches(t *testing.T) {
 }
 
 func TestListConversations_EmptyDatabase(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
-	conversations, err := store.ListConversations(10, "")
+	conversations, err := store.ListConversations(t.Context(), 10, "")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -400,9 +404,9 @@ func TestListConversations_EmptyDatabase(t *testing.T) {
 }
 
 func TestGetConversation_WithMessages(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -418,12 +422,12 @@ func TestGetConversation_WithMessages(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
 
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -467,13 +471,13 @@ func TestGetConversation_WithMessages(t *testing.T) {
 }
 
 func TestGetConversation_NotFound(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
-	_, err = store.GetConversation(999)
+	_, err = store.GetConversation(t.Context(), 999)
 	if err == nil {
 		t.Error("expected error for non-existent conversation, got nil")
 	}
@@ -484,9 +488,9 @@ func TestGetConversation_NotFound(t *testing.T) {
 }
 
 func TestGetConversation_NoMessages(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -496,12 +500,12 @@ func TestGetConversation_NoMessages(t *testing.T) {
 		Provider: "openai",
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
 
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -512,9 +516,9 @@ func TestGetConversation_NoMessages(t *testing.T) {
 }
 
 func TestMessagesChronologicalOrder(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -527,7 +531,7 @@ func TestMessagesChronologicalOrder(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
@@ -535,18 +539,18 @@ func TestMessagesChronologicalOrder(t *testing.T) {
 	// Add more messages over time
 	time.Sleep(10 * time.Millisecond)
 	conv.Messages = []Message{{Role: "assistant", Content: "Message 2"}}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
 	time.Sleep(10 * time.Millisecond)
 	conv.Messages = []Message{{Role: "user", Content: "Message 3"}}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
 	time.Sleep(10 * time.Millisecond)
 	conv.Messages = []Message{{Role: "assistant", Content: "Message 4"}}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
 	// Retrieve and verify order
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -651,9 +655,9 @@ func TestTruncate(t *testing.T) {
 }
 
 func TestStoreClose(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 
 	err = store.Close()
@@ -670,9 +674,9 @@ func TestStoreClose(t *testing.T) {
 }
 
 func TestSaveConversation_PreservesExplicitTitle(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -685,12 +689,12 @@ func TestSaveConversation_PreservesExplicitTitle(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
 
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -701,9 +705,9 @@ func TestSaveConversation_PreservesExplicitTitle(t *testing.T) {
 }
 
 func TestSaveConversation_SkipsAlreadyPersistedMessages(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -716,13 +720,13 @@ func TestSaveConversation_SkipsAlreadyPersistedMessages(t *testing.T) {
 		},
 	}
 
-	id, err := store.SaveConversation(conv)
+	id, err := store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
 
 	// Retrieve to get the message IDs
-	retrieved, err := store.GetConversation(id)
+	retrieved, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -730,13 +734,13 @@ func TestSaveConversation_SkipsAlreadyPersistedMessages(t *testing.T) {
 	// Include already persisted message (with ID) and new message (without ID)
 	conv.Messages = append(retrieved.Messages, Message{Role: "assistant", Content: "Second"})
 
-	_, err = store.SaveConversation(conv)
+	_, err = store.SaveConversation(t.Context(), conv)
 	if err != nil {
 		t.Fatalf("SaveConversation failed: %v", err)
 	}
 
 	// Verify only one new message was added
-	final, err := store.GetConversation(id)
+	final, err := store.GetConversation(t.Context(), id)
 	if err != nil {
 		t.Fatalf("GetConversation failed: %v", err)
 	}
@@ -747,9 +751,9 @@ func TestSaveConversation_SkipsAlreadyPersistedMessages(t *testing.T) {
 }
 
 func TestListConversations_SearchCaseInsensitive(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -759,7 +763,7 @@ func TestListConversations_SearchCaseInsensitive(t *testing.T) {
 		Provider: "test",
 		Messages: []Message{{Role: "user", Content: "Tell me about KUBERNETES"}},
 	}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
 	tests := []struct {
 		search   string
@@ -773,7 +777,7 @@ func TestListConversations_SearchCaseInsensitive(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		conversations, err := store.ListConversations(10, tt.search)
+		conversations, err := store.ListConversations(t.Context(), 10, tt.search)
 		if err != nil {
 			t.Fatalf("ListConversations failed for %q: %v", tt.search, err)
 		}
@@ -785,9 +789,9 @@ func TestListConversations_SearchCaseInsensitive(t *testing.T) {
 }
 
 func TestListConversations_PartialMatch(t *testing.T) {
-	store, err := NewStore(":memory:")
+	store, err := NewSQLiteStore(":memory:")
 	if err != nil {
-		t.Fatalf("NewStore failed: %v", err)
+		t.Fatalf("NewSQLiteStore failed: %v", err)
 	}
 	defer store.Close()
 
@@ -796,10 +800,10 @@ func TestListConversations_PartialMatch(t *testing.T) {
 		Model:    "gpt-4",
 		Provider: "test",
 	}
-	store.SaveConversation(conv)
+	store.SaveConversation(t.Context(), conv)
 
 	// Search for partial match
-	conversations, err := store.ListConversations(10, "gram")
+	conversations, err := store.ListConversations(t.Context(), 10, "gram")
 	if err != nil {
 		t.Fatalf("ListConversations failed: %v", err)
 	}
@@ -808,3 +812,349 @@ func TestListConversations_PartialMatch(t *testing.T) {
 		t.Errorf("expected 1 conversation for partial match, got %d", len(conversations))
 	}
 }
+
+func TestLatestConversationID(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.LatestConversationID(t.Context()); err == nil {
+		t.Error("expected error for empty store")
+	}
+
+	first := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{{Role: "user", Content: "First"}},
+	}
+	if _, err := store.SaveConversation(t.Context(), first); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	second := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{{Role: "user", Content: "Second"}},
+	}
+	if _, err := store.SaveConversation(t.Context(), second); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	id, err := store.LatestConversationID(t.Context())
+	if err != nil {
+		t.Fatalf("LatestConversationID failed: %v", err)
+	}
+	if id != second.ID {
+		t.Errorf("expected latest ID %d, got %d", second.ID, id)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Adding a message makes the first conversation the most recently active
+	first.Messages = []Message{{Role: "user", Content: "Follow up"}}
+	if _, err := store.SaveConversation(t.Context(), first); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	id, err = store.LatestConversationID(t.Context())
+	if err != nil {
+		t.Fatalf("LatestConversationID failed: %v", err)
+	}
+	if id != first.ID {
+		t.Errorf("expected latest ID %d, got %d", first.ID, id)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	convs := []*Conversation{
+		{Model: "gpt-4", Provider: "openai", Workspace: "ask", Messages: []Message{{Role: "user", Content: "In ask"}}},
+		{Model: "gpt-4", Provider: "openai", Messages: []Message{{Role: "user", Content: "Nowhere"}}},
+		{Model: "gpt-4", Provider: "openai", Workspace: "other", Messages: []Message{{Role: "user", Content: "In other"}}},
+	}
+	for _, conv := range convs {
+		if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+			t.Fatalf("SaveConversation failed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got, err := store.GetConversation(t.Context(), convs[0].ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if got.Workspace != "ask" {
+		t.Errorf("Workspace = %q, want ask", got.Workspace)
+	}
+
+	listed, err := store.ListConversationsIn(t.Context(), "ask", 10, "")
+	if err != nil {
+		t.Fatalf("ListConversationsIn failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != convs[0].ID || listed[0].Workspace != "ask" {
+		t.Errorf("ListConversationsIn(ask) = %+v, want only the first conversation", listed)
+	}
+	if listed, _ := store.ListConversationsIn(t.Context(), "ask", 10, "other"); len(listed) != 0 {
+		t.Errorf("search across workspaces found %+v", listed)
+	}
+	if listed, _ := store.ListConversations(t.Context(), 10, ""); len(listed) != 3 {
+		t.Errorf("ListConversations found %d, want all 3", len(listed))
+	}
+
+	id, err := store.LatestConversationIDIn(t.Context(), "ask")
+	if err != nil || id != convs[0].ID {
+		t.Errorf("LatestConversationIDIn(ask) = %d, %v; want %d", id, err, convs[0].ID)
+	}
+	if _, err := store.LatestConversationIDIn(t.Context(), "missing"); err == nil {
+		t.Error("LatestConversationIDIn of an empty workspace succeeded")
+	}
+	if id, _ := store.LatestConversationID(t.Context()); id != convs[2].ID {
+		t.Errorf("LatestConversationID = %d, want %d", id, convs[2].ID)
+	}
+}
+
+func TestNewSQLiteStore_MigratesExistingDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+
+	// Create a database with the original, unversioned schema
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	for _, stmt := range migrations[:4] {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("creating old schema: %v", err)
+		}
+	}
+	_, err = db.Exec(`INSERT INTO conversations (title, model, provider, created_at) VALUES ('Old', 'gpt-4', 'openai', ?)`, time.Now())
+	if err != nil {
+		t.Fatalf("inserting conversation: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO messages (conversation_id, role, content, created_at) VALUES (1, 'user', 'Hello', ?)`, time.Now())
+	if err != nil {
+		t.Fatalf("inserting message: %v", err)
+	}
+	db.Close()
+
+	store, err := NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	conv, err := store.GetConversation(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if len(conv.Messages) != 1 || conv.Messages[0].Attempt != 1 {
+		t.Errorf("expected existing message with attempt 1, got %+v", conv.Messages)
+	}
+
+	var version int
+	if err := store.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("reading user_version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("expected user_version %d, got %d", len(migrations), version)
+	}
+
+	// Reopening an up-to-date database is a no-op
+	store.Close()
+	store, err = NewSQLiteStore(dbPath)
+	if err != nil {
+		t.Fatalf("reopening store failed: %v", err)
+	}
+}
+
+func TestSaveConversation_SetsMessageIDsAndAttempts(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	conv := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{
+			{Role: "user", Content: "Hello"},
+			{Role: "assistant", Content: "Hi", Attempt: 3},
+		},
+	}
+	if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	for i, msg := range conv.Messages {
+		if msg.ID == 0 {
+			t.Errorf("message %d: expected ID to be set", i)
+		}
+	}
+
+	loaded, err := store.GetConversation(t.Context(), conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if loaded.Messages[0].Attempt != 1 {
+		t.Errorf("expected default attempt 1, got %d", loaded.Messages[0].Attempt)
+	}
+	if loaded.Messages[1].Attempt != 3 {
+		t.Errorf("expected attempt 3, got %d", loaded.Messages[1].Attempt)
+	}
+}
+
+func TestSaveConversation_Partial(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	conv := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{
+			{Role: "user", Content: "Write an essay"},
+			{Role: "assistant", Content: "Once upon", Partial: true},
+		},
+	}
+	if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	loaded, err := store.GetConversation(t.Context(), conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if loaded.Messages[0].Partial {
+		t.Error("user message loaded as partial")
+	}
+	if !loaded.Messages[1].Partial {
+		t.Error("partial response loaded as complete")
+	}
+}
+
+func TestDeleteMessages(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	conv := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{
+			{Role: "user", Content: "One"},
+			{Role: "assistant", Content: "Two"},
+			{Role: "user", Content: "Three"},
+		},
+	}
+	if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	if err := store.DeleteMessages(t.Context(), conv.Messages[1].ID, conv.Messages[2].ID); err != nil {
+		t.Fatalf("DeleteMessages failed: %v", err)
+	}
+
+	loaded, err := store.GetConversation(t.Context(), conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Content != "One" {
+		t.Errorf("expected only the first message to remain, got %+v", loaded.Messages)
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	conv := &Conversation{
+		Model:    "gpt-4",
+		Provider: "openai",
+		Messages: []Message{
+			{Role: "user", Content: "One"},
+			{Role: "assistant", Content: "Two"},
+			{Role: "user", Content: "Three"},
+			{Role: "assistant", Content: "Four"},
+		},
+	}
+	if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+		t.Fatalf("SaveConversation failed: %v", err)
+	}
+
+	sum, err := store.LatestSummary(t.Context(), conv.ID)
+	if err != nil || sum != nil {
+		t.Fatalf("LatestSummary() with none = %+v, %v; want nil, nil", sum, err)
+	}
+
+	for _, s := range []*Summary{
+		{ConversationID: conv.ID, ThroughMessageID: conv.Messages[3].ID, Content: "later", Model: "m"},
+		{ConversationID: conv.ID, ThroughMessageID: conv.Messages[1].ID, Content: "earlier", Model: "m"},
+	} {
+		if err := store.SaveSummary(t.Context(), s); err != nil {
+			t.Fatalf("SaveSummary failed: %v", err)
+		}
+		if s.ID == 0 {
+			t.Error("SaveSummary did not set the ID")
+		}
+	}
+
+	sum, err = store.LatestSummary(t.Context(), conv.ID)
+	if err != nil {
+		t.Fatalf("LatestSummary failed: %v", err)
+	}
+	if sum.Content != "later" || sum.ThroughMessageID != conv.Messages[3].ID {
+		t.Errorf("LatestSummary() = %+v, want the one covering the most messages", sum)
+	}
+
+	// The summarized messages are kept
+	loaded, err := store.GetConversation(t.Context(), conv.ID)
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if len(loaded.Messages) != 4 {
+		t.Errorf("expected 4 messages, got %d", len(loaded.Messages))
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	conv := &Conversation{Model: "gpt-4o", Provider: "openai", Messages: []Message{{Role: "user", Content: "hi"}}}
+	if _, err := store.SaveConversation(ctx, conv); !errors.Is(err, context.Canceled) {
+		t.Errorf("SaveConversation() error = %v, want context.Canceled", err)
+	}
+	if conv.ID != 0 {
+		t.Errorf("conversation ID = %d after a cancelled save, want 0", conv.ID)
+	}
+	if _, err := store.ListConversations(ctx, 10, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListConversations() error = %v, want context.Canceled", err)
+	}
+
+	convs, err := store.ListConversations(t.Context(), 10, "")
+	if err != nil || len(convs) != 0 {
+		t.Errorf("ListConversations() = %d conversations, %v; want none saved", len(convs), err)
+	}
+}
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Usage records the tokens and estimated cost of one provider request.
+// It is kept independently of conversations, so deleting history does not
+// lose spending records.
+type Usage struct {
+	ID           int64
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64 // US dollars
+	CreatedAt    time.Time
+}
+
+// RecordUsage stores u and sets its ID. A zero CreatedAt means now.
+func (s *SQLiteStore) RecordUsage(ctx context.Context, u *Usage) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	// Stored in UTC so timestamps compare correctly as text
+	id, err := s.insert(ctx,
+		`INSERT INTO usage (provider, model, input_tokens, output_tokens, cost, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		u.Provider, u.Model, u.InputTokens, u.OutputTokens, u.Cost, u.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	u.ID = id
+	slog.Debug("recorded usage", "provider", u.Provider, "model", u.Model, "input_tokens", u.InputTokens, "output_tokens", u.OutputTokens, "cost", u.Cost)
+	return nil
+}
+
+// Spend returns the total recorded cost since the given time, for one
+// provider or, if providerName is empty, for all of them.
+func (s *SQLiteStore) Spend(ctx context.Context, providerName string, since time.Time) (float64, error) {
+	var total float64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(cost), 0)
+		FROM usage
+		WHERE created_at >= ? AND (? = '' OR provider = ?)
+	`, since.UTC(), providerName, providerName).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage: %w", err)
+	}
+	return total, nil
+}
+
+// ListUsage returns the usage recorded in [since, until), oldest first.
+func (s *SQLiteStore) ListUsage(ctx context.Context, since, until time.Time) ([]Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, provider, model, input_tokens, output_tokens, cost, created_at
+		FROM usage
+		WHERE created_at >= ? AND created_at < ?
+		ORDER BY created_at, id
+	`, since.UTC(), until.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Usage
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.ID, &u.Provider, &u.Model, &u.InputTokens, &u.OutputTokens, &u.Cost, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		records = append(records, u)
+	}
+	return records, rows.Err()
+}
+
+// UsageTotal sums usage records. Day, Provider, and Model are set when
+// the records were grouped by them.
+type UsageTotal struct {
+	Day          string  `json:"day,omitempty"` // YYYY-MM-DD, local time
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// Add adds u to the total.
+func (t *UsageTotal) Add(u Usage) {
+	t.Requests++
+	t.InputTokens += u.InputTokens
+	t.OutputTokens += u.OutputTokens
+	t.Cost += u.Cost
+}
+
+// Usage grouping fields for SumUsage.
+const (
+	GroupDay      = "day"
+	GroupProvider = "provider"
+	GroupModel    = "model"
+)
+
+// SumUsage totals records grouped by any of GroupDay, GroupProvider,
+// and GroupModel, sorted by day, provider, and model.
+func SumUsage(records []Usage, by ...string) []UsageTotal {
+	index := make(map[UsageTotal]int)
+	var totals []UsageTotal
+	for _, u := range records {
+		var key UsageTotal
+		if slices.Contains(by, GroupDay) {
+			key.Day = u.CreatedAt.Local().Format(time.DateOnly)
+		}
+		if slices.Contains(by, GroupProvider) {
+			key.Provider = u.Provider
+		}
+		if slices.Contains(by, GroupModel) {
+			key.Model = u.Model
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(totals)
+			index[key] = i
+			totals = append(totals, key)
+		}
+		totals[i].Add(u)
+	}
+
+	slices.SortFunc(totals, func(a, b UsageTotal) int {
+		return cmp.Or(cmp.Compare(a.Day, b.Day), cmp.Compare(a.Provider, b.Provider), cmp.Compare(a.Model, b.Model))
+	})
+	return totals
+}
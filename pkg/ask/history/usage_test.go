@@ -0,0 +1,108 @@
+package history
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSpend(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	records := []Usage{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, Cost: 1.25, CreatedAt: now.Add(-time.Hour)},
+		{Provider: "anthropic", Model: "claude-sonnet-4", Cost: 2, CreatedAt: now.Add(-2 * time.Hour)},
+		{Provider: "openai", Model: "gpt-4o", Cost: 10, CreatedAt: now.Add(-48 * time.Hour)},
+		{Provider: "openai", Model: "gpt-4o-mini", Cost: 0.5},
+	}
+	for i := range records {
+		if err := store.RecordUsage(t.Context(), &records[i]); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+		if records[i].ID == 0 {
+			t.Errorf("RecordUsage did not set the ID")
+		}
+	}
+
+	tests := []struct {
+		provider string
+		since    time.Time
+		want     float64
+	}{
+		{"", now.Add(-24 * time.Hour), 3.75},
+		{"openai", now.Add(-24 * time.Hour), 1.75},
+		{"anthropic", now.Add(-24 * time.Hour), 2},
+		{"openai", now.Add(-72 * time.Hour), 11.75},
+		{"", now.Add(time.Minute), 0},
+		{"other", time.Time{}, 0},
+	}
+	for _, tt := range tests {
+		got, err := store.Spend(t.Context(), tt.provider, tt.since)
+		if err != nil {
+			t.Fatalf("Spend(%q) failed: %v", tt.provider, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Spend(%q, %v) = %v, want %v", tt.provider, tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestListAndSumUsage(t *testing.T) {
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close()
+
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	records := []Usage{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 10, Cost: 1, CreatedAt: day1},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 200, OutputTokens: 20, Cost: 2, CreatedAt: day1.Add(time.Hour)},
+		{Provider: "anthropic", Model: "claude", InputTokens: 50, OutputTokens: 5, Cost: 0.5, CreatedAt: day2},
+		{Provider: "openai", Model: "gpt-4o-mini", InputTokens: 10, OutputTokens: 1, Cost: 0.1, CreatedAt: day2},
+		{Provider: "openai", Model: "gpt-4o", Cost: 9, CreatedAt: day1.AddDate(0, 1, 0)},
+	}
+	for i := range records {
+		if err := store.RecordUsage(t.Context(), &records[i]); err != nil {
+			t.Fatalf("RecordUsage failed: %v", err)
+		}
+	}
+
+	listed, err := store.ListUsage(t.Context(), day1.AddDate(0, 0, -1), day1.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("ListUsage failed: %v", err)
+	}
+	if len(listed) != 4 {
+		t.Fatalf("ListUsage returned %d records, want 4", len(listed))
+	}
+
+	byDay := SumUsage(listed, GroupDay)
+	if len(byDay) != 2 || byDay[0].Day != "2025-03-01" || byDay[0].Requests != 2 || byDay[0].InputTokens != 300 || byDay[0].Cost != 3 {
+		t.Errorf("SumUsage(day) = %+v", byDay)
+	}
+
+	byModel := SumUsage(listed, GroupProvider, GroupModel)
+	want := []UsageTotal{
+		{Provider: "anthropic", Model: "claude", Requests: 1, InputTokens: 50, OutputTokens: 5, Cost: 0.5},
+		{Provider: "openai", Model: "gpt-4o", Requests: 2, InputTokens: 300, OutputTokens: 30, Cost: 3},
+		{Provider: "openai", Model: "gpt-4o-mini", Requests: 1, InputTokens: 10, OutputTokens: 1, Cost: 0.1},
+	}
+	if len(byModel) != len(want) {
+		t.Fatalf("SumUsage(provider, model) = %+v, want %+v", byModel, want)
+	}
+	for i := range want {
+		if byModel[i] != want[i] {
+			t.Errorf("SumUsage(provider, model)[%d] = %+v, want %+v", i, byModel[i], want[i])
+		}
+	}
+
+	if all := SumUsage(listed); len(all) != 1 || all[0].Requests != 4 {
+		t.Errorf("SumUsage() = %+v, want a single total of 4 requests", all)
+	}
+}
@@ -0,0 +1,105 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Several ask processes may write to history at once: an interactive
+// session, piped one-shot chats, and batch runs. SQLite allows one writer
+// at a time, so write transactions take the database's write lock when
+// they begin (BEGIN IMMEDIATE) rather than part way through, wait up to
+// busyTimeout for other processes to release it, and are retried with
+// backoff if it stays busy. Within a process, writes queue behind the
+// store's mutex instead of contending for the lock. The WAL journal lets
+// readers carry on while a write is in progress.
+const (
+	busyTimeout      = 5 * time.Second
+	maxWriteAttempts = 5
+	retryDelay       = 50 * time.Millisecond
+)
+
+// dataSourceName returns the DSN that opens the database at path with the
+// busy timeout, immediate transactions, and the WAL journal.
+func dataSourceName(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_busy_timeout=%d&_txlock=immediate&_journal_mode=WAL", path, sep, busyTimeout.Milliseconds())
+}
+
+// write runs fn in a transaction holding the database's write lock. If
+// another process holds the lock for too long, the transaction is rolled
+// back and fn is run again, so fn must only change state outside the
+// transaction once write returns successfully. Cancelling ctx rolls the
+// transaction back and stops any retries.
+func (s *SQLiteStore) write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := s.writeOnce(ctx, fn)
+		if err == nil || !isBusy(err) || attempt == maxWriteAttempts {
+			return err
+		}
+		slog.Debug("history database busy, retrying", "attempt", attempt, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+func (s *SQLiteStore) writeOnce(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insert runs an INSERT statement as a write and returns the ID of the
+// row it added.
+func (s *SQLiteStore) insert(ctx context.Context, query string, args ...any) (int64, error) {
+	var id int64
+	err := s.write(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		id, err = result.LastInsertId()
+		return err
+	})
+	return id, err
+}
+
+// exec runs a statement as a write and returns the number of rows it
+// affected.
+func (s *SQLiteStore) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	var n int64
+	err := s.write(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		n, err = result.RowsAffected()
+		return err
+	})
+	return n, err
+}
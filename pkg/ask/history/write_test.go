@@ -0,0 +1,111 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentWriters has several stores, standing in for separate ask
+// processes, create and write to one database at the same time.
+func TestConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	const writers, perWriter = 6, 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store, err := NewSQLiteStore(path)
+			if err != nil {
+				errs <- fmt.Errorf("writer %d: NewStore: %w", w, err)
+				return
+			}
+			defer store.Close()
+
+			for i := range perWriter {
+				conv := &Conversation{
+					Model:    "gpt-4o",
+					Provider: "openai",
+					Messages: []Message{
+						{Role: "user", Content: fmt.Sprintf("question %d.%d", w, i)},
+						{Role: "assistant", Content: "answer"},
+					},
+				}
+				if _, err := store.SaveConversation(t.Context(), conv); err != nil {
+					errs <- fmt.Errorf("writer %d: SaveConversation: %w", w, err)
+					continue
+				}
+				if err := store.RecordUsage(t.Context(), &Usage{Provider: "openai", Model: "gpt-4o", Cost: 0.01}); err != nil {
+					errs <- fmt.Errorf("writer %d: RecordUsage: %w", w, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var conversations, messages, usage int
+	store.db.QueryRow(`SELECT COUNT(*) FROM conversations`).Scan(&conversations)
+	store.db.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messages)
+	store.db.QueryRow(`SELECT COUNT(*) FROM usage`).Scan(&usage)
+	if want := writers * perWriter; conversations != want || messages != 2*want || usage != want {
+		t.Errorf("got %d conversations, %d messages, %d usage records; want %d, %d, %d",
+			conversations, messages, usage, want, 2*want, want)
+	}
+}
+
+// TestWriteWaitsForLock checks that a write waits for another process's
+// write to finish rather than failing.
+func TestWriteWaitsForLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	holder, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer holder.Close()
+	writer, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	tx, err := holder.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`INSERT INTO usage (provider, model, input_tokens, output_tokens, cost, created_at) VALUES ('openai', 'gpt-4o', 1, 1, 0, ?)`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		close(released)
+		tx.Commit()
+	}()
+
+	conv := &Conversation{Model: "gpt-4o", Provider: "openai", Messages: []Message{{Role: "user", Content: "hi"}}}
+	if _, err := writer.SaveConversation(t.Context(), conv); err != nil {
+		t.Fatalf("SaveConversation() error = %v", err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("SaveConversation() did not wait for the lock")
+	}
+	if conv.ID == 0 || conv.Messages[0].ID == 0 || conv.Messages[0].ConversationID != conv.ID {
+		t.Errorf("IDs not set: %+v", conv)
+	}
+}
@@ -0,0 +1,344 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/devaloi/ask/internal/logging"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/sse"
+)
+
+const (
+	anthropicAPIURL     = "https://api.anthropic.com/v1/messages"
+	anthropicCountURL   = "https://api.anthropic.com/v1/messages/count_tokens"
+	anthropicAPIVersion = "2023-06-01"
+	defaultMaxTokens    = 4096
+)
+
+// Anthropic implements the Provider interface for Anthropic's Claude API.
+type Anthropic struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewAnthropic creates a new Anthropic provider with the given API key.
+func NewAnthropic(apiKey string) *Anthropic {
+	return &Anthropic{
+		apiKey: apiKey,
+		client: &http.Client{},
+	}
+}
+
+// SetTransport sends a's requests through rt.
+func (a *Anthropic) SetTransport(rt http.RoundTripper) {
+	a.client.Transport = rt
+}
+
+// Name returns the provider name.
+func (a *Anthropic) Name() string {
+	return "anthropic"
+}
+
+// Models returns the list of available Claude models.
+func (a *Anthropic) Models() []string {
+	return []string{
+		"claude-sonnet-4-20250514",
+		"claude-3-5-haiku-20241022",
+		"claude-3-opus-20240229",
+	}
+}
+
+// anthropicRequest is the request body for the Anthropic API.
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	Messages    []anthropicMessage `json:"messages"`
+	System      string             `json:"system,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	Temperature float64            `json:"temperature,omitempty"`
+	Stream      bool               `json:"stream"`
+}
+
+// anthropicMessage represents a message in the Anthropic API format.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicSSEEvent represents a parsed SSE event from the Anthropic API.
+type anthropicSSEEvent struct {
+	Type  string          `json:"type"`
+	Delta json.RawMessage `json:"delta,omitempty"`
+}
+
+// anthropicDelta represents the delta object in content_block_delta events.
+type anthropicDelta struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+
+	// StopReason is set in message_delta events.
+	StopReason string `json:"stop_reason"`
+
+	// Citation is set in citations_delta events.
+	Citation *struct {
+		URL   string `json:"url"`
+		Title string `json:"title"`
+	} `json:"citation"`
+}
+
+// anthropicTool is a server tool, run by Anthropic.
+type anthropicTool struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	MaxUses int    `json:"max_uses,omitempty"`
+}
+
+// anthropicWebSearch is the web search tool for WebSearch requests.
+var anthropicWebSearch = anthropicTool{Type: "web_search_20250305", Name: "web_search", MaxUses: 5}
+
+// splitSystem separates system messages, which Anthropic expects as a
+// top-level field, from user/assistant messages.
+func splitSystem(msgs []Message) (string, []anthropicMessage) {
+	var systemPrompt string
+	var messages []anthropicMessage
+
+	for _, msg := range msgs {
+		if msg.Role == "system" {
+			if systemPrompt != "" {
+				systemPrompt += "\n\n"
+			}
+			systemPrompt += msg.Content
+		} else {
+			messages = append(messages, anthropicMessage(msg))
+		}
+	}
+
+	return systemPrompt, messages
+}
+
+// newHTTPRequest creates a JSON POST request to url with the required headers.
+func (a *Anthropic) newHTTPRequest(ctx context.Context, url string, body any) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set required headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", a.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	return httpReq, nil
+}
+
+// BuildRequest builds the HTTP request that Chat would send for req.
+func (a *Anthropic) BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error) {
+	systemPrompt, messages := splitSystem(req.Messages)
+
+	// Set max_tokens to default if not specified (required by Anthropic API)
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
+
+	// Build the request body
+	apiReq := anthropicRequest{
+		Model:     req.Model,
+		Messages:  messages,
+		System:    systemPrompt,
+		MaxTokens: maxTokens,
+		Stream:    true,
+	}
+
+	// Only include temperature if it's set (non-zero)
+	if req.Temperature > 0 {
+		apiReq.Temperature = req.Temperature
+	}
+	if req.WebSearch {
+		apiReq.Tools = []anthropicTool{anthropicWebSearch}
+	}
+
+	return a.newHTTPRequest(ctx, anthropicAPIURL, apiReq)
+}
+
+// Prefill returns req with partial as the start of the reply, which
+// Anthropic's models continue when it is the last message. Trailing
+// whitespace is left off, since the API refuses it.
+func (a *Anthropic) Prefill(req *ChatRequest, partial string) *ChatRequest {
+	prefilled := *req
+	prefilled.Messages = append(slices.Clone(req.Messages), Message{Role: "assistant", Content: strings.TrimRightFunc(partial, unicode.IsSpace)})
+	return &prefilled
+}
+
+// anthropicCountRequest is the request body for the count_tokens API.
+type anthropicCountRequest struct {
+	Model    string             `json:"model"`
+	Messages []anthropicMessage `json:"messages"`
+	System   string             `json:"system,omitempty"`
+}
+
+// anthropicCountResponse is the response body from the count_tokens API.
+type anthropicCountResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// CountTokens returns the number of input tokens req would consume,
+// using Anthropic's count_tokens API.
+func (a *Anthropic) CountTokens(ctx context.Context, req *ChatRequest) (int, error) {
+	systemPrompt, messages := splitSystem(req.Messages)
+
+	httpReq, err := a.newHTTPRequest(ctx, anthropicCountURL, anthropicCountRequest{
+		Model:    req.Model,
+		Messages: messages,
+		System:   systemPrompt,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, httpError("anthropic", resp, "check your ANTHROPIC_API_KEY")
+	}
+
+	var countResp anthropicCountResponse
+	if err := json.NewDecoder(resp.Body).Decode(&countResp); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return countResp.InputTokens, nil
+}
+
+// Chat sends a chat request to the Anthropic API and streams tokens to the channel.
+func (a *Anthropic) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	defer close(stream)
+
+	httpReq, err := a.BuildRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	// Send the request
+	start := time.Now()
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	slog.Debug("chat response", "provider", "anthropic", "status", resp.StatusCode, "elapsed", time.Since(start))
+
+	// Handle HTTP errors
+	if resp.StatusCode != http.StatusOK {
+		return httpError("anthropic", resp, "check your ANTHROPIC_API_KEY")
+	}
+
+	// Parse SSE stream
+	return a.parseSSEStream(ctx, resp.Body, stream)
+}
+
+// parseSSEStream parses the SSE stream from the Anthropic API and sends tokens to the channel.
+func (a *Anthropic) parseSSEStream(ctx context.Context, body io.Reader, stream chan<- string) error {
+	reader := sse.NewReader(ctx, body)
+	events := make(chan sse.Event, util.DefaultChannelBuffer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- reader.Read(events)
+		close(events)
+	}()
+
+	var cited citations
+	truncated := false
+	for event := range events {
+		// Handle message_stop event
+		if event.Type == "message_stop" {
+			if err := sendSources(ctx, stream, cited.list); err != nil {
+				return err
+			}
+			if truncated {
+				return &Error{Provider: "anthropic", Kind: ErrTruncated}
+			}
+			return nil
+		}
+		if event.Type == "error" {
+			return streamError("anthropic", event.Data, anthropicStreamErrors)
+		}
+
+		// Only process content_block_delta events, and message_delta
+		// events for the stop reason
+		if event.Type != "content_block_delta" && event.Type != "message_delta" {
+			continue
+		}
+
+		var sseEvent anthropicSSEEvent
+		if err := json.Unmarshal([]byte(event.Data), &sseEvent); err != nil {
+			slog.Warn("skipping malformed SSE event", "provider", "anthropic", "data", logging.Truncate(event.Data, 200), "error", err)
+			continue
+		}
+
+		// Extract text from delta
+		if sseEvent.Delta != nil {
+			var delta anthropicDelta
+			if err := json.Unmarshal(sseEvent.Delta, &delta); err != nil {
+				slog.Warn("skipping malformed SSE delta", "provider", "anthropic", "data", logging.Truncate(string(sseEvent.Delta), 200), "error", err)
+				continue
+			}
+
+			if delta.StopReason == "max_tokens" {
+				truncated = true
+			}
+			if delta.Citation != nil {
+				cited.add(delta.Citation.URL, delta.Citation.Title)
+			}
+			if delta.Text != "" {
+				select {
+				case stream <- delta.Text:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+
+	err := <-errCh
+	if err == nil {
+		slog.Warn("SSE stream ended without message_stop", "provider", "anthropic")
+	}
+	return streamEnded(ctx, "anthropic", err)
+}
+
+// anthropicStreamErrors categorizes the error types Anthropic reports in
+// error events.
+var anthropicStreamErrors = map[string]streamErrorKind{
+	"overloaded_error":     {ErrServer, true},
+	"api_error":            {ErrServer, true},
+	"rate_limit_error":     {ErrRateLimited, true},
+	"authentication_error": {ErrAuth, false},
+	"permission_error":     {ErrAuth, false},
+}
@@ -2,9 +2,12 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -200,42 +203,49 @@ func TestAnthropicChatHTTPErrors(t *testing.T) {
 		statusCode     int
 		responseBody   string
 		wantErrContain string
+		wantErrIs      error
 	}{
 		{
 			name:           "unauthorized",
 			statusCode:     http.StatusUnauthorized,
 			responseBody:   `{"error":{"message":"Invalid API Key"}}`,
 			wantErrContain: "invalid API key",
+			wantErrIs:      ErrAuth,
 		},
 		{
 			name:           "rate_limited",
 			statusCode:     http.StatusTooManyRequests,
 			responseBody:   `{"error":{"message":"Rate limit exceeded"}}`,
 			wantErrContain: "rate limited",
+			wantErrIs:      ErrRateLimited,
 		},
 		{
 			name:           "server_error",
 			statusCode:     http.StatusInternalServerError,
 			responseBody:   `{"error":{"message":"Internal server error"}}`,
 			wantErrContain: "service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "bad_gateway",
 			statusCode:     http.StatusBadGateway,
 			responseBody:   `{"error":{"message":"Bad gateway"}}`,
 			wantErrContain: "service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "service_unavailable",
 			statusCode:     http.StatusServiceUnavailable,
 			responseBody:   `{"error":{"message":"Service unavailable"}}`,
 			wantErrContain: "service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "bad_request",
 			statusCode:     http.StatusBadRequest,
 			responseBody:   `{"error":{"message":"Invalid request body"}}`,
 			wantErrContain: "API error",
+			wantErrIs:      ErrAPI,
 		},
 	}
 
@@ -265,6 +275,9 @@ func TestAnthropicChatHTTPErrors(t *testing.T) {
 			if !strings.Contains(err.Error(), tt.wantErrContain) {
 				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErrContain)
 			}
+			if !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("error = %v, want errors.Is %v", err, tt.wantErrIs)
+			}
 
 			// Verify stream is closed even on error
 			select {
@@ -464,6 +477,108 @@ func TestAnthropicChatMalformedSSE(t *testing.T) {
 	}
 }
 
+// TestAnthropicChatStreamErrors tests that errors after the response
+// began are reported rather than ending the response as if complete.
+func TestAnthropicChatStreamErrors(t *testing.T) {
+	delta := "event: content_block_delta\n" +
+		"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n" +
+		"\n"
+	tests := []struct {
+		name          string
+		sseResponse   string
+		wantTokens    string
+		wantKind      error
+		wantCode      string
+		wantRetryable bool
+	}{
+		{
+			name: "overloaded",
+			sseResponse: delta +
+				"event: error\n" +
+				"data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n" +
+				"\n",
+			wantTokens:    "Hel",
+			wantKind:      ErrServer,
+			wantCode:      "overloaded_error",
+			wantRetryable: true,
+		},
+		{
+			name: "invalid request",
+			sseResponse: "event: error\n" +
+				"data: {\"type\":\"error\",\"error\":{\"type\":\"invalid_request_error\",\"message\":\"bad\"}}\n" +
+				"\n",
+			wantKind: ErrAPI,
+			wantCode: "invalid_request_error",
+		},
+		{
+			name:          "ended early",
+			sseResponse:   delta,
+			wantTokens:    "Hel",
+			wantKind:      ErrServer,
+			wantRetryable: true,
+		},
+		{
+			name: "token limit",
+			sseResponse: delta +
+				"event: message_delta\n" +
+				"data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\",\"stop_sequence\":null},\"usage\":{\"output_tokens\":1}}\n" +
+				"\n" +
+				"event: message_stop\n" +
+				"data: {\"type\":\"message_stop\"}\n" +
+				"\n",
+			wantTokens: "Hel",
+			wantKind:   ErrTruncated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.sseResponse))
+			}))
+			defer server.Close()
+
+			provider := newTestAnthropicWithServer(server, "test-api-key")
+			stream := make(chan string, 10)
+			err := provider.Chat(context.Background(), &ChatRequest{
+				Messages: []Message{{Role: "user", Content: "test"}},
+				Model:    "claude-sonnet-4-20250514",
+			}, stream)
+
+			var tokens strings.Builder
+			for token := range stream {
+				tokens.WriteString(token)
+			}
+			if tokens.String() != tt.wantTokens {
+				t.Errorf("tokens = %q, want %q", tokens.String(), tt.wantTokens)
+			}
+			var pe *Error
+			if !errors.As(err, &pe) || !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Chat() error = %v, want a %v Error", err, tt.wantKind)
+			}
+			if pe.Code != tt.wantCode || pe.Retryable != tt.wantRetryable {
+				t.Errorf("error code = %q, retryable = %v; want %q, %v", pe.Code, pe.Retryable, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+// TestAnthropicPrefill tests that a partial response is sent as the
+// start of the reply, without the trailing whitespace the API refuses.
+func TestAnthropicPrefill(t *testing.T) {
+	req := &ChatRequest{Model: "claude-sonnet-4-20250514", Messages: []Message{{Role: "user", Content: "Tell me a story"}}}
+	got := (&Anthropic{}).Prefill(req, "Once upon \n")
+	want := []Message{{Role: "user", Content: "Tell me a story"}, {Role: "assistant", Content: "Once upon"}}
+	if !slices.Equal(got.Messages, want) || got.Model != req.Model {
+		t.Errorf("Prefill() = %+v, want messages %+v", got, want)
+	}
+	if len(req.Messages) != 1 {
+		t.Errorf("Prefill() changed the request: %+v", req.Messages)
+	}
+}
+
 // TestAnthropicChatSystemMessage tests that system messages are extracted to top-level field.
 func TestAnthropicChatSystemMessage(t *testing.T) {
 	var capturedBody []byte
@@ -617,6 +732,53 @@ func TestAnthropicChatMessageStop(t *testing.T) {
 	}
 }
 
+// TestAnthropicChatWebSearch tests that web search requests send the web
+// search tool and end the response with the cited sources.
+func TestAnthropicChatWebSearch(t *testing.T) {
+	sseResponse := "event: content_block_delta\n" +
+		"data: {\"type\":\"content_block_delta\",\"index\":2,\"delta\":{\"type\":\"text_delta\",\"text\":\"It is sunny.\"}}\n" +
+		"\n" +
+		"event: content_block_delta\n" +
+		"data: {\"type\":\"content_block_delta\",\"index\":2,\"delta\":{\"type\":\"citations_delta\",\"citation\":{\"type\":\"web_search_result_location\",\"url\":\"https://weather.example/today\",\"title\":\"Today's Weather\"}}}\n" +
+		"\n" +
+		"event: message_stop\n" +
+		"data: {\"type\":\"message_stop\"}\n" +
+		"\n"
+
+	var capturedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sseResponse))
+	}))
+	defer server.Close()
+
+	provider := newTestAnthropicWithServer(server, "test-api-key")
+
+	stream := make(chan string, 10)
+	req := &ChatRequest{
+		Messages:  []Message{{Role: "user", Content: "weather?"}},
+		Model:     "claude-sonnet-4-20250514",
+		WebSearch: true,
+	}
+	if err := provider.Chat(context.Background(), req, stream); err != nil {
+		t.Fatalf("Chat() returned error: %v", err)
+	}
+
+	var response strings.Builder
+	for token := range stream {
+		response.WriteString(token)
+	}
+	want := "It is sunny.\n\nSources:\n1. Today's Weather - https://weather.example/today\n"
+	if response.String() != want {
+		t.Errorf("response = %q, want %q", response.String(), want)
+	}
+	if !strings.Contains(string(capturedBody), `"tools":[{"type":"web_search_20250305","name":"web_search","max_uses":5}]`) {
+		t.Errorf("body should contain the web search tool: %s", capturedBody)
+	}
+}
+
 // TestAnthropicChatDefaultMaxTokens tests that default max_tokens is set when not specified.
 func TestAnthropicChatDefaultMaxTokens(t *testing.T) {
 	var capturedBody []byte
@@ -914,3 +1076,69 @@ func TestAnthropicChatRequestModel(t *testing.T) {
 		t.Errorf("request body should contain the specified model: %s", bodyStr)
 	}
 }
+
+// TestAnthropicCountTokens tests counting tokens via the count_tokens API.
+func TestAnthropicCountTokens(t *testing.T) {
+	var capturedPath string
+	var capturedBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedPath = r.URL.Path
+		body := make([]byte, r.ContentLength)
+		r.Body.Read(body)
+		capturedBody = body
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"input_tokens":42}`))
+	}))
+	defer server.Close()
+
+	provider := newTestAnthropicWithServer(server, "test-api-key")
+
+	req := &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: "Be brief"},
+			{Role: "user", Content: "Hello"},
+		},
+		Model: "claude-sonnet-4-20250514",
+	}
+
+	count, err := provider.CountTokens(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CountTokens() returned error: %v", err)
+	}
+
+	if count != 42 {
+		t.Errorf("CountTokens() = %d, want 42", count)
+	}
+	if capturedPath != "/v1/messages/count_tokens" {
+		t.Errorf("request path = %q, want %q", capturedPath, "/v1/messages/count_tokens")
+	}
+
+	bodyStr := string(capturedBody)
+	if !strings.Contains(bodyStr, `"system":"Be brief"`) {
+		t.Errorf("request body should contain system prompt: %s", bodyStr)
+	}
+	if strings.Contains(bodyStr, `"stream"`) || strings.Contains(bodyStr, `"max_tokens"`) {
+		t.Errorf("request body should not contain stream or max_tokens: %s", bodyStr)
+	}
+}
+
+// TestAnthropicCountTokensHTTPError tests error handling for the count_tokens API.
+func TestAnthropicCountTokensHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	provider := newTestAnthropicWithServer(server, "bad-key")
+
+	_, err := provider.CountTokens(context.Background(), &ChatRequest{
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+		Model:    "claude-sonnet-4-20250514",
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid API key") {
+		t.Errorf("CountTokens() error = %v, want invalid API key error", err)
+	}
+}
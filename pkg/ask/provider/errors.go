@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error categories returned by providers. Errors wrap one of these so
+// callers can classify failures with errors.Is.
+var (
+	// ErrNoAPIKey means no API key is configured for the provider.
+	ErrNoAPIKey = errors.New("API key not found")
+
+	// ErrAuth means the provider rejected the API key.
+	ErrAuth = errors.New("invalid API key")
+
+	// ErrRateLimited means the provider throttled the request.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrServer means the provider failed with a 5xx status.
+	ErrServer = errors.New("service error")
+
+	// ErrAPI means the provider rejected the request for another reason.
+	ErrAPI = errors.New("API error")
+
+	// ErrContentFiltered means the response was stopped by the
+	// provider's content filter.
+	ErrContentFiltered = errors.New("response blocked by content filter")
+
+	// ErrTruncated means the response stopped at the token limit before
+	// it was complete. What was streamed is still usable.
+	ErrTruncated = errors.New("response cut short by the token limit")
+)
+
+// Error is a failure reported by a provider's API, such as a rejected
+// request or a stream that ended in an error. It wraps one of the error
+// categories above, its Kind, so errors.Is still classifies it, while
+// errors.As gives callers the details.
+type Error struct {
+	// Provider is the name of the provider, such as "openai".
+	Provider string `json:"provider"`
+
+	// Kind is the category: ErrAuth, ErrRateLimited, ErrServer, ErrAPI,
+	// ErrContentFiltered, or ErrTruncated.
+	Kind error `json:"-"`
+
+	// Status is the HTTP status, or 0 for an error reported mid-stream.
+	Status int `json:"status,omitempty"`
+
+	// Code is the provider's error type or code, such as
+	// "insufficient_quota", if it gave one.
+	Code string `json:"code,omitempty"`
+
+	// Message is the provider's description of the error, if it gave one.
+	Message string `json:"message,omitempty"`
+
+	// Hint suggests what to do about the error, such as which API key to
+	// check, or when a rate limit resets.
+	Hint string `json:"hint,omitempty"`
+
+	// Retryable means the same request may succeed if sent again later.
+	Retryable bool `json:"retryable,omitempty"`
+
+	// RateLimits holds the provider's rate limits when it throttled the
+	// request, if it reported them.
+	RateLimits *RateLimits `json:"rate_limits,omitempty"`
+}
+
+// displayNames are the names of providers in error messages.
+var displayNames = map[string]string{
+	"openai":    "OpenAI",
+	"anthropic": "Anthropic",
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if name, ok := displayNames[e.Provider]; ok {
+		b.WriteString(name + " ")
+	} else if e.Provider != "" {
+		b.WriteString(e.Provider + " ")
+	}
+	if e.Kind != nil {
+		b.WriteString(e.Kind.Error())
+	} else {
+		b.WriteString("error")
+	}
+	if e.Status != 0 {
+		fmt.Fprintf(&b, " (status %d)", e.Status)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	} else if e.Code != "" {
+		b.WriteString(": " + e.Code)
+	}
+	if e.Hint != "" {
+		b.WriteString("; " + e.Hint)
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// IsRetryable reports whether the request that failed with err may
+// succeed if sent again: a provider Error marked Retryable, or otherwise
+// a rate limit or server error.
+func IsRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Retryable
+	}
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer)
+}
+
+// nonRetryableCodes are error codes of rate limits that waiting does not
+// lift.
+var nonRetryableCodes = map[string]bool{
+	"insufficient_quota": true, // OpenAI: the account is out of credit
+}
+
+// httpError returns the Error for resp, a failed response from the
+// provider called name, reading the provider's error from its body.
+// authHint is the Hint for a rejected API key.
+func httpError(name string, resp *http.Response, authHint string) *Error {
+	e := &Error{Provider: name, Status: resp.StatusCode}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		e.Message = fmt.Sprintf("failed to read response body: %v", err)
+	} else {
+		e.Code, e.Message = parseErrorBody(body)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		e.Kind = ErrAuth
+		e.Hint = authHint
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+		e.Retryable = !nonRetryableCodes[e.Code]
+		e.RateLimits = parseRateLimits(resp.Header, time.Now())
+		switch {
+		case !e.Retryable:
+		case e.RateLimits != nil && e.RateLimits.String() != "":
+			e.Hint = e.RateLimits.String()
+		default:
+			e.Hint = "please wait and try again"
+		}
+	case resp.StatusCode >= 500:
+		e.Kind = ErrServer
+		e.Retryable = true
+		e.Hint = "please try again later"
+	default:
+		e.Kind = ErrAPI
+		e.Retryable = resp.StatusCode == http.StatusRequestTimeout
+	}
+	return e
+}
+
+// parseErrorBody returns the error code and message of an error response
+// body. Both OpenAI and Anthropic send {"error": {"type", "message"}},
+// OpenAI with a more specific "code". A body that is not such JSON is the
+// message itself.
+func parseErrorBody(body []byte) (code, message string) {
+	var resp struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    any    `json:"code"` // a string, a number, or null
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error.Message == "" && resp.Error.Type == "" {
+		return "", strings.TrimSpace(string(body))
+	}
+	code = resp.Error.Type
+	if c, ok := resp.Error.Code.(string); ok && c != "" {
+		code = c
+	}
+	return code, resp.Error.Message
+}
+
+// streamEnded returns the error for a response from the provider called
+// name whose stream ended early: err, the failure reading it, such as a
+// dropped connection, or nil if it closed without one. Both may succeed
+// if sent again, or be resumed.
+func streamEnded(ctx context.Context, name string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	e := &Error{Provider: name, Kind: ErrServer, Message: "the response ended before it was complete", Retryable: true}
+	if err != nil {
+		e.Message = fmt.Sprintf("the connection was lost: %v", err)
+	}
+	return e
+}
+
+// streamErrorKind is the category of an error a provider reports
+// mid-stream, and whether a retry may succeed.
+type streamErrorKind struct {
+	kind      error
+	retryable bool
+}
+
+// streamError returns the Error for data, the error object that the
+// provider called name sends in place of the rest of a response that
+// fails after it began, such as when its servers are overloaded. kinds
+// categorizes it by its code; others are ErrAPI.
+func streamError(name, data string, kinds map[string]streamErrorKind) *Error {
+	e := &Error{Provider: name, Kind: ErrAPI}
+	e.Code, e.Message = parseErrorBody([]byte(data))
+	if k, ok := kinds[e.Code]; ok {
+		e.Kind, e.Retryable = k.kind, k.retryable
+	}
+	if e.Retryable {
+		e.Hint = "please try again later"
+	}
+	return e
+}
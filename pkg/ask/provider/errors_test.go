@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTTPError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		wantKind      error
+		wantCode      string
+		wantMessage   string
+		wantRetryable bool
+		wantErr       string
+	}{
+		{
+			name:        "openai auth",
+			status:      http.StatusUnauthorized,
+			body:        `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			wantKind:    ErrAuth,
+			wantCode:    "invalid_api_key",
+			wantMessage: "Incorrect API key provided",
+			wantErr:     "OpenAI invalid API key (status 401): Incorrect API key provided; check your KEY",
+		},
+		{
+			name:          "rate limit",
+			status:        http.StatusTooManyRequests,
+			body:          `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantKind:      ErrRateLimited,
+			wantCode:      "rate_limit_exceeded",
+			wantMessage:   "Rate limit reached",
+			wantRetryable: true,
+			wantErr:       "OpenAI rate limited (status 429): Rate limit reached; please wait and try again",
+		},
+		{
+			name:        "exhausted quota",
+			status:      http.StatusTooManyRequests,
+			body:        `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantKind:    ErrRateLimited,
+			wantCode:    "insufficient_quota",
+			wantMessage: "You exceeded your current quota",
+			wantErr:     "OpenAI rate limited (status 429): You exceeded your current quota",
+		},
+		{
+			name:          "overloaded",
+			status:        529,
+			body:          `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			wantKind:      ErrServer,
+			wantCode:      "overloaded_error",
+			wantMessage:   "Overloaded",
+			wantRetryable: true,
+			wantErr:       "OpenAI service error (status 529): Overloaded; please try again later",
+		},
+		{
+			name:        "null code",
+			status:      http.StatusBadRequest,
+			body:        `{"error":{"message":"Invalid model","type":"invalid_request_error","code":null}}`,
+			wantKind:    ErrAPI,
+			wantCode:    "invalid_request_error",
+			wantMessage: "Invalid model",
+			wantErr:     "OpenAI API error (status 400): Invalid model",
+		},
+		{
+			name:        "plain text body",
+			status:      http.StatusNotFound,
+			body:        "404 page not found\n",
+			wantKind:    ErrAPI,
+			wantMessage: "404 page not found",
+			wantErr:     "OpenAI API error (status 404): 404 page not found",
+		},
+		{
+			name:          "empty gateway error",
+			status:        http.StatusBadGateway,
+			wantKind:      ErrServer,
+			wantRetryable: true,
+			wantErr:       "OpenAI service error (status 502); please try again later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			e := httpError("openai", resp, "check your KEY")
+			if !errors.Is(e, tt.wantKind) {
+				t.Errorf("Kind = %v, want errors.Is %v", e.Kind, tt.wantKind)
+			}
+			if e.Code != tt.wantCode || e.Message != tt.wantMessage {
+				t.Errorf("Code, Message = %q, %q; want %q, %q", e.Code, e.Message, tt.wantCode, tt.wantMessage)
+			}
+			if e.Retryable != tt.wantRetryable {
+				t.Errorf("Retryable = %v, want %v", e.Retryable, tt.wantRetryable)
+			}
+			if e.Error() != tt.wantErr {
+				t.Errorf("Error() = %q\nwant %q", e.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"typed retryable", fmt.Errorf("chat stream: %w", &Error{Kind: ErrServer, Retryable: true}), true},
+		{"typed not retryable", &Error{Kind: ErrRateLimited, Code: "insufficient_quota"}, false},
+		{"rate limit sentinel", fmt.Errorf("%w: slow down", ErrRateLimited), true},
+		{"server sentinel", ErrServer, true},
+		{"auth", ErrAuth, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoLocal means ask was built without llama.cpp, so it cannot run
+// local models.
+var ErrNoLocal = errors.New("this build of ask cannot run local models; rebuild it with -tags llamacpp")
+
+// defaultLocalMaxTokens caps responses from local models when the request
+// sets no limit.
+const defaultLocalMaxTokens = 2048
+
+// Local implements the Provider interface by running GGUF models
+// in-process with llama.cpp, with no server or network. The model is the
+// path of a .gguf file. It needs ask built with -tags llamacpp and cgo,
+// against an installed llama.cpp; other builds fail with ErrNoLocal.
+//
+// The last model used stays loaded for the next request. Requests are
+// run one at a time.
+type Local struct {
+	mu     sync.Mutex
+	loaded localModel
+}
+
+// NewLocal creates a local provider, or fails with ErrNoLocal if this
+// build cannot run local models.
+func NewLocal() (*Local, error) {
+	if !llamaSupported {
+		return nil, ErrNoLocal
+	}
+	return &Local{}, nil
+}
+
+// Name returns the provider name.
+func (l *Local) Name() string {
+	return "local"
+}
+
+// Models returns nil: local models are files, named by path.
+func (l *Local) Models() []string {
+	return nil
+}
+
+// localRequest describes a local request for --dry-run.
+type localRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens"`
+}
+
+// BuildRequest returns a request describing what Chat would run. Local
+// models are not sent over HTTP, so its URL is the model's file.
+func (l *Local) BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error) {
+	path, err := localModelPath(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(localRequest{
+		Model:       path,
+		Messages:    req.Messages,
+		Temperature: req.Temperature,
+		MaxTokens:   localMaxTokens(req),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "file://"+filepath.ToSlash(path), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// localModelPath returns the absolute path of a model given as a .gguf
+// file, expanding a leading ~.
+func localModelPath(model string) (string, error) {
+	if !strings.HasSuffix(strings.ToLower(model), ".gguf") {
+		return "", &Error{Provider: "local", Kind: ErrAPI, Message: fmt.Sprintf("model %q is not a .gguf file; pass its path with -m, e.g. -m ~/models/qwen.gguf", model)}
+	}
+	if rest, ok := strings.CutPrefix(model, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		model = filepath.Join(home, rest)
+	}
+	return filepath.Abs(model)
+}
+
+func localMaxTokens(req *ChatRequest) int {
+	if req.MaxTokens > 0 {
+		return req.MaxTokens
+	}
+	return defaultLocalMaxTokens
+}
+
+// completeUTF8 returns the length of the longest prefix of b that does
+// not end partway through a character, since llama.cpp's tokens can
+// split one.
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,227 @@
+//go:build llamacpp && cgo
+
+package provider
+
+/*
+#cgo LDFLAGS: -lllama
+#include <stdlib.h>
+#include <llama.h>
+
+static void ask_discard_log(enum ggml_log_level level, const char *text, void *data) {}
+
+// ask_quiet stops llama.cpp logging to stderr, which is for the response.
+static void ask_quiet(void) {
+	llama_log_set(ask_discard_log, NULL);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const llamaSupported = true
+
+// llamaInit initializes llama.cpp once per process.
+var llamaInit sync.Once
+
+// localModel is the model Local has loaded.
+type localModel struct {
+	path  string
+	model *C.struct_llama_model
+	vocab *C.struct_llama_vocab
+}
+
+// Chat runs req with the local model at req.Model and streams tokens to
+// the channel.
+func (l *Local) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	defer close(stream)
+
+	path, err := localModelPath(req.Model)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(path); err != nil {
+		return err
+	}
+
+	prompt, err := l.applyTemplate(req.Messages)
+	if err != nil {
+		return err
+	}
+	tokens, n, err := l.tokenize(prompt)
+	if err != nil {
+		return err
+	}
+	defer C.free(unsafe.Pointer(tokens))
+
+	maxTokens := localMaxTokens(req)
+	params := C.llama_context_default_params()
+	params.n_ctx = C.uint32_t(int(n) + maxTokens)
+	params.n_batch = params.n_ctx
+	lctx := C.llama_init_from_model(l.loaded.model, params)
+	if lctx == nil {
+		return &Error{Provider: "local", Kind: ErrAPI, Message: fmt.Sprintf("cannot create a context of %d tokens", params.n_ctx)}
+	}
+	defer C.llama_free(lctx)
+
+	sampler := newLocalSampler(req.Temperature)
+	defer C.llama_sampler_free(sampler)
+
+	// The next token to decode lives in C memory, which the batch points to
+	next := (*C.llama_token)(C.malloc(C.size_t(unsafe.Sizeof(C.llama_token(0)))))
+	defer C.free(unsafe.Pointer(next))
+
+	start := time.Now()
+	batch := C.llama_batch_get_one(tokens, n)
+	var pending []byte
+	for generated := 0; generated < maxTokens; generated++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if rc := C.llama_decode(lctx, batch); rc != 0 {
+			return &Error{Provider: "local", Kind: ErrAPI, Message: fmt.Sprintf("decoding failed (%d)", int(rc))}
+		}
+		token := C.llama_sampler_sample(sampler, lctx, -1)
+		if C.llama_vocab_is_eog(l.loaded.vocab, token) {
+			break
+		}
+
+		piece, err := l.piece(token)
+		if err != nil {
+			return err
+		}
+		pending = append(pending, piece...)
+		if n := completeUTF8(pending); n > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case stream <- string(pending[:n]):
+			}
+			pending = pending[n:]
+		}
+
+		*next = token
+		batch = C.llama_batch_get_one(next, 1)
+	}
+	slog.Debug("local chat finished", "model", path, "prompt_tokens", int(n), "elapsed", time.Since(start))
+	return nil
+}
+
+// load loads the model at path, unless it is loaded already. l.mu must
+// be held.
+func (l *Local) load(path string) error {
+	if l.loaded.model != nil && l.loaded.path == path {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("local model: %w", err)
+	}
+	llamaInit.Do(func() {
+		C.ask_quiet()
+		C.llama_backend_init()
+	})
+	if l.loaded.model != nil {
+		C.llama_model_free(l.loaded.model)
+		l.loaded = localModel{}
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	start := time.Now()
+	model := C.llama_model_load_from_file(cpath, C.llama_model_default_params())
+	if model == nil {
+		return &Error{Provider: "local", Kind: ErrAPI, Message: fmt.Sprintf("cannot load model %s", path)}
+	}
+	slog.Debug("loaded local model", "model", path, "elapsed", time.Since(start))
+	l.loaded = localModel{path: path, model: model, vocab: C.llama_model_get_vocab(model)}
+	return nil
+}
+
+// chatml is the chat template for models that do not include one.
+var chatml = C.CString("chatml")
+
+// applyTemplate formats msgs as a prompt with the model's chat template.
+func (l *Local) applyTemplate(msgs []Message) (string, error) {
+	tmpl := C.llama_model_chat_template(l.loaded.model, nil)
+	if tmpl == nil {
+		tmpl = chatml
+	}
+	if len(msgs) == 0 {
+		return "", &Error{Provider: "local", Kind: ErrAPI, Message: "no messages"}
+	}
+
+	size := C.size_t(len(msgs)) * C.size_t(unsafe.Sizeof(C.llama_chat_message{}))
+	cmsgs := unsafe.Slice((*C.llama_chat_message)(C.malloc(size)), len(msgs))
+	defer C.free(unsafe.Pointer(&cmsgs[0]))
+	for i, m := range msgs {
+		cmsgs[i].role = C.CString(m.Role)
+		cmsgs[i].content = C.CString(m.Content)
+	}
+	defer func() {
+		for _, m := range cmsgs {
+			C.free(unsafe.Pointer(m.role))
+			C.free(unsafe.Pointer(m.content))
+		}
+	}()
+
+	n := C.llama_chat_apply_template(tmpl, &cmsgs[0], C.size_t(len(msgs)), true, nil, 0)
+	if n < 0 {
+		return "", &Error{Provider: "local", Kind: ErrAPI, Message: "the model's chat template is not supported"}
+	}
+	buf := (*C.char)(C.malloc(C.size_t(n) + 1))
+	defer C.free(unsafe.Pointer(buf))
+	n = C.llama_chat_apply_template(tmpl, &cmsgs[0], C.size_t(len(msgs)), true, buf, n+1)
+	return C.GoStringN(buf, C.int(n)), nil
+}
+
+// tokenize returns prompt's tokens in C memory, which the caller frees,
+// and their number.
+func (l *Local) tokenize(prompt string) (*C.llama_token, C.int32_t, error) {
+	text := C.CString(prompt)
+	defer C.free(unsafe.Pointer(text))
+	length := C.int32_t(len(prompt))
+
+	n := -C.llama_tokenize(l.loaded.vocab, text, length, nil, 0, true, true)
+	if n <= 0 {
+		return nil, 0, &Error{Provider: "local", Kind: ErrAPI, Message: "cannot tokenize the prompt"}
+	}
+	tokens := (*C.llama_token)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.llama_token(0)))))
+	if C.llama_tokenize(l.loaded.vocab, text, length, tokens, n, true, true) < 0 {
+		C.free(unsafe.Pointer(tokens))
+		return nil, 0, &Error{Provider: "local", Kind: ErrAPI, Message: "cannot tokenize the prompt"}
+	}
+	return tokens, n, nil
+}
+
+// piece returns the text of token.
+func (l *Local) piece(token C.llama_token) ([]byte, error) {
+	var buf [256]C.char
+	n := C.llama_token_to_piece(l.loaded.vocab, token, &buf[0], C.int32_t(len(buf)), 0, false)
+	if n < 0 {
+		return nil, &Error{Provider: "local", Kind: ErrAPI, Message: fmt.Sprintf("token %d is too long", int(token))}
+	}
+	return C.GoBytes(unsafe.Pointer(&buf[0]), C.int(n)), nil
+}
+
+// newLocalSampler returns a sampler for temperature: the most likely
+// token at 0, or a random one from those at least 5% as likely as it.
+func newLocalSampler(temperature float64) *C.struct_llama_sampler {
+	sampler := C.llama_sampler_chain_init(C.llama_sampler_chain_default_params())
+	if temperature <= 0 {
+		C.llama_sampler_chain_add(sampler, C.llama_sampler_init_greedy())
+		return sampler
+	}
+	C.llama_sampler_chain_add(sampler, C.llama_sampler_init_min_p(0.05, 1))
+	C.llama_sampler_chain_add(sampler, C.llama_sampler_init_temp(C.float(temperature)))
+	C.llama_sampler_chain_add(sampler, C.llama_sampler_init_dist(C.LLAMA_DEFAULT_SEED))
+	return sampler
+}
@@ -0,0 +1,16 @@
+//go:build !llamacpp || !cgo
+
+package provider
+
+import "context"
+
+// llamaSupported reports whether this build can run local models.
+const llamaSupported = false
+
+type localModel struct{}
+
+// Chat fails with ErrNoLocal.
+func (l *Local) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	close(stream)
+	return ErrNoLocal
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalModelPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	got, err := localModelPath("~/models/qwen.gguf")
+	if err != nil || got != filepath.Join(home, "models", "qwen.gguf") {
+		t.Errorf("localModelPath(~) = %q, %v", got, err)
+	}
+	if got, err := localModelPath("qwen.GGUF"); err != nil || !filepath.IsAbs(got) {
+		t.Errorf("localModelPath(relative) = %q, %v", got, err)
+	}
+	if _, err := localModelPath("gpt-4o"); err == nil || !strings.Contains(err.Error(), ".gguf") {
+		t.Errorf("localModelPath(gpt-4o) error = %v, want a .gguf error", err)
+	}
+}
+
+func TestLocalBuildRequest(t *testing.T) {
+	l := &Local{}
+	req, err := l.BuildRequest(context.Background(), &ChatRequest{
+		Model:    "/models/qwen.gguf",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Scheme != "file" || !strings.HasSuffix(req.URL.Path, "/models/qwen.gguf") {
+		t.Errorf("URL = %s", req.URL)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if !strings.Contains(string(body), `"max_tokens":2048`) || !strings.Contains(string(body), `"content":"hi"`) {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestNewLocal(t *testing.T) {
+	if llamaSupported {
+		t.Skip("built with llama.cpp")
+	}
+	if _, err := New("local", ""); !errors.Is(err, ErrNoLocal) {
+		t.Errorf("New(local) error = %v, want ErrNoLocal", err)
+	}
+}
+
+func TestCompleteUTF8(t *testing.T) {
+	euro := []byte("€") // 3 bytes
+	tests := []struct {
+		b    []byte
+		want int
+	}{
+		{[]byte("hello"), 5},
+		{append([]byte("a"), euro[:1]...), 1},
+		{append([]byte("a"), euro[:2]...), 1},
+		{append([]byte("a"), euro...), 4},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := completeUTF8(tt.b); got != tt.want {
+			t.Errorf("completeUTF8(%q) = %d, want %d", tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Middleware wraps a Provider to add behavior around its chat requests,
+// such as logging, retries, or accounting, so that it need not be built
+// into every provider.
+type Middleware func(Provider) Provider
+
+// Chain wraps p in mws. The first middleware is the outermost: it sees
+// each request first and its result last.
+func Chain(p Provider, mws ...Middleware) Provider {
+	for i := len(mws) - 1; i >= 0; i-- {
+		p = mws[i](p)
+	}
+	return p
+}
+
+// ChatFunc is the signature of Provider.Chat.
+type ChatFunc func(ctx context.Context, req *ChatRequest, stream chan<- string) error
+
+// WrapChat returns p with chat in place of its Chat method. The other
+// methods are p's; optional interfaces such as TokenCounter are found
+// with As.
+func WrapChat(p Provider, chat ChatFunc) Provider {
+	return &wrapped{Provider: p, chat: chat}
+}
+
+type wrapped struct {
+	Provider
+	chat ChatFunc
+}
+
+func (w *wrapped) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	return w.chat(ctx, req, stream)
+}
+
+// Unwrap returns the provider w wraps.
+func (w *wrapped) Unwrap() Provider {
+	return w.Provider
+}
+
+// As returns the first provider in p's chain of middleware that
+// implements T, such as TokenCounter or Embedder.
+func As[T any](p Provider) (T, bool) {
+	for {
+		if t, ok := p.(T); ok {
+			return t, true
+		}
+		u, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		p = u.Unwrap()
+	}
+}
+
+// Forward sends req with chat, passing its tokens on to stream, and
+// returns the complete response along with chat's error. Like Chat, it
+// closes stream when done.
+func Forward(ctx context.Context, chat ChatFunc, req *ChatRequest, stream chan<- string) (string, error) {
+	defer close(stream)
+
+	inner := make(chan string, cap(stream))
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- chat(ctx, req, inner)
+	}()
+
+	var response strings.Builder
+	for token := range inner {
+		response.WriteString(token)
+		stream <- token
+	}
+	return response.String(), <-errCh
+}
+
+// Logging logs each chat request and its outcome.
+func Logging() Middleware {
+	return func(p Provider) Provider {
+		return WrapChat(p, func(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+			start := time.Now()
+			slog.Debug("sending chat request", "provider", p.Name(), "model", req.Model, "messages", len(req.Messages))
+			response, err := Forward(ctx, p.Chat, req, stream)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				slog.Warn("chat request failed", "provider", p.Name(), "model", req.Model, "elapsed", time.Since(start), "error", err)
+			default:
+				slog.Debug("chat finished", "provider", p.Name(), "model", req.Model, "elapsed", time.Since(start), "bytes", len(response), "error", err)
+			}
+			return err
+		})
+	}
+}
+
+// Guard calls check before each chat request, and fails the request
+// with check's error instead of sending it.
+func Guard(check func(ctx context.Context, p Provider, req *ChatRequest) error) Middleware {
+	return func(p Provider) Provider {
+		return WrapChat(p, func(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+			if err := check(ctx, p, req); err != nil {
+				close(stream)
+				return err
+			}
+			return p.Chat(ctx, req, stream)
+		})
+	}
+}
+
+// Observe calls done after each chat request with the response, which
+// is partial if the request failed.
+func Observe(done func(p Provider, req *ChatRequest, response string, err error)) Middleware {
+	return func(p Provider) Provider {
+		return WrapChat(p, func(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+			response, err := Forward(ctx, p.Chat, req, stream)
+			done(p, req, response, err)
+			return err
+		})
+	}
+}
+
+// RateLimit calls wait before each chat request, such as a limiter
+// shared by concurrent workers. An error from wait fails the request.
+func RateLimit(wait func(ctx context.Context) error) Middleware {
+	return Guard(func(ctx context.Context, _ Provider, _ *ChatRequest) error {
+		return wait(ctx)
+	})
+}
+
+// Retry retries rate-limited chat requests up to attempts times in all,
+// pausing for backoff doubled each attempt. See RetryWith.
+func Retry(attempts int, backoff time.Duration, pause func(ctx context.Context, d time.Duration) error) Middleware {
+	return RetryWith(RetryPolicy{Attempts: attempts, Backoff: backoff}, pause)
+}
+
+// RetryPolicy decides which failed chat requests RetryWith sends again,
+// and how long it pauses first.
+type RetryPolicy struct {
+	// Attempts is the most times a request is sent, including the first.
+	Attempts int
+
+	// Backoff is the pause before the first retry. Each later pause is
+	// Multiplier times the one before, or twice if Multiplier is 0.
+	Backoff    time.Duration
+	Multiplier float64
+
+	// MaxElapsed, if positive, is how long after the first attempt
+	// started the last may start. A rate limit that resets later is
+	// returned at once, with its reset time, rather than waited out.
+	MaxElapsed time.Duration
+
+	// Statuses are the HTTP statuses of the errors retried. If empty,
+	// rate limits are.
+	Statuses []int
+
+	// Resume continues a response whose stream fails partway, such as
+	// when the connection drops, if the provider is a Prefiller: the
+	// request is sent again with what was received as the start of the
+	// reply, and only the rest is streamed.
+	Resume bool
+}
+
+// retries reports whether the policy retries a request that failed with
+// err, having streamed partial.
+func (rp RetryPolicy) retries(err error, partial string) bool {
+	if !IsRetryable(err) {
+		return false
+	}
+	if partial != "" {
+		// Errors mid-stream have no status
+		return rp.Resume
+	}
+	if len(rp.Statuses) == 0 {
+		return errors.Is(err, ErrRateLimited)
+	}
+	var pe *Error
+	return errors.As(err, &pe) && slices.Contains(rp.Statuses, pe.Status)
+}
+
+// backoff returns the pause before attempt n+1.
+func (rp RetryPolicy) backoff(n int) time.Duration {
+	m := rp.Multiplier
+	if m == 0 {
+		m = 2
+	}
+	return time.Duration(float64(rp.Backoff) * math.Pow(m, float64(n-1)))
+}
+
+// RetryWith retries chat requests as policy says. Before attempt n+1 it
+// calls pause with the policy's backoff, or longer if the provider said
+// when its limit resets, which may sleep or hold back other requests
+// too. A request that has streamed any tokens is not retried unless the
+// policy resumes it, nor one that cannot succeed when sent again, such
+// as one refused for an exhausted quota.
+func RetryWith(policy RetryPolicy, pause func(ctx context.Context, d time.Duration) error) Middleware {
+	return func(p Provider) Provider {
+		// The middleware may wrap other providers that can resume
+		policy := policy
+		prefiller, canResume := As[Prefiller](p)
+		if !canResume {
+			policy.Resume = false
+		}
+		return WrapChat(p, func(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+			defer close(stream)
+			start := time.Now()
+			var partial strings.Builder
+			send, trimSpace := req, false
+			for attempt := 1; ; attempt++ {
+				inner := make(chan string, cap(stream))
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- p.Chat(ctx, send, inner)
+				}()
+
+				relay(inner, stream, &partial, trimSpace)
+				err := <-errCh
+				if attempt >= policy.Attempts || !policy.retries(err, partial.String()) {
+					return err
+				}
+				if partial.Len() > 0 {
+					send = prefiller.Prefill(req, partial.String())
+					trimSpace = endsInSpace(partial.String())
+				}
+
+				d := policy.backoff(attempt)
+				var pe *Error
+				if errors.As(err, &pe) && pe.RateLimits != nil {
+					d = max(d, pe.RateLimits.Wait())
+				}
+				if policy.MaxElapsed > 0 && time.Since(start)+d > policy.MaxElapsed {
+					return err
+				}
+				slog.Info("request failed, retrying", "provider", p.Name(), "attempt", attempt, "backoff", d, "error", err)
+				if err := pause(ctx, d); err != nil {
+					return err
+				}
+			}
+		})
+	}
+}
+
+// ContinuePrompt is the user message AutoContinue sends for the rest of
+// a response, to providers that cannot prefill.
+const ContinuePrompt = "Continue exactly where you stopped, without repeating anything or adding commentary."
+
+// AutoContinue asks for the rest of a response cut short by the token
+// limit, up to limit more times, streaming the parts on as one response.
+// A Prefiller is sent the response so far as the start of its reply;
+// other providers are sent it as an assistant message followed by
+// ContinuePrompt. If the last part is cut short too, its ErrTruncated
+// error is returned.
+func AutoContinue(limit int) Middleware {
+	return func(p Provider) Provider {
+		prefiller, canPrefill := As[Prefiller](p)
+		return WrapChat(p, func(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+			defer close(stream)
+			var response strings.Builder
+			send, trimSpace := req, false
+			for n := 0; ; n++ {
+				inner := make(chan string, cap(stream))
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- p.Chat(ctx, send, inner)
+				}()
+
+				relay(inner, stream, &response, trimSpace)
+				err := <-errCh
+				if n >= limit || response.Len() == 0 || !errors.Is(err, ErrTruncated) {
+					return err
+				}
+
+				slog.Info("response cut short, continuing", "provider", p.Name(), "continuation", n+1)
+				if canPrefill {
+					send = prefiller.Prefill(req, response.String())
+					trimSpace = endsInSpace(response.String())
+				} else {
+					send = continueRequest(req, response.String())
+				}
+			}
+		})
+	}
+}
+
+// relay sends the tokens from inner on to stream, adding them to
+// response. With trimSpace, whitespace at the start is dropped: a
+// response prefilled with text that ends in whitespace may repeat it.
+func relay(inner <-chan string, stream chan<- string, response *strings.Builder, trimSpace bool) {
+	for token := range inner {
+		if trimSpace {
+			if token = strings.TrimLeftFunc(token, unicode.IsSpace); token == "" {
+				continue
+			}
+			trimSpace = false
+		}
+		response.WriteString(token)
+		stream <- token
+	}
+}
+
+// endsInSpace reports whether s ends in whitespace.
+func endsInSpace(s string) bool {
+	last, _ := utf8.DecodeLastRuneInString(s)
+	return unicode.IsSpace(last)
+}
+
+// continueRequest returns req with partial, the response so far, as an
+// assistant message, and ContinuePrompt after it.
+func continueRequest(req *ChatRequest, partial string) *ChatRequest {
+	r := *req
+	r.Messages = append(slices.Clone(req.Messages),
+		Message{Role: "assistant", Content: partial},
+		Message{Role: "user", Content: ContinuePrompt},
+	)
+	return &r
+}
+
+// Sleep pauses for d, or until ctx is done. It suits Retry.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
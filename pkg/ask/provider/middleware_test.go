@@ -0,0 +1,297 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider answers with a fixed response after failing a number of
+// times.
+type fakeProvider struct {
+	response string
+	failures []error
+	calls    int
+}
+
+func (f *fakeProvider) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	defer close(stream)
+	f.calls++
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return err
+	}
+	for _, word := range strings.SplitAfter(f.response, " ") {
+		stream <- word
+	}
+	return nil
+}
+
+func (f *fakeProvider) BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *fakeProvider) Models() []string { return []string{"fake"} }
+func (f *fakeProvider) Name() string     { return "fake" }
+
+// counter adds the TokenCounter interface to fakeProvider.
+type counter struct{ fakeProvider }
+
+func (c *counter) CountTokens(ctx context.Context, req *ChatRequest) (int, error) { return 42, nil }
+
+// chat sends a request to p and returns the response.
+func chat(p Provider) (string, error) {
+	stream := make(chan string, 4)
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Chat(context.Background(), &ChatRequest{Model: "fake"}, stream) }()
+	var b strings.Builder
+	for token := range stream {
+		b.WriteString(token)
+	}
+	return b.String(), <-errCh
+}
+
+func TestChain_Order(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return Guard(func(context.Context, Provider, *ChatRequest) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	p := Chain(&fakeProvider{response: "hello world"}, mark("outer"), mark("inner"))
+	got, err := chat(p)
+	if err != nil || got != "hello world" {
+		t.Fatalf("chat() = %q, %v", got, err)
+	}
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("middleware ran in order %v, want outer then inner", order)
+	}
+	if p.Name() != "fake" {
+		t.Errorf("Name() = %q, want the wrapped provider's", p.Name())
+	}
+}
+
+func TestAs(t *testing.T) {
+	p := Chain(&counter{}, Logging(), Observe(func(Provider, *ChatRequest, string, error) {}))
+	tc, ok := As[TokenCounter](p)
+	if !ok {
+		t.Fatal("As[TokenCounter]() found nothing through middleware")
+	}
+	if n, _ := tc.CountTokens(context.Background(), nil); n != 42 {
+		t.Errorf("CountTokens() = %d, want 42", n)
+	}
+	if _, ok := As[Embedder](p); ok {
+		t.Error("As[Embedder]() found an interface no provider implements")
+	}
+}
+
+func TestGuard(t *testing.T) {
+	refused := errors.New("over budget")
+	f := &fakeProvider{response: "hi"}
+	p := Chain(f, Guard(func(context.Context, Provider, *ChatRequest) error { return refused }))
+	if _, err := chat(p); !errors.Is(err, refused) {
+		t.Errorf("chat() error = %v, want %v", err, refused)
+	}
+	if f.calls != 0 {
+		t.Errorf("refused request reached the provider")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var seen string
+	p := Chain(&fakeProvider{response: "a b c"}, Observe(func(p Provider, req *ChatRequest, response string, err error) {
+		seen = response
+	}))
+	if _, err := chat(p); err != nil {
+		t.Fatal(err)
+	}
+	if seen != "a b c" {
+		t.Errorf("observed response %q, want %q", seen, "a b c")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var pauses []time.Duration
+	pause := func(ctx context.Context, d time.Duration) error {
+		pauses = append(pauses, d)
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		failures []error
+		wantErr  error
+		calls    int
+		pauses   []time.Duration
+	}{
+		{"success", nil, nil, 1, nil},
+		{"recovers", []error{ErrRateLimited, ErrRateLimited}, nil, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"gives up", []error{ErrRateLimited, ErrRateLimited, ErrRateLimited}, ErrRateLimited, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"other errors", []error{ErrAuth}, ErrAuth, 1, nil},
+		{"waits for reset", []error{&Error{Kind: ErrRateLimited, Retryable: true, RateLimits: &RateLimits{RetryAfter: 5 * time.Second}}}, nil, 2, []time.Duration{5 * time.Second}},
+		{"exhausted quota", []error{&Error{Kind: ErrRateLimited, Code: "insufficient_quota"}}, ErrRateLimited, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pauses = nil
+			f := &fakeProvider{response: "ok", failures: tt.failures}
+			got, err := chat(Chain(f, Retry(3, time.Second, pause)))
+			if !errors.Is(err, tt.wantErr) || (err == nil && got != "ok") {
+				t.Errorf("chat() = %q, %v; want ok, %v", got, err, tt.wantErr)
+			}
+			if f.calls != tt.calls {
+				t.Errorf("provider called %d times, want %d", f.calls, tt.calls)
+			}
+			if len(pauses) != len(tt.pauses) {
+				t.Fatalf("pauses = %v, want %v", pauses, tt.pauses)
+			}
+			for i := range pauses {
+				if pauses[i] != tt.pauses[i] {
+					t.Errorf("pauses = %v, want %v", pauses, tt.pauses)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryWith(t *testing.T) {
+	overloaded := &Error{Kind: ErrServer, Status: 529, Retryable: true}
+	limited := &Error{Kind: ErrRateLimited, Status: 429, Retryable: true}
+	limitedForAnHour := &Error{Kind: ErrRateLimited, Status: 429, Retryable: true, RateLimits: &RateLimits{RetryAfter: time.Hour}}
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		failures []error
+		calls    int
+		pauses   []time.Duration
+	}{
+		{"multiplier", RetryPolicy{Attempts: 3, Backoff: time.Second, Multiplier: 3}, []error{limited, limited}, 3, []time.Duration{time.Second, 3 * time.Second}},
+		{"constant", RetryPolicy{Attempts: 3, Backoff: time.Second, Multiplier: 1}, []error{limited, limited}, 3, []time.Duration{time.Second, time.Second}},
+		{"server errors not retried by default", RetryPolicy{Attempts: 3, Backoff: time.Second}, []error{overloaded}, 1, nil},
+		{"listed status", RetryPolicy{Attempts: 3, Backoff: time.Second, Statuses: []int{529}}, []error{overloaded}, 2, []time.Duration{time.Second}},
+		{"unlisted status", RetryPolicy{Attempts: 3, Backoff: time.Second, Statuses: []int{529}}, []error{limited}, 1, nil},
+		{"max elapsed", RetryPolicy{Attempts: 5, Backoff: 2 * time.Second, MaxElapsed: 3 * time.Second}, []error{limited, limited}, 2, []time.Duration{2 * time.Second}},
+		{"reset after max elapsed", RetryPolicy{Attempts: 3, Backoff: time.Second, MaxElapsed: 2 * time.Minute}, []error{limitedForAnHour}, 1, nil},
+		{"reset waited for", RetryPolicy{Attempts: 3, Backoff: time.Second}, []error{limitedForAnHour}, 2, []time.Duration{time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pauses []time.Duration
+			pause := func(ctx context.Context, d time.Duration) error {
+				pauses = append(pauses, d)
+				return nil
+			}
+			f := &fakeProvider{response: "ok", failures: tt.failures}
+			_, _ = chat(Chain(f, RetryWith(tt.policy, pause)))
+			if f.calls != tt.calls {
+				t.Errorf("provider called %d times, want %d", f.calls, tt.calls)
+			}
+			if !slices.Equal(pauses, tt.pauses) {
+				t.Errorf("pauses = %v, want %v", pauses, tt.pauses)
+			}
+		})
+	}
+}
+
+func TestRetryWith_Resume(t *testing.T) {
+	dropped := &Error{Kind: ErrServer, Message: "the connection was lost", Retryable: true}
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Statuses: []int{529}, Resume: true}
+	pause := func(ctx context.Context, d time.Duration) error { return nil }
+
+	t.Run("resumed", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"Once ", "upon "}, Err: dropped}, MockResponse{Tokens: []string{" a", " time."}})
+		got, err := chat(Chain(m, RetryWith(policy, pause)))
+		if err != nil || got != "Once upon a time." {
+			t.Fatalf("chat() = %q, %v; want the response stitched together", got, err)
+		}
+		reqs := m.Requests()
+		if len(reqs) != 2 || len(reqs[1].Messages) != 1 || reqs[1].Messages[0] != (Message{Role: "assistant", Content: "Once upon "}) {
+			t.Errorf("requests = %+v, want the partial response prefilled", reqs)
+		}
+	})
+
+	t.Run("off", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"Once "}, Err: dropped})
+		off := policy
+		off.Resume = false
+		if got, err := chat(Chain(m, RetryWith(off, pause))); !errors.Is(err, dropped) || got != "Once " {
+			t.Errorf("chat() = %q, %v; want the partial response and error", got, err)
+		}
+	})
+
+	t.Run("provider cannot prefill", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"Once "}, Err: dropped})
+		// Only the Provider methods of m, without Prefill
+		p := struct{ Provider }{m}
+		if got, err := chat(Chain(p, RetryWith(policy, pause))); !errors.Is(err, dropped) || len(m.Requests()) != 1 {
+			t.Errorf("chat() = %q, %v after %d requests; want the error at once", got, err, len(m.Requests()))
+		}
+	})
+
+	t.Run("after wrapping a provider that cannot prefill", func(t *testing.T) {
+		retry := RetryWith(policy, pause)
+		retry(struct{ Provider }{NewMock()})
+		m := NewMock(MockResponse{Tokens: []string{"Once "}, Err: dropped}, MockResponse{Tokens: []string{"upon"}})
+		if got, err := chat(retry(m)); err != nil || got != "Once upon" {
+			t.Errorf("chat() = %q, %v; want the response resumed", got, err)
+		}
+	})
+}
+
+func TestAutoContinue(t *testing.T) {
+	truncated := &Error{Kind: ErrTruncated}
+
+	t.Run("prefilled", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"func main() {\n"}, Err: truncated}, MockResponse{Tokens: []string{"\n}"}})
+		got, err := chat(Chain(m, AutoContinue(2)))
+		if err != nil || got != "func main() {\n}" {
+			t.Fatalf("chat() = %q, %v; want the response stitched together", got, err)
+		}
+		reqs := m.Requests()
+		if len(reqs) != 2 || len(reqs[1].Messages) != 1 || reqs[1].Messages[0] != (Message{Role: "assistant", Content: "func main() {\n"}) {
+			t.Errorf("requests = %+v, want the response so far prefilled", reqs)
+		}
+	})
+
+	t.Run("continue turn", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"One, "}, Err: truncated}, MockResponse{Tokens: []string{"two."}})
+		// Only the Provider methods of m, without Prefill
+		got, err := chat(Chain(struct{ Provider }{m}, AutoContinue(2)))
+		if err != nil || got != "One, two." {
+			t.Fatalf("chat() = %q, %v; want the response stitched together", got, err)
+		}
+		reqs := m.Requests()
+		if len(reqs) != 2 || len(reqs[1].Messages) != 2 ||
+			reqs[1].Messages[0] != (Message{Role: "assistant", Content: "One, "}) ||
+			reqs[1].Messages[1] != (Message{Role: "user", Content: ContinuePrompt}) {
+			t.Errorf("requests = %+v, want a continue turn after the response so far", reqs)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		m := NewMock(
+			MockResponse{Tokens: []string{"a"}, Err: truncated},
+			MockResponse{Tokens: []string{"b"}, Err: truncated},
+			MockResponse{Tokens: []string{"c"}, Err: truncated},
+		)
+		got, err := chat(Chain(m, AutoContinue(1)))
+		if !errors.Is(err, ErrTruncated) || got != "ab" || len(m.Requests()) != 2 {
+			t.Errorf("chat() = %q, %v after %d requests; want ab, truncated, after 2", got, err, len(m.Requests()))
+		}
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		m := NewMock(MockResponse{Tokens: []string{"a"}, Err: &Error{Kind: ErrServer}})
+		if _, err := chat(Chain(m, AutoContinue(3))); !errors.Is(err, ErrServer) || len(m.Requests()) != 1 {
+			t.Errorf("chat() error = %v after %d requests; want it at once", err, len(m.Requests()))
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockName is the name New gives a Mock. It is not listed among the
+// providers: it is for testing programs that use them, such as ask
+// itself.
+const MockName = "mock"
+
+// MockResponse is one scripted response of a Mock.
+type MockResponse struct {
+	// Tokens are streamed in order.
+	Tokens []string
+
+	// Err, if not nil, is returned once Tokens have been streamed, as if
+	// the response failed partway.
+	Err error
+}
+
+// Mock implements the Provider interface with scripted responses and no
+// network, for tests.
+type Mock struct {
+	// Responses are returned by successive calls to Chat. Once they run
+	// out, Chat echoes the last user message, a word per token.
+	Responses []MockResponse
+
+	// Latency is waited before each token.
+	Latency time.Duration
+
+	// ModelNames is returned by Models.
+	ModelNames []string
+
+	mu       sync.Mutex
+	next     int
+	requests []ChatRequest
+}
+
+// NewMock creates a mock provider that returns responses in turn.
+func NewMock(responses ...MockResponse) *Mock {
+	return &Mock{Responses: responses}
+}
+
+var (
+	mockMu sync.Mutex
+	mock   *Mock
+)
+
+// SetMock makes New return m for MockName. If m is nil, as it is to start
+// with, New returns a new Mock each time, which echoes prompts.
+func SetMock(m *Mock) {
+	mockMu.Lock()
+	defer mockMu.Unlock()
+	mock = m
+}
+
+// currentMock returns the Mock New returns for MockName.
+func currentMock() *Mock {
+	mockMu.Lock()
+	defer mockMu.Unlock()
+	if mock == nil {
+		return NewMock()
+	}
+	return mock
+}
+
+// Name returns the provider name.
+func (m *Mock) Name() string {
+	return MockName
+}
+
+// Models returns m.ModelNames.
+func (m *Mock) Models() []string {
+	return m.ModelNames
+}
+
+// Requests returns the requests Chat has received, in order.
+func (m *Mock) Requests() []ChatRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.requests)
+}
+
+// Prefill returns req with partial as the start of the reply, as a
+// final assistant message.
+func (m *Mock) Prefill(req *ChatRequest, partial string) *ChatRequest {
+	prefilled := *req
+	prefilled.Messages = append(slices.Clone(req.Messages), Message{Role: "assistant", Content: partial})
+	return &prefilled
+}
+
+// mockRequest describes a mock request for --dry-run.
+type mockRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+}
+
+// BuildRequest returns a request describing what Chat would be asked.
+// It is never sent.
+func (m *Mock) BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error) {
+	body, err := json.Marshal(mockRequest{Model: req.Model, Messages: req.Messages})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "mock://mock/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	return httpReq, nil
+}
+
+// Chat records req and streams the next scripted response.
+func (m *Mock) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	defer close(stream)
+
+	m.mu.Lock()
+	recorded := *req
+	recorded.Messages = slices.Clone(req.Messages)
+	m.requests = append(m.requests, recorded)
+	var resp MockResponse
+	if m.next < len(m.Responses) {
+		resp = m.Responses[m.next]
+		m.next++
+	} else {
+		resp = MockResponse{Tokens: echoTokens(req.Messages)}
+	}
+	m.mu.Unlock()
+
+	for _, token := range resp.Tokens {
+		if m.Latency > 0 {
+			select {
+			case <-time.After(m.Latency):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case stream <- token:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return resp.Err
+}
+
+// echoTokens splits the last user message into tokens of a word each,
+// with the spaces before them.
+func echoTokens(messages []Message) []string {
+	var prompt string
+	for _, msg := range slices.Backward(messages) {
+		if msg.Role == "user" {
+			prompt = msg.Content
+			break
+		}
+	}
+	var tokens []string
+	for i, word := range strings.Fields(prompt) {
+		if i > 0 {
+			word = " " + word
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
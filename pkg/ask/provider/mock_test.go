@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// collect runs p.Chat with req and returns the tokens it streamed.
+func collect(ctx context.Context, p Provider, req *ChatRequest) ([]string, error) {
+	stream := make(chan string)
+	errc := make(chan error, 1)
+	go func() { errc <- p.Chat(ctx, req, stream) }()
+	var tokens []string
+	for token := range stream {
+		tokens = append(tokens, token)
+	}
+	return tokens, <-errc
+}
+
+func TestMock(t *testing.T) {
+	m := NewMock(
+		MockResponse{Tokens: []string{"Hello", ", world"}},
+		MockResponse{Tokens: []string{"Half"}, Err: &Error{Provider: "openai", Kind: ErrServer, Status: 500, Retryable: true}},
+	)
+	req := &ChatRequest{Model: "any", Messages: []Message{{Role: "user", Content: "say  it back"}}}
+
+	tests := []struct {
+		want    []string
+		wantErr error
+	}{
+		{want: []string{"Hello", ", world"}},
+		{want: []string{"Half"}, wantErr: ErrServer},
+		{want: []string{"say", " it", " back"}}, // scripts ran out
+	}
+	for i, tt := range tests {
+		got, err := collect(t.Context(), m, req)
+		if !slices.Equal(got, tt.want) || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Chat #%d = %q, %v; want %q, %v", i+1, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if reqs := m.Requests(); len(reqs) != 3 || reqs[0].Messages[0].Content != "say  it back" {
+		t.Errorf("Requests() = %+v", reqs)
+	}
+}
+
+func TestMock_Latency(t *testing.T) {
+	m := NewMock(MockResponse{Tokens: []string{"slow", "er"}})
+	m.Latency = time.Hour
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	tokens, err := collect(ctx, m, &ChatRequest{})
+	if len(tokens) != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Chat() = %q, %v; want nothing before the deadline", tokens, err)
+	}
+}
+
+func TestNewMock(t *testing.T) {
+	m := NewMock()
+	SetMock(m)
+	defer SetMock(nil)
+	p, err := New(MockName, "")
+	if err != nil {
+		t.Fatalf("New(mock) error = %v", err)
+	}
+	if got, ok := As[*Mock](p); !ok || got != m {
+		t.Errorf("New(mock) = %T, want the Mock set", p)
+	}
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"slices"
+	"sync"
+)
+
+// ModelInfo describes a model ask knows: the provider that offers it,
+// its limits, its price, and what it can do.
+type ModelInfo struct {
+	Name          string `json:"name"`
+	Provider      string `json:"provider"`
+	ContextWindow int    `json:"context_window"` // tokens
+	Price         Price  `json:"price"`
+	Vision        bool   `json:"vision"` // accepts images
+	Tools         bool   `json:"tools"`  // can call tools
+
+	// KnowledgeCutoff is the month its training data ends, as YYYY-MM,
+	// if known.
+	KnowledgeCutoff string `json:"knowledge_cutoff,omitempty"`
+}
+
+// builtinModels lists the models ask knows, by provider, newest first.
+var builtinModels = []ModelInfo{
+	{Name: "gpt-4.1", Provider: "openai", ContextWindow: 1047576, Price: Price{2, 8}, Vision: true, Tools: true, KnowledgeCutoff: "2024-06"},
+	{Name: "gpt-4.1-mini", Provider: "openai", ContextWindow: 1047576, Price: Price{0.4, 1.6}, Vision: true, Tools: true, KnowledgeCutoff: "2024-06"},
+	{Name: "o3", Provider: "openai", ContextWindow: 200000, Price: Price{2, 8}, Vision: true, Tools: true, KnowledgeCutoff: "2024-06"},
+	{Name: "o4-mini", Provider: "openai", ContextWindow: 200000, Price: Price{1.1, 4.4}, Vision: true, Tools: true, KnowledgeCutoff: "2024-06"},
+	{Name: "gpt-4o", Provider: "openai", ContextWindow: 128000, Price: Price{2.5, 10}, Vision: true, Tools: true, KnowledgeCutoff: "2023-10"},
+	{Name: "gpt-4o-mini", Provider: "openai", ContextWindow: 128000, Price: Price{0.15, 0.6}, Vision: true, Tools: true, KnowledgeCutoff: "2023-10"},
+	{Name: "gpt-4-turbo", Provider: "openai", ContextWindow: 128000, Price: Price{10, 30}, Vision: true, Tools: true, KnowledgeCutoff: "2023-12"},
+	{Name: "gpt-3.5-turbo", Provider: "openai", ContextWindow: 16385, Price: Price{0.5, 1.5}, Tools: true, KnowledgeCutoff: "2021-09"},
+	{Name: "claude-opus-4-20250514", Provider: "anthropic", ContextWindow: 200000, Price: Price{15, 75}, Vision: true, Tools: true, KnowledgeCutoff: "2025-03"},
+	{Name: "claude-sonnet-4-20250514", Provider: "anthropic", ContextWindow: 200000, Price: Price{3, 15}, Vision: true, Tools: true, KnowledgeCutoff: "2025-03"},
+	{Name: "claude-3-7-sonnet-20250219", Provider: "anthropic", ContextWindow: 200000, Price: Price{3, 15}, Vision: true, Tools: true, KnowledgeCutoff: "2024-11"},
+	{Name: "claude-3-5-sonnet-20241022", Provider: "anthropic", ContextWindow: 200000, Price: Price{3, 15}, Vision: true, Tools: true, KnowledgeCutoff: "2024-04"},
+	{Name: "claude-3-5-haiku-20241022", Provider: "anthropic", ContextWindow: 200000, Price: Price{0.8, 4}, Vision: true, Tools: true, KnowledgeCutoff: "2024-07"},
+	{Name: "claude-3-opus-20240229", Provider: "anthropic", ContextWindow: 200000, Price: Price{15, 75}, Vision: true, Tools: true, KnowledgeCutoff: "2023-08"},
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = builtinModels
+)
+
+// SetModels replaces what ask knows of models with overrides, such as
+// from the config file, adding those it does not know. It replaces the
+// overrides of earlier calls; nil leaves the built-in models alone.
+func SetModels(overrides []ModelInfo) {
+	models := slices.Clone(builtinModels)
+	for _, m := range overrides {
+		if i := slices.IndexFunc(models, func(b ModelInfo) bool { return b.Name == m.Name }); i >= 0 {
+			models[i] = m
+		} else {
+			models = append(models, m)
+		}
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalog = models
+}
+
+// Catalog returns the models ask knows, by provider, newest first, then
+// those added with SetModels.
+func Catalog() []ModelInfo {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return slices.Clone(catalog)
+}
+
+// LookupModel returns what ask knows of model, or false if nothing.
+func LookupModel(model string) (ModelInfo, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	i := slices.IndexFunc(catalog, func(m ModelInfo) bool { return m.Name == model })
+	if i < 0 {
+		return ModelInfo{}, false
+	}
+	return catalog[i], true
+}
+
+// ContextWindow returns the context window size in tokens for model,
+// or 0 if the model is unknown.
+func ContextWindow(model string) int {
+	m, _ := LookupModel(model)
+	return m.ContextWindow
+}
+
+// defaultModels maps providers to the model used when the config file
+// names none.
+var defaultModels = map[string]string{
+	"openai":    "gpt-4o",
+	"anthropic": "claude-sonnet-4-20250514",
+}
+
+// DefaultModel returns the provider's default model, or "" if the
+// provider is unknown or, like the local provider, has none.
+func DefaultModel(providerName string) string {
+	return defaultModels[providerName]
+}
+
+// smallModels maps providers to a fast, inexpensive model for background
+// work such as summarizing long conversations.
+var smallModels = map[string]string{
+	"openai":    "gpt-4o-mini",
+	"anthropic": "claude-3-5-haiku-20241022",
+}
+
+// SmallModel returns the provider's fast, inexpensive model, or "" if the
+// provider is unknown.
+func SmallModel(providerName string) string {
+	return smallModels[providerName]
+}
+
+// Price is a model's list price in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Cost returns the cost in US dollars of a request to model with the
+// given token counts. ok is false if the model's price is unknown.
+func Cost(model string, inputTokens, outputTokens int) (cost float64, ok bool) {
+	m, ok := LookupModel(model)
+	if !ok || m.Price == (Price{}) {
+		return 0, false
+	}
+	return (float64(inputTokens)*m.Price.Input + float64(outputTokens)*m.Price.Output) / 1e6, true
+}
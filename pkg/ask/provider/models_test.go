@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCost(t *testing.T) {
+	tests := []struct {
+		model         string
+		input, output int
+		want          float64
+		ok            bool
+	}{
+		{"gpt-4o", 1_000_000, 0, 2.5, true},
+		{"gpt-4o", 2000, 1000, 0.015, true},
+		{"claude-sonnet-4-20250514", 1000, 1000, 0.018, true},
+		{"my-local-model", 1000, 1000, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := Cost(tt.model, tt.input, tt.output)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Cost(%q, %d, %d) = %v, %v; want %v, %v", tt.model, tt.input, tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetModels(t *testing.T) {
+	t.Cleanup(func() { SetModels(nil) })
+	SetModels([]ModelInfo{
+		{Name: "gpt-4o", Provider: "openai", ContextWindow: 64000, Price: Price{1, 2}},
+		{Name: "my-model", ContextWindow: 8192},
+	})
+	if got := ContextWindow("gpt-4o"); got != 64000 {
+		t.Errorf("ContextWindow(gpt-4o) = %d, want the override 64000", got)
+	}
+	if got, ok := Cost("gpt-4o", 1_000_000, 1_000_000); !ok || got != 3 {
+		t.Errorf("Cost(gpt-4o) = %v, %v; want the override's 3", got, ok)
+	}
+	if got := ContextWindow("my-model"); got != 8192 {
+		t.Errorf("ContextWindow(my-model) = %d, want 8192", got)
+	}
+	if _, ok := Cost("my-model", 1000, 1000); ok {
+		t.Error("Cost(my-model) is known without a price")
+	}
+
+	SetModels(nil)
+	if got := ContextWindow("gpt-4o"); got != 128000 {
+		t.Errorf("ContextWindow(gpt-4o) after SetModels(nil) = %d, want the built-in 128000", got)
+	}
+	if _, ok := LookupModel("my-model"); ok {
+		t.Error("my-model still known after SetModels(nil)")
+	}
+}
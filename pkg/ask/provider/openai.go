@@ -0,0 +1,328 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/devaloi/ask/internal/logging"
+	"github.com/devaloi/ask/internal/util"
+	"github.com/devaloi/ask/pkg/ask/sse"
+)
+
+const (
+	defaultOpenAIBaseURL       = "https://api.openai.com/v1/chat/completions"
+	defaultOpenAIEmbeddingsURL = "https://api.openai.com/v1/embeddings"
+)
+
+// OpenAI implements the Provider interface for OpenAI's API.
+type OpenAI struct {
+	apiKey        string
+	client        *http.Client
+	baseURL       string
+	embeddingsURL string
+}
+
+// NewOpenAI creates a new OpenAI provider with the given API key.
+func NewOpenAI(apiKey string) *OpenAI {
+	return &OpenAI{
+		apiKey:        apiKey,
+		client:        &http.Client{},
+		baseURL:       defaultOpenAIBaseURL,
+		embeddingsURL: defaultOpenAIEmbeddingsURL,
+	}
+}
+
+// NewOpenAIWithBaseURL creates a new OpenAI provider with a custom base URL (for testing).
+// Embeddings are requested from the sibling /embeddings endpoint.
+func NewOpenAIWithBaseURL(apiKey, baseURL string) *OpenAI {
+	return &OpenAI{
+		apiKey:        apiKey,
+		client:        &http.Client{},
+		baseURL:       baseURL,
+		embeddingsURL: strings.TrimSuffix(baseURL, "/chat/completions") + "/embeddings",
+	}
+}
+
+// SetTransport sends o's requests through rt.
+func (o *OpenAI) SetTransport(rt http.RoundTripper) {
+	o.client.Transport = rt
+}
+
+// Name returns the provider name.
+func (o *OpenAI) Name() string {
+	return "openai"
+}
+
+// Models returns the list of available models for OpenAI.
+func (o *OpenAI) Models() []string {
+	return []string{
+		"gpt-4o",
+		"gpt-4o-mini",
+		"gpt-4-turbo",
+		"gpt-3.5-turbo",
+	}
+}
+
+// openAIRequest is the request body for the OpenAI chat completions API.
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stream      bool      `json:"stream"`
+
+	// WebSearchOptions enables web search for search models.
+	WebSearchOptions *struct{} `json:"web_search_options,omitempty"`
+}
+
+// openAIStreamResponse represents a single SSE chunk from the OpenAI API.
+type openAIStreamResponse struct {
+	Choices []struct {
+		Delta struct {
+			Content     string `json:"content"`
+			Annotations []struct {
+				Type        string `json:"type"`
+				URLCitation struct {
+					URL   string `json:"url"`
+					Title string `json:"title"`
+				} `json:"url_citation"`
+			} `json:"annotations"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+
+	// Error is set instead of Choices when the response fails after it
+	// began.
+	Error json.RawMessage `json:"error"`
+}
+
+// openAIStreamErrors categorizes the error types and codes OpenAI reports
+// mid-stream.
+var openAIStreamErrors = map[string]streamErrorKind{
+	"server_error":        {ErrServer, true},
+	"rate_limit_exceeded": {ErrRateLimited, true},
+	"insufficient_quota":  {ErrRateLimited, false},
+	"invalid_api_key":     {ErrAuth, false},
+}
+
+// openAISearchModels maps models to the search models that stand in for
+// them with WebSearch, since only those can search the web.
+var openAISearchModels = map[string]string{
+	"gpt-4o":      "gpt-4o-search-preview",
+	"gpt-4o-mini": "gpt-4o-mini-search-preview",
+}
+
+// BuildRequest builds the HTTP request that Chat would send for req.
+func (o *OpenAI) BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error) {
+	reqBody := openAIRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   true,
+	}
+	if req.MaxTokens > 0 {
+		reqBody.MaxTokens = req.MaxTokens
+	}
+	if req.WebSearch {
+		// Search models take no sampling parameters
+		if model, ok := openAISearchModels[req.Model]; ok {
+			reqBody.Model = model
+		}
+		reqBody.WebSearchOptions = &struct{}{}
+	} else {
+		temperature := req.Temperature
+		reqBody.Temperature = &temperature
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	return httpReq, nil
+}
+
+// Chat sends a chat request to OpenAI and streams tokens to the channel.
+func (o *OpenAI) Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error {
+	defer close(stream)
+
+	httpReq, err := o.BuildRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	slog.Debug("chat response", "provider", "openai", "status", resp.StatusCode, "elapsed", time.Since(start))
+
+	if resp.StatusCode != http.StatusOK {
+		return httpError("openai", resp, "check your OPENAI_API_KEY")
+	}
+
+	return o.parseSSEStream(ctx, resp.Body, stream)
+}
+
+// openAIEmbeddingsRequest is the request body for the embeddings API.
+type openAIEmbeddingsRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+// openAIEmbeddingsResponse is the response body from the embeddings API.
+type openAIEmbeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns an embedding vector for each of texts, in order.
+func (o *OpenAI) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	jsonBody, err := json.Marshal(openAIEmbeddingsRequest{Model: model, Input: texts, EncodingFormat: "float"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.embeddingsURL, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	slog.Debug("sending embeddings request", "provider", "openai", "model", model, "inputs", len(texts))
+	resp, err := o.client.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		slog.Warn("embeddings request failed", "provider", "openai", "error", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := httpError("openai", resp, "check your OPENAI_API_KEY")
+		slog.Warn("embeddings request rejected", "provider", "openai", "status", resp.StatusCode, "error", err)
+		return nil, err
+	}
+
+	var embResp openAIEmbeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, &Error{Provider: "openai", Kind: ErrAPI, Message: fmt.Sprintf("embedding index %d out of range", d.Index)}
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, &Error{Provider: "openai", Kind: ErrAPI, Message: fmt.Sprintf("no embedding returned for input %d", i)}
+		}
+	}
+	return vectors, nil
+}
+
+// parseSSEStream reads the SSE stream and sends tokens to the channel.
+func (o *OpenAI) parseSSEStream(ctx context.Context, body io.Reader, stream chan<- string) error {
+	reader := sse.NewReader(ctx, body)
+	events := make(chan sse.Event, util.DefaultChannelBuffer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- reader.Read(events)
+		close(events)
+	}()
+
+	var cited citations
+	finished, truncated := false, false
+	for event := range events {
+		// Check for the [DONE] sentinel
+		if event.Data == "[DONE]" {
+			if err := sendSources(ctx, stream, cited.list); err != nil {
+				return err
+			}
+			if truncated {
+				return &Error{Provider: "openai", Kind: ErrTruncated}
+			}
+			return nil
+		}
+
+		var chunk openAIStreamResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			slog.Warn("skipping malformed SSE event", "provider", "openai", "data", logging.Truncate(event.Data, 200), "error", err)
+			continue
+		}
+		if len(chunk.Error) > 0 && string(chunk.Error) != "null" {
+			return streamError("openai", event.Data, openAIStreamErrors)
+		}
+
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		for _, a := range choice.Delta.Annotations {
+			if a.Type == "url_citation" {
+				cited.add(a.URLCitation.URL, a.URLCitation.Title)
+			}
+		}
+		if choice.Delta.Content != "" {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case stream <- choice.Delta.Content:
+			}
+		}
+		if choice.FinishReason != nil {
+			finished = true
+			switch *choice.FinishReason {
+			case "content_filter":
+				return &Error{Provider: "openai", Kind: ErrContentFiltered, Code: "content_filter"}
+			case "length":
+				// Finish the stream, for any sources
+				truncated = true
+			}
+		}
+	}
+
+	err := <-errCh
+	if err != nil || !finished {
+		return streamEnded(ctx, "openai", err)
+	}
+	// Some compatible servers finish without [DONE]
+	slog.Warn("SSE stream ended without [DONE]", "provider", "openai")
+	if err := sendSources(ctx, stream, cited.list); err != nil {
+		return err
+	}
+	if truncated {
+		return &Error{Provider: "openai", Kind: ErrTruncated}
+	}
+	return nil
+}
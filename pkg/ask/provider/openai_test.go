@@ -2,7 +2,10 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -123,42 +126,49 @@ func TestOpenAI_Chat_HTTPErrors(t *testing.T) {
 		statusCode     int
 		responseBody   string
 		expectedErrMsg string
+		wantErrIs      error
 	}{
 		{
 			name:           "401 Unauthorized",
 			statusCode:     http.StatusUnauthorized,
 			responseBody:   `{"error": {"message": "Invalid API key"}}`,
 			expectedErrMsg: "invalid API key",
+			wantErrIs:      ErrAuth,
 		},
 		{
 			name:           "429 Rate Limited",
 			statusCode:     http.StatusTooManyRequests,
 			responseBody:   `{"error": {"message": "Rate limit exceeded"}}`,
 			expectedErrMsg: "rate limited",
+			wantErrIs:      ErrRateLimited,
 		},
 		{
 			name:           "500 Server Error",
 			statusCode:     http.StatusInternalServerError,
 			responseBody:   `{"error": {"message": "Internal server error"}}`,
 			expectedErrMsg: "OpenAI service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "502 Bad Gateway",
 			statusCode:     http.StatusBadGateway,
 			responseBody:   `{"error": {"message": "Bad gateway"}}`,
 			expectedErrMsg: "OpenAI service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "503 Service Unavailable",
 			statusCode:     http.StatusServiceUnavailable,
 			responseBody:   `{"error": {"message": "Service unavailable"}}`,
 			expectedErrMsg: "OpenAI service error",
+			wantErrIs:      ErrServer,
 		},
 		{
 			name:           "400 Bad Request",
 			statusCode:     http.StatusBadRequest,
 			responseBody:   `{"error": {"message": "Invalid request"}}`,
 			expectedErrMsg: "OpenAI API error (status 400)",
+			wantErrIs:      ErrAPI,
 		},
 	}
 
@@ -186,6 +196,9 @@ func TestOpenAI_Chat_HTTPErrors(t *testing.T) {
 			if !strings.Contains(err.Error(), tt.expectedErrMsg) {
 				t.Errorf("Chat() error = %q, want to contain %q", err.Error(), tt.expectedErrMsg)
 			}
+			if !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("error = %v, want errors.Is %v", err, tt.wantErrIs)
+			}
 
 			// Verify channel is closed after error
 			select {
@@ -713,6 +726,26 @@ func TestOpenAI_Chat_RequestBody(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "web search request",
+			request: &ChatRequest{
+				Model:       "gpt-4o",
+				Messages:    []Message{{Role: "user", Content: "Hello"}},
+				Temperature: 0.7,
+				WebSearch:   true,
+			},
+			checkBody: func(t *testing.T, body string) {
+				if !strings.Contains(body, `"model":"gpt-4o-search-preview"`) {
+					t.Error("body should use the search model")
+				}
+				if !strings.Contains(body, `"web_search_options":{}`) {
+					t.Error("body should contain web_search_options")
+				}
+				if strings.Contains(body, `"temperature"`) {
+					t.Error("body should not contain temperature for web search")
+				}
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -842,6 +875,163 @@ data: [DONE]
 	}
 }
 
+// TestOpenAI_Chat_WebSearchCitations tests that cited URLs end the
+// response as a list of sources.
+func TestOpenAI_Chat_WebSearchCitations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		sseData := `data: {"choices":[{"delta":{"content":"Go 1.25 is out."}}]}
+
+data: {"choices":[{"delta":{"annotations":[{"type":"url_citation","url_citation":{"url":"https://go.dev/doc/go1.25","title":"Go 1.25 Release Notes"}}]}}]}
+
+data: {"choices":[{"delta":{"annotations":[{"type":"url_citation","url_citation":{"url":"https://go.dev/doc/go1.25","title":"Go 1.25 Release Notes"}},{"type":"url_citation","url_citation":{"url":"https://go.dev/blog"}}]}}]}
+
+data: {"choices":[{"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+		w.Write([]byte(sseData))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIWithBaseURL("test-api-key", server.URL)
+	stream := make(chan string, 10)
+	req := &ChatRequest{
+		Model:     "gpt-4o",
+		Messages:  []Message{{Role: "user", Content: "Hello"}},
+		WebSearch: true,
+	}
+	if err := provider.Chat(context.Background(), req, stream); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	var response strings.Builder
+	for token := range stream {
+		response.WriteString(token)
+	}
+	want := "Go 1.25 is out.\n\nSources:\n1. Go 1.25 Release Notes - https://go.dev/doc/go1.25\n2. https://go.dev/blog\n"
+	if response.String() != want {
+		t.Errorf("response = %q, want %q", response.String(), want)
+	}
+}
+
+// TestOpenAI_Chat_ContentFilter tests that a content_filter finish reason
+// is reported as an error after the partial content.
+func TestOpenAI_Chat_ContentFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Partial"}}]}
+
+data: {"choices":[{"delta":{},"finish_reason":"content_filter"}]}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIWithBaseURL("test-api-key", server.URL)
+	stream := make(chan string, 10)
+
+	err := provider.Chat(context.Background(), &ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	}, stream)
+	if !errors.Is(err, ErrContentFiltered) {
+		t.Fatalf("Chat() error = %v, want ErrContentFiltered", err)
+	}
+	if token := <-stream; token != "Partial" {
+		t.Errorf("first token = %q, want %q", token, "Partial")
+	}
+}
+
+// TestOpenAI_Chat_StreamErrors tests that an error sent after the
+// response began, a stream that ends before the response finished, or a
+// response stopped by the token limit, is reported as an error after the
+// partial content.
+func TestOpenAI_Chat_StreamErrors(t *testing.T) {
+	partial := `data: {"choices":[{"delta":{"content":"Partial"}}]}
+
+`
+	tests := []struct {
+		name          string
+		sseResponse   string
+		wantKind      error
+		wantCode      string
+		wantRetryable bool
+	}{
+		{
+			name: "server error",
+			sseResponse: partial + `data: {"error":{"message":"The server had an error","type":"server_error","code":null}}
+
+`,
+			wantKind:      ErrServer,
+			wantCode:      "server_error",
+			wantRetryable: true,
+		},
+		{
+			name: "invalid request",
+			sseResponse: partial + `data: {"error":{"message":"bad","type":"invalid_request_error","code":"context_length_exceeded"}}
+
+`,
+			wantKind: ErrAPI,
+			wantCode: "context_length_exceeded",
+		},
+		{
+			name: "token limit",
+			sseResponse: partial + `data: {"choices":[{"delta":{},"finish_reason":"length"}]}
+
+data: [DONE]
+
+`,
+			wantKind: ErrTruncated,
+		},
+		{
+			name:          "ended early",
+			sseResponse:   partial,
+			wantKind:      ErrServer,
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(tt.sseResponse))
+			}))
+			defer server.Close()
+
+			provider := NewOpenAIWithBaseURL("test-api-key", server.URL)
+			stream := make(chan string, 10)
+			err := provider.Chat(context.Background(), &ChatRequest{
+				Model:    "gpt-4o",
+				Messages: []Message{{Role: "user", Content: "Hello"}},
+			}, stream)
+
+			var tokens strings.Builder
+			for token := range stream {
+				tokens.WriteString(token)
+			}
+			if tokens.String() != "Partial" {
+				t.Errorf("tokens = %q, want %q", tokens.String(), "Partial")
+			}
+			var pe *Error
+			if !errors.As(err, &pe) || !errors.Is(err, tt.wantKind) {
+				t.Fatalf("Chat() error = %v, want a %v Error", err, tt.wantKind)
+			}
+			if pe.Code != tt.wantCode || pe.Retryable != tt.wantRetryable {
+				t.Errorf("error code = %q, retryable = %v; want %q, %v", pe.Code, pe.Retryable, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
+
 // TestOpenAI_Chat_UnicodeContent tests handling of Unicode content in responses.
 func TestOpenAI_Chat_UnicodeContent(t *testing.T) {
 	expectedTokens := []string{"Hello", " 世界", " 🌍", " مرحبا"}
@@ -931,3 +1121,64 @@ func TestNewOpenAI(t *testing.T) {
 		t.Error("client should not be nil")
 	}
 }
+
+// TestOpenAI_BuildRequest verifies the request is built without being sent.
+func TestOpenAI_BuildRequest(t *testing.T) {
+	provider := NewOpenAIWithBaseURL("test-key", "https://example.com/v1/chat")
+
+	req := &ChatRequest{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	}
+
+	httpReq, err := provider.BuildRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("BuildRequest() error = %v", err)
+	}
+
+	if httpReq.Method != http.MethodPost {
+		t.Errorf("Method = %q, want %q", httpReq.Method, http.MethodPost)
+	}
+	if httpReq.URL.String() != "https://example.com/v1/chat" {
+		t.Errorf("URL = %q, want %q", httpReq.URL.String(), "https://example.com/v1/chat")
+	}
+	if got := httpReq.Header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("Authorization header = %q, want %q", got, "Bearer test-key")
+	}
+
+	body, err := io.ReadAll(httpReq.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if !strings.Contains(string(body), `"model":"gpt-4o"`) {
+		t.Errorf("body = %s, want to contain model", body)
+	}
+}
+
+// TestOpenAI_Embed tests that embeddings are returned in input order.
+func TestOpenAI_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %q, want /embeddings", r.URL.Path)
+		}
+		var req openAIEmbeddingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "text-embedding-3-small" || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+		// Out of order, as the API does not guarantee ordering
+		w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer server.Close()
+
+	provider := NewOpenAIWithBaseURL("test-api-key", server.URL)
+	vectors, err := provider.Embed(context.Background(), "text-embedding-3-small", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("Embed() = %v, want vectors in input order", vectors)
+	}
+}
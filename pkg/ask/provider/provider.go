@@ -0,0 +1,105 @@
+// Package provider defines the interface for LLM providers
+// and implements the provider factory.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Message represents a chat message.
+type Message struct {
+	Role    string `json:"role"` // "system", "user", or "assistant"
+	Content string `json:"content"`
+}
+
+// ChatRequest contains the parameters for a chat completion request.
+type ChatRequest struct {
+	Messages    []Message
+	Model       string
+	Temperature float64
+	MaxTokens   int
+
+	// WebSearch lets the model search the web with the provider's own
+	// search tool. The response ends with the sources it cites (see
+	// FormatSources).
+	WebSearch bool
+}
+
+// Provider is the interface that all LLM providers must implement.
+// Adding a new provider requires implementing this interface in a single file.
+type Provider interface {
+	// Chat sends a chat request and streams tokens to the channel.
+	// The channel is closed when the response is complete or an error occurs.
+	Chat(ctx context.Context, req *ChatRequest, stream chan<- string) error
+
+	// BuildRequest builds the HTTP request Chat would send, without sending it.
+	BuildRequest(ctx context.Context, req *ChatRequest) (*http.Request, error)
+
+	// Models returns the list of available models for this provider.
+	Models() []string
+
+	// Name returns the provider name.
+	Name() string
+}
+
+// TokenCounter is implemented by providers that can count the input
+// tokens of a request exactly.
+type TokenCounter interface {
+	// CountTokens returns the number of input tokens req would consume.
+	CountTokens(ctx context.Context, req *ChatRequest) (int, error)
+}
+
+// Embedder is implemented by providers that can compute text embeddings.
+type Embedder interface {
+	// Embed returns an embedding vector for each of texts, in order.
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// Prefiller is implemented by providers whose models can carry on from
+// the start of a reply, so that a response cut short can be resumed
+// rather than sent again.
+type Prefiller interface {
+	// Prefill returns req with partial as the start of the reply, for
+	// the rest to be streamed.
+	Prefill(req *ChatRequest, partial string) *ChatRequest
+}
+
+// Transporter is implemented by providers that send HTTP requests, to
+// change how they are sent, such as to trace them.
+type Transporter interface {
+	// SetTransport sends the provider's requests through rt.
+	SetTransport(rt http.RoundTripper)
+}
+
+// DefaultEmbeddingModel is the embedding model used when none is given.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// New creates the provider called name, "openai", "anthropic", or
+// "local", using apiKey, with Logging middleware. Local models need no
+// key, nor does MockName, which is for tests (see SetMock).
+func New(name, apiKey string) (Provider, error) {
+	switch name {
+	case "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("OpenAI %w", ErrNoAPIKey)
+		}
+		return Chain(NewOpenAI(apiKey), Logging()), nil
+	case "anthropic":
+		if apiKey == "" {
+			return nil, fmt.Errorf("Anthropic %w", ErrNoAPIKey)
+		}
+		return Chain(NewAnthropic(apiKey), Logging()), nil
+	case "local":
+		l, err := NewLocal()
+		if err != nil {
+			return nil, err
+		}
+		return Chain(l, Logging()), nil
+	case MockName:
+		return Chain(currentMock(), Logging()), nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s\n\nAvailable providers: openai, anthropic, local", name)
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimits is what a provider said about its rate limits when it
+// throttled a request.
+type RateLimits struct {
+	// RetryAfter is how long the provider asked to wait, or 0 if it did
+	// not say.
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+
+	// Requests and Tokens are the request and token quotas, where known.
+	Requests Quota `json:"requests,omitzero"`
+	Tokens   Quota `json:"tokens,omitzero"`
+}
+
+// Quota is one rate limit: how much of Limit is left, and when it
+// resets. A zero Limit means the provider did not report it.
+type Quota struct {
+	Limit     int           `json:"limit"`
+	Remaining int           `json:"remaining"`
+	Reset     time.Duration `json:"reset,omitempty"` // time until it is replenished
+}
+
+// exhausted reports whether the quota is known and used up.
+func (q Quota) exhausted() bool {
+	return q.Limit > 0 && q.Remaining <= 0
+}
+
+// Wait returns how long to wait before the request may succeed: the
+// provider's Retry-After, or else the time until the used-up quotas
+// reset, or 0 if unknown.
+func (r *RateLimits) Wait() time.Duration {
+	if r.RetryAfter > 0 {
+		return r.RetryAfter
+	}
+	var wait time.Duration
+	for _, q := range []Quota{r.Requests, r.Tokens} {
+		if q.exhausted() {
+			wait = max(wait, q.Reset)
+		}
+	}
+	return wait
+}
+
+// String describes the limit, such as "resets in 34s, 0/500k tokens
+// remaining". Used-up quotas are listed, or if none is, every quota
+// reported.
+func (r *RateLimits) String() string {
+	var parts []string
+	if wait := r.Wait(); wait > 0 {
+		parts = append(parts, "resets in "+formatWait(wait))
+	}
+	quotas := []struct {
+		q    Quota
+		unit string
+	}{{r.Requests, "requests"}, {r.Tokens, "tokens"}}
+	anyExhausted := r.Requests.exhausted() || r.Tokens.exhausted()
+	for _, quota := range quotas {
+		if quota.q.Limit == 0 || anyExhausted && !quota.q.exhausted() {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s/%s %s remaining", shortCount(max(quota.q.Remaining, 0)), shortCount(quota.q.Limit), quota.unit))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rateLimitHeaders are the names of a provider's rate limit headers.
+type rateLimitHeaders struct {
+	limit, remaining, reset string
+}
+
+var (
+	// OpenAI resets are durations such as "6m0s"
+	openAIRequestHeaders = rateLimitHeaders{"X-Ratelimit-Limit-Requests", "X-Ratelimit-Remaining-Requests", "X-Ratelimit-Reset-Requests"}
+	openAITokenHeaders   = rateLimitHeaders{"X-Ratelimit-Limit-Tokens", "X-Ratelimit-Remaining-Tokens", "X-Ratelimit-Reset-Tokens"}
+
+	// Anthropic resets are RFC 3339 times
+	anthropicRequestHeaders = rateLimitHeaders{"Anthropic-Ratelimit-Requests-Limit", "Anthropic-Ratelimit-Requests-Remaining", "Anthropic-Ratelimit-Requests-Reset"}
+	anthropicTokenHeaders   = rateLimitHeaders{"Anthropic-Ratelimit-Tokens-Limit", "Anthropic-Ratelimit-Tokens-Remaining", "Anthropic-Ratelimit-Tokens-Reset"}
+	anthropicInputHeaders   = rateLimitHeaders{"Anthropic-Ratelimit-Input-Tokens-Limit", "Anthropic-Ratelimit-Input-Tokens-Remaining", "Anthropic-Ratelimit-Input-Tokens-Reset"}
+)
+
+// parseRateLimits reads the rate limit headers of OpenAI and Anthropic
+// responses, as of now. It returns nil if there are none.
+func parseRateLimits(h http.Header, now time.Time) *RateLimits {
+	r := &RateLimits{RetryAfter: parseRetryAfter(h.Get("Retry-After"), now)}
+	r.Requests = firstQuota(h, now, openAIRequestHeaders, anthropicRequestHeaders)
+	// Anthropic reports input tokens apart when they are limited apart
+	r.Tokens = firstQuota(h, now, openAITokenHeaders, anthropicTokenHeaders, anthropicInputHeaders)
+	if *r == (RateLimits{}) {
+		return nil
+	}
+	return r
+}
+
+// firstQuota returns the quota of the first of names that h has.
+func firstQuota(h http.Header, now time.Time, names ...rateLimitHeaders) Quota {
+	for _, n := range names {
+		limit, err := strconv.Atoi(h.Get(n.limit))
+		if err != nil || limit <= 0 {
+			continue
+		}
+		q := Quota{Limit: limit}
+		q.Remaining, _ = strconv.Atoi(h.Get(n.remaining))
+		reset := h.Get(n.reset)
+		if d, err := time.ParseDuration(reset); err == nil {
+			q.Reset = d
+		} else if t, err := time.Parse(time.RFC3339, reset); err == nil {
+			q.Reset = max(t.Sub(now), 0)
+		}
+		return q
+	}
+	return Quota{}
+}
+
+// parseRetryAfter parses a Retry-After header: seconds, or an HTTP date.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// formatWait formats a wait rounded up to whole seconds, such as "34s"
+// or "2m5s".
+func formatWait(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
+
+// shortCount formats a count compactly, such as "500k" or "1.5M".
+func shortCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e6), ".0") + "M"
+	case n >= 1_000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1e3), ".0") + "k"
+	default:
+		return strconv.Itoa(n)
+	}
+}
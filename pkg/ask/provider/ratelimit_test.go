@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		want      *RateLimits
+		wantWait  time.Duration
+		wantShown string
+	}{
+		{name: "none", headers: map[string]string{"Content-Type": "application/json"}},
+		{
+			name: "openai tokens exhausted",
+			headers: map[string]string{
+				"x-ratelimit-limit-requests":     "500",
+				"x-ratelimit-remaining-requests": "499",
+				"x-ratelimit-reset-requests":     "120ms",
+				"x-ratelimit-limit-tokens":       "500000",
+				"x-ratelimit-remaining-tokens":   "0",
+				"x-ratelimit-reset-tokens":       "33.2s",
+			},
+			want: &RateLimits{
+				Requests: Quota{Limit: 500, Remaining: 499, Reset: 120 * time.Millisecond},
+				Tokens:   Quota{Limit: 500000, Remaining: 0, Reset: 33200 * time.Millisecond},
+			},
+			wantWait:  33200 * time.Millisecond,
+			wantShown: "resets in 34s, 0/500k tokens remaining",
+		},
+		{
+			name: "anthropic requests exhausted",
+			headers: map[string]string{
+				"retry-after":                                "7",
+				"anthropic-ratelimit-requests-limit":         "50",
+				"anthropic-ratelimit-requests-remaining":     "0",
+				"anthropic-ratelimit-requests-reset":         "2025-06-01T12:00:07Z",
+				"anthropic-ratelimit-input-tokens-limit":     "1500000",
+				"anthropic-ratelimit-input-tokens-remaining": "1200000",
+				"anthropic-ratelimit-input-tokens-reset":     "2025-06-01T12:00:01Z",
+			},
+			want: &RateLimits{
+				RetryAfter: 7 * time.Second,
+				Requests:   Quota{Limit: 50, Remaining: 0, Reset: 7 * time.Second},
+				Tokens:     Quota{Limit: 1500000, Remaining: 1200000, Reset: time.Second},
+			},
+			wantWait:  7 * time.Second,
+			wantShown: "resets in 7s, 0/50 requests remaining",
+		},
+		{
+			name:      "retry after date only",
+			headers:   map[string]string{"Retry-After": "Sun, 01 Jun 2025 12:02:05 GMT"},
+			want:      &RateLimits{RetryAfter: 2*time.Minute + 5*time.Second},
+			wantWait:  2*time.Minute + 5*time.Second,
+			wantShown: "resets in 2m5s",
+		},
+		{
+			name: "nothing exhausted",
+			headers: map[string]string{
+				"x-ratelimit-limit-requests":     "10000",
+				"x-ratelimit-remaining-requests": "9000",
+				"x-ratelimit-limit-tokens":       "2000000",
+				"x-ratelimit-remaining-tokens":   "1250",
+			},
+			want: &RateLimits{
+				Requests: Quota{Limit: 10000, Remaining: 9000},
+				Tokens:   Quota{Limit: 2000000, Remaining: 1250},
+			},
+			wantShown: "9k/10k requests remaining, 1.2k/2M tokens remaining",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			got := parseRateLimits(h, now)
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Fatalf("parseRateLimits() = %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if w := got.Wait(); w != tt.wantWait {
+				t.Errorf("Wait() = %v, want %v", w, tt.wantWait)
+			}
+			if s := got.String(); s != tt.wantShown {
+				t.Errorf("String() = %q, want %q", s, tt.wantShown)
+			}
+		})
+	}
+}
+
+func TestHTTPError_RateLimits(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Retry-After":                  {"34"},
+			"X-Ratelimit-Limit-Tokens":     {"500000"},
+			"X-Ratelimit-Remaining-Tokens": {"0"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"error":{"message":"Rate limit reached","code":"rate_limit_exceeded"}}`)),
+	}
+	e := httpError("openai", resp, "")
+	want := "OpenAI rate limited (status 429): Rate limit reached; resets in 34s, 0/500k tokens remaining"
+	if e.Error() != want {
+		t.Errorf("Error() = %q\nwant %q", e.Error(), want)
+	}
+	if e.RateLimits == nil || e.RateLimits.Wait() != 34*time.Second {
+		t.Errorf("RateLimits = %+v, want a 34s wait", e.RateLimits)
+	}
+}
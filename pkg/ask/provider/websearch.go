@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Citation is a web page that a response to a ChatRequest with WebSearch
+// cites.
+type Citation struct {
+	URL   string
+	Title string
+}
+
+// citations collects citations in the order they are first cited.
+type citations struct {
+	list []Citation
+	seen map[string]bool
+}
+
+// add records a citation, ignoring repeats of a URL.
+func (c *citations) add(url, title string) {
+	if url == "" || c.seen[url] {
+		return
+	}
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+	}
+	c.seen[url] = true
+	c.list = append(c.list, Citation{URL: url, Title: strings.TrimSpace(title)})
+}
+
+// FormatSources returns a numbered list of sources to end a response
+// with, so they are shown and saved along with it, or "" if there are
+// none.
+func FormatSources(cs []Citation) string {
+	if len(cs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nSources:\n")
+	for i, c := range cs {
+		if c.Title != "" {
+			fmt.Fprintf(&b, "%d. %s - %s\n", i+1, c.Title, c.URL)
+		} else {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, c.URL)
+		}
+	}
+	return b.String()
+}
+
+// sendSources streams the sources of cs, if any, to end a response.
+func sendSources(ctx context.Context, stream chan<- string, cs []Citation) error {
+	sources := FormatSources(cs)
+	if sources == "" {
+		return nil
+	}
+	select {
+	case stream <- sources:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,177 @@
+// Package sse provides a shared SSE (Server-Sent Events) reader
+// for parsing streaming responses from LLM providers.
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devaloi/ask/internal/logging"
+)
+
+// Event represents a parsed SSE event.
+type Event struct {
+	Type string // The event type (from "event:" lines), may be empty
+	Data string // The event data (from "data:" lines)
+
+	// ID is the last event ID (from "id:" lines) the stream has set, by
+	// this event or an earlier one, for resuming it. It may be empty.
+	ID string
+
+	// Retry is how long the stream asks clients to wait before
+	// reconnecting (from "retry:" lines), or 0 if it has not said.
+	Retry time.Duration
+}
+
+// Reader reads SSE events from an io.Reader.
+type Reader struct {
+	scanner *bufio.Scanner
+	ctx     context.Context
+
+	lastID  string
+	retry   time.Duration
+	afterCR bool // the last line ended in CR, so a LF next ends nothing
+}
+
+// NewReader creates a new SSE reader.
+func NewReader(ctx context.Context, r io.Reader) *Reader {
+	reader := &Reader{
+		scanner: bufio.NewScanner(r),
+		ctx:     ctx,
+	}
+	reader.scanner.Split(reader.scanLines)
+	return reader
+}
+
+// scanLines is a bufio.SplitFunc for the lines of an event stream, which
+// may end in CRLF, LF, or CR alone. A line ending in CR is returned at
+// once, without waiting to see whether LF follows.
+func (r *Reader) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if r.afterCR && len(data) > 0 {
+		r.afterCR = false
+		if data[0] == '\n' {
+			return 1, nil, nil
+		}
+	}
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' {
+			if i+1 == len(data) {
+				r.afterCR = true
+			} else if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Read reads SSE events and sends them to the provided channel.
+// The channel is NOT closed by this function; the caller is responsible for closing it.
+// Returns nil when the stream ends normally, or an error if something goes wrong.
+func (r *Reader) Read(events chan<- Event) error {
+	var currentEvent Event
+	var data strings.Builder
+	hasData := false
+	first := true
+
+	// dispatch sends the event gathered so far, if it has data, and
+	// starts the next.
+	dispatch := func() error {
+		currentEvent.Data = data.String()
+		if currentEvent.Data != "" {
+			currentEvent.ID, currentEvent.Retry = r.lastID, r.retry
+			select {
+			case events <- currentEvent:
+			case <-r.ctx.Done():
+				return r.ctx.Err()
+			}
+		}
+		currentEvent = Event{}
+		data.Reset()
+		hasData = false
+		return nil
+	}
+
+	for r.scanner.Scan() {
+		// Check for context cancellation
+		select {
+		case <-r.ctx.Done():
+			return r.ctx.Err()
+		default:
+		}
+
+		line := r.scanner.Text()
+		if first {
+			// A stream may start with a byte order mark
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+
+		// Empty line marks the end of an event
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Skip comments (lines starting with ':')
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		// A field is its name, then optionally a colon, an optional
+		// space, and its value
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			currentEvent.Type = strings.TrimSpace(value)
+		case "data":
+			// Multiple data lines are joined by newlines
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			// The spec ignores IDs holding NUL
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		case "retry":
+			// Milliseconds, in ASCII digits only
+			if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		default:
+			slog.Debug("ignoring unexpected SSE line", "line", logging.Truncate(line, 200))
+		}
+	}
+
+	// Check for scanner errors
+	if err := r.scanner.Err(); err != nil {
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		slog.Warn("SSE stream read failed", "error", err)
+		return fmt.Errorf("error reading SSE stream: %w", err)
+	}
+
+	// Emit any remaining event
+	return dispatch()
+}
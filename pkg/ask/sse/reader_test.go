@@ -2,8 +2,10 @@ package sse
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 )
 
@@ -129,6 +131,75 @@ func TestReader_Read(t *testing.T) {
 	}
 }
 
+func TestReader_Read_Spec(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{
+			name:  "CRLF line endings",
+			input: "event: delta\r\ndata: a\r\ndata: b\r\n\r\ndata: c\r\n\r\n",
+			want:  []Event{{Type: "delta", Data: "a\nb"}, {Data: "c"}},
+		},
+		{
+			name:  "CR line endings",
+			input: "data: a\rdata: b\r\rdata: c\r\r",
+			want:  []Event{{Data: "a\nb"}, {Data: "c"}},
+		},
+		{
+			name:  "byte order mark",
+			input: "\ufeffdata: hello\n\n",
+			want:  []Event{{Data: "hello"}},
+		},
+		{
+			name:  "ids persist",
+			input: "id: 1\ndata: a\n\ndata: b\n\nid: 2\ndata: c\n\nid\ndata: d\n\n",
+			want:  []Event{{ID: "1", Data: "a"}, {ID: "1", Data: "b"}, {ID: "2", Data: "c"}, {Data: "d"}},
+		},
+		{
+			name:  "id with NUL ignored",
+			input: "id: 1\ndata: a\n\nid: 2\x00\ndata: b\n\n",
+			want:  []Event{{ID: "1", Data: "a"}, {ID: "1", Data: "b"}},
+		},
+		{
+			name:  "retry",
+			input: "retry: 3000\n\ndata: a\n\nretry: soon\ndata: b\n\n",
+			want:  []Event{{Data: "a", Retry: 3 * time.Second}, {Data: "b", Retry: 3 * time.Second}},
+		},
+		{
+			name:  "empty first data line kept",
+			input: "data\ndata: a\n\n",
+			want:  []Event{{Data: "\na"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time, so line endings are split across reads
+			reader := NewReader(context.Background(), iotest.OneByteReader(strings.NewReader(tt.input)))
+
+			events := make(chan Event, 10)
+			done := make(chan error, 1)
+			go func() {
+				done <- reader.Read(events)
+				close(events)
+			}()
+
+			var got []Event
+			for e := range events {
+				got = append(got, e)
+			}
+			if err := <-done; err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestReader_Read_ContextCancellation(t *testing.T) {
 	// Create a slow reader that never ends
 	slowReader := &slowStringReader{